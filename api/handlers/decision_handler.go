@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kcloud-opt/policy/internal/enforcer"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// DecisionHandler handles decision-related HTTP requests
+type DecisionHandler struct {
+	storage   storage.StorageManager
+	approvals enforcer.ApprovalManager
+	enforcer  enforcer.PolicyEnforcer
+	logger    types.Logger
+}
+
+// NewDecisionHandler creates a new decision handler
+func NewDecisionHandler(storage storage.StorageManager, approvals enforcer.ApprovalManager, policyEnforcer enforcer.PolicyEnforcer, logger types.Logger) *DecisionHandler {
+	return &DecisionHandler{
+		storage:   storage,
+		approvals: approvals,
+		enforcer:  policyEnforcer,
+		logger:    logger,
+	}
+}
+
+// reviewRequest is the body of an approve or reject request
+type reviewRequest struct {
+	Approver string `json:"approver" binding:"required"`
+	Comment  string `json:"comment"`
+}
+
+// ListDecisions handles GET /decisions
+func (h *DecisionHandler) ListDecisions(c *gin.Context) {
+	startTime := time.Now()
+
+	filters := &storage.DecisionFilters{}
+	if decisionType := c.Query("type"); decisionType != "" {
+		dt := types.DecisionType(decisionType)
+		filters.Type = &dt
+	}
+	if status := c.Query("status"); status != "" {
+		ds := types.DecisionStatus(status)
+		filters.Status = &ds
+	}
+	if workloadID := c.Query("workload_id"); workloadID != "" {
+		filters.WorkloadID = &workloadID
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil && l > 0 {
+			filters.Limit = l
+		}
+	}
+	if offset := c.Query("offset"); offset != "" {
+		if o, err := strconv.Atoi(offset); err == nil && o >= 0 {
+			filters.Offset = o
+		}
+	}
+
+	decisions, err := h.storage.Decision().List(c.Request.Context(), filters)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to list decisions")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "decision_list_failed",
+			"message": "Failed to list decisions",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("decisions listed successfully", "count", len(decisions))
+
+	c.JSON(http.StatusOK, gin.H{
+		"decisions": decisions,
+		"count":     len(decisions),
+		"duration":  duration.String(),
+	})
+}
+
+// GetDecision handles GET /decisions/:id
+func (h *DecisionHandler) GetDecision(c *gin.Context) {
+	startTime := time.Now()
+	decisionID := c.Param("id")
+
+	decision, err := h.storage.Decision().Get(c.Request.Context(), decisionID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get decision", "decision_id", decisionID)
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "decision_not_found",
+			"message": "Decision not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	c.JSON(http.StatusOK, gin.H{
+		"decision": decision,
+		"duration": duration.String(),
+	})
+}
+
+// SubmitDecision handles POST /decisions/:id/submit
+func (h *DecisionHandler) SubmitDecision(c *gin.Context) {
+	startTime := time.Now()
+	decisionID := c.Param("id")
+
+	outcome, err := h.approvals.Submit(c.Request.Context(), &types.Decision{ID: decisionID})
+	if err != nil {
+		h.logger.WithError(err).Error("failed to submit decision for approval", "decision_id", decisionID)
+		c.JSON(approvalErrorStatus(err), gin.H{
+			"error":   "decision_submission_failed",
+			"message": "Failed to submit decision for approval",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	c.JSON(http.StatusOK, gin.H{
+		"outcome":  outcome,
+		"duration": duration.String(),
+	})
+}
+
+// ApproveDecision handles POST /decisions/:id/approve
+func (h *DecisionHandler) ApproveDecision(c *gin.Context) {
+	h.reviewDecision(c, true)
+}
+
+// RejectDecision handles POST /decisions/:id/reject
+func (h *DecisionHandler) RejectDecision(c *gin.Context) {
+	h.reviewDecision(c, false)
+}
+
+// reviewDecision records a reviewer's verdict on a decision
+func (h *DecisionHandler) reviewDecision(c *gin.Context, approve bool) {
+	startTime := time.Now()
+	decisionID := c.Param("id")
+
+	var request reviewRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("failed to bind review JSON")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_review_format",
+			"message": "Review requires an approver",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	review := h.approvals.Approve
+	verdict := "approved"
+	if !approve {
+		review = h.approvals.Reject
+		verdict = "rejected"
+	}
+
+	decision, err := review(c.Request.Context(), decisionID, request.Approver, request.Comment)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to review decision", "decision_id", decisionID)
+		c.JSON(approvalErrorStatus(err), gin.H{
+			"error":   "decision_review_failed",
+			"message": "Failed to review decision",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("decision reviewed", "decision_id", decisionID, "verdict", verdict, "approver", request.Approver)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Decision " + verdict,
+		"decision": decision,
+		"duration": duration.String(),
+	})
+}
+
+// RollbackDecision handles POST /decisions/:id/rollback
+func (h *DecisionHandler) RollbackDecision(c *gin.Context) {
+	startTime := time.Now()
+	decisionID := c.Param("id")
+
+	status, err := h.enforcer.Rollback(c.Request.Context(), decisionID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to roll back decision", "decision_id", decisionID)
+		c.JSON(approvalErrorStatus(err), gin.H{
+			"error":   "decision_rollback_failed",
+			"message": "Failed to roll back decision",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("decision rolled back", "decision_id", decisionID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Decision rolled back",
+		"enforcement": status,
+		"duration":    duration.String(),
+	})
+}
+
+// GetDecisionPlan handles GET /decisions/:id/plan. It returns the plan
+// recorded by a dry run, or plans the decision when there is none or when
+// refresh=true is passed.
+func (h *DecisionHandler) GetDecisionPlan(c *gin.Context) {
+	startTime := time.Now()
+	decisionID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var plan *enforcer.ExecutionPlan
+	var err error
+	if refresh, _ := strconv.ParseBool(c.Query("refresh")); refresh {
+		var decision *types.Decision
+		if decision, err = h.storage.Decision().Get(ctx, decisionID); err == nil {
+			plan, err = h.enforcer.Plan(ctx, decision)
+		}
+	} else {
+		plan, err = h.enforcer.GetPlan(ctx, decisionID)
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("failed to plan decision", "decision_id", decisionID)
+		c.JSON(approvalErrorStatus(err), gin.H{
+			"error":   "decision_plan_failed",
+			"message": "Failed to plan decision",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("decision planned", "decision_id", decisionID, "steps", len(plan.Steps))
+
+	c.JSON(http.StatusOK, gin.H{
+		"plan":     plan,
+		"duration": duration.String(),
+	})
+}
+
+// GetDecisionHistory handles GET /decisions/:id/history
+func (h *DecisionHandler) GetDecisionHistory(c *gin.Context) {
+	startTime := time.Now()
+	decisionID := c.Param("id")
+
+	history, err := h.storage.Decision().GetHistory(c.Request.Context(), decisionID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get decision history", "decision_id", decisionID)
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "decision_not_found",
+			"message": "Decision not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	c.JSON(http.StatusOK, gin.H{
+		"history":  history,
+		"count":    len(history),
+		"duration": duration.String(),
+	})
+}
+
+// approvalErrorStatus maps approval and enforcement errors onto HTTP status codes
+func approvalErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrDecisionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, types.ErrInvalidDecisionStatus), errors.Is(err, types.ErrInvalidTransition):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
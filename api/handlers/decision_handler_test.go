@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kcloud-opt/policy/internal/actions"
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/decisions"
+	"github.com/kcloud-opt/policy/internal/enforcer"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLogger discards log output
+type testLogger struct{}
+
+func (l testLogger) Info(msg string, fields ...interface{})                    {}
+func (l testLogger) Warn(msg string, fields ...interface{})                    {}
+func (l testLogger) Error(msg string, fields ...interface{})                   {}
+func (l testLogger) Debug(msg string, fields ...interface{})                   {}
+func (l testLogger) Fatal(msg string, fields ...interface{})                   {}
+func (l testLogger) WithError(err error) types.Logger                          { return l }
+func (l testLogger) WithDuration(duration time.Duration) types.Logger          { return l }
+func (l testLogger) WithPolicy(policyID, policyName string) types.Logger       { return l }
+func (l testLogger) WithWorkload(workloadID, workloadType string) types.Logger { return l }
+func (l testLogger) WithEvaluation(evaluationID string) types.Logger           { return l }
+
+// newPlanningHandler returns a decision handler over memory storage whose
+// enforcer only plans decisions
+func newPlanningHandler(t *testing.T) (*DecisionHandler, storage.StorageManager, decisions.Lifecycle) {
+	t.Helper()
+	store := memory.NewStorageManager()
+	lifecycle := decisions.NewLifecycle(store, config.DecisionConfig{}, testLogger{})
+
+	registry := actions.NewRegistry(testLogger{})
+	for _, executor := range enforcer.NewDefaultExecutors(store, testLogger{}) {
+		require.NoError(t, registry.Register(executor))
+	}
+	cfg := config.EnforcementConfig{Mode: config.EnforcementModeDryRun}
+	policyEnforcer := enforcer.NewPolicyEnforcer(
+		enforcer.NewEnforcementEngine(registry, testLogger{}),
+		store,
+		lifecycle,
+		enforcer.NewSafetyLimiter(store, cfg.Safety, testLogger{}),
+		enforcer.NewMaintenanceCalendar(store, testLogger{}),
+		enforcer.NewLockManager(cfg.Locks, testLogger{}),
+		nil,
+		cfg,
+		testLogger{},
+	)
+
+	return NewDecisionHandler(store, nil, policyEnforcer, testLogger{}), store, lifecycle
+}
+
+// getPlan serves GET /decisions/:id/plan
+func getPlan(handler *DecisionHandler, decisionID, query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/decisions/"+decisionID+"/plan"+query, nil)
+	c.Params = []gin.Param{{Key: "id", Value: decisionID}}
+	handler.GetDecisionPlan(c)
+	return w
+}
+
+func TestDecisionHandler_GetDecisionPlan(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	handler, store, lifecycle := newPlanningHandler(t)
+
+	require.NoError(t, store.Workload().Create(ctx, &types.Workload{
+		ID:          "wl-1",
+		Name:        "wl-1",
+		Type:        types.WorkloadTypeWeb,
+		Status:      types.WorkloadStatusRunning,
+		Labels:      map[string]string{"cluster": "cluster-a", "node": "node-1"},
+		Annotations: map[string]string{enforcer.AnnotationReplicas: "2"},
+	}))
+	decision := &types.Decision{
+		ID:                 "d-1",
+		PolicyID:           "policy-1",
+		Type:               types.DecisionTypeMigrate,
+		WorkloadID:         "wl-1",
+		RecommendedCluster: "cluster-b",
+		RecommendedNode:    "node-2",
+	}
+	require.NoError(t, lifecycle.Create(ctx, decision))
+
+	var body struct {
+		Plan *enforcer.ExecutionPlan `json:"plan"`
+	}
+
+	w := getPlan(handler, "d-1", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "d-1", body.Plan.DecisionID)
+	assert.Equal(t, config.EnforcementModeDryRun, body.Plan.Mode)
+	assert.True(t, body.Plan.Executable)
+	assert.Equal(t, []enforcer.RiskFlag{enforcer.RiskFlagDisruptive}, body.Plan.Risks)
+	require.Len(t, body.Plan.Steps, 1)
+	assert.Equal(t, enforcer.ActionTypeMigrate, body.Plan.Steps[0].Action.Type)
+	assert.Equal(t, "node-1", body.Plan.Steps[0].Target.Node)
+	assert.Equal(t, "node-2", body.Plan.Steps[0].After["node"])
+	generatedAt := body.Plan.GeneratedAt
+
+	// The recorded plan is returned until a refresh plans the decision again
+	w = getPlan(handler, "d-1", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.True(t, generatedAt.Equal(body.Plan.GeneratedAt))
+
+	w = getPlan(handler, "d-1", "?refresh=true")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.True(t, body.Plan.GeneratedAt.After(generatedAt))
+
+	w = getPlan(handler, "d-2", "")
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+}
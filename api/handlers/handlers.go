@@ -2,18 +2,26 @@ package handlers
 
 import (
 	"github.com/kcloud-opt/policy/internal/automation"
+	"github.com/kcloud-opt/policy/internal/enforcer"
 	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/optimizer"
 	"github.com/kcloud-opt/policy/internal/storage"
 	"github.com/kcloud-opt/policy/internal/types"
+	"github.com/kcloud-opt/policy/internal/webhook"
 )
 
 // Handlers contains all HTTP handlers for the policy engine API
 type Handlers struct {
-	Policy     *PolicyHandler
-	Workload   *WorkloadHandler
-	Evaluation *EvaluationHandler
-	Automation *AutomationHandler
-	Health     *HealthHandler
+	Policy       *PolicyHandler
+	Workload     *WorkloadHandler
+	Evaluation   *EvaluationHandler
+	Automation   *AutomationHandler
+	Decision     *DecisionHandler
+	Maintenance  *MaintenanceHandler
+	Rollout      *RolloutHandler
+	Webhook      *WebhookHandler
+	Optimization *OptimizationHandler
+	Health       *HealthHandler
 }
 
 // NewHandlers creates a new handlers instance with all dependencies
@@ -21,13 +29,27 @@ func NewHandlers(
 	storage storage.StorageManager,
 	evaluator evaluator.EvaluationEngine,
 	automation automation.AutomationEngine,
+	rightSizer optimizer.RightSizingAnalyzer,
+	preemption optimizer.PreemptionPlanner,
+	spot optimizer.SpotAnalyzer,
+	consolidation optimizer.ConsolidationPlanner,
+	approvals enforcer.ApprovalManager,
+	policyEnforcer enforcer.PolicyEnforcer,
+	safetyLimiter enforcer.SafetyLimiter,
+	lockManager enforcer.LockManager,
+	dispatcher webhook.Dispatcher,
 	logger types.Logger,
 ) *Handlers {
 	return &Handlers{
-		Policy:     NewPolicyHandler(storage, logger),
-		Workload:   NewWorkloadHandler(storage, logger),
-		Evaluation: NewEvaluationHandler(storage, evaluator, logger),
-		Automation: NewAutomationHandler(storage, automation, logger),
-		Health:     NewHealthHandler(storage, evaluator, automation, logger),
+		Policy:       NewPolicyHandler(storage, logger),
+		Workload:     NewWorkloadHandler(storage, rightSizer, logger),
+		Evaluation:   NewEvaluationHandler(storage, evaluator, logger),
+		Automation:   NewAutomationHandler(storage, automation, logger),
+		Decision:     NewDecisionHandler(storage, approvals, policyEnforcer, logger),
+		Maintenance:  NewMaintenanceHandler(storage, logger),
+		Rollout:      NewRolloutHandler(storage, policyEnforcer, logger),
+		Webhook:      NewWebhookHandler(dispatcher, logger),
+		Optimization: NewOptimizationHandler(storage, preemption, spot, consolidation, logger),
+		Health:       NewHealthHandler(storage, evaluator, automation, safetyLimiter, lockManager, logger),
 	}
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/kcloud-opt/policy/internal/automation"
+	"github.com/kcloud-opt/policy/internal/enforcer"
 	"github.com/kcloud-opt/policy/internal/evaluator"
 	"github.com/kcloud-opt/policy/internal/storage"
 	"github.com/kcloud-opt/policy/internal/types"
@@ -16,15 +17,19 @@ type HealthHandler struct {
 	storage    storage.StorageManager
 	evaluator  evaluator.EvaluationEngine
 	automation automation.AutomationEngine
+	limiter    enforcer.SafetyLimiter
+	locks      enforcer.LockManager
 	logger     types.Logger
 }
 
 // NewHealthHandler creates a new health handler
-func NewHealthHandler(storage storage.StorageManager, evaluator evaluator.EvaluationEngine, automation automation.AutomationEngine, logger types.Logger) *HealthHandler {
+func NewHealthHandler(storage storage.StorageManager, evaluator evaluator.EvaluationEngine, automation automation.AutomationEngine, limiter enforcer.SafetyLimiter, locks enforcer.LockManager, logger types.Logger) *HealthHandler {
 	return &HealthHandler{
 		storage:    storage,
 		evaluator:  evaluator,
 		automation: automation,
+		limiter:    limiter,
+		locks:      locks,
 		logger:     logger,
 	}
 }
@@ -216,6 +221,18 @@ func (h *HealthHandler) SystemStatus(c *gin.Context) {
 		}
 	}
 
+	// Enforcement safety budgets and workload locks
+	enforcement := map[string]interface{}{}
+	if h.limiter != nil {
+		enforcement["budgets"] = h.limiter.Usage()
+	}
+	if h.locks != nil {
+		enforcement["locks"] = h.locks.Leases()
+	}
+	if len(enforcement) > 0 {
+		status["enforcement"] = enforcement
+	}
+
 	// System info
 	status["system"] = map[string]interface{}{
 		"service": "policy-engine",
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// MaintenanceHandler handles maintenance window and change freeze HTTP requests
+type MaintenanceHandler struct {
+	storage storage.StorageManager
+	logger  types.Logger
+}
+
+// NewMaintenanceHandler creates a new maintenance handler
+func NewMaintenanceHandler(storage storage.StorageManager, logger types.Logger) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// CreateMaintenanceWindow handles POST /maintenance-windows
+func (h *MaintenanceHandler) CreateMaintenanceWindow(c *gin.Context) {
+	startTime := time.Now()
+
+	var window types.MaintenanceWindow
+	if err := c.ShouldBindJSON(&window); err != nil {
+		h.logger.WithError(err).Error("failed to bind maintenance window JSON")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_window_format",
+			"message": "Failed to parse maintenance window JSON",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.storage.Maintenance().Create(c.Request.Context(), &window); err != nil {
+		h.logger.WithError(err).Error("failed to create maintenance window")
+		c.JSON(maintenanceErrorStatus(err), gin.H{
+			"error":   "window_creation_failed",
+			"message": "Failed to create maintenance window",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("maintenance window created successfully", "window_id", window.ID, "kind", window.Kind)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Maintenance window created successfully",
+		"window":   window,
+		"duration": duration.String(),
+	})
+}
+
+// GetMaintenanceWindow handles GET /maintenance-windows/:id
+func (h *MaintenanceHandler) GetMaintenanceWindow(c *gin.Context) {
+	startTime := time.Now()
+	windowID := c.Param("id")
+
+	window, err := h.storage.Maintenance().Get(c.Request.Context(), windowID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get maintenance window")
+		c.JSON(maintenanceErrorStatus(err), gin.H{
+			"error":   "window_not_found",
+			"message": "Maintenance window not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Report when the period is next open to make schedules easy to check
+	response := gin.H{
+		"window":   window,
+		"duration": time.Since(startTime).String(),
+	}
+	now := time.Now()
+	if end, open, err := window.OpenAt(now); err == nil && open {
+		response["openUntil"] = end
+	} else if next, ok, err := window.NextOpening(now); err == nil && ok {
+		response["nextOpening"] = next
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateMaintenanceWindow handles PUT /maintenance-windows/:id
+func (h *MaintenanceHandler) UpdateMaintenanceWindow(c *gin.Context) {
+	startTime := time.Now()
+	windowID := c.Param("id")
+
+	var window types.MaintenanceWindow
+	if err := c.ShouldBindJSON(&window); err != nil {
+		h.logger.WithError(err).Error("failed to bind maintenance window JSON")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_window_format",
+			"message": "Failed to parse maintenance window JSON",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Ensure ID matches
+	window.ID = windowID
+
+	if err := h.storage.Maintenance().Update(c.Request.Context(), &window); err != nil {
+		h.logger.WithError(err).Error("failed to update maintenance window")
+		c.JSON(maintenanceErrorStatus(err), gin.H{
+			"error":   "window_update_failed",
+			"message": "Failed to update maintenance window",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("maintenance window updated successfully", "window_id", windowID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Maintenance window updated successfully",
+		"window":   window,
+		"duration": duration.String(),
+	})
+}
+
+// DeleteMaintenanceWindow handles DELETE /maintenance-windows/:id
+func (h *MaintenanceHandler) DeleteMaintenanceWindow(c *gin.Context) {
+	startTime := time.Now()
+	windowID := c.Param("id")
+
+	if err := h.storage.Maintenance().Delete(c.Request.Context(), windowID); err != nil {
+		h.logger.WithError(err).Error("failed to delete maintenance window")
+		c.JSON(maintenanceErrorStatus(err), gin.H{
+			"error":   "window_deletion_failed",
+			"message": "Failed to delete maintenance window",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("maintenance window deleted successfully", "window_id", windowID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Maintenance window deleted successfully",
+		"duration": duration.String(),
+	})
+}
+
+// ListMaintenanceWindows handles GET /maintenance-windows
+func (h *MaintenanceHandler) ListMaintenanceWindows(c *gin.Context) {
+	startTime := time.Now()
+
+	filters := &storage.MaintenanceFilters{}
+	if kind := c.Query("kind"); kind != "" {
+		k := types.MaintenanceWindowKind(kind)
+		filters.Kind = &k
+	}
+	if enabled := c.Query("enabled"); enabled != "" {
+		if e, err := strconv.ParseBool(enabled); err == nil {
+			filters.Enabled = &e
+		}
+	}
+
+	windows, err := h.storage.Maintenance().List(c.Request.Context(), filters)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to list maintenance windows")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "window_list_failed",
+			"message": "Failed to list maintenance windows",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("maintenance windows listed successfully", "count", len(windows))
+
+	c.JSON(http.StatusOK, gin.H{
+		"windows":  windows,
+		"count":    len(windows),
+		"duration": duration.String(),
+	})
+}
+
+// maintenanceErrorStatus maps maintenance window storage errors onto HTTP status codes
+func maintenanceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrStorageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrStorageInvalidData):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrStorageAlreadyExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/optimizer"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// OptimizationHandler handles optimization planning HTTP requests
+type OptimizationHandler struct {
+	storage       storage.StorageManager
+	preemption    optimizer.PreemptionPlanner
+	spot          optimizer.SpotAnalyzer
+	consolidation optimizer.ConsolidationPlanner
+	logger        types.Logger
+}
+
+// NewOptimizationHandler creates a new optimization handler
+func NewOptimizationHandler(storage storage.StorageManager, preemption optimizer.PreemptionPlanner, spot optimizer.SpotAnalyzer, consolidation optimizer.ConsolidationPlanner, logger types.Logger) *OptimizationHandler {
+	return &OptimizationHandler{
+		storage:       storage,
+		preemption:    preemption,
+		spot:          spot,
+		consolidation: consolidation,
+		logger:        logger,
+	}
+}
+
+// preemptionRequest is the body of a preemption planning request. The
+// candidate victims are the running workloads in storage.
+type preemptionRequest struct {
+	WorkloadID       string                `json:"workloadId" binding:"required"`
+	Nodes            []*evaluator.NodeInfo `json:"nodes" binding:"required,min=1"`
+	PriorityPolicyID string                `json:"priorityPolicyId,omitempty"`
+	PolicyID         string                `json:"policyId" binding:"required"`
+}
+
+// PlanPreemption handles POST /optimization/preemption
+func (h *OptimizationHandler) PlanPreemption(c *gin.Context) {
+	startTime := time.Now()
+	ctx := c.Request.Context()
+
+	var request preemptionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("failed to bind preemption request JSON")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request_format",
+			"message": "Failed to parse preemption request JSON",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	workload, err := h.storage.Workload().Get(ctx, request.WorkloadID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get workload for preemption", "workload_id", request.WorkloadID)
+		c.JSON(optimizationErrorStatus(err), gin.H{
+			"error":   "workload_not_found",
+			"message": "Failed to get workload " + request.WorkloadID,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	running, err := h.runningWorkloads(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to list running workloads")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "workload_list_failed",
+			"message": "Failed to list running workloads",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	planRequest := &optimizer.PreemptionRequest{
+		Workload: workload,
+		Nodes:    request.Nodes,
+		Running:  running,
+		PolicyID: request.PolicyID,
+	}
+
+	if request.PriorityPolicyID != "" {
+		policy, err := h.storage.Policy().Get(ctx, request.PriorityPolicyID)
+		if err != nil {
+			h.logger.WithError(err).Error("failed to get priority policy", "policy_id", request.PriorityPolicyID)
+			c.JSON(optimizationErrorStatus(err), gin.H{
+				"error":   "policy_not_found",
+				"message": "Failed to get priority policy " + request.PriorityPolicyID,
+				"details": err.Error(),
+			})
+			return
+		}
+		priorityPolicy, ok := policy.(*types.WorkloadPriorityPolicy)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_policy_type",
+				"message": "Policy " + request.PriorityPolicyID + " is not a workload priority policy",
+				"details": string(policy.GetType()),
+			})
+			return
+		}
+		planRequest.PriorityPolicy = priorityPolicy
+	}
+
+	plan, err := h.preemption.Plan(ctx, planRequest)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to plan preemption", "workload_id", request.WorkloadID)
+		c.JSON(optimizationErrorStatus(err), gin.H{
+			"error":   "preemption_planning_failed",
+			"message": "Failed to plan preemption",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("preemption planned",
+		"plan_id", plan.ID, "feasible", plan.Feasible, "victims", len(plan.Victims))
+
+	c.JSON(http.StatusOK, gin.H{
+		"plan":     plan,
+		"duration": duration.String(),
+	})
+}
+
+// spotRequest is the body of a spot eligibility request
+type spotRequest struct {
+	WorkloadID   string                   `json:"workloadId" binding:"required"`
+	PolicyID     string                   `json:"policyId" binding:"required"`
+	SpotClusters []*evaluator.ClusterInfo `json:"spotClusters,omitempty"`
+}
+
+// AnalyzeSpot handles POST /optimization/spot
+func (h *OptimizationHandler) AnalyzeSpot(c *gin.Context) {
+	startTime := time.Now()
+	ctx := c.Request.Context()
+
+	var request spotRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("failed to bind spot request JSON")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request_format",
+			"message": "Failed to parse spot request JSON",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	workload, err := h.storage.Workload().Get(ctx, request.WorkloadID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get workload for spot analysis", "workload_id", request.WorkloadID)
+		c.JSON(optimizationErrorStatus(err), gin.H{
+			"error":   "workload_not_found",
+			"message": "Failed to get workload " + request.WorkloadID,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	policy, err := h.storage.Policy().Get(ctx, request.PolicyID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get cost optimization policy", "policy_id", request.PolicyID)
+		c.JSON(optimizationErrorStatus(err), gin.H{
+			"error":   "policy_not_found",
+			"message": "Failed to get cost optimization policy " + request.PolicyID,
+			"details": err.Error(),
+		})
+		return
+	}
+	costPolicy, ok := policy.(*types.CostOptimizationPolicy)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_policy_type",
+			"message": "Policy " + request.PolicyID + " is not a cost optimization policy",
+			"details": string(policy.GetType()),
+		})
+		return
+	}
+
+	assessment, err := h.spot.Analyze(ctx, &optimizer.SpotRequest{
+		Workload:     workload,
+		Policy:       costPolicy,
+		SpotClusters: request.SpotClusters,
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("failed to analyze spot eligibility", "workload_id", request.WorkloadID)
+		c.JSON(optimizationErrorStatus(err), gin.H{
+			"error":   "spot_analysis_failed",
+			"message": "Failed to analyze spot eligibility",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("spot eligibility analyzed",
+		"workload_id", request.WorkloadID, "eligible", assessment.Eligible, "target_cluster", assessment.TargetCluster)
+
+	c.JSON(http.StatusOK, gin.H{
+		"assessment": assessment,
+		"duration":   duration.String(),
+	})
+}
+
+// consolidationRequest is the body of a consolidation planning request.
+// The workloads to move are the running workloads in storage.
+type consolidationRequest struct {
+	ClusterID            string                       `json:"clusterId" binding:"required"`
+	Nodes                []*evaluator.NodeInfo        `json:"nodes" binding:"required,min=1"`
+	UtilizationThreshold float64                      `json:"utilizationThreshold,omitempty"`
+	MaxNodesToDrain      int                          `json:"maxNodesToDrain,omitempty"`
+	DisruptionBudgets    []optimizer.DisruptionBudget `json:"disruptionBudgets,omitempty"`
+	PolicyID             string                       `json:"policyId" binding:"required"`
+}
+
+// PlanConsolidation handles POST /optimization/consolidation
+func (h *OptimizationHandler) PlanConsolidation(c *gin.Context) {
+	startTime := time.Now()
+	ctx := c.Request.Context()
+
+	var request consolidationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("failed to bind consolidation request JSON")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request_format",
+			"message": "Failed to parse consolidation request JSON",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	running, err := h.runningWorkloads(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to list running workloads")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "workload_list_failed",
+			"message": "Failed to list running workloads",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	plan, err := h.consolidation.Plan(ctx, &optimizer.ConsolidationRequest{
+		ClusterID:            request.ClusterID,
+		Nodes:                request.Nodes,
+		Workloads:            running,
+		UtilizationThreshold: request.UtilizationThreshold,
+		MaxNodesToDrain:      request.MaxNodesToDrain,
+		DisruptionBudgets:    request.DisruptionBudgets,
+		PolicyID:             request.PolicyID,
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("failed to plan consolidation", "cluster_id", request.ClusterID)
+		c.JSON(optimizationErrorStatus(err), gin.H{
+			"error":   "consolidation_planning_failed",
+			"message": "Failed to plan consolidation",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("consolidation planned",
+		"plan_id", plan.ID, "drained_nodes", len(plan.DrainedNodes), "moves", len(plan.Moves))
+
+	c.JSON(http.StatusOK, gin.H{
+		"plan":     plan,
+		"duration": duration.String(),
+	})
+}
+
+// runningWorkloads returns the running workloads optimization plans may
+// move or preempt
+func (h *OptimizationHandler) runningWorkloads(ctx context.Context) ([]*types.Workload, error) {
+	status := types.WorkloadStatusRunning
+	return h.storage.Workload().List(ctx, &storage.WorkloadFilters{Status: &status})
+}
+
+// optimizationErrorStatus maps optimization errors to HTTP status codes
+func optimizationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrWorkloadNotFound), errors.Is(err, types.ErrPolicyNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
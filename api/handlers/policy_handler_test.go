@@ -58,6 +58,21 @@ func (m *MockStorageManager) Evaluation() storage.EvaluationStore {
 	return args.Get(0).(storage.EvaluationStore)
 }
 
+func (m *MockStorageManager) Enforcement() storage.EnforcementStore {
+	args := m.Called()
+	return args.Get(0).(storage.EnforcementStore)
+}
+
+func (m *MockStorageManager) Maintenance() storage.MaintenanceStore {
+	args := m.Called()
+	return args.Get(0).(storage.MaintenanceStore)
+}
+
+func (m *MockStorageManager) ConditionState() storage.ConditionStateStore {
+	args := m.Called()
+	return args.Get(0).(storage.ConditionStateStore)
+}
+
 func (m *MockStorageManager) Health(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kcloud-opt/policy/internal/enforcer"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// RolloutHandler handles progressive rollout HTTP requests
+type RolloutHandler struct {
+	storage  storage.StorageManager
+	enforcer enforcer.PolicyEnforcer
+	logger   types.Logger
+}
+
+// NewRolloutHandler creates a new rollout handler
+func NewRolloutHandler(storage storage.StorageManager, policyEnforcer enforcer.PolicyEnforcer, logger types.Logger) *RolloutHandler {
+	return &RolloutHandler{
+		storage:  storage,
+		enforcer: policyEnforcer,
+		logger:   logger,
+	}
+}
+
+// rolloutRequest is the body of a start rollout request. Without a
+// strategy the configured one is used.
+type rolloutRequest struct {
+	DecisionIDs []string                  `json:"decisionIds" binding:"required,min=1"`
+	Strategy    *enforcer.RolloutStrategy `json:"strategy,omitempty"`
+}
+
+// StartRollout handles POST /rollouts
+func (h *RolloutHandler) StartRollout(c *gin.Context) {
+	startTime := time.Now()
+	ctx := c.Request.Context()
+
+	var request rolloutRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("failed to bind rollout request JSON")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request_format",
+			"message": "Failed to parse rollout request JSON",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	decisions := make([]*types.Decision, 0, len(request.DecisionIDs))
+	for _, decisionID := range request.DecisionIDs {
+		decision, err := h.storage.Decision().Get(ctx, decisionID)
+		if err != nil {
+			h.logger.WithError(err).Error("failed to get rollout decision", "decision_id", decisionID)
+			c.JSON(rolloutErrorStatus(err), gin.H{
+				"error":   "decision_not_found",
+				"message": "Failed to get decision " + decisionID,
+				"details": err.Error(),
+			})
+			return
+		}
+		decisions = append(decisions, decision)
+	}
+
+	rollout, err := h.enforcer.StartRollout(ctx, decisions, request.Strategy)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to start rollout")
+		c.JSON(rolloutErrorStatus(err), gin.H{
+			"error":   "rollout_start_failed",
+			"message": "Failed to start rollout",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("rollout started", "rollout_id", rollout.ID, "waves", len(rollout.Waves))
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "Rollout started",
+		"rollout":  rollout,
+		"duration": duration.String(),
+	})
+}
+
+// ListRollouts handles GET /rollouts
+func (h *RolloutHandler) ListRollouts(c *gin.Context) {
+	startTime := time.Now()
+
+	rollouts := h.enforcer.ListRollouts(c.Request.Context())
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("rollouts listed", "count", len(rollouts))
+
+	c.JSON(http.StatusOK, gin.H{
+		"rollouts": rollouts,
+		"count":    len(rollouts),
+		"duration": duration.String(),
+	})
+}
+
+// GetRollout handles GET /rollouts/:id
+func (h *RolloutHandler) GetRollout(c *gin.Context) {
+	startTime := time.Now()
+	rolloutID := c.Param("id")
+
+	rollout, err := h.enforcer.GetRollout(c.Request.Context(), rolloutID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get rollout", "rollout_id", rolloutID)
+		c.JSON(rolloutErrorStatus(err), gin.H{
+			"error":   "rollout_not_found",
+			"message": "Rollout not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("rollout retrieved", "rollout_id", rolloutID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"rollout":  rollout,
+		"duration": duration.String(),
+	})
+}
+
+// PauseRollout handles POST /rollouts/:id/pause
+func (h *RolloutHandler) PauseRollout(c *gin.Context) {
+	h.steerRollout(c, "paused", h.enforcer.PauseRollout)
+}
+
+// ResumeRollout handles POST /rollouts/:id/resume
+func (h *RolloutHandler) ResumeRollout(c *gin.Context) {
+	h.steerRollout(c, "resumed", h.enforcer.ResumeRollout)
+}
+
+// AbortRollout handles POST /rollouts/:id/abort
+func (h *RolloutHandler) AbortRollout(c *gin.Context) {
+	h.steerRollout(c, "aborted", h.enforcer.AbortRollout)
+}
+
+// steerRollout pauses, resumes or aborts a rollout
+func (h *RolloutHandler) steerRollout(c *gin.Context, verdict string, steer func(ctx context.Context, rolloutID string) (*enforcer.Rollout, error)) {
+	startTime := time.Now()
+	rolloutID := c.Param("id")
+
+	rollout, err := steer(c.Request.Context(), rolloutID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to steer rollout", "rollout_id", rolloutID, "verdict", verdict)
+		c.JSON(rolloutErrorStatus(err), gin.H{
+			"error":   "rollout_update_failed",
+			"message": "Failed to update rollout",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("rollout "+verdict, "rollout_id", rolloutID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Rollout " + verdict,
+		"rollout":  rollout,
+		"duration": duration.String(),
+	})
+}
+
+// rolloutErrorStatus maps rollout errors to HTTP status codes
+func rolloutErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrRolloutNotFound), errors.Is(err, types.ErrDecisionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, types.ErrInvalidRolloutState):
+		return http.StatusConflict
+	case errors.Is(err, types.ErrInvalidRolloutStrategy):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kcloud-opt/policy/internal/types"
+	"github.com/kcloud-opt/policy/internal/webhook"
+)
+
+// maxCallbackSize bounds the body of a webhook callback
+const maxCallbackSize = 1 << 20
+
+// WebhookHandler handles the callbacks of asynchronous webhook targets
+type WebhookHandler struct {
+	dispatcher webhook.Dispatcher
+	logger     types.Logger
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(dispatcher webhook.Dispatcher, logger types.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		dispatcher: dispatcher,
+		logger:     logger,
+	}
+}
+
+// Callback handles POST /webhooks/callbacks/:id
+func (h *WebhookHandler) Callback(c *gin.Context) {
+	startTime := time.Now()
+	eventID := c.Param("id")
+
+	if h.dispatcher == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "webhooks_disabled",
+			"message": "Webhook delivery is not configured",
+		})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCallbackSize))
+	if err != nil {
+		h.logger.WithError(err).Error("failed to read webhook callback", "event_id", eventID)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_callback",
+			"message": "Failed to read webhook callback",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.dispatcher.Complete(eventID, body, c.GetHeader(webhook.SignatureHeader)); err != nil {
+		h.logger.WithError(err).Error("failed to complete webhook callback", "event_id", eventID)
+		c.JSON(webhookErrorStatus(err), gin.H{
+			"error":   "callback_failed",
+			"message": "Failed to complete webhook callback",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("webhook callback completed", "event_id", eventID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Webhook callback completed",
+		"eventId":  eventID,
+		"duration": duration.String(),
+	})
+}
+
+// ListPending handles GET /webhooks/pending
+func (h *WebhookHandler) ListPending(c *gin.Context) {
+	startTime := time.Now()
+
+	pending := []string{}
+	if h.dispatcher != nil {
+		pending = h.dispatcher.Pending()
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithDuration(duration).Info("pending webhooks listed", "count", len(pending))
+
+	c.JSON(http.StatusOK, gin.H{
+		"pending":  pending,
+		"count":    len(pending),
+		"duration": duration.String(),
+	})
+}
+
+// webhookErrorStatus maps callback errors to HTTP status codes
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrWebhookCallbackNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, types.ErrInvalidSignature):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -6,21 +6,24 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kcloud-opt/policy/internal/optimizer"
 	"github.com/kcloud-opt/policy/internal/storage"
 	"github.com/kcloud-opt/policy/internal/types"
 )
 
 // WorkloadHandler handles workload-related HTTP requests
 type WorkloadHandler struct {
-	storage storage.StorageManager
-	logger  types.Logger
+	storage    storage.StorageManager
+	rightSizer optimizer.RightSizingAnalyzer
+	logger     types.Logger
 }
 
 // NewWorkloadHandler creates a new workload handler
-func NewWorkloadHandler(storage storage.StorageManager, logger types.Logger) *WorkloadHandler {
+func NewWorkloadHandler(storage storage.StorageManager, rightSizer optimizer.RightSizingAnalyzer, logger types.Logger) *WorkloadHandler {
 	return &WorkloadHandler{
-		storage: storage,
-		logger:  logger,
+		storage:    storage,
+		rightSizer: rightSizer,
+		logger:     logger,
 	}
 }
 
@@ -293,6 +296,80 @@ func (h *WorkloadHandler) GetWorkloadMetrics(c *gin.Context) {
 	})
 }
 
+// GetWorkloadRecommendations handles GET /workloads/:id/recommendations
+func (h *WorkloadHandler) GetWorkloadRecommendations(c *gin.Context) {
+	startTime := time.Now()
+	workloadID := c.Param("id")
+
+	options := &optimizer.RightSizingOptions{}
+
+	if window := c.Query("window"); window != "" {
+		w, err := time.ParseDuration(window)
+		if err != nil || w <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_window",
+				"message": "Invalid window, use a positive duration such as 168h",
+			})
+			return
+		}
+		options.Window = w
+	}
+	if percentile := c.Query("percentile"); percentile != "" {
+		p, err := strconv.ParseFloat(percentile, 64)
+		if err != nil || p <= 0 || p > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_percentile",
+				"message": "Invalid percentile, must be in (0, 100]",
+			})
+			return
+		}
+		options.Percentile = p
+	}
+	if headroom := c.Query("headroom"); headroom != "" {
+		hr, err := strconv.ParseFloat(headroom, 64)
+		if err != nil || hr < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_headroom",
+				"message": "Invalid headroom, must be a non-negative fraction",
+			})
+			return
+		}
+		options.Headroom = &hr
+	}
+
+	if _, err := h.storage.Workload().Get(c.Request.Context(), workloadID); err != nil {
+		h.logger.WithError(err).WithWorkload(workloadID, "").Error("failed to get workload")
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "workload_not_found",
+			"message": "Workload not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	report, err := h.rightSizer.Analyze(c.Request.Context(), workloadID, options)
+	if err != nil {
+		h.logger.WithError(err).WithWorkload(workloadID, "").Error("failed to analyze workload right-sizing")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "rightsizing_failed",
+			"message": "Failed to compute workload recommendations",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	h.logger.WithWorkload(workloadID, "").WithDuration(duration).Info("workload recommendations retrieved successfully",
+		"count", len(report.Recommendations))
+
+	c.JSON(http.StatusOK, gin.H{
+		"recommendations": report.Recommendations,
+		"report":          report,
+		"count":           len(report.Recommendations),
+		"duration":        duration.String(),
+	})
+}
+
 // GetWorkloadHistory handles GET /workloads/:id/history
 func (h *WorkloadHandler) GetWorkloadHistory(c *gin.Context) {
 	startTime := time.Now()
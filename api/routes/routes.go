@@ -118,6 +118,7 @@ func (r *Router) setupAPIRoutes(router *gin.Engine) {
 			workloads.PUT("/:id", r.handlers.Workload.UpdateWorkload)
 			workloads.DELETE("/:id", r.handlers.Workload.DeleteWorkload)
 			workloads.GET("/:id/metrics", r.handlers.Workload.GetWorkloadMetrics)
+			workloads.GET("/:id/recommendations", r.handlers.Workload.GetWorkloadRecommendations)
 			workloads.GET("/:id/history", r.handlers.Workload.GetWorkloadHistory)
 		}
 
@@ -132,6 +133,50 @@ func (r *Router) setupAPIRoutes(router *gin.Engine) {
 			evaluations.GET("/:id", r.handlers.Evaluation.GetEvaluation)
 		}
 
+		decisions := v1.Group("/decisions")
+		{
+			decisions.GET("", r.handlers.Decision.ListDecisions)
+			decisions.GET("/:id", r.handlers.Decision.GetDecision)
+			decisions.POST("/:id/submit", r.handlers.Decision.SubmitDecision)
+			decisions.POST("/:id/approve", r.handlers.Decision.ApproveDecision)
+			decisions.POST("/:id/reject", r.handlers.Decision.RejectDecision)
+			decisions.POST("/:id/rollback", r.handlers.Decision.RollbackDecision)
+			decisions.GET("/:id/plan", r.handlers.Decision.GetDecisionPlan)
+			decisions.GET("/:id/history", r.handlers.Decision.GetDecisionHistory)
+		}
+
+		maintenance := v1.Group("/maintenance-windows")
+		{
+			maintenance.GET("", r.handlers.Maintenance.ListMaintenanceWindows)
+			maintenance.POST("", r.handlers.Maintenance.CreateMaintenanceWindow)
+			maintenance.GET("/:id", r.handlers.Maintenance.GetMaintenanceWindow)
+			maintenance.PUT("/:id", r.handlers.Maintenance.UpdateMaintenanceWindow)
+			maintenance.DELETE("/:id", r.handlers.Maintenance.DeleteMaintenanceWindow)
+		}
+
+		rollouts := v1.Group("/rollouts")
+		{
+			rollouts.GET("", r.handlers.Rollout.ListRollouts)
+			rollouts.POST("", r.handlers.Rollout.StartRollout)
+			rollouts.GET("/:id", r.handlers.Rollout.GetRollout)
+			rollouts.POST("/:id/pause", r.handlers.Rollout.PauseRollout)
+			rollouts.POST("/:id/resume", r.handlers.Rollout.ResumeRollout)
+			rollouts.POST("/:id/abort", r.handlers.Rollout.AbortRollout)
+		}
+
+		webhooks := v1.Group("/webhooks")
+		{
+			webhooks.GET("/pending", r.handlers.Webhook.ListPending)
+			webhooks.POST("/callbacks/:id", r.handlers.Webhook.Callback)
+		}
+
+		optimization := v1.Group("/optimization")
+		{
+			optimization.POST("/preemption", r.handlers.Optimization.PlanPreemption)
+			optimization.POST("/spot", r.handlers.Optimization.AnalyzeSpot)
+			optimization.POST("/consolidation", r.handlers.Optimization.PlanConsolidation)
+		}
+
 		automation := v1.Group("/automation")
 		{
 			rules := automation.Group("/rules")
@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/kcloud-opt/policy/internal/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// maintenanceCmd represents the maintenance command
+var maintenanceCmd = &cobra.Command{
+	Use:   "maintenance",
+	Short: "Manage maintenance windows and change freezes",
+	Long: `Create, read, update, and delete maintenance windows and change freezes.
+
+Disruptive decisions covered by a maintenance window are only enforced while
+the window is open, and change freezes defer or block them.`,
+}
+
+var maintenanceCreateCmd = &cobra.Command{
+	Use:   "create [file]",
+	Short: "Create a maintenance window or change freeze",
+	Long:  `Create a maintenance window or change freeze from a YAML or JSON file.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		windowData := readMaintenanceWindow(args[0])
+
+		url := fmt.Sprintf("http://%s:%d/api/v1/maintenance-windows", serverHost, serverPort)
+		resp, err := http.Post(url, "application/json", bytes.NewReader(windowData))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating maintenance window: %v\n", err)
+			os.Exit(1)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusCreated {
+			body, _ := io.ReadAll(resp.Body)
+			fmt.Fprintf(os.Stderr, "Error creating maintenance window: %s\n", string(body))
+			os.Exit(1)
+		}
+
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if verbose {
+			jsonData, _ := json.MarshalIndent(result, "", "  ")
+			fmt.Println(string(jsonData))
+		} else {
+			window, _ := result["window"].(map[string]interface{})
+			if id, ok := window["id"].(string); ok {
+				fmt.Printf("Maintenance window created successfully with ID: %s\n", id)
+			} else {
+				fmt.Println("Maintenance window created successfully")
+			}
+		}
+	},
+}
+
+var maintenanceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List maintenance windows and change freezes",
+	Long:  `List maintenance windows and change freezes, optionally filtered by kind.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		url := fmt.Sprintf("http://%s:%d/api/v1/maintenance-windows", serverHost, serverPort)
+		if kind, _ := cmd.Flags().GetString("kind"); kind != "" {
+			url += "?kind=" + kind
+		}
+		resp, err := http.Get(url)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing maintenance windows: %v\n", err)
+			os.Exit(1)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			fmt.Fprintf(os.Stderr, "Error listing maintenance windows: %s\n", string(body))
+			os.Exit(1)
+		}
+
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		jsonData, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(jsonData))
+	},
+}
+
+var maintenanceGetCmd = &cobra.Command{
+	Use:   "get <window-id>",
+	Short: "Get a maintenance window or change freeze",
+	Long:  `Get details of a maintenance window or change freeze, including when it next opens.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		windowID := args[0]
+
+		url := fmt.Sprintf("http://%s:%d/api/v1/maintenance-windows/%s", serverHost, serverPort, windowID)
+		resp, err := http.Get(url)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting maintenance window: %v\n", err)
+			os.Exit(1)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			fmt.Fprintf(os.Stderr, "Error getting maintenance window: %s\n", string(body))
+			os.Exit(1)
+		}
+
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		jsonData, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(jsonData))
+	},
+}
+
+var maintenanceUpdateCmd = &cobra.Command{
+	Use:   "update <window-id> [file]",
+	Short: "Update a maintenance window or change freeze",
+	Long:  `Update an existing maintenance window or change freeze from a YAML or JSON file.`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		windowID := args[0]
+		windowData := readMaintenanceWindow(args[1])
+
+		url := fmt.Sprintf("http://%s:%d/api/v1/maintenance-windows/%s", serverHost, serverPort, windowID)
+		req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(windowData))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
+			os.Exit(1)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		client := &http.Client{}
+		resp, err := client.Do(req)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error updating maintenance window: %v\n", err)
+			os.Exit(1)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			fmt.Fprintf(os.Stderr, "Error updating maintenance window: %s\n", string(body))
+			os.Exit(1)
+		}
+
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if verbose {
+			jsonData, _ := json.MarshalIndent(result, "", "  ")
+			fmt.Println(string(jsonData))
+		} else {
+			fmt.Printf("Maintenance window %s updated successfully\n", windowID)
+		}
+	},
+}
+
+var maintenanceDeleteCmd = &cobra.Command{
+	Use:   "delete <window-id>",
+	Short: "Delete a maintenance window or change freeze",
+	Long:  `Delete a maintenance window or change freeze by its ID.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		windowID := args[0]
+
+		url := fmt.Sprintf("http://%s:%d/api/v1/maintenance-windows/%s", serverHost, serverPort, windowID)
+		req, err := http.NewRequest(http.MethodDelete, url, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
+			os.Exit(1)
+		}
+
+		client := &http.Client{}
+		resp, err := client.Do(req)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error deleting maintenance window: %v\n", err)
+			os.Exit(1)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			fmt.Fprintf(os.Stderr, "Error deleting maintenance window: %s\n", string(body))
+			os.Exit(1)
+		}
+
+		fmt.Printf("Maintenance window %s deleted successfully\n", windowID)
+	},
+}
+
+// readMaintenanceWindow reads a YAML or JSON maintenance window file and
+// returns it as JSON for the API
+func readMaintenanceWindow(filePath string) []byte {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// JSON is valid YAML, so both formats decode the same way
+	var window types.MaintenanceWindow
+	if err := yaml.Unmarshal(data, &window); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing maintenance window: %v\n", err)
+		os.Exit(1)
+	}
+
+	windowData, err := json.Marshal(window)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding maintenance window: %v\n", err)
+		os.Exit(1)
+	}
+	return windowData
+}
+
+func init() {
+	rootCmd.AddCommand(maintenanceCmd)
+
+	maintenanceCmd.AddCommand(maintenanceCreateCmd)
+	maintenanceCmd.AddCommand(maintenanceListCmd)
+	maintenanceCmd.AddCommand(maintenanceGetCmd)
+	maintenanceCmd.AddCommand(maintenanceUpdateCmd)
+	maintenanceCmd.AddCommand(maintenanceDeleteCmd)
+
+	maintenanceListCmd.Flags().String("kind", "", "filter by kind (window or freeze)")
+}
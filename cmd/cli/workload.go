@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 
@@ -205,6 +206,76 @@ var workloadDeleteCmd = &cobra.Command{
 	},
 }
 
+var (
+	rightsizeWindow     string
+	rightsizePercentile float64
+	rightsizeHeadroom   float64
+)
+
+var workloadRightsizeCmd = &cobra.Command{
+	Use:   "rightsize <workload-id>",
+	Short: "Recommend resource requests for a workload",
+	Long:  `Recommend CPU, memory and accelerator requests for a workload from its observed usage.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		workloadID := args[0]
+
+		query := url.Values{}
+		if rightsizeWindow != "" {
+			query.Set("window", rightsizeWindow)
+		}
+		if rightsizePercentile > 0 {
+			query.Set("percentile", fmt.Sprintf("%g", rightsizePercentile))
+		}
+		if cmd.Flags().Changed("headroom") {
+			query.Set("headroom", fmt.Sprintf("%g", rightsizeHeadroom))
+		}
+
+		requestURL := fmt.Sprintf("http://%s:%d/api/v1/workloads/%s/recommendations?%s", serverHost, serverPort, workloadID, query.Encode())
+		resp, err := http.Get(requestURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting recommendations: %v\n", err)
+			os.Exit(1)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			fmt.Fprintf(os.Stderr, "Error getting recommendations: %s\n", string(body))
+			os.Exit(1)
+		}
+
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if verbose {
+			jsonData, _ := json.MarshalIndent(result, "", "  ")
+			fmt.Println(string(jsonData))
+			return
+		}
+
+		report, _ := result["report"].(map[string]interface{})
+		if sufficient, _ := report["sufficient"].(bool); !sufficient {
+			fmt.Printf("Not enough metrics to right-size workload %s (%v samples)\n", workloadID, report["samples"])
+			return
+		}
+
+		recommendations, _ := result["recommendations"].([]interface{})
+		if len(recommendations) == 0 {
+			fmt.Printf("Workload %s is already right-sized\n", workloadID)
+			return
+		}
+		for _, item := range recommendations {
+			if recommendation, ok := item.(map[string]interface{}); ok {
+				fmt.Printf("[%v] %v\n", recommendation["priority"], recommendation["message"])
+			}
+		}
+		fmt.Printf("Estimated savings: %.2f %v/hour (%.2f %v/month)\n",
+			report["estimatedSavingsPerHour"], report["currency"],
+			report["estimatedMonthlySavings"], report["currency"])
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(workloadCmd)
 
@@ -213,4 +284,9 @@ func init() {
 	workloadCmd.AddCommand(workloadGetCmd)
 	workloadCmd.AddCommand(workloadUpdateCmd)
 	workloadCmd.AddCommand(workloadDeleteCmd)
+	workloadCmd.AddCommand(workloadRightsizeCmd)
+
+	workloadRightsizeCmd.Flags().StringVar(&rightsizeWindow, "window", "", "observation window, e.g. 168h (server default if unset)")
+	workloadRightsizeCmd.Flags().Float64Var(&rightsizePercentile, "percentile", 0, "usage percentile to size for (server default if unset)")
+	workloadRightsizeCmd.Flags().Float64Var(&rightsizeHeadroom, "headroom", 0, "headroom fraction added on top of the percentile (server default if unset)")
 }
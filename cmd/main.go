@@ -14,14 +14,21 @@ import (
 
 	"github.com/kcloud-opt/policy/api/handlers"
 	"github.com/kcloud-opt/policy/api/routes"
+	"github.com/kcloud-opt/policy/internal/actions"
 	"github.com/kcloud-opt/policy/internal/automation"
 	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/decisions"
+	"github.com/kcloud-opt/policy/internal/enforcer"
+	"github.com/kcloud-opt/policy/internal/enforcer/kubernetes"
 	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/events"
 	"github.com/kcloud-opt/policy/internal/logger"
 	"github.com/kcloud-opt/policy/internal/metrics"
+	"github.com/kcloud-opt/policy/internal/optimizer"
 	"github.com/kcloud-opt/policy/internal/storage/memory"
 	"github.com/kcloud-opt/policy/internal/types"
 	"github.com/kcloud-opt/policy/internal/validator"
+	"github.com/kcloud-opt/policy/internal/webhook"
 )
 
 var (
@@ -133,7 +140,10 @@ func main() {
 
 	var appLogger types.Logger = &LoggerWrapper{loggerInstance}
 
-	storageManager := memory.NewStorageManager()
+	// Writes through the storage manager, the API handlers' included,
+	// publish workload, policy and decision events on the bus
+	eventBus := events.NewBus(appLogger)
+	storageManager := events.NewPublishingStorage(memory.NewStorageManager(), eventBus)
 	loggerInstance.Info("Storage manager initialized")
 
 	metricsInstance := metrics.NewMetrics(appLogger)
@@ -155,21 +165,90 @@ func main() {
 	evaluationEngine := evaluator.NewEvaluationEngine(policyEvaluator, conflictResolver, storageManager, appLogger)
 	loggerInstance.Info("Evaluation engine initialized")
 
+	// Enforcement and automation share one action registry, so executors
+	// registered here serve both
+	actionRegistry := actions.NewRegistry(appLogger)
+	for _, executor := range enforcer.NewDefaultExecutors(storageManager, appLogger) {
+		if err := actionRegistry.Register(executor); err != nil {
+			log.Fatalf("Failed to register action executor: %v", err)
+		}
+	}
+
+	// Kubernetes executors replace the storage-only defaults for the
+	// action types they apply to the cluster
+	if cfg.Kubernetes.Enabled {
+		clientset, err := kubernetes.NewClientset(cfg.Kubernetes)
+		if err != nil {
+			log.Fatalf("Failed to create Kubernetes client: %v", err)
+		}
+		for _, executor := range kubernetes.NewExecutors(clientset, storageManager, cfg.Kubernetes, appLogger) {
+			if err := actionRegistry.Register(executor); err != nil {
+				log.Fatalf("Failed to register Kubernetes executor: %v", err)
+			}
+		}
+		loggerInstance.Info("Kubernetes executors registered")
+	}
+
+	webhookDispatcher := webhook.NewDispatcher(cfg.Webhooks, nil, appLogger)
+	if err := webhookDispatcher.Health(context.Background()); err != nil {
+		loggerInstance.WithError(err).Warn("Invalid webhook configuration")
+	}
+	// Registered last so webhook targets take over the action types they list
+	if err := actionRegistry.Register(webhook.NewExecutor(webhookDispatcher, storageManager, appLogger)); err != nil {
+		log.Fatalf("Failed to register webhook executor: %v", err)
+	}
+	loggerInstance.Info("Action registry initialized", zap.Strings("action_types", actionRegistry.ActionTypes()))
+
+	conditionEvaluator := automation.NewConditionEvaluator(storageManager.ConditionState(), ruleEngine, appLogger)
+	ruleExecutor := automation.NewRuleExecutor(conditionEvaluator, actionRegistry, appLogger)
+
 	var automationEngine automation.AutomationEngine
-	if ae := automation.NewAutomationEngine(storageManager, nil, nil, nil, appLogger); ae != nil {
+	if ae := automation.NewAutomationEngine(storageManager, ruleExecutor, conditionEvaluator, automation.NewScheduler(appLogger), eventBus, appLogger); ae != nil {
 		if err := ae.Initialize(context.Background()); err != nil {
 			loggerInstance.WithError(err).Warn("Failed to initialize automation engine - continuing without automation")
 			automationEngine = nil
 		} else {
 			automationEngine = ae
 			loggerInstance.Info("Automation engine initialized")
+			if err := ae.Start(context.Background()); err != nil {
+				loggerInstance.WithError(err).Warn("Failed to start automation engine")
+			}
 		}
 	} else {
 		loggerInstance.Warn("Automation engine not available - continuing without automation")
 		automationEngine = nil
 	}
 
-	handlersInstance := handlers.NewHandlers(storageManager, evaluationEngine, automationEngine, appLogger)
+	decisionLifecycle := decisions.NewLifecycle(storageManager, cfg.Decisions, appLogger)
+	go decisionLifecycle.Start(context.Background())
+	loggerInstance.Info("Decision lifecycle initialized")
+
+	costModel := optimizer.NewCostModel(cfg.Optimizer.Cost)
+	rightSizer := optimizer.NewRightSizingAnalyzer(storageManager, costModel, cfg.Optimizer, appLogger)
+	preemptionPlanner := optimizer.NewPreemptionPlanner(decisionLifecycle, appLogger)
+	spotAnalyzer := optimizer.NewSpotAnalyzer(storageManager, decisionLifecycle, costModel, cfg.Optimizer.Spot, appLogger)
+	consolidationPlanner := optimizer.NewConsolidationPlanner(decisionLifecycle, appLogger)
+	loggerInstance.Info("Optimizer initialized")
+
+	approvalManager := enforcer.NewApprovalManager(storageManager, decisionLifecycle, cfg.Approval, appLogger)
+	decisionLifecycle.SetApprover(func(ctx context.Context, decision *types.Decision) error {
+		_, err := approvalManager.Submit(ctx, decision)
+		return err
+	})
+	loggerInstance.Info("Approval manager initialized")
+
+	enforcementEngine := enforcer.NewEnforcementEngine(actionRegistry, appLogger)
+	safetyLimiter := enforcer.NewSafetyLimiter(storageManager, cfg.Enforcement.Safety, appLogger)
+	maintenanceCalendar := enforcer.NewMaintenanceCalendar(storageManager, appLogger)
+	lockManager := enforcer.NewLockManager(cfg.Enforcement.Locks, appLogger)
+	policyEnforcer := enforcer.NewPolicyEnforcer(enforcementEngine, storageManager, decisionLifecycle, safetyLimiter, maintenanceCalendar, lockManager, ruleEngine, cfg.Enforcement, appLogger)
+	if recovered, err := policyEnforcer.Recover(context.Background()); err != nil {
+		loggerInstance.WithError(err).Warn("Failed to recover interrupted enforcements")
+	} else {
+		loggerInstance.Info("Policy enforcer initialized", zap.Int("recovered", len(recovered)))
+	}
+
+	handlersInstance := handlers.NewHandlers(storageManager, evaluationEngine, automationEngine, rightSizer, preemptionPlanner, spotAnalyzer, consolidationPlanner, approvalManager, policyEnforcer, safetyLimiter, lockManager, webhookDispatcher, appLogger)
 	loggerInstance.Info("Handlers initialized")
 
 	router := routes.NewRouter(handlersInstance, cfg, loggerInstance)
@@ -207,6 +286,7 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		loggerInstance.Error("Server forced to shutdown")
 	}
+	eventBus.Close()
 
 	loggerInstance.Info("Server exited")
 }
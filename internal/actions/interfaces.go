@@ -0,0 +1,130 @@
+package actions
+
+import (
+	"context"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// Executor executes the actions of the types it can handle
+type Executor interface {
+	// CanExecute checks if this executor can handle the given action type
+	CanExecute(actionType string) bool
+
+	// Execute executes the action
+	Execute(ctx context.Context, action *Action) (*Result, error)
+
+	// Validate validates the action before execution
+	Validate(action *Action) error
+
+	// Health checks the health of the executor
+	Health(ctx context.Context) error
+}
+
+// Observer is implemented by executors that can read back from the system
+// they change whether the target of an action is ready
+type Observer interface {
+	// Observe reports the observed state of the target of an action. The
+	// state includes "ready", true once the change the action made is live.
+	Observe(ctx context.Context, action *Action) (map[string]interface{}, error)
+}
+
+// Registry dispatches actions to the executors registered for their type.
+// Policy enforcement and automation rules share one registry, so an
+// executor registered once serves both.
+type Registry interface {
+	// Register registers an executor for every action type it can handle,
+	// replacing executors registered before it for those types
+	Register(executor Executor) error
+
+	// Unregister removes the executor of an action type
+	Unregister(actionType string) error
+
+	// Executor returns the executor registered for an action type
+	Executor(actionType string) (Executor, bool)
+
+	// Observer returns the executor registered for an action type if it is
+	// an observer
+	Observer(actionType string) (Observer, bool)
+
+	// ActionTypes returns the action types that have an executor
+	ActionTypes() []string
+
+	// Validate checks an action with the executor that would run it,
+	// without executing it
+	Validate(action *Action) error
+
+	// Execute validates and executes an action within its timeout, retrying
+	// it according to its retry policy
+	Execute(ctx context.Context, action *Action) (*Result, error)
+
+	// Health checks the health of every registered executor
+	Health(ctx context.Context) error
+}
+
+// Action represents an action to be executed
+type Action = types.EnforcementAction
+
+// Result represents the result of action execution
+type Result struct {
+	ActionType string                 `json:"actionType"`
+	Success    bool                   `json:"success"`
+	Message    string                 `json:"message"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	Duration   time.Duration          `json:"duration"`
+	Timestamp  time.Time              `json:"timestamp"`
+	Error      string                 `json:"error,omitempty"`
+	RetryCount int                    `json:"retryCount"`
+}
+
+// RetryPolicy defines retry behavior for actions
+type RetryPolicy = types.RetryPolicy
+
+// BackoffType represents the backoff strategy
+type BackoffType = types.BackoffType
+
+const (
+	BackoffTypeLinear      = types.BackoffTypeLinear
+	BackoffTypeExponential = types.BackoffTypeExponential
+	BackoffTypeFixed       = types.BackoffTypeFixed
+)
+
+// DefaultTimeout bounds actions that do not set a timeout
+const DefaultTimeout = 5 * time.Minute
+
+// Common action types
+const (
+	ActionTypeSchedule   = "schedule"
+	ActionTypeReschedule = "reschedule"
+	ActionTypeMigrate    = "migrate"
+	ActionTypeScale      = "scale"
+	ActionTypeTerminate  = "terminate"
+	ActionTypeSuspend    = "suspend"
+	ActionTypeResume     = "resume"
+	ActionTypeNotify     = "notify"
+	ActionTypeUpdate     = "update"
+	ActionTypeDelete     = "delete"
+	ActionTypeCreate     = "create"
+	ActionTypeOptimize   = "optimize"
+
+	// ActionTypeWebhook delivers an action to the webhook target it names
+	ActionTypeWebhook = "webhook"
+)
+
+// actionTypes lists the action types executors are registered for
+var actionTypes = []string{
+	ActionTypeSchedule,
+	ActionTypeReschedule,
+	ActionTypeMigrate,
+	ActionTypeScale,
+	ActionTypeTerminate,
+	ActionTypeSuspend,
+	ActionTypeResume,
+	ActionTypeNotify,
+	ActionTypeUpdate,
+	ActionTypeDelete,
+	ActionTypeCreate,
+	ActionTypeOptimize,
+	ActionTypeWebhook,
+}
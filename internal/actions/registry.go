@@ -0,0 +1,205 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// registry implements Registry interface
+type registry struct {
+	executors map[string]Executor
+	mu        sync.RWMutex
+	logger    types.Logger
+}
+
+// NewRegistry creates a new action registry
+func NewRegistry(logger types.Logger) Registry {
+	return &registry{
+		executors: make(map[string]Executor),
+		logger:    logger,
+	}
+}
+
+// Register registers an executor for the action types it can handle
+func (r *registry) Register(executor Executor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	registered := false
+	for _, actionType := range actionTypes {
+		if executor.CanExecute(actionType) {
+			r.executors[actionType] = executor
+			registered = true
+			r.logger.Info("registered action executor", "action_type", actionType)
+		}
+	}
+	if !registered {
+		return fmt.Errorf("executor handles no known action type")
+	}
+
+	return nil
+}
+
+// Unregister removes the executor of an action type
+func (r *registry) Unregister(actionType string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.executors[actionType]; !exists {
+		return fmt.Errorf("executor for action type %s not found", actionType)
+	}
+
+	delete(r.executors, actionType)
+	r.logger.Info("unregistered action executor", "action_type", actionType)
+
+	return nil
+}
+
+// Executor returns the executor registered for an action type
+func (r *registry) Executor(actionType string) (Executor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	executor, exists := r.executors[actionType]
+	return executor, exists
+}
+
+// Observer returns the executor registered for an action type if it is an
+// observer
+func (r *registry) Observer(actionType string) (Observer, bool) {
+	executor, exists := r.Executor(actionType)
+	if !exists {
+		return nil, false
+	}
+	observer, ok := executor.(Observer)
+	return observer, ok
+}
+
+// ActionTypes returns the action types that have an executor, sorted
+func (r *registry) ActionTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	registered := make([]string, 0, len(r.executors))
+	for actionType := range r.executors {
+		registered = append(registered, actionType)
+	}
+	sort.Strings(registered)
+	return registered
+}
+
+// Validate checks an action with the executor that would run it
+func (r *registry) Validate(action *Action) error {
+	executor, exists := r.Executor(action.Type)
+	if !exists {
+		return fmt.Errorf("executor not found for action type %s", action.Type)
+	}
+	return executor.Validate(action)
+}
+
+// Execute validates and executes an action
+func (r *registry) Execute(ctx context.Context, action *Action) (*Result, error) {
+	startTime := time.Now()
+
+	executor, exists := r.Executor(action.Type)
+	if !exists {
+		return FailedResult(action, startTime, fmt.Sprintf("No executor found for action type: %s", action.Type),
+			fmt.Sprintf("executor not found for action type %s", action.Type), nil), nil
+	}
+
+	// Validate action
+	if err := executor.Validate(action); err != nil {
+		return FailedResult(action, startTime, fmt.Sprintf("Action validation failed: %v", err), err.Error(), nil), nil
+	}
+
+	// Set timeout if not specified
+	if action.Timeout == 0 {
+		action.Timeout = DefaultTimeout
+	}
+
+	// A cancelled or timed out caller starts nothing new
+	if err := ctx.Err(); err != nil {
+		return FailedResult(action, startTime, fmt.Sprintf("Action not started: %v", err), err.Error(), nil),
+			fmt.Errorf("action %s not started: %w", action.Type, err)
+	}
+
+	// Create context with timeout
+	actionCtx, cancel := context.WithTimeout(ctx, action.Timeout)
+	defer cancel()
+
+	// Execute with retry if retry policy is specified
+	var result *Result
+	var err error
+
+	if action.RetryPolicy != nil {
+		result, err = executeWithRetry(actionCtx, executor, action, r.logger)
+	} else {
+		result, err = executor.Execute(actionCtx, action)
+	}
+
+	// Executors stop early when their context ends; report why instead of
+	// whatever partial result they returned
+	if ctxErr := actionCtx.Err(); ctxErr != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("action %s exceeded its %s timeout: %w", action.Type, action.Timeout, ctxErr)
+		} else {
+			err = fmt.Errorf("action %s interrupted: %w", action.Type, ctx.Err())
+		}
+		r.logger.Warn("action did not finish",
+			"action_type", action.Type,
+			"target", action.Target,
+			"error", err.Error())
+		return FailedResult(action, startTime, err.Error(), err.Error(), nil), err
+	}
+
+	if err != nil || result == nil {
+		if err == nil {
+			err = fmt.Errorf("executor returned no result")
+		}
+		result = FailedResult(action, startTime, fmt.Sprintf("Action execution failed: %v", err), err.Error(), nil)
+	}
+
+	// Ensure duration is set
+	result.Duration = time.Since(startTime)
+
+	r.logger.Info("executed action",
+		"action_type", action.Type,
+		"target", action.Target,
+		"success", result.Success,
+		"duration", result.Duration,
+		"retry_count", result.RetryCount)
+
+	return result, nil
+}
+
+// Health checks the health of every registered executor
+func (r *registry) Health(ctx context.Context) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for actionType, executor := range r.executors {
+		if err := executor.Health(ctx); err != nil {
+			return fmt.Errorf("executor health check failed for action type %s: %w", actionType, err)
+		}
+	}
+
+	return nil
+}
+
+// FailedResult builds the result of an action that did not succeed
+func FailedResult(action *Action, startTime time.Time, message, errMessage string, data map[string]interface{}) *Result {
+	return &Result{
+		ActionType: action.Type,
+		Success:    false,
+		Message:    message,
+		Data:       data,
+		Duration:   time.Since(startTime),
+		Timestamp:  time.Now(),
+		Error:      errMessage,
+	}
+}
@@ -0,0 +1,163 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// testLogger discards log output
+type testLogger struct{}
+
+func (l testLogger) Info(msg string, fields ...interface{})                    {}
+func (l testLogger) Warn(msg string, fields ...interface{})                    {}
+func (l testLogger) Error(msg string, fields ...interface{})                   {}
+func (l testLogger) Debug(msg string, fields ...interface{})                   {}
+func (l testLogger) Fatal(msg string, fields ...interface{})                   {}
+func (l testLogger) WithError(err error) types.Logger                          { return l }
+func (l testLogger) WithDuration(duration time.Duration) types.Logger          { return l }
+func (l testLogger) WithPolicy(policyID, policyName string) types.Logger       { return l }
+func (l testLogger) WithWorkload(workloadID, workloadType string) types.Logger { return l }
+func (l testLogger) WithEvaluation(evaluationID string) types.Logger           { return l }
+
+// stubExecutor fails its first failures calls, then succeeds
+type stubExecutor struct {
+	actionTypes []string
+	failures    int
+	calls       int
+	block       bool
+}
+
+func (s *stubExecutor) CanExecute(actionType string) bool {
+	for _, supported := range s.actionTypes {
+		if supported == actionType {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *stubExecutor) Execute(ctx context.Context, action *Action) (*Result, error) {
+	s.calls++
+	if s.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if s.calls <= s.failures {
+		return &Result{ActionType: action.Type, Success: false, Error: "not yet"}, nil
+	}
+	return &Result{ActionType: action.Type, Success: true, Message: "done"}, nil
+}
+
+func (s *stubExecutor) Validate(action *Action) error {
+	if action.Target == "" {
+		return errors.New("action target cannot be empty")
+	}
+	return nil
+}
+
+func (s *stubExecutor) Health(ctx context.Context) error { return nil }
+
+func TestRegistry_RegisterReplacesByActionType(t *testing.T) {
+	registry := NewRegistry(testLogger{})
+	first := &stubExecutor{actionTypes: []string{ActionTypeScale, ActionTypeNotify}}
+	second := &stubExecutor{actionTypes: []string{ActionTypeScale}}
+
+	require.NoError(t, registry.Register(first))
+	require.NoError(t, registry.Register(second))
+	assert.Error(t, registry.Register(&stubExecutor{actionTypes: []string{"unknown"}}))
+
+	executor, ok := registry.Executor(ActionTypeScale)
+	require.True(t, ok)
+	assert.Same(t, second, executor)
+	executor, ok = registry.Executor(ActionTypeNotify)
+	require.True(t, ok)
+	assert.Same(t, first, executor)
+	assert.Equal(t, []string{ActionTypeNotify, ActionTypeScale}, registry.ActionTypes())
+
+	require.NoError(t, registry.Unregister(ActionTypeNotify))
+	assert.Error(t, registry.Unregister(ActionTypeNotify))
+}
+
+// observingExecutor is a stub executor that can observe its actions
+type observingExecutor struct {
+	stubExecutor
+}
+
+func (o *observingExecutor) Observe(ctx context.Context, action *Action) (map[string]interface{}, error) {
+	return map[string]interface{}{"ready": true}, nil
+}
+
+func TestRegistry_Observer(t *testing.T) {
+	registry := NewRegistry(testLogger{})
+	observing := &observingExecutor{stubExecutor{actionTypes: []string{ActionTypeResume}}}
+	require.NoError(t, registry.Register(observing))
+	require.NoError(t, registry.Register(&stubExecutor{actionTypes: []string{ActionTypeScale}}))
+
+	observer, ok := registry.Observer(ActionTypeResume)
+	require.True(t, ok)
+	assert.Same(t, observing, observer)
+	_, ok = registry.Observer(ActionTypeScale)
+	assert.False(t, ok)
+	_, ok = registry.Observer(ActionTypeNotify)
+	assert.False(t, ok)
+}
+
+func TestRegistry_ExecuteRetriesWithBackoff(t *testing.T) {
+	registry := NewRegistry(testLogger{})
+	executor := &stubExecutor{actionTypes: []string{ActionTypeScale}, failures: 2}
+	require.NoError(t, registry.Register(executor))
+
+	result, err := registry.Execute(context.Background(), &Action{
+		Type:        ActionTypeScale,
+		Target:      "wl-1",
+		RetryPolicy: &RetryPolicy{MaxRetries: 3, Interval: time.Millisecond, Backoff: BackoffTypeExponential},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, 2, result.RetryCount)
+	assert.Equal(t, 3, executor.calls)
+}
+
+func TestRegistry_ExecuteFailures(t *testing.T) {
+	registry := NewRegistry(testLogger{})
+	require.NoError(t, registry.Register(&stubExecutor{actionTypes: []string{ActionTypeScale}, block: true}))
+
+	result, err := registry.Execute(context.Background(), &Action{Type: ActionTypeMigrate, Target: "wl-1"})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "executor not found")
+
+	result, err = registry.Execute(context.Background(), &Action{Type: ActionTypeScale})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Message, "validation failed")
+
+	result, err = registry.Execute(context.Background(), &Action{Type: ActionTypeScale, Target: "wl-1", Timeout: 20 * time.Millisecond})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.False(t, result.Success)
+}
+
+func TestBackoff(t *testing.T) {
+	policy := &RetryPolicy{Interval: time.Second}
+	for _, tc := range []struct {
+		backoff BackoffType
+		attempt int
+		want    time.Duration
+	}{
+		{BackoffTypeFixed, 2, time.Second},
+		{BackoffTypeLinear, 2, 3 * time.Second},
+		{BackoffTypeExponential, 0, time.Second},
+		{BackoffTypeExponential, 3, 8 * time.Second},
+	} {
+		policy.Backoff = tc.backoff
+		assert.Equal(t, tc.want, Backoff(policy, tc.attempt), "%s attempt %d", tc.backoff, tc.attempt)
+	}
+}
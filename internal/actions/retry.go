@@ -0,0 +1,79 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// Backoff returns the delay before the retry following the given attempt,
+// counted from zero
+func Backoff(policy *RetryPolicy, attempt int) time.Duration {
+	baseDelay := policy.Interval
+
+	switch policy.Backoff {
+	case BackoffTypeLinear:
+		return baseDelay * time.Duration(attempt+1)
+	case BackoffTypeExponential:
+		delay := baseDelay
+		for i := 0; i < attempt; i++ {
+			delay *= 2
+		}
+		return delay
+	default:
+		return baseDelay
+	}
+}
+
+// executeWithRetry executes an action until it succeeds or its retry policy
+// is exhausted
+func executeWithRetry(ctx context.Context, executor Executor, action *Action, logger types.Logger) (*Result, error) {
+	retryPolicy := action.RetryPolicy
+	var lastResult *Result
+	var lastErr error
+
+	for attempt := 0; attempt <= retryPolicy.MaxRetries; attempt++ {
+		result, err := executor.Execute(ctx, action)
+		lastResult = result
+		lastErr = err
+
+		// If successful, return result
+		if err == nil && result != nil && result.Success {
+			result.RetryCount = attempt
+			return result, nil
+		}
+
+		// If this is the last attempt, don't sleep
+		if attempt == retryPolicy.MaxRetries {
+			break
+		}
+
+		// Calculate backoff delay
+		delay := Backoff(retryPolicy, attempt)
+
+		logger.Warn("action execution failed, retrying",
+			"action_type", action.Type,
+			"attempt", attempt+1,
+			"max_retries", retryPolicy.MaxRetries,
+			"delay", delay,
+			"error", err)
+
+		// Sleep for the calculated delay
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+			// Continue to next attempt
+		}
+	}
+
+	// All retries exhausted
+	if lastResult != nil {
+		lastResult.RetryCount = retryPolicy.MaxRetries
+		return lastResult, lastErr
+	}
+
+	return nil, fmt.Errorf("action execution failed after %d retries: %v", retryPolicy.MaxRetries, lastErr)
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/kcloud-opt/policy/internal/events"
 	"github.com/kcloud-opt/policy/internal/storage"
 	"github.com/kcloud-opt/policy/internal/types"
 )
@@ -16,8 +17,9 @@ type automationEngine struct {
 	ruleExecutor       RuleExecutor
 	conditionEvaluator ConditionEvaluator
 	scheduler          Scheduler
+	bus                events.Bus
+	subscriptionID     string
 	eventHandlers      map[string]EventHandler
-	actionExecutors    map[string]ActionExecutor
 	rules              map[string]*AutomationRule
 	ruleStatuses       map[string]*RuleStatus
 	running            bool
@@ -26,12 +28,18 @@ type automationEngine struct {
 	stopChan           chan struct{}
 }
 
-// NewAutomationEngine creates a new automation engine
+// scheduleTick is how often the engine asks the scheduler for due rules,
+// which bounds how late a scheduled rule can start
+const scheduleTick = time.Second
+
+// NewAutomationEngine creates a new automation engine. Rules with
+// triggers fire for the matching events published on bus.
 func NewAutomationEngine(
 	storage storage.StorageManager,
 	ruleExecutor RuleExecutor,
 	conditionEvaluator ConditionEvaluator,
 	scheduler Scheduler,
+	bus events.Bus,
 	logger types.Logger,
 ) AutomationEngine {
 	return &automationEngine{
@@ -39,8 +47,8 @@ func NewAutomationEngine(
 		ruleExecutor:       ruleExecutor,
 		conditionEvaluator: conditionEvaluator,
 		scheduler:          scheduler,
+		bus:                bus,
 		eventHandlers:      make(map[string]EventHandler),
-		actionExecutors:    make(map[string]ActionExecutor),
 		rules:              make(map[string]*AutomationRule),
 		ruleStatuses:       make(map[string]*RuleStatus),
 		running:            false,
@@ -58,12 +66,17 @@ func (ae *automationEngine) CreateRule(ctx context.Context, rule *AutomationRule
 		return fmt.Errorf("invalid rule: %w", err)
 	}
 
+	if err := ae.reschedule(ctx, rule); err != nil {
+		return err
+	}
+
 	ae.rules[rule.ID] = rule
 	ae.ruleStatuses[rule.ID] = &RuleStatus{
-		RuleID:      rule.ID,
-		Status:      "registered",
-		LastChecked: time.Now(),
-		CreatedAt:   time.Now(),
+		RuleID:        rule.ID,
+		Status:        "registered",
+		LastChecked:   time.Now(),
+		CreatedAt:     time.Now(),
+		NextExecution: ae.scheduler.NextExecution(rule.ID),
 	}
 
 	return nil
@@ -81,9 +94,13 @@ func (ae *automationEngine) UpdateRule(ctx context.Context, rule *AutomationRule
 	if _, exists := ae.rules[rule.ID]; !exists {
 		return fmt.Errorf("rule not found: %s", rule.ID)
 	}
+	if err := ae.reschedule(ctx, rule); err != nil {
+		return err
+	}
 
 	ae.rules[rule.ID] = rule
 	ae.ruleStatuses[rule.ID].LastUpdated = time.Now()
+	ae.pruneConditionStates(ctx, rule.ID, rule.Conditions)
 
 	return nil
 }
@@ -97,8 +114,12 @@ func (ae *automationEngine) DeleteRule(ctx context.Context, ruleID string) error
 		return fmt.Errorf("rule not found: %s", ruleID)
 	}
 
+	// The rule may never have been scheduled
+	_ = ae.scheduler.UnscheduleRule(ctx, ruleID)
+	delete(ae.eventHandlers, ruleID)
 	delete(ae.rules, ruleID)
 	delete(ae.ruleStatuses, ruleID)
+	ae.pruneConditionStates(ctx, ruleID, nil)
 
 	return nil
 }
@@ -140,6 +161,10 @@ func (ae *automationEngine) EnableRule(ctx context.Context, ruleID string) error
 	}
 
 	rule.Enabled = true
+	if err := ae.reschedule(ctx, rule); err != nil {
+		rule.Enabled = false
+		return err
+	}
 	ae.ruleStatuses[ruleID].LastUpdated = time.Now()
 
 	return nil
@@ -156,8 +181,13 @@ func (ae *automationEngine) DisableRule(ctx context.Context, ruleID string) erro
 	}
 
 	rule.Enabled = false
+	ae.reschedule(ctx, rule)
 	ae.ruleStatuses[ruleID].LastUpdated = time.Now()
 
+	// Conditions are not evaluated while the rule is disabled, so they
+	// cannot be known to have held
+	ae.pruneConditionStates(ctx, ruleID, nil)
+
 	return nil
 }
 
@@ -311,14 +341,15 @@ func (ae *automationEngine) Start(ctx context.Context) error {
 
 	// Register rules with scheduler
 	for _, rule := range ae.rules {
-		if rule.Enabled && rule.Schedule != nil {
-			if err := ae.scheduler.ScheduleRule(ctx, rule); err != nil {
-				ae.logger.WithError(err).WithPolicy(rule.ID, rule.Name).Warn("failed to schedule rule")
-			}
+		if err := ae.reschedule(ctx, rule); err != nil {
+			ae.logger.WithError(err).WithPolicy(rule.ID, rule.Name).Warn("failed to schedule rule")
 		}
 	}
 
 	ae.running = true
+	if ae.bus != nil {
+		ae.subscriptionID = ae.bus.Subscribe(ae)
+	}
 
 	// Start event processing loop
 	go ae.eventProcessingLoop(ctx)
@@ -341,6 +372,9 @@ func (ae *automationEngine) Stop(ctx context.Context) error {
 
 	// Signal stop
 	close(ae.stopChan)
+	if ae.bus != nil {
+		ae.bus.Unsubscribe(ae.subscriptionID)
+	}
 
 	// Unschedule all rules
 	for ruleID := range ae.rules {
@@ -386,11 +420,10 @@ func (ae *automationEngine) RegisterRule(ctx context.Context, rule *AutomationRu
 	}
 
 	// Schedule rule if it has a schedule and is enabled
-	if rule.Enabled && rule.Schedule != nil {
-		if err := ae.scheduler.ScheduleRule(ctx, rule); err != nil {
-			return fmt.Errorf("failed to schedule rule: %w", err)
-		}
+	if err := ae.reschedule(ctx, rule); err != nil {
+		return err
 	}
+	ae.ruleStatuses[rule.ID].NextExecution = ae.scheduler.NextExecution(rule.ID)
 
 	ae.logger.Info("registered automation rule", "rule_id", rule.ID, "rule_name", rule.Name)
 
@@ -413,8 +446,10 @@ func (ae *automationEngine) UnregisterRule(ctx context.Context, ruleID string) e
 	}
 
 	// Remove rule and status
+	delete(ae.eventHandlers, ruleID)
 	delete(ae.rules, ruleID)
 	delete(ae.ruleStatuses, ruleID)
+	ae.pruneConditionStates(ctx, ruleID, nil)
 
 	ae.logger.Info("unregistered automation rule", "rule_id", ruleID, "rule_name", rule.Name)
 
@@ -466,6 +501,14 @@ func (ae *automationEngine) GetRuleStatus(ctx context.Context, ruleID string) (*
 
 	// Return a copy to avoid modification
 	statusCopy := *status
+	statusCopy.NextExecution = ae.scheduler.NextExecution(ruleID)
+
+	conditions, err := ae.storage.ConditionState().List(ctx, &storage.ConditionStateFilters{RuleID: &ruleID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get condition states of rule %s: %w", ruleID, err)
+	}
+	statusCopy.Conditions = conditions
+
 	return &statusCopy, nil
 }
 
@@ -511,83 +554,30 @@ func (ae *automationEngine) Health(ctx context.Context) error {
 
 // Helper methods
 
-// loadRules loads rules from storage
+// loadRules loads the rules of the automation policies in storage
 func (ae *automationEngine) loadRules(ctx context.Context) error {
-	// Get automation policies from storage
 	policies, err := ae.storage.Policy().GetByType(ctx, types.PolicyTypeAutomation)
 	if err != nil {
 		return fmt.Errorf("failed to get automation policies: %w", err)
 	}
 
 	for _, policy := range policies {
-		// Convert policy to automation rule
-		rule, err := ae.policyToRule(policy)
+		rule, err := ae.convertPolicy(ctx, policy)
+		ae.recordPolicyConversion(ctx, policy, err)
 		if err != nil {
-			ae.logger.WithError(err).WithPolicy(policy.GetMetadata().Name, "").Warn("failed to convert policy to rule")
 			continue
 		}
 
 		ae.rules[rule.ID] = rule
-
-		// Initialize rule status
-		ae.ruleStatuses[rule.ID] = &RuleStatus{
-			RuleID:         rule.ID,
-			Name:           rule.Name,
-			Status:         RuleStatusActive,
-			ExecutionCount: 0,
-			SuccessCount:   0,
-			FailureCount:   0,
-			Metadata:       make(map[string]interface{}),
-		}
+		ae.ruleStatuses[rule.ID] = newRuleStatus(rule)
 	}
 
 	return nil
 }
 
-// policyToRule converts a policy to an automation rule
-func (ae *automationEngine) policyToRule(policy types.Policy) (*AutomationRule, error) {
-	metadata := policy.GetMetadata()
-
-	rule := &AutomationRule{
-		ID:          metadata.Name,
-		Name:        metadata.Name,
-		Description: "Converted from policy",
-		Enabled:     policy.GetStatus() == types.PolicyStatusActive,
-		Priority:    int(policy.GetPriority()),
-		Conditions:  []*Condition{},
-		Actions:     []*Action{},
-		Metadata:    convertMapStringToString(metadata.Labels),
-		CreatedAt:   metadata.CreationTimestamp,
-		UpdatedAt:   metadata.LastModified,
-	}
-
-	// Add basic condition and action based on policy type
-	if policy.GetType() == types.PolicyTypeAutomation {
-		// Add a simple condition
-		condition := &Condition{
-			Field:    "status",
-			Operator: OperatorEquals,
-			Value:    "active",
-		}
-		rule.Conditions = append(rule.Conditions, condition)
-
-		// Add a simple action
-		action := &Action{
-			Type:   ActionTypeNotify,
-			Target: "automation",
-			Parameters: map[string]interface{}{
-				"message": "Automation rule triggered",
-			},
-		}
-		rule.Actions = append(rule.Actions, action)
-	}
-
-	return rule, nil
-}
-
 // eventProcessingLoop processes automation events
 func (ae *automationEngine) eventProcessingLoop(ctx context.Context) {
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(scheduleTick)
 	defer ticker.Stop()
 
 	for {
@@ -602,62 +592,146 @@ func (ae *automationEngine) eventProcessingLoop(ctx context.Context) {
 	}
 }
 
-// processScheduledRules processes scheduled rules
+// processScheduledRules runs the rules the scheduler reports due. A rule
+// still running from an earlier activation skips this one.
 func (ae *automationEngine) processScheduledRules(ctx context.Context) {
+	for _, rule := range ae.scheduler.DueRules(ctx) {
+		ae.mu.Lock()
+		status := ae.ruleStatuses[rule.ID]
+		if status != nil && status.Status == RuleStatusRunning {
+			ae.mu.Unlock()
+			ae.logger.Warn("skipping scheduled rule still running", "rule_id", rule.ID)
+			continue
+		}
+		if status != nil {
+			status.Status = RuleStatusRunning
+		}
+		ae.mu.Unlock()
+
+		go func(r *AutomationRule) {
+			context := map[string]interface{}{
+				"trigger": "schedule",
+				"time":    time.Now(),
+			}
+
+			result, err := ae.ruleExecutor.ExecuteRule(ctx, r, context)
+			if err != nil {
+				ae.logger.WithError(err).WithPolicy(r.ID, r.Name).Error("failed to execute scheduled rule")
+				result = &ExecutionResult{
+					RuleID:    r.ID,
+					Success:   false,
+					Error:     err.Error(),
+					Timestamp: time.Now(),
+				}
+			}
+			ae.updateRuleStatus(r.ID, result)
+		}(rule)
+	}
+}
+
+// HandleEvent keeps the rules of automation policies in sync and fires the
+// rules whose triggers match an event
+func (ae *automationEngine) HandleEvent(ctx context.Context, event *Event) error {
+	if events.MatchesType(policyEvents, event.Type) {
+		ae.syncPolicy(ctx, event)
+	}
+
 	ae.mu.RLock()
-	rules := make([]*AutomationRule, 0, len(ae.rules))
-	for _, rule := range ae.rules {
-		if rule.Enabled && rule.Schedule != nil {
-			rules = append(rules, rule)
+	handlers := make([]EventHandler, 0, len(ae.eventHandlers))
+	for _, handler := range ae.eventHandlers {
+		if handler.CanHandle(event.Type) {
+			handlers = append(handlers, handler)
 		}
 	}
 	ae.mu.RUnlock()
 
-	for _, rule := range rules {
-		// Check if rule should be executed based on schedule
-		if ae.shouldExecuteRule(rule) {
-			go func(r *AutomationRule) {
-				context := map[string]interface{}{
-					"trigger": "schedule",
-					"time":    time.Now(),
-				}
-
-				result, err := ae.ruleExecutor.ExecuteRule(ctx, r, context)
-				if err != nil {
-					ae.logger.WithError(err).WithPolicy(r.ID, r.Name).Error("failed to execute scheduled rule")
-				} else {
-					ae.updateRuleStatus(r.ID, result)
-				}
-			}(rule)
+	for _, handler := range handlers {
+		if err := handler.HandleEvent(ctx, event); err != nil {
+			ae.logger.WithError(err).Warn("failed to match event triggers", "event_id", event.ID, "event_type", event.Type)
 		}
 	}
+	return nil
 }
 
-// shouldExecuteRule checks if a rule should be executed based on its schedule
-func (ae *automationEngine) shouldExecuteRule(rule *AutomationRule) bool {
-	if rule.Schedule == nil {
-		return false
+// CanHandle checks if the event type is a policy event or any rule has a
+// trigger for it
+func (ae *automationEngine) CanHandle(eventType string) bool {
+	if events.MatchesType(policyEvents, eventType) {
+		return true
 	}
 
-	now := time.Now()
+	ae.mu.RLock()
+	defer ae.mu.RUnlock()
 
-	// Check if within time window
-	if !rule.Schedule.StartTime.IsZero() && now.Before(rule.Schedule.StartTime) {
-		return false
+	for _, handler := range ae.eventHandlers {
+		if handler.CanHandle(eventType) {
+			return true
+		}
 	}
-	if !rule.Schedule.EndTime.IsZero() && now.After(rule.Schedule.EndTime) {
-		return false
+	return false
+}
+
+// fireRule runs a rule triggered by an event with the event payload as its
+// context
+func (ae *automationEngine) fireRule(ctx context.Context, rule *AutomationRule, event *Event) {
+	ae.logger.Info("event triggered rule", "rule_id", rule.ID, "event_id", event.ID, "event_type", event.Type)
+
+	go func() {
+		result, err := ae.ruleExecutor.ExecuteRule(ctx, rule, eventContext(event))
+		if err != nil {
+			ae.logger.WithError(err).WithPolicy(rule.ID, rule.Name).Error("failed to execute event triggered rule")
+			result = &ExecutionResult{
+				RuleID:    rule.ID,
+				Success:   false,
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			}
+		}
+		ae.updateRuleStatus(rule.ID, result)
+	}()
+}
+
+// reschedule registers a rule with the scheduler while it is enabled and
+// has a schedule, and removes it from the scheduler otherwise. An enabled
+// rule with triggers also gets an event handler.
+func (ae *automationEngine) reschedule(ctx context.Context, rule *AutomationRule) error {
+	if rule.Enabled && len(rule.Triggers) > 0 {
+		ae.eventHandlers[rule.ID] = newTriggerHandler(rule, ae.conditionEvaluator, ae.fireRule)
+	} else {
+		delete(ae.eventHandlers, rule.ID)
 	}
 
-	if rule.Schedule.Interval == "30s" {
-		status := ae.ruleStatuses[rule.ID]
-		if status == nil || status.LastExecuted.IsZero() {
-			return true
+	if rule.Enabled && rule.Schedule != nil {
+		if err := ae.scheduler.ScheduleRule(ctx, rule); err != nil {
+			return fmt.Errorf("failed to schedule rule: %w", err)
 		}
-		return now.Sub(*status.LastExecuted) >= 30*time.Second
+		return nil
 	}
 
-	return false
+	// The rule may never have been scheduled
+	_ = ae.scheduler.UnscheduleRule(ctx, rule.ID)
+	return nil
+}
+
+// pruneConditionStates deletes the stored condition states of a rule
+// except those of the conditions in keep
+func (ae *automationEngine) pruneConditionStates(ctx context.Context, ruleID string, keep []*Condition) {
+	states, err := ae.storage.ConditionState().List(ctx, &storage.ConditionStateFilters{RuleID: &ruleID})
+	if err != nil {
+		ae.logger.WithError(err).Warn("failed to list condition states", "rule_id", ruleID)
+		return
+	}
+
+	kept := make(map[string]bool, len(keep))
+	durationConditionKeys(keep, kept)
+	for _, state := range states {
+		if kept[state.Condition] {
+			continue
+		}
+		if err := ae.storage.ConditionState().Delete(ctx, ruleID, state.Condition, state.Subject); err != nil {
+			ae.logger.WithError(err).Warn("failed to delete condition state", "rule_id", ruleID, "condition", state.Condition)
+		}
+	}
 }
 
 // updateRuleStatus updates the status of a rule
@@ -683,19 +757,5 @@ func (ae *automationEngine) updateRuleStatus(ruleID string, result *ExecutionRes
 		status.LastError = result.Error
 	}
 
-	// Calculate next execution time for scheduled rules
-	rule := ae.rules[ruleID]
-	if rule != nil && rule.Schedule != nil && rule.Enabled {
-		nextExecution := result.Timestamp.Add(30 * time.Second) // Simplified
-		status.NextExecution = &nextExecution
-	}
-}
-
-// convertMapStringToString converts map[string]string to map[string]interface{}
-func convertMapStringToString(m map[string]string) map[string]interface{} {
-	result := make(map[string]interface{})
-	for k, v := range m {
-		result[k] = v
-	}
-	return result
+	status.NextExecution = ae.scheduler.NextExecution(ruleID)
 }
@@ -2,47 +2,100 @@ package automation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/storage"
 	"github.com/kcloud-opt/policy/internal/types"
 )
 
 // conditionEvaluator implements ConditionEvaluator interface
 type conditionEvaluator struct {
+	states storage.ConditionStateStore
+	rules  evaluator.RuleEngine
+	clock  Clock
 	logger types.Logger
 }
 
-// NewConditionEvaluator creates a new condition evaluator
-func NewConditionEvaluator(logger types.Logger) ConditionEvaluator {
+// errConditionKind is returned for a condition that is not exactly one kind
+var errConditionKind = errors.New("condition must have exactly one of a field, an expression, all, any or not")
+
+// subjectFields are the context fields that name what a rule is evaluated
+// for, in order of preference. Duration qualified conditions are tracked
+// separately for each subject.
+var subjectFields = []string{"subject", "workloadId", "workload.id", "decisionId", "policyId"}
+
+// NewConditionEvaluator creates a new condition evaluator that keeps the
+// state of duration qualified conditions in states and evaluates
+// expressions with rules
+func NewConditionEvaluator(states storage.ConditionStateStore, rules evaluator.RuleEngine, logger types.Logger) ConditionEvaluator {
+	return NewConditionEvaluatorWithClock(states, rules, time.Now, logger)
+}
+
+// NewConditionEvaluatorWithClock creates a new condition evaluator that
+// reads the time from clock
+func NewConditionEvaluatorWithClock(states storage.ConditionStateStore, rules evaluator.RuleEngine, clock Clock, logger types.Logger) ConditionEvaluator {
 	return &conditionEvaluator{
+		states: states,
+		rules:  rules,
+		clock:  clock,
 		logger: logger,
 	}
 }
 
-// EvaluateCondition evaluates a condition against context
+// EvaluateCondition evaluates a condition and the conditions nested in it
+// against context. A condition with a duration can only be evaluated as
+// part of a rule, which tracks how long it has held.
 func (ce *conditionEvaluator) EvaluateCondition(ctx context.Context, condition *Condition, context map[string]interface{}) (bool, error) {
-	// Get the value from context
-	value, exists := ce.getValueFromContext(context, condition.Field)
-	if !exists {
-		ce.logger.Debug("field not found in context", "field", condition.Field)
-		return false, nil
+	return ce.evaluateTree(ctx, condition, context, nil)
+}
+
+// EvaluateExpression evaluates a boolean expression against context with
+// the rule engine
+func (ce *conditionEvaluator) EvaluateExpression(ctx context.Context, expression string, context map[string]interface{}) (bool, error) {
+	if ce.rules == nil {
+		return false, fmt.Errorf("no rule engine to evaluate expressions with")
 	}
+	return ce.rules.EvaluateCondition(ctx, expression, context)
+}
 
-	// Evaluate condition based on operator
-	result, err := ce.evaluateOperator(condition.Operator, value, condition.Value)
-	if err != nil {
-		return false, fmt.Errorf("failed to evaluate operator %s: %w", condition.Operator, err)
+// ValidateExpression checks the syntax of an expression
+func (ce *conditionEvaluator) ValidateExpression(ctx context.Context, expression string) error {
+	if ce.rules == nil {
+		return fmt.Errorf("no rule engine to evaluate expressions with")
 	}
+	return ce.rules.ValidateRule(ctx, expression)
+}
 
-	// Check duration requirement if specified
-	if condition.Duration != nil && result {
-		return ce.checkDurationRequirement(ctx, condition, context, *condition.Duration)
+// EvaluateRule evaluates the conditions of a rule against context. Every
+// condition is evaluated, including the nested ones and those after one is
+// not met, so that duration qualified conditions see each sample and start
+// over when they go false.
+func (ce *conditionEvaluator) EvaluateRule(ctx context.Context, rule *AutomationRule, context map[string]interface{}) (bool, error) {
+	tracker := &durationTracker{
+		ruleID:  rule.ID,
+		subject: ce.subject(context),
+		now:     ce.clock(),
 	}
 
-	return result, nil
+	met := true
+	for i, condition := range rule.Conditions {
+		result, err := ce.evaluateTree(ctx, condition, context, tracker)
+		if err != nil {
+			return false, fmt.Errorf("condition %d evaluation failed: %w", i, err)
+		}
+
+		if !result {
+			ce.logger.Debug("condition not met", "rule_id", rule.ID, "condition_index", i, "condition", condition.String())
+			met = false
+		}
+	}
+
+	return met, nil
 }
 
 // EvaluateConditions evaluates multiple conditions
@@ -59,7 +112,7 @@ func (ce *conditionEvaluator) EvaluateConditions(ctx context.Context, conditions
 		}
 
 		if !result {
-			ce.logger.Debug("condition not met", "condition_index", i, "field", condition.Field, "operator", condition.Operator)
+			ce.logger.Debug("condition not met", "condition_index", i, "condition", condition.String())
 			return false, nil
 		}
 	}
@@ -315,17 +368,163 @@ func (ce *conditionEvaluator) toNumber(value interface{}) (float64, error) {
 	}
 }
 
-// checkDurationRequirement checks if a condition has been true for the specified duration
-func (ce *conditionEvaluator) checkDurationRequirement(ctx context.Context, condition *Condition, context map[string]interface{}, duration time.Duration) (bool, error) {
-	// This is a simplified implementation
-	// In a real implementation, you would track the state of conditions over time
+// durationTracker is what the duration qualified conditions of a rule are
+// tracked by while the rule is evaluated
+type durationTracker struct {
+	ruleID  string
+	subject string
+	now     time.Time
+}
 
-	// For now, we'll just return true if the condition is met
-	// In practice, you would need to store the timestamp when the condition first became true
-	// and check if enough time has passed
+// evaluateTree evaluates a condition and the conditions nested in it. The
+// conditions of a group are all evaluated, even once the result of the
+// group is known, so that the duration qualified ones among them are
+// tracked. Without a tracker, conditions with a duration cannot be
+// evaluated.
+func (ce *conditionEvaluator) evaluateTree(ctx context.Context, condition *Condition, context map[string]interface{}, tracker *durationTracker) (bool, error) {
+	if condition == nil {
+		return false, fmt.Errorf("condition cannot be nil")
+	}
 
-	ce.logger.Debug("checking duration requirement", "field", condition.Field, "duration", duration)
+	var result bool
+	var err error
+	switch condition.Kind() {
+	case ConditionKindComparison:
+		result, err = ce.evaluate(condition, context)
+	case ConditionKindExpression:
+		result, err = ce.EvaluateExpression(ctx, condition.Expression, context)
+	case ConditionKindAll:
+		result, err = ce.evaluateGroup(ctx, condition.All, context, tracker, true)
+	case ConditionKindAny:
+		result, err = ce.evaluateGroup(ctx, condition.Any, context, tracker, false)
+	case ConditionKindNot:
+		result, err = ce.evaluateTree(ctx, condition.Not, context, tracker)
+		result = !result
+	default:
+		err = errConditionKind
+	}
+	if err != nil {
+		return false, err
+	}
 
-	// Simulate duration check - in reality, this would check historical data
-	return true, nil
+	if condition.Duration == nil {
+		return result, nil
+	}
+	if tracker == nil {
+		return false, fmt.Errorf("condition %s has a duration and must be evaluated as part of a rule", condition)
+	}
+	result, err = ce.trackDuration(ctx, tracker.ruleID, condition, tracker.subject, result, tracker.now)
+	if err != nil {
+		return false, fmt.Errorf("duration tracking failed: %w", err)
+	}
+	return result, nil
+}
+
+// evaluateGroup evaluates the conditions of a group, which holds when all
+// of them hold or, for an any group, when one of them does
+func (ce *conditionEvaluator) evaluateGroup(ctx context.Context, conditions []*Condition, context map[string]interface{}, tracker *durationTracker, all bool) (bool, error) {
+	met := all
+	for i, condition := range conditions {
+		result, err := ce.evaluateTree(ctx, condition, context, tracker)
+		if err != nil {
+			return false, fmt.Errorf("condition %d: %w", i, err)
+		}
+		if all && !result {
+			met = false
+		} else if !all && result {
+			met = true
+		}
+	}
+	return met, nil
+}
+
+// evaluate evaluates a comparison against context, ignoring its duration
+func (ce *conditionEvaluator) evaluate(condition *Condition, context map[string]interface{}) (bool, error) {
+	// Get the value from context
+	value, exists := ce.getValueFromContext(context, condition.Field)
+	if !exists {
+		ce.logger.Debug("field not found in context", "field", condition.Field)
+		return false, nil
+	}
+
+	// Evaluate condition based on operator
+	result, err := ce.evaluateOperator(condition.Operator, value, condition.Value)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate operator %s: %w", condition.Operator, err)
+	}
+
+	return result, nil
+}
+
+// trackDuration records whether a duration qualified condition holds for a
+// subject and returns true once it has held for the condition's duration.
+// A condition that does not hold starts over.
+func (ce *conditionEvaluator) trackDuration(ctx context.Context, ruleID string, condition *Condition, subject string, holds bool, now time.Time) (bool, error) {
+	if ce.states == nil {
+		return false, fmt.Errorf("no condition state store to track durations in")
+	}
+	key := conditionKey(condition)
+
+	if !holds {
+		if err := ce.states.Delete(ctx, ruleID, key, subject); err != nil && !errors.Is(err, storage.ErrStorageNotFound) {
+			return false, fmt.Errorf("failed to reset condition state: %w", err)
+		}
+		return false, nil
+	}
+
+	state, err := ce.states.Get(ctx, ruleID, key, subject)
+	if err != nil {
+		if !errors.Is(err, storage.ErrStorageNotFound) {
+			return false, fmt.Errorf("failed to get condition state: %w", err)
+		}
+		state = &types.ConditionState{RuleID: ruleID, Condition: key, Subject: subject, Since: now}
+	}
+	state.Duration = *condition.Duration
+	state.LastSeen = now
+	if err := ce.states.Save(ctx, state); err != nil {
+		return false, fmt.Errorf("failed to save condition state: %w", err)
+	}
+
+	ce.logger.Debug("checked duration requirement", "rule_id", ruleID, "condition", key,
+		"subject", subject, "held_for", state.HeldFor(now), "duration", state.Duration)
+
+	return state.Satisfied(now), nil
+}
+
+// subject returns what the context is about, or an empty string for a
+// context about nothing in particular
+func (ce *conditionEvaluator) subject(context map[string]interface{}) string {
+	for _, field := range subjectFields {
+		if value, exists := ce.getValueFromContext(context, field); exists && value != nil {
+			if subject := fmt.Sprintf("%v", value); subject != "" {
+				return subject
+			}
+		}
+	}
+	return ""
+}
+
+// conditionKey identifies a condition of a rule in its stored state. It
+// leaves out the duration, so changing how long a condition must hold
+// keeps the time it has held so far.
+func conditionKey(condition *Condition) string {
+	return condition.String()
+}
+
+// durationConditionKeys adds the keys of the duration qualified conditions
+// among conditions and the conditions nested in them to keys
+func durationConditionKeys(conditions []*Condition, keys map[string]bool) {
+	for _, condition := range conditions {
+		if condition == nil {
+			continue
+		}
+		if condition.Duration != nil {
+			keys[conditionKey(condition)] = true
+		}
+		durationConditionKeys(condition.All, keys)
+		durationConditionKeys(condition.Any, keys)
+		if condition.Not != nil {
+			durationConditionKeys([]*Condition{condition.Not}, keys)
+		}
+	}
 }
@@ -0,0 +1,230 @@
+package automation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+)
+
+func newHotCPURule(hold time.Duration) *AutomationRule {
+	return &AutomationRule{
+		ID:      "hot-cpu",
+		Name:    "hot-cpu",
+		Enabled: true,
+		Conditions: []*Condition{
+			{Field: "workload.type", Operator: OperatorEquals, Value: "inference"},
+			{Field: "metrics.cpu", Operator: OperatorGreaterThan, Value: 80, Duration: &hold},
+		},
+		Actions: []*Action{{Type: ActionTypeScale, Target: "workload"}},
+	}
+}
+
+func cpuSample(workloadID string, cpu float64) map[string]interface{} {
+	return map[string]interface{}{
+		"workloadId": workloadID,
+		"workload":   map[string]interface{}{"type": "inference"},
+		"metrics":    map[string]interface{}{"cpu": cpu},
+	}
+}
+
+// idleGPUCondition holds for "(gpu_util < 10 AND age > 1h) OR label idle=true"
+func idleGPUCondition() *Condition {
+	return &Condition{Any: []*Condition{
+		{All: []*Condition{
+			{Field: "metrics.gpu_util", Operator: OperatorLessThan, Value: 10},
+			{Expression: "age > 3600"},
+		}},
+		{Field: "labels.idle", Operator: OperatorEquals, Value: "true"},
+	}}
+}
+
+func gpuSample(gpuUtil float64, age int, idle string) map[string]interface{} {
+	return map[string]interface{}{
+		"workloadId": "wl-1",
+		"metrics":    map[string]interface{}{"gpu_util": gpuUtil},
+		"age":        age,
+		"labels":     map[string]interface{}{"idle": idle},
+	}
+}
+
+func TestConditionEvaluator_EvaluatesConditionGroups(t *testing.T) {
+	ctx := context.Background()
+	ce := NewConditionEvaluator(nil, evaluator.NewRuleEngine(testLogger{}), testLogger{})
+
+	tests := []struct {
+		name    string
+		context map[string]interface{}
+		want    bool
+	}{
+		{"idle and old", gpuSample(3, 7200, "false"), true},
+		{"idle but new", gpuSample(3, 60, "false"), false},
+		{"busy and old", gpuSample(80, 7200, "false"), false},
+		{"labelled idle", gpuSample(80, 60, "true"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			met, err := ce.EvaluateCondition(ctx, idleGPUCondition(), tt.context)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, met)
+
+			met, err = ce.EvaluateCondition(ctx, &Condition{Not: idleGPUCondition()}, tt.context)
+			require.NoError(t, err)
+			assert.Equal(t, !tt.want, met)
+		})
+	}
+
+	_, err := ce.EvaluateCondition(ctx, &Condition{Field: "age", Operator: OperatorGreaterThan, Expression: "age > 1"}, gpuSample(3, 60, "false"))
+	assert.Error(t, err)
+}
+
+func TestConditionEvaluator_TracksDurationsInGroups(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStorageManager()
+	clock := &fakeClock{now: time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)}
+	ce := NewConditionEvaluatorWithClock(store.ConditionState(), evaluator.NewRuleEngine(testLogger{}), clock.Now, testLogger{})
+
+	hold := time.Hour
+	rule := &AutomationRule{
+		ID: "idle-gpu",
+		Conditions: []*Condition{{Any: []*Condition{
+			{Field: "metrics.gpu_util", Operator: OperatorLessThan, Value: 10, Duration: &hold},
+			{Field: "labels.idle", Operator: OperatorEquals, Value: "true"},
+		}}},
+	}
+
+	met, err := ce.EvaluateRule(ctx, rule, gpuSample(3, 0, "false"))
+	require.NoError(t, err)
+	assert.False(t, met)
+
+	// The nested duration is tracked while another branch holds
+	clock.now = clock.now.Add(30 * time.Minute)
+	met, _ = ce.EvaluateRule(ctx, rule, gpuSample(3, 0, "true"))
+	assert.True(t, met)
+
+	clock.now = clock.now.Add(30 * time.Minute)
+	met, _ = ce.EvaluateRule(ctx, rule, gpuSample(3, 0, "false"))
+	assert.True(t, met, "held for 1h")
+
+	states, err := store.ConditionState().List(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+	assert.Equal(t, "metrics.gpu_util less_than 10", states[0].Condition)
+}
+
+func TestRuleExecutor_ValidatesConditionGroups(t *testing.T) {
+	ctx := context.Background()
+	executor := newRuleExecutor(t, &stubActionExecutor{actionTypes: []string{ActionTypeNotify}})
+	newRule := func(condition *Condition) *AutomationRule {
+		return &AutomationRule{
+			ID:         "idle-gpu",
+			Name:       "idle-gpu",
+			Conditions: []*Condition{condition},
+			Actions:    []*Action{{Type: ActionTypeNotify}},
+		}
+	}
+
+	require.NoError(t, executor.ValidateRule(ctx, newRule(idleGPUCondition())))
+
+	invalid := map[string]*Condition{
+		"no kind":          {},
+		"two kinds":        {Field: "age", Operator: OperatorGreaterThan, Value: 1, Expression: "age > 1"},
+		"empty group":      {All: []*Condition{}},
+		"bad expression":   {Not: &Condition{Expression: "age >"}},
+		"nested operator":  {Any: []*Condition{{Field: "age", Operator: "older_than", Value: 1}}},
+		"nested duration":  {Any: []*Condition{{Expression: "age > 1", Duration: new(time.Duration)}}},
+		"nil in the group": {All: []*Condition{nil}},
+	}
+	for name, condition := range invalid {
+		assert.Error(t, executor.ValidateRule(ctx, newRule(condition)), name)
+	}
+}
+
+func TestConditionEvaluator_DurationMustHoldContinuously(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)}
+	ce := NewConditionEvaluatorWithClock(memory.NewStorageManager().ConditionState(), nil, clock.Now, testLogger{})
+	rule := newHotCPURule(10 * time.Minute)
+
+	met, err := ce.EvaluateRule(ctx, rule, cpuSample("wl-1", 95))
+	require.NoError(t, err)
+	assert.False(t, met, "first sample")
+
+	clock.now = clock.now.Add(6 * time.Minute)
+	met, _ = ce.EvaluateRule(ctx, rule, cpuSample("wl-1", 90))
+	assert.False(t, met, "held for 6m")
+
+	// Dropping below the threshold starts over
+	clock.now = clock.now.Add(time.Minute)
+	met, _ = ce.EvaluateRule(ctx, rule, cpuSample("wl-1", 40))
+	assert.False(t, met)
+	clock.now = clock.now.Add(time.Minute)
+	met, _ = ce.EvaluateRule(ctx, rule, cpuSample("wl-1", 85))
+	assert.False(t, met, "restarted after going false")
+
+	clock.now = clock.now.Add(10 * time.Minute)
+	met, _ = ce.EvaluateRule(ctx, rule, cpuSample("wl-1", 85))
+	assert.True(t, met, "held for 10m")
+
+	// Another workload is tracked on its own
+	met, _ = ce.EvaluateRule(ctx, rule, cpuSample("wl-2", 99))
+	assert.False(t, met)
+}
+
+func TestConditionEvaluator_DurationSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStorageManager()
+	clock := &fakeClock{now: time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)}
+	rule := newHotCPURule(5 * time.Minute)
+
+	before := NewConditionEvaluatorWithClock(store.ConditionState(), nil, clock.Now, testLogger{})
+	met, err := before.EvaluateRule(ctx, rule, cpuSample("wl-1", 95))
+	require.NoError(t, err)
+	assert.False(t, met)
+
+	clock.now = clock.now.Add(5 * time.Minute)
+	after := NewConditionEvaluatorWithClock(store.ConditionState(), nil, clock.Now, testLogger{})
+	met, err = after.EvaluateRule(ctx, rule, cpuSample("wl-1", 95))
+	require.NoError(t, err)
+	assert.True(t, met)
+}
+
+func TestConditionEvaluator_DurationNeedsRule(t *testing.T) {
+	hold := time.Minute
+	ce := NewConditionEvaluator(memory.NewStorageManager().ConditionState(), nil, testLogger{})
+
+	_, err := ce.EvaluateCondition(context.Background(),
+		&Condition{Field: "metrics.cpu", Operator: OperatorGreaterThan, Value: 80, Duration: &hold},
+		cpuSample("wl-1", 95))
+	assert.Error(t, err)
+}
+
+func TestAutomationEngine_RuleStatusShowsConditionStates(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStorageManager()
+	ce := NewConditionEvaluator(store.ConditionState(), nil, testLogger{})
+	engine := NewAutomationEngine(store, &recordingExecutor{}, ce, NewScheduler(testLogger{}), nil, testLogger{})
+
+	rule := newHotCPURule(10 * time.Minute)
+	require.NoError(t, engine.CreateRule(ctx, rule))
+	_, err := ce.EvaluateRule(ctx, rule, cpuSample("wl-1", 95))
+	require.NoError(t, err)
+
+	status, err := engine.GetRuleStatus(ctx, "hot-cpu")
+	require.NoError(t, err)
+	require.Len(t, status.Conditions, 1)
+	assert.Equal(t, "wl-1", status.Conditions[0].Subject)
+	assert.Equal(t, 10*time.Minute, status.Conditions[0].Duration)
+
+	// Dropping the condition drops its state
+	rule.Conditions = rule.Conditions[:1]
+	require.NoError(t, engine.UpdateRule(ctx, rule))
+	status, err = engine.GetRuleStatus(ctx, "hot-cpu")
+	require.NoError(t, err)
+	assert.Empty(t, status.Conditions)
+}
@@ -3,7 +3,12 @@ package automation
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/kcloud-opt/policy/internal/actions"
+	"github.com/kcloud-opt/policy/internal/events"
+	"github.com/kcloud-opt/policy/internal/types"
 )
 
 // AutomationEngine defines the interface for automation engine
@@ -84,6 +89,7 @@ type AutomationRule struct {
 	Actions     []*Action              `json:"actions"`
 	Schedule    *Schedule              `json:"schedule,omitempty"`
 	Triggers    []*Trigger             `json:"triggers"`
+	Exceptions  []*Exception           `json:"exceptions,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 	CreatedAt   time.Time              `json:"createdAt"`
 	UpdatedAt   time.Time              `json:"updatedAt"`
@@ -106,26 +112,115 @@ func (r *AutomationRule) Validate() error {
 	return nil
 }
 
-// Condition represents a condition for automation
+// Condition represents a condition for automation. It is exactly one of a
+// comparison of a context field with a value, an expression evaluated by
+// the rule engine, or a group that holds when all of its conditions hold,
+// when any of them holds, or when its one condition does not. Groups nest,
+// and a condition of any kind may carry a duration.
 type Condition struct {
-	Field    string                 `json:"field"`
-	Operator string                 `json:"operator"`
-	Value    interface{}            `json:"value"`
-	Duration *time.Duration         `json:"duration,omitempty"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Field      string                 `json:"field,omitempty"`
+	Operator   string                 `json:"operator,omitempty"`
+	Value      interface{}            `json:"value,omitempty"`
+	Expression string                 `json:"expression,omitempty"`
+	All        []*Condition           `json:"all,omitempty"`
+	Any        []*Condition           `json:"any,omitempty"`
+	Not        *Condition             `json:"not,omitempty"`
+	Duration   *time.Duration         `json:"duration,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
 }
 
-// Action represents an automation action
-type Action struct {
-	Type       string                 `json:"type"`
-	Target     string                 `json:"target,omitempty"`
-	Parameters map[string]interface{} `json:"parameters,omitempty"`
-	Timeout    *time.Duration         `json:"timeout,omitempty"`
-	Retry      *RetryConfig           `json:"retry,omitempty"`
-	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+// Condition kinds
+const (
+	ConditionKindComparison = "comparison"
+	ConditionKindExpression = "expression"
+	ConditionKindAll        = "all"
+	ConditionKindAny        = "any"
+	ConditionKindNot        = "not"
+)
+
+// Kind returns the kind of a condition, or an empty string when it has
+// none or more than one of a field, an expression, and a group
+func (c *Condition) Kind() string {
+	kinds := make([]string, 0, 1)
+	if c.Field != "" || c.Operator != "" {
+		kinds = append(kinds, ConditionKindComparison)
+	}
+	if c.Expression != "" {
+		kinds = append(kinds, ConditionKindExpression)
+	}
+	if c.All != nil {
+		kinds = append(kinds, ConditionKindAll)
+	}
+	if c.Any != nil {
+		kinds = append(kinds, ConditionKindAny)
+	}
+	if c.Not != nil {
+		kinds = append(kinds, ConditionKindNot)
+	}
+	if len(kinds) != 1 {
+		return ""
+	}
+	return kinds[0]
+}
+
+// String describes a condition and the conditions nested in it, leaving
+// out durations
+func (c *Condition) String() string {
+	if c == nil {
+		return "<nil>"
+	}
+	switch c.Kind() {
+	case ConditionKindExpression:
+		return fmt.Sprintf("expression(%s)", c.Expression)
+	case ConditionKindAll:
+		return fmt.Sprintf("all(%s)", joinConditions(c.All))
+	case ConditionKindAny:
+		return fmt.Sprintf("any(%s)", joinConditions(c.Any))
+	case ConditionKindNot:
+		return fmt.Sprintf("not(%s)", c.Not)
+	default:
+		return fmt.Sprintf("%s %s %v", c.Field, c.Operator, c.Value)
+	}
 }
 
-// Schedule represents a schedule for time-based automation
+// joinConditions describes a list of conditions
+func joinConditions(conditions []*Condition) string {
+	descriptions := make([]string, len(conditions))
+	for i, condition := range conditions {
+		descriptions[i] = condition.String()
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// Action represents an automation action. Its metadata may hold a grace
+// period to wait before the action runs and an expression that must
+// confirm it right before it runs.
+type Action = actions.Action
+
+// Action and rule metadata keys
+const (
+	// ActionMetadataGracePeriod is a Go duration to wait before the action
+	ActionMetadataGracePeriod = "grace_period"
+
+	// ActionMetadataConfirmWith is an expression that must hold for the
+	// action to run. It sees the rule context and, under "actions", the
+	// results of the earlier actions of the rule by action type.
+	ActionMetadataConfirmWith = "confirm_with"
+
+	// RuleMetadataPolicy names the policy a rule was converted from
+	RuleMetadataPolicy = "policy"
+)
+
+// Exception is an expression that, when it holds, keeps a rule whose
+// conditions are met from running
+type Exception struct {
+	Condition string `json:"condition"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Schedule represents a schedule for time-based automation. A rule runs on
+// either a cron expression, evaluated in Timezone, or a Go duration
+// interval, and only between StartTime and EndTime when they are set.
 type Schedule struct {
 	Cron      string    `json:"cron,omitempty"`
 	Interval  string    `json:"interval,omitempty"`
@@ -134,6 +229,10 @@ type Schedule struct {
 	EndTime   time.Time `json:"endTime,omitempty"`
 }
 
+// Clock returns the current time; the scheduler reads the time from one so
+// schedules can be tested without waiting
+type Clock func() time.Time
+
 // Trigger represents an event trigger
 type Trigger struct {
 	Type     string                 `json:"type"`
@@ -143,11 +242,7 @@ type Trigger struct {
 }
 
 // RetryConfig represents retry configuration
-type RetryConfig struct {
-	MaxRetries int           `json:"maxRetries"`
-	Interval   time.Duration `json:"interval"`
-	Backoff    string        `json:"backoff,omitempty"`
-}
+type RetryConfig = actions.RetryPolicy
 
 // RuleStatus represents the status of an automation rule
 type RuleStatus struct {
@@ -164,6 +259,10 @@ type RuleStatus struct {
 	FailureCount   int64                  `json:"failureCount"`
 	LastError      string                 `json:"lastError,omitempty"`
 	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+
+	// Conditions are the duration qualified conditions of the rule that
+	// currently hold, with since when, for each subject
+	Conditions []*types.ConditionState `json:"conditions,omitempty"`
 }
 
 // RuleExecutionStatus represents the execution status of a rule
@@ -178,26 +277,10 @@ const (
 )
 
 // Event represents an automation event
-type Event struct {
-	ID        string                 `json:"id"`
-	Type      string                 `json:"type"`
-	Source    string                 `json:"source"`
-	Data      map[string]interface{} `json:"data"`
-	Timestamp time.Time              `json:"timestamp"`
-	Metadata  map[string]interface{} `json:"metadata,omitempty"`
-}
+type Event = events.Event
 
 // EventHandler defines the interface for handling events
-type EventHandler interface {
-	// HandleEvent handles an automation event
-	HandleEvent(ctx context.Context, event *Event) error
-
-	// CanHandle checks if this handler can handle the event type
-	CanHandle(eventType string) bool
-
-	// Health checks the health of the event handler
-	Health(ctx context.Context) error
-}
+type EventHandler = events.Handler
 
 // RuleExecutor defines the interface for executing automation rules
 type RuleExecutor interface {
@@ -224,16 +307,7 @@ type ExecutionResult struct {
 }
 
 // ActionResult represents the result of an action execution
-type ActionResult struct {
-	ActionType string                 `json:"actionType"`
-	Success    bool                   `json:"success"`
-	Message    string                 `json:"message"`
-	Duration   time.Duration          `json:"duration"`
-	Timestamp  time.Time              `json:"timestamp"`
-	Data       map[string]interface{} `json:"data,omitempty"`
-	Error      string                 `json:"error,omitempty"`
-	RetryCount int                    `json:"retryCount"`
-}
+type ActionResult = actions.Result
 
 // ConditionEvaluator defines the interface for evaluating conditions
 type ConditionEvaluator interface {
@@ -243,22 +317,24 @@ type ConditionEvaluator interface {
 	// EvaluateConditions evaluates multiple conditions
 	EvaluateConditions(ctx context.Context, conditions []*Condition, context map[string]interface{}) (bool, error)
 
-	// Health checks the health of the condition evaluator
-	Health(ctx context.Context) error
-}
+	// EvaluateExpression evaluates a boolean expression against context
+	EvaluateExpression(ctx context.Context, expression string, context map[string]interface{}) (bool, error)
 
-// ActionExecutor defines the interface for executing actions
-type ActionExecutor interface {
-	// ExecuteAction executes an action
-	ExecuteAction(ctx context.Context, action *Action) (*ActionResult, error)
+	// ValidateExpression checks the syntax of an expression
+	ValidateExpression(ctx context.Context, expression string) error
 
-	// CanExecute checks if this executor can handle the action type
-	CanExecute(actionType string) bool
+	// EvaluateRule evaluates the conditions of a rule. A condition with a
+	// duration is only met once it has held that long for the subject of
+	// the context.
+	EvaluateRule(ctx context.Context, rule *AutomationRule, context map[string]interface{}) (bool, error)
 
-	// Health checks the health of the action executor
+	// Health checks the health of the condition evaluator
 	Health(ctx context.Context) error
 }
 
+// ActionExecutor executes the actions of the types it can handle
+type ActionExecutor = actions.Executor
+
 // Scheduler defines the interface for scheduling automation rules
 type Scheduler interface {
 	// ScheduleRule schedules a rule for execution
@@ -270,44 +346,55 @@ type Scheduler interface {
 	// GetScheduledRules returns all scheduled rules
 	GetScheduledRules(ctx context.Context) ([]*AutomationRule, error)
 
+	// DueRules returns the scheduled rules due to run and records that
+	// they ran
+	DueRules(ctx context.Context) []*AutomationRule
+
+	// NextExecution returns when a scheduled rule runs next, or nil if it
+	// is not scheduled or its schedule has ended
+	NextExecution(ruleID string) *time.Time
+
 	// Health checks the health of the scheduler
 	Health(ctx context.Context) error
 }
 
 // Common automation event types
 const (
-	EventTypeWorkloadCreated   = "workload.created"
-	EventTypeWorkloadUpdated   = "workload.updated"
-	EventTypeWorkloadDeleted   = "workload.deleted"
-	EventTypeWorkloadCompleted = "workload.completed"
-	EventTypeWorkloadFailed    = "workload.failed"
-
-	EventTypePolicyCreated = "policy.created"
-	EventTypePolicyUpdated = "policy.updated"
-	EventTypePolicyDeleted = "policy.deleted"
-
-	EventTypeDecisionCreated   = "decision.created"
-	EventTypeDecisionCompleted = "decision.completed"
-	EventTypeDecisionFailed    = "decision.failed"
-
-	EventTypeSchedule = "schedule"
-	EventTypeManual   = "manual"
+	EventTypeWorkloadCreated   = events.EventTypeWorkloadCreated
+	EventTypeWorkloadUpdated   = events.EventTypeWorkloadUpdated
+	EventTypeWorkloadDeleted   = events.EventTypeWorkloadDeleted
+	EventTypeWorkloadCompleted = events.EventTypeWorkloadCompleted
+	EventTypeWorkloadFailed    = events.EventTypeWorkloadFailed
+
+	EventTypePolicyCreated = events.EventTypePolicyCreated
+	EventTypePolicyUpdated = events.EventTypePolicyUpdated
+	EventTypePolicyDeleted = events.EventTypePolicyDeleted
+
+	EventTypeDecisionCreated   = events.EventTypeDecisionCreated
+	EventTypeDecisionUpdated   = events.EventTypeDecisionUpdated
+	EventTypeDecisionDeleted   = events.EventTypeDecisionDeleted
+	EventTypeDecisionCompleted = events.EventTypeDecisionCompleted
+	EventTypeDecisionFailed    = events.EventTypeDecisionFailed
+
+	EventTypeSchedule = events.EventTypeSchedule
+	EventTypeManual   = events.EventTypeManual
 )
 
 // Common automation action types
 const (
-	ActionTypeNotify     = "notify"
-	ActionTypeScale      = "scale"
-	ActionTypeMigrate    = "migrate"
-	ActionTypeTerminate  = "terminate"
-	ActionTypeSuspend    = "suspend"
-	ActionTypeResume     = "resume"
-	ActionTypeUpdate     = "update"
-	ActionTypeCreate     = "create"
-	ActionTypeDelete     = "delete"
-	ActionTypeSchedule   = "schedule"
-	ActionTypeReschedule = "reschedule"
-	ActionTypeOptimize   = "optimize"
+	ActionTypeNotify     = actions.ActionTypeNotify
+	ActionTypeScale      = actions.ActionTypeScale
+	ActionTypeMigrate    = actions.ActionTypeMigrate
+	ActionTypeTerminate  = actions.ActionTypeTerminate
+	ActionTypeSuspend    = actions.ActionTypeSuspend
+	ActionTypeResume     = actions.ActionTypeResume
+	ActionTypeUpdate     = actions.ActionTypeUpdate
+	ActionTypeCreate     = actions.ActionTypeCreate
+	ActionTypeDelete     = actions.ActionTypeDelete
+	ActionTypeSchedule   = actions.ActionTypeSchedule
+	ActionTypeReschedule = actions.ActionTypeReschedule
+	ActionTypeOptimize   = actions.ActionTypeOptimize
+	ActionTypeWebhook    = actions.ActionTypeWebhook
 )
 
 // Common condition operators
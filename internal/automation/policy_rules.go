@@ -0,0 +1,313 @@
+package automation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// policyEvents are the events that keep the rules of automation policies
+// in sync with the policies
+const policyEvents = "policy.*"
+
+// policyOperators maps the comparison symbols policies may use to
+// condition operators
+var policyOperators = map[string]string{
+	"==": OperatorEquals,
+	"!=": OperatorNotEquals,
+	">":  OperatorGreaterThan,
+	"<":  OperatorLessThan,
+	">=": OperatorGreaterThanOrEqual,
+	"<=": OperatorLessThanOrEqual,
+}
+
+// policyToRule converts an automation policy to the rule that runs it. The
+// rule takes the policy's name as its ID and is enabled while the policy
+// is active.
+func policyToRule(policy types.Policy) (*AutomationRule, error) {
+	automationPolicy, ok := policy.(*types.AutomationRulePolicy)
+	if !ok {
+		return nil, fmt.Errorf("expected *types.AutomationRulePolicy, got %T", policy)
+	}
+	metadata := automationPolicy.Metadata
+	spec := automationPolicy.Spec
+
+	rule := &AutomationRule{
+		ID:          metadata.Name,
+		Name:        metadata.Name,
+		Type:        string(types.PolicyTypeAutomation),
+		Description: metadata.Annotations["description"],
+		Enabled:     automationPolicy.Status == types.PolicyStatusActive,
+		Priority:    int(spec.Priority),
+		Conditions:  make([]*Condition, 0, len(spec.Conditions)),
+		Actions:     make([]*Action, 0, len(spec.Actions)),
+		Metadata:    convertMapStringToString(metadata.Labels),
+		CreatedAt:   metadata.CreationTimestamp,
+		UpdatedAt:   metadata.LastModified,
+	}
+	rule.Metadata[RuleMetadataPolicy] = metadata.Name
+
+	for i, policyCondition := range spec.Conditions {
+		condition, err := convertPolicyCondition(policyCondition)
+		if err != nil {
+			return nil, fmt.Errorf("condition %d: %w", i, err)
+		}
+		rule.Conditions = append(rule.Conditions, condition)
+	}
+
+	for i, policyAction := range spec.Actions {
+		action, err := convertPolicyAction(policyAction)
+		if err != nil {
+			return nil, fmt.Errorf("action %d: %w", i, err)
+		}
+		rule.Actions = append(rule.Actions, action)
+	}
+
+	for i, exception := range spec.Exceptions {
+		if exception.Condition == "" {
+			return nil, fmt.Errorf("exception %d: condition cannot be empty", i)
+		}
+		rule.Exceptions = append(rule.Exceptions, &Exception{
+			Condition: exception.Condition,
+			Reason:    exception.Reason,
+		})
+	}
+
+	if spec.Schedule != nil {
+		rule.Schedule = &Schedule{
+			Cron:     spec.Schedule.Cron,
+			Interval: spec.Schedule.Interval,
+			Timezone: spec.Schedule.Timezone,
+		}
+	}
+
+	return rule, nil
+}
+
+// convertPolicyCondition converts a policy condition and the conditions
+// nested in it, accepting comparison symbols for operators
+func convertPolicyCondition(policyCondition types.AutomationCondition) (*Condition, error) {
+	condition := &Condition{
+		Field:      policyCondition.Field,
+		Operator:   policyCondition.Operator,
+		Value:      policyCondition.Value,
+		Expression: policyCondition.Expression,
+	}
+	if operator, exists := policyOperators[condition.Operator]; exists {
+		condition.Operator = operator
+	}
+
+	var err error
+	if policyCondition.All != nil {
+		if condition.All, err = convertPolicyConditions(ConditionKindAll, policyCondition.All); err != nil {
+			return nil, err
+		}
+	}
+	if policyCondition.Any != nil {
+		if condition.Any, err = convertPolicyConditions(ConditionKindAny, policyCondition.Any); err != nil {
+			return nil, err
+		}
+	}
+	if policyCondition.Not != nil {
+		if condition.Not, err = convertPolicyCondition(*policyCondition.Not); err != nil {
+			return nil, fmt.Errorf("not: %w", err)
+		}
+	}
+
+	if policyCondition.Duration != nil {
+		duration, err := time.ParseDuration(*policyCondition.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration %q: %w", *policyCondition.Duration, err)
+		}
+		condition.Duration = &duration
+	}
+
+	return condition, nil
+}
+
+// convertPolicyConditions converts the conditions of an all or any group
+func convertPolicyConditions(kind string, policyConditions []types.AutomationCondition) ([]*Condition, error) {
+	conditions := make([]*Condition, 0, len(policyConditions))
+	for i, policyCondition := range policyConditions {
+		condition, err := convertPolicyCondition(policyCondition)
+		if err != nil {
+			return nil, fmt.Errorf("%s condition %d: %w", kind, i, err)
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+// convertPolicyAction converts a policy action. Its message becomes the
+// message parameter, and its grace period and confirmation go to the
+// action metadata the rule executor reads them from.
+func convertPolicyAction(policyAction types.AutomationAction) (*Action, error) {
+	action := &Action{
+		Type:       policyAction.Type,
+		Target:     policyAction.Target,
+		Parameters: make(map[string]interface{}, len(policyAction.Parameters)+1),
+		Metadata:   make(map[string]interface{}),
+	}
+	for key, value := range policyAction.Parameters {
+		action.Parameters[key] = value
+	}
+	if _, exists := action.Parameters["message"]; !exists && policyAction.Message != "" {
+		action.Parameters["message"] = policyAction.Message
+	}
+
+	if policyAction.GracePeriod != nil {
+		action.Metadata[ActionMetadataGracePeriod] = *policyAction.GracePeriod
+		if _, err := actionGracePeriod(action); err != nil {
+			return nil, err
+		}
+	}
+	if policyAction.ConfirmWith != nil {
+		action.Metadata[ActionMetadataConfirmWith] = *policyAction.ConfirmWith
+	}
+
+	return action, nil
+}
+
+// syncPolicy updates the rule of an automation policy that was created,
+// updated or deleted
+func (ae *automationEngine) syncPolicy(ctx context.Context, event *Event) {
+	if event.Data["policyType"] != string(types.PolicyTypeAutomation) {
+		return
+	}
+	name, _ := event.Data["policyName"].(string)
+	if name == "" {
+		return
+	}
+
+	if event.Type == EventTypePolicyDeleted {
+		ae.removePolicyRule(ctx, name)
+		return
+	}
+
+	policy, err := ae.storage.Policy().GetByName(ctx, name)
+	if err != nil {
+		ae.logger.WithError(err).WithPolicy(name, "").Warn("failed to get automation policy")
+		return
+	}
+
+	rule, err := ae.convertPolicy(ctx, policy)
+	if err == nil {
+		err = ae.installPolicyRule(ctx, rule)
+	} else {
+		// A policy that no longer converts stops running its old rule
+		ae.removePolicyRule(ctx, name)
+	}
+	ae.recordPolicyConversion(ctx, policy, err)
+}
+
+// convertPolicy converts a policy to a rule and validates the rule
+func (ae *automationEngine) convertPolicy(ctx context.Context, policy types.Policy) (*AutomationRule, error) {
+	rule, err := policyToRule(policy)
+	if err != nil {
+		return nil, err
+	}
+	if err := ae.ruleExecutor.ValidateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// installPolicyRule adds or replaces the rule of a policy
+func (ae *automationEngine) installPolicyRule(ctx context.Context, rule *AutomationRule) error {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+
+	if existing, exists := ae.rules[rule.ID]; exists && !fromPolicy(existing) {
+		return fmt.Errorf("rule %s already exists and was not created from a policy", rule.ID)
+	}
+	if err := ae.reschedule(ctx, rule); err != nil {
+		return err
+	}
+
+	ae.rules[rule.ID] = rule
+	if status, exists := ae.ruleStatuses[rule.ID]; exists {
+		status.LastUpdated = time.Now()
+	} else {
+		ae.ruleStatuses[rule.ID] = newRuleStatus(rule)
+	}
+	if rule.Enabled {
+		ae.pruneConditionStates(ctx, rule.ID, rule.Conditions)
+	} else {
+		ae.pruneConditionStates(ctx, rule.ID, nil)
+	}
+
+	ae.logger.Info("synced automation policy rule", "rule_id", rule.ID, "enabled", rule.Enabled)
+	return nil
+}
+
+// removePolicyRule removes the rule of a policy, leaving rules that were
+// not created from a policy alone
+func (ae *automationEngine) removePolicyRule(ctx context.Context, name string) {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+
+	rule, exists := ae.rules[name]
+	if !exists || !fromPolicy(rule) {
+		return
+	}
+
+	// The rule may never have been scheduled
+	_ = ae.scheduler.UnscheduleRule(ctx, rule.ID)
+	delete(ae.eventHandlers, rule.ID)
+	delete(ae.rules, rule.ID)
+	delete(ae.ruleStatuses, rule.ID)
+	ae.pruneConditionStates(ctx, rule.ID, nil)
+
+	ae.logger.Info("removed automation policy rule", "rule_id", rule.ID)
+}
+
+// recordPolicyConversion records on an automation policy why it could not
+// be converted, or clears the message once it can. Policies whose message
+// is unchanged are not written, so the update this publishes settles.
+func (ae *automationEngine) recordPolicyConversion(ctx context.Context, policy types.Policy, conversionErr error) {
+	automationPolicy, ok := policy.(*types.AutomationRulePolicy)
+	if !ok {
+		return
+	}
+
+	message := ""
+	if conversionErr != nil {
+		message = fmt.Sprintf("cannot run automation policy: %v", conversionErr)
+		ae.logger.WithError(conversionErr).WithPolicy(automationPolicy.Metadata.Name, "").Warn("failed to convert automation policy")
+	}
+	if automationPolicy.StatusMessage == message {
+		return
+	}
+
+	automationPolicy.StatusMessage = message
+	if err := ae.storage.Policy().Update(ctx, automationPolicy); err != nil {
+		ae.logger.WithError(err).WithPolicy(automationPolicy.Metadata.Name, "").Warn("failed to record automation policy status")
+	}
+}
+
+// fromPolicy returns true if a rule was converted from a policy
+func fromPolicy(rule *AutomationRule) bool {
+	_, exists := rule.Metadata[RuleMetadataPolicy]
+	return exists
+}
+
+// newRuleStatus creates the status of a rule that has not run yet
+func newRuleStatus(rule *AutomationRule) *RuleStatus {
+	return &RuleStatus{
+		RuleID:   rule.ID,
+		Name:     rule.Name,
+		Status:   RuleStatusActive,
+		Metadata: make(map[string]interface{}),
+	}
+}
+
+// convertMapStringToString converts map[string]string to map[string]interface{}
+func convertMapStringToString(m map[string]string) map[string]interface{} {
+	result := make(map[string]interface{})
+	for k, v := range m {
+		result[k] = v
+	}
+	return result
+}
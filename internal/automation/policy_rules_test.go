@@ -0,0 +1,231 @@
+package automation
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/actions"
+	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/events"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// stubActionExecutor records the actions it executes
+type stubActionExecutor struct {
+	actionTypes []string
+	mu          sync.Mutex
+	executed    []string
+}
+
+func (s *stubActionExecutor) CanExecute(actionType string) bool {
+	for _, supported := range s.actionTypes {
+		if supported == actionType {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *stubActionExecutor) Execute(ctx context.Context, action *Action) (*ActionResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.executed = append(s.executed, action.Type)
+	return &ActionResult{ActionType: action.Type, Success: true, Timestamp: time.Now()}, nil
+}
+
+func (s *stubActionExecutor) Validate(action *Action) error { return nil }
+
+func (s *stubActionExecutor) Health(ctx context.Context) error { return nil }
+
+func (s *stubActionExecutor) actions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.executed...)
+}
+
+func newRuleExecutor(t *testing.T, store *stubActionExecutor) RuleExecutor {
+	registry := actions.NewRegistry(testLogger{})
+	require.NoError(t, registry.Register(store))
+	ce := NewConditionEvaluator(memory.NewStorageManager().ConditionState(), evaluator.NewRuleEngine(testLogger{}), testLogger{})
+	return NewRuleExecutor(ce, registry, testLogger{})
+}
+
+func stringPtr(s string) *string { return &s }
+
+func newIdleClusterPolicy() *types.AutomationRulePolicy {
+	return &types.AutomationRulePolicy{
+		Kind: types.PolicyTypeAutomation,
+		Metadata: types.PolicyMetadata{
+			Name:        "idle-cluster-cleanup",
+			Labels:      map[string]string{"team": "ops"},
+			Annotations: map[string]string{"description": "Clean up idle clusters"},
+		},
+		Status: types.PolicyStatusActive,
+		Spec: types.AutomationRuleSpec{
+			Priority: 50,
+			Conditions: []types.AutomationCondition{
+				{Field: "cluster.utilization", Operator: "<", Value: 10, Duration: stringPtr("2h")},
+				{Field: "cluster.workload_count", Operator: OperatorEquals, Value: 0},
+			},
+			Actions: []types.AutomationAction{
+				{Type: ActionTypeNotify, Target: "operations-team", Message: "Idle cluster detected"},
+				{Type: ActionTypeDelete, Target: "cluster", GracePeriod: stringPtr("1h"), ConfirmWith: stringPtr("actions.notify.success")},
+			},
+			Exceptions: []types.Exception{
+				{Condition: `cluster.labels.persistent == "true"`, Reason: "persistent cluster"},
+			},
+			Schedule: &types.Schedule{Cron: "*/15 * * * *", Timezone: "Asia/Seoul"},
+		},
+	}
+}
+
+func TestPolicyToRule(t *testing.T) {
+	rule, err := policyToRule(newIdleClusterPolicy())
+	require.NoError(t, err)
+
+	assert.Equal(t, "idle-cluster-cleanup", rule.ID)
+	assert.Equal(t, "Clean up idle clusters", rule.Description)
+	assert.True(t, rule.Enabled)
+	assert.Equal(t, 50, rule.Priority)
+	assert.Equal(t, "ops", rule.Metadata["team"])
+	assert.Equal(t, "idle-cluster-cleanup", rule.Metadata[RuleMetadataPolicy])
+
+	require.Len(t, rule.Conditions, 2)
+	assert.Equal(t, OperatorLessThan, rule.Conditions[0].Operator)
+	require.NotNil(t, rule.Conditions[0].Duration)
+	assert.Equal(t, 2*time.Hour, *rule.Conditions[0].Duration)
+	assert.Nil(t, rule.Conditions[1].Duration)
+
+	require.Len(t, rule.Actions, 2)
+	assert.Equal(t, "Idle cluster detected", rule.Actions[0].Parameters["message"])
+	assert.Equal(t, "1h", rule.Actions[1].Metadata[ActionMetadataGracePeriod])
+	assert.Equal(t, "actions.notify.success", rule.Actions[1].Metadata[ActionMetadataConfirmWith])
+
+	require.Len(t, rule.Exceptions, 1)
+	assert.Equal(t, "persistent cluster", rule.Exceptions[0].Reason)
+	assert.Equal(t, &Schedule{Cron: "*/15 * * * *", Timezone: "Asia/Seoul"}, rule.Schedule)
+
+	policy := newIdleClusterPolicy()
+	policy.Spec.Conditions = []types.AutomationCondition{{Any: []types.AutomationCondition{
+		{All: []types.AutomationCondition{
+			{Field: "metrics.gpu_util", Operator: "<", Value: 10},
+			{Expression: "age > 3600", Duration: stringPtr("1h")},
+		}},
+		{Not: &types.AutomationCondition{Field: "labels.idle", Operator: "!=", Value: "true"}},
+	}}}
+	rule, err = policyToRule(policy)
+	require.NoError(t, err)
+	require.Len(t, rule.Conditions, 1)
+	assert.Equal(t, "any(all(metrics.gpu_util less_than 10, expression(age > 3600)), not(labels.idle not_equals true))",
+		rule.Conditions[0].String())
+	assert.Equal(t, time.Hour, *rule.Conditions[0].Any[0].All[1].Duration)
+
+	policy = newIdleClusterPolicy()
+	policy.Spec.Conditions[0].Duration = stringPtr("two hours")
+	_, err = policyToRule(policy)
+	assert.ErrorContains(t, err, "condition 0")
+
+	policy = newIdleClusterPolicy()
+	policy.Spec.Conditions = []types.AutomationCondition{{All: []types.AutomationCondition{
+		{Field: "cluster.utilization", Operator: "<", Value: 10, Duration: stringPtr("soon")},
+	}}}
+	_, err = policyToRule(policy)
+	assert.ErrorContains(t, err, "all condition 0")
+
+	policy = newIdleClusterPolicy()
+	policy.Spec.Actions[1].GracePeriod = stringPtr("-1h")
+	_, err = policyToRule(policy)
+	assert.ErrorContains(t, err, "action 1")
+}
+
+func TestRuleExecutor_ExceptionsAndConfirmations(t *testing.T) {
+	ctx := context.Background()
+	stub := &stubActionExecutor{actionTypes: []string{ActionTypeNotify, ActionTypeDelete}}
+	executor := newRuleExecutor(t, stub)
+
+	rule, err := policyToRule(newIdleClusterPolicy())
+	require.NoError(t, err)
+	rule.Conditions[0].Duration = nil
+	rule.Actions[1].Metadata[ActionMetadataGracePeriod] = "10ms"
+	require.NoError(t, executor.ValidateRule(ctx, rule))
+
+	cluster := func(persistent string) map[string]interface{} {
+		return map[string]interface{}{"cluster": map[string]interface{}{
+			"utilization":    3,
+			"workload_count": 0,
+			"labels":         map[string]interface{}{"persistent": persistent},
+		}}
+	}
+
+	result, err := executor.ExecuteRule(ctx, rule, cluster("true"))
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Contains(t, result.Message, "persistent cluster")
+	assert.Empty(t, stub.actions())
+
+	started := time.Now()
+	result, err = executor.ExecuteRule(ctx, rule, cluster("false"))
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.GreaterOrEqual(t, time.Since(started), 10*time.Millisecond)
+	assert.Equal(t, []string{ActionTypeNotify, ActionTypeDelete}, stub.actions())
+
+	// An unconfirmed action is skipped
+	rule.Actions[1].Metadata[ActionMetadataConfirmWith] = "cluster.utilization > 50"
+	result, err = executor.ExecuteRule(ctx, rule, cluster("false"))
+	require.NoError(t, err)
+	require.Len(t, result.Actions, 2)
+	assert.Equal(t, true, result.Actions[1].Data["skipped"])
+	assert.Len(t, stub.actions(), 3)
+}
+
+func TestAutomationEngine_SyncsAutomationPolicies(t *testing.T) {
+	ctx := context.Background()
+	bus := events.NewBus(testLogger{})
+	defer bus.Close()
+	store := events.NewPublishingStorage(memory.NewStorageManager(), bus)
+
+	stub := &stubActionExecutor{actionTypes: []string{ActionTypeNotify, ActionTypeDelete}}
+	engine := NewAutomationEngine(store, newRuleExecutor(t, stub), NewConditionEvaluator(store.ConditionState(), nil, testLogger{}),
+		NewScheduler(testLogger{}), bus, testLogger{})
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	policy := newIdleClusterPolicy()
+	require.NoError(t, store.Policy().Create(ctx, policy))
+	require.Eventually(t, func() bool {
+		rule, err := engine.GetRule(ctx, "idle-cluster-cleanup")
+		return err == nil && len(rule.Actions) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	// A policy that no longer converts stops its rule and says why
+	policy.Spec.Actions[1].Type = "mark_for_deletion"
+	require.NoError(t, store.Policy().Update(ctx, policy))
+	require.Eventually(t, func() bool {
+		_, err := engine.GetRule(ctx, "idle-cluster-cleanup")
+		return err != nil
+	}, time.Second, 5*time.Millisecond)
+	stored, err := store.Policy().GetByName(ctx, "idle-cluster-cleanup")
+	require.NoError(t, err)
+	assert.Contains(t, stored.(*types.AutomationRulePolicy).StatusMessage, "mark_for_deletion")
+
+	policy.Spec.Actions = policy.Spec.Actions[:1]
+	require.NoError(t, store.Policy().Update(ctx, policy))
+	require.Eventually(t, func() bool {
+		rule, err := engine.GetRule(ctx, "idle-cluster-cleanup")
+		return err == nil && len(rule.Actions) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Empty(t, policy.StatusMessage)
+
+	require.NoError(t, store.Policy().Delete(ctx, "AutomationRule-idle-cluster-cleanup"))
+	require.Eventually(t, func() bool {
+		_, err := engine.GetRule(ctx, "idle-cluster-cleanup")
+		return err != nil
+	}, time.Second, 5*time.Millisecond)
+}
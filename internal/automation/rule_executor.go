@@ -3,57 +3,29 @@ package automation
 import (
 	"context"
 	"fmt"
-	"sync"
 	"time"
 
+	"github.com/kcloud-opt/policy/internal/actions"
 	"github.com/kcloud-opt/policy/internal/types"
 )
 
 // ruleExecutor implements RuleExecutor interface
 type ruleExecutor struct {
 	conditionEvaluator ConditionEvaluator
-	actionExecutors    map[string]ActionExecutor
-	mu                 sync.RWMutex
+	registry           actions.Registry
 	logger             types.Logger
 }
 
-// NewRuleExecutor creates a new rule executor
-func NewRuleExecutor(conditionEvaluator ConditionEvaluator, logger types.Logger) RuleExecutor {
+// NewRuleExecutor creates a new rule executor dispatching actions through
+// the given registry
+func NewRuleExecutor(conditionEvaluator ConditionEvaluator, registry actions.Registry, logger types.Logger) RuleExecutor {
 	return &ruleExecutor{
 		conditionEvaluator: conditionEvaluator,
-		actionExecutors:    make(map[string]ActionExecutor),
+		registry:           registry,
 		logger:             logger,
 	}
 }
 
-// RegisterActionExecutor registers an action executor
-func (re *ruleExecutor) RegisterActionExecutor(executor ActionExecutor) {
-	re.mu.Lock()
-	defer re.mu.Unlock()
-
-	// Register for all action types this executor can handle
-	actionTypes := []string{
-		ActionTypeNotify,
-		ActionTypeScale,
-		ActionTypeMigrate,
-		ActionTypeTerminate,
-		ActionTypeSuspend,
-		ActionTypeResume,
-		ActionTypeUpdate,
-		ActionTypeCreate,
-		ActionTypeDelete,
-		ActionTypeSchedule,
-		ActionTypeReschedule,
-		ActionTypeOptimize,
-	}
-
-	for _, actionType := range actionTypes {
-		if executor.CanExecute(actionType) {
-			re.actionExecutors[actionType] = executor
-		}
-	}
-}
-
 // ExecuteRule executes an automation rule
 func (re *ruleExecutor) ExecuteRule(ctx context.Context, rule *AutomationRule, contextData map[string]interface{}) (*ExecutionResult, error) {
 	startTime := time.Now()
@@ -71,7 +43,7 @@ func (re *ruleExecutor) ExecuteRule(ctx context.Context, rule *AutomationRule, c
 	}
 
 	// Evaluate conditions
-	conditionsMet, err := re.conditionEvaluator.EvaluateConditions(ctx, rule.Conditions, contextData)
+	conditionsMet, err := re.conditionEvaluator.EvaluateRule(ctx, rule, contextData)
 	if err != nil {
 		result.Error = fmt.Sprintf("condition evaluation failed: %v", err)
 		result.Duration = time.Since(startTime)
@@ -86,6 +58,22 @@ func (re *ruleExecutor) ExecuteRule(ctx context.Context, rule *AutomationRule, c
 		return result, nil
 	}
 
+	// Exceptions keep a rule whose conditions are met from running
+	exception, err := re.matchException(ctx, rule, contextData)
+	if err != nil {
+		result.Error = fmt.Sprintf("exception evaluation failed: %v", err)
+		result.Duration = time.Since(startTime)
+		return result, nil
+	}
+	if exception != nil {
+		result.Success = true
+		result.Message = fmt.Sprintf("Rule skipped by exception: %s", exceptionReason(exception))
+		result.Metadata["exception"] = exception.Condition
+		result.Duration = time.Since(startTime)
+		re.logger.Info("rule skipped by exception", "rule_id", rule.ID, "exception", exception.Condition)
+		return result, nil
+	}
+
 	re.logger.Info("rule conditions met, executing actions", "rule_id", rule.ID, "actions_count", len(rule.Actions))
 
 	// Execute actions
@@ -93,7 +81,7 @@ func (re *ruleExecutor) ExecuteRule(ctx context.Context, rule *AutomationRule, c
 	for i, action := range rule.Actions {
 		re.logger.Debug("executing action", "rule_id", rule.ID, "action_index", i, "action_type", action.Type)
 
-		actionResult, err := re.executeAction(ctx, action, contextData)
+		actionResult, err := re.runAction(ctx, action, contextData, actionResults)
 		if err != nil {
 			re.logger.WithError(err).Error("action execution failed", "rule_id", rule.ID, "action_index", i)
 
@@ -164,21 +152,28 @@ func (re *ruleExecutor) ValidateRule(ctx context.Context, rule *AutomationRule)
 
 	// Validate conditions
 	for i, condition := range rule.Conditions {
-		if err := re.validateCondition(condition); err != nil {
+		if err := re.validateCondition(ctx, condition); err != nil {
 			return fmt.Errorf("condition %d validation failed: %w", i, err)
 		}
 	}
 
 	// Validate actions
 	for i, action := range rule.Actions {
-		if err := re.validateAction(action); err != nil {
+		if err := re.validateAction(ctx, action); err != nil {
 			return fmt.Errorf("action %d validation failed: %w", i, err)
 		}
 	}
 
+	// Validate exceptions
+	for i, exception := range rule.Exceptions {
+		if err := re.conditionEvaluator.ValidateExpression(ctx, exception.Condition); err != nil {
+			return fmt.Errorf("exception %d validation failed: %w", i, err)
+		}
+	}
+
 	// Validate schedule if present
 	if rule.Schedule != nil {
-		if err := re.validateSchedule(rule.Schedule); err != nil {
+		if err := rule.Schedule.Validate(); err != nil {
 			return fmt.Errorf("schedule validation failed: %w", err)
 		}
 	}
@@ -194,13 +189,8 @@ func (re *ruleExecutor) Health(ctx context.Context) error {
 	}
 
 	// Check action executors health
-	re.mu.RLock()
-	defer re.mu.RUnlock()
-
-	for actionType, executor := range re.actionExecutors {
-		if err := executor.Health(ctx); err != nil {
-			return fmt.Errorf("action executor health check failed for type %s: %w", actionType, err)
-		}
+	if err := re.registry.Health(ctx); err != nil {
+		return fmt.Errorf("action registry health check failed: %w", err)
 	}
 
 	return nil
@@ -208,106 +198,111 @@ func (re *ruleExecutor) Health(ctx context.Context) error {
 
 // Helper methods
 
-// executeAction executes a single action
-func (re *ruleExecutor) executeAction(ctx context.Context, action *Action, contextData map[string]interface{}) (*ActionResult, error) {
-	startTime := time.Now()
-
-	re.mu.RLock()
-	executor, exists := re.actionExecutors[action.Type]
-	re.mu.RUnlock()
+// executeAction executes a single action through the action registry,
+// which applies its timeout and retry policy
+func (re *ruleExecutor) executeAction(ctx context.Context, action *Action) (*ActionResult, error) {
+	return re.registry.Execute(ctx, action)
+}
 
-	if !exists {
-		return &ActionResult{
-			ActionType: action.Type,
-			Success:    false,
-			Message:    fmt.Sprintf("No executor found for action type: %s", action.Type),
-			Duration:   time.Since(startTime),
-			Timestamp:  time.Now(),
-			Error:      fmt.Sprintf("executor not found for action type %s", action.Type),
-		}, nil
+// runAction waits out the grace period of an action, checks that its
+// confirmation holds and executes it. An action that is not confirmed is
+// skipped rather than failed.
+func (re *ruleExecutor) runAction(ctx context.Context, action *Action, contextData map[string]interface{}, earlier []*ActionResult) (*ActionResult, error) {
+	gracePeriod, err := actionGracePeriod(action)
+	if err != nil {
+		return nil, err
 	}
-
-	// Execute with timeout if specified
-	if action.Timeout != nil {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, *action.Timeout)
-		defer cancel()
+	if gracePeriod > 0 {
+		re.logger.Debug("waiting for action grace period", "action_type", action.Type, "grace_period", gracePeriod)
+		timer := time.NewTimer(gracePeriod)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("grace period of action %s interrupted: %w", action.Type, ctx.Err())
+		case <-timer.C:
+		}
 	}
 
-	// Execute action with retry if configured
-	if action.Retry != nil {
-		return re.executeActionWithRetry(ctx, executor, action, contextData)
+	confirmWith, _ := action.Metadata[ActionMetadataConfirmWith].(string)
+	if confirmWith != "" {
+		confirmed, err := re.conditionEvaluator.EvaluateExpression(ctx, confirmWith, confirmationContext(contextData, earlier))
+		if err != nil {
+			return nil, fmt.Errorf("failed to confirm action %s: %w", action.Type, err)
+		}
+		if !confirmed {
+			re.logger.Info("action not confirmed, skipping", "action_type", action.Type, "confirm_with", confirmWith)
+			return &ActionResult{
+				ActionType: action.Type,
+				Success:    true,
+				Message:    fmt.Sprintf("Action skipped, not confirmed by %s", confirmWith),
+				Data:       map[string]interface{}{"skipped": true},
+				Timestamp:  time.Now(),
+			}, nil
+		}
 	}
 
-	return executor.ExecuteAction(ctx, action)
+	return re.executeAction(ctx, action)
 }
 
-// executeActionWithRetry executes an action with retry logic
-func (re *ruleExecutor) executeActionWithRetry(ctx context.Context, executor ActionExecutor, action *Action, contextData map[string]interface{}) (*ActionResult, error) {
-	retryConfig := action.Retry
-	var lastResult *ActionResult
-	var lastErr error
-
-	for attempt := 0; attempt <= retryConfig.MaxRetries; attempt++ {
-		result, err := executor.ExecuteAction(ctx, action)
-		lastResult = result
-		lastErr = err
-
-		// If successful, return result
-		if err == nil && result != nil && result.Success {
-			result.RetryCount = attempt
-			return result, nil
-		}
-
-		// If this is the last attempt, don't sleep
-		if attempt == retryConfig.MaxRetries {
-			break
+// matchException returns the first exception of a rule that holds
+func (re *ruleExecutor) matchException(ctx context.Context, rule *AutomationRule, contextData map[string]interface{}) (*Exception, error) {
+	for i, exception := range rule.Exceptions {
+		holds, err := re.conditionEvaluator.EvaluateExpression(ctx, exception.Condition, contextData)
+		if err != nil {
+			return nil, fmt.Errorf("exception %d: %w", i, err)
 		}
-
-		// Calculate backoff delay
-		delay := re.calculateBackoffDelay(retryConfig, attempt)
-
-		re.logger.Warn("action execution failed, retrying",
-			"action_type", action.Type,
-			"attempt", attempt+1,
-			"max_retries", retryConfig.MaxRetries,
-			"delay", delay,
-			"error", err)
-
-		// Sleep for the calculated delay
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-time.After(delay):
-			// Continue to next attempt
+		if holds {
+			return exception, nil
 		}
 	}
+	return nil, nil
+}
 
-	// All retries exhausted
-	if lastResult != nil {
-		lastResult.RetryCount = retryConfig.MaxRetries
-		return lastResult, lastErr
+// exceptionReason describes why an exception stopped a rule
+func exceptionReason(exception *Exception) string {
+	if exception.Reason != "" {
+		return exception.Reason
 	}
+	return exception.Condition
+}
 
-	return nil, fmt.Errorf("action execution failed after %d retries: %v", retryConfig.MaxRetries, lastErr)
+// actionGracePeriod reads the grace period of an action from its metadata
+func actionGracePeriod(action *Action) (time.Duration, error) {
+	switch gracePeriod := action.Metadata[ActionMetadataGracePeriod].(type) {
+	case nil:
+		return 0, nil
+	case time.Duration:
+		return gracePeriod, nil
+	case string:
+		duration, err := time.ParseDuration(gracePeriod)
+		if err != nil || duration < 0 {
+			return 0, fmt.Errorf("grace period must be a non-negative duration, got %q", gracePeriod)
+		}
+		return duration, nil
+	default:
+		return 0, fmt.Errorf("grace period must be a duration, got %T", gracePeriod)
+	}
 }
 
-// calculateBackoffDelay calculates the delay for the next retry attempt
-func (re *ruleExecutor) calculateBackoffDelay(retryConfig *RetryConfig, attempt int) time.Duration {
-	baseDelay := retryConfig.Interval
+// confirmationContext is the rule context with the results of the actions
+// run so far under "actions", keyed by action type
+func confirmationContext(contextData map[string]interface{}, earlier []*ActionResult) map[string]interface{} {
+	context := make(map[string]interface{}, len(contextData)+1)
+	for key, value := range contextData {
+		context[key] = value
+	}
 
-	switch retryConfig.Backoff {
-	case "exponential":
-		delay := baseDelay
-		for i := 0; i < attempt; i++ {
-			delay *= 2
+	results := make(map[string]interface{}, len(earlier))
+	for _, result := range earlier {
+		results[result.ActionType] = map[string]interface{}{
+			"success": result.Success,
+			"message": result.Message,
+			"data":    result.Data,
 		}
-		return delay
-	case "linear":
-		return baseDelay * time.Duration(attempt+1)
-	default: // "fixed"
-		return baseDelay
 	}
+	context["actions"] = results
+
+	return context
 }
 
 // shouldStopOnFailure determines if rule execution should stop on action failure
@@ -323,8 +318,53 @@ func (re *ruleExecutor) shouldStopOnFailure(rule *AutomationRule) bool {
 	return false
 }
 
-// validateCondition validates a condition
-func (re *ruleExecutor) validateCondition(condition *Condition) error {
+// validateCondition validates a condition and the conditions nested in it
+func (re *ruleExecutor) validateCondition(ctx context.Context, condition *Condition) error {
+	if condition == nil {
+		return fmt.Errorf("condition cannot be nil")
+	}
+
+	if condition.Duration != nil && *condition.Duration <= 0 {
+		return fmt.Errorf("condition duration must be positive, got %s", *condition.Duration)
+	}
+
+	switch condition.Kind() {
+	case ConditionKindComparison:
+		return re.validateComparison(condition)
+	case ConditionKindExpression:
+		if err := re.conditionEvaluator.ValidateExpression(ctx, condition.Expression); err != nil {
+			return fmt.Errorf("invalid expression: %w", err)
+		}
+		return nil
+	case ConditionKindAll:
+		return re.validateGroup(ctx, ConditionKindAll, condition.All)
+	case ConditionKindAny:
+		return re.validateGroup(ctx, ConditionKindAny, condition.Any)
+	case ConditionKindNot:
+		if err := re.validateCondition(ctx, condition.Not); err != nil {
+			return fmt.Errorf("not: %w", err)
+		}
+		return nil
+	default:
+		return errConditionKind
+	}
+}
+
+// validateGroup validates the conditions of an all or any group
+func (re *ruleExecutor) validateGroup(ctx context.Context, kind string, conditions []*Condition) error {
+	if len(conditions) == 0 {
+		return fmt.Errorf("%s group must have at least one condition", kind)
+	}
+	for i, condition := range conditions {
+		if err := re.validateCondition(ctx, condition); err != nil {
+			return fmt.Errorf("%s condition %d: %w", kind, i, err)
+		}
+	}
+	return nil
+}
+
+// validateComparison validates a comparison of a field with a value
+func (re *ruleExecutor) validateComparison(condition *Condition) error {
 	if condition.Field == "" {
 		return fmt.Errorf("condition field cannot be empty")
 	}
@@ -357,47 +397,22 @@ func (re *ruleExecutor) validateCondition(condition *Condition) error {
 }
 
 // validateAction validates an action
-func (re *ruleExecutor) validateAction(action *Action) error {
+func (re *ruleExecutor) validateAction(ctx context.Context, action *Action) error {
 	if action.Type == "" {
 		return fmt.Errorf("action type cannot be empty")
 	}
 
 	// Check if executor exists for this action type
-	re.mu.RLock()
-	_, exists := re.actionExecutors[action.Type]
-	re.mu.RUnlock()
-
-	if !exists {
+	if _, exists := re.registry.Executor(action.Type); !exists {
 		return fmt.Errorf("no executor found for action type: %s", action.Type)
 	}
 
-	return nil
-}
-
-// validateSchedule validates a schedule
-func (re *ruleExecutor) validateSchedule(schedule *Schedule) error {
-	// Validate cron expression if present
-	if schedule.Cron != "" {
-		// In a real implementation, you would validate the cron expression
-		// For now, we'll do a basic check
-		if len(schedule.Cron) < 5 {
-			return fmt.Errorf("invalid cron expression: %s", schedule.Cron)
-		}
+	if _, err := actionGracePeriod(action); err != nil {
+		return err
 	}
-
-	// Validate interval if present
-	if schedule.Interval != "" {
-		// In a real implementation, you would parse and validate the interval
-		// For now, we'll do a basic check
-		if len(schedule.Interval) < 2 {
-			return fmt.Errorf("invalid interval: %s", schedule.Interval)
-		}
-	}
-
-	// Validate time window
-	if !schedule.StartTime.IsZero() && !schedule.EndTime.IsZero() {
-		if schedule.StartTime.After(schedule.EndTime) {
-			return fmt.Errorf("start time cannot be after end time")
+	if confirmWith, _ := action.Metadata[ActionMetadataConfirmWith].(string); confirmWith != "" {
+		if err := re.conditionEvaluator.ValidateExpression(ctx, confirmWith); err != nil {
+			return fmt.Errorf("invalid confirmation: %w", err)
 		}
 	}
 
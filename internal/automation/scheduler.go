@@ -3,38 +3,78 @@ package automation
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/kcloud-opt/policy/internal/cron"
 	"github.com/kcloud-opt/policy/internal/types"
 )
 
+// scheduledRule is a rule registered with the scheduler and when it ran
+type scheduledRule struct {
+	rule     *AutomationRule
+	cron     *cron.Schedule
+	interval time.Duration
+
+	// since is when the rule was first scheduled; a cron rule that has not
+	// run yet fires at its first activation after it
+	since time.Time
+	last  *time.Time
+}
+
 // scheduler implements Scheduler interface
 type scheduler struct {
-	scheduledRules map[string]*AutomationRule
+	scheduledRules map[string]*scheduledRule
+	clock          Clock
 	mu             sync.RWMutex
 	logger         types.Logger
 }
 
 // NewScheduler creates a new scheduler
 func NewScheduler(logger types.Logger) Scheduler {
+	return NewSchedulerWithClock(time.Now, logger)
+}
+
+// NewSchedulerWithClock creates a new scheduler that reads the time from
+// clock
+func NewSchedulerWithClock(clock Clock, logger types.Logger) Scheduler {
 	return &scheduler{
-		scheduledRules: make(map[string]*AutomationRule),
+		scheduledRules: make(map[string]*scheduledRule),
+		clock:          clock,
 		logger:         logger,
 	}
 }
 
-// ScheduleRule schedules a rule for execution
+// ScheduleRule schedules a rule for execution. Scheduling a rule again
+// replaces its schedule but keeps when it last ran.
 func (s *scheduler) ScheduleRule(ctx context.Context, rule *AutomationRule) error {
+	if rule.Schedule == nil {
+		return fmt.Errorf("rule has no schedule")
+	}
+	if err := rule.Schedule.Validate(); err != nil {
+		return fmt.Errorf("invalid schedule for rule %s: %w", rule.ID, err)
+	}
+
+	entry := &scheduledRule{rule: rule, since: s.clock()}
+	if rule.Schedule.Cron != "" {
+		location, _ := rule.Schedule.location()
+		entry.cron, _ = cron.Parse(rule.Schedule.Cron, location)
+	} else {
+		entry.interval, _ = time.ParseDuration(rule.Schedule.Interval)
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if rule.Schedule == nil {
-		return fmt.Errorf("rule has no schedule")
+	if existing, exists := s.scheduledRules[rule.ID]; exists {
+		entry.since = existing.since
+		entry.last = existing.last
 	}
+	s.scheduledRules[rule.ID] = entry
 
-	s.scheduledRules[rule.ID] = rule
-	s.logger.Info("scheduled automation rule", "rule_id", rule.ID, "rule_name", rule.Name)
+	next, _ := entry.next()
+	s.logger.Info("scheduled automation rule", "rule_id", rule.ID, "rule_name", rule.Name, "next_execution", next)
 
 	return nil
 }
@@ -60,135 +100,151 @@ func (s *scheduler) GetScheduledRules(ctx context.Context) ([]*AutomationRule, e
 	defer s.mu.RUnlock()
 
 	var rules []*AutomationRule
-	for _, rule := range s.scheduledRules {
+	for _, entry := range s.scheduledRules {
 		// Return a copy to avoid modification
-		ruleCopy := *rule
+		ruleCopy := *entry.rule
 		rules = append(rules, &ruleCopy)
 	}
 
 	return rules, nil
 }
 
-// Health checks the health of the scheduler
-func (s *scheduler) Health(ctx context.Context) error {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// DueRules returns the scheduled rules whose next execution has come, in
+// the order they became due, and records that they ran now. A rule that
+// missed several activations runs once.
+func (s *scheduler) DueRules(ctx context.Context) []*AutomationRule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Basic health check - ensure map is accessible
-	_ = len(s.scheduledRules)
+	now := s.clock()
+	type dueRule struct {
+		rule *AutomationRule
+		at   time.Time
+	}
+	var due []dueRule
+	for _, entry := range s.scheduledRules {
+		next, ok := entry.next()
+		if !ok || next.After(now) {
+			continue
+		}
+		ranAt := now
+		entry.last = &ranAt
+		due = append(due, dueRule{rule: entry.rule, at: next})
+	}
 
-	return nil
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].at.Before(due[j].at)
+	})
+	rules := make([]*AutomationRule, len(due))
+	for i, d := range due {
+		rules[i] = d.rule
+	}
+	return rules
 }
 
-// Helper methods
-
-// IsRuleScheduled checks if a rule is scheduled
-func (s *scheduler) IsRuleScheduled(ruleID string) bool {
+// NextExecution returns when a scheduled rule runs next
+func (s *scheduler) NextExecution(ruleID string) *time.Time {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	_, exists := s.scheduledRules[ruleID]
-	return exists
-}
-
-// GetNextExecutionTime calculates the next execution time for a rule
-func (s *scheduler) GetNextExecutionTime(rule *AutomationRule) *time.Time {
-	if rule.Schedule == nil {
+	entry, exists := s.scheduledRules[ruleID]
+	if !exists {
 		return nil
 	}
-
-	now := time.Now()
-
-	// Handle cron-based scheduling
-	if rule.Schedule.Cron != "" {
-		// In a real implementation, you would use a cron parser
-		// For now, we'll use a simple interval-based approach
-		nextExecution := now.Add(30 * time.Second) // Simplified
-		return &nextExecution
+	next, ok := entry.next()
+	if !ok {
+		return nil
 	}
+	return &next
+}
 
-	// Handle interval-based scheduling
-	if rule.Schedule.Interval != "" {
-		duration, err := s.parseInterval(rule.Schedule.Interval)
-		if err != nil {
-			s.logger.WithError(err).Warn("failed to parse interval", "rule_id", rule.ID, "interval", rule.Schedule.Interval)
-			return nil
-		}
+// Health checks the health of the scheduler
+func (s *scheduler) Health(ctx context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-		nextExecution := now.Add(duration)
-		return &nextExecution
-	}
+	// Basic health check - ensure map is accessible
+	_ = len(s.scheduledRules)
 
-	// Handle time window scheduling
-	if !rule.Schedule.StartTime.IsZero() {
-		if now.Before(rule.Schedule.StartTime) {
-			return &rule.Schedule.StartTime
-		}
+	return nil
+}
 
-		// If we're past the start time, calculate next execution
-		if !rule.Schedule.EndTime.IsZero() && now.Before(rule.Schedule.EndTime) {
-			// Execute every minute within the time window
-			nextExecution := now.Add(time.Minute)
-			return &nextExecution
+// next returns when the rule runs next, or false if its schedule has
+// ended. Interval rules run as soon as they are scheduled and then every
+// interval after their last run; cron rules run at each activation after
+// their last run. Neither runs before the window start or after its end.
+func (e *scheduledRule) next() (time.Time, bool) {
+	schedule := e.rule.Schedule
+
+	var next time.Time
+	if e.cron != nil {
+		from := e.since
+		if e.last != nil {
+			from = *e.last
+		}
+		if !schedule.StartTime.IsZero() && from.Before(schedule.StartTime) {
+			// Next is strictly after its argument, so the window start
+			// itself can fire
+			from = schedule.StartTime.Add(-time.Nanosecond)
+		}
+		if next = e.cron.Next(from); next.IsZero() {
+			return time.Time{}, false
+		}
+	} else {
+		next = e.since
+		if e.last != nil {
+			next = e.last.Add(e.interval)
+		}
+		if !schedule.StartTime.IsZero() && next.Before(schedule.StartTime) {
+			next = schedule.StartTime
 		}
 	}
 
-	return nil
-}
-
-// parseInterval parses an interval string and returns a duration
-func (s *scheduler) parseInterval(interval string) (time.Duration, error) {
-	// Simple interval parsing - in practice, you'd have more sophisticated parsing
-	switch interval {
-	case "1s":
-		return time.Second, nil
-	case "30s":
-		return 30 * time.Second, nil
-	case "1m":
-		return time.Minute, nil
-	case "5m":
-		return 5 * time.Minute, nil
-	case "10m":
-		return 10 * time.Minute, nil
-	case "30m":
-		return 30 * time.Minute, nil
-	case "1h":
-		return time.Hour, nil
-	case "6h":
-		return 6 * time.Hour, nil
-	case "12h":
-		return 12 * time.Hour, nil
-	case "24h":
-		return 24 * time.Hour, nil
-	default:
-		return 0, fmt.Errorf("unsupported interval: %s", interval)
+	if !schedule.EndTime.IsZero() && next.After(schedule.EndTime) {
+		return time.Time{}, false
 	}
+	return next, true
 }
 
-// ShouldExecute checks if a rule should be executed now
-func (s *scheduler) ShouldExecute(rule *AutomationRule, lastExecuted *time.Time) bool {
-	if rule.Schedule == nil {
-		return false
+// Validate checks that a schedule has either a valid cron expression or a
+// positive interval, a known time zone and a window that ends after it
+// starts
+func (s *Schedule) Validate() error {
+	switch {
+	case s.Cron != "" && s.Interval != "":
+		return fmt.Errorf("schedule takes either a cron expression or an interval")
+	case s.Cron != "":
+		location, err := s.location()
+		if err != nil {
+			return err
+		}
+		if _, err := cron.Parse(s.Cron, location); err != nil {
+			return fmt.Errorf("invalid cron expression: %w", err)
+		}
+	case s.Interval != "":
+		interval, err := time.ParseDuration(s.Interval)
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("interval must be a positive duration, got %q", s.Interval)
+		}
+	default:
+		return fmt.Errorf("schedule requires a cron expression or an interval")
 	}
 
-	now := time.Now()
-
-	// Check if we're within the time window
-	if !rule.Schedule.StartTime.IsZero() && now.Before(rule.Schedule.StartTime) {
-		return false
+	if !s.StartTime.IsZero() && !s.EndTime.IsZero() && s.StartTime.After(s.EndTime) {
+		return fmt.Errorf("start time cannot be after end time")
 	}
+	return nil
+}
 
-	if !rule.Schedule.EndTime.IsZero() && now.After(rule.Schedule.EndTime) {
-		return false
+// location loads the time zone cron expressions are evaluated in,
+// defaulting to UTC
+func (s *Schedule) location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
 	}
-
-	// Check if enough time has passed since last execution
-	if lastExecuted != nil {
-		nextExecution := s.GetNextExecutionTime(rule)
-		if nextExecution != nil && now.Before(*nextExecution) {
-			return false
-		}
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule timezone %q: %w", s.Timezone, err)
 	}
-
-	return true
+	return location, nil
 }
@@ -0,0 +1,155 @@
+package automation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// testLogger discards log output
+type testLogger struct{}
+
+func (l testLogger) Info(msg string, fields ...interface{})                    {}
+func (l testLogger) Warn(msg string, fields ...interface{})                    {}
+func (l testLogger) Error(msg string, fields ...interface{})                   {}
+func (l testLogger) Debug(msg string, fields ...interface{})                   {}
+func (l testLogger) Fatal(msg string, fields ...interface{})                   {}
+func (l testLogger) WithError(err error) types.Logger                          { return l }
+func (l testLogger) WithDuration(duration time.Duration) types.Logger          { return l }
+func (l testLogger) WithPolicy(policyID, policyName string) types.Logger       { return l }
+func (l testLogger) WithWorkload(workloadID, workloadType string) types.Logger { return l }
+func (l testLogger) WithEvaluation(evaluationID string) types.Logger           { return l }
+
+// fakeClock is a clock tests move by hand
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newScheduledRule(id string, schedule *Schedule) *AutomationRule {
+	return &AutomationRule{ID: id, Name: id, Enabled: true, Schedule: schedule}
+}
+
+func TestScheduler_CronRuns(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2026, 5, 4, 10, 2, 0, 0, time.UTC)}
+	s := NewSchedulerWithClock(clock.Now, testLogger{})
+
+	require.NoError(t, s.ScheduleRule(ctx, newScheduledRule("r1", &Schedule{Cron: "*/5 * * * *"})))
+	assert.Equal(t, time.Date(2026, 5, 4, 10, 5, 0, 0, time.UTC), *s.NextExecution("r1"))
+
+	clock.now = clock.now.Add(2 * time.Minute)
+	assert.Empty(t, s.DueRules(ctx))
+
+	clock.now = time.Date(2026, 5, 4, 10, 5, 0, 0, time.UTC)
+	due := s.DueRules(ctx)
+	require.Len(t, due, 1)
+	assert.Equal(t, "r1", due[0].ID)
+	assert.Empty(t, s.DueRules(ctx))
+	assert.Equal(t, time.Date(2026, 5, 4, 10, 10, 0, 0, time.UTC), *s.NextExecution("r1"))
+
+	// Missed activations run once
+	clock.now = clock.now.Add(time.Hour)
+	assert.Len(t, s.DueRules(ctx), 1)
+	assert.Empty(t, s.DueRules(ctx))
+}
+
+func TestScheduler_CronHonoursTimezoneAcrossDST(t *testing.T) {
+	ctx := context.Background()
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// Clocks in New York spring forward on 8 March 2026
+	clock := &fakeClock{now: time.Date(2026, 3, 7, 12, 0, 0, 0, newYork)}
+	s := NewSchedulerWithClock(clock.Now, testLogger{})
+	require.NoError(t, s.ScheduleRule(ctx, newScheduledRule("r1", &Schedule{Cron: "@daily", Timezone: "America/New_York"})))
+
+	next := s.NextExecution("r1")
+	require.NotNil(t, next)
+	assert.Equal(t, time.Date(2026, 3, 8, 5, 0, 0, 0, time.UTC), next.UTC())
+
+	clock.now = *next
+	require.Len(t, s.DueRules(ctx), 1)
+	next = s.NextExecution("r1")
+	require.NotNil(t, next)
+	assert.Equal(t, time.Date(2026, 3, 9, 4, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestScheduler_IntervalWithinWindow(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start.Add(-time.Hour)}
+	s := NewSchedulerWithClock(clock.Now, testLogger{})
+
+	require.NoError(t, s.ScheduleRule(ctx, newScheduledRule("r1", &Schedule{
+		Interval:  "90s",
+		StartTime: start,
+		EndTime:   start.Add(2 * time.Minute),
+	})))
+	assert.Equal(t, start, *s.NextExecution("r1"))
+	assert.Empty(t, s.DueRules(ctx))
+
+	clock.now = start
+	assert.Len(t, s.DueRules(ctx), 1)
+	assert.Equal(t, start.Add(90*time.Second), *s.NextExecution("r1"))
+
+	clock.now = start.Add(90 * time.Second)
+	assert.Len(t, s.DueRules(ctx), 1)
+
+	// The next run would fall after the window ends
+	assert.Nil(t, s.NextExecution("r1"))
+	clock.now = start.Add(time.Hour)
+	assert.Empty(t, s.DueRules(ctx))
+}
+
+func TestScheduler_RescheduleKeepsLastRun(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)}
+	s := NewSchedulerWithClock(clock.Now, testLogger{})
+
+	rule := newScheduledRule("r1", &Schedule{Interval: "10m"})
+	require.NoError(t, s.ScheduleRule(ctx, rule))
+	require.Len(t, s.DueRules(ctx), 1)
+
+	rule.Schedule = &Schedule{Interval: "1h"}
+	require.NoError(t, s.ScheduleRule(ctx, rule))
+	assert.Equal(t, clock.now.Add(time.Hour), *s.NextExecution("r1"))
+
+	require.NoError(t, s.UnscheduleRule(ctx, "r1"))
+	assert.Nil(t, s.NextExecution("r1"))
+}
+
+func TestSchedule_Validate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		schedule Schedule
+		valid    bool
+	}{
+		"cron":              {Schedule{Cron: "0 */2 * * 1-5"}, true},
+		"seconds cron":      {Schedule{Cron: "*/30 * * * * *"}, true},
+		"macro":             {Schedule{Cron: "@hourly", Timezone: "Europe/Berlin"}, true},
+		"interval":          {Schedule{Interval: "45m"}, true},
+		"cron and interval": {Schedule{Cron: "@daily", Interval: "1h"}, false},
+		"bad cron":          {Schedule{Cron: "61 * * * *"}, false},
+		"bad timezone":      {Schedule{Cron: "@daily", Timezone: "Mars/Olympus"}, false},
+		"zero interval":     {Schedule{Interval: "0s"}, false},
+		"neither":           {Schedule{}, false},
+		"inverted window": {Schedule{
+			Interval:  "1m",
+			StartTime: time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2026, 5, 4, 11, 0, 0, 0, time.UTC),
+		}, false},
+	} {
+		err := tc.schedule.Validate()
+		if tc.valid {
+			assert.NoError(t, err, name)
+		} else {
+			assert.Error(t, err, name)
+		}
+	}
+}
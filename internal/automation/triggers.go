@@ -0,0 +1,96 @@
+package automation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kcloud-opt/policy/internal/events"
+)
+
+// triggerHandler fires a rule for the events its triggers match
+type triggerHandler struct {
+	rule               *AutomationRule
+	conditionEvaluator ConditionEvaluator
+	fire               func(ctx context.Context, rule *AutomationRule, event *Event)
+}
+
+// newTriggerHandler creates the event handler of a rule with triggers
+func newTriggerHandler(rule *AutomationRule, conditionEvaluator ConditionEvaluator, fire func(ctx context.Context, rule *AutomationRule, event *Event)) EventHandler {
+	return &triggerHandler{
+		rule:               rule,
+		conditionEvaluator: conditionEvaluator,
+		fire:               fire,
+	}
+}
+
+// HandleEvent fires the rule once if any of its triggers matches the event
+func (h *triggerHandler) HandleEvent(ctx context.Context, event *Event) error {
+	for i, trigger := range h.rule.Triggers {
+		if !events.MatchesType(trigger.Event, event.Type) {
+			continue
+		}
+		matched, err := h.matchFilters(ctx, trigger, event)
+		if err != nil {
+			return fmt.Errorf("trigger %d of rule %s: %w", i, h.rule.ID, err)
+		}
+		if matched {
+			h.fire(ctx, h.rule, event)
+			return nil
+		}
+	}
+	return nil
+}
+
+// CanHandle checks if any trigger of the rule listens for the event type
+func (h *triggerHandler) CanHandle(eventType string) bool {
+	for _, trigger := range h.rule.Triggers {
+		if events.MatchesType(trigger.Event, eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Health checks the health of the trigger handler
+func (h *triggerHandler) Health(ctx context.Context) error {
+	return nil
+}
+
+// matchFilters checks every filter of a trigger against the event data. A
+// filter maps a dotted field path to the value it must equal, or to a list
+// of values it must be one of.
+func (h *triggerHandler) matchFilters(ctx context.Context, trigger *Trigger, event *Event) (bool, error) {
+	for field, expected := range trigger.Filters {
+		condition := &Condition{Field: field, Operator: OperatorEquals, Value: expected}
+		switch expected.(type) {
+		case []interface{}, []string, []int:
+			condition.Operator = OperatorIn
+		}
+
+		matched, err := h.conditionEvaluator.EvaluateCondition(ctx, condition, event.Data)
+		if err != nil {
+			return false, fmt.Errorf("filter %s: %w", field, err)
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// eventContext builds the context a rule fired by an event runs with: the
+// event data plus the trigger, the event and its time
+func eventContext(event *Event) map[string]interface{} {
+	context := make(map[string]interface{}, len(event.Data)+3)
+	for key, value := range event.Data {
+		context[key] = value
+	}
+	context["trigger"] = "event"
+	context["event"] = map[string]interface{}{
+		"id":     event.ID,
+		"type":   event.Type,
+		"source": event.Source,
+	}
+	context["time"] = event.Timestamp
+	return context
+}
@@ -0,0 +1,97 @@
+package automation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/events"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// recordingExecutor records the context of each rule it executes
+type recordingExecutor struct {
+	executed chan map[string]interface{}
+}
+
+func (e *recordingExecutor) ExecuteRule(ctx context.Context, rule *AutomationRule, context map[string]interface{}) (*ExecutionResult, error) {
+	e.executed <- context
+	return &ExecutionResult{RuleID: rule.ID, Success: true, Timestamp: time.Now()}, nil
+}
+
+func (e *recordingExecutor) ValidateRule(ctx context.Context, rule *AutomationRule) error { return nil }
+
+func (e *recordingExecutor) Health(ctx context.Context) error { return nil }
+
+func TestAutomationEngine_FiresRulesForMatchingEvents(t *testing.T) {
+	ctx := context.Background()
+	bus := events.NewBus(testLogger{})
+	defer bus.Close()
+	store := events.NewPublishingStorage(memory.NewStorageManager(), bus)
+
+	executor := &recordingExecutor{executed: make(chan map[string]interface{}, 10)}
+	engine := NewAutomationEngine(store, executor, NewConditionEvaluator(store.ConditionState(), nil, testLogger{}), NewScheduler(testLogger{}), bus, testLogger{})
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	require.NoError(t, engine.CreateRule(ctx, &AutomationRule{
+		ID:      "restart-failed-training",
+		Name:    "restart-failed-training",
+		Enabled: true,
+		Triggers: []*Trigger{{
+			Type:  "event",
+			Event: EventTypeWorkloadFailed,
+			Filters: map[string]interface{}{
+				"workload.type":        "ml_training",
+				"workload.labels.team": []string{"ml", "research"},
+			},
+		}},
+		Conditions: []*Condition{{Field: "workload.status", Operator: OperatorEquals, Value: "failed"}},
+		Actions:    []*Action{{Type: ActionTypeNotify, Target: "ml-oncall"}},
+	}))
+
+	workloads := []*types.Workload{
+		{ID: "wl-1", Name: "trainer", Type: types.WorkloadTypeMLTraining, Labels: map[string]string{"team": "ml"}},
+		{ID: "wl-2", Name: "web", Type: types.WorkloadTypeDeployment, Labels: map[string]string{"team": "ml"}},
+		{ID: "wl-3", Name: "etl", Type: types.WorkloadTypeMLTraining, Labels: map[string]string{"team": "data"}},
+	}
+	for _, workload := range workloads {
+		workload.Status = types.WorkloadStatusRunning
+		require.NoError(t, store.Workload().Create(ctx, workload))
+	}
+	for _, workload := range workloads {
+		workload.Status = types.WorkloadStatusFailed
+		require.NoError(t, store.Workload().Update(ctx, workload))
+	}
+
+	select {
+	case context := <-executor.executed:
+		assert.Equal(t, "event", context["trigger"])
+		assert.Equal(t, EventTypeWorkloadFailed, context["event"].(map[string]interface{})["type"])
+		assert.Equal(t, "wl-1", context["workloadId"])
+		assert.Equal(t, "running", context["previousStatus"])
+	case <-time.After(time.Second):
+		t.Fatal("rule did not fire")
+	}
+	select {
+	case context := <-executor.executed:
+		t.Fatalf("rule fired for unmatched workload %v", context["workloadId"])
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Disabled rules stop listening
+	require.NoError(t, engine.DisableRule(ctx, "restart-failed-training"))
+	workloads[0].Status = types.WorkloadStatusRunning
+	require.NoError(t, store.Workload().Update(ctx, workloads[0]))
+	workloads[0].Status = types.WorkloadStatusFailed
+	require.NoError(t, store.Workload().Update(ctx, workloads[0]))
+	select {
+	case <-executor.executed:
+		t.Fatal("disabled rule fired")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -9,14 +9,19 @@ import (
 
 // Config holds all configuration for the policy engine
 type Config struct {
-	Server     ServerConfig     `mapstructure:"server"`
-	Database   DatabaseConfig   `mapstructure:"database"`
-	Redis      RedisConfig      `mapstructure:"redis"`
-	Logging    LoggingConfig    `mapstructure:"logging"`
-	Policy     PolicyConfig     `mapstructure:"policy"`
-	Automation AutomationConfig `mapstructure:"automation"`
-	Monitoring MonitoringConfig `mapstructure:"monitoring"`
-	Kubernetes KubernetesConfig `mapstructure:"kubernetes"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Redis       RedisConfig       `mapstructure:"redis"`
+	Logging     LoggingConfig     `mapstructure:"logging"`
+	Policy      PolicyConfig      `mapstructure:"policy"`
+	Automation  AutomationConfig  `mapstructure:"automation"`
+	Monitoring  MonitoringConfig  `mapstructure:"monitoring"`
+	Kubernetes  KubernetesConfig  `mapstructure:"kubernetes"`
+	Optimizer   OptimizerConfig   `mapstructure:"optimizer"`
+	Approval    ApprovalConfig    `mapstructure:"approval"`
+	Decisions   DecisionConfig    `mapstructure:"decisions"`
+	Enforcement EnforcementConfig `mapstructure:"enforcement"`
+	Webhooks    WebhookConfig     `mapstructure:"webhooks"`
 }
 
 // ServerConfig holds server configuration
@@ -100,6 +105,226 @@ type KubernetesConfig struct {
 	InCluster  bool   `mapstructure:"in_cluster"`
 }
 
+// OptimizerConfig holds optimizer configuration
+type OptimizerConfig struct {
+	RightSizingWindow     time.Duration `mapstructure:"rightsizing_window"`
+	RightSizingPercentile float64       `mapstructure:"rightsizing_percentile"`
+	RightSizingHeadroom   float64       `mapstructure:"rightsizing_headroom"`
+	RightSizingMinSamples int           `mapstructure:"rightsizing_min_samples"`
+	Cost                  CostConfig    `mapstructure:"cost"`
+	Spot                  SpotConfig    `mapstructure:"spot"`
+}
+
+// SpotConfig holds spot/preemptible capacity assumptions
+type SpotConfig struct {
+	Discount                   float64       `mapstructure:"discount"`
+	ExpectedAvailability       float64       `mapstructure:"expected_availability"`
+	MaxUninterruptedRuntime    time.Duration `mapstructure:"max_uninterrupted_runtime"`
+	MaxInterruptionFailureRate float64       `mapstructure:"max_interruption_failure_rate"`
+}
+
+// CostConfig holds the hourly resource rates used by the cost model
+type CostConfig struct {
+	Currency         string  `mapstructure:"currency"`
+	CPUPerHour       float64 `mapstructure:"cpu_per_hour"`
+	MemoryGiBPerHour float64 `mapstructure:"memory_gib_per_hour"`
+	GPUPerHour       float64 `mapstructure:"gpu_per_hour"`
+	NPUPerHour       float64 `mapstructure:"npu_per_hour"`
+}
+
+// ApprovalConfig holds decision approval configuration. Rules are matched
+// in order and the first match decides; unmatched decisions use
+// DefaultAction.
+type ApprovalConfig struct {
+	Enabled       bool           `mapstructure:"enabled"`
+	DefaultAction string         `mapstructure:"default_action"`
+	Rules         []ApprovalRule `mapstructure:"rules"`
+}
+
+// ApprovalRule matches decisions and decides whether they auto-approve,
+// auto-reject or wait for human review. Empty matchers match every decision.
+type ApprovalRule struct {
+	Name          string   `mapstructure:"name"`
+	Action        string   `mapstructure:"action"`
+	DecisionTypes []string `mapstructure:"decision_types"`
+	Namespaces    []string `mapstructure:"namespaces"`
+	Environments  []string `mapstructure:"environments"`
+	MinConfidence float64  `mapstructure:"min_confidence"`
+	MaxCostDelta  *float64 `mapstructure:"max_cost_delta"`
+}
+
+// DecisionConfig holds decision lifecycle configuration
+type DecisionConfig struct {
+	// PendingTTL is how long a decision may stay pending before it
+	// expires; zero keeps pending decisions until they are reviewed
+	PendingTTL time.Duration `mapstructure:"pending_ttl"`
+
+	// ExpiryInterval is how often pending decisions are checked for expiry
+	ExpiryInterval time.Duration `mapstructure:"expiry_interval"`
+
+	// SupersedePending makes a new decision supersede the decisions still
+	// pending for the same workload
+	SupersedePending bool `mapstructure:"supersede_pending"`
+}
+
+// Enforcement modes
+const (
+	// EnforcementModeEnforce executes the actions of approved decisions
+	EnforcementModeEnforce = "enforce"
+
+	// EnforcementModeDryRun only plans the actions of approved decisions
+	EnforcementModeDryRun = "dry-run"
+)
+
+// EnforcementConfig holds decision enforcement configuration
+type EnforcementConfig struct {
+	// Mode is enforce or dry-run
+	Mode string `mapstructure:"mode"`
+
+	// DecisionTimeout bounds the enforcement of a single decision across
+	// all of its actions; zero disables the deadline
+	DecisionTimeout time.Duration `mapstructure:"decision_timeout"`
+	Safety          SafetyConfig  `mapstructure:"safety"`
+	Locks           LockConfig    `mapstructure:"locks"`
+	Steps           StepConfig    `mapstructure:"steps"`
+	Rollout         RolloutConfig `mapstructure:"rollout"`
+}
+
+// RolloutConfig holds the progressive rollout of decision batches. Batches
+// larger than the initial wave are enforced in waves, each observed for the
+// bake time before the next starts. Zero health thresholds are not checked.
+// Rollouts are off by default: a wave whose workloads report no metrics
+// halts and rolls back unless missing metrics are allowed.
+type RolloutConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`
+	InitialWave int     `mapstructure:"initial_wave"`
+	WaveGrowth  float64 `mapstructure:"wave_growth"`
+
+	BakeTime             time.Duration `mapstructure:"bake_time"`
+	MaxErrorRate         float64       `mapstructure:"max_error_rate"`
+	MaxErrorRateIncrease float64       `mapstructure:"max_error_rate_increase"`
+	MaxLatencyIncrease   float64       `mapstructure:"max_latency_increase"`
+
+	// AllowMissingMetrics passes waves whose workloads reported no metrics
+	// during the bake time instead of halting the rollout
+	AllowMissingMetrics bool `mapstructure:"allow_missing_metrics"`
+
+	// CheckInterval is how often a rollout checks on the enforcements of
+	// its current wave
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+}
+
+// StepConfig holds the defaults of the steps of enforcement plans
+type StepConfig struct {
+	// ReadinessTimeout bounds how long a step waits for the change it made
+	// to become ready
+	ReadinessTimeout time.Duration `mapstructure:"readiness_timeout"`
+
+	// ReadinessInterval is how often a step checks whether it is ready
+	ReadinessInterval time.Duration `mapstructure:"readiness_interval"`
+
+	// MaxRetries is how many more times a failed step is run; zero fails
+	// the enforcement on the first failure
+	MaxRetries    int           `mapstructure:"max_retries"`
+	RetryInterval time.Duration `mapstructure:"retry_interval"`
+}
+
+// LockConfig holds the workload and node leases taken by enforcements
+type LockConfig struct {
+	// LeaseDuration bounds how long an enforcement holds its leases before
+	// another decision may take them over; zero holds them until the
+	// enforcement ends
+	LeaseDuration time.Duration `mapstructure:"lease_duration"`
+
+	// Supersede lets a decision of higher priority cancel a running
+	// enforcement of lower priority holding its locks instead of waiting
+	Supersede bool `mapstructure:"supersede"`
+}
+
+// SafetyConfig holds the blast-radius limits of enforcement. A decision is
+// admitted only if every limit matching its disruptions has budget left.
+type SafetyConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Limits  []SafetyLimit `mapstructure:"limits"`
+}
+
+// SafetyLimit bounds the disruptions caused by the decisions it matches.
+// Empty matchers match every disruption and zero limits are not enforced.
+// Budgets are shared by everything the limit matches, so per-cluster or
+// per-namespace budgets need a limit each.
+type SafetyLimit struct {
+	Name        string   `mapstructure:"name"`
+	Clusters    []string `mapstructure:"clusters"`
+	Namespaces  []string `mapstructure:"namespaces"`
+	ActionTypes []string `mapstructure:"action_types"`
+
+	// MaxConcurrent bounds the disruptive actions in flight at once
+	MaxConcurrent int `mapstructure:"max_concurrent"`
+
+	// MaxPerHour bounds the disruptive actions started in a sliding hour
+	MaxPerHour int `mapstructure:"max_per_hour"`
+
+	// MaxNamespaceFraction bounds the share of a namespace's workloads
+	// disrupted at once, between 0 and 1
+	MaxNamespaceFraction float64 `mapstructure:"max_namespace_fraction"`
+
+	// MinHealthyReplicas is the fewest replicas a disrupted workload may
+	// be left with
+	MinHealthyReplicas int `mapstructure:"min_healthy_replicas"`
+
+	// OverLimit is what happens to a decision over the limit: queue waits
+	// for budget to free up, reject refuses it
+	OverLimit string `mapstructure:"over_limit"`
+}
+
+// Webhook delivery modes
+const (
+	// WebhookModeSync takes the result of an action from the response body
+	WebhookModeSync = "sync"
+
+	// WebhookModeAsync waits for the target to post the result of an action
+	// to its callback URL
+	WebhookModeAsync = "async"
+)
+
+// WebhookConfig holds the HTTP targets actions can be delivered to instead
+// of being executed in-process
+type WebhookConfig struct {
+	// Source is the CloudEvents source of delivered events
+	Source string `mapstructure:"source"`
+
+	// CallbackURL is the externally reachable base URL of the callback
+	// endpoint asynchronous targets post their results to
+	CallbackURL string `mapstructure:"callback_url"`
+
+	// Timeout and CallbackTimeout apply to targets that set neither
+	Timeout         time.Duration   `mapstructure:"timeout"`
+	CallbackTimeout time.Duration   `mapstructure:"callback_timeout"`
+	Targets         []WebhookTarget `mapstructure:"targets"`
+}
+
+// WebhookTarget is an HTTP endpoint that receives actions. Actions of the
+// listed types are delivered to it; any target also receives webhook
+// actions naming it.
+type WebhookTarget struct {
+	Name    string            `mapstructure:"name"`
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+
+	// Secret signs payloads and verifies callbacks with HMAC-SHA256; it is
+	// required, as unsigned callbacks could settle actions for anyone
+	Secret string `mapstructure:"secret"`
+
+	// Mode is sync or async
+	Mode        string   `mapstructure:"mode"`
+	ActionTypes []string `mapstructure:"action_types"`
+
+	// Timeout bounds each request and CallbackTimeout how long an
+	// asynchronous action waits for its result
+	Timeout         time.Duration `mapstructure:"timeout"`
+	CallbackTimeout time.Duration `mapstructure:"callback_timeout"`
+}
+
 // LoadConfig loads configuration from file and environment variables
 func LoadConfig(configPath ...string) (*Config, error) {
 	// Set default config path if not provided
@@ -145,6 +370,11 @@ func setDefaults() {
 	setAutomationDefaults()
 	setMonitoringDefaults()
 	setKubernetesDefaults()
+	setOptimizerDefaults()
+	setApprovalDefaults()
+	setDecisionDefaults()
+	setEnforcementDefaults()
+	setWebhookDefaults()
 }
 
 func setServerDefaults() {
@@ -210,6 +440,59 @@ func setKubernetesDefaults() {
 	viper.SetDefault("kubernetes.in_cluster", true)
 }
 
+func setOptimizerDefaults() {
+	viper.SetDefault("optimizer.rightsizing_window", "168h")
+	viper.SetDefault("optimizer.rightsizing_percentile", 95.0)
+	viper.SetDefault("optimizer.rightsizing_headroom", 0.15)
+	viper.SetDefault("optimizer.rightsizing_min_samples", 12)
+	viper.SetDefault("optimizer.cost.currency", "USD")
+	viper.SetDefault("optimizer.cost.cpu_per_hour", 0.04)
+	viper.SetDefault("optimizer.cost.memory_gib_per_hour", 0.005)
+	viper.SetDefault("optimizer.cost.gpu_per_hour", 2.5)
+	viper.SetDefault("optimizer.cost.npu_per_hour", 1.5)
+	viper.SetDefault("optimizer.spot.discount", 0.65)
+	viper.SetDefault("optimizer.spot.expected_availability", 0.95)
+	viper.SetDefault("optimizer.spot.max_uninterrupted_runtime", "2h")
+	viper.SetDefault("optimizer.spot.max_interruption_failure_rate", 0.2)
+}
+
+func setApprovalDefaults() {
+	viper.SetDefault("approval.enabled", true)
+	viper.SetDefault("approval.default_action", "review")
+}
+
+func setDecisionDefaults() {
+	viper.SetDefault("decisions.pending_ttl", "24h")
+	viper.SetDefault("decisions.expiry_interval", "5m")
+	viper.SetDefault("decisions.supersede_pending", true)
+}
+
+func setEnforcementDefaults() {
+	viper.SetDefault("enforcement.mode", EnforcementModeEnforce)
+	viper.SetDefault("enforcement.decision_timeout", "30m")
+	viper.SetDefault("enforcement.safety.enabled", false)
+	viper.SetDefault("enforcement.locks.lease_duration", "1h")
+	viper.SetDefault("enforcement.locks.supersede", true)
+	viper.SetDefault("enforcement.steps.readiness_timeout", "5m")
+	viper.SetDefault("enforcement.steps.readiness_interval", "5s")
+	viper.SetDefault("enforcement.steps.max_retries", 0)
+	viper.SetDefault("enforcement.steps.retry_interval", "10s")
+	viper.SetDefault("enforcement.rollout.enabled", false)
+	viper.SetDefault("enforcement.rollout.initial_wave", 10)
+	viper.SetDefault("enforcement.rollout.wave_growth", 2.0)
+	viper.SetDefault("enforcement.rollout.bake_time", "5m")
+	viper.SetDefault("enforcement.rollout.max_error_rate_increase", 0.05)
+	viper.SetDefault("enforcement.rollout.max_latency_increase", 0.5)
+	viper.SetDefault("enforcement.rollout.allow_missing_metrics", false)
+	viper.SetDefault("enforcement.rollout.check_interval", "5s")
+}
+
+func setWebhookDefaults() {
+	viper.SetDefault("webhooks.source", "/kcloud-opt/policy-engine")
+	viper.SetDefault("webhooks.timeout", "10s")
+	viper.SetDefault("webhooks.callback_timeout", "10m")
+}
+
 // GetDSN returns database connection string
 func (d *DatabaseConfig) GetDSN() string {
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
@@ -0,0 +1,290 @@
+// Package cron parses cron expressions and computes when they next fire.
+//
+// Expressions have five fields (minute, hour, day of month, month, day of
+// week) or six with a leading seconds field. Fields accept *, ?, values,
+// ranges (1-5), lists (1,3,5) and steps (*/15, 10-50/10); months and
+// weekdays also accept three-letter names. The macros @yearly, @annually,
+// @monthly, @weekly, @daily, @midnight and @hourly are supported.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression evaluated in a time zone
+type Schedule struct {
+	second, minute, hour, dom, month, dow uint64
+
+	// A restricted day of month or day of week matches when either field
+	// matches, following standard cron
+	domRestricted, dowRestricted bool
+
+	location *time.Location
+}
+
+// bounds are the allowed values of a cron field
+type bounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	seconds = bounds{0, 59, nil}
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	dom     = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dow = bounds{0, 7, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// macros are the predefined schedules
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// searchYears bounds how far ahead Next looks for a matching time
+const searchYears = 5
+
+// Parse parses a cron expression evaluated in location, or in UTC when
+// location is nil
+func Parse(expr string, location *time.Location) (*Schedule, error) {
+	if location == nil {
+		location = time.UTC
+	}
+
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "@") {
+		macro, exists := macros[strings.ToLower(spec)]
+		if !exists {
+			return nil, fmt.Errorf("unknown cron macro %q", spec)
+		}
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron expression %q must have 5 or 6 fields, got %d", expr, len(fields))
+	}
+
+	schedule := &Schedule{location: location}
+	var err error
+	if schedule.second, err = parseField(fields[0], seconds); err != nil {
+		return nil, fmt.Errorf("invalid seconds field: %w", err)
+	}
+	if schedule.minute, err = parseField(fields[1], minutes); err != nil {
+		return nil, fmt.Errorf("invalid minute field: %w", err)
+	}
+	if schedule.hour, err = parseField(fields[2], hours); err != nil {
+		return nil, fmt.Errorf("invalid hour field: %w", err)
+	}
+	if schedule.dom, err = parseField(fields[3], dom); err != nil {
+		return nil, fmt.Errorf("invalid day of month field: %w", err)
+	}
+	if schedule.month, err = parseField(fields[4], months); err != nil {
+		return nil, fmt.Errorf("invalid month field: %w", err)
+	}
+	if schedule.dow, err = parseField(fields[5], dow); err != nil {
+		return nil, fmt.Errorf("invalid day of week field: %w", err)
+	}
+
+	// Sunday may be written as 0 or 7
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow = schedule.dow&^(1<<7) | 1
+	}
+	schedule.domRestricted = !isWildcard(fields[3])
+	schedule.dowRestricted = !isWildcard(fields[5])
+
+	return schedule, nil
+}
+
+// Location returns the time zone the schedule is evaluated in
+func (s *Schedule) Location() *time.Location {
+	return s.location
+}
+
+// Next returns the first activation strictly after t, in the schedule's
+// time zone, or the zero time if there is none within the search horizon.
+// Wall-clock times skipped by a daylight saving change do not fire, and
+// times repeated by one fire once per occurrence.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(s.location)
+
+	// Start at the next whole second
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	limit := t.Year() + searchYears
+
+	added := false
+wrap:
+	if t.Year() > limit {
+		return time.Time{}
+	}
+
+	for s.month&(1<<uint(t.Month())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, s.location)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.location)
+		}
+		t = t.AddDate(0, 0, 1)
+
+		// Midnight may not exist on a daylight saving change
+		if t.Hour() != 0 {
+			if t.Hour() > 12 {
+				t = t.Add(time.Duration(24-t.Hour()) * time.Hour)
+			} else {
+				t = t.Add(time.Duration(-t.Hour()) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, s.location)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		if !added {
+			added = true
+			t = t.Add(-time.Duration(t.Second()) * time.Second)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	for s.second&(1<<uint(t.Second())) == 0 {
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto wrap
+		}
+	}
+
+	return t
+}
+
+// dayMatches returns true if the day of month and day of week fields
+// accept t's day
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// parseField parses a comma-separated cron field into a bit set
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		partBits, err := parseRange(part, b)
+		if err != nil {
+			return 0, err
+		}
+		bits |= partBits
+	}
+	return bits, nil
+}
+
+// parseRange parses a single value, range or wildcard with optional step
+func parseRange(part string, b bounds) (uint64, error) {
+	rangeSpec, stepSpec, hasStep := strings.Cut(part, "/")
+
+	var start, end uint
+	switch {
+	case rangeSpec == "*" || rangeSpec == "?":
+		start, end = b.min, b.max
+	default:
+		lowSpec, highSpec, isRange := strings.Cut(rangeSpec, "-")
+		low, err := parseValue(lowSpec, b)
+		if err != nil {
+			return 0, err
+		}
+		start, end = low, low
+		if isRange {
+			if end, err = parseValue(highSpec, b); err != nil {
+				return 0, err
+			}
+		} else if hasStep {
+			// A single value with a step runs to the end of the field
+			end = b.max
+		}
+	}
+	if start > end {
+		return 0, fmt.Errorf("range %q starts after it ends", part)
+	}
+
+	step := uint(1)
+	if hasStep {
+		value, err := strconv.ParseUint(stepSpec, 10, 8)
+		if err != nil || value == 0 {
+			return 0, fmt.Errorf("invalid step %q", stepSpec)
+		}
+		step = uint(value)
+	}
+
+	var bits uint64
+	for value := start; value <= end; value += step {
+		bits |= 1 << value
+	}
+	return bits, nil
+}
+
+// parseValue parses a field value or name within the field's bounds
+func parseValue(spec string, b bounds) (uint, error) {
+	if value, exists := b.names[strings.ToLower(spec)]; exists {
+		return value, nil
+	}
+	value, err := strconv.ParseUint(spec, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", spec)
+	}
+	if uint(value) < b.min || uint(value) > b.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", value, b.min, b.max)
+	}
+	return uint(value), nil
+}
+
+// isWildcard returns true if a field starts with a wildcard, which makes
+// day fields combine with AND rather than OR as in standard cron
+func isWildcard(field string) bool {
+	return strings.HasPrefix(field, "*") || strings.HasPrefix(field, "?")
+}
@@ -0,0 +1,108 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"too few fields", "* * * *"},
+		{"too many fields", "0 0 * * * * *"},
+		{"value out of range", "60 * * * *"},
+		{"unknown name", "0 0 * * mon-xyz"},
+		{"zero step", "*/0 * * * *"},
+		{"reversed range", "0 5-1 * * *"},
+		{"day of month zero", "0 0 0 * *"},
+		{"unknown macro", "@fortnightly"},
+		{"empty", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.expr, nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	// 2026-05-04 is a Monday
+	from := time.Date(2026, 5, 4, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"step", "*/15 * * * *", from, time.Date(2026, 5, 4, 10, 15, 0, 0, time.UTC)},
+		{"range with step", "10-50/20 * * * *", from, time.Date(2026, 5, 4, 10, 10, 0, 0, time.UTC)},
+		{"list", "0 6,18 * * *", from, time.Date(2026, 5, 4, 18, 0, 0, 0, time.UTC)},
+		{"seconds field", "30 * * * * *", from, time.Date(2026, 5, 4, 10, 8, 30, 0, time.UTC)},
+		{"strictly after", "30 7 10 * * *", from, time.Date(2026, 5, 5, 10, 7, 30, 0, time.UTC)},
+		{"weekday names", "0 9 * * mon-fri", time.Date(2026, 5, 8, 10, 0, 0, 0, time.UTC), time.Date(2026, 5, 11, 9, 0, 0, 0, time.UTC)},
+		{"sunday as seven", "0 0 * * 7", from, time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)},
+		{"month names", "0 0 1 feb *", from, time.Date(2027, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"day fields combine with or", "0 0 13 * fri", from, time.Date(2026, 5, 8, 0, 0, 0, 0, time.UTC)},
+		{"wildcard day of week combines with and", "0 0 13 * *", from, time.Date(2026, 5, 13, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", from, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"macro", "@daily", from, time.Date(2026, 5, 5, 0, 0, 0, 0, time.UTC)},
+		{"macro case", "@Hourly", from, time.Date(2026, 5, 4, 11, 0, 0, 0, time.UTC)},
+		{"year end", "0 0 1 1 *", from, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.expr, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schedule.Next(tt.from))
+		})
+	}
+}
+
+func TestSchedule_NextBeyondHorizon(t *testing.T) {
+	schedule, err := Parse("0 0 30 2 *", nil)
+	require.NoError(t, err)
+	assert.True(t, schedule.Next(time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC)).IsZero())
+}
+
+func TestSchedule_Location(t *testing.T) {
+	schedule, err := Parse("@daily", nil)
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, schedule.Location())
+
+	// Activations follow the wall clock of the schedule's zone
+	tokyo := time.FixedZone("JST", 9*60*60)
+	schedule, err = Parse("0 9 * * *", tokyo)
+	require.NoError(t, err)
+	next := schedule.Next(time.Date(2026, 5, 4, 1, 0, 0, 0, time.UTC))
+	assert.Equal(t, tokyo, next.Location())
+	assert.True(t, next.Equal(time.Date(2026, 5, 5, 0, 0, 0, 0, time.UTC)), "got %s", next)
+}
+
+func TestSchedule_DaylightSaving(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	// 02:30 does not exist on 2026-03-08, so that day does not fire
+	schedule, err := Parse("30 2 * * *", newYork)
+	require.NoError(t, err)
+	next := schedule.Next(time.Date(2026, 3, 7, 3, 0, 0, 0, newYork))
+	assert.True(t, next.Equal(time.Date(2026, 3, 9, 2, 30, 0, 0, newYork)), "got %s", next)
+
+	// 01:30 happens twice on 2026-11-01 and fires on both
+	schedule, err = Parse("30 1 * * *", newYork)
+	require.NoError(t, err)
+	first := schedule.Next(time.Date(2026, 11, 1, 0, 0, 0, 0, newYork))
+	second := schedule.Next(first)
+	assert.Equal(t, time.Hour, second.Sub(first))
+}
@@ -0,0 +1,61 @@
+package decisions
+
+import (
+	"context"
+
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// Lifecycle moves decisions through their statuses. Every status change
+// goes through it, so only the transitions the decision lifecycle allows
+// happen and each one is recorded in the decision history.
+type Lifecycle interface {
+	// Create stores a new decision. A pending decision is given its expiry
+	// time, supersedes the decisions still pending for its workload, or its
+	// cluster when it has no workload, and is submitted to the approver.
+	Create(ctx context.Context, decision *types.Decision) error
+
+	// SetApprover sets the approver new pending decisions are submitted to
+	SetApprover(approver Approver)
+
+	// OnTerminated registers a listener told about every decision that
+	// reaches a terminal status
+	OnTerminated(listener Listener)
+
+	// Transition moves a stored decision to a new status, stores it and
+	// records the change in its history
+	Transition(ctx context.Context, decision *types.Decision, change Change) error
+
+	// Expire moves the pending decisions past their expiry time to expired
+	// and returns them
+	Expire(ctx context.Context) ([]*types.Decision, error)
+
+	// Start expires stale pending decisions periodically until the context
+	// is done
+	Start(ctx context.Context)
+
+	// Health checks the health of the lifecycle
+	Health(ctx context.Context) error
+}
+
+// Approver applies the approval rules to a new pending decision, leaving
+// the decision with the status they gave it
+type Approver func(ctx context.Context, decision *types.Decision) error
+
+// Listener is told about a decision once its new status is stored
+type Listener func(decision *types.Decision)
+
+// Change is a status change of a decision and who made it
+type Change struct {
+	// Status is the status the decision moves to
+	Status types.DecisionStatus
+
+	// Action is recorded in the decision history; it defaults to the new
+	// status
+	Action  string
+	Actor   string
+	Comment string
+}
+
+// SystemActor is the actor recorded for expired and superseded decisions
+const SystemActor = "system:decision-lifecycle"
@@ -0,0 +1,302 @@
+package decisions
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// defaultExpiryInterval is how often pending decisions are checked for
+// expiry when no interval is configured
+const defaultExpiryInterval = 5 * time.Minute
+
+// lifecycle implements Lifecycle interface
+type lifecycle struct {
+	storage storage.StorageManager
+	config  config.DecisionConfig
+	logger  types.Logger
+
+	// approver is set once at startup, before decisions are created
+	approver Approver
+
+	// mu serializes transitions so each one starts from the stored status,
+	// and guards listeners
+	mu        sync.Mutex
+	listeners []Listener
+	now       func() time.Time
+}
+
+// NewLifecycle creates a new decision lifecycle
+func NewLifecycle(storage storage.StorageManager, cfg config.DecisionConfig, logger types.Logger) Lifecycle {
+	return &lifecycle{
+		storage: storage,
+		config:  cfg,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// Create stores a new decision. Decisions without a status start pending
+// and are submitted to the approver once stored; a failed submission
+// leaves the decision pending for review.
+func (l *lifecycle) Create(ctx context.Context, decision *types.Decision) error {
+	if decision == nil {
+		return fmt.Errorf("decision cannot be nil")
+	}
+	if decision.Status == "" {
+		decision.Status = types.DecisionStatusPending
+	}
+	if decision.IsPending() && decision.ExpiresAt == nil && l.config.PendingTTL > 0 {
+		expiresAt := l.now().Add(l.config.PendingTTL)
+		decision.ExpiresAt = &expiresAt
+	}
+
+	if err := l.storage.Decision().Create(ctx, decision); err != nil {
+		return err
+	}
+
+	if decision.IsPending() && l.config.SupersedePending {
+		l.supersede(ctx, decision)
+	}
+
+	if decision.IsPending() && l.approver != nil {
+		if err := l.approver(ctx, decision); err != nil {
+			l.logger.WithError(err).Warn("failed to submit decision for approval", "decision_id", decision.ID)
+		}
+	}
+	return nil
+}
+
+// SetApprover sets the approver new pending decisions are submitted to
+func (l *lifecycle) SetApprover(approver Approver) {
+	l.approver = approver
+}
+
+// OnTerminated registers a listener told about every decision that
+// reaches a terminal status
+func (l *lifecycle) OnTerminated(listener Listener) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.listeners = append(l.listeners, listener)
+}
+
+// Transition moves a decision to a new status. The transition is checked
+// against the stored status, so a decision that moved on since the caller
+// read it is not overwritten. Listeners are told about a terminated
+// decision after the transitions lock is released.
+func (l *lifecycle) Transition(ctx context.Context, decision *types.Decision, change Change) error {
+	if decision == nil {
+		return fmt.Errorf("decision cannot be nil")
+	}
+
+	l.mu.Lock()
+	changed, err := l.transition(ctx, decision, change)
+	listeners := l.listeners
+	l.mu.Unlock()
+	if err != nil || !changed || !decision.IsTerminated() {
+		return err
+	}
+
+	for _, listener := range listeners {
+		listener(decision)
+	}
+	return nil
+}
+
+// transition stores a decision's new status and records it in its history,
+// reporting whether the status changed
+func (l *lifecycle) transition(ctx context.Context, decision *types.Decision, change Change) (bool, error) {
+	stored, err := l.storage.Decision().Get(ctx, decision.ID)
+	if err != nil {
+		return false, err
+	}
+	from := stored.Status
+	if from == change.Status {
+		decision.Status = from
+		return false, nil
+	}
+
+	// The change is applied to a copy, so the caller's decision is only
+	// written once the new status is stored
+	updated := *decision
+	updated.Status = from
+	if err := updated.SetStatus(change.Status); err != nil {
+		return false, err
+	}
+	if err := l.storage.Decision().Update(ctx, &updated); err != nil {
+		return false, err
+	}
+	decision.Status = updated.Status
+	decision.UpdatedAt = updated.UpdatedAt
+	decision.ExecutedAt = updated.ExecutedAt
+
+	action := change.Action
+	if action == "" {
+		action = string(change.Status)
+	}
+	now := l.now()
+	if err := l.storage.Decision().AddHistory(ctx, &types.DecisionHistory{
+		DecisionID: decision.ID,
+		WorkloadID: decision.WorkloadID,
+		Action:     action,
+		Status:     decision.Status,
+		StartTime:  now,
+		EndTime:    &now,
+		Result:     string(decision.Status),
+		Approver:   change.Actor,
+		Comment:    change.Comment,
+		Events: []types.DecisionEvent{{
+			Type:      "status_changed",
+			Message:   fmt.Sprintf("Decision moved from %s to %s", from, decision.Status),
+			Timestamp: now,
+			Source:    "decision-lifecycle",
+			Data: map[string]interface{}{
+				"from": string(from),
+				"to":   string(decision.Status),
+			},
+		}},
+	}); err != nil {
+		l.logger.WithError(err).Warn("failed to record decision transition", "decision_id", decision.ID)
+	}
+
+	l.logger.Debug("decision status changed",
+		"decision_id", decision.ID,
+		"from", from,
+		"to", decision.Status,
+		"action", action)
+
+	return true, nil
+}
+
+// Expire moves pending decisions past their expiry time to expired.
+// Decisions created without an expiry time expire once they have been
+// pending for the configured TTL.
+func (l *lifecycle) Expire(ctx context.Context) ([]*types.Decision, error) {
+	pending, err := l.storage.Decision().GetByStatus(ctx, types.DecisionStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending decisions: %w", err)
+	}
+
+	now := l.now()
+	var expired []*types.Decision
+	for _, decision := range pending {
+		expiresAt := l.expiresAt(decision)
+		if expiresAt == nil || now.Before(*expiresAt) {
+			continue
+		}
+
+		if err := l.Transition(ctx, decision, Change{
+			Status:  types.DecisionStatusExpired,
+			Action:  "expire",
+			Actor:   SystemActor,
+			Comment: fmt.Sprintf("pending since %s, expired at %s", decision.CreatedAt.Format(time.RFC3339), expiresAt.Format(time.RFC3339)),
+		}); err != nil {
+			l.logger.WithError(err).Warn("failed to expire pending decision", "decision_id", decision.ID)
+			continue
+		}
+		expired = append(expired, decision)
+	}
+
+	if len(expired) > 0 {
+		l.logger.Info("expired pending decisions", "count", len(expired))
+	}
+	return expired, nil
+}
+
+// Start expires stale pending decisions every expiry interval
+func (l *lifecycle) Start(ctx context.Context) {
+	interval := l.config.ExpiryInterval
+	if interval <= 0 {
+		interval = defaultExpiryInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := l.Expire(ctx); err != nil {
+				l.logger.WithError(err).Warn("failed to expire pending decisions")
+			}
+		}
+	}
+}
+
+// Health checks the health of the decision lifecycle
+func (l *lifecycle) Health(ctx context.Context) error {
+	if l.storage == nil {
+		return fmt.Errorf("storage not configured")
+	}
+	return l.storage.Health(ctx)
+}
+
+// supersede moves the decisions still pending for the workload of a new
+// decision to superseded. Decisions without a workload, such as
+// consolidations, supersede the pending decisions of their type in their
+// cluster instead.
+func (l *lifecycle) supersede(ctx context.Context, decision *types.Decision) {
+	existing, err := l.supersedable(ctx, decision)
+	if err != nil {
+		l.logger.WithError(err).Warn("failed to list decisions to supersede", "decision_id", decision.ID)
+		return
+	}
+
+	for _, older := range existing {
+		if older.ID == decision.ID || !older.IsPending() || older.CreatedAt.After(decision.CreatedAt) {
+			continue
+		}
+		older.AddDetail("supersededBy", decision.ID)
+		if err := l.Transition(ctx, older, Change{
+			Status:  types.DecisionStatusSuperseded,
+			Action:  "supersede",
+			Actor:   SystemActor,
+			Comment: fmt.Sprintf("superseded by decision %s", decision.ID),
+		}); err != nil {
+			l.logger.WithError(err).Warn("failed to supersede pending decision",
+				"decision_id", older.ID, "superseded_by", decision.ID)
+		}
+	}
+}
+
+// supersedable returns the decisions sharing the subject of a decision
+func (l *lifecycle) supersedable(ctx context.Context, decision *types.Decision) ([]*types.Decision, error) {
+	if decision.WorkloadID != "" {
+		return l.storage.Decision().GetByWorkload(ctx, decision.WorkloadID)
+	}
+	if decision.ClusterID == "" {
+		return nil, nil
+	}
+
+	sameType, err := l.storage.Decision().GetByType(ctx, decision.Type)
+	if err != nil {
+		return nil, err
+	}
+	var existing []*types.Decision
+	for _, candidate := range sameType {
+		if candidate.WorkloadID == "" && candidate.ClusterID == decision.ClusterID {
+			existing = append(existing, candidate)
+		}
+	}
+	return existing, nil
+}
+
+// expiresAt returns when a pending decision expires, or nil if it never
+// does
+func (l *lifecycle) expiresAt(decision *types.Decision) *time.Time {
+	if decision.ExpiresAt != nil {
+		return decision.ExpiresAt
+	}
+	if l.config.PendingTTL <= 0 {
+		return nil
+	}
+	expiresAt := decision.CreatedAt.Add(l.config.PendingTTL)
+	return &expiresAt
+}
@@ -0,0 +1,243 @@
+package decisions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// testLogger discards log output
+type testLogger struct{}
+
+func (l testLogger) Info(msg string, fields ...interface{})                    {}
+func (l testLogger) Warn(msg string, fields ...interface{})                    {}
+func (l testLogger) Error(msg string, fields ...interface{})                   {}
+func (l testLogger) Debug(msg string, fields ...interface{})                   {}
+func (l testLogger) Fatal(msg string, fields ...interface{})                   {}
+func (l testLogger) WithError(err error) types.Logger                          { return l }
+func (l testLogger) WithDuration(duration time.Duration) types.Logger          { return l }
+func (l testLogger) WithPolicy(policyID, policyName string) types.Logger       { return l }
+func (l testLogger) WithWorkload(workloadID, workloadType string) types.Logger { return l }
+func (l testLogger) WithEvaluation(evaluationID string) types.Logger           { return l }
+
+func newDecision(id, workloadID string) *types.Decision {
+	return &types.Decision{
+		ID:         id,
+		Type:       types.DecisionTypeMigrate,
+		WorkloadID: workloadID,
+		PolicyID:   "policy-1",
+	}
+}
+
+func TestLifecycle_TransitionRecordsHistory(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStorageManager()
+	lc := NewLifecycle(store, config.DecisionConfig{}, testLogger{})
+
+	decision := newDecision("d-1", "wl-1")
+	require.NoError(t, lc.Create(ctx, decision))
+	assert.Equal(t, types.DecisionStatusPending, decision.Status)
+
+	for _, status := range []types.DecisionStatus{
+		types.DecisionStatusApproved,
+		types.DecisionStatusExecuting,
+		types.DecisionStatusCompleted,
+	} {
+		require.NoError(t, lc.Transition(ctx, decision, Change{Status: status, Actor: "alice"}))
+	}
+	assert.NotNil(t, decision.ExecutedAt)
+
+	err := lc.Transition(ctx, decision, Change{Status: types.DecisionStatusExecuting})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrInvalidTransition))
+
+	stored, err := store.Decision().Get(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, types.DecisionStatusCompleted, stored.Status)
+
+	history, err := store.Decision().GetHistory(ctx, "d-1")
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	latest := history[0]
+	assert.Equal(t, "completed", latest.Action)
+	assert.Equal(t, "alice", latest.Approver)
+	require.Len(t, latest.Events, 1)
+	assert.Equal(t, "executing", latest.Events[0].Data["from"])
+	assert.Equal(t, "completed", latest.Events[0].Data["to"])
+}
+
+func TestLifecycle_TransitionChecksStoredStatus(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStorageManager()
+	lc := NewLifecycle(store, config.DecisionConfig{}, testLogger{})
+
+	require.NoError(t, lc.Create(ctx, newDecision("d-1", "wl-1")))
+	stale, err := store.Decision().Get(ctx, "d-1")
+	require.NoError(t, err)
+
+	current, err := store.Decision().Get(ctx, "d-1")
+	require.NoError(t, err)
+	require.NoError(t, lc.Transition(ctx, current, Change{Status: types.DecisionStatusRejected}))
+
+	err = lc.Transition(ctx, stale, Change{Status: types.DecisionStatusApproved})
+	assert.True(t, errors.Is(err, types.ErrInvalidTransition))
+}
+
+func TestLifecycle_Expire(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStorageManager()
+	lc := NewLifecycle(store, config.DecisionConfig{PendingTTL: time.Hour}, testLogger{}).(*lifecycle)
+
+	pending := newDecision("d-1", "wl-1")
+	require.NoError(t, lc.Create(ctx, pending))
+	require.NotNil(t, pending.ExpiresAt)
+	approved := newDecision("d-2", "wl-2")
+	require.NoError(t, lc.Create(ctx, approved))
+	require.NoError(t, lc.Transition(ctx, approved, Change{Status: types.DecisionStatusApproved}))
+
+	expired, err := lc.Expire(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+
+	lc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	expired, err = lc.Expire(ctx)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "d-1", expired[0].ID)
+
+	stored, err := store.Decision().Get(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, types.DecisionStatusExpired, stored.Status)
+	stored, err = store.Decision().Get(ctx, "d-2")
+	require.NoError(t, err)
+	assert.Equal(t, types.DecisionStatusApproved, stored.Status)
+}
+
+func TestLifecycle_CreateSupersedesPending(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStorageManager()
+	lc := NewLifecycle(store, config.DecisionConfig{SupersedePending: true}, testLogger{})
+
+	require.NoError(t, lc.Create(ctx, newDecision("d-1", "wl-1")))
+	approved := newDecision("d-2", "wl-1")
+	require.NoError(t, lc.Create(ctx, approved))
+	require.NoError(t, lc.Transition(ctx, approved, Change{Status: types.DecisionStatusApproved}))
+	require.NoError(t, lc.Create(ctx, newDecision("d-3", "wl-2")))
+	require.NoError(t, lc.Create(ctx, newDecision("d-4", "wl-1")))
+	require.NoError(t, lc.Create(ctx, newDecision("d-5", "wl-1")))
+
+	for id, want := range map[string]types.DecisionStatus{
+		"d-1": types.DecisionStatusSuperseded,
+		"d-2": types.DecisionStatusApproved,
+		"d-3": types.DecisionStatusPending,
+		"d-4": types.DecisionStatusSuperseded,
+		"d-5": types.DecisionStatusPending,
+	} {
+		stored, err := store.Decision().Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, want, stored.Status, id)
+	}
+
+	history, err := store.Decision().GetHistory(ctx, "d-4")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, SystemActor, history[0].Approver)
+	assert.Contains(t, history[0].Comment, "d-5")
+}
+
+func TestLifecycle_CreateSupersedesPendingInCluster(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStorageManager()
+	lc := NewLifecycle(store, config.DecisionConfig{SupersedePending: true}, testLogger{})
+
+	consolidate := func(id, clusterID string) *types.Decision {
+		return &types.Decision{ID: id, Type: types.DecisionTypeConsolidate, ClusterID: clusterID, PolicyID: "policy-1"}
+	}
+	require.NoError(t, lc.Create(ctx, consolidate("d-1", "cluster-a")))
+	require.NoError(t, lc.Create(ctx, consolidate("d-2", "cluster-b")))
+	require.NoError(t, lc.Create(ctx, newDecision("d-3", "wl-1")))
+	require.NoError(t, lc.Create(ctx, consolidate("d-4", "cluster-a")))
+
+	// Decisions without a workload only supersede their cluster's
+	for id, want := range map[string]types.DecisionStatus{
+		"d-1": types.DecisionStatusSuperseded,
+		"d-2": types.DecisionStatusPending,
+		"d-3": types.DecisionStatusPending,
+		"d-4": types.DecisionStatusPending,
+	} {
+		stored, err := store.Decision().Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, want, stored.Status, id)
+	}
+}
+
+func TestLifecycle_CreateSubmitsToApprover(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStorageManager()
+	lc := NewLifecycle(store, config.DecisionConfig{}, testLogger{})
+
+	var submitted []string
+	lc.SetApprover(func(ctx context.Context, decision *types.Decision) error {
+		submitted = append(submitted, decision.ID)
+		if decision.WorkloadID == "wl-fail" {
+			return errors.New("approval rules unavailable")
+		}
+		return lc.Transition(ctx, decision, Change{Status: types.DecisionStatusApproved, Actor: "approver"})
+	})
+
+	approved := newDecision("d-1", "wl-1")
+	require.NoError(t, lc.Create(ctx, approved))
+	assert.Equal(t, types.DecisionStatusApproved, approved.Status)
+
+	// A failed submission leaves the decision pending for review
+	failed := newDecision("d-2", "wl-fail")
+	require.NoError(t, lc.Create(ctx, failed))
+	assert.Equal(t, types.DecisionStatusPending, failed.Status)
+
+	// Only pending decisions are submitted
+	completed := newDecision("d-3", "wl-1")
+	completed.Status = types.DecisionStatusCompleted
+	require.NoError(t, lc.Create(ctx, completed))
+
+	assert.Equal(t, []string{"d-1", "d-2"}, submitted)
+}
+
+func TestLifecycle_OnTerminated(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStorageManager()
+	lc := NewLifecycle(store, config.DecisionConfig{SupersedePending: true}, testLogger{})
+
+	var terminated []string
+	lc.OnTerminated(func(decision *types.Decision) {
+		// The new status is stored before listeners are told
+		stored, err := store.Decision().Get(ctx, decision.ID)
+		require.NoError(t, err)
+		assert.True(t, stored.IsTerminated())
+		terminated = append(terminated, decision.ID)
+	})
+
+	completed := newDecision("d-1", "wl-1")
+	require.NoError(t, lc.Create(ctx, completed))
+	for _, status := range []types.DecisionStatus{
+		types.DecisionStatusApproved,
+		types.DecisionStatusExecuting,
+		types.DecisionStatusCompleted,
+		types.DecisionStatusCompleted,
+	} {
+		require.NoError(t, lc.Transition(ctx, completed, Change{Status: status}))
+	}
+	assert.Equal(t, []string{"d-1"}, terminated)
+
+	// Superseded decisions terminate too
+	require.NoError(t, lc.Create(ctx, newDecision("d-2", "wl-2")))
+	require.NoError(t, lc.Create(ctx, newDecision("d-3", "wl-2")))
+	assert.Equal(t, []string{"d-1", "d-2"}, terminated)
+}
@@ -0,0 +1,493 @@
+package enforcer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/decisions"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// admit starts an enforcement once the maintenance calendar, the workload
+// locks and the safety limits allow it, and defers, queues or refuses it
+// otherwise
+func (pe *policyEnforcer) admit(ctx context.Context, decision *types.Decision, status *EnforcementStatus) error {
+	if held, err := pe.holdForMaintenance(ctx, decision, status); held {
+		return err
+	}
+
+	if held, err := pe.holdForLock(ctx, decision, status); held {
+		if err == nil {
+			pe.mu.Lock()
+			pe.queue = append(pe.queue, decision)
+			pe.mu.Unlock()
+		}
+		return err
+	}
+
+	verdict, err := pe.limiter.Admit(ctx, decision)
+	if err != nil {
+		pe.unlock(status)
+		pe.updateStatus(status, EnforcementStateFailed, fmt.Sprintf("Failed to check safety limits: %v", err))
+		return fmt.Errorf("failed to check safety limits: %w", err)
+	}
+	if !verdict.Allowed {
+		pe.unlock(status)
+		return pe.holdBack(ctx, decision, status, verdict)
+	}
+
+	pe.begin(ctx, decision, status)
+	return nil
+}
+
+// holdForLock locks the workloads and nodes of a decision, returning true
+// if they are held by another decision. A waiting decision of higher
+// priority supersedes the running enforcements holding its locks when
+// superseding is enabled. The caller queues a waiting decision.
+func (pe *policyEnforcer) holdForLock(ctx context.Context, decision *types.Decision, status *EnforcementStatus) (bool, error) {
+	resources := lockResources(decision)
+	priority := pe.decisionPriority(ctx, decision)
+
+	conflicts, err := pe.locks.Acquire(decision.ID, priority, resources)
+	if err != nil {
+		pe.updateStatus(status, EnforcementStateFailed, fmt.Sprintf("Failed to lock workloads: %v", err))
+		return true, fmt.Errorf("failed to lock workloads: %w", err)
+	}
+
+	now := time.Now()
+	if len(conflicts) == 0 {
+		pe.mu.Lock()
+		status.Lock = &types.LockStatus{Resources: resources, Holder: decision.ID, Held: true, AcquiredAt: &now}
+		pe.mu.Unlock()
+		return false, nil
+	}
+
+	holder := conflicts[0]
+	pe.mu.Lock()
+	waiting := status.Lock != nil && !status.Lock.Held && status.Lock.Holder == holder.DecisionID
+	status.Message = fmt.Sprintf("Waiting for %s held by decision %s", holder.Resource, holder.DecisionID)
+	status.Details["queued"] = true
+	status.Lock = &types.LockStatus{Resources: resources, Holder: holder.DecisionID}
+	pe.mu.Unlock()
+
+	// Record the wait once per holder rather than on every drain
+	if !waiting {
+		pe.addEvent(status, EnforcementEvent{
+			Type:      "lock_wait",
+			Message:   fmt.Sprintf("Waiting for %s held by decision %s", holder.Resource, holder.DecisionID),
+			Timestamp: now,
+			Data: map[string]interface{}{
+				"resource": holder.Resource,
+				"holder":   holder.DecisionID,
+			},
+		})
+		pe.logger.Info("policy enforcement waiting for lock",
+			"decision_id", decision.ID,
+			"resource", holder.Resource,
+			"holder", holder.DecisionID)
+	} else {
+		pe.persist(status)
+	}
+
+	if pe.config.Locks.Supersede {
+		pe.supersede(ctx, decision, priority, conflicts)
+	}
+	return true, nil
+}
+
+// unlock releases the locks of a decision and records that it no longer
+// holds them
+func (pe *policyEnforcer) unlock(status *EnforcementStatus) {
+	pe.locks.Release(status.DecisionID)
+
+	pe.mu.Lock()
+	if status.Lock != nil {
+		status.Lock.Held = false
+	}
+	pe.mu.Unlock()
+}
+
+// supersede cancels the running enforcements holding a decision's locks if
+// the decision outranks every one of them. Cancelled enforcements roll back
+// and release their locks, letting the waiting decision run.
+func (pe *policyEnforcer) supersede(ctx context.Context, decision *types.Decision, priority types.Priority, conflicts []*Lease) {
+	holders := make(map[string]types.Priority)
+	for _, lease := range conflicts {
+		if lease.Priority >= priority {
+			return
+		}
+		holders[lease.DecisionID] = lease.Priority
+	}
+
+	for holderID, holderPriority := range holders {
+		pe.mu.RLock()
+		holderStatus := pe.enforcements[holderID]
+		running := holderStatus != nil && holderStatus.Status == EnforcementStateRunning
+		pe.mu.RUnlock()
+		if !running {
+			continue
+		}
+
+		comment := fmt.Sprintf("Superseded by decision %s of priority %d over %d", decision.ID, priority, holderPriority)
+		pe.addEvent(holderStatus, EnforcementEvent{
+			Type:      "superseded",
+			Message:   comment,
+			Timestamp: time.Now(),
+			Data: map[string]interface{}{
+				"decision_id": decision.ID,
+			},
+		})
+		if err := pe.CancelEnforcement(ctx, holderID); err != nil {
+			pe.logger.WithError(err).Warn("failed to supersede policy enforcement", "decision_id", holderID, "superseded_by", decision.ID)
+			continue
+		}
+
+		now := time.Now()
+		if err := pe.storage.Decision().AddHistory(ctx, &types.DecisionHistory{
+			DecisionID: holderID,
+			Action:     "superseded",
+			Status:     types.DecisionStatusCancelled,
+			StartTime:  now,
+			EndTime:    &now,
+			Result:     string(types.DecisionStatusCancelled),
+			Comment:    comment,
+		}); err != nil {
+			pe.logger.WithError(err).Warn("failed to record superseded history", "decision_id", holderID)
+		}
+
+		pe.logger.Info("superseded policy enforcement",
+			"decision_id", holderID,
+			"superseded_by", decision.ID,
+			"priority", priority,
+			"holder_priority", holderPriority)
+	}
+}
+
+// decisionPriority returns the priority of a decision: a priority in its
+// details, else the priority of its policy, else normal priority
+func (pe *policyEnforcer) decisionPriority(ctx context.Context, decision *types.Decision) types.Priority {
+	if priority, ok, err := numberParam(decision.Details, "priority"); ok && err == nil && priority > 0 {
+		return types.Priority(priority)
+	}
+	if decision.PolicyID != "" {
+		if policy, err := pe.storage.Policy().Get(ctx, decision.PolicyID); err == nil && policy.GetPriority() > 0 {
+			return policy.GetPriority()
+		}
+	}
+	return types.PriorityNormal
+}
+
+// holdForMaintenance defers or blocks a decision the maintenance calendar
+// does not allow now, returning true if the decision was held
+func (pe *policyEnforcer) holdForMaintenance(ctx context.Context, decision *types.Decision, status *EnforcementStatus) (bool, error) {
+	verdict, err := pe.calendar.Check(ctx, decision)
+	if err != nil {
+		pe.updateStatus(status, EnforcementStateFailed, fmt.Sprintf("Failed to check maintenance windows: %v", err))
+		return true, fmt.Errorf("failed to check maintenance windows: %w", err)
+	}
+
+	switch verdict.Action {
+	case MaintenanceActionBlock:
+		pe.block(status, verdict)
+		return true, types.NewDecisionError(decision.ID, string(decision.Type), decision.WorkloadID, decision.PolicyID, "enforce",
+			fmt.Errorf("%w: %s", types.ErrChangeFreeze, verdict.Reason))
+	case MaintenanceActionDefer:
+		pe.deferEnforcement(ctx, decision, status, verdict)
+		return true, nil
+	}
+	return false, nil
+}
+
+// block refuses a decision a change freeze or missing maintenance window
+// does not allow. The decision stays approved so it can be enforced later.
+func (pe *policyEnforcer) block(status *EnforcementStatus, verdict *MaintenanceVerdict) {
+	pe.mu.Lock()
+	status.Error = verdict.Reason
+	status.Details["maintenanceWindow"] = verdict.Window
+	delete(status.Details, "queued")
+	delete(status.Details, "deferred")
+	pe.mu.Unlock()
+
+	pe.addEvent(status, EnforcementEvent{
+		Type:      "blocked",
+		Message:   verdict.Reason,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"window": verdict.Window,
+		},
+	})
+	pe.updateStatus(status, EnforcementStateFailed, fmt.Sprintf("Blocked: %s", verdict.Reason))
+
+	pe.logger.Warn("blocked policy enforcement",
+		"decision_id", status.DecisionID,
+		"window", verdict.Window,
+		"reason", verdict.Reason)
+}
+
+// deferEnforcement holds a decision until the time the maintenance calendar
+// scheduled it for and records that time on the decision
+func (pe *policyEnforcer) deferEnforcement(ctx context.Context, decision *types.Decision, status *EnforcementStatus, verdict *MaintenanceVerdict) {
+	until := *verdict.ScheduledFor
+
+	pe.mu.Lock()
+	status.Message = fmt.Sprintf("Deferred until %s: %s", until.Format(time.RFC3339), verdict.Reason)
+	status.Details["deferred"] = true
+	status.Details["deferredUntil"] = until
+	status.Details["maintenanceWindow"] = verdict.Window
+	delete(status.Details, "queued")
+	pe.mu.Unlock()
+
+	pe.addEvent(status, EnforcementEvent{
+		Type:      "deferred",
+		Message:   verdict.Reason,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"window":        verdict.Window,
+			"scheduled_for": until,
+		},
+	})
+
+	decision.ScheduledFor = &until
+	decision.UpdatedAt = time.Now()
+	if err := pe.storage.Decision().Update(ctx, decision); err != nil {
+		pe.logger.WithError(err).Warn("failed to record decision schedule", "decision_id", decision.ID)
+	}
+	pe.scheduleDeferred(ctx, decision.ID, until)
+
+	pe.logger.Info("deferred policy enforcement",
+		"decision_id", decision.ID,
+		"window", verdict.Window,
+		"scheduled_for", until,
+		"reason", verdict.Reason)
+}
+
+// scheduleDeferred admits a deferred decision again at its scheduled time
+func (pe *policyEnforcer) scheduleDeferred(ctx context.Context, decisionID string, until time.Time) {
+	base := context.WithoutCancel(ctx)
+	timer := time.AfterFunc(time.Until(until), func() {
+		pe.resumeDeferred(base, decisionID)
+	})
+
+	pe.mu.Lock()
+	if previous, exists := pe.timers[decisionID]; exists {
+		previous.Stop()
+	}
+	pe.timers[decisionID] = timer
+	pe.mu.Unlock()
+}
+
+// resumeDeferred admits a deferred decision once its scheduled time comes,
+// which may defer it again if its window has since changed
+func (pe *policyEnforcer) resumeDeferred(ctx context.Context, decisionID string) {
+	pe.mu.Lock()
+	delete(pe.timers, decisionID)
+	status := pe.enforcements[decisionID]
+	pe.mu.Unlock()
+	if status == nil || status.IsTerminated() {
+		return
+	}
+
+	decision, err := pe.storage.Decision().Get(ctx, decisionID)
+	if err != nil || !decision.CanBeExecuted() {
+		pe.updateStatus(status, EnforcementStateCancelled, "Decision is no longer approved")
+		return
+	}
+
+	pe.mu.Lock()
+	delete(status.Details, "deferred")
+	pe.mu.Unlock()
+	pe.addEvent(status, EnforcementEvent{
+		Type:      "resumed",
+		Message:   "Scheduled time reached",
+		Timestamp: time.Now(),
+	})
+
+	decision.ScheduledFor = nil
+	decision.UpdatedAt = time.Now()
+	if err := pe.storage.Decision().Update(ctx, decision); err != nil {
+		pe.logger.WithError(err).Warn("failed to clear decision schedule", "decision_id", decisionID)
+	}
+
+	if err := pe.admit(ctx, decision, status); err != nil {
+		pe.logger.WithError(err).Warn("failed to enforce deferred decision", "decision_id", decisionID)
+	}
+}
+
+// holdBack queues or rejects a decision the safety limits did not admit
+func (pe *policyEnforcer) holdBack(ctx context.Context, decision *types.Decision, status *EnforcementStatus, verdict *SafetyVerdict) error {
+	if verdict.Action == SafetyActionReject {
+		pe.reject(ctx, decision, status, verdict)
+		return types.NewDecisionError(decision.ID, string(decision.Type), decision.WorkloadID, decision.PolicyID, "enforce",
+			fmt.Errorf("%w: %s", types.ErrSafetyLimitExceeded, verdict.Reason))
+	}
+
+	pe.mu.Lock()
+	status.Message = fmt.Sprintf("Queued: %s", verdict.Reason)
+	status.Details["queued"] = true
+	status.Details["safetyLimit"] = verdict.Limit
+	pe.queue = append(pe.queue, decision)
+	pe.mu.Unlock()
+
+	pe.addEvent(status, EnforcementEvent{
+		Type:      "queued",
+		Message:   verdict.Reason,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"limit": verdict.Limit,
+		},
+	})
+	pe.scheduleDrain(ctx, verdict)
+
+	pe.logger.Info("queued policy enforcement",
+		"decision_id", decision.ID,
+		"limit", verdict.Limit,
+		"reason", verdict.Reason)
+
+	return nil
+}
+
+// reject refuses a decision over a safety limit and records why
+func (pe *policyEnforcer) reject(ctx context.Context, decision *types.Decision, status *EnforcementStatus, verdict *SafetyVerdict) {
+	pe.mu.Lock()
+	status.Error = verdict.Reason
+	status.Details["safetyLimit"] = verdict.Limit
+	delete(status.Details, "queued")
+	pe.mu.Unlock()
+
+	pe.addEvent(status, EnforcementEvent{
+		Type:      "rejected",
+		Message:   verdict.Reason,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"limit": verdict.Limit,
+		},
+	})
+	pe.updateStatus(status, EnforcementStateFailed, fmt.Sprintf("Rejected: %s", verdict.Reason))
+
+	if err := pe.lifecycle.Transition(ctx, decision, decisions.Change{
+		Status:  types.DecisionStatusRejected,
+		Action:  "safety_reject",
+		Comment: verdict.Reason,
+	}); err != nil {
+		pe.logger.WithError(err).Warn("failed to reject decision", "decision_id", decision.ID)
+	}
+
+	pe.logger.Warn("rejected policy enforcement",
+		"decision_id", decision.ID,
+		"limit", verdict.Limit,
+		"reason", verdict.Reason)
+}
+
+// drainQueue starts the queued enforcements whose locks are free and that
+// the safety limits now admit, highest priority first and otherwise in the
+// order they were queued
+func (pe *policyEnforcer) drainQueue(ctx context.Context) {
+	pe.drainMu.Lock()
+	defer pe.drainMu.Unlock()
+
+	pe.mu.Lock()
+	queued := pe.queue
+	pe.queue = nil
+	pe.mu.Unlock()
+
+	priorities := make(map[string]types.Priority, len(queued))
+	for _, decision := range queued {
+		priorities[decision.ID] = pe.decisionPriority(ctx, decision)
+	}
+	sort.SliceStable(queued, func(i, j int) bool {
+		return priorities[queued[i].ID] > priorities[queued[j].ID]
+	})
+
+	var remaining []*types.Decision
+	for _, decision := range queued {
+		pe.mu.RLock()
+		status := pe.enforcements[decision.ID]
+		pe.mu.RUnlock()
+		if status == nil || status.IsTerminated() {
+			continue
+		}
+
+		stored, err := pe.storage.Decision().Get(ctx, decision.ID)
+		if err != nil || !stored.CanBeExecuted() {
+			pe.updateStatus(status, EnforcementStateCancelled, "Decision is no longer approved")
+			continue
+		}
+
+		// The calendar may have changed while the decision was queued
+		if held, err := pe.holdForMaintenance(ctx, stored, status); held {
+			if err != nil {
+				pe.logger.WithError(err).Warn("queued enforcement held by maintenance calendar", "decision_id", decision.ID)
+			}
+			continue
+		}
+
+		if held, err := pe.holdForLock(ctx, stored, status); held {
+			if err == nil {
+				remaining = append(remaining, decision)
+			}
+			continue
+		}
+
+		verdict, err := pe.limiter.Admit(ctx, stored)
+		if err != nil {
+			pe.unlock(status)
+			pe.logger.WithError(err).Warn("failed to check safety limits for queued enforcement", "decision_id", decision.ID)
+			remaining = append(remaining, decision)
+			continue
+		}
+		if !verdict.Allowed {
+			pe.unlock(status)
+			if verdict.Action == SafetyActionReject {
+				pe.reject(ctx, stored, status, verdict)
+				continue
+			}
+			pe.mu.Lock()
+			status.Message = fmt.Sprintf("Queued: %s", verdict.Reason)
+			pe.mu.Unlock()
+			remaining = append(remaining, decision)
+			pe.scheduleDrain(ctx, verdict)
+			continue
+		}
+
+		pe.mu.Lock()
+		delete(status.Details, "queued")
+		pe.mu.Unlock()
+		pe.addEvent(status, EnforcementEvent{
+			Type:      "dequeued",
+			Message:   "Locked workloads and admitted by safety limits",
+			Timestamp: time.Now(),
+		})
+		pe.begin(ctx, stored, status)
+	}
+
+	// Decisions queued while draining go after the ones still waiting
+	pe.mu.Lock()
+	pe.queue = append(remaining, pe.queue...)
+	pe.mu.Unlock()
+}
+
+// scheduleDrain retries the queue once hourly budget frees up; concurrency
+// budget is retried whenever an enforcement finishes
+func (pe *policyEnforcer) scheduleDrain(ctx context.Context, verdict *SafetyVerdict) {
+	if verdict.RetryAfter <= 0 {
+		return
+	}
+	base := context.WithoutCancel(ctx)
+	time.AfterFunc(verdict.RetryAfter, func() {
+		pe.drainQueue(base)
+	})
+}
+
+// dequeue removes a decision from the queue, returning false if it was not
+// queued. The caller must hold pe.mu.
+func (pe *policyEnforcer) dequeue(decisionID string) bool {
+	for i, decision := range pe.queue {
+		if decision.ID == decisionID {
+			pe.queue = append(pe.queue[:i], pe.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
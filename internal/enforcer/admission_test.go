@@ -0,0 +1,418 @@
+package enforcer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// blockingExecutor runs actions of a type until release is closed
+func blockingExecutor(actionType string) (*stubExecutor, chan struct{}) {
+	release := make(chan struct{})
+	executor := newStubExecutor(actionType, func(ctx context.Context, action *Action) (*ActionResult, error) {
+		select {
+		case <-release:
+			return succeeded(action, nil), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+	return executor, release
+}
+
+func safetyConfig(overLimit string) config.EnforcementConfig {
+	return config.EnforcementConfig{Safety: config.SafetyConfig{
+		Enabled: true,
+		Limits: []config.SafetyLimit{{
+			Name:          "migrations",
+			ActionTypes:   []string{ActionTypeMigrate},
+			MaxConcurrent: 1,
+			OverLimit:     overLimit,
+		}},
+	}}
+}
+
+func TestAdmission_QueuedDecisionDrainsWhenBudgetFrees(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, safetyConfig("queue"))
+	newTestWorkload(t, te.store, "wl-1")
+	newTestWorkload(t, te.store, "wl-2")
+
+	migrator, release := blockingExecutor(ActionTypeMigrate)
+	require.NoError(t, te.registry.Register(migrator))
+
+	first := te.approve(t, migrateDecision("d-1", "wl-1"))
+	second := te.approve(t, migrateDecision("d-2", "wl-2"))
+	require.NoError(t, te.Enforce(ctx, first))
+	te.waitForState(t, "d-1", EnforcementStateRunning)
+
+	require.NoError(t, te.Enforce(ctx, second))
+	status, err := te.GetEnforcementStatus(ctx, "d-2")
+	require.NoError(t, err)
+	assert.Equal(t, EnforcementStatePending, status.Status)
+	assert.Equal(t, true, status.Details["queued"])
+	assert.Equal(t, "migrations", status.Details["safetyLimit"])
+	assert.Contains(t, status.Message, "Queued")
+
+	stored, err := te.store.Decision().Get(ctx, "d-2")
+	require.NoError(t, err)
+	assert.Equal(t, types.DecisionStatusApproved, stored.Status)
+
+	// The first enforcement finishing frees the budget for the second
+	close(release)
+	te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+	te.waitForDecision(t, "d-2", types.DecisionStatusCompleted)
+
+	status, err = te.GetEnforcementStatus(ctx, "d-2")
+	require.NoError(t, err)
+	assert.NotContains(t, status.Details, "queued")
+	assert.Len(t, migrator.executed(), 2)
+
+	var dequeued bool
+	for _, event := range status.Events {
+		dequeued = dequeued || event.Type == "dequeued"
+	}
+	assert.True(t, dequeued)
+}
+
+func TestAdmission_RejectMovesDecisionToRejected(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, safetyConfig("reject"))
+	newTestWorkload(t, te.store, "wl-1")
+	newTestWorkload(t, te.store, "wl-2")
+
+	migrator, release := blockingExecutor(ActionTypeMigrate)
+	require.NoError(t, te.registry.Register(migrator))
+	defer close(release)
+
+	first := te.approve(t, migrateDecision("d-1", "wl-1"))
+	second := te.approve(t, migrateDecision("d-2", "wl-2"))
+	require.NoError(t, te.Enforce(ctx, first))
+	te.waitForState(t, "d-1", EnforcementStateRunning)
+
+	err := te.Enforce(ctx, second)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, types.ErrSafetyLimitExceeded)
+
+	stored, err := te.store.Decision().Get(ctx, "d-2")
+	require.NoError(t, err)
+	assert.Equal(t, types.DecisionStatusRejected, stored.Status)
+
+	status, err := te.GetEnforcementStatus(ctx, "d-2")
+	require.NoError(t, err)
+	assert.Equal(t, EnforcementStateFailed, status.Status)
+	assert.NotContains(t, status.Details, "queued")
+
+	history, err := te.store.Decision().GetHistory(ctx, "d-2")
+	require.NoError(t, err)
+	require.NotEmpty(t, history)
+	assert.Equal(t, "safety_reject", history[0].Action)
+
+	// The rejected decision released its locks
+	for _, lease := range te.locks.Leases() {
+		assert.NotEqual(t, "d-2", lease.DecisionID)
+	}
+	assert.Len(t, migrator.executed(), 1)
+}
+
+func TestAdmission_QueuedDecisionNoLongerApprovedIsCancelled(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, safetyConfig("queue"))
+	newTestWorkload(t, te.store, "wl-1")
+	newTestWorkload(t, te.store, "wl-2")
+
+	migrator, release := blockingExecutor(ActionTypeMigrate)
+	require.NoError(t, te.registry.Register(migrator))
+
+	first := te.approve(t, migrateDecision("d-1", "wl-1"))
+	second := te.approve(t, migrateDecision("d-2", "wl-2"))
+	require.NoError(t, te.Enforce(ctx, first))
+	te.waitForState(t, "d-1", EnforcementStateRunning)
+	require.NoError(t, te.Enforce(ctx, second))
+
+	// The queued decision is cancelled by its reviewer before budget frees
+	stored, err := te.store.Decision().Get(ctx, "d-2")
+	require.NoError(t, err)
+	stored.Status = types.DecisionStatusCancelled
+	require.NoError(t, te.store.Decision().Update(ctx, stored))
+
+	close(release)
+	te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+	te.waitForState(t, "d-2", EnforcementStateCancelled)
+	assert.Len(t, migrator.executed(), 1)
+}
+
+func TestAdmission_ChangeFreezeBlocks(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+	now := time.Now()
+	require.NoError(t, te.store.Maintenance().Create(ctx, freezePeriod("release", now.Add(-time.Hour), now.Add(time.Hour), types.FreezeActionBlock)))
+
+	migrator := newStubExecutor(ActionTypeMigrate, nil)
+	require.NoError(t, te.registry.Register(migrator))
+
+	err := te.Enforce(ctx, te.approve(t, migrateDecision("d-1", "wl-1")))
+	require.Error(t, err)
+	assert.ErrorIs(t, err, types.ErrChangeFreeze)
+
+	status, err := te.GetEnforcementStatus(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, EnforcementStateFailed, status.Status)
+	assert.Equal(t, "release", status.Details["maintenanceWindow"])
+
+	// The decision stays approved so it can be enforced after the freeze
+	stored, err := te.store.Decision().Get(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, types.DecisionStatusApproved, stored.Status)
+	assert.Empty(t, migrator.executed())
+}
+
+func TestAdmission_DeferredDecisionResumesWhenFreezeEnds(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+	now := time.Now()
+	end := now.Add(300 * time.Millisecond)
+	require.NoError(t, te.store.Maintenance().Create(ctx, freezePeriod("release", now.Add(-time.Hour), end, "")))
+
+	migrator := newStubExecutor(ActionTypeMigrate, nil)
+	require.NoError(t, te.registry.Register(migrator))
+
+	require.NoError(t, te.Enforce(ctx, te.approve(t, migrateDecision("d-1", "wl-1"))))
+	status, err := te.GetEnforcementStatus(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, EnforcementStatePending, status.Status)
+	assert.Equal(t, true, status.Details["deferred"])
+	assert.Equal(t, end, status.Details["deferredUntil"])
+	assert.Equal(t, "release", status.Details["maintenanceWindow"])
+
+	stored, err := te.store.Decision().Get(ctx, "d-1")
+	require.NoError(t, err)
+	require.NotNil(t, stored.ScheduledFor)
+	assert.True(t, end.Equal(*stored.ScheduledFor))
+	assert.Empty(t, migrator.executed())
+
+	// The timer admits the decision again once the freeze has ended
+	te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+	assert.Len(t, migrator.executed(), 1)
+
+	stored, err = te.store.Decision().Get(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Nil(t, stored.ScheduledFor)
+
+	status, err = te.GetEnforcementStatus(ctx, "d-1")
+	require.NoError(t, err)
+	assert.NotContains(t, status.Details, "deferred")
+	var resumed bool
+	for _, event := range status.Events {
+		resumed = resumed || event.Type == "resumed"
+	}
+	assert.True(t, resumed)
+}
+
+func TestAdmission_DeferredToNextMaintenanceWindow(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+	opening := time.Now().UTC().Truncate(time.Hour).Add(2 * time.Hour)
+	require.NoError(t, te.store.Maintenance().Create(ctx, dailyWindow("nightly", opening.Hour())))
+
+	require.NoError(t, te.Enforce(ctx, te.approve(t, migrateDecision("d-1", "wl-1"))))
+	status, err := te.GetEnforcementStatus(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, EnforcementStatePending, status.Status)
+	assert.Equal(t, "nightly", status.Details["maintenanceWindow"])
+	until, ok := status.Details["deferredUntil"].(time.Time)
+	require.True(t, ok)
+	assert.True(t, opening.Equal(until), "deferred until %s, want %s", until, opening)
+
+	stored, err := te.store.Decision().Get(ctx, "d-1")
+	require.NoError(t, err)
+	require.NotNil(t, stored.ScheduledFor)
+	assert.True(t, opening.Equal(*stored.ScheduledFor))
+
+	// Cancelling a deferred enforcement stops its timer
+	require.NoError(t, te.CancelEnforcement(ctx, "d-1"))
+	te.mu.RLock()
+	assert.NotContains(t, te.timers, "d-1")
+	te.mu.RUnlock()
+	te.waitForDecision(t, "d-1", types.DecisionStatusCancelled)
+}
+
+func TestAdmission_ResumedDecisionDefersAgainWhenFreezeExtended(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+	now := time.Now()
+	freeze := freezePeriod("release", now.Add(-time.Hour), now.Add(200*time.Millisecond), "")
+	require.NoError(t, te.store.Maintenance().Create(ctx, freeze))
+
+	require.NoError(t, te.Enforce(ctx, te.approve(t, migrateDecision("d-1", "wl-1"))))
+
+	// The freeze is extended before the deferred decision resumes
+	extended := now.Add(time.Hour)
+	freeze.End = &extended
+	require.NoError(t, te.store.Maintenance().Update(ctx, freeze))
+
+	require.Eventually(t, func() bool {
+		status, err := te.GetEnforcementStatus(ctx, "d-1")
+		if err != nil {
+			return false
+		}
+		te.mu.RLock()
+		defer te.mu.RUnlock()
+		until, _ := status.Details["deferredUntil"].(time.Time)
+		return until.Equal(extended)
+	}, 2*time.Second, 5*time.Millisecond)
+
+	status, err := te.GetEnforcementStatus(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, EnforcementStatePending, status.Status)
+	assert.Equal(t, true, status.Details["deferred"])
+	require.NoError(t, te.CancelEnforcement(ctx, "d-1"))
+}
+
+func TestAdmission_LockConflictWaitsForHolder(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{Locks: config.LockConfig{Supersede: true}})
+	newTestWorkload(t, te.store, "wl-1")
+
+	migrator, release := blockingExecutor(ActionTypeMigrate)
+	require.NoError(t, te.registry.Register(migrator))
+
+	first := te.approve(t, migrateDecision("d-1", "wl-1"))
+	second := te.approve(t, migrateDecision("d-2", "wl-1"))
+	require.NoError(t, te.Enforce(ctx, first))
+	te.waitForState(t, "d-1", EnforcementStateRunning)
+
+	// Equal priority waits rather than superseding the holder
+	require.NoError(t, te.Enforce(ctx, second))
+	status, err := te.GetEnforcementStatus(ctx, "d-2")
+	require.NoError(t, err)
+	assert.Equal(t, EnforcementStatePending, status.Status)
+	assert.Equal(t, true, status.Details["queued"])
+	assert.Contains(t, status.Message, "held by decision d-1")
+	require.NotNil(t, status.Lock)
+	assert.Equal(t, "d-1", status.Lock.Holder)
+	assert.False(t, status.Lock.Held)
+
+	leases := te.locks.Leases()
+	require.Len(t, leases, 1)
+	assert.Equal(t, "d-1", leases[0].DecisionID)
+
+	close(release)
+	te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+	te.waitForDecision(t, "d-2", types.DecisionStatusCompleted)
+	assert.Len(t, migrator.executed(), 2)
+	assert.Empty(t, te.locks.Leases())
+
+	status, err = te.GetEnforcementStatus(ctx, "d-2")
+	require.NoError(t, err)
+	waits := 0
+	for _, event := range status.Events {
+		if event.Type == "lock_wait" {
+			waits++
+		}
+	}
+	assert.Equal(t, 1, waits)
+}
+
+func TestAdmission_HigherPrioritySupersedesHolder(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{Locks: config.LockConfig{Supersede: true}})
+	newTestWorkload(t, te.store, "wl-1")
+
+	// The holder moves the workload, then hangs until it is cancelled
+	mover := NewMigrateExecutor(te.store, testLogger{})
+	moved := make(chan struct{})
+	migrator := newStubExecutor(ActionTypeMigrate, func(ctx context.Context, action *Action) (*ActionResult, error) {
+		result, err := mover.Execute(ctx, action)
+		if err != nil || action.Metadata["decision_id"] != "d-1" || action.Metadata["compensates"] != nil {
+			return result, err
+		}
+		close(moved)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	require.NoError(t, te.registry.Register(migrator))
+
+	holder := migrateDecision("d-1", "wl-1")
+	holder.Details = map[string]interface{}{"priority": int(types.PriorityLow)}
+	require.NoError(t, te.Enforce(ctx, te.approve(t, holder)))
+	<-moved
+	assert.True(t, placedOn(storedWorkload(t, te.store, "wl-1"), "cluster-b", "node-2"))
+
+	urgent := &types.Decision{
+		ID:                 "d-2",
+		Type:               types.DecisionTypeMigrate,
+		WorkloadID:         "wl-1",
+		RecommendedCluster: "cluster-c",
+		RecommendedNode:    "node-3",
+		Details:            map[string]interface{}{"priority": int(types.PriorityCritical)},
+	}
+	require.NoError(t, te.Enforce(ctx, te.approve(t, urgent)))
+
+	// The holder is cancelled and rolled back before the urgent decision runs
+	te.waitForDecision(t, "d-1", types.DecisionStatusCancelled)
+	te.waitForDecision(t, "d-2", types.DecisionStatusCompleted)
+	assert.True(t, placedOn(storedWorkload(t, te.store, "wl-1"), "cluster-c", "node-3"))
+
+	calls := migrator.executed()
+	require.Len(t, calls, 3)
+	assert.Equal(t, ActionTypeMigrate, calls[1].Metadata["compensates"])
+	assert.Equal(t, "cluster-a", calls[1].Parameters["target_cluster"])
+	assert.Equal(t, "node-1", calls[1].Parameters["target_node"])
+	assert.Equal(t, "d-2", calls[2].Metadata["decision_id"])
+
+	status, err := te.GetEnforcementStatus(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, EnforcementStateCancelled, status.Status)
+	var superseded, rolledBack bool
+	for _, event := range status.Events {
+		superseded = superseded || event.Type == "superseded"
+		rolledBack = rolledBack || event.Type == "rollback_action_completed"
+	}
+	assert.True(t, superseded)
+	assert.True(t, rolledBack)
+
+	history, err := te.store.Decision().GetHistory(ctx, "d-1")
+	require.NoError(t, err)
+	var recorded bool
+	for _, entry := range history {
+		recorded = recorded || entry.Action == "superseded"
+	}
+	assert.True(t, recorded)
+}
+
+func TestAdmission_LowerPriorityDoesNotSupersede(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{Locks: config.LockConfig{Supersede: true}})
+	newTestWorkload(t, te.store, "wl-1")
+
+	migrator, release := blockingExecutor(ActionTypeMigrate)
+	require.NoError(t, te.registry.Register(migrator))
+	defer close(release)
+
+	holder := migrateDecision("d-1", "wl-1")
+	holder.Details = map[string]interface{}{"priority": int(types.PriorityHigh)}
+	require.NoError(t, te.Enforce(ctx, te.approve(t, holder)))
+	te.waitForState(t, "d-1", EnforcementStateRunning)
+
+	waiting := migrateDecision("d-2", "wl-1")
+	waiting.Details = map[string]interface{}{"priority": int(types.PriorityLow)}
+	require.NoError(t, te.Enforce(ctx, te.approve(t, waiting)))
+
+	status, err := te.GetEnforcementStatus(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, EnforcementStateRunning, status.Status)
+	status, err = te.GetEnforcementStatus(ctx, "d-2")
+	require.NoError(t, err)
+	assert.Equal(t, true, status.Details["queued"])
+}
@@ -0,0 +1,253 @@
+package enforcer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/decisions"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// SystemApprover is the approver recorded for automatic approvals and
+// rejections
+const SystemApprover = "system:auto-approval"
+
+// approvalManager implements ApprovalManager interface
+type approvalManager struct {
+	storage   storage.StorageManager
+	lifecycle decisions.Lifecycle
+	config    config.ApprovalConfig
+	logger    types.Logger
+}
+
+// NewApprovalManager creates a new approval manager
+func NewApprovalManager(storage storage.StorageManager, lifecycle decisions.Lifecycle, cfg config.ApprovalConfig, logger types.Logger) ApprovalManager {
+	for _, rule := range cfg.Rules {
+		if !validApprovalAction(rule.Action) {
+			logger.Warn("approval rule has unknown action, matching decisions will wait for review",
+				"rule", rule.Name, "action", rule.Action)
+		}
+	}
+
+	return &approvalManager{
+		storage:   storage,
+		lifecycle: lifecycle,
+		config:    cfg,
+		logger:    logger,
+	}
+}
+
+// Submit applies the approval rules to a pending decision. The first
+// matching rule approves, rejects or holds it for review; unmatched
+// decisions follow the default action, and every decision auto-approves
+// when the workflow is disabled.
+func (am *approvalManager) Submit(ctx context.Context, decision *types.Decision) (*ApprovalOutcome, error) {
+	if decision == nil {
+		return nil, fmt.Errorf("decision cannot be nil")
+	}
+
+	stored, err := am.storage.Decision().Get(ctx, decision.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !stored.IsPending() {
+		return nil, types.NewDecisionError(stored.ID, string(stored.Type), stored.WorkloadID, stored.PolicyID, "submit", types.ErrInvalidDecisionStatus)
+	}
+
+	// Decisions that span many workloads have no single namespace or
+	// environment, so rules matching on those never apply to them
+	workload, err := am.storage.Workload().Get(ctx, stored.WorkloadID)
+	if err != nil {
+		workload = nil
+	}
+
+	outcome := am.evaluate(stored, workload)
+	switch outcome.Action {
+	case ApprovalActionAutoApprove:
+		if err := am.transition(ctx, stored, types.DecisionStatusApproved, "approve", SystemApprover, outcome.Reason); err != nil {
+			return nil, err
+		}
+	case ApprovalActionAutoReject:
+		if err := am.transition(ctx, stored, types.DecisionStatusRejected, "reject", SystemApprover, outcome.Reason); err != nil {
+			return nil, err
+		}
+	default:
+		if err := am.record(ctx, stored, "review_requested", SystemApprover, outcome.Reason); err != nil {
+			return nil, err
+		}
+	}
+
+	outcome.Status = stored.Status
+	*decision = *stored
+
+	am.logger.Info("decision submitted for approval",
+		"decision_id", stored.ID,
+		"decision_type", stored.Type,
+		"action", outcome.Action,
+		"rule", outcome.Rule,
+		"status", outcome.Status)
+
+	return outcome, nil
+}
+
+// Approve approves a pending decision on behalf of a reviewer
+func (am *approvalManager) Approve(ctx context.Context, decisionID, approver, comment string) (*types.Decision, error) {
+	return am.review(ctx, decisionID, approver, comment, types.DecisionStatusApproved, "approve")
+}
+
+// Reject rejects a pending decision on behalf of a reviewer
+func (am *approvalManager) Reject(ctx context.Context, decisionID, approver, comment string) (*types.Decision, error) {
+	return am.review(ctx, decisionID, approver, comment, types.DecisionStatusRejected, "reject")
+}
+
+// Health checks the health of the approval manager
+func (am *approvalManager) Health(ctx context.Context) error {
+	if am.storage == nil {
+		return fmt.Errorf("storage not configured")
+	}
+	return am.storage.Health(ctx)
+}
+
+// review records a reviewer's verdict on a pending decision
+func (am *approvalManager) review(ctx context.Context, decisionID, approver, comment string, status types.DecisionStatus, action string) (*types.Decision, error) {
+	if approver == "" {
+		return nil, fmt.Errorf("approver is required")
+	}
+
+	decision, err := am.storage.Decision().Get(ctx, decisionID)
+	if err != nil {
+		return nil, err
+	}
+	if !decision.IsPending() {
+		return nil, types.NewDecisionError(decision.ID, string(decision.Type), decision.WorkloadID, decision.PolicyID, action, types.ErrInvalidDecisionStatus)
+	}
+
+	if err := am.transition(ctx, decision, status, action, approver, comment); err != nil {
+		return nil, err
+	}
+
+	am.logger.Info("decision reviewed",
+		"decision_id", decision.ID,
+		"status", status,
+		"approver", approver)
+
+	return decision, nil
+}
+
+// transition updates a decision's status and records who changed it
+func (am *approvalManager) transition(ctx context.Context, decision *types.Decision, status types.DecisionStatus, action, approver, comment string) error {
+	return am.lifecycle.Transition(ctx, decision, decisions.Change{
+		Status:  status,
+		Action:  action,
+		Actor:   approver,
+		Comment: comment,
+	})
+}
+
+// record appends an approval entry to the decision's history
+func (am *approvalManager) record(ctx context.Context, decision *types.Decision, action, approver, comment string) error {
+	now := time.Now()
+	return am.storage.Decision().AddHistory(ctx, &types.DecisionHistory{
+		DecisionID: decision.ID,
+		WorkloadID: decision.WorkloadID,
+		Action:     action,
+		Status:     decision.Status,
+		StartTime:  now,
+		EndTime:    &now,
+		Result:     string(decision.Status),
+		Approver:   approver,
+		Comment:    comment,
+	})
+}
+
+// evaluate decides how a decision is approved
+func (am *approvalManager) evaluate(decision *types.Decision, workload *types.Workload) *ApprovalOutcome {
+	outcome := &ApprovalOutcome{DecisionID: decision.ID}
+
+	if !am.config.Enabled {
+		outcome.Action = ApprovalActionAutoApprove
+		outcome.Reason = "approval workflow disabled"
+		return outcome
+	}
+
+	for _, rule := range am.config.Rules {
+		if ruleMatches(rule, decision, workload) {
+			outcome.Action = approvalAction(rule.Action)
+			outcome.Rule = rule.Name
+			outcome.Reason = fmt.Sprintf("matched approval rule %s", rule.Name)
+			return outcome
+		}
+	}
+
+	outcome.Action = approvalAction(am.config.DefaultAction)
+	outcome.Reason = "no approval rule matched"
+	return outcome
+}
+
+// ruleMatches returns true if every matcher of the rule accepts the decision
+func ruleMatches(rule config.ApprovalRule, decision *types.Decision, workload *types.Workload) bool {
+	if len(rule.DecisionTypes) > 0 && !containsValue(rule.DecisionTypes, string(decision.Type)) {
+		return false
+	}
+	if len(rule.Namespaces) > 0 && (workload == nil || !containsValue(rule.Namespaces, workload.Metadata.Namespace)) {
+		return false
+	}
+	if len(rule.Environments) > 0 && (workload == nil || !containsValue(rule.Environments, workload.Metadata.Environment)) {
+		return false
+	}
+	if rule.MinConfidence > 0 && decision.Confidence < rule.MinConfidence {
+		return false
+	}
+	if rule.MaxCostDelta != nil && costDelta(decision) > *rule.MaxCostDelta {
+		return false
+	}
+	return true
+}
+
+// costDelta returns the estimated change in hourly cost a decision causes,
+// read from the costDelta detail and falling back to EstimatedCost
+func costDelta(decision *types.Decision) float64 {
+	if value, exists := decision.GetDetail("costDelta"); exists {
+		switch v := value.(type) {
+		case float64:
+			return v
+		case int:
+			return float64(v)
+		}
+	}
+	return decision.EstimatedCost
+}
+
+// approvalAction maps a configured action onto an ApprovalAction, treating
+// unknown actions as requiring review
+func approvalAction(action string) ApprovalAction {
+	switch ApprovalAction(action) {
+	case ApprovalActionAutoApprove, ApprovalActionAutoReject:
+		return ApprovalAction(action)
+	default:
+		return ApprovalActionReview
+	}
+}
+
+// validApprovalAction returns true if the configured action is known
+func validApprovalAction(action string) bool {
+	switch ApprovalAction(action) {
+	case ApprovalActionAutoApprove, ApprovalActionAutoReject, ApprovalActionReview:
+		return true
+	default:
+		return false
+	}
+}
+
+// containsValue returns true if values contains value
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package enforcer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/decisions"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+func approvalConfig(rules ...config.ApprovalRule) config.ApprovalConfig {
+	return config.ApprovalConfig{Enabled: true, DefaultAction: string(ApprovalActionReview), Rules: rules}
+}
+
+func TestApprovalManager_Evaluate(t *testing.T) {
+	maxCost := 1.0
+	production := &types.Workload{Metadata: types.WorkloadMetadata{Namespace: "payments", Environment: "production"}}
+	staging := &types.Workload{Metadata: types.WorkloadMetadata{Namespace: "payments", Environment: "staging"}}
+
+	tests := []struct {
+		name     string
+		config   config.ApprovalConfig
+		decision *types.Decision
+		workload *types.Workload
+		action   ApprovalAction
+		rule     string
+	}{
+		{
+			name:     "workflow disabled",
+			config:   config.ApprovalConfig{DefaultAction: string(ApprovalActionReview)},
+			decision: &types.Decision{Type: types.DecisionTypeMigrate},
+			action:   ApprovalActionAutoApprove,
+		},
+		{
+			name:     "default action",
+			config:   approvalConfig(config.ApprovalRule{Name: "scale", Action: "auto_approve", DecisionTypes: []string{"scale"}}),
+			decision: &types.Decision{Type: types.DecisionTypeMigrate},
+			action:   ApprovalActionReview,
+		},
+		{
+			name:     "decision type",
+			config:   approvalConfig(config.ApprovalRule{Name: "migrate", Action: "auto_approve", DecisionTypes: []string{"migrate"}}),
+			decision: &types.Decision{Type: types.DecisionTypeMigrate},
+			action:   ApprovalActionAutoApprove,
+			rule:     "migrate",
+		},
+		{
+			name: "first match wins",
+			config: approvalConfig(
+				config.ApprovalRule{Name: "production", Action: "auto_reject", Environments: []string{"production"}},
+				config.ApprovalRule{Name: "payments", Action: "auto_approve", Namespaces: []string{"payments"}},
+			),
+			decision: &types.Decision{Type: types.DecisionTypeMigrate},
+			workload: production,
+			action:   ApprovalActionAutoReject,
+			rule:     "production",
+		},
+		{
+			name: "environment mismatch falls through",
+			config: approvalConfig(
+				config.ApprovalRule{Name: "production", Action: "auto_reject", Environments: []string{"production"}},
+				config.ApprovalRule{Name: "payments", Action: "auto_approve", Namespaces: []string{"payments"}},
+			),
+			decision: &types.Decision{Type: types.DecisionTypeMigrate},
+			workload: staging,
+			action:   ApprovalActionAutoApprove,
+			rule:     "payments",
+		},
+		{
+			name:     "namespace rule skips decisions without a workload",
+			config:   approvalConfig(config.ApprovalRule{Name: "payments", Action: "auto_approve", Namespaces: []string{"payments"}}),
+			decision: &types.Decision{Type: types.DecisionTypeConsolidate},
+			action:   ApprovalActionReview,
+		},
+		{
+			name:     "confidence below threshold",
+			config:   approvalConfig(config.ApprovalRule{Name: "confident", Action: "auto_approve", MinConfidence: 0.9}),
+			decision: &types.Decision{Type: types.DecisionTypeScale, Confidence: 0.8},
+			action:   ApprovalActionReview,
+		},
+		{
+			name:     "confidence at threshold",
+			config:   approvalConfig(config.ApprovalRule{Name: "confident", Action: "auto_approve", MinConfidence: 0.9}),
+			decision: &types.Decision{Type: types.DecisionTypeScale, Confidence: 0.9},
+			action:   ApprovalActionAutoApprove,
+			rule:     "confident",
+		},
+		{
+			name:     "cost delta detail over limit",
+			config:   approvalConfig(config.ApprovalRule{Name: "cheap", Action: "auto_approve", MaxCostDelta: &maxCost}),
+			decision: &types.Decision{Type: types.DecisionTypeScale, Details: map[string]interface{}{"costDelta": 2.5}},
+			action:   ApprovalActionReview,
+		},
+		{
+			name:     "cost delta falls back to estimated cost",
+			config:   approvalConfig(config.ApprovalRule{Name: "cheap", Action: "auto_approve", MaxCostDelta: &maxCost}),
+			decision: &types.Decision{Type: types.DecisionTypeScale, EstimatedCost: 0.5},
+			action:   ApprovalActionAutoApprove,
+			rule:     "cheap",
+		},
+		{
+			name:     "unknown action waits for review",
+			config:   approvalConfig(config.ApprovalRule{Name: "typo", Action: "approve"}),
+			decision: &types.Decision{Type: types.DecisionTypeScale},
+			action:   ApprovalActionReview,
+			rule:     "typo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			am := NewApprovalManager(nil, nil, tt.config, testLogger{}).(*approvalManager)
+
+			outcome := am.evaluate(tt.decision, tt.workload)
+			assert.Equal(t, tt.action, outcome.Action)
+			assert.Equal(t, tt.rule, outcome.Rule)
+			assert.NotEmpty(t, outcome.Reason)
+		})
+	}
+}
+
+func TestApprovalManager_SubmittedOnCreate(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		status types.DecisionStatus
+		record string
+	}{
+		{"auto approve", "auto_approve", types.DecisionStatusApproved, "approve"},
+		{"auto reject", "auto_reject", types.DecisionStatusRejected, "reject"},
+		{"review", "review", types.DecisionStatusPending, "review_requested"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := memory.NewStorageManager()
+			lifecycle := decisions.NewLifecycle(store, config.DecisionConfig{}, testLogger{})
+			am := NewApprovalManager(store, lifecycle, approvalConfig(
+				config.ApprovalRule{Name: "migrations", Action: tt.action, DecisionTypes: []string{"migrate"}},
+			), testLogger{})
+			lifecycle.SetApprover(func(ctx context.Context, decision *types.Decision) error {
+				_, err := am.Submit(ctx, decision)
+				return err
+			})
+
+			decision := &types.Decision{ID: "d-1", Type: types.DecisionTypeMigrate, WorkloadID: "wl-1", PolicyID: "policy-1"}
+			require.NoError(t, lifecycle.Create(ctx, decision))
+			assert.Equal(t, tt.status, decision.Status)
+
+			stored, err := store.Decision().Get(ctx, "d-1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, stored.Status)
+
+			history, err := store.Decision().GetHistory(ctx, "d-1")
+			require.NoError(t, err)
+			require.NotEmpty(t, history)
+			last := history[len(history)-1]
+			assert.Equal(t, tt.record, last.Action)
+			assert.Equal(t, SystemApprover, last.Approver)
+			assert.Equal(t, "matched approval rule migrations", last.Comment)
+		})
+	}
+}
+
+func TestApprovalManager_ReviewRequiresPending(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStorageManager()
+	lifecycle := decisions.NewLifecycle(store, config.DecisionConfig{}, testLogger{})
+	am := NewApprovalManager(store, lifecycle, approvalConfig(), testLogger{})
+
+	require.NoError(t, lifecycle.Create(ctx, &types.Decision{ID: "d-1", Type: types.DecisionTypeMigrate, WorkloadID: "wl-1", PolicyID: "policy-1"}))
+
+	_, err := am.Approve(ctx, "d-1", "", "")
+	assert.Error(t, err)
+
+	decision, err := am.Approve(ctx, "d-1", "alice", "looks good")
+	require.NoError(t, err)
+	assert.Equal(t, types.DecisionStatusApproved, decision.Status)
+
+	_, err = am.Reject(ctx, "d-1", "bob", "too late")
+	assert.ErrorIs(t, err, types.ErrInvalidDecisionStatus)
+}
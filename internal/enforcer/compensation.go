@@ -0,0 +1,130 @@
+package enforcer
+
+import (
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// compensationFor builds the action that undoes a successfully executed
+// action. Relocations are undone from the workload state captured before
+// the action ran and scaling from the previous state its executor reported
+// in result. Actions without an inverse, such as notifications and
+// terminations, return nil.
+func compensationFor(action *Action, before, result map[string]interface{}) *Action {
+	workloadID, _ := action.Parameters["workload_id"].(string)
+	if workloadID == "" {
+		return nil
+	}
+
+	switch action.Type {
+	case ActionTypeSuspend:
+		return compensationAction(ActionTypeResume, action, map[string]interface{}{
+			"workload_id": workloadID,
+			"reason":      "rollback",
+		}, 2*time.Minute)
+	case ActionTypeResume:
+		return compensationAction(ActionTypeSuspend, action, map[string]interface{}{
+			"workload_id": workloadID,
+			"reason":      "rollback",
+		}, 2*time.Minute)
+	case ActionTypeScale:
+		parameters := previousScale(result)
+		if parameters == nil {
+			return nil
+		}
+		parameters["workload_id"] = workloadID
+		return compensationAction(ActionTypeScale, action, parameters, 5*time.Minute)
+	case ActionTypeMigrate, ActionTypeReschedule:
+		if before == nil {
+			return nil
+		}
+		sourceCluster, _ := before["cluster"].(string)
+		sourceNode, _ := before["node"].(string)
+		if sourceCluster == "" && sourceNode == "" {
+			return nil
+		}
+
+		// A workload that had no node placement goes back to its cluster's
+		// scheduler rather than to a specific node
+		if sourceNode == "" {
+			return compensationAction(ActionTypeReschedule, action, map[string]interface{}{
+				"workload_id":         workloadID,
+				"current_cluster":     action.Parameters["target_cluster"],
+				"recommended_cluster": sourceCluster,
+				"reason":              "rollback",
+			}, 10*time.Minute)
+		}
+		return compensationAction(ActionTypeMigrate, action, map[string]interface{}{
+			"workload_id":        workloadID,
+			"source_cluster":     action.Parameters["target_cluster"],
+			"target_cluster":     sourceCluster,
+			"source_node":        action.Parameters["target_node"],
+			"target_node":        sourceNode,
+			"migration_strategy": "live",
+		}, 15*time.Minute)
+	default:
+		return nil
+	}
+}
+
+// compensationAction creates a compensating action for the original action
+func compensationAction(actionType string, original *Action, parameters map[string]interface{}, timeout time.Duration) *Action {
+	return &Action{
+		Type:       actionType,
+		Target:     original.Target,
+		Parameters: parameters,
+		Timeout:    timeout,
+		Metadata: map[string]interface{}{
+			"compensates": original.Type,
+			"decision_id": original.Metadata["decision_id"],
+		},
+	}
+}
+
+// previousScale builds the scale parameters that restore the state a scale
+// executor reported it changed: autoscaler bounds, a replica count, or
+// resource requests. It returns nil when the result records none of them.
+func previousScale(result map[string]interface{}) map[string]interface{} {
+	if result == nil {
+		return nil
+	}
+
+	parameters := make(map[string]interface{})
+	if minReplicas, ok := result["previous_min_replicas"]; ok {
+		parameters["min_replicas"] = minReplicas
+		parameters["max_replicas"] = result["previous_max_replicas"]
+		return parameters
+	}
+	if replicas, ok := result["previous_replicas"]; ok {
+		parameters["replicas"] = replicas
+	}
+	if cpu, ok, err := numberParam(result, "previous_cpu"); ok && err == nil && cpu > 0 {
+		parameters["cpu"] = result["previous_cpu"]
+	}
+	if memory, _ := result["previous_memory"].(string); memory != "" {
+		parameters["memory"] = memory
+	}
+	if len(parameters) == 0 {
+		return nil
+	}
+	return parameters
+}
+
+// snapshotResources reads the resource requirements of a workload snapshot,
+// accepting both in-memory and JSON-decoded shapes
+func snapshotResources(snapshot map[string]interface{}) (types.Resources, bool) {
+	switch requirements := snapshot["requirements"].(type) {
+	case types.Resources:
+		return requirements, true
+	case map[string]interface{}:
+		var resources types.Resources
+		if cpu, ok, err := numberParam(requirements, "cpu"); ok && err == nil {
+			resources.CPU = int(cpu)
+		}
+		resources.Memory, _ = requirements["memory"].(string)
+		return resources, true
+	default:
+		return types.Resources{}, false
+	}
+}
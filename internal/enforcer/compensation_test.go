@@ -0,0 +1,132 @@
+package enforcer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+func TestCompensationFor_ScaleUsesExecutorResult(t *testing.T) {
+	action := &Action{Type: ActionTypeScale, Target: "wl-1", Parameters: map[string]interface{}{"workload_id": "wl-1"}}
+	before := map[string]interface{}{"replicas": 2, "requirements": types.Resources{CPU: 4, Memory: "4Gi"}}
+
+	tests := []struct {
+		name       string
+		result     map[string]interface{}
+		parameters map[string]interface{}
+	}{
+		{
+			name:       "replica count",
+			result:     map[string]interface{}{"previous_replicas": int32(3), "replicas": int32(6)},
+			parameters: map[string]interface{}{"workload_id": "wl-1", "replicas": int32(3)},
+		},
+		{
+			name:       "autoscaler bounds",
+			result:     map[string]interface{}{"previous_min_replicas": int32(2), "previous_max_replicas": int32(10), "previous_replicas": int32(3)},
+			parameters: map[string]interface{}{"workload_id": "wl-1", "min_replicas": int32(2), "max_replicas": int32(10)},
+		},
+		{
+			name:       "resource requests",
+			result:     map[string]interface{}{"previous_replicas": 2, "previous_cpu": 4, "previous_memory": "4Gi"},
+			parameters: map[string]interface{}{"workload_id": "wl-1", "replicas": 2, "cpu": 4, "memory": "4Gi"},
+		},
+		{
+			name:       "decoded from JSON",
+			result:     map[string]interface{}{"previous_replicas": 2.0, "previous_cpu": 0.0, "previous_memory": ""},
+			parameters: map[string]interface{}{"workload_id": "wl-1", "replicas": 2.0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compensation := compensationFor(action, before, tt.result)
+			require.NotNil(t, compensation)
+			assert.Equal(t, ActionTypeScale, compensation.Type)
+			assert.Equal(t, tt.parameters, compensation.Parameters)
+		})
+	}
+
+	// The storage snapshot alone does not say what the executor changed
+	assert.Nil(t, compensationFor(action, before, nil))
+	assert.Nil(t, compensationFor(action, before, map[string]interface{}{"workload_id": "wl-1"}))
+}
+
+func TestRollback_RestoresScaleFromExecutorResult(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+
+	decision := te.approve(t, scaleDecision("d-1", "wl-1"))
+	require.NoError(t, te.Enforce(ctx, decision))
+	te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+	assert.Equal(t, "4", storedWorkload(t, te.store, "wl-1").Annotations[AnnotationReplicas])
+
+	status, err := te.GetEnforcementStatus(ctx, "d-1")
+	require.NoError(t, err)
+	require.Len(t, status.Steps, 1)
+	assert.Equal(t, 2, status.Steps[0].Result["previous_replicas"])
+
+	// The rollback restores the requests the executor reported replacing
+	workload := storedWorkload(t, te.store, "wl-1")
+	workload.Requirements.CPU = 16
+	require.NoError(t, te.store.Workload().Update(ctx, workload))
+
+	_, err = te.Rollback(ctx, "d-1")
+	require.NoError(t, err)
+	te.waitForDecision(t, "d-1", types.DecisionStatusRolledBack)
+
+	workload = storedWorkload(t, te.store, "wl-1")
+	assert.Equal(t, "2", workload.Annotations[AnnotationReplicas])
+	assert.Equal(t, 4, workload.Requirements.CPU)
+}
+
+func TestRollback_RestoresAutoscalerBounds(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+
+	// An autoscaler-backed executor reports the bounds it replaced
+	scaler := newStubExecutor(ActionTypeScale, func(ctx context.Context, action *Action) (*ActionResult, error) {
+		return succeeded(action, map[string]interface{}{
+			"previous_min_replicas": int32(2),
+			"previous_max_replicas": int32(8),
+		}), nil
+	})
+	require.NoError(t, te.registry.Register(scaler))
+
+	decision := te.approve(t, scaleDecision("d-1", "wl-1"))
+	require.NoError(t, te.Enforce(ctx, decision))
+	te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+
+	_, err := te.Rollback(ctx, "d-1")
+	require.NoError(t, err)
+
+	calls := scaler.executed()
+	require.Len(t, calls, 2)
+	assert.Equal(t, int32(2), calls[1].Parameters["min_replicas"])
+	assert.Equal(t, int32(8), calls[1].Parameters["max_replicas"])
+	assert.NotContains(t, calls[1].Parameters, "replicas")
+	assert.Equal(t, ActionTypeScale, calls[1].Metadata["compensates"])
+}
+
+func TestRollback_ScaleWithoutPreviousStateIsIrreversible(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+
+	scaler := newStubExecutor(ActionTypeScale, nil)
+	require.NoError(t, te.registry.Register(scaler))
+
+	decision := te.approve(t, scaleDecision("d-1", "wl-1"))
+	require.NoError(t, te.Enforce(ctx, decision))
+	te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+
+	_, err := te.Rollback(ctx, "d-1")
+	assert.ErrorContains(t, err, "no reversible actions")
+	assert.Len(t, scaler.executed(), 1)
+}
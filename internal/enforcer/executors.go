@@ -3,12 +3,32 @@ package enforcer
 import (
 	"context"
 	"fmt"
+	"math"
+	"strconv"
 	"time"
 
+	"github.com/kcloud-opt/policy/internal/actions"
+	"github.com/kcloud-opt/policy/internal/evaluator"
 	"github.com/kcloud-opt/policy/internal/storage"
 	"github.com/kcloud-opt/policy/internal/types"
 )
 
+// NewDefaultExecutors creates the storage-backed executors for every action
+// type the policy enforcer generates
+func NewDefaultExecutors(storage storage.StorageManager, logger types.Logger) []ActionExecutor {
+	return []ActionExecutor{
+		NewScheduleExecutor(storage, logger),
+		NewRescheduleExecutor(storage, logger),
+		NewMigrateExecutor(storage, logger),
+		NewScaleExecutor(storage, logger),
+		NewTerminateExecutor(storage, logger),
+		NewSuspendExecutor(storage, logger),
+		NewResumeExecutor(storage, logger),
+		NewUpdateExecutor(storage, logger),
+		NewNotifyExecutor(logger),
+	}
+}
+
 // baseExecutor provides common functionality for action executors
 type baseExecutor struct {
 	actionTypes []string
@@ -108,7 +128,9 @@ func (se *scheduleExecutor) Execute(ctx context.Context, action *Action) (*Actio
 	}
 
 	// Simulate scheduling work
-	time.Sleep(100 * time.Millisecond)
+	if err := simulateWork(ctx, 100*time.Millisecond); err != nil {
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Action interrupted: %v", err), err.Error(), nil), nil
+	}
 
 	se.logger.Info("schedule action completed", "workload_id", workloadID)
 
@@ -150,7 +172,9 @@ func (ne *notifyExecutor) Execute(ctx context.Context, action *Action) (*ActionR
 	}
 
 	// Simulate notification sending
-	time.Sleep(50 * time.Millisecond)
+	if err := simulateWork(ctx, 50*time.Millisecond); err != nil {
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Action interrupted: %v", err), err.Error(), nil), nil
+	}
 
 	ne.logger.Info("notification sent", "target", action.Target, "message", message)
 
@@ -237,7 +261,9 @@ func (ue *updateExecutor) Execute(ctx context.Context, action *Action) (*ActionR
 	}
 
 	// Simulate update work
-	time.Sleep(200 * time.Millisecond)
+	if err := simulateWork(ctx, 200*time.Millisecond); err != nil {
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Action interrupted: %v", err), err.Error(), nil), nil
+	}
 
 	ue.logger.Info("update action completed", "workload_id", workloadID)
 
@@ -319,7 +345,9 @@ func (te *terminateExecutor) Execute(ctx context.Context, action *Action) (*Acti
 	}
 
 	// Simulate termination work
-	time.Sleep(300 * time.Millisecond)
+	if err := simulateWork(ctx, 300*time.Millisecond); err != nil {
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Action interrupted: %v", err), err.Error(), nil), nil
+	}
 
 	te.logger.Info("terminate action completed", "workload_id", workloadID)
 
@@ -402,7 +430,9 @@ func (se *suspendExecutor) Execute(ctx context.Context, action *Action) (*Action
 	}
 
 	// Simulate suspension work
-	time.Sleep(150 * time.Millisecond)
+	if err := simulateWork(ctx, 150*time.Millisecond); err != nil {
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Action interrupted: %v", err), err.Error(), nil), nil
+	}
 
 	se.logger.Info("suspend action completed", "workload_id", workloadID)
 
@@ -485,7 +515,9 @@ func (re *resumeExecutor) Execute(ctx context.Context, action *Action) (*ActionR
 	}
 
 	// Simulate resume work
-	time.Sleep(150 * time.Millisecond)
+	if err := simulateWork(ctx, 150*time.Millisecond); err != nil {
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Action interrupted: %v", err), err.Error(), nil), nil
+	}
 
 	re.logger.Info("resume action completed", "workload_id", workloadID)
 
@@ -501,3 +533,437 @@ func (re *resumeExecutor) Execute(ctx context.Context, action *Action) (*ActionR
 		},
 	}, nil
 }
+
+// Workload placement labels, with the alternative keys also accepted
+const (
+	labelCluster    = "cluster"
+	labelClusterAlt = "cluster-id"
+	labelNode       = "node"
+	labelNodeAlt    = "node-id"
+)
+
+// AnnotationReplicas records the replica count of a workload
+const AnnotationReplicas = "kcloud.io/replicas"
+
+// workloadSnapshot captures the reversible state of a workload
+func workloadSnapshot(workload *types.Workload) map[string]interface{} {
+	return map[string]interface{}{
+		"status":       workload.Status,
+		"cluster":      workload.Labels[placementKey(workload.Labels, labelCluster, labelClusterAlt)],
+		"node":         workload.Labels[placementKey(workload.Labels, labelNode, labelNodeAlt)],
+		"replicas":     workloadReplicas(workload),
+		"requirements": workload.Requirements,
+	}
+}
+
+// placementKey returns the label key a workload uses for a placement field,
+// preferring the primary key when neither is set
+func placementKey(labels map[string]string, primary, alternative string) string {
+	if _, exists := labels[primary]; !exists {
+		if _, exists := labels[alternative]; exists {
+			return alternative
+		}
+	}
+	return primary
+}
+
+// setPlacement sets a placement label, removing it when value is empty
+func setPlacement(workload *types.Workload, primary, alternative, value string) {
+	if workload.Labels == nil {
+		workload.Labels = make(map[string]string)
+	}
+	key := placementKey(workload.Labels, primary, alternative)
+	if value == "" {
+		delete(workload.Labels, key)
+		return
+	}
+	workload.Labels[key] = value
+}
+
+// workloadReplicas returns the recorded replica count of a workload,
+// defaulting to one
+func workloadReplicas(workload *types.Workload) int {
+	if value, exists := workload.Annotations[AnnotationReplicas]; exists {
+		if replicas, err := strconv.Atoi(value); err == nil && replicas >= 0 {
+			return replicas
+		}
+	}
+	return 1
+}
+
+// simulateWork waits for the given duration, returning early with the
+// context error when ctx ends first
+func simulateWork(ctx context.Context, duration time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(duration):
+		return nil
+	}
+}
+
+// migrateExecutor executes migrate actions
+type migrateExecutor struct {
+	baseExecutor
+	storage storage.StorageManager
+}
+
+// NewMigrateExecutor creates a new migrate executor
+func NewMigrateExecutor(storage storage.StorageManager, logger types.Logger) ActionExecutor {
+	return &migrateExecutor{
+		baseExecutor: baseExecutor{
+			actionTypes: []string{ActionTypeMigrate},
+			logger:      logger,
+		},
+		storage: storage,
+	}
+}
+
+// Execute migrates a workload by draining it from its current placement
+// and placing it on the target cluster and node
+func (me *migrateExecutor) Execute(ctx context.Context, action *Action) (*ActionResult, error) {
+	targetCluster, _ := action.Parameters["target_cluster"].(string)
+	targetNode, _ := action.Parameters["target_node"].(string)
+	if targetCluster == "" && targetNode == "" {
+		return actions.FailedResult(action, time.Now(), "target_cluster or target_node parameter is required", "missing migration target", nil), nil
+	}
+
+	return relocate(ctx, me.storage, me.logger, action, targetCluster, targetNode, false)
+}
+
+// rescheduleExecutor executes reschedule actions
+type rescheduleExecutor struct {
+	baseExecutor
+	storage storage.StorageManager
+}
+
+// NewRescheduleExecutor creates a new reschedule executor
+func NewRescheduleExecutor(storage storage.StorageManager, logger types.Logger) ActionExecutor {
+	return &rescheduleExecutor{
+		baseExecutor: baseExecutor{
+			actionTypes: []string{ActionTypeReschedule},
+			logger:      logger,
+		},
+		storage: storage,
+	}
+}
+
+// Execute moves a workload to the recommended cluster, leaving node
+// selection to the cluster's scheduler
+func (re *rescheduleExecutor) Execute(ctx context.Context, action *Action) (*ActionResult, error) {
+	recommendedCluster, _ := action.Parameters["recommended_cluster"].(string)
+	if recommendedCluster == "" {
+		return actions.FailedResult(action, time.Now(), "recommended_cluster parameter is required", "missing recommended_cluster parameter", nil), nil
+	}
+
+	return relocate(ctx, re.storage, re.logger, action, recommendedCluster, "", true)
+}
+
+// relocate drains a workload and places it on the target. Empty targets
+// keep the current value unless clearNode is set, in which case the node
+// placement is removed, and a failed placement restores the previous one.
+// Placement is only recorded in storage, so relocate does not verify it.
+// The verification step is the readiness gate of the enforcement step: when
+// the executor registered for the action type observes readiness, the step
+// waits until the cluster reports the workload ready, and a step whose gate
+// fails is compensated, moving the workload back. Relocations run by the
+// storage-only executors have no gate and are not verified.
+func relocate(ctx context.Context, store storage.StorageManager, logger types.Logger, action *Action, targetCluster, targetNode string, clearNode bool) (*ActionResult, error) {
+	startTime := time.Now()
+
+	logger.Info("executing relocation action", "action_type", action.Type, "target", action.Target)
+
+	workloadID, ok := action.Parameters["workload_id"].(string)
+	if !ok {
+		return actions.FailedResult(action, startTime, "workload_id parameter is required", "missing workload_id parameter", nil), nil
+	}
+
+	workload, err := store.Workload().Get(ctx, workloadID)
+	if err != nil {
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Failed to get workload: %v", err), err.Error(), nil), nil
+	}
+
+	before := workloadSnapshot(workload)
+
+	// Replaying a relocation that already took effect changes nothing
+	if placedOn(workload, targetCluster, targetNode) &&
+		(!clearNode || workload.Labels[placementKey(workload.Labels, labelNode, labelNodeAlt)] == "") {
+		return &ActionResult{
+			ActionType: action.Type,
+			Success:    true,
+			Message:    fmt.Sprintf("Workload already on %v/%v", before["cluster"], before["node"]),
+			Duration:   time.Since(startTime),
+			Timestamp:  time.Now(),
+			Data: map[string]interface{}{
+				"workload_id": workloadID,
+				"before":      before,
+				"after":       before,
+				"strategy":    action.Parameters["migration_strategy"],
+			},
+		}, nil
+	}
+
+	previousStatus := workload.Status
+	previousLabels := make(map[string]string, len(workload.Labels))
+	for k, v := range workload.Labels {
+		previousLabels[k] = v
+	}
+	restore := func() {
+		workload.Status = previousStatus
+		workload.Labels = previousLabels
+		workload.UpdatedAt = time.Now()
+		if err := store.Workload().Update(ctx, workload); err != nil {
+			logger.WithError(err).Error("failed to restore workload placement", "workload_id", workloadID)
+		}
+	}
+
+	// Drain: stop the workload at its current placement
+	workload.Status = types.WorkloadStatusSuspended
+	workload.UpdatedAt = time.Now()
+	if err := store.Workload().Update(ctx, workload); err != nil {
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Failed to drain workload: %v", err), err.Error(),
+			map[string]interface{}{"workload_id": workloadID, "before": before}), nil
+	}
+
+	// Stop before placing if the action was cancelled or timed out while
+	// draining, leaving the workload where it was
+	if err := ctx.Err(); err != nil {
+		restore()
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Relocation interrupted: %v", err), err.Error(),
+			map[string]interface{}{"workload_id": workloadID, "before": before}), nil
+	}
+
+	// Place: move the workload to its target and start it
+	if targetCluster != "" {
+		setPlacement(workload, labelCluster, labelClusterAlt, targetCluster)
+	}
+	if targetNode != "" || clearNode {
+		setPlacement(workload, labelNode, labelNodeAlt, targetNode)
+	}
+	workload.Status = types.WorkloadStatusRunning
+	workload.UpdatedAt = time.Now()
+	if err := store.Workload().Update(ctx, workload); err != nil {
+		restore()
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Failed to place workload: %v", err), err.Error(),
+			map[string]interface{}{"workload_id": workloadID, "before": before}), nil
+	}
+
+	after := workloadSnapshot(workload)
+	logger.Info("relocation action completed", "workload_id", workloadID,
+		"from_cluster", before["cluster"], "to_cluster", after["cluster"],
+		"from_node", before["node"], "to_node", after["node"])
+
+	return &ActionResult{
+		ActionType: action.Type,
+		Success:    true,
+		Message:    fmt.Sprintf("Workload moved from %v/%v to %v/%v", before["cluster"], before["node"], after["cluster"], after["node"]),
+		Duration:   time.Since(startTime),
+		Timestamp:  time.Now(),
+		Data: map[string]interface{}{
+			"workload_id": workloadID,
+			"before":      before,
+			"after":       after,
+			"strategy":    action.Parameters["migration_strategy"],
+		},
+	}, nil
+}
+
+// placedOn returns true if a workload runs on the given placement. Empty
+// targets match any value.
+func placedOn(workload *types.Workload, cluster, node string) bool {
+	if workload.Status != types.WorkloadStatusRunning {
+		return false
+	}
+	if cluster != "" && workload.Labels[placementKey(workload.Labels, labelCluster, labelClusterAlt)] != cluster {
+		return false
+	}
+	if node != "" && workload.Labels[placementKey(workload.Labels, labelNode, labelNodeAlt)] != node {
+		return false
+	}
+	return true
+}
+
+// scaleExecutor executes scale actions
+type scaleExecutor struct {
+	baseExecutor
+	storage storage.StorageManager
+}
+
+// NewScaleExecutor creates a new scale executor
+func NewScaleExecutor(storage storage.StorageManager, logger types.Logger) ActionExecutor {
+	return &scaleExecutor{
+		baseExecutor: baseExecutor{
+			actionTypes: []string{ActionTypeScale},
+			logger:      logger,
+		},
+		storage: storage,
+	}
+}
+
+// Execute scales a workload. The replicas parameter sets the replica count
+// and the cpu and memory parameters set resource requests. Otherwise
+// scale_factor is applied to replicas, or to CPU and memory requests when
+// scale_type is "resources"; with scale_direction "down" a factor above one
+// divides rather than multiplies.
+func (se *scaleExecutor) Execute(ctx context.Context, action *Action) (*ActionResult, error) {
+	startTime := time.Now()
+
+	se.logger.Info("executing scale action", "target", action.Target)
+
+	workloadID, ok := action.Parameters["workload_id"].(string)
+	if !ok {
+		return actions.FailedResult(action, startTime, "workload_id parameter is required", "missing workload_id parameter", nil), nil
+	}
+
+	workload, err := se.storage.Workload().Get(ctx, workloadID)
+	if err != nil {
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Failed to get workload: %v", err), err.Error(), nil), nil
+	}
+
+	before := workloadSnapshot(workload)
+	if err := applyScale(workload, action.Parameters); err != nil {
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Invalid scale parameters: %v", err), err.Error(), nil), nil
+	}
+
+	workload.UpdatedAt = time.Now()
+	if err := se.storage.Workload().Update(ctx, workload); err != nil {
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Failed to update workload: %v", err), err.Error(), nil), nil
+	}
+	after := workloadSnapshot(workload)
+
+	se.logger.Info("scale action completed", "workload_id", workloadID,
+		"replicas", after["replicas"], "cpu", workload.Requirements.CPU, "memory", workload.Requirements.Memory)
+
+	return &ActionResult{
+		ActionType: action.Type,
+		Success:    true,
+		Message: fmt.Sprintf("Workload scaled to %v replicas with %d CPU and %s memory",
+			after["replicas"], workload.Requirements.CPU, workload.Requirements.Memory),
+		Duration:  time.Since(startTime),
+		Timestamp: time.Now(),
+		Data: previousState(before, map[string]interface{}{
+			"workload_id": workloadID,
+			"before":      before,
+			"after":       after,
+		}),
+	}, nil
+}
+
+// previousState adds the replica count and resource requests of a
+// workload snapshot to data, under the keys compensation restores them from
+func previousState(snapshot, data map[string]interface{}) map[string]interface{} {
+	data["previous_replicas"] = snapshot["replicas"]
+	if requirements, ok := snapshotResources(snapshot); ok {
+		data["previous_cpu"] = requirements.CPU
+		data["previous_memory"] = requirements.Memory
+	}
+	return data
+}
+
+// applyScale applies scale parameters to a workload in place
+func applyScale(workload *types.Workload, parameters map[string]interface{}) error {
+	replicas, hasReplicas, err := numberParam(parameters, "replicas")
+	if err != nil {
+		return err
+	}
+	cpu, hasCPU, err := numberParam(parameters, "cpu")
+	if err != nil {
+		return err
+	}
+	memory, hasMemory := parameters["memory"].(string)
+
+	if hasReplicas || hasCPU || hasMemory {
+		if hasReplicas {
+			if replicas < 0 || replicas != math.Trunc(replicas) {
+				return fmt.Errorf("replicas must be a non-negative whole number, got %v", replicas)
+			}
+			setReplicas(workload, int(replicas))
+		}
+		if hasCPU {
+			if cpu < 1 || cpu != math.Trunc(cpu) {
+				return fmt.Errorf("cpu must be a positive whole number, got %v", cpu)
+			}
+			workload.Requirements.CPU = int(cpu)
+		}
+		if hasMemory {
+			if _, err := evaluator.ParseMemoryString(memory); err != nil {
+				return err
+			}
+			workload.Requirements.Memory = memory
+		}
+		return nil
+	}
+
+	factor, ok, err := numberParam(parameters, "scale_factor")
+	if err != nil {
+		return err
+	}
+	if !ok || factor <= 0 {
+		return fmt.Errorf("replicas, cpu, memory or a positive scale_factor parameter is required")
+	}
+	if direction, _ := parameters["scale_direction"].(string); direction == "down" && factor > 1 {
+		factor = 1 / factor
+	}
+
+	if scaleType, _ := parameters["scale_type"].(string); scaleType == "resources" {
+		workload.Requirements.CPU = int(math.Max(1, math.Ceil(float64(workload.Requirements.CPU)*factor)))
+		if workload.Requirements.Memory != "" {
+			bytes, err := evaluator.ParseMemoryString(workload.Requirements.Memory)
+			if err != nil {
+				return err
+			}
+			workload.Requirements.Memory = formatMebibytes(int64(math.Max(1, math.Ceil(float64(bytes)*factor/(1024*1024)))))
+		}
+		return nil
+	}
+
+	setReplicas(workload, int(math.Max(1, math.Ceil(float64(workloadReplicas(workload))*factor))))
+	return nil
+}
+
+// formatMebibytes formats a MiB count as a Kubernetes quantity, using Gi
+// when the value is a whole number of GiB
+func formatMebibytes(mebibytes int64) string {
+	if mebibytes%1024 == 0 {
+		return fmt.Sprintf("%dGi", mebibytes/1024)
+	}
+	return fmt.Sprintf("%dMi", mebibytes)
+}
+
+// setReplicas records the replica count of a workload
+func setReplicas(workload *types.Workload, replicas int) {
+	if workload.Annotations == nil {
+		workload.Annotations = make(map[string]string)
+	}
+	workload.Annotations[AnnotationReplicas] = strconv.Itoa(replicas)
+}
+
+// numberParam reads a numeric action parameter, accepting Go numeric types,
+// JSON numbers and numeric strings
+func numberParam(parameters map[string]interface{}, key string) (float64, bool, error) {
+	value, exists := parameters[key]
+	if !exists || value == nil {
+		return 0, false, nil
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return v, true, nil
+	case float32:
+		return float64(v), true, nil
+	case int:
+		return float64(v), true, nil
+	case int32:
+		return float64(v), true, nil
+	case int64:
+		return float64(v), true, nil
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("parameter %s: %w", key, err)
+		}
+		return parsed, true, nil
+	default:
+		return 0, false, fmt.Errorf("parameter %s has unsupported type %T", key, value)
+	}
+}
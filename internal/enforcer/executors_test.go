@@ -0,0 +1,194 @@
+package enforcer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// testLogger discards log output
+type testLogger struct{}
+
+func (l testLogger) Info(msg string, fields ...interface{})                    {}
+func (l testLogger) Warn(msg string, fields ...interface{})                    {}
+func (l testLogger) Error(msg string, fields ...interface{})                   {}
+func (l testLogger) Debug(msg string, fields ...interface{})                   {}
+func (l testLogger) Fatal(msg string, fields ...interface{})                   {}
+func (l testLogger) WithError(err error) types.Logger                          { return l }
+func (l testLogger) WithDuration(duration time.Duration) types.Logger          { return l }
+func (l testLogger) WithPolicy(policyID, policyName string) types.Logger       { return l }
+func (l testLogger) WithWorkload(workloadID, workloadType string) types.Logger { return l }
+func (l testLogger) WithEvaluation(evaluationID string) types.Logger           { return l }
+
+// newTestWorkload stores a running workload placed on cluster-a/node-1
+func newTestWorkload(t *testing.T, store storage.StorageManager, id string) *types.Workload {
+	t.Helper()
+	workload := &types.Workload{
+		ID:           id,
+		Name:         id,
+		Type:         types.WorkloadTypeWeb,
+		Status:       types.WorkloadStatusRunning,
+		Priority:     types.PriorityNormal,
+		Labels:       map[string]string{"cluster": "cluster-a", "node": "node-1"},
+		Annotations:  map[string]string{AnnotationReplicas: "2"},
+		Requirements: types.Resources{CPU: 4, Memory: "4Gi"},
+	}
+	require.NoError(t, store.Workload().Create(context.Background(), workload))
+	return workload
+}
+
+func storedWorkload(t *testing.T, store storage.StorageManager, id string) *types.Workload {
+	t.Helper()
+	workload, err := store.Workload().Get(context.Background(), id)
+	require.NoError(t, err)
+	return workload
+}
+
+func TestMigrateExecutor(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStorageManager()
+	newTestWorkload(t, store, "wl-1")
+	executor := NewMigrateExecutor(store, testLogger{})
+
+	action := &Action{Type: ActionTypeMigrate, Target: "wl-1", Parameters: map[string]interface{}{
+		"workload_id":    "wl-1",
+		"target_cluster": "cluster-b",
+		"target_node":    "node-2",
+	}}
+	result, err := executor.Execute(ctx, action)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Message)
+
+	workload := storedWorkload(t, store, "wl-1")
+	assert.Equal(t, types.WorkloadStatusRunning, workload.Status)
+	assert.Equal(t, "cluster-b", workload.Labels["cluster"])
+	assert.Equal(t, "node-2", workload.Labels["node"])
+
+	before := result.Data["before"].(map[string]interface{})
+	after := result.Data["after"].(map[string]interface{})
+	assert.Equal(t, "cluster-a", before["cluster"])
+	assert.Equal(t, "node-1", before["node"])
+	assert.Equal(t, "cluster-b", after["cluster"])
+	assert.Equal(t, "node-2", after["node"])
+
+	// Replaying the action finds the workload already in place
+	result, err = executor.Execute(ctx, action)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	assert.Contains(t, result.Message, "already on")
+}
+
+func TestMigrateExecutor_Failures(t *testing.T) {
+	store := memory.NewStorageManager()
+	newTestWorkload(t, store, "wl-1")
+	executor := NewMigrateExecutor(store, testLogger{})
+
+	result, err := executor.Execute(context.Background(), &Action{Type: ActionTypeMigrate, Target: "wl-1",
+		Parameters: map[string]interface{}{"workload_id": "wl-1"}})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+
+	result, err = executor.Execute(context.Background(), &Action{Type: ActionTypeMigrate, Target: "missing",
+		Parameters: map[string]interface{}{"workload_id": "missing", "target_node": "node-2"}})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+
+	// An action cancelled while draining leaves the workload where it was
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err = executor.Execute(ctx, &Action{Type: ActionTypeMigrate, Target: "wl-1",
+		Parameters: map[string]interface{}{"workload_id": "wl-1", "target_node": "node-2"}})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+
+	workload := storedWorkload(t, store, "wl-1")
+	assert.Equal(t, types.WorkloadStatusRunning, workload.Status)
+	assert.Equal(t, "node-1", workload.Labels["node"])
+}
+
+func TestRescheduleExecutor_ClearsNode(t *testing.T) {
+	store := memory.NewStorageManager()
+	newTestWorkload(t, store, "wl-1")
+	executor := NewRescheduleExecutor(store, testLogger{})
+
+	result, err := executor.Execute(context.Background(), &Action{Type: ActionTypeReschedule, Target: "wl-1",
+		Parameters: map[string]interface{}{"workload_id": "wl-1", "recommended_cluster": "cluster-b"}})
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Message)
+
+	workload := storedWorkload(t, store, "wl-1")
+	assert.Equal(t, "cluster-b", workload.Labels["cluster"])
+	assert.NotContains(t, workload.Labels, "node")
+
+	result, err = executor.Execute(context.Background(), &Action{Type: ActionTypeReschedule, Target: "wl-1",
+		Parameters: map[string]interface{}{"workload_id": "wl-1"}})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+}
+
+func TestScaleExecutor(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]interface{}
+		replicas   string
+		cpu        int
+		memory     string
+	}{
+		{"replicas", map[string]interface{}{"replicas": 5}, "5", 4, "4Gi"},
+		{"factor up", map[string]interface{}{"scale_factor": 1.5}, "3", 4, "4Gi"},
+		{"factor down", map[string]interface{}{"scale_factor": 2.0, "scale_direction": "down"}, "1", 4, "4Gi"},
+		{"resources", map[string]interface{}{"scale_factor": 0.5, "scale_type": "resources"}, "2", 2, "2Gi"},
+		{"explicit resources", map[string]interface{}{"cpu": "8", "memory": "6Gi"}, "2", 8, "6Gi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewStorageManager()
+			newTestWorkload(t, store, "wl-1")
+
+			parameters := map[string]interface{}{"workload_id": "wl-1"}
+			for k, v := range tt.parameters {
+				parameters[k] = v
+			}
+			result, err := NewScaleExecutor(store, testLogger{}).Execute(context.Background(),
+				&Action{Type: ActionTypeScale, Target: "wl-1", Parameters: parameters})
+			require.NoError(t, err)
+			require.True(t, result.Success, result.Message)
+
+			workload := storedWorkload(t, store, "wl-1")
+			assert.Equal(t, tt.replicas, workload.Annotations[AnnotationReplicas])
+			assert.Equal(t, tt.cpu, workload.Requirements.CPU)
+			assert.Equal(t, tt.memory, workload.Requirements.Memory)
+			assert.Equal(t, 2, result.Data["before"].(map[string]interface{})["replicas"])
+		})
+	}
+
+	for _, invalid := range []map[string]interface{}{
+		{},
+		{"replicas": -1},
+		{"replicas": 1.5},
+		{"cpu": 0},
+		{"memory": "lots"},
+		{"scale_factor": "fast"},
+	} {
+		store := memory.NewStorageManager()
+		newTestWorkload(t, store, "wl-1")
+
+		parameters := map[string]interface{}{"workload_id": "wl-1"}
+		for k, v := range invalid {
+			parameters[k] = v
+		}
+		result, err := NewScaleExecutor(store, testLogger{}).Execute(context.Background(),
+			&Action{Type: ActionTypeScale, Target: "wl-1", Parameters: parameters})
+		require.NoError(t, err)
+		assert.False(t, result.Success, "%v", invalid)
+		assert.Equal(t, "2", storedWorkload(t, store, "wl-1").Annotations[AnnotationReplicas])
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/kcloud-opt/policy/internal/actions"
 	"github.com/kcloud-opt/policy/internal/types"
 )
 
@@ -12,104 +13,205 @@ type PolicyEnforcer interface {
 	// Enforce enforces a policy decision
 	Enforce(ctx context.Context, decision *types.Decision) error
 
-	// EnforceMany enforces multiple policy decisions
+	// EnforceMany enforces multiple policy decisions, rolling large batches
+	// out in waves when progressive rollout is enabled
 	EnforceMany(ctx context.Context, decisions []*types.Decision) error
 
+	// StartRollout enforces decisions in waves, observing the health of
+	// each wave before the next. Zero fields of the strategy, or all of a
+	// nil one, take the configured values.
+	StartRollout(ctx context.Context, decisions []*types.Decision, strategy *RolloutStrategy) (*Rollout, error)
+
+	// GetRollout gets the state of a rollout
+	GetRollout(ctx context.Context, rolloutID string) (*Rollout, error)
+
+	// ListRollouts lists the rollouts started since the enforcer started
+	ListRollouts(ctx context.Context) []*Rollout
+
+	// PauseRollout stops a rollout from starting its next wave
+	PauseRollout(ctx context.Context, rolloutID string) (*Rollout, error)
+
+	// ResumeRollout lets a paused rollout continue
+	ResumeRollout(ctx context.Context, rolloutID string) (*Rollout, error)
+
+	// AbortRollout stops a rollout and rolls back the waves it enforced
+	AbortRollout(ctx context.Context, rolloutID string) (*Rollout, error)
+
 	// GetEnforcementStatus gets the status of policy enforcement
 	GetEnforcementStatus(ctx context.Context, decisionID string) (*EnforcementStatus, error)
 
 	// CancelEnforcement cancels ongoing policy enforcement
 	CancelEnforcement(ctx context.Context, decisionID string) error
 
+	// Plan works out what enforcing a decision would do without executing
+	// anything, and records the plan until the decision terminates
+	Plan(ctx context.Context, decision *types.Decision) (*ExecutionPlan, error)
+
+	// GetPlan returns the recorded plan of a decision, planning it afresh
+	// if none was recorded
+	GetPlan(ctx context.Context, decisionID string) (*ExecutionPlan, error)
+
+	// Rollback undoes the actions of a completed enforcement
+	Rollback(ctx context.Context, decisionID string) (*EnforcementStatus, error)
+
+	// Recover settles decisions left executing by a previous run
+	Recover(ctx context.Context) ([]*RecoveryResult, error)
+
 	// Health checks the health of the enforcer
 	Health(ctx context.Context) error
 }
 
 // EnforcementStatus represents the status of policy enforcement
-type EnforcementStatus struct {
-	DecisionID  string                 `json:"decisionId"`
-	Status      EnforcementState       `json:"status"`
-	Progress    float64                `json:"progress"`
-	Message     string                 `json:"message"`
-	StartedAt   time.Time              `json:"startedAt"`
-	CompletedAt *time.Time             `json:"completedAt,omitempty"`
-	Duration    *time.Duration         `json:"duration,omitempty"`
-	Error       string                 `json:"error,omitempty"`
-	Details     map[string]interface{} `json:"details,omitempty"`
-	Events      []EnforcementEvent     `json:"events,omitempty"`
-}
+type EnforcementStatus = types.EnforcementStatus
 
 // EnforcementState represents the state of enforcement
-type EnforcementState string
+type EnforcementState = types.EnforcementState
 
 const (
-	EnforcementStatePending   EnforcementState = "pending"
-	EnforcementStateRunning   EnforcementState = "running"
-	EnforcementStateCompleted EnforcementState = "completed"
-	EnforcementStateFailed    EnforcementState = "failed"
-	EnforcementStateCancelled EnforcementState = "cancelled"
-	EnforcementStateTimeout   EnforcementState = "timeout"
+	EnforcementStatePending    = types.EnforcementStatePending
+	EnforcementStateRunning    = types.EnforcementStateRunning
+	EnforcementStateCompleted  = types.EnforcementStateCompleted
+	EnforcementStateFailed     = types.EnforcementStateFailed
+	EnforcementStateCancelled  = types.EnforcementStateCancelled
+	EnforcementStateTimeout    = types.EnforcementStateTimeout
+	EnforcementStateRolledBack = types.EnforcementStateRolledBack
 )
 
 // EnforcementEvent represents an enforcement event
-type EnforcementEvent struct {
-	Type      string                 `json:"type"`
-	Message   string                 `json:"message"`
-	Timestamp time.Time              `json:"timestamp"`
-	Data      map[string]interface{} `json:"data,omitempty"`
+type EnforcementEvent = types.EnforcementEvent
+
+// Rollout is a batch of decisions enforced in waves
+type Rollout = types.Rollout
+
+// RolloutStrategy controls how a rollout advances from wave to wave
+type RolloutStrategy = types.RolloutStrategy
+
+// RecoveryOutcome is what recovery did with an interrupted enforcement
+type RecoveryOutcome string
+
+const (
+	RecoveryOutcomeResumed    RecoveryOutcome = "resumed"
+	RecoveryOutcomeRestarted  RecoveryOutcome = "restarted"
+	RecoveryOutcomeCompleted  RecoveryOutcome = "completed"
+	RecoveryOutcomeRolledBack RecoveryOutcome = "rolled_back"
+	RecoveryOutcomeRequeued   RecoveryOutcome = "requeued"
+	RecoveryOutcomeDeferred   RecoveryOutcome = "deferred"
+	RecoveryOutcomeFailed     RecoveryOutcome = "failed"
+)
+
+// RecoveryResult describes how an interrupted enforcement was settled
+type RecoveryResult struct {
+	DecisionID string          `json:"decisionId"`
+	Outcome    RecoveryOutcome `json:"outcome"`
+	Reason     string          `json:"reason"`
 }
 
-// ActionExecutor defines the interface for executing specific actions
-type ActionExecutor interface {
-	// CanExecute checks if this executor can handle the given action type
-	CanExecute(actionType string) bool
+// ExecutionPlan is what enforcing a decision would do, worked out without
+// executing anything
+type ExecutionPlan struct {
+	DecisionID   string             `json:"decisionId"`
+	DecisionType types.DecisionType `json:"decisionType"`
+	Mode         string             `json:"mode"`
 
-	// Execute executes the action
-	Execute(ctx context.Context, action *Action) (*ActionResult, error)
+	// Executable is true if every step validated and resolved its target
+	Executable bool        `json:"executable"`
+	Steps      []*PlanStep `json:"steps"`
+	Risks      []RiskFlag  `json:"risks,omitempty"`
+	Error      string      `json:"error,omitempty"`
 
-	// Validate validates the action before execution
-	Validate(action *Action) error
+	// Maintenance is when the maintenance calendar would let the decision run
+	Maintenance *MaintenanceVerdict `json:"maintenance,omitempty"`
 
-	// Health checks the health of the executor
-	Health(ctx context.Context) error
+	GeneratedAt time.Time `json:"generatedAt"`
 }
 
-// Action represents an action to be executed
-type Action struct {
-	Type        string                 `json:"type"`
-	Target      string                 `json:"target"`
-	Parameters  map[string]interface{} `json:"parameters,omitempty"`
-	Timeout     time.Duration          `json:"timeout,omitempty"`
-	RetryPolicy *RetryPolicy           `json:"retryPolicy,omitempty"`
-	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+// PlanStep is a single action of an execution plan with the state change
+// it is expected to make
+type PlanStep struct {
+	Index  int         `json:"index"`
+	ID     string      `json:"id"`
+	Action *Action     `json:"action"`
+	Target *PlanTarget `json:"target,omitempty"`
+	Valid  bool        `json:"valid"`
+	Error  string      `json:"error,omitempty"`
+
+	// DependsOn are the steps that must complete before this one runs, and
+	// Gate is the condition under which it counts as done
+	DependsOn []string             `json:"dependsOn,omitempty"`
+	Gate      *types.ReadinessGate `json:"gate,omitempty"`
+
+	// Before and After are the workload state before the step and the state
+	// it is expected to leave, following the earlier steps of the plan
+	Before  map[string]interface{} `json:"before,omitempty"`
+	After   map[string]interface{} `json:"after,omitempty"`
+	Changes []StateChange          `json:"changes,omitempty"`
+
+	Reversible bool       `json:"reversible"`
+	Risks      []RiskFlag `json:"risks,omitempty"`
 }
 
-// ActionResult represents the result of action execution
-type ActionResult struct {
-	ActionType string                 `json:"actionType"`
-	Success    bool                   `json:"success"`
-	Message    string                 `json:"message"`
-	Data       map[string]interface{} `json:"data,omitempty"`
-	Duration   time.Duration          `json:"duration"`
-	Timestamp  time.Time              `json:"timestamp"`
-	Error      string                 `json:"error,omitempty"`
-	RetryCount int                    `json:"retryCount"`
+// PlanTarget is the workload an action resolves to
+type PlanTarget struct {
+	WorkloadID string `json:"workloadId"`
+	Name       string `json:"name,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Cluster    string `json:"cluster,omitempty"`
+	Node       string `json:"node,omitempty"`
+	Found      bool   `json:"found"`
 }
 
-// RetryPolicy defines retry behavior for actions
-type RetryPolicy struct {
-	MaxRetries int           `json:"maxRetries"`
-	Interval   time.Duration `json:"interval"`
-	Backoff    BackoffType   `json:"backoff"`
+// StateChange is a workload field an action is expected to change
+type StateChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
 }
 
+// RiskFlag marks a risk of executing a plan or one of its steps
+type RiskFlag string
+
+const (
+	// RiskFlagDisruptive marks actions that take replicas out of service
+	RiskFlagDisruptive RiskFlag = "disruptive"
+
+	// RiskFlagOutage marks actions that leave a workload with no replica
+	// in service
+	RiskFlagOutage RiskFlag = "outage"
+
+	// RiskFlagIrreversible marks changes no compensating action can undo
+	RiskFlagIrreversible RiskFlag = "irreversible"
+
+	// RiskFlagTargetNotFound marks actions whose workload does not exist
+	RiskFlagTargetNotFound RiskFlag = "target_not_found"
+
+	// RiskFlagInvalid marks actions their executor rejects
+	RiskFlagInvalid RiskFlag = "invalid"
+
+	// RiskFlagDeferred and RiskFlagBlocked mark decisions the maintenance
+	// calendar would hold
+	RiskFlagDeferred RiskFlag = "deferred"
+	RiskFlagBlocked  RiskFlag = "blocked"
+)
+
+// ActionExecutor executes the actions of the types it can handle
+type ActionExecutor = actions.Executor
+
+// Action represents an action to be executed
+type Action = actions.Action
+
+// ActionResult represents the result of action execution
+type ActionResult = actions.Result
+
+// RetryPolicy defines retry behavior for actions
+type RetryPolicy = actions.RetryPolicy
+
 // BackoffType represents the backoff strategy
-type BackoffType string
+type BackoffType = actions.BackoffType
 
 const (
-	BackoffTypeLinear      BackoffType = "linear"
-	BackoffTypeExponential BackoffType = "exponential"
-	BackoffTypeFixed       BackoffType = "fixed"
+	BackoffTypeLinear      = actions.BackoffTypeLinear
+	BackoffTypeExponential = actions.BackoffTypeExponential
+	BackoffTypeFixed       = actions.BackoffTypeFixed
 )
 
 // EnforcementEngine defines the main enforcement engine interface
@@ -123,26 +225,39 @@ type EnforcementEngine interface {
 	// ExecuteAction executes a single action
 	ExecuteAction(ctx context.Context, action *Action) (*ActionResult, error)
 
+	// ValidateAction checks an action with the executor that would run it,
+	// without executing it
+	ValidateAction(action *Action) error
+
 	// ExecuteActions executes multiple actions
 	ExecuteActions(ctx context.Context, actions []*Action) ([]*ActionResult, error)
 
+	// CanObserve checks if the executor of an action type can observe
+	// whether the targets of its actions are ready
+	CanObserve(actionType string) bool
+
+	// ObserveAction reports the observed state of the target of an action
+	// through the executor of its type
+	ObserveAction(ctx context.Context, action *Action) (map[string]interface{}, error)
+
 	// Health checks the health of the enforcement engine
 	Health(ctx context.Context) error
 }
 
 // Common action types
 const (
-	ActionTypeSchedule   = "schedule"
-	ActionTypeReschedule = "reschedule"
-	ActionTypeMigrate    = "migrate"
-	ActionTypeScale      = "scale"
-	ActionTypeTerminate  = "terminate"
-	ActionTypeSuspend    = "suspend"
-	ActionTypeResume     = "resume"
-	ActionTypeNotify     = "notify"
-	ActionTypeUpdate     = "update"
-	ActionTypeDelete     = "delete"
-	ActionTypeCreate     = "create"
+	ActionTypeSchedule   = actions.ActionTypeSchedule
+	ActionTypeReschedule = actions.ActionTypeReschedule
+	ActionTypeMigrate    = actions.ActionTypeMigrate
+	ActionTypeScale      = actions.ActionTypeScale
+	ActionTypeTerminate  = actions.ActionTypeTerminate
+	ActionTypeSuspend    = actions.ActionTypeSuspend
+	ActionTypeResume     = actions.ActionTypeResume
+	ActionTypeNotify     = actions.ActionTypeNotify
+	ActionTypeUpdate     = actions.ActionTypeUpdate
+	ActionTypeDelete     = actions.ActionTypeDelete
+	ActionTypeCreate     = actions.ActionTypeCreate
+	ActionTypeWebhook    = actions.ActionTypeWebhook
 )
 
 // Action execution context
@@ -179,3 +294,136 @@ type NodeInfo struct {
 	Labels      map[string]string `json:"labels,omitempty"`
 	Annotations map[string]string `json:"annotations,omitempty"`
 }
+
+// ApprovalManager gates enforcement on decision approval
+type ApprovalManager interface {
+	// Submit applies the approval rules to a pending decision, approving or
+	// rejecting it automatically or leaving it pending for human review
+	Submit(ctx context.Context, decision *types.Decision) (*ApprovalOutcome, error)
+
+	// Approve approves a pending decision on behalf of a reviewer
+	Approve(ctx context.Context, decisionID, approver, comment string) (*types.Decision, error)
+
+	// Reject rejects a pending decision on behalf of a reviewer
+	Reject(ctx context.Context, decisionID, approver, comment string) (*types.Decision, error)
+
+	// Health checks the health of the approval manager
+	Health(ctx context.Context) error
+}
+
+// ApprovalAction is what an approval rule does with a matching decision
+type ApprovalAction string
+
+const (
+	ApprovalActionAutoApprove ApprovalAction = "auto_approve"
+	ApprovalActionAutoReject  ApprovalAction = "auto_reject"
+	ApprovalActionReview      ApprovalAction = "review"
+)
+
+// ApprovalOutcome is the result of submitting a decision for approval
+type ApprovalOutcome struct {
+	DecisionID string               `json:"decisionId"`
+	Action     ApprovalAction       `json:"action"`
+	Status     types.DecisionStatus `json:"status"`
+	Rule       string               `json:"rule,omitempty"`
+	Reason     string               `json:"reason"`
+}
+
+// SafetyLimiter bounds the disruption enforcement may cause, admitting
+// decisions only while the blast-radius limits matching them have budget
+type SafetyLimiter interface {
+	// Admit checks a decision against the limits and, when it is allowed,
+	// reserves budget for it until Release
+	Admit(ctx context.Context, decision *types.Decision) (*SafetyVerdict, error)
+
+	// Release returns the concurrency budget reserved for a decision
+	Release(decisionID string)
+
+	// Usage reports the current budget usage of every limit
+	Usage() []*BudgetUsage
+}
+
+// SafetyAction is what happens to a decision over a safety limit
+type SafetyAction string
+
+const (
+	SafetyActionQueue  SafetyAction = "queue"
+	SafetyActionReject SafetyAction = "reject"
+)
+
+// SafetyVerdict is the result of checking a decision against the limits
+type SafetyVerdict struct {
+	Allowed bool         `json:"allowed"`
+	Action  SafetyAction `json:"action,omitempty"`
+	Limit   string       `json:"limit,omitempty"`
+	Reason  string       `json:"reason,omitempty"`
+
+	// RetryAfter is when hourly budget next frees up for a queued decision
+	RetryAfter time.Duration `json:"retryAfter,omitempty"`
+}
+
+// BudgetUsage is the budget a safety limit has used
+type BudgetUsage struct {
+	Limit         string `json:"limit"`
+	Concurrent    int    `json:"concurrent"`
+	MaxConcurrent int    `json:"maxConcurrent,omitempty"`
+	LastHour      int    `json:"lastHour"`
+	MaxPerHour    int    `json:"maxPerHour,omitempty"`
+
+	// Namespaces are the namespaces with disruptions in flight and the
+	// share of their workloads disrupted
+	Namespaces           map[string]float64 `json:"namespaces,omitempty"`
+	MaxNamespaceFraction float64            `json:"maxNamespaceFraction,omitempty"`
+}
+
+// LockManager leases workloads and nodes to the enforcements acting on
+// them, so concurrent decisions never change the same resource
+type LockManager interface {
+	// Acquire leases every resource to a decision, all or nothing. If any
+	// resource is leased to another decision nothing is acquired and the
+	// conflicting leases are returned.
+	Acquire(decisionID string, priority types.Priority, resources []string) ([]*Lease, error)
+
+	// Release releases every lease held by a decision
+	Release(decisionID string)
+
+	// Leases returns the active leases
+	Leases() []*Lease
+}
+
+// Lease is a resource held by the enforcement of a decision
+type Lease struct {
+	Resource   string         `json:"resource"`
+	DecisionID string         `json:"decisionId"`
+	Priority   types.Priority `json:"priority"`
+	AcquiredAt time.Time      `json:"acquiredAt"`
+	ExpiresAt  *time.Time     `json:"expiresAt,omitempty"`
+}
+
+// MaintenanceCalendar decides when disruptive decisions may be enforced
+// from the maintenance windows and change freezes that cover them
+type MaintenanceCalendar interface {
+	// Check reports whether a decision may be enforced now, must wait for
+	// a window to open or a freeze to end, or is blocked by a freeze
+	Check(ctx context.Context, decision *types.Decision) (*MaintenanceVerdict, error)
+}
+
+// MaintenanceAction is what the maintenance calendar does with a decision
+type MaintenanceAction string
+
+const (
+	MaintenanceActionAllow MaintenanceAction = "allow"
+	MaintenanceActionDefer MaintenanceAction = "defer"
+	MaintenanceActionBlock MaintenanceAction = "block"
+)
+
+// MaintenanceVerdict is the result of checking a decision against the
+// maintenance calendar
+type MaintenanceVerdict struct {
+	Action MaintenanceAction `json:"action"`
+	Window string            `json:"window,omitempty"`
+	Reason string            `json:"reason"`
+
+	// ScheduledFor is when a deferred decision may be enforced
+	ScheduledFor *time.Time `json:"scheduledFor,omitempty"`
+}
@@ -0,0 +1,108 @@
+package kubernetes
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// hostnameLabel is the well-known node label holding the node name
+const hostnameLabel = "kubernetes.io/hostname"
+
+// toAffinity converts workload affinity rules to their Kubernetes form
+func toAffinity(affinity *types.Affinity) *corev1.Affinity {
+	if affinity == nil {
+		return nil
+	}
+
+	result := &corev1.Affinity{}
+	if na := affinity.NodeAffinity; na != nil {
+		result.NodeAffinity = &corev1.NodeAffinity{}
+		if required := na.RequiredDuringSchedulingIgnoredDuringExecution; required != nil {
+			selector := &corev1.NodeSelector{}
+			for _, term := range required.NodeSelectorTerms {
+				selector.NodeSelectorTerms = append(selector.NodeSelectorTerms, toNodeSelectorTerm(term))
+			}
+			result.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = selector
+		}
+		for _, preferred := range na.PreferredDuringSchedulingIgnoredDuringExecution {
+			result.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+				result.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+				corev1.PreferredSchedulingTerm{
+					Weight:     int32(preferred.Weight),
+					Preference: toNodeSelectorTerm(preferred.Preference),
+				})
+		}
+	}
+	if pa := affinity.PodAffinity; pa != nil {
+		result.PodAffinity = &corev1.PodAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution:  toPodAffinityTerms(pa.RequiredDuringSchedulingIgnoredDuringExecution),
+			PreferredDuringSchedulingIgnoredDuringExecution: toWeightedPodAffinityTerms(pa.PreferredDuringSchedulingIgnoredDuringExecution),
+		}
+	}
+	if paa := affinity.PodAntiAffinity; paa != nil {
+		result.PodAntiAffinity = &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution:  toPodAffinityTerms(paa.RequiredDuringSchedulingIgnoredDuringExecution),
+			PreferredDuringSchedulingIgnoredDuringExecution: toWeightedPodAffinityTerms(paa.PreferredDuringSchedulingIgnoredDuringExecution),
+		}
+	}
+	return result
+}
+
+func toNodeSelectorTerm(term types.NodeSelectorTerm) corev1.NodeSelectorTerm {
+	result := corev1.NodeSelectorTerm{}
+	for _, requirement := range term.MatchExpressions {
+		result.MatchExpressions = append(result.MatchExpressions, toNodeSelectorRequirement(requirement))
+	}
+	for _, requirement := range term.MatchFields {
+		result.MatchFields = append(result.MatchFields, toNodeSelectorRequirement(requirement))
+	}
+	return result
+}
+
+func toNodeSelectorRequirement(requirement types.NodeSelectorRequirement) corev1.NodeSelectorRequirement {
+	return corev1.NodeSelectorRequirement{
+		Key:      requirement.Key,
+		Operator: corev1.NodeSelectorOperator(requirement.Operator),
+		Values:   requirement.Values,
+	}
+}
+
+func toPodAffinityTerms(terms []types.PodAffinityTerm) []corev1.PodAffinityTerm {
+	var result []corev1.PodAffinityTerm
+	for _, term := range terms {
+		result = append(result, toPodAffinityTerm(term))
+	}
+	return result
+}
+
+func toWeightedPodAffinityTerms(terms []types.WeightedPodAffinityTerm) []corev1.WeightedPodAffinityTerm {
+	var result []corev1.WeightedPodAffinityTerm
+	for _, term := range terms {
+		result = append(result, corev1.WeightedPodAffinityTerm{
+			Weight:          int32(term.Weight),
+			PodAffinityTerm: toPodAffinityTerm(term.PodAffinityTerm),
+		})
+	}
+	return result
+}
+
+func toPodAffinityTerm(term types.PodAffinityTerm) corev1.PodAffinityTerm {
+	result := corev1.PodAffinityTerm{
+		Namespaces:  term.Namespaces,
+		TopologyKey: term.TopologyKey,
+	}
+	if term.LabelSelector != nil {
+		selector := &metav1.LabelSelector{MatchLabels: term.LabelSelector.MatchLabels}
+		for _, requirement := range term.LabelSelector.MatchExpressions {
+			selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+				Key:      requirement.Key,
+				Operator: metav1.LabelSelectorOperator(requirement.Operator),
+				Values:   requirement.Values,
+			})
+		}
+		result.LabelSelector = selector
+	}
+	return result
+}
@@ -0,0 +1,36 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/kcloud-opt/policy/internal/config"
+)
+
+// NewClientset creates a Kubernetes clientset from configuration, using the
+// in-cluster service account or the kubeconfig at ConfigPath
+func NewClientset(cfg config.KubernetesConfig) (kubernetes.Interface, error) {
+	if !cfg.Enabled {
+		return nil, fmt.Errorf("kubernetes integration is disabled")
+	}
+
+	var restConfig *rest.Config
+	var err error
+	if cfg.InCluster {
+		restConfig, err = rest.InClusterConfig()
+	} else {
+		restConfig, err = clientcmd.BuildConfigFromFlags("", cfg.ConfigPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubernetes config: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	return clientset, nil
+}
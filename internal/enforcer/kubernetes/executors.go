@@ -0,0 +1,655 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kcloud-opt/policy/internal/actions"
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// baseExecutor provides common functionality for Kubernetes action executors
+type baseExecutor struct {
+	actionTypes []string
+	client      kubernetes.Interface
+	storage     storage.StorageManager
+	config      config.KubernetesConfig
+	logger      types.Logger
+}
+
+// operation applies an action to the Kubernetes object backing a workload
+// and returns a result message and data
+type operation func(ctx context.Context, action *actions.Action, workload *types.Workload, ref workloadRef) (string, map[string]interface{}, error)
+
+// NewExecutors creates every Kubernetes-backed action executor
+func NewExecutors(client kubernetes.Interface, storage storage.StorageManager, cfg config.KubernetesConfig, logger types.Logger) []actions.Executor {
+	return []actions.Executor{
+		NewScheduleExecutor(client, storage, cfg, logger),
+		NewSuspendExecutor(client, storage, cfg, logger),
+		NewResumeExecutor(client, storage, cfg, logger),
+		NewTerminateExecutor(client, storage, cfg, logger),
+		NewScaleExecutor(client, storage, cfg, logger),
+	}
+}
+
+// CanExecute checks if this executor can handle the given action type
+func (be *baseExecutor) CanExecute(actionType string) bool {
+	for _, supportedType := range be.actionTypes {
+		if supportedType == actionType {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate validates the action before execution
+func (be *baseExecutor) Validate(action *actions.Action) error {
+	if action.Type == "" {
+		return fmt.Errorf("action type cannot be empty")
+	}
+	if action.Target == "" {
+		return fmt.Errorf("action target cannot be empty")
+	}
+	if workloadID, _ := action.Parameters["workload_id"].(string); workloadID == "" {
+		return fmt.Errorf("workload_id parameter is required")
+	}
+	return nil
+}
+
+// Health checks that the Kubernetes API server is reachable
+func (be *baseExecutor) Health(ctx context.Context) error {
+	if _, err := be.client.Discovery().ServerVersion(); err != nil {
+		return fmt.Errorf("kubernetes api unreachable: %w", err)
+	}
+	return nil
+}
+
+// execute resolves the workload's Kubernetes object, applies the operation
+// and records the resulting workload status in storage. An empty status
+// leaves the stored status unchanged.
+func (be *baseExecutor) execute(ctx context.Context, action *actions.Action, status types.WorkloadStatus, op operation) (*actions.Result, error) {
+	startTime := time.Now()
+
+	be.logger.Info("executing kubernetes action", "action_type", action.Type, "target", action.Target)
+
+	workloadID, ok := action.Parameters["workload_id"].(string)
+	if !ok || workloadID == "" {
+		return failedResult(action, startTime, "workload_id parameter is required", "missing workload_id parameter"), nil
+	}
+
+	workload, err := be.storage.Workload().Get(ctx, workloadID)
+	if err != nil {
+		return failedResult(action, startTime, fmt.Sprintf("Failed to get workload: %v", err), err.Error()), nil
+	}
+
+	ref, err := resolveRef(workload, action.Parameters, be.config.Namespace)
+	if err != nil {
+		return failedResult(action, startTime, fmt.Sprintf("Failed to resolve kubernetes object: %v", err), err.Error()), nil
+	}
+
+	message, data, err := op(ctx, action, workload, ref)
+	if err != nil {
+		return failedResult(action, startTime, fmt.Sprintf("Failed to %s %s: %v", action.Type, ref, err), err.Error()), nil
+	}
+
+	if status != "" {
+		workload.Status = status
+		workload.UpdatedAt = time.Now()
+		if err := be.storage.Workload().Update(ctx, workload); err != nil {
+			return failedResult(action, startTime, fmt.Sprintf("Failed to update workload: %v", err), err.Error()), nil
+		}
+	}
+
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["workload_id"] = workloadID
+	data["kind"] = ref.Kind
+	data["namespace"] = ref.Namespace
+	data["name"] = ref.Name
+
+	be.logger.Info("kubernetes action completed", "action_type", action.Type, "workload_id", workloadID, "object", ref.String())
+
+	return &actions.Result{
+		ActionType: action.Type,
+		Success:    true,
+		Message:    message,
+		Duration:   time.Since(startTime),
+		Timestamp:  time.Now(),
+		Data:       data,
+	}, nil
+}
+
+// failedResult builds the result of an action that could not be applied
+func failedResult(action *actions.Action, startTime time.Time, message, errMessage string) *actions.Result {
+	return &actions.Result{
+		ActionType: action.Type,
+		Success:    false,
+		Message:    message,
+		Duration:   time.Since(startTime),
+		Timestamp:  time.Now(),
+		Error:      errMessage,
+	}
+}
+
+// patch applies a JSON merge patch to the object
+func (be *baseExecutor) patch(ctx context.Context, ref workloadRef, patch map[string]interface{}) error {
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("failed to encode patch: %w", err)
+	}
+
+	switch ref.Kind {
+	case KindDeployment:
+		_, err = be.client.AppsV1().Deployments(ref.Namespace).Patch(ctx, ref.Name, k8stypes.MergePatchType, data, metav1.PatchOptions{})
+	case KindStatefulSet:
+		_, err = be.client.AppsV1().StatefulSets(ref.Namespace).Patch(ctx, ref.Name, k8stypes.MergePatchType, data, metav1.PatchOptions{})
+	case KindJob:
+		_, err = be.client.BatchV1().Jobs(ref.Namespace).Patch(ctx, ref.Name, k8stypes.MergePatchType, data, metav1.PatchOptions{})
+	default:
+		err = fmt.Errorf("unsupported kubernetes kind: %s", ref.Kind)
+	}
+	return err
+}
+
+// scaleOf returns the object's replica count, or parallelism for a Job, and
+// its annotations. Unset counts default to one as in Kubernetes.
+func (be *baseExecutor) scaleOf(ctx context.Context, ref workloadRef) (int32, map[string]string, error) {
+	var count *int32
+	var annotations map[string]string
+
+	switch ref.Kind {
+	case KindDeployment:
+		deployment, err := be.client.AppsV1().Deployments(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, nil, err
+		}
+		count, annotations = deployment.Spec.Replicas, deployment.Annotations
+	case KindStatefulSet:
+		statefulSet, err := be.client.AppsV1().StatefulSets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, nil, err
+		}
+		count, annotations = statefulSet.Spec.Replicas, statefulSet.Annotations
+	case KindJob:
+		job, err := be.client.BatchV1().Jobs(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, nil, err
+		}
+		count, annotations = job.Spec.Parallelism, job.Annotations
+	default:
+		return 0, nil, fmt.Errorf("unsupported kubernetes kind: %s", ref.Kind)
+	}
+
+	return replicasOrOne(count), annotations, nil
+}
+
+// podSpecOf returns the pod template spec of the object
+func (be *baseExecutor) podSpecOf(ctx context.Context, ref workloadRef) (*corev1.PodSpec, error) {
+	switch ref.Kind {
+	case KindDeployment:
+		deployment, err := be.client.AppsV1().Deployments(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &deployment.Spec.Template.Spec, nil
+	case KindStatefulSet:
+		statefulSet, err := be.client.AppsV1().StatefulSets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &statefulSet.Spec.Template.Spec, nil
+	case KindJob:
+		job, err := be.client.BatchV1().Jobs(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &job.Spec.Template.Spec, nil
+	default:
+		return nil, fmt.Errorf("unsupported kubernetes kind: %s", ref.Kind)
+	}
+}
+
+// findAutoscaler returns the HorizontalPodAutoscaler targeting the object,
+// or nil if there is none
+func (be *baseExecutor) findAutoscaler(ctx context.Context, ref workloadRef) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	list, err := be.client.AutoscalingV2().HorizontalPodAutoscalers(ref.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Items {
+		target := list.Items[i].Spec.ScaleTargetRef
+		if target.Kind == ref.Kind && target.Name == ref.Name {
+			return &list.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// scheduleExecutor pins a workload's pods through nodeSelector and affinity
+type scheduleExecutor struct {
+	baseExecutor
+}
+
+// NewScheduleExecutor creates a new Kubernetes schedule executor
+func NewScheduleExecutor(client kubernetes.Interface, storage storage.StorageManager, cfg config.KubernetesConfig, logger types.Logger) actions.Executor {
+	return &scheduleExecutor{
+		baseExecutor: baseExecutor{
+			actionTypes: []string{actions.ActionTypeSchedule},
+			client:      client,
+			storage:     storage,
+			config:      cfg,
+			logger:      logger,
+		},
+	}
+}
+
+// Execute patches the pod template with the workload's node selectors and
+// affinity, pinning it to the recommended node if one is given. The result
+// records the previous nodeSelector and affinity so the placement can be
+// reverted.
+func (se *scheduleExecutor) Execute(ctx context.Context, action *actions.Action) (*actions.Result, error) {
+	return se.execute(ctx, action, types.WorkloadStatusRunning, func(ctx context.Context, action *actions.Action, workload *types.Workload, ref workloadRef) (string, map[string]interface{}, error) {
+		nodeSelector := make(map[string]string)
+		var affinity interface{}
+		if workload.Constraints != nil {
+			for key, value := range workload.Constraints.NodeSelectors {
+				nodeSelector[key] = value
+			}
+			if converted := toAffinity(workload.Constraints.Affinity); converted != nil {
+				affinity = converted
+			}
+		}
+
+		node, _ := action.Parameters["recommended_node"].(string)
+		if node == "" {
+			node, _ = action.Parameters["node_id"].(string)
+		}
+		if node != "" {
+			nodeSelector[hostnameLabel] = node
+		}
+
+		podSpec := make(map[string]interface{})
+		if len(nodeSelector) > 0 {
+			podSpec["nodeSelector"] = nodeSelector
+		}
+		if affinity != nil {
+			podSpec["affinity"] = affinity
+		}
+		if len(podSpec) == 0 {
+			return "Workload has no placement constraints to apply", nil, nil
+		}
+
+		previous, err := se.podSpecOf(ctx, ref)
+		if err != nil {
+			return "", nil, err
+		}
+
+		patch := map[string]interface{}{
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": podSpec,
+				},
+			},
+		}
+		if err := se.patch(ctx, ref, patch); err != nil {
+			return "", nil, err
+		}
+
+		return "Workload scheduled successfully", map[string]interface{}{
+			"node_selector":          nodeSelector,
+			"previous_node_selector": previous.NodeSelector,
+			"previous_affinity":      previous.Affinity,
+		}, nil
+	})
+}
+
+// suspendExecutor stops a workload without deleting it
+type suspendExecutor struct {
+	baseExecutor
+}
+
+// NewSuspendExecutor creates a new Kubernetes suspend executor
+func NewSuspendExecutor(client kubernetes.Interface, storage storage.StorageManager, cfg config.KubernetesConfig, logger types.Logger) actions.Executor {
+	return &suspendExecutor{
+		baseExecutor: baseExecutor{
+			actionTypes: []string{actions.ActionTypeSuspend},
+			client:      client,
+			storage:     storage,
+			config:      cfg,
+			logger:      logger,
+		},
+	}
+}
+
+// Execute suspends a Job through spec.suspend, or scales a Deployment or
+// StatefulSet to zero while recording its replica count for resume
+func (se *suspendExecutor) Execute(ctx context.Context, action *actions.Action) (*actions.Result, error) {
+	return se.execute(ctx, action, types.WorkloadStatusSuspended, func(ctx context.Context, action *actions.Action, workload *types.Workload, ref workloadRef) (string, map[string]interface{}, error) {
+		if ref.Kind == KindJob {
+			patch := map[string]interface{}{"spec": map[string]interface{}{"suspend": true}}
+			if err := se.patch(ctx, ref, patch); err != nil {
+				return "", nil, err
+			}
+			return "Workload suspended successfully", nil, nil
+		}
+
+		replicas, annotations, err := se.scaleOf(ctx, ref)
+		if err != nil {
+			return "", nil, err
+		}
+		if _, suspended := annotations[AnnotationSuspendedReplicas]; suspended && replicas == 0 {
+			return "Workload already suspended", nil, nil
+		}
+
+		patch := map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{
+					AnnotationSuspendedReplicas: strconv.Itoa(int(replicas)),
+				},
+			},
+			"spec": map[string]interface{}{"replicas": 0},
+		}
+		if err := se.patch(ctx, ref, patch); err != nil {
+			return "", nil, err
+		}
+
+		return "Workload suspended successfully", map[string]interface{}{
+			"previous_replicas": replicas,
+		}, nil
+	})
+}
+
+// resumeExecutor restarts a suspended workload
+type resumeExecutor struct {
+	baseExecutor
+}
+
+// NewResumeExecutor creates a new Kubernetes resume executor
+func NewResumeExecutor(client kubernetes.Interface, storage storage.StorageManager, cfg config.KubernetesConfig, logger types.Logger) actions.Executor {
+	return &resumeExecutor{
+		baseExecutor: baseExecutor{
+			actionTypes: []string{actions.ActionTypeResume},
+			client:      client,
+			storage:     storage,
+			config:      cfg,
+			logger:      logger,
+		},
+	}
+}
+
+// Execute clears a Job's spec.suspend, or restores the replica count a
+// Deployment or StatefulSet had before it was suspended
+func (re *resumeExecutor) Execute(ctx context.Context, action *actions.Action) (*actions.Result, error) {
+	return re.execute(ctx, action, types.WorkloadStatusRunning, func(ctx context.Context, action *actions.Action, workload *types.Workload, ref workloadRef) (string, map[string]interface{}, error) {
+		if ref.Kind == KindJob {
+			patch := map[string]interface{}{"spec": map[string]interface{}{"suspend": false}}
+			if err := re.patch(ctx, ref, patch); err != nil {
+				return "", nil, err
+			}
+			return "Workload resumed successfully", nil, nil
+		}
+
+		_, annotations, err := re.scaleOf(ctx, ref)
+		if err != nil {
+			return "", nil, err
+		}
+		recorded, suspended := annotations[AnnotationSuspendedReplicas]
+		if !suspended {
+			return "Workload is not suspended", nil, nil
+		}
+
+		replicas := 1
+		if parsed, err := strconv.Atoi(recorded); err == nil && parsed > 0 {
+			replicas = parsed
+		}
+
+		patch := map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{
+					AnnotationSuspendedReplicas: nil,
+				},
+			},
+			"spec": map[string]interface{}{"replicas": replicas},
+		}
+		if err := re.patch(ctx, ref, patch); err != nil {
+			return "", nil, err
+		}
+
+		return "Workload resumed successfully", map[string]interface{}{
+			"replicas": replicas,
+		}, nil
+	})
+}
+
+// terminateExecutor deletes a workload's Kubernetes object
+type terminateExecutor struct {
+	baseExecutor
+}
+
+// NewTerminateExecutor creates a new Kubernetes terminate executor
+func NewTerminateExecutor(client kubernetes.Interface, storage storage.StorageManager, cfg config.KubernetesConfig, logger types.Logger) actions.Executor {
+	return &terminateExecutor{
+		baseExecutor: baseExecutor{
+			actionTypes: []string{actions.ActionTypeTerminate},
+			client:      client,
+			storage:     storage,
+			config:      cfg,
+			logger:      logger,
+		},
+	}
+}
+
+// Execute deletes the object with background propagation, honoring the
+// grace_period parameter. An object that is already gone is not an error.
+func (te *terminateExecutor) Execute(ctx context.Context, action *actions.Action) (*actions.Result, error) {
+	return te.execute(ctx, action, types.WorkloadStatusCompleted, func(ctx context.Context, action *actions.Action, workload *types.Workload, ref workloadRef) (string, map[string]interface{}, error) {
+		propagation := metav1.DeletePropagationBackground
+		options := metav1.DeleteOptions{PropagationPolicy: &propagation}
+		if gracePeriod, _ := action.Parameters["grace_period"].(string); gracePeriod != "" {
+			duration, err := time.ParseDuration(gracePeriod)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid grace_period: %w", err)
+			}
+			seconds := int64(duration.Seconds())
+			options.GracePeriodSeconds = &seconds
+		}
+
+		var err error
+		switch ref.Kind {
+		case KindDeployment:
+			err = te.client.AppsV1().Deployments(ref.Namespace).Delete(ctx, ref.Name, options)
+		case KindStatefulSet:
+			err = te.client.AppsV1().StatefulSets(ref.Namespace).Delete(ctx, ref.Name, options)
+		case KindJob:
+			err = te.client.BatchV1().Jobs(ref.Namespace).Delete(ctx, ref.Name, options)
+		}
+		if apierrors.IsNotFound(err) {
+			return "Workload already deleted", nil, nil
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		return "Workload terminated successfully", nil, nil
+	})
+}
+
+// scaleExecutor changes a workload's replica count or autoscaling bounds
+type scaleExecutor struct {
+	baseExecutor
+}
+
+// NewScaleExecutor creates a new Kubernetes scale executor
+func NewScaleExecutor(client kubernetes.Interface, storage storage.StorageManager, cfg config.KubernetesConfig, logger types.Logger) actions.Executor {
+	return &scaleExecutor{
+		baseExecutor: baseExecutor{
+			actionTypes: []string{actions.ActionTypeScale},
+			client:      client,
+			storage:     storage,
+			config:      cfg,
+			logger:      logger,
+		},
+	}
+}
+
+// Execute scales the workload. The min_replicas and max_replicas parameters
+// set HorizontalPodAutoscaler bounds. Otherwise the target comes from
+// replicas, or from scale_factor applied to the current count, and is
+// written to the autoscaler's minimum when one manages the object, to
+// spec.replicas for Deployments and StatefulSets, or to spec.parallelism
+// for Jobs.
+func (se *scaleExecutor) Execute(ctx context.Context, action *actions.Action) (*actions.Result, error) {
+	return se.execute(ctx, action, "", func(ctx context.Context, action *actions.Action, workload *types.Workload, ref workloadRef) (string, map[string]interface{}, error) {
+		minReplicas, hasMin, err := intParam(action.Parameters, "min_replicas")
+		if err != nil {
+			return "", nil, err
+		}
+		maxReplicas, hasMax, err := intParam(action.Parameters, "max_replicas")
+		if err != nil {
+			return "", nil, err
+		}
+		if hasMin || hasMax {
+			return se.scaleAutoscaler(ctx, ref, minReplicas, hasMin, maxReplicas, hasMax)
+		}
+
+		current, _, err := se.scaleOf(ctx, ref)
+		if err != nil {
+			return "", nil, err
+		}
+		desired, err := desiredReplicas(action.Parameters, current)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if ref.Kind == KindJob {
+			patch := map[string]interface{}{"spec": map[string]interface{}{"parallelism": desired}}
+			if err := se.patch(ctx, ref, patch); err != nil {
+				return "", nil, err
+			}
+			return fmt.Sprintf("Job parallelism scaled from %d to %d", current, desired), map[string]interface{}{
+				"previous_replicas": current,
+				"replicas":          desired,
+			}, nil
+		}
+
+		hpa, err := se.findAutoscaler(ctx, ref)
+		if err != nil {
+			return "", nil, err
+		}
+		if hpa != nil {
+			// The autoscaler would revert a direct replica change, so raise
+			// or lower its floor instead
+			bound := hpa.Spec.MaxReplicas
+			if desired > bound {
+				bound = desired
+			}
+			return se.scaleAutoscaler(ctx, ref, desired, true, bound, true)
+		}
+
+		patch := map[string]interface{}{"spec": map[string]interface{}{"replicas": desired}}
+		if err := se.patch(ctx, ref, patch); err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("Workload scaled from %d to %d replicas", current, desired), map[string]interface{}{
+			"previous_replicas": current,
+			"replicas":          desired,
+		}, nil
+	})
+}
+
+// scaleAutoscaler patches the bounds of the autoscaler managing the object.
+// The result records the previous bounds so the change can be reverted.
+func (se *scaleExecutor) scaleAutoscaler(ctx context.Context, ref workloadRef, minReplicas int32, hasMin bool, maxReplicas int32, hasMax bool) (string, map[string]interface{}, error) {
+	hpa, err := se.findAutoscaler(ctx, ref)
+	if err != nil {
+		return "", nil, err
+	}
+	if hpa == nil {
+		return "", nil, fmt.Errorf("no HorizontalPodAutoscaler targets %s", ref)
+	}
+
+	// Kubernetes defaults an unset minimum to one
+	previousMin := int32(1)
+	if hpa.Spec.MinReplicas != nil {
+		previousMin = *hpa.Spec.MinReplicas
+	}
+	previousMax := hpa.Spec.MaxReplicas
+
+	if !hasMin {
+		minReplicas = previousMin
+	}
+	if !hasMax {
+		maxReplicas = previousMax
+	}
+	if minReplicas < 1 {
+		return "", nil, fmt.Errorf("autoscaler minimum must be at least 1, got %d", minReplicas)
+	}
+	if maxReplicas < minReplicas {
+		return "", nil, fmt.Errorf("autoscaler maximum %d is below minimum %d", maxReplicas, minReplicas)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"minReplicas": minReplicas,
+			"maxReplicas": maxReplicas,
+		},
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode patch: %w", err)
+	}
+	if _, err := se.client.AutoscalingV2().HorizontalPodAutoscalers(ref.Namespace).Patch(ctx, hpa.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return "", nil, err
+	}
+
+	return fmt.Sprintf("Autoscaler %s bounds set to %d-%d", hpa.Name, minReplicas, maxReplicas), map[string]interface{}{
+		"autoscaler":            hpa.Name,
+		"min_replicas":          minReplicas,
+		"max_replicas":          maxReplicas,
+		"previous_min_replicas": previousMin,
+		"previous_max_replicas": previousMax,
+	}, nil
+}
+
+// desiredReplicas computes the target count from the replicas parameter or
+// from scale_factor and scale_direction relative to the current count. A
+// factor above one with direction "down" divides rather than multiplies.
+func desiredReplicas(parameters map[string]interface{}, current int32) (int32, error) {
+	replicas, ok, err := intParam(parameters, "replicas")
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		return replicas, nil
+	}
+
+	factor, ok, err := floatParam(parameters, "scale_factor")
+	if err != nil {
+		return 0, err
+	}
+	if !ok || factor <= 0 {
+		return 0, fmt.Errorf("replicas or a positive scale_factor parameter is required")
+	}
+	if direction, _ := parameters["scale_direction"].(string); direction == "down" && factor > 1 {
+		factor = 1 / factor
+	}
+
+	desired := math.Ceil(float64(current) * factor)
+	if desired > math.MaxInt32 {
+		return 0, fmt.Errorf("scaled replica count out of range: %v", desired)
+	}
+	return int32(desired), nil
+}
@@ -0,0 +1,271 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kcloud-opt/policy/internal/actions"
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+const testNamespace = "workloads"
+
+// testLogger discards log output
+type testLogger struct{}
+
+func (l testLogger) Info(msg string, fields ...interface{})                    {}
+func (l testLogger) Warn(msg string, fields ...interface{})                    {}
+func (l testLogger) Error(msg string, fields ...interface{})                   {}
+func (l testLogger) Debug(msg string, fields ...interface{})                   {}
+func (l testLogger) Fatal(msg string, fields ...interface{})                   {}
+func (l testLogger) WithError(err error) types.Logger                          { return l }
+func (l testLogger) WithDuration(duration time.Duration) types.Logger          { return l }
+func (l testLogger) WithPolicy(policyID, policyName string) types.Logger       { return l }
+func (l testLogger) WithWorkload(workloadID, workloadType string) types.Logger { return l }
+func (l testLogger) WithEvaluation(evaluationID string) types.Logger           { return l }
+
+func int32Ptr(v int32) *int32 { return &v }
+
+func newDeployment(name string, replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(replicas)},
+	}
+}
+
+func newJob(name string) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		Spec:       batchv1.JobSpec{Parallelism: int32Ptr(2)},
+	}
+}
+
+func setup(t *testing.T, workload *types.Workload, objects ...runtime.Object) (*fake.Clientset, storage.StorageManager) {
+	t.Helper()
+
+	client := fake.NewClientset(objects...)
+	store := memory.NewStorageManager()
+	workload.Metadata.Namespace = testNamespace
+	require.NoError(t, store.Workload().Create(context.Background(), workload))
+	return client, store
+}
+
+func newWorkload(id, name string, workloadType types.WorkloadType) *types.Workload {
+	return &types.Workload{
+		ID:           id,
+		Name:         name,
+		Type:         workloadType,
+		Status:       types.WorkloadStatusRunning,
+		Requirements: types.Resources{CPU: 1, Memory: "1Gi"},
+	}
+}
+
+func newAction(actionType, workloadID string, parameters map[string]interface{}) *actions.Action {
+	params := map[string]interface{}{"workload_id": workloadID}
+	for k, v := range parameters {
+		params[k] = v
+	}
+	return &actions.Action{Type: actionType, Target: workloadID, Parameters: params}
+}
+
+func TestScheduleExecutor_PatchesPlacement(t *testing.T) {
+	workload := newWorkload("wl-1", "web", types.WorkloadTypeWeb)
+	workload.Constraints = &types.WorkloadConstraints{
+		NodeSelectors: map[string]string{"pool": "general"},
+		Affinity: &types.Affinity{
+			PodAntiAffinity: &types.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []types.PodAffinityTerm{{
+					LabelSelector: &types.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+					TopologyKey:   hostnameLabel,
+				}},
+			},
+		},
+	}
+	deployment := newDeployment("web", 2)
+	deployment.Spec.Template.Spec.NodeSelector = map[string]string{"pool": "legacy"}
+	client, store := setup(t, workload, deployment)
+	executor := NewScheduleExecutor(client, store, config.KubernetesConfig{}, testLogger{})
+
+	result, err := executor.Execute(context.Background(), newAction(actions.ActionTypeSchedule, "wl-1",
+		map[string]interface{}{"recommended_node": "node-a"}))
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Message)
+
+	// The placement it replaced is kept so it can be reverted
+	assert.Equal(t, map[string]string{"pool": "legacy"}, result.Data["previous_node_selector"])
+	assert.Nil(t, result.Data["previous_affinity"])
+
+	deployment, err = client.AppsV1().Deployments(testNamespace).Get(context.Background(), "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	podSpec := deployment.Spec.Template.Spec
+	assert.Equal(t, map[string]string{"pool": "general", hostnameLabel: "node-a"}, podSpec.NodeSelector)
+	require.NotNil(t, podSpec.Affinity)
+	require.NotNil(t, podSpec.Affinity.PodAntiAffinity)
+	assert.Equal(t, "web", podSpec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0].LabelSelector.MatchLabels["app"])
+}
+
+func TestSuspendResume_Deployment(t *testing.T) {
+	workload := newWorkload("wl-1", "api", types.WorkloadTypeWeb)
+	client, store := setup(t, workload, newDeployment("api", 3))
+	ctx := context.Background()
+
+	suspend := NewSuspendExecutor(client, store, config.KubernetesConfig{}, testLogger{})
+	result, err := suspend.Execute(ctx, newAction(actions.ActionTypeSuspend, "wl-1", nil))
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Message)
+
+	deployment, err := client.AppsV1().Deployments(testNamespace).Get(ctx, "api", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), *deployment.Spec.Replicas)
+	assert.Equal(t, "3", deployment.Annotations[AnnotationSuspendedReplicas])
+
+	stored, err := store.Workload().Get(ctx, "wl-1")
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkloadStatusSuspended, stored.Status)
+
+	resume := NewResumeExecutor(client, store, config.KubernetesConfig{}, testLogger{})
+	result, err = resume.Execute(ctx, newAction(actions.ActionTypeResume, "wl-1", nil))
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Message)
+
+	deployment, err = client.AppsV1().Deployments(testNamespace).Get(ctx, "api", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), *deployment.Spec.Replicas)
+	assert.NotContains(t, deployment.Annotations, AnnotationSuspendedReplicas)
+
+	stored, err = store.Workload().Get(ctx, "wl-1")
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkloadStatusRunning, stored.Status)
+}
+
+func TestSuspendResume_Job(t *testing.T) {
+	workload := newWorkload("wl-1", "train", types.WorkloadTypeMLTraining)
+	client, store := setup(t, workload, newJob("train"))
+	ctx := context.Background()
+
+	result, err := NewSuspendExecutor(client, store, config.KubernetesConfig{}, testLogger{}).
+		Execute(ctx, newAction(actions.ActionTypeSuspend, "wl-1", nil))
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Message)
+
+	job, err := client.BatchV1().Jobs(testNamespace).Get(ctx, "train", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, job.Spec.Suspend)
+	assert.True(t, *job.Spec.Suspend)
+
+	result, err = NewResumeExecutor(client, store, config.KubernetesConfig{}, testLogger{}).
+		Execute(ctx, newAction(actions.ActionTypeResume, "wl-1", nil))
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Message)
+
+	job, err = client.BatchV1().Jobs(testNamespace).Get(ctx, "train", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.False(t, *job.Spec.Suspend)
+}
+
+func TestTerminateExecutor_DeletesObject(t *testing.T) {
+	workload := newWorkload("wl-1", "db", types.WorkloadTypeDatabase)
+	statefulSet := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: testNamespace}}
+	client, store := setup(t, workload, statefulSet)
+	ctx := context.Background()
+	executor := NewTerminateExecutor(client, store, config.KubernetesConfig{}, testLogger{})
+
+	result, err := executor.Execute(ctx, newAction(actions.ActionTypeTerminate, "wl-1",
+		map[string]interface{}{"grace_period": "30s"}))
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Message)
+
+	_, err = client.AppsV1().StatefulSets(testNamespace).Get(ctx, "db", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	// Terminating again is idempotent
+	result, err = executor.Execute(ctx, newAction(actions.ActionTypeTerminate, "wl-1", nil))
+	require.NoError(t, err)
+	assert.True(t, result.Success, result.Message)
+}
+
+func TestScaleExecutor(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("replicas", func(t *testing.T) {
+		workload := newWorkload("wl-1", "api", types.WorkloadTypeWeb)
+		client, store := setup(t, workload, newDeployment("api", 2))
+
+		result, err := NewScaleExecutor(client, store, config.KubernetesConfig{}, testLogger{}).
+			Execute(ctx, newAction(actions.ActionTypeScale, "wl-1",
+				map[string]interface{}{"scale_factor": 2.0, "scale_direction": "up"}))
+		require.NoError(t, err)
+		require.True(t, result.Success, result.Message)
+
+		deployment, err := client.AppsV1().Deployments(testNamespace).Get(ctx, "api", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, int32(4), *deployment.Spec.Replicas)
+		assert.Equal(t, int32(2), result.Data["previous_replicas"])
+	})
+
+	t.Run("autoscaler bounds", func(t *testing.T) {
+		workload := newWorkload("wl-1", "api", types.WorkloadTypeWeb)
+		hpa := &autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-hpa", Namespace: testNamespace},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: KindDeployment, Name: "api", APIVersion: "apps/v1"},
+				MinReplicas:    int32Ptr(2),
+				MaxReplicas:    5,
+			},
+		}
+		client, store := setup(t, workload, newDeployment("api", 2), hpa)
+
+		result, err := NewScaleExecutor(client, store, config.KubernetesConfig{}, testLogger{}).
+			Execute(ctx, newAction(actions.ActionTypeScale, "wl-1", map[string]interface{}{"replicas": 8}))
+		require.NoError(t, err)
+		require.True(t, result.Success, result.Message)
+
+		updated, err := client.AutoscalingV2().HorizontalPodAutoscalers(testNamespace).Get(ctx, "api-hpa", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, int32(8), *updated.Spec.MinReplicas)
+		assert.Equal(t, int32(8), updated.Spec.MaxReplicas)
+		assert.Equal(t, int32(2), result.Data["previous_min_replicas"])
+		assert.Equal(t, int32(5), result.Data["previous_max_replicas"])
+
+		// Replicas are left to the autoscaler
+		deployment, err := client.AppsV1().Deployments(testNamespace).Get(ctx, "api", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), *deployment.Spec.Replicas)
+	})
+}
+
+func TestExecutor_Failures(t *testing.T) {
+	ctx := context.Background()
+	workload := newWorkload("wl-1", "missing", types.WorkloadTypeWeb)
+	client, store := setup(t, workload)
+	executor := NewSuspendExecutor(client, store, config.KubernetesConfig{}, testLogger{})
+
+	result, err := executor.Execute(ctx, newAction(actions.ActionTypeSuspend, "wl-1", nil))
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.NotEmpty(t, result.Error)
+
+	stored, err := store.Workload().Get(ctx, "wl-1")
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkloadStatusRunning, stored.Status)
+
+	result, err = executor.Execute(ctx, newAction(actions.ActionTypeSuspend, "wl-1",
+		map[string]interface{}{"kind": "CronJob"}))
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "unsupported kubernetes kind")
+}
@@ -0,0 +1,91 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kcloud-opt/policy/internal/actions"
+)
+
+// Observe reads back the status the cluster reports for the object of the
+// workload an action targets. A Deployment or StatefulSet is ready once its
+// controller has seen the latest spec and every desired replica is updated
+// and ready; a Job once it runs unsuspended or has succeeded.
+func (be *baseExecutor) Observe(ctx context.Context, action *actions.Action) (map[string]interface{}, error) {
+	workloadID, _ := action.Parameters["workload_id"].(string)
+	if workloadID == "" {
+		return nil, fmt.Errorf("workload_id parameter is required")
+	}
+
+	workload, err := be.storage.Workload().Get(ctx, workloadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload: %w", err)
+	}
+
+	ref, err := resolveRef(workload, action.Parameters, be.config.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve kubernetes object: %w", err)
+	}
+
+	var desired, ready, updated, available int32
+	var live bool
+	switch ref.Kind {
+	case KindDeployment:
+		deployment, err := be.client.AppsV1().Deployments(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		status := deployment.Status
+		desired = replicasOrOne(deployment.Spec.Replicas)
+		ready, updated, available = status.ReadyReplicas, status.UpdatedReplicas, status.AvailableReplicas
+		// Replicas of the previous revision still count in status.Replicas
+		// until the rollout finishes
+		live = status.ObservedGeneration >= deployment.Generation &&
+			status.Replicas == desired && updated == desired && ready == desired && available == desired
+	case KindStatefulSet:
+		statefulSet, err := be.client.AppsV1().StatefulSets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		status := statefulSet.Status
+		desired = replicasOrOne(statefulSet.Spec.Replicas)
+		ready, updated, available = status.ReadyReplicas, status.UpdatedReplicas, status.AvailableReplicas
+		live = status.ObservedGeneration >= statefulSet.Generation && updated == desired && ready == desired
+	case KindJob:
+		job, err := be.client.BatchV1().Jobs(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		desired = replicasOrOne(job.Spec.Parallelism)
+		ready, available = job.Status.Active, job.Status.Active
+		if job.Status.Ready != nil {
+			ready = *job.Status.Ready
+		}
+		suspended := job.Spec.Suspend != nil && *job.Spec.Suspend
+		live = !suspended && (ready > 0 || job.Status.Succeeded > 0)
+	default:
+		return nil, fmt.Errorf("unsupported kubernetes kind: %s", ref.Kind)
+	}
+
+	return map[string]interface{}{
+		"kind":               ref.Kind,
+		"namespace":          ref.Namespace,
+		"name":               ref.Name,
+		"replicas":           int(desired),
+		"ready_replicas":     int(ready),
+		"updated_replicas":   int(updated),
+		"available_replicas": int(available),
+		"ready":              live,
+	}, nil
+}
+
+// replicasOrOne returns a replica count, defaulting an unset one to one as
+// Kubernetes does
+func replicasOrOne(count *int32) int32 {
+	if count == nil {
+		return 1
+	}
+	return *count
+}
@@ -0,0 +1,98 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kcloud-opt/policy/internal/actions"
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+func TestObserve_Deployment(t *testing.T) {
+	ctx := context.Background()
+	deployment := newDeployment("api", 3)
+	deployment.Generation = 2
+	client, store := setup(t, newWorkload("wl-1", "api", types.WorkloadTypeWeb), deployment)
+	executor := NewResumeExecutor(client, store, config.KubernetesConfig{}, testLogger{})
+	observer, ok := executor.(actions.Observer)
+	require.True(t, ok)
+	action := newAction(actions.ActionTypeResume, "wl-1", nil)
+
+	tests := []struct {
+		name   string
+		status appsv1.DeploymentStatus
+		ready  bool
+	}{
+		{
+			name:   "controller has not seen the latest spec",
+			status: appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 3, ReadyReplicas: 3, AvailableReplicas: 3},
+		},
+		{
+			name:   "replicas still starting",
+			status: appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, ReadyReplicas: 1, AvailableReplicas: 1},
+		},
+		{
+			name:   "old replicas still running",
+			status: appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 4, UpdatedReplicas: 3, ReadyReplicas: 3, AvailableReplicas: 3},
+		},
+		{
+			name:   "every replica updated and ready",
+			status: appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, ReadyReplicas: 3, AvailableReplicas: 3},
+			ready:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment.Status = tt.status
+			_, err := client.AppsV1().Deployments(testNamespace).UpdateStatus(ctx, deployment, metav1.UpdateOptions{})
+			require.NoError(t, err)
+
+			observed, err := observer.Observe(ctx, action)
+			require.NoError(t, err)
+			assert.Equal(t, tt.ready, observed["ready"])
+			assert.Equal(t, 3, observed["replicas"])
+			assert.Equal(t, int(tt.status.ReadyReplicas), observed["ready_replicas"])
+			assert.Equal(t, KindDeployment, observed["kind"])
+		})
+	}
+}
+
+func TestObserve_Job(t *testing.T) {
+	ctx := context.Background()
+	job := newJob("batch")
+	client, store := setup(t, newWorkload("wl-1", "batch", types.WorkloadTypeBatch), job)
+	observer := NewResumeExecutor(client, store, config.KubernetesConfig{}, testLogger{}).(actions.Observer)
+	action := newAction(actions.ActionTypeResume, "wl-1", nil)
+
+	// A job whose pods have not started is not ready
+	observed, err := observer.Observe(ctx, action)
+	require.NoError(t, err)
+	assert.Equal(t, false, observed["ready"])
+
+	job.Status.Active = 2
+	job.Status.Ready = int32Ptr(1)
+	_, err = client.BatchV1().Jobs(testNamespace).UpdateStatus(ctx, job, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	observed, err = observer.Observe(ctx, action)
+	require.NoError(t, err)
+	assert.Equal(t, true, observed["ready"])
+	assert.Equal(t, 1, observed["ready_replicas"])
+
+	// A suspended job is not ready whatever its pods report
+	job.Spec.Suspend = &[]bool{true}[0]
+	_, err = client.BatchV1().Jobs(testNamespace).Update(ctx, job, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	observed, err = observer.Observe(ctx, action)
+	require.NoError(t, err)
+	assert.Equal(t, false, observed["ready"])
+
+	_, err = observer.Observe(ctx, newAction(actions.ActionTypeResume, "wl-2", nil))
+	assert.Error(t, err)
+}
@@ -0,0 +1,164 @@
+package kubernetes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// Supported Kubernetes workload kinds
+const (
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+	KindJob         = "Job"
+)
+
+// Annotations that map a workload onto its Kubernetes object
+const (
+	AnnotationKind      = "kcloud.io/kind"
+	AnnotationName      = "kcloud.io/name"
+	AnnotationNamespace = "kcloud.io/namespace"
+
+	// AnnotationSuspendedReplicas records the replica count of a suspended
+	// Deployment or StatefulSet so resume can restore it
+	AnnotationSuspendedReplicas = "kcloud.io/suspended-replicas"
+)
+
+// workloadRef identifies the Kubernetes object backing a workload
+type workloadRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// String returns the ref as kind/namespace/name
+func (r workloadRef) String() string {
+	return fmt.Sprintf("%s/%s/%s", r.Kind, r.Namespace, r.Name)
+}
+
+// resolveRef determines the Kubernetes object for a workload. Action
+// parameters take precedence over workload annotations, which take
+// precedence over defaults derived from the workload itself.
+func resolveRef(workload *types.Workload, parameters map[string]interface{}, defaultNamespace string) (workloadRef, error) {
+	ref := workloadRef{
+		Kind:      defaultKind(workload.Type),
+		Namespace: workload.Metadata.Namespace,
+		Name:      workload.Name,
+	}
+	if ref.Namespace == "" {
+		ref.Namespace = defaultNamespace
+	}
+
+	if kind := workload.Annotations[AnnotationKind]; kind != "" {
+		ref.Kind = kind
+	}
+	if name := workload.Annotations[AnnotationName]; name != "" {
+		ref.Name = name
+	}
+	if namespace := workload.Annotations[AnnotationNamespace]; namespace != "" {
+		ref.Namespace = namespace
+	}
+
+	if kind, ok := parameters["kind"].(string); ok && kind != "" {
+		ref.Kind = kind
+	}
+	if name, ok := parameters["name"].(string); ok && name != "" {
+		ref.Name = name
+	}
+	if namespace, ok := parameters["namespace"].(string); ok && namespace != "" {
+		ref.Namespace = namespace
+	}
+
+	switch ref.Kind {
+	case KindDeployment, KindStatefulSet, KindJob:
+	default:
+		return ref, fmt.Errorf("unsupported kubernetes kind: %s", ref.Kind)
+	}
+	if ref.Name == "" {
+		return ref, fmt.Errorf("workload %s has no kubernetes object name", workload.ID)
+	}
+	if ref.Namespace == "" {
+		return ref, fmt.Errorf("workload %s has no kubernetes namespace", workload.ID)
+	}
+
+	return ref, nil
+}
+
+// defaultKind maps a workload type onto the Kubernetes kind that usually
+// runs it
+func defaultKind(workloadType types.WorkloadType) string {
+	switch workloadType {
+	case types.WorkloadTypeBatch, types.WorkloadTypeMLTraining:
+		return KindJob
+	case types.WorkloadTypeDatabase, types.WorkloadTypeStorage:
+		return KindStatefulSet
+	default:
+		return KindDeployment
+	}
+}
+
+// intParam reads an integer action parameter, accepting the numeric and
+// string forms produced by Go callers and JSON decoding
+func intParam(parameters map[string]interface{}, key string) (int32, bool, error) {
+	value, exists := parameters[key]
+	if !exists || value == nil {
+		return 0, false, nil
+	}
+
+	var parsed int64
+	switch v := value.(type) {
+	case int:
+		parsed = int64(v)
+	case int32:
+		parsed = int64(v)
+	case int64:
+		parsed = v
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, false, fmt.Errorf("parameter %s must be a whole number, got %v", key, v)
+		}
+		parsed = int64(v)
+	case string:
+		var err error
+		parsed, err = strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return 0, false, fmt.Errorf("parameter %s: %w", key, err)
+		}
+	default:
+		return 0, false, fmt.Errorf("parameter %s has unsupported type %T", key, value)
+	}
+
+	if parsed < 0 || parsed > math.MaxInt32 {
+		return 0, false, fmt.Errorf("parameter %s out of range: %d", key, parsed)
+	}
+	return int32(parsed), true, nil
+}
+
+// floatParam reads a floating point action parameter
+func floatParam(parameters map[string]interface{}, key string) (float64, bool, error) {
+	value, exists := parameters[key]
+	if !exists || value == nil {
+		return 0, false, nil
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return v, true, nil
+	case float32:
+		return float64(v), true, nil
+	case int:
+		return float64(v), true, nil
+	case int64:
+		return float64(v), true, nil
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("parameter %s: %w", key, err)
+		}
+		return parsed, true, nil
+	default:
+		return 0, false, fmt.Errorf("parameter %s has unsupported type %T", key, value)
+	}
+}
@@ -0,0 +1,149 @@
+package enforcer
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// Lock resource kinds
+const (
+	lockWorkload = "workload"
+	lockNode     = "node"
+)
+
+// lockManager implements LockManager interface with in-memory leases
+type lockManager struct {
+	config config.LockConfig
+	logger types.Logger
+	leases map[string]*Lease
+	mu     sync.Mutex
+}
+
+// NewLockManager creates a new lock manager
+func NewLockManager(cfg config.LockConfig, logger types.Logger) LockManager {
+	return &lockManager{
+		config: cfg,
+		logger: logger,
+		leases: make(map[string]*Lease),
+	}
+}
+
+// Acquire leases every resource to a decision, all or nothing. Leases the
+// decision already holds are renewed and expired leases are taken over.
+func (lm *lockManager) Acquire(decisionID string, priority types.Priority, resources []string) ([]*Lease, error) {
+	if decisionID == "" {
+		return nil, fmt.Errorf("decision ID cannot be empty")
+	}
+
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	now := time.Now()
+	var conflicts []*Lease
+	for _, resource := range resources {
+		lease, exists := lm.leases[resource]
+		if !exists || lease.DecisionID == decisionID {
+			continue
+		}
+		if lease.ExpiresAt != nil && !now.Before(*lease.ExpiresAt) {
+			lm.logger.Warn("taking over expired lease",
+				"resource", resource,
+				"holder", lease.DecisionID,
+				"decision_id", decisionID)
+			delete(lm.leases, resource)
+			continue
+		}
+		leaseCopy := *lease
+		conflicts = append(conflicts, &leaseCopy)
+	}
+	if len(conflicts) > 0 {
+		return conflicts, nil
+	}
+
+	var expiresAt *time.Time
+	if lm.config.LeaseDuration > 0 {
+		expiry := now.Add(lm.config.LeaseDuration)
+		expiresAt = &expiry
+	}
+	for _, resource := range resources {
+		acquiredAt := now
+		if lease, exists := lm.leases[resource]; exists {
+			acquiredAt = lease.AcquiredAt
+		}
+		lm.leases[resource] = &Lease{
+			Resource:   resource,
+			DecisionID: decisionID,
+			Priority:   priority,
+			AcquiredAt: acquiredAt,
+			ExpiresAt:  expiresAt,
+		}
+	}
+
+	return nil, nil
+}
+
+// Release releases every lease held by a decision
+func (lm *lockManager) Release(decisionID string) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	for resource, lease := range lm.leases {
+		if lease.DecisionID == decisionID {
+			delete(lm.leases, resource)
+		}
+	}
+}
+
+// Leases returns the active leases ordered by resource
+func (lm *lockManager) Leases() []*Lease {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	now := time.Now()
+	leases := make([]*Lease, 0, len(lm.leases))
+	for _, lease := range lm.leases {
+		if lease.ExpiresAt != nil && !now.Before(*lease.ExpiresAt) {
+			continue
+		}
+		leaseCopy := *lease
+		leases = append(leases, &leaseCopy)
+	}
+	sort.Slice(leases, func(i, j int) bool {
+		return leases[i].Resource < leases[j].Resource
+	})
+
+	return leases
+}
+
+// lockResources returns the resources a decision must lock: the workloads
+// it acts on and, for consolidations, the nodes workloads move between
+func lockResources(decision *types.Decision) []string {
+	seen := make(map[string]bool)
+	var resources []string
+	add := func(kind, name string) {
+		resource := kind + "/" + name
+		if name == "" || seen[resource] {
+			return
+		}
+		seen[resource] = true
+		resources = append(resources, resource)
+	}
+
+	if decision.Type == types.DecisionTypeConsolidate {
+		for _, move := range consolidationMoves(decision) {
+			add(lockWorkload, move.workloadID)
+			add(lockNode, move.sourceNode)
+			add(lockNode, move.targetNode)
+		}
+	} else {
+		add(lockWorkload, decision.WorkloadID)
+	}
+
+	sort.Strings(resources)
+	return resources
+}
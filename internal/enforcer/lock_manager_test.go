@@ -0,0 +1,91 @@
+package enforcer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+func TestLockManager_AcquireIsAllOrNothing(t *testing.T) {
+	lm := NewLockManager(config.LockConfig{}, testLogger{})
+
+	conflicts, err := lm.Acquire("d-1", types.PriorityNormal, []string{"workload/wl-1"})
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+
+	conflicts, err = lm.Acquire("d-2", types.PriorityHigh, []string{"node/node-1", "workload/wl-1"})
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "workload/wl-1", conflicts[0].Resource)
+	assert.Equal(t, "d-1", conflicts[0].DecisionID)
+	assert.Equal(t, types.PriorityNormal, conflicts[0].Priority)
+
+	// The free node was not leased to the decision that conflicted
+	leases := lm.Leases()
+	require.Len(t, leases, 1)
+	assert.Equal(t, "d-1", leases[0].DecisionID)
+
+	lm.Release("d-1")
+	conflicts, err = lm.Acquire("d-2", types.PriorityHigh, []string{"node/node-1", "workload/wl-1"})
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	leases = lm.Leases()
+	require.Len(t, leases, 2)
+	assert.Equal(t, "node/node-1", leases[0].Resource)
+	assert.Equal(t, "workload/wl-1", leases[1].Resource)
+
+	_, err = lm.Acquire("", types.PriorityNormal, []string{"workload/wl-2"})
+	assert.Error(t, err)
+}
+
+func TestLockManager_RenewKeepsAcquiredAt(t *testing.T) {
+	lm := NewLockManager(config.LockConfig{LeaseDuration: time.Hour}, testLogger{})
+
+	_, err := lm.Acquire("d-1", types.PriorityNormal, []string{"workload/wl-1"})
+	require.NoError(t, err)
+	first := lm.Leases()[0]
+	require.NotNil(t, first.ExpiresAt)
+
+	time.Sleep(5 * time.Millisecond)
+	conflicts, err := lm.Acquire("d-1", types.PriorityNormal, []string{"workload/wl-1", "workload/wl-2"})
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+
+	renewed := lm.Leases()[0]
+	assert.Equal(t, first.AcquiredAt, renewed.AcquiredAt)
+	assert.True(t, renewed.ExpiresAt.After(*first.ExpiresAt))
+}
+
+func TestLockManager_ExpiredLeaseIsTakenOver(t *testing.T) {
+	lm := NewLockManager(config.LockConfig{LeaseDuration: 10 * time.Millisecond}, testLogger{})
+
+	_, err := lm.Acquire("d-1", types.PriorityNormal, []string{"workload/wl-1"})
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, lm.Leases())
+
+	conflicts, err := lm.Acquire("d-2", types.PriorityNormal, []string{"workload/wl-1"})
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	require.Len(t, lm.Leases(), 1)
+	assert.Equal(t, "d-2", lm.Leases()[0].DecisionID)
+}
+
+func TestLockResources(t *testing.T) {
+	assert.Equal(t, []string{"workload/wl-1"}, lockResources(migrateDecision("d-1", "wl-1")))
+
+	consolidate := &types.Decision{
+		ID:   "d-2",
+		Type: types.DecisionTypeConsolidate,
+		Details: map[string]interface{}{"moves": []map[string]interface{}{
+			{"workloadId": "wl-2", "sourceNode": "node-3", "targetNode": "node-1"},
+			{"workloadId": "wl-1", "sourceNode": "node-3", "targetNode": "node-1"},
+		}},
+	}
+	assert.Equal(t, []string{"node/node-1", "node/node-3", "workload/wl-1", "workload/wl-2"}, lockResources(consolidate))
+}
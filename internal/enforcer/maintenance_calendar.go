@@ -0,0 +1,215 @@
+package enforcer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// maxDeferrals bounds how many windows and freezes are stepped through to
+// find a time a decision may be enforced
+const maxDeferrals = 64
+
+// maintenanceCalendar implements MaintenanceCalendar interface from the
+// stored maintenance windows
+type maintenanceCalendar struct {
+	storage storage.StorageManager
+	logger  types.Logger
+}
+
+// NewMaintenanceCalendar creates a new maintenance calendar
+func NewMaintenanceCalendar(storage storage.StorageManager, logger types.Logger) MaintenanceCalendar {
+	return &maintenanceCalendar{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// Check reports when a decision may be enforced. Decisions covered by no
+// window or freeze are allowed at once; ones covered by windows wait for
+// one to open, and active freezes defer or block them. A decision spanning
+// several workloads waits for the latest of them.
+func (mc *maintenanceCalendar) Check(ctx context.Context, decision *types.Decision) (*MaintenanceVerdict, error) {
+	enabled := true
+	periods, err := mc.storage.Maintenance().List(ctx, &storage.MaintenanceFilters{Enabled: &enabled})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list maintenance windows: %w", err)
+	}
+
+	var applicable []*types.MaintenanceWindow
+	for _, period := range periods {
+		if period.AppliesTo(decision.Type) {
+			applicable = append(applicable, period)
+		}
+	}
+	if len(applicable) == 0 {
+		return &MaintenanceVerdict{Action: MaintenanceActionAllow, Reason: "no maintenance window or freeze applies"}, nil
+	}
+
+	now := time.Now()
+	verdict := &MaintenanceVerdict{Action: MaintenanceActionAllow, Reason: "no maintenance window or freeze applies"}
+	for _, workload := range mc.workloads(ctx, decision) {
+		workloadVerdict, err := mc.evaluate(applicable, decision, workload, now)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case workloadVerdict.Action == MaintenanceActionBlock:
+			return workloadVerdict, nil
+		case workloadVerdict.Action == MaintenanceActionDefer:
+			if verdict.Action != MaintenanceActionDefer || workloadVerdict.ScheduledFor.After(*verdict.ScheduledFor) {
+				verdict = workloadVerdict
+			}
+		case verdict.Action == MaintenanceActionAllow:
+			verdict = workloadVerdict
+		}
+	}
+
+	return verdict, nil
+}
+
+// evaluate finds the first time from now at which a workload is inside one
+// of its windows, if any apply, and outside every freeze
+func (mc *maintenanceCalendar) evaluate(periods []*types.MaintenanceWindow, decision *types.Decision, workload *types.Workload, now time.Time) (*MaintenanceVerdict, error) {
+	var windows, freezes []*types.MaintenanceWindow
+	for _, period := range periods {
+		if !scopeMatches(period.Scope, decision, workload) {
+			continue
+		}
+		if period.IsFreeze() {
+			freezes = append(freezes, period)
+		} else {
+			windows = append(windows, period)
+		}
+	}
+	if len(windows) == 0 && len(freezes) == 0 {
+		return &MaintenanceVerdict{Action: MaintenanceActionAllow, Reason: "no maintenance window or freeze applies"}, nil
+	}
+
+	at := now
+	verdict := &MaintenanceVerdict{Action: MaintenanceActionAllow}
+	for i := 0; i < maxDeferrals; i++ {
+		// Step past every freeze in effect
+		frozen := false
+		for _, freeze := range freezes {
+			end, open, err := freeze.OpenAt(at)
+			if err != nil {
+				return nil, fmt.Errorf("maintenance window %s: %w", freeze.Name, err)
+			}
+			if !open {
+				continue
+			}
+			if freeze.Blocks() && at.Equal(now) {
+				return &MaintenanceVerdict{
+					Action: MaintenanceActionBlock,
+					Window: freeze.Name,
+					Reason: fmt.Sprintf("change freeze %s is in effect until %s", freeze.Name, end.Format(time.RFC3339)),
+				}, nil
+			}
+			frozen = true
+			at = end
+			verdict.Window = freeze.Name
+			verdict.Reason = fmt.Sprintf("change freeze %s is in effect until %s", freeze.Name, end.Format(time.RFC3339))
+		}
+		if frozen {
+			continue
+		}
+
+		if len(windows) == 0 {
+			return mc.settle(verdict, at, now, "no maintenance window applies"), nil
+		}
+
+		// Use an open window, or wait for the next one to open
+		var next time.Time
+		var nextWindow *types.MaintenanceWindow
+		for _, window := range windows {
+			_, open, err := window.OpenAt(at)
+			if err != nil {
+				return nil, fmt.Errorf("maintenance window %s: %w", window.Name, err)
+			}
+			if open {
+				return mc.settle(verdict, at, now, fmt.Sprintf("maintenance window %s is open", window.Name)), nil
+			}
+			opening, ok, err := window.NextOpening(at)
+			if err != nil {
+				return nil, fmt.Errorf("maintenance window %s: %w", window.Name, err)
+			}
+			if ok && (next.IsZero() || opening.Before(next)) {
+				next, nextWindow = opening, window
+			}
+		}
+		if nextWindow == nil {
+			return &MaintenanceVerdict{Action: MaintenanceActionBlock, Reason: "no upcoming maintenance window"}, nil
+		}
+		at = next
+		verdict.Window = nextWindow.Name
+		verdict.Reason = fmt.Sprintf("waiting for maintenance window %s to open", nextWindow.Name)
+	}
+
+	return &MaintenanceVerdict{Action: MaintenanceActionBlock, Reason: "no maintenance window clear of change freezes found"}, nil
+}
+
+// settle allows a decision that may run now and defers one that must wait
+// until at
+func (mc *maintenanceCalendar) settle(verdict *MaintenanceVerdict, at, now time.Time, allowReason string) *MaintenanceVerdict {
+	if at.Equal(now) {
+		return &MaintenanceVerdict{Action: MaintenanceActionAllow, Reason: allowReason}
+	}
+	verdict.Action = MaintenanceActionDefer
+	verdict.ScheduledFor = &at
+	return verdict
+}
+
+// workloads returns the workloads a decision acts on, with a nil entry for
+// a workload that cannot be found so that cluster scopes still apply
+func (mc *maintenanceCalendar) workloads(ctx context.Context, decision *types.Decision) []*types.Workload {
+	ids := []string{decision.WorkloadID}
+	if decision.Type == types.DecisionTypeConsolidate {
+		ids = ids[:0]
+		for _, move := range consolidationMoves(decision) {
+			ids = append(ids, move.workloadID)
+		}
+	}
+
+	workloads := make([]*types.Workload, 0, len(ids))
+	for _, id := range ids {
+		workload, err := mc.storage.Workload().Get(ctx, id)
+		if err != nil {
+			mc.logger.Debug("maintenance scope check without workload", "decision_id", decision.ID, "workload_id", id)
+			workload = nil
+		}
+		workloads = append(workloads, workload)
+	}
+	if len(workloads) == 0 {
+		workloads = append(workloads, nil)
+	}
+	return workloads
+}
+
+// scopeMatches returns true if every matcher of the scope accepts the
+// workload, using the decision's cluster when the workload has none
+func scopeMatches(scope types.MaintenanceScope, decision *types.Decision, workload *types.Workload) bool {
+	if len(scope.Namespaces) > 0 && (workload == nil || !containsValue(scope.Namespaces, workload.Metadata.Namespace)) {
+		return false
+	}
+	if len(scope.Clusters) > 0 {
+		cluster := decision.ClusterID
+		if workload != nil {
+			if label := workload.Labels[placementKey(workload.Labels, labelCluster, labelClusterAlt)]; label != "" {
+				cluster = label
+			}
+		}
+		if !containsValue(scope.Clusters, cluster) {
+			return false
+		}
+	}
+	for key, value := range scope.Labels {
+		if workload == nil || workload.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
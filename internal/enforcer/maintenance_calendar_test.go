@@ -0,0 +1,218 @@
+package enforcer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// freezePeriod is a one-off change freeze from start to end
+func freezePeriod(name string, start, end time.Time, action types.FreezeAction) *types.MaintenanceWindow {
+	return &types.MaintenanceWindow{ID: name, Name: name, Kind: types.MaintenanceWindowKindFreeze, Enabled: true, Start: &start, End: &end, FreezeAction: action}
+}
+
+// windowPeriod is a one-off maintenance window from start to end
+func windowPeriod(name string, start, end time.Time) *types.MaintenanceWindow {
+	return &types.MaintenanceWindow{ID: name, Name: name, Kind: types.MaintenanceWindowKindWindow, Enabled: true, Start: &start, End: &end}
+}
+
+// dailyWindow is a maintenance window opening every day at hour UTC for
+// half an hour
+func dailyWindow(name string, hour int) *types.MaintenanceWindow {
+	return &types.MaintenanceWindow{ID: name, Name: name, Kind: types.MaintenanceWindowKindWindow, Enabled: true, Cron: fmt.Sprintf("0 %d * * *", hour), Duration: "30m"}
+}
+
+func newTestCalendar(t *testing.T, periods ...*types.MaintenanceWindow) (MaintenanceCalendar, storage.StorageManager) {
+	t.Helper()
+	store := memory.NewStorageManager()
+	for id, namespace := range map[string]string{"wl-1": "payments", "wl-2": "checkout"} {
+		workload := newTestWorkload(t, store, id)
+		workload.Metadata.Namespace = namespace
+		require.NoError(t, store.Workload().Update(context.Background(), workload))
+	}
+	for _, period := range periods {
+		require.NoError(t, store.Maintenance().Create(context.Background(), period))
+	}
+	return NewMaintenanceCalendar(store, testLogger{}), store
+}
+
+func TestMaintenanceCalendar_Check(t *testing.T) {
+	now := time.Now().UTC()
+	// The daily window opens at the top of the hour after next
+	opening := now.Truncate(time.Hour).Add(2 * time.Hour)
+	openingHour := opening.Hour()
+
+	disabled := freezePeriod("disabled", now.Add(-time.Hour), now.Add(time.Hour), types.FreezeActionBlock)
+	disabled.Enabled = false
+	otherNamespace := freezePeriod("checkout-freeze", now.Add(-time.Hour), now.Add(time.Hour), "")
+	otherNamespace.Scope.Namespaces = []string{"checkout"}
+	clusterFreeze := freezePeriod("cluster-freeze", now.Add(-time.Hour), now.Add(time.Hour), "")
+	clusterFreeze.Scope.Clusters = []string{"cluster-a"}
+	scaleFreeze := freezePeriod("scale-freeze", now.Add(-time.Hour), now.Add(time.Hour), "")
+	scaleFreeze.DecisionTypes = []types.DecisionType{types.DecisionTypeScale}
+
+	tests := []struct {
+		name         string
+		periods      []*types.MaintenanceWindow
+		decision     *types.Decision
+		action       MaintenanceAction
+		window       string
+		scheduledFor time.Time
+	}{
+		{
+			name:     "nothing applies",
+			decision: migrateDecision("d-1", "wl-1"),
+			action:   MaintenanceActionAllow,
+		},
+		{
+			name:     "disabled freeze",
+			periods:  []*types.MaintenanceWindow{disabled},
+			decision: migrateDecision("d-1", "wl-1"),
+			action:   MaintenanceActionAllow,
+		},
+		{
+			name:     "blocking freeze",
+			periods:  []*types.MaintenanceWindow{freezePeriod("release", now.Add(-time.Hour), now.Add(time.Hour), types.FreezeActionBlock)},
+			decision: migrateDecision("d-1", "wl-1"),
+			action:   MaintenanceActionBlock,
+			window:   "release",
+		},
+		{
+			name:         "freeze defers by default",
+			periods:      []*types.MaintenanceWindow{freezePeriod("release", now.Add(-time.Hour), now.Add(time.Hour), "")},
+			decision:     migrateDecision("d-1", "wl-1"),
+			action:       MaintenanceActionDefer,
+			window:       "release",
+			scheduledFor: now.Add(time.Hour),
+		},
+		{
+			name:     "freeze scoped to another namespace",
+			periods:  []*types.MaintenanceWindow{otherNamespace},
+			decision: migrateDecision("d-1", "wl-1"),
+			action:   MaintenanceActionAllow,
+		},
+		{
+			name:         "freeze scoped to the workload's cluster",
+			periods:      []*types.MaintenanceWindow{clusterFreeze},
+			decision:     migrateDecision("d-1", "wl-1"),
+			action:       MaintenanceActionDefer,
+			window:       "cluster-freeze",
+			scheduledFor: now.Add(time.Hour),
+		},
+		{
+			name:     "decision is not disruptive",
+			periods:  []*types.MaintenanceWindow{freezePeriod("release", now.Add(-time.Hour), now.Add(time.Hour), types.FreezeActionBlock)},
+			decision: scaleDecision("d-1", "wl-1"),
+			action:   MaintenanceActionAllow,
+		},
+		{
+			name:         "freeze listing the decision type",
+			periods:      []*types.MaintenanceWindow{scaleFreeze},
+			decision:     scaleDecision("d-1", "wl-1"),
+			action:       MaintenanceActionDefer,
+			window:       "scale-freeze",
+			scheduledFor: now.Add(time.Hour),
+		},
+		{
+			name:     "window is open",
+			periods:  []*types.MaintenanceWindow{windowPeriod("weekend", now.Add(-time.Hour), now.Add(time.Hour))},
+			decision: migrateDecision("d-1", "wl-1"),
+			action:   MaintenanceActionAllow,
+		},
+		{
+			name:         "waits for the next cron window",
+			periods:      []*types.MaintenanceWindow{dailyWindow("nightly", openingHour)},
+			decision:     migrateDecision("d-1", "wl-1"),
+			action:       MaintenanceActionDefer,
+			window:       "nightly",
+			scheduledFor: opening,
+		},
+		{
+			name: "freeze over the next window moves to the one after",
+			periods: []*types.MaintenanceWindow{
+				dailyWindow("nightly", openingHour),
+				freezePeriod("release", now.Add(-time.Hour), opening.Add(time.Hour), ""),
+			},
+			decision:     migrateDecision("d-1", "wl-1"),
+			action:       MaintenanceActionDefer,
+			window:       "nightly",
+			scheduledFor: opening.Add(24 * time.Hour),
+		},
+		{
+			name: "freeze ending inside an open window",
+			periods: []*types.MaintenanceWindow{
+				windowPeriod("weekend", now.Add(-time.Hour), now.Add(2*time.Hour)),
+				freezePeriod("release", now.Add(-time.Hour), now.Add(time.Hour), ""),
+			},
+			decision:     migrateDecision("d-1", "wl-1"),
+			action:       MaintenanceActionDefer,
+			window:       "release",
+			scheduledFor: now.Add(time.Hour),
+		},
+		{
+			name:     "no upcoming window",
+			periods:  []*types.MaintenanceWindow{windowPeriod("last-week", now.Add(-7*24*time.Hour), now.Add(-6*24*time.Hour))},
+			decision: migrateDecision("d-1", "wl-1"),
+			action:   MaintenanceActionBlock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calendar, _ := newTestCalendar(t, tt.periods...)
+
+			verdict, err := calendar.Check(context.Background(), tt.decision)
+			require.NoError(t, err)
+			assert.Equal(t, tt.action, verdict.Action, verdict.Reason)
+			assert.Equal(t, tt.window, verdict.Window)
+			assert.NotEmpty(t, verdict.Reason)
+			if tt.scheduledFor.IsZero() {
+				assert.Nil(t, verdict.ScheduledFor)
+				return
+			}
+			require.NotNil(t, verdict.ScheduledFor)
+			assert.True(t, tt.scheduledFor.Equal(*verdict.ScheduledFor), "scheduled for %s, want %s", verdict.ScheduledFor, tt.scheduledFor)
+		})
+	}
+}
+
+func TestMaintenanceCalendar_ConsolidationWaitsForLatestWorkload(t *testing.T) {
+	now := time.Now()
+	payments := freezePeriod("payments-freeze", now.Add(-time.Hour), now.Add(time.Hour), "")
+	payments.Scope.Namespaces = []string{"payments"}
+	checkout := freezePeriod("checkout-freeze", now.Add(-time.Hour), now.Add(2*time.Hour), "")
+	checkout.Scope.Namespaces = []string{"checkout"}
+	calendar, store := newTestCalendar(t, payments, checkout)
+
+	consolidate := &types.Decision{
+		ID:        "d-1",
+		Type:      types.DecisionTypeConsolidate,
+		ClusterID: "cluster-a",
+		Details: map[string]interface{}{"moves": []map[string]interface{}{
+			{"workloadId": "wl-1", "sourceNode": "node-1", "targetNode": "node-2"},
+			{"workloadId": "wl-2", "sourceNode": "node-1", "targetNode": "node-2"},
+		}},
+	}
+	verdict, err := calendar.Check(context.Background(), consolidate)
+	require.NoError(t, err)
+	assert.Equal(t, MaintenanceActionDefer, verdict.Action)
+	assert.Equal(t, "checkout-freeze", verdict.Window)
+	require.NotNil(t, verdict.ScheduledFor)
+	assert.True(t, verdict.ScheduledFor.Equal(now.Add(2*time.Hour)))
+
+	// Any workload under a blocking freeze blocks the whole decision
+	payments.FreezeAction = types.FreezeActionBlock
+	require.NoError(t, store.Maintenance().Update(context.Background(), payments))
+	verdict, err = calendar.Check(context.Background(), consolidate)
+	require.NoError(t, err)
+	assert.Equal(t, MaintenanceActionBlock, verdict.Action)
+	assert.Equal(t, "payments-freeze", verdict.Window)
+}
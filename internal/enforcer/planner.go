@@ -0,0 +1,342 @@
+package enforcer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// snapshotFields are the workload snapshot fields compared by a plan, in
+// the order their changes are reported
+var snapshotFields = []string{"status", "cluster", "node", "replicas", "requirements"}
+
+// Plan works out what enforcing a decision would do. It generates the
+// decision's actions, validates each with its executor, resolves the
+// workload each targets and predicts the state it leaves, following the
+// earlier steps. Nothing is executed and nothing but the plan is recorded.
+func (pe *policyEnforcer) Plan(ctx context.Context, decision *types.Decision) (*ExecutionPlan, error) {
+	plan := &ExecutionPlan{
+		DecisionID:   decision.ID,
+		DecisionType: decision.Type,
+		Mode:         pe.config.Mode,
+		Executable:   true,
+		Steps:        []*PlanStep{},
+		GeneratedAt:  time.Now(),
+	}
+
+	if verdict, err := pe.calendar.Check(ctx, decision); err != nil {
+		pe.logger.WithError(err).Warn("failed to check maintenance windows for plan", "decision_id", decision.ID)
+	} else {
+		plan.Maintenance = verdict
+		switch verdict.Action {
+		case MaintenanceActionDefer:
+			plan.Risks = addRisk(plan.Risks, RiskFlagDeferred)
+		case MaintenanceActionBlock:
+			plan.Risks = addRisk(plan.Risks, RiskFlagBlocked)
+		}
+	}
+
+	// Consolidate decisions carry their workloads in their moves
+	var workload *types.Workload
+	if decision.Type != types.DecisionTypeConsolidate {
+		var err error
+		workload, err = pe.storage.Workload().Get(ctx, decision.WorkloadID)
+		if err != nil {
+			plan.Executable = false
+			plan.Error = fmt.Sprintf("failed to get workload: %v", err)
+			plan.Risks = addRisk(plan.Risks, RiskFlagTargetNotFound)
+			pe.recordPlan(decision, plan)
+			return plan, nil
+		}
+	}
+
+	steps, err := pe.generateSteps(ctx, decision, workload)
+	if err != nil {
+		plan.Executable = false
+		plan.Error = fmt.Sprintf("failed to generate actions: %v", err)
+		pe.recordPlan(decision, plan)
+		return plan, nil
+	}
+
+	// Workloads as the plan expects them to be after the steps so far
+	predicted := make(map[string]*types.Workload)
+	for i := range steps {
+		step := pe.planStep(ctx, i, steps[i], predicted)
+		if !step.Valid {
+			plan.Executable = false
+		}
+		for _, risk := range step.Risks {
+			plan.Risks = addRisk(plan.Risks, risk)
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+
+	pe.recordPlan(decision, plan)
+	return plan, nil
+}
+
+// GetPlan returns the recorded plan of a decision, planning the stored
+// decision if none was recorded
+func (pe *policyEnforcer) GetPlan(ctx context.Context, decisionID string) (*ExecutionPlan, error) {
+	pe.mu.RLock()
+	plan, exists := pe.plans[decisionID]
+	pe.mu.RUnlock()
+	if exists {
+		return plan, nil
+	}
+
+	decision, err := pe.storage.Decision().Get(ctx, decisionID)
+	if err != nil {
+		return nil, err
+	}
+	return pe.Plan(ctx, decision)
+}
+
+// dryRun enforces a decision in dry-run mode by planning it and recording
+// the plan in the decision history
+func (pe *policyEnforcer) dryRun(ctx context.Context, decision *types.Decision) error {
+	plan, err := pe.Plan(ctx, decision)
+	if err != nil {
+		return fmt.Errorf("failed to plan decision %s: %w", decision.ID, err)
+	}
+
+	now := time.Now()
+	if err := pe.storage.Decision().AddHistory(ctx, &types.DecisionHistory{
+		DecisionID: decision.ID,
+		WorkloadID: decision.WorkloadID,
+		Action:     "dry_run",
+		Status:     decision.Status,
+		StartTime:  now,
+		EndTime:    &now,
+		Result:     "planned",
+		Comment:    fmt.Sprintf("%d steps planned, executable: %t", len(plan.Steps), plan.Executable),
+	}); err != nil {
+		pe.logger.WithError(err).Warn("failed to record dry run history", "decision_id", decision.ID)
+	}
+
+	pe.logger.Info("planned policy enforcement in dry-run mode",
+		"decision_id", decision.ID,
+		"steps", len(plan.Steps),
+		"executable", plan.Executable,
+		"risks", plan.Risks)
+
+	return nil
+}
+
+// planStep validates the action of a step, resolves its target and predicts
+// its effect on the workload as left by the earlier steps
+func (pe *policyEnforcer) planStep(ctx context.Context, index int, planned types.EnforcementStep, predicted map[string]*types.Workload) *PlanStep {
+	action := planned.Action
+	step := &PlanStep{
+		Index:      index,
+		ID:         planned.ID,
+		Action:     action,
+		DependsOn:  planned.DependsOn,
+		Gate:       planned.Gate,
+		Valid:      true,
+		Reversible: true,
+	}
+
+	if err := pe.enforcementEngine.ValidateAction(action); err != nil {
+		step.Valid = false
+		step.Error = err.Error()
+		step.Risks = addRisk(step.Risks, RiskFlagInvalid)
+	}
+
+	// Notifications and other actions without a workload change nothing
+	workloadID, _ := action.Parameters["workload_id"].(string)
+	if workloadID == "" || action.Type == ActionTypeNotify {
+		return step
+	}
+
+	current, exists := predicted[workloadID]
+	if !exists {
+		workload, err := pe.storage.Workload().Get(ctx, workloadID)
+		if err == nil {
+			current = workload
+			predicted[workloadID] = workload
+		}
+	}
+	step.Target = planTarget(workloadID, current)
+	if current == nil {
+		step.Valid = false
+		step.Error = fmt.Sprintf("workload %s not found", workloadID)
+		step.Risks = addRisk(step.Risks, RiskFlagTargetNotFound)
+		return step
+	}
+
+	next, err := predictAction(current, action)
+	if err != nil {
+		step.Valid = false
+		if step.Error == "" {
+			step.Error = err.Error()
+		}
+		step.Risks = addRisk(step.Risks, RiskFlagInvalid)
+		return step
+	}
+	predicted[workloadID] = next
+
+	step.Before = workloadSnapshot(current)
+	step.After = workloadSnapshot(next)
+	step.Changes = snapshotChanges(step.Before, step.After)
+	// The result a scale executor reports is not known until it runs, so
+	// plan with the previous state the default executor would report
+	if len(step.Changes) > 0 && compensationFor(action, step.Before, previousState(step.Before, map[string]interface{}{})) == nil {
+		step.Reversible = false
+		step.Risks = addRisk(step.Risks, RiskFlagIrreversible)
+	}
+	for _, risk := range disruptionRisks(action, current, next) {
+		step.Risks = addRisk(step.Risks, risk)
+	}
+
+	return step
+}
+
+// recordPlan keeps the latest plan of a decision until it terminates
+func (pe *policyEnforcer) recordPlan(decision *types.Decision, plan *ExecutionPlan) {
+	if decision.IsTerminated() {
+		return
+	}
+
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	pe.plans[plan.DecisionID] = plan
+}
+
+// forgetPlan drops the plan of a terminated decision
+func (pe *policyEnforcer) forgetPlan(decision *types.Decision) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	delete(pe.plans, decision.ID)
+}
+
+// predictAction returns the workload as the action's executor would leave
+// it, mirroring the executors without touching storage
+func predictAction(workload *types.Workload, action *Action) (*types.Workload, error) {
+	next := *workload
+	next.Labels = make(map[string]string, len(workload.Labels))
+	for k, v := range workload.Labels {
+		next.Labels[k] = v
+	}
+	next.Annotations = make(map[string]string, len(workload.Annotations))
+	for k, v := range workload.Annotations {
+		next.Annotations[k] = v
+	}
+
+	switch action.Type {
+	case ActionTypeSchedule, ActionTypeResume:
+		next.Status = types.WorkloadStatusRunning
+	case ActionTypeSuspend:
+		next.Status = types.WorkloadStatusSuspended
+	case ActionTypeTerminate:
+		next.Status = types.WorkloadStatusCompleted
+	case ActionTypeMigrate:
+		targetCluster, _ := action.Parameters["target_cluster"].(string)
+		targetNode, _ := action.Parameters["target_node"].(string)
+		if targetCluster == "" && targetNode == "" {
+			return nil, fmt.Errorf("target_cluster or target_node parameter is required")
+		}
+		if targetCluster != "" {
+			setPlacement(&next, labelCluster, labelClusterAlt, targetCluster)
+		}
+		if targetNode != "" {
+			setPlacement(&next, labelNode, labelNodeAlt, targetNode)
+		}
+		next.Status = types.WorkloadStatusRunning
+	case ActionTypeReschedule:
+		recommendedCluster, _ := action.Parameters["recommended_cluster"].(string)
+		if recommendedCluster == "" {
+			return nil, fmt.Errorf("recommended_cluster parameter is required")
+		}
+		setPlacement(&next, labelCluster, labelClusterAlt, recommendedCluster)
+		setPlacement(&next, labelNode, labelNodeAlt, "")
+		next.Status = types.WorkloadStatusRunning
+	case ActionTypeScale:
+		if err := applyScale(&next, action.Parameters); err != nil {
+			return nil, fmt.Errorf("invalid scale parameters: %w", err)
+		}
+	}
+
+	return &next, nil
+}
+
+// disruptionRisks flags actions that take replicas out of service, and
+// those that leave none in service. Relocations move one replica at a time.
+func disruptionRisks(action *Action, before, after *types.Workload) []RiskFlag {
+	replicas := workloadReplicas(before)
+	remaining := replicas
+
+	switch action.Type {
+	case ActionTypeSuspend, ActionTypeTerminate:
+		remaining = 0
+	case ActionTypeMigrate, ActionTypeReschedule:
+		remaining = replicas - 1
+	case ActionTypeScale:
+		remaining = workloadReplicas(after)
+	}
+	if before.Status != types.WorkloadStatusRunning || remaining >= replicas {
+		return nil
+	}
+
+	risks := []RiskFlag{RiskFlagDisruptive}
+	if remaining <= 0 {
+		risks = append(risks, RiskFlagOutage)
+	}
+	return risks
+}
+
+// snapshotChanges lists the fields that differ between two workload snapshots
+func snapshotChanges(before, after map[string]interface{}) []StateChange {
+	var changes []StateChange
+	for _, field := range snapshotFields {
+		if !reflect.DeepEqual(before[field], after[field]) {
+			changes = append(changes, StateChange{Field: field, From: before[field], To: after[field]})
+		}
+	}
+	return changes
+}
+
+// planTarget describes the workload an action targets
+func planTarget(workloadID string, workload *types.Workload) *PlanTarget {
+	if workload == nil {
+		return &PlanTarget{WorkloadID: workloadID}
+	}
+	return &PlanTarget{
+		WorkloadID: workloadID,
+		Name:       workload.Name,
+		Namespace:  workload.Metadata.Namespace,
+		Cluster:    workload.Labels[placementKey(workload.Labels, labelCluster, labelClusterAlt)],
+		Node:       workload.Labels[placementKey(workload.Labels, labelNode, labelNodeAlt)],
+		Found:      true,
+	}
+}
+
+// addRisk adds a risk flag once
+func addRisk(risks []RiskFlag, risk RiskFlag) []RiskFlag {
+	for _, existing := range risks {
+		if existing == risk {
+			return risks
+		}
+	}
+	return append(risks, risk)
+}
+
+// enforcementMode returns the configured enforcement mode. Unknown modes
+// plan only, so a mistyped mode never executes anything.
+func enforcementMode(mode string, logger types.Logger) string {
+	switch mode {
+	case "", config.EnforcementModeEnforce:
+		return config.EnforcementModeEnforce
+	case config.EnforcementModeDryRun:
+		return config.EnforcementModeDryRun
+	default:
+		logger.Warn("unknown enforcement mode, planning decisions without executing them", "mode", mode)
+		return config.EnforcementModeDryRun
+	}
+}
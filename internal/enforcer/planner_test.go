@@ -0,0 +1,230 @@
+package enforcer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/decisions"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+func TestDryRun_ExecutesNothing(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{Mode: config.EnforcementModeDryRun})
+	newTestWorkload(t, te.store, "wl-1")
+	scaler := newStubExecutor(ActionTypeScale, nil)
+	require.NoError(t, te.registry.Register(scaler))
+
+	require.NoError(t, te.Enforce(ctx, te.approve(t, scaleDecision("d-1", "wl-1"))))
+
+	assert.Empty(t, scaler.executed())
+	assert.Equal(t, "2", storedWorkload(t, te.store, "wl-1").Annotations[AnnotationReplicas])
+	stored, err := te.store.Decision().Get(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, types.DecisionStatusApproved, stored.Status)
+	_, err = te.store.Enforcement().Get(ctx, "d-1")
+	assert.Error(t, err)
+
+	history, err := te.store.Decision().GetHistory(ctx, "d-1")
+	require.NoError(t, err)
+	var dryRun *types.DecisionHistory
+	for _, entry := range history {
+		if entry.Action == "dry_run" {
+			dryRun = entry
+		}
+	}
+	require.NotNil(t, dryRun, "no dry_run history entry")
+	assert.Equal(t, "planned", dryRun.Result)
+	assert.Equal(t, "1 steps planned, executable: true", dryRun.Comment)
+
+	plan, err := te.GetPlan(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, config.EnforcementModeDryRun, plan.Mode)
+	assert.True(t, plan.Executable)
+	require.Len(t, plan.Steps, 1)
+	assert.Equal(t, []StateChange{{Field: "replicas", From: 2, To: 4}}, plan.Steps[0].Changes)
+}
+
+func TestPlan_StepOrder(t *testing.T) {
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+	newTestWorkload(t, te.store, "wl-2")
+
+	plan, err := te.Plan(context.Background(), te.approve(t, consolidateDecision("d-1")))
+	require.NoError(t, err)
+	assert.True(t, plan.Executable)
+
+	// The moves run in parallel and the notification waits for both
+	require.Len(t, plan.Steps, 3)
+	for i, step := range plan.Steps {
+		assert.Equal(t, i, step.Index)
+	}
+	assert.Equal(t, "migrate-1", plan.Steps[0].ID)
+	assert.Equal(t, "wl-1", plan.Steps[0].Target.WorkloadID)
+	assert.Equal(t, "migrate-2", plan.Steps[1].ID)
+	assert.Equal(t, "wl-2", plan.Steps[1].Target.WorkloadID)
+	assert.Equal(t, "notify-3", plan.Steps[2].ID)
+	assert.Equal(t, []string{"migrate-1", "migrate-2"}, plan.Steps[2].DependsOn)
+	assert.Empty(t, plan.Steps[2].Changes)
+
+	assert.Equal(t, []StateChange{{Field: "node", From: "node-1", To: "node-2"}}, plan.Steps[0].Changes)
+	assert.Equal(t, []StateChange{{Field: "node", From: "node-1", To: "node-3"}}, plan.Steps[1].Changes)
+}
+
+func TestPlanStep_PredictsFromEarlierSteps(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+
+	chain := []*Action{
+		te.createAction(ActionTypeMigrate, "wl-1", map[string]interface{}{
+			"workload_id":    "wl-1",
+			"target_cluster": "cluster-b",
+			"target_node":    "node-2",
+		}, time.Minute),
+		te.createAction(ActionTypeScale, "wl-1", map[string]interface{}{
+			"workload_id":  "wl-1",
+			"scale_factor": 2.0,
+		}, time.Minute),
+		te.createAction(ActionTypeSuspend, "wl-1", map[string]interface{}{
+			"workload_id": "wl-1",
+		}, time.Minute),
+	}
+
+	predicted := make(map[string]*types.Workload)
+	steps := make([]*PlanStep, len(chain))
+	for i, action := range chain {
+		steps[i] = te.planStep(ctx, i, types.EnforcementStep{ID: action.Type, Action: action}, predicted)
+		require.True(t, steps[i].Valid, steps[i].Error)
+	}
+
+	// Each step starts from the state the step before it left
+	assert.Equal(t, []StateChange{
+		{Field: "cluster", From: "cluster-a", To: "cluster-b"},
+		{Field: "node", From: "node-1", To: "node-2"},
+	}, steps[0].Changes)
+	assert.Equal(t, steps[0].After, steps[1].Before)
+	assert.Equal(t, []StateChange{{Field: "replicas", From: 2, To: 4}}, steps[1].Changes)
+	assert.Equal(t, steps[1].After, steps[2].Before)
+	assert.Equal(t, []StateChange{{Field: "status", From: types.WorkloadStatusRunning, To: types.WorkloadStatusSuspended}}, steps[2].Changes)
+	assert.Equal(t, "cluster-b", steps[2].Target.Cluster)
+
+	// Nothing was stored
+	workload := storedWorkload(t, te.store, "wl-1")
+	assert.Equal(t, "cluster-a", workload.Labels["cluster"])
+	assert.Equal(t, "2", workload.Annotations[AnnotationReplicas])
+	assert.Equal(t, types.WorkloadStatusRunning, workload.Status)
+}
+
+func TestPlan_Risks(t *testing.T) {
+	tests := []struct {
+		name       string
+		decision   *types.Decision
+		replicas   string
+		executable bool
+		risks      []RiskFlag
+	}{
+		{
+			name:       "migration takes a replica out of service",
+			decision:   migrateDecision("d-1", "wl-1"),
+			executable: true,
+			risks:      []RiskFlag{RiskFlagDisruptive},
+		},
+		{
+			name:       "migrating the only replica is an outage",
+			decision:   migrateDecision("d-1", "wl-1"),
+			replicas:   "1",
+			executable: true,
+			risks:      []RiskFlag{RiskFlagDisruptive, RiskFlagOutage},
+		},
+		{
+			name:       "termination cannot be undone",
+			decision:   &types.Decision{ID: "d-1", Type: types.DecisionTypeTerminate, WorkloadID: "wl-1"},
+			executable: true,
+			risks:      []RiskFlag{RiskFlagIrreversible, RiskFlagDisruptive, RiskFlagOutage},
+		},
+		{
+			name:       "migration without a target is invalid",
+			decision:   &types.Decision{ID: "d-1", Type: types.DecisionTypeMigrate, WorkloadID: "wl-1"},
+			executable: false,
+			risks:      []RiskFlag{RiskFlagInvalid},
+		},
+		{
+			name:       "missing workload",
+			decision:   migrateDecision("d-1", "wl-2"),
+			executable: false,
+			risks:      []RiskFlag{RiskFlagTargetNotFound},
+		},
+		{
+			name: "consolidation move of a missing workload",
+			decision: &types.Decision{
+				ID:                 "d-1",
+				Type:               types.DecisionTypeConsolidate,
+				ClusterID:          "cluster-a",
+				RecommendedCluster: "cluster-a",
+				Details: map[string]interface{}{"moves": []map[string]interface{}{
+					{"workloadId": "wl-3", "sourceNode": "node-1", "targetNode": "node-2"},
+				}},
+			},
+			executable: false,
+			risks:      []RiskFlag{RiskFlagTargetNotFound},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := newTestEnforcer(t, config.EnforcementConfig{})
+			workload := newTestWorkload(t, te.store, "wl-1")
+			if tt.replicas != "" {
+				workload.Annotations[AnnotationReplicas] = tt.replicas
+				require.NoError(t, te.store.Workload().Update(context.Background(), workload))
+			}
+
+			plan, err := te.Plan(context.Background(), tt.decision)
+			require.NoError(t, err)
+			assert.Equal(t, tt.executable, plan.Executable, plan.Error)
+			assert.ElementsMatch(t, tt.risks, plan.Risks)
+		})
+	}
+}
+
+func TestPlan_RecordedUntilDecisionTerminates(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+
+	decision := te.approve(t, scaleDecision("d-1", "wl-1"))
+	planned, err := te.Plan(ctx, decision)
+	require.NoError(t, err)
+	recorded, err := te.GetPlan(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Same(t, planned, recorded)
+
+	require.NoError(t, te.lifecycle.Transition(ctx, decision, decisions.Change{Status: types.DecisionStatusCancelled}))
+	te.mu.RLock()
+	_, exists := te.plans["d-1"]
+	te.mu.RUnlock()
+	assert.False(t, exists)
+
+	// A terminated decision can still be planned, but its plan is not kept
+	_, err = te.GetPlan(ctx, "d-1")
+	require.NoError(t, err)
+	te.mu.RLock()
+	assert.Empty(t, te.plans)
+	te.mu.RUnlock()
+
+	// Enforced decisions drop their plans once they complete
+	enforced := te.approve(t, scaleDecision("d-2", "wl-1"))
+	_, err = te.Plan(ctx, enforced)
+	require.NoError(t, err)
+	require.NoError(t, te.Enforce(ctx, enforced))
+	te.waitForDecision(t, "d-2", types.DecisionStatusCompleted)
+	te.mu.RLock()
+	assert.Empty(t, te.plans)
+	te.mu.RUnlock()
+}
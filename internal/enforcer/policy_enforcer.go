@@ -2,41 +2,95 @@ package enforcer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/decisions"
+	"github.com/kcloud-opt/policy/internal/evaluator"
 	"github.com/kcloud-opt/policy/internal/storage"
 	"github.com/kcloud-opt/policy/internal/types"
 )
 
+// persistTimeout bounds a single save of enforcement state
+const persistTimeout = 5 * time.Second
+
 // policyEnforcer implements PolicyEnforcer interface
 type policyEnforcer struct {
 	enforcementEngine EnforcementEngine
 	storage           storage.StorageManager
+	lifecycle         decisions.Lifecycle
+	limiter           SafetyLimiter
+	calendar          MaintenanceCalendar
+	locks             LockManager
+	rules             evaluator.RuleEngine
+	config            config.EnforcementConfig
 	logger            types.Logger
 	enforcements      map[string]*EnforcementStatus
+	cancels           map[string]context.CancelFunc
+	timers            map[string]*time.Timer
+	plans             map[string]*ExecutionPlan
+	rollouts          map[string]*rolloutRun
+	queue             []*types.Decision
 	mu                sync.RWMutex
+	drainMu           sync.Mutex
 }
 
 // NewPolicyEnforcer creates a new policy enforcer
-func NewPolicyEnforcer(enforcementEngine EnforcementEngine, storage storage.StorageManager, logger types.Logger) PolicyEnforcer {
-	return &policyEnforcer{
+func NewPolicyEnforcer(enforcementEngine EnforcementEngine, storage storage.StorageManager, lifecycle decisions.Lifecycle, limiter SafetyLimiter, calendar MaintenanceCalendar, locks LockManager, rules evaluator.RuleEngine, cfg config.EnforcementConfig, logger types.Logger) PolicyEnforcer {
+	cfg.Mode = enforcementMode(cfg.Mode, logger)
+
+	pe := &policyEnforcer{
 		enforcementEngine: enforcementEngine,
 		storage:           storage,
+		lifecycle:         lifecycle,
+		limiter:           limiter,
+		calendar:          calendar,
+		locks:             locks,
+		rules:             rules,
+		config:            cfg,
 		logger:            logger,
 		enforcements:      make(map[string]*EnforcementStatus),
+		cancels:           make(map[string]context.CancelFunc),
+		timers:            make(map[string]*time.Timer),
+		plans:             make(map[string]*ExecutionPlan),
+		rollouts:          make(map[string]*rolloutRun),
 	}
+
+	// Plans are kept until their decision terminates
+	lifecycle.OnTerminated(pe.forgetPlan)
+
+	return pe
 }
 
-// Enforce enforces a policy decision
+// Enforce enforces a policy decision. Only decisions whose stored status is
+// approved are enforced, and only once the maintenance calendar, the
+// workload locks and the safety limits admit them: decisions outside a
+// maintenance window are deferred, ones under a change freeze are deferred
+// or blocked, ones whose workloads are locked by another decision wait for
+// it or supersede it, and ones over a limit are queued or rejected. In
+// dry-run mode decisions are only planned.
 func (pe *policyEnforcer) Enforce(ctx context.Context, decision *types.Decision) error {
+	stored, err := pe.storage.Decision().Get(ctx, decision.ID)
+	if err != nil {
+		return err
+	}
+	if !stored.CanBeExecuted() {
+		return types.NewDecisionError(decision.ID, string(decision.Type), decision.WorkloadID, decision.PolicyID, "enforce", types.ErrDecisionNotApproved)
+	}
+
+	if pe.config.Mode == config.EnforcementModeDryRun {
+		return pe.dryRun(ctx, stored)
+	}
+
 	pe.mu.Lock()
-	defer pe.mu.Unlock()
 
-	// Check if enforcement is already in progress
+	// Check if enforcement is already in progress, queued or deferred
 	if status, exists := pe.enforcements[decision.ID]; exists {
-		if status.Status == EnforcementStateRunning {
+		if !status.IsTerminated() {
+			pe.mu.Unlock()
 			return fmt.Errorf("enforcement already in progress for decision %s", decision.ID)
 		}
 	}
@@ -53,15 +107,78 @@ func (pe *policyEnforcer) Enforce(ctx context.Context, decision *types.Decision)
 	}
 
 	pe.enforcements[decision.ID] = status
+	pe.mu.Unlock()
+
+	return pe.admit(ctx, decision, status)
+}
+
+// begin starts an admitted enforcement
+func (pe *policyEnforcer) begin(ctx context.Context, decision *types.Decision, status *EnforcementStatus) {
+	// Persist the enforcement before the decision is marked executing, so
+	// recovery always finds state for an executing decision
+	pe.persist(status)
+	pe.setDecisionStatus(ctx, decision, types.DecisionStatusExecuting)
 
 	// Start enforcement in background
-	go pe.executeEnforcement(ctx, decision, status)
+	pe.start(ctx, decision.ID, func(ctx context.Context) {
+		pe.executeEnforcement(ctx, decision, status)
+	})
+}
 
-	return nil
+// start runs an enforcement in the background under its own context. The
+// context outlives the caller's, is cancelled by CancelEnforcement and is
+// bounded by the configured decision timeout.
+func (pe *policyEnforcer) start(ctx context.Context, decisionID string, run func(ctx context.Context)) {
+	base := context.WithoutCancel(ctx)
+
+	var enforcementCtx context.Context
+	var cancel context.CancelFunc
+	if pe.config.DecisionTimeout > 0 {
+		enforcementCtx, cancel = context.WithTimeout(base, pe.config.DecisionTimeout)
+	} else {
+		enforcementCtx, cancel = context.WithCancel(base)
+	}
+
+	pe.mu.Lock()
+	pe.cancels[decisionID] = cancel
+	pe.mu.Unlock()
+
+	go func() {
+		defer func() {
+			pe.mu.Lock()
+			delete(pe.cancels, decisionID)
+			status := pe.enforcements[decisionID]
+			pe.mu.Unlock()
+			cancel()
+
+			// The finished enforcement's locks and budget may admit queued
+			// ones
+			if status != nil {
+				pe.unlock(status)
+			} else {
+				pe.locks.Release(decisionID)
+			}
+			pe.limiter.Release(decisionID)
+			pe.drainQueue(base)
+		}()
+		run(enforcementCtx)
+	}()
 }
 
-// EnforceMany enforces multiple policy decisions
+// EnforceMany enforces multiple policy decisions. With progressive rollout
+// enabled, batches larger than the initial wave are rolled out in waves.
 func (pe *policyEnforcer) EnforceMany(ctx context.Context, decisions []*types.Decision) error {
+	if pe.config.Rollout.Enabled {
+		strategy, err := pe.rolloutStrategy(nil)
+		if err != nil {
+			return err
+		}
+		if len(decisions) > strategy.InitialWave {
+			_, err := pe.StartRollout(ctx, decisions, nil)
+			return err
+		}
+	}
+
 	var wg sync.WaitGroup
 	errChan := make(chan error, len(decisions))
 
@@ -91,42 +208,72 @@ func (pe *policyEnforcer) EnforceMany(ctx context.Context, decisions []*types.De
 	return nil
 }
 
-// GetEnforcementStatus gets the status of policy enforcement
+// GetEnforcementStatus gets the status of policy enforcement, falling back
+// to the stored status for enforcements that ran before a restart
 func (pe *policyEnforcer) GetEnforcementStatus(ctx context.Context, decisionID string) (*EnforcementStatus, error) {
+	status, err := pe.lookup(ctx, decisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return a copy to avoid modification
 	pe.mu.RLock()
 	defer pe.mu.RUnlock()
+	return status.Copy(), nil
+}
 
+// lookup returns the tracked status of an enforcement, loading it from
+// storage when it is not tracked yet
+func (pe *policyEnforcer) lookup(ctx context.Context, decisionID string) (*EnforcementStatus, error) {
+	pe.mu.RLock()
 	status, exists := pe.enforcements[decisionID]
-	if !exists {
-		return nil, fmt.Errorf("enforcement status not found for decision %s", decisionID)
+	pe.mu.RUnlock()
+	if exists {
+		return status, nil
 	}
 
-	// Return a copy to avoid modification
-	statusCopy := *status
-	return &statusCopy, nil
+	stored, err := pe.storage.Enforcement().Get(ctx, decisionID)
+	if err != nil {
+		return nil, fmt.Errorf("enforcement status not found for decision %s: %w", decisionID, err)
+	}
+
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	if status, exists := pe.enforcements[decisionID]; exists {
+		return status, nil
+	}
+	pe.enforcements[decisionID] = stored
+	return stored, nil
 }
 
 // CancelEnforcement cancels ongoing policy enforcement
 func (pe *policyEnforcer) CancelEnforcement(ctx context.Context, decisionID string) error {
 	pe.mu.Lock()
-	defer pe.mu.Unlock()
 
 	status, exists := pe.enforcements[decisionID]
 	if !exists {
+		pe.mu.Unlock()
 		return fmt.Errorf("enforcement status not found for decision %s", decisionID)
 	}
 
-	if status.Status != EnforcementStateRunning {
+	queued := status.Status == EnforcementStatePending &&
+		(status.Details["queued"] == true || status.Details["deferred"] == true)
+	pe.dequeue(decisionID)
+	if timer, exists := pe.timers[decisionID]; exists {
+		timer.Stop()
+		delete(pe.timers, decisionID)
+	}
+	if status.Status != EnforcementStateRunning && !queued {
+		pe.mu.Unlock()
 		return fmt.Errorf("cannot cancel enforcement in state %s", status.Status)
 	}
 
 	// Update status to cancelled
 	status.Status = EnforcementStateCancelled
 	status.Message = "Enforcement cancelled"
-	status.CompletedAt = &time.Time{}
 	now := time.Now()
 	status.CompletedAt = &now
-	if status.StartedAt.IsZero() {
+	if !status.StartedAt.IsZero() {
 		duration := time.Since(status.StartedAt)
 		status.Duration = &duration
 	}
@@ -139,7 +286,154 @@ func (pe *policyEnforcer) CancelEnforcement(ctx context.Context, decisionID stri
 	}
 	status.Events = append(status.Events, event)
 
-	pe.logger.Info("cancelled policy enforcement", "decision_id", decisionID)
+	// Stop the running action; the enforcement rolls back what it applied
+	if cancel, exists := pe.cancels[decisionID]; exists {
+		cancel()
+	}
+	pe.mu.Unlock()
+
+	pe.persist(status)
+
+	// A queued or deferred enforcement has nothing running to settle its
+	// decision
+	if queued {
+		if decision, err := pe.storage.Decision().Get(ctx, decisionID); err == nil {
+			pe.setDecisionStatus(ctx, decision, types.DecisionStatusCancelled)
+		}
+	}
+	pe.logger.Info("cancelled policy enforcement", "decision_id", decisionID, "queued", queued)
+
+	return nil
+}
+
+// Rollback undoes the actions of a completed enforcement by running their
+// compensations in reverse order
+func (pe *policyEnforcer) Rollback(ctx context.Context, decisionID string) (*EnforcementStatus, error) {
+	decision, err := pe.storage.Decision().Get(ctx, decisionID)
+	if err != nil {
+		return nil, err
+	}
+	if !decision.IsCompleted() {
+		return nil, types.NewDecisionError(decision.ID, string(decision.Type), decision.WorkloadID, decision.PolicyID, "rollback", types.ErrInvalidDecisionStatus)
+	}
+
+	status, err := pe.lookup(ctx, decisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	pe.mu.Lock()
+	if status.Status == EnforcementStateRunning {
+		pe.mu.Unlock()
+		return nil, fmt.Errorf("enforcement already in progress for decision %s", decisionID)
+	}
+	if len(status.Compensations) == 0 {
+		pe.mu.Unlock()
+		return nil, fmt.Errorf("decision %s has no reversible actions", decisionID)
+	}
+	previousState := status.Status
+	status.Status = EnforcementStateRunning
+	status.Message = "Rollback in progress"
+	pe.mu.Unlock()
+
+	if err := pe.compensate(ctx, decision, status, "requested"); err != nil {
+		pe.updateStatus(status, previousState, fmt.Sprintf("Rollback failed: %v", err))
+		return nil, err
+	}
+
+	if err := pe.lifecycle.Transition(ctx, decision, decisions.Change{
+		Status: types.DecisionStatusRolledBack,
+		Action: "rollback",
+	}); err != nil {
+		return nil, err
+	}
+
+	return pe.GetEnforcementStatus(ctx, decisionID)
+}
+
+// compensate runs the recorded compensations of an enforcement in reverse
+// order. Every compensation is attempted even when an earlier one fails.
+func (pe *policyEnforcer) compensate(ctx context.Context, decision *types.Decision, status *EnforcementStatus, reason string) error {
+	pe.mu.Lock()
+	compensations := status.Compensations
+	pe.mu.Unlock()
+
+	if len(compensations) == 0 {
+		return nil
+	}
+
+	pe.addEvent(status, EnforcementEvent{
+		Type:      "rollback_started",
+		Message:   fmt.Sprintf("Rolling back %d actions", len(compensations)),
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"reason": reason,
+		},
+	})
+
+	var failed []*Action
+	for i := len(compensations) - 1; i >= 0; i-- {
+		action := compensations[i]
+
+		result, err := pe.enforcementEngine.ExecuteAction(ctx, action)
+		if err == nil && !result.Success {
+			err = fmt.Errorf("%s", result.Error)
+		}
+		if err != nil {
+			failed = append([]*Action{action}, failed...)
+			pe.addEvent(status, EnforcementEvent{
+				Type:      "rollback_action_failed",
+				Message:   fmt.Sprintf("Rollback action failed: %v", err),
+				Timestamp: time.Now(),
+				Data: map[string]interface{}{
+					"action_type": action.Type,
+					"compensates": action.Metadata["compensates"],
+					"error":       err.Error(),
+				},
+			})
+			pe.logger.WithError(err).Error("rollback action failed",
+				"decision_id", decision.ID,
+				"action_type", action.Type)
+			continue
+		}
+
+		pe.addEvent(status, EnforcementEvent{
+			Type:      "rollback_action_completed",
+			Message:   fmt.Sprintf("Rollback action completed: %s", action.Type),
+			Timestamp: time.Now(),
+			Data: map[string]interface{}{
+				"action_type": action.Type,
+				"compensates": action.Metadata["compensates"],
+				"duration":    result.Duration,
+			},
+		})
+	}
+
+	// Only the compensations that failed remain to be retried
+	pe.mu.Lock()
+	status.Compensations = failed
+	if len(failed) == 0 && reason == "requested" {
+		status.Status = EnforcementStateRolledBack
+		status.Message = "Enforcement rolled back"
+	}
+	pe.mu.Unlock()
+	pe.persist(status)
+
+	if len(failed) > 0 {
+		pe.addEvent(status, EnforcementEvent{
+			Type:      "rollback_failed",
+			Message:   fmt.Sprintf("Rollback incomplete: %d of %d actions failed", len(failed), len(compensations)),
+			Timestamp: time.Now(),
+		})
+		return fmt.Errorf("rollback of decision %s incomplete: %d of %d actions failed", decision.ID, len(failed), len(compensations))
+	}
+
+	pe.addEvent(status, EnforcementEvent{
+		Type:      "rollback_completed",
+		Message:   "Rollback completed",
+		Timestamp: time.Now(),
+	})
+	pe.logger.Info("rolled back policy enforcement", "decision_id", decision.ID, "reason", reason, "actions", len(compensations))
 
 	return nil
 }
@@ -174,101 +468,235 @@ func (pe *policyEnforcer) executeEnforcement(ctx context.Context, decision *type
 	}
 	pe.addEvent(status, event)
 
-	defer func() {
-		pe.mu.Lock()
-		if status.Status == EnforcementStateRunning {
-			now := time.Now()
-			status.CompletedAt = &now
-			duration := time.Since(status.StartedAt)
-			status.Duration = &duration
-			status.Status = EnforcementStateCompleted
-			status.Message = "Enforcement completed successfully"
-			status.Progress = 100.0
-		}
-		pe.mu.Unlock()
+	defer pe.finish(status)
 
-		// Add completion event
-		completionEvent := EnforcementEvent{
-			Type:      "completed",
-			Message:   status.Message,
-			Timestamp: time.Now(),
+	// Get workload information; consolidate decisions span many workloads
+	// and carry their moves in the decision details instead
+	var workload *types.Workload
+	if decision.Type != types.DecisionTypeConsolidate {
+		var err error
+		workload, err = pe.storage.Workload().Get(ctx, decision.WorkloadID)
+		if err != nil {
+			pe.updateStatus(status, EnforcementStateFailed, fmt.Sprintf("Failed to get workload: %v", err))
+			pe.setDecisionStatus(ctx, decision, types.DecisionStatusFailed)
+			return
 		}
-		pe.addEvent(status, completionEvent)
-	}()
-
-	// Get workload information
-	workload, err := pe.storage.Workload().Get(ctx, decision.WorkloadID)
-	if err != nil {
-		pe.updateStatus(status, EnforcementStateFailed, fmt.Sprintf("Failed to get workload: %v", err))
-		return
 	}
 
-	// Generate actions based on decision type
-	actions, err := pe.generateActions(ctx, decision, workload)
+	// Plan the steps based on decision type
+	steps, err := pe.generateSteps(ctx, decision, workload)
 	if err != nil {
 		pe.updateStatus(status, EnforcementStateFailed, fmt.Sprintf("Failed to generate actions: %v", err))
+		pe.setDecisionStatus(ctx, decision, types.DecisionStatusFailed)
 		return
 	}
 
-	// Execute actions
-	totalActions := len(actions)
-	for i, action := range actions {
-		pe.mu.Lock()
-		status.Progress = float64(i) / float64(totalActions) * 100.0
-		pe.mu.Unlock()
+	// Record the plan before running it so a restart can pick it up
+	pe.mu.Lock()
+	status.Steps = steps
+	pe.mu.Unlock()
+	pe.persist(status)
 
-		// Add action start event
-		actionEvent := EnforcementEvent{
-			Type:      "action_started",
-			Message:   fmt.Sprintf("Executing action: %s", action.Type),
-			Timestamp: time.Now(),
-			Data: map[string]interface{}{
-				"action_type":   action.Type,
-				"action_target": action.Target,
-			},
+	pe.runSteps(ctx, decision, status)
+}
+
+// resumeEnforcement continues an enforcement recovered after a restart
+func (pe *policyEnforcer) resumeEnforcement(ctx context.Context, decision *types.Decision, status *EnforcementStatus) {
+	pe.mu.Lock()
+	status.Status = EnforcementStateRunning
+	status.Message = "Enforcement in progress"
+	pe.mu.Unlock()
+
+	pe.addEvent(status, EnforcementEvent{
+		Type:      "resumed",
+		Message:   "Enforcement resumed after restart",
+		Timestamp: time.Now(),
+	})
+
+	defer pe.finish(status)
+
+	pe.runSteps(ctx, decision, status)
+}
+
+// finish marks a still running enforcement as completed
+func (pe *policyEnforcer) finish(status *EnforcementStatus) {
+	pe.mu.Lock()
+	if status.Status == EnforcementStateRunning {
+		now := time.Now()
+		status.CompletedAt = &now
+		duration := time.Since(status.StartedAt)
+		status.Duration = &duration
+		status.Status = EnforcementStateCompleted
+		status.Message = "Enforcement completed successfully"
+		status.Progress = 100.0
+	}
+	pe.mu.Unlock()
+
+	// Add completion event
+	completionEvent := EnforcementEvent{
+		Type:      "completed",
+		Message:   status.Message,
+		Timestamp: time.Now(),
+	}
+	pe.addEvent(status, completionEvent)
+}
+
+// interrupt settles an enforcement stopped by cancellation or a timeout.
+// Actions of the running steps are kept for rollback if they took effect
+// before they stopped.
+func (pe *policyEnforcer) interrupt(ctx context.Context, decision *types.Decision, status *EnforcementStatus, cause error) {
+	pe.mu.RLock()
+	steps := make([]types.EnforcementStep, len(status.Steps))
+	copy(steps, status.Steps)
+	pe.mu.RUnlock()
+
+	state := EnforcementStateTimeout
+	if errors.Is(cause, context.Canceled) {
+		state = EnforcementStateCancelled
+	}
+
+	// The first step interrupted, or the first that was still to run
+	index := -1
+	for i, step := range steps {
+		if step.State != EnforcementStateRunning {
+			if index < 0 && step.State != EnforcementStateCompleted {
+				index = i
+			}
+			continue
+		}
+		if index < 0 || steps[index].State != EnforcementStateRunning {
+			index = i
 		}
-		pe.addEvent(status, actionEvent)
 
-		// Execute action
-		result, err := pe.enforcementEngine.ExecuteAction(ctx, action)
-		if err != nil {
-			pe.addEvent(status, EnforcementEvent{
-				Type:      "action_failed",
-				Message:   fmt.Sprintf("Action failed: %v", err),
-				Timestamp: time.Now(),
-				Data: map[string]interface{}{
-					"action_type": action.Type,
-					"error":       err.Error(),
-				},
+		if applied, _ := pe.actionApplied(ctx, step); applied {
+			pe.completeStep(status, i)
+		} else {
+			pe.updateStep(status, i, func(step *types.EnforcementStep) {
+				now := time.Now()
+				step.State = state
+				step.CompletedAt = &now
+				step.Error = cause.Error()
 			})
-			pe.updateStatus(status, EnforcementStateFailed, fmt.Sprintf("Action execution failed: %v", err))
-			return
 		}
+	}
 
-		// Add action completion event
-		actionCompleteEvent := EnforcementEvent{
-			Type:      "action_completed",
-			Message:   fmt.Sprintf("Action completed: %s", action.Type),
-			Timestamp: time.Now(),
-			Data: map[string]interface{}{
-				"action_type": action.Type,
-				"success":     result.Success,
-				"duration":    result.Duration,
-			},
-		}
-		pe.addEvent(status, actionCompleteEvent)
+	if state == EnforcementStateCancelled {
+		pe.updateStatus(status, EnforcementStateCancelled, "Enforcement cancelled")
+		pe.compensate(ctx, decision, status, "cancelled")
+		pe.setDecisionStatus(ctx, decision, types.DecisionStatusCancelled)
+		return
+	}
 
-		pe.logger.Info("executed action",
-			"decision_id", decision.ID,
-			"action_type", action.Type,
-			"success", result.Success,
-			"duration", result.Duration)
+	message := fmt.Sprintf("Enforcement timed out: %v", cause)
+	data := map[string]interface{}{}
+	pe.mu.Lock()
+	status.Error = cause.Error()
+	if status.Details == nil {
+		status.Details = make(map[string]interface{})
+	}
+	if index >= 0 {
+		action := steps[index].Action
+		message = fmt.Sprintf("Enforcement timed out during %s action: %v", action.Type, cause)
+		status.Details["timedOutAction"] = action.Type
+		status.Details["timedOutStep"] = index
+		data["action_type"] = action.Type
+		data["action_target"] = action.Target
+		data["step"] = index
+		data["step_id"] = steps[index].ID
 	}
+	pe.mu.Unlock()
 
-	// Update decision status
-	decision.SetStatus(types.DecisionStatusCompleted)
-	if err := pe.storage.Decision().Update(ctx, decision); err != nil {
-		pe.logger.WithError(err).Warn("failed to update decision status")
+	pe.addEvent(status, EnforcementEvent{
+		Type:      "timeout",
+		Message:   message,
+		Timestamp: time.Now(),
+		Data:      data,
+	})
+	pe.updateStatus(status, EnforcementStateTimeout, message)
+	pe.logger.Warn("policy enforcement timed out", "decision_id", decision.ID, "cause", cause.Error())
+
+	pe.compensate(ctx, decision, status, "timeout")
+	pe.setDecisionStatus(ctx, decision, types.DecisionStatusFailed)
+}
+
+// completeStep marks a step as completed, records how to undo it and
+// updates the progress of the enforcement
+func (pe *policyEnforcer) completeStep(status *EnforcementStatus, index int) {
+	pe.mu.Lock()
+	step := &status.Steps[index]
+	now := time.Now()
+	step.State = EnforcementStateCompleted
+	step.CompletedAt = &now
+	step.Error = ""
+	if compensation := compensationFor(step.Action, step.Before, step.Result); compensation != nil {
+		status.Compensations = append(status.Compensations, compensation)
+	}
+	status.Progress = stepProgress(status.Steps)
+	pe.mu.Unlock()
+
+	pe.persist(status)
+}
+
+// updateStep applies a change to a step and persists the enforcement
+func (pe *policyEnforcer) updateStep(status *EnforcementStatus, index int, update func(step *types.EnforcementStep)) {
+	pe.mu.Lock()
+	update(&status.Steps[index])
+	pe.mu.Unlock()
+
+	pe.persist(status)
+}
+
+// captureState snapshots the workload an action targets, if any
+func (pe *policyEnforcer) captureState(ctx context.Context, action *Action) map[string]interface{} {
+	workloadID, _ := action.Parameters["workload_id"].(string)
+	if workloadID == "" {
+		return nil
+	}
+	workload, err := pe.storage.Workload().Get(ctx, workloadID)
+	if err != nil {
+		return nil
+	}
+	return workloadSnapshot(workload)
+}
+
+// setDecisionStatus moves a decision to the status its enforcement reached
+func (pe *policyEnforcer) setDecisionStatus(ctx context.Context, decision *types.Decision, status types.DecisionStatus) {
+	if err := pe.lifecycle.Transition(ctx, decision, decisions.Change{Status: status, Action: "enforce"}); err != nil {
+		pe.logger.WithError(err).Warn("failed to update decision status", "decision_id", decision.ID, "status", status)
+	}
+}
+
+// completeGroupedDecisions marks the migrate decisions grouped by a
+// consolidate decision as completed. Moves still pending were never
+// reviewed on their own, so the consolidate decision supersedes them.
+func (pe *policyEnforcer) completeGroupedDecisions(ctx context.Context, decision *types.Decision) {
+	for _, move := range consolidationMoves(decision) {
+		if move.decisionID == "" {
+			continue
+		}
+		grouped, err := pe.storage.Decision().Get(ctx, move.decisionID)
+		if err != nil {
+			pe.logger.WithError(err).Warn("failed to get grouped decision", "decision_id", move.decisionID)
+			continue
+		}
+
+		path := []types.DecisionStatus{types.DecisionStatusCompleted}
+		switch {
+		case grouped.IsPending():
+			path = []types.DecisionStatus{types.DecisionStatusSuperseded}
+		case grouped.IsApproved():
+			path = []types.DecisionStatus{types.DecisionStatusExecuting, types.DecisionStatusCompleted}
+		}
+		for _, status := range path {
+			if err := pe.lifecycle.Transition(ctx, grouped, decisions.Change{
+				Status:  status,
+				Action:  "consolidate",
+				Comment: fmt.Sprintf("applied by consolidate decision %s", decision.ID),
+			}); err != nil {
+				pe.logger.WithError(err).Warn("failed to update grouped decision status", "decision_id", move.decisionID)
+				break
+			}
+		}
 	}
 }
 
@@ -293,10 +721,24 @@ func (pe *policyEnforcer) generateActions(ctx context.Context, decision *types.D
 		actions = pe.generateResumeActions(decision, workload)
 	case types.DecisionTypeOptimize:
 		actions = pe.generateOptimizeActions(decision, workload)
+	case types.DecisionTypeConsolidate:
+		var err error
+		if actions, err = pe.generateConsolidateActions(decision); err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("unsupported decision type: %s", decision.Type)
 	}
 
+	// Executors that hand actions off, such as webhooks, report the
+	// decision an action belongs to
+	for _, action := range actions {
+		if action.Metadata == nil {
+			action.Metadata = make(map[string]interface{})
+		}
+		action.Metadata["decision_id"] = decision.ID
+	}
+
 	return actions, nil
 }
 
@@ -402,6 +844,9 @@ func (pe *policyEnforcer) generateResumeActions(decision *types.Decision, worklo
 	return []*Action{pe.createAction(ActionTypeResume, decision.WorkloadID, parameters, 2*time.Minute)}
 }
 
+// generateOptimizeActions maps an optimize decision to an update action
+// applying its optimizations, followed by a notification. No executor is
+// registered for the optimize action type, so none is generated.
 func (pe *policyEnforcer) generateOptimizeActions(decision *types.Decision, workload *types.Workload) []*Action {
 	parameters := map[string]interface{}{
 		"workload_id":   workload.ID,
@@ -414,26 +859,104 @@ func (pe *policyEnforcer) generateOptimizeActions(decision *types.Decision, work
 	return actions
 }
 
+// consolidationMove is a single migration of a consolidate decision
+type consolidationMove struct {
+	workloadID string
+	sourceNode string
+	targetNode string
+	decisionID string
+}
+
+// consolidationMoves reads the moves of a consolidate decision, accepting
+// both in-memory and JSON-decoded detail shapes
+func consolidationMoves(decision *types.Decision) []consolidationMove {
+	var raw []map[string]interface{}
+	switch moves := decision.Details["moves"].(type) {
+	case []map[string]interface{}:
+		raw = moves
+	case []interface{}:
+		for _, item := range moves {
+			if move, ok := item.(map[string]interface{}); ok {
+				raw = append(raw, move)
+			}
+		}
+	}
+
+	moves := make([]consolidationMove, 0, len(raw))
+	for _, move := range raw {
+		workloadID, _ := move["workloadId"].(string)
+		sourceNode, _ := move["sourceNode"].(string)
+		targetNode, _ := move["targetNode"].(string)
+		decisionID, _ := move["decisionId"].(string)
+		moves = append(moves, consolidationMove{workloadID, sourceNode, targetNode, decisionID})
+	}
+	return moves
+}
+
+func (pe *policyEnforcer) generateConsolidateActions(decision *types.Decision) ([]*Action, error) {
+	moves := consolidationMoves(decision)
+	if len(moves) == 0 {
+		return nil, fmt.Errorf("consolidate decision %s has no moves", decision.ID)
+	}
+
+	actions := make([]*Action, 0, len(moves)+1)
+	for _, move := range moves {
+		if move.workloadID == "" || move.targetNode == "" {
+			return nil, fmt.Errorf("consolidate decision %s has an incomplete move", decision.ID)
+		}
+		parameters := map[string]interface{}{
+			"workload_id":        move.workloadID,
+			"source_cluster":     decision.ClusterID,
+			"target_cluster":     decision.RecommendedCluster,
+			"source_node":        move.sourceNode,
+			"target_node":        move.targetNode,
+			"migration_strategy": "live",
+			"decision_id":        move.decisionID,
+		}
+		actions = append(actions, pe.createAction(ActionTypeMigrate, move.workloadID, parameters, 15*time.Minute))
+	}
+
+	actions = append(actions, pe.createNotificationAction("optimizer", decision.Message, decision.WorkloadID, decision.ID))
+
+	return actions, nil
+}
+
 // updateStatus updates enforcement status
 func (pe *policyEnforcer) updateStatus(status *EnforcementStatus, state EnforcementState, message string) {
 	pe.mu.Lock()
-	defer pe.mu.Unlock()
-
 	status.Status = state
 	status.Message = message
-	if state == EnforcementStateCompleted || state == EnforcementStateFailed {
+	if state != EnforcementStatePending && state != EnforcementStateRunning {
 		now := time.Now()
 		status.CompletedAt = &now
 		duration := time.Since(status.StartedAt)
 		status.Duration = &duration
-		status.Progress = 100.0
 	}
+	pe.mu.Unlock()
+
+	pe.persist(status)
 }
 
 // addEvent adds an event to enforcement status
 func (pe *policyEnforcer) addEvent(status *EnforcementStatus, event EnforcementEvent) {
 	pe.mu.Lock()
-	defer pe.mu.Unlock()
-
 	status.Events = append(status.Events, event)
+	pe.mu.Unlock()
+
+	pe.persist(status)
+}
+
+// persist saves the enforcement status to storage. Enforcements outlive the
+// request that started them, so saving does not use the request context.
+func (pe *policyEnforcer) persist(status *EnforcementStatus) {
+	pe.mu.RLock()
+	snapshot := status.Copy()
+	pe.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), persistTimeout)
+	defer cancel()
+
+	if err := pe.storage.Enforcement().Save(ctx, snapshot); err != nil {
+		pe.logger.WithError(err).Warn("failed to persist enforcement status", "decision_id", snapshot.DecisionID)
+	}
 }
@@ -0,0 +1,292 @@
+package enforcer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/actions"
+	"github.com/kcloud-opt/policy/internal/automation"
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/decisions"
+	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// testEnforcer is a policy enforcer over memory storage with the default
+// executors registered
+type testEnforcer struct {
+	*policyEnforcer
+	store     storage.StorageManager
+	lifecycle decisions.Lifecycle
+	registry  actions.Registry
+}
+
+func newTestEnforcer(t *testing.T, cfg config.EnforcementConfig) *testEnforcer {
+	t.Helper()
+	store := memory.NewStorageManager()
+	lifecycle := decisions.NewLifecycle(store, config.DecisionConfig{}, testLogger{})
+
+	registry := actions.NewRegistry(testLogger{})
+	for _, executor := range NewDefaultExecutors(store, testLogger{}) {
+		require.NoError(t, registry.Register(executor))
+	}
+
+	pe := NewPolicyEnforcer(
+		NewEnforcementEngine(registry, testLogger{}),
+		store,
+		lifecycle,
+		NewSafetyLimiter(store, cfg.Safety, testLogger{}),
+		NewMaintenanceCalendar(store, testLogger{}),
+		NewLockManager(cfg.Locks, testLogger{}),
+		nil,
+		cfg,
+		testLogger{},
+	).(*policyEnforcer)
+
+	return &testEnforcer{policyEnforcer: pe, store: store, lifecycle: lifecycle, registry: registry}
+}
+
+// approve stores a decision and approves it
+func (te *testEnforcer) approve(t *testing.T, decision *types.Decision) *types.Decision {
+	t.Helper()
+	if decision.PolicyID == "" {
+		decision.PolicyID = "policy-1"
+	}
+	require.NoError(t, te.lifecycle.Create(context.Background(), decision))
+	require.NoError(t, te.lifecycle.Transition(context.Background(), decision, decisions.Change{Status: types.DecisionStatusApproved}))
+	return decision
+}
+
+// scaleDecision doubles the replicas of a workload
+func scaleDecision(id, workloadID string) *types.Decision {
+	return &types.Decision{
+		ID:         id,
+		Type:       types.DecisionTypeScale,
+		WorkloadID: workloadID,
+		Details:    map[string]interface{}{"scale_factor": 2.0},
+	}
+}
+
+// waitForDecision waits until the stored decision reaches a status
+func (te *testEnforcer) waitForDecision(t *testing.T, decisionID string, status types.DecisionStatus) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		decision, err := te.store.Decision().Get(context.Background(), decisionID)
+		return err == nil && decision.Status == status
+	}, 2*time.Second, 5*time.Millisecond, "decision %s never reached %s", decisionID, status)
+}
+
+// waitForState waits until an enforcement reaches a state
+func (te *testEnforcer) waitForState(t *testing.T, decisionID string, state EnforcementState) *EnforcementStatus {
+	t.Helper()
+	var status *EnforcementStatus
+	require.Eventually(t, func() bool {
+		var err error
+		status, err = te.GetEnforcementStatus(context.Background(), decisionID)
+		return err == nil && status.Status == state
+	}, 2*time.Second, 5*time.Millisecond, "enforcement %s never reached %s", decisionID, state)
+	return status
+}
+
+// stubExecutor handles one action type with a configurable outcome and
+// records the actions it ran
+type stubExecutor struct {
+	baseExecutor
+	execute func(ctx context.Context, action *Action) (*ActionResult, error)
+
+	mu    sync.Mutex
+	calls []*Action
+}
+
+func newStubExecutor(actionType string, execute func(ctx context.Context, action *Action) (*ActionResult, error)) *stubExecutor {
+	return &stubExecutor{
+		baseExecutor: baseExecutor{actionTypes: []string{actionType}, logger: testLogger{}},
+		execute:      execute,
+	}
+}
+
+func (se *stubExecutor) Execute(ctx context.Context, action *Action) (*ActionResult, error) {
+	se.mu.Lock()
+	se.calls = append(se.calls, action)
+	se.mu.Unlock()
+
+	if se.execute == nil {
+		return succeeded(action, nil), nil
+	}
+	return se.execute(ctx, action)
+}
+
+// executed returns the actions the executor ran
+func (se *stubExecutor) executed() []*Action {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	return append([]*Action(nil), se.calls...)
+}
+
+// succeeded is a successful result reporting data
+func succeeded(action *Action, data map[string]interface{}) *ActionResult {
+	return &ActionResult{ActionType: action.Type, Success: true, Message: "done", Data: data, Timestamp: time.Now()}
+}
+
+func TestPolicyEnforcer_SharesExecutorsWithAutomation(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+
+	// One executor registered once serves both enforcement and automation
+	scaler := newStubExecutor(ActionTypeScale, nil)
+	require.NoError(t, te.registry.Register(scaler))
+	rules := automation.NewRuleExecutor(
+		automation.NewConditionEvaluator(te.store.ConditionState(), evaluator.NewRuleEngine(testLogger{}), testLogger{}),
+		te.registry,
+		testLogger{},
+	)
+
+	decision := te.approve(t, scaleDecision("d-1", "wl-1"))
+	require.NoError(t, te.Enforce(ctx, decision))
+	te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+
+	rule := &automation.AutomationRule{
+		ID:         "scale-out",
+		Name:       "scale-out",
+		Enabled:    true,
+		Conditions: []*automation.Condition{{Field: "workload.status", Operator: automation.OperatorEquals, Value: "running"}},
+		Actions: []*automation.Action{{
+			Type:       ActionTypeScale,
+			Target:     "wl-1",
+			Parameters: map[string]interface{}{"workload_id": "wl-1", "replicas": 6},
+		}},
+	}
+	require.NoError(t, rules.ValidateRule(ctx, rule))
+	result, err := rules.ExecuteRule(ctx, rule, map[string]interface{}{"workload": map[string]interface{}{"status": "running"}})
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+
+	calls := scaler.executed()
+	require.Len(t, calls, 2)
+	assert.Equal(t, "d-1", calls[0].Metadata["decision_id"])
+	assert.Equal(t, 6, calls[1].Parameters["replicas"])
+}
+
+// observedExecutor blocks actions of a type until their context ends and
+// reports that they stopped
+func observedExecutor(actionType string) (*stubExecutor, chan struct{}, chan error) {
+	started := make(chan struct{}, 1)
+	stopped := make(chan error, 1)
+	executor := newStubExecutor(actionType, func(ctx context.Context, action *Action) (*ActionResult, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return nil, ctx.Err()
+	})
+	return executor, started, stopped
+}
+
+// awaitSignal waits for a value on a channel
+func awaitSignal[T any](t *testing.T, ch <-chan T, what string) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "timed out waiting", what)
+	}
+	var zero T
+	return zero
+}
+
+func TestCancelEnforcement_StopsRunningAction(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+	migrator, started, stopped := observedExecutor(ActionTypeMigrate)
+	require.NoError(t, te.registry.Register(migrator))
+
+	require.NoError(t, te.Enforce(ctx, te.approve(t, migrateDecision("d-1", "wl-1"))))
+	awaitSignal(t, started, "migration to start")
+
+	require.NoError(t, te.CancelEnforcement(ctx, "d-1"))
+	assert.ErrorIs(t, awaitSignal(t, stopped, "migration to stop"), context.Canceled)
+	te.waitForDecision(t, "d-1", types.DecisionStatusCancelled)
+
+	status, err := te.GetEnforcementStatus(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, EnforcementStateCancelled, status.Status)
+	require.Len(t, status.Steps, 1)
+	assert.Equal(t, EnforcementStateCancelled, status.Steps[0].State)
+	assert.Empty(t, status.Compensations)
+	assert.Equal(t, "cluster-a", storedWorkload(t, te.store, "wl-1").Labels["cluster"])
+
+	// A settled enforcement cannot be cancelled again
+	assert.Error(t, te.CancelEnforcement(ctx, "d-1"))
+}
+
+func TestPolicyEnforcer_Timeouts(t *testing.T) {
+	// timedOut checks an enforcement timed out during its migration
+	timedOut := func(t *testing.T, te *testEnforcer) {
+		t.Helper()
+		te.waitForDecision(t, "d-1", types.DecisionStatusFailed)
+
+		status, err := te.GetEnforcementStatus(context.Background(), "d-1")
+		require.NoError(t, err)
+		assert.Equal(t, EnforcementStateTimeout, status.Status)
+		assert.Contains(t, status.Message, "timed out during migrate action")
+		assert.Equal(t, ActionTypeMigrate, status.Details["timedOutAction"])
+		assert.Equal(t, 0, status.Details["timedOutStep"])
+		require.Len(t, status.Steps, 1)
+		assert.Equal(t, EnforcementStateTimeout, status.Steps[0].State)
+		assert.Contains(t, status.Steps[0].Error, context.DeadlineExceeded.Error())
+
+		var timeout *EnforcementEvent
+		for i := range status.Events {
+			if status.Events[i].Type == "timeout" {
+				timeout = &status.Events[i]
+			}
+		}
+		require.NotNil(t, timeout)
+		assert.Equal(t, ActionTypeMigrate, timeout.Data["action_type"])
+		assert.Equal(t, "wl-1", timeout.Data["action_target"])
+		assert.Equal(t, "migrate-1", timeout.Data["step_id"])
+	}
+
+	t.Run("action timeout", func(t *testing.T) {
+		te := newTestEnforcer(t, config.EnforcementConfig{})
+		newTestWorkload(t, te.store, "wl-1")
+		migrator, started, stopped := observedExecutor(ActionTypeMigrate)
+		require.NoError(t, te.registry.Register(migrator))
+
+		// Generated actions allow minutes, so the planned migration is
+		// resumed with a short timeout of its own
+		decision := migrateDecision("d-1", "wl-1")
+		steps := te.interruptedSteps(t, decision)
+		steps[0].State = EnforcementStatePending
+		steps[0].StartedAt = nil
+		steps[0].Action.Timeout = 20 * time.Millisecond
+		te.interrupted(t, decision, &EnforcementStatus{Status: EnforcementStateRunning, StartedAt: time.Now(), Steps: steps})
+
+		_, err := te.Recover(context.Background())
+		require.NoError(t, err)
+		awaitSignal(t, started, "migration to start")
+		assert.ErrorIs(t, awaitSignal(t, stopped, "migration to stop"), context.DeadlineExceeded)
+		timedOut(t, te)
+	})
+
+	t.Run("decision timeout", func(t *testing.T) {
+		te := newTestEnforcer(t, config.EnforcementConfig{DecisionTimeout: 20 * time.Millisecond})
+		newTestWorkload(t, te.store, "wl-1")
+		migrator, started, stopped := observedExecutor(ActionTypeMigrate)
+		require.NoError(t, te.registry.Register(migrator))
+
+		require.NoError(t, te.Enforce(context.Background(), te.approve(t, migrateDecision("d-1", "wl-1"))))
+		awaitSignal(t, started, "migration to start")
+		assert.ErrorIs(t, awaitSignal(t, stopped, "migration to stop"), context.DeadlineExceeded)
+		timedOut(t, te)
+	})
+}
@@ -0,0 +1,285 @@
+package enforcer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// Recover settles decisions left executing by a previous run. Enforcements
+// whose plan was never recorded start over, interrupted ones resume after
+// checking whether their in-flight action took effect, and ones that had
+// already failed or been cancelled are rolled back. Enforcements waiting
+// for safety limit budget are queued again, and ones deferred to a
+// maintenance window are scheduled again. Recovering twice is
+// harmless: decisions settled by the first pass are no longer executing and
+// enforcements it resumed are skipped while they run.
+func (pe *policyEnforcer) Recover(ctx context.Context) ([]*RecoveryResult, error) {
+	decisions, err := pe.storage.Decision().GetByStatus(ctx, types.DecisionStatusExecuting)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list executing decisions: %w", err)
+	}
+
+	results := make([]*RecoveryResult, 0, len(decisions))
+	for _, decision := range decisions {
+		result := pe.recoverDecision(ctx, decision)
+		if result == nil {
+			continue
+		}
+		results = append(results, result)
+	}
+
+	requeued, err := pe.requeue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, requeued...)
+
+	for _, result := range results {
+		pe.logger.Info("recovered policy enforcement",
+			"decision_id", result.DecisionID,
+			"outcome", result.Outcome,
+			"reason", result.Reason)
+	}
+
+	if len(requeued) > 0 {
+		pe.drainQueue(context.WithoutCancel(ctx))
+	}
+
+	return results, nil
+}
+
+// requeue restores enforcements the safety limits had queued, oldest first,
+// and reschedules deferred ones, for decisions that are still approved
+func (pe *policyEnforcer) requeue(ctx context.Context) ([]*RecoveryResult, error) {
+	pending := EnforcementStatePending
+	statuses, err := pe.storage.Enforcement().List(ctx, &storage.EnforcementFilters{Status: &pending})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending enforcements: %w", err)
+	}
+
+	var results []*RecoveryResult
+	for i := len(statuses) - 1; i >= 0; i-- {
+		status := statuses[i]
+		queued := status.Details["queued"] == true
+		deferred := status.Details["deferred"] == true
+		if !queued && !deferred {
+			continue
+		}
+
+		pe.mu.RLock()
+		_, tracked := pe.enforcements[status.DecisionID]
+		pe.mu.RUnlock()
+		if tracked {
+			continue
+		}
+
+		decision, err := pe.storage.Decision().Get(ctx, status.DecisionID)
+		if err != nil || !decision.CanBeExecuted() {
+			pe.track(status)
+			pe.updateStatus(status, EnforcementStateCancelled, "Decision is no longer approved")
+			continue
+		}
+
+		pe.track(status)
+		if deferred {
+			// A schedule that passed during the restart fires at once
+			until := time.Now()
+			if decision.ScheduledFor != nil {
+				until = *decision.ScheduledFor
+			}
+			pe.scheduleDeferred(ctx, decision.ID, until)
+			results = append(results, &RecoveryResult{DecisionID: decision.ID, Outcome: RecoveryOutcomeDeferred, Reason: "enforcement was waiting for a maintenance window"})
+			continue
+		}
+		pe.mu.Lock()
+		pe.queue = append(pe.queue, decision)
+		pe.mu.Unlock()
+
+		results = append(results, &RecoveryResult{DecisionID: decision.ID, Outcome: RecoveryOutcomeRequeued, Reason: "enforcement was waiting for safety limit budget"})
+	}
+
+	return results, nil
+}
+
+// recoverDecision settles a single executing decision, returning nil when
+// its enforcement is already running in this process
+func (pe *policyEnforcer) recoverDecision(ctx context.Context, decision *types.Decision) *RecoveryResult {
+	pe.mu.RLock()
+	tracked, exists := pe.enforcements[decision.ID]
+	running := exists && !tracked.IsTerminated()
+	pe.mu.RUnlock()
+	if running {
+		return nil
+	}
+
+	status, err := pe.storage.Enforcement().Get(ctx, decision.ID)
+	if err != nil || len(status.Steps) == 0 {
+		// No action had started, so the enforcement starts over
+		if err != nil {
+			status = &EnforcementStatus{
+				DecisionID: decision.ID,
+				Status:     EnforcementStatePending,
+				Message:    "Enforcement pending",
+				StartedAt:  time.Now(),
+				Details:    make(map[string]interface{}),
+			}
+		}
+		pe.track(status)
+		pe.relock(ctx, decision, status)
+		pe.start(ctx, decision.ID, func(ctx context.Context) {
+			pe.executeEnforcement(ctx, decision, status)
+		})
+		return &RecoveryResult{DecisionID: decision.ID, Outcome: RecoveryOutcomeRestarted, Reason: "no action had started"}
+	}
+	pe.track(status)
+
+	switch status.Status {
+	case EnforcementStateCompleted:
+		pe.setDecisionStatus(ctx, decision, types.DecisionStatusCompleted)
+		if decision.Type == types.DecisionTypeConsolidate {
+			pe.completeGroupedDecisions(ctx, decision)
+		}
+		return &RecoveryResult{DecisionID: decision.ID, Outcome: RecoveryOutcomeCompleted, Reason: "all actions had completed"}
+	case EnforcementStateFailed, EnforcementStateTimeout, EnforcementStateCancelled, EnforcementStateRolledBack:
+		return pe.abandon(ctx, decision, status, fmt.Sprintf("enforcement had %s", status.Status))
+	}
+
+	// Settle the action that was in flight: keep it if it took effect,
+	// otherwise run it again
+	for i := range status.Steps {
+		step := status.Steps[i]
+		if step.State != EnforcementStateRunning {
+			continue
+		}
+
+		applied, err := pe.actionApplied(ctx, step)
+		if err != nil {
+			pe.updateStep(status, i, func(step *types.EnforcementStep) {
+				now := time.Now()
+				step.State = EnforcementStateFailed
+				step.CompletedAt = &now
+				step.Error = err.Error()
+			})
+			pe.updateStatus(status, EnforcementStateFailed, fmt.Sprintf("Recovery failed: %v", err))
+			return pe.abandon(ctx, decision, status, fmt.Sprintf("could not check %s action: %v", step.Action.Type, err))
+		}
+		if applied {
+			pe.completeStep(status, i)
+			continue
+		}
+		pe.updateStep(status, i, func(step *types.EnforcementStep) {
+			step.State = EnforcementStatePending
+			step.StartedAt = nil
+		})
+	}
+
+	pe.relock(ctx, decision, status)
+	pe.start(ctx, decision.ID, func(ctx context.Context) {
+		pe.resumeEnforcement(ctx, decision, status)
+	})
+	return &RecoveryResult{DecisionID: decision.ID, Outcome: RecoveryOutcomeResumed, Reason: "enforcement was interrupted"}
+}
+
+// abandon rolls back an interrupted enforcement and settles its decision
+func (pe *policyEnforcer) abandon(ctx context.Context, decision *types.Decision, status *EnforcementStatus, reason string) *RecoveryResult {
+	decisionStatus := types.DecisionStatusFailed
+	if status.Status == EnforcementStateCancelled {
+		decisionStatus = types.DecisionStatusCancelled
+	}
+
+	if err := pe.compensate(ctx, decision, status, "recovery"); err != nil {
+		pe.setDecisionStatus(ctx, decision, types.DecisionStatusFailed)
+		return &RecoveryResult{DecisionID: decision.ID, Outcome: RecoveryOutcomeFailed, Reason: fmt.Sprintf("%s; %v", reason, err)}
+	}
+
+	pe.setDecisionStatus(ctx, decision, decisionStatus)
+	return &RecoveryResult{DecisionID: decision.ID, Outcome: RecoveryOutcomeRolledBack, Reason: reason}
+}
+
+// relock takes the locks of an enforcement resumed after a restart. Resumed
+// enforcements were already running, so they go ahead even if the locks
+// are taken, which only happens when two decisions were recovered onto the
+// same workload.
+func (pe *policyEnforcer) relock(ctx context.Context, decision *types.Decision, status *EnforcementStatus) {
+	resources := lockResources(decision)
+	conflicts, err := pe.locks.Acquire(decision.ID, pe.decisionPriority(ctx, decision), resources)
+	if err != nil || len(conflicts) > 0 {
+		pe.logger.Warn("resuming policy enforcement without its locks",
+			"decision_id", decision.ID,
+			"conflicts", len(conflicts),
+			"error", err)
+		return
+	}
+
+	now := time.Now()
+	pe.mu.Lock()
+	status.Lock = &types.LockStatus{Resources: resources, Holder: decision.ID, Held: true, AcquiredAt: &now}
+	pe.mu.Unlock()
+}
+
+// track starts tracking a recovered enforcement status
+func (pe *policyEnforcer) track(status *EnforcementStatus) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	pe.enforcements[status.DecisionID] = status
+}
+
+// actionApplied checks whether an interrupted action took effect by
+// comparing the workload it targets with the action's intent. Actions whose
+// effect cannot be observed report false and are run again.
+func (pe *policyEnforcer) actionApplied(ctx context.Context, step types.EnforcementStep) (bool, error) {
+	action := step.Action
+	workloadID, _ := action.Parameters["workload_id"].(string)
+	if workloadID == "" {
+		return false, nil
+	}
+
+	workload, err := pe.storage.Workload().Get(ctx, workloadID)
+	if err != nil {
+		return false, err
+	}
+
+	switch action.Type {
+	case ActionTypeMigrate:
+		targetCluster, _ := action.Parameters["target_cluster"].(string)
+		targetNode, _ := action.Parameters["target_node"].(string)
+		return placedOn(workload, targetCluster, targetNode), nil
+	case ActionTypeReschedule:
+		recommendedCluster, _ := action.Parameters["recommended_cluster"].(string)
+		return placedOn(workload, recommendedCluster, ""), nil
+	case ActionTypeSuspend:
+		return workload.Status == types.WorkloadStatusSuspended, nil
+	case ActionTypeResume:
+		return workload.Status == types.WorkloadStatusRunning, nil
+	case ActionTypeTerminate:
+		return workload.Status == types.WorkloadStatusCompleted, nil
+	case ActionTypeScale:
+		// Relative scaling cannot be replayed safely, so any change since
+		// the captured state counts as the scale having been applied
+		if step.Before == nil {
+			return false, nil
+		}
+		return snapshotChanged(step.Before, workloadSnapshot(workload)), nil
+	default:
+		return false, nil
+	}
+}
+
+// snapshotChanged returns true if the replicas or resource requests of two
+// workload snapshots differ
+func snapshotChanged(before, after map[string]interface{}) bool {
+	beforeReplicas, _, _ := numberParam(before, "replicas")
+	afterReplicas, _, _ := numberParam(after, "replicas")
+	if beforeReplicas != afterReplicas {
+		return true
+	}
+
+	beforeResources, _ := snapshotResources(before)
+	afterResources, _ := snapshotResources(after)
+	return beforeResources.CPU != afterResources.CPU || beforeResources.Memory != afterResources.Memory
+}
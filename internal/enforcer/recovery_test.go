@@ -0,0 +1,255 @@
+package enforcer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/decisions"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// interrupted stores a decision left executing by a previous run, with the
+// enforcement status that run saved, if any
+func (te *testEnforcer) interrupted(t *testing.T, decision *types.Decision, status *EnforcementStatus) *types.Decision {
+	t.Helper()
+	te.approve(t, decision)
+	require.NoError(t, te.lifecycle.Transition(context.Background(), decision, decisions.Change{Status: types.DecisionStatusExecuting}))
+	if status != nil {
+		status.DecisionID = decision.ID
+		require.NoError(t, te.store.Enforcement().Save(context.Background(), status))
+	}
+	return decision
+}
+
+// interruptedSteps are the steps of a decision with its first step left
+// running
+func (te *testEnforcer) interruptedSteps(t *testing.T, decision *types.Decision) []types.EnforcementStep {
+	t.Helper()
+	workload := storedWorkload(t, te.store, decision.WorkloadID)
+	steps, err := te.generateSteps(context.Background(), decision, workload)
+	require.NoError(t, err)
+
+	startedAt := time.Now()
+	steps[0].State = EnforcementStateRunning
+	steps[0].StartedAt = &startedAt
+	steps[0].Before = workloadSnapshot(workload)
+	return steps
+}
+
+// moveWorkload places a workload as if a migration had taken effect
+func moveWorkload(t *testing.T, te *testEnforcer, workloadID, cluster, node string) {
+	t.Helper()
+	workload := storedWorkload(t, te.store, workloadID)
+	setPlacement(workload, labelCluster, labelClusterAlt, cluster)
+	setPlacement(workload, labelNode, labelNodeAlt, node)
+	require.NoError(t, te.store.Workload().Update(context.Background(), workload))
+}
+
+func TestRecover_RestartsEnforcementWithoutSteps(t *testing.T) {
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+	newTestWorkload(t, te.store, "wl-2")
+
+	// One run saved no status at all, the other a status without steps
+	te.interrupted(t, scaleDecision("d-1", "wl-1"), nil)
+	te.interrupted(t, scaleDecision("d-2", "wl-2"), &EnforcementStatus{Status: EnforcementStatePending, StartedAt: time.Now()})
+
+	results, err := te.Recover(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.Equal(t, RecoveryOutcomeRestarted, result.Outcome, result.DecisionID)
+	}
+
+	te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+	te.waitForDecision(t, "d-2", types.DecisionStatusCompleted)
+	assert.Equal(t, "4", storedWorkload(t, te.store, "wl-1").Annotations[AnnotationReplicas])
+	assert.Equal(t, "4", storedWorkload(t, te.store, "wl-2").Annotations[AnnotationReplicas])
+}
+
+func TestRecover_RunningStep(t *testing.T) {
+	t.Run("applied step is completed without replaying it", func(t *testing.T) {
+		te := newTestEnforcer(t, config.EnforcementConfig{})
+		newTestWorkload(t, te.store, "wl-1")
+		migrator := newStubExecutor(ActionTypeMigrate, nil)
+		require.NoError(t, te.registry.Register(migrator))
+
+		decision := migrateDecision("d-1", "wl-1")
+		steps := te.interruptedSteps(t, decision)
+		te.interrupted(t, decision, &EnforcementStatus{Status: EnforcementStateRunning, StartedAt: time.Now(), Steps: steps})
+		moveWorkload(t, te, "wl-1", "cluster-b", "node-2")
+
+		results, err := te.Recover(context.Background())
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, RecoveryOutcomeResumed, results[0].Outcome)
+
+		te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+		status := te.waitForState(t, "d-1", EnforcementStateCompleted)
+		assert.Equal(t, EnforcementStateCompleted, status.Steps[0].State)
+		assert.Empty(t, migrator.executed())
+	})
+
+	t.Run("step that did not take effect runs again", func(t *testing.T) {
+		te := newTestEnforcer(t, config.EnforcementConfig{})
+		newTestWorkload(t, te.store, "wl-1")
+		migrator := newStubExecutor(ActionTypeMigrate, nil)
+		require.NoError(t, te.registry.Register(migrator))
+
+		decision := migrateDecision("d-1", "wl-1")
+		steps := te.interruptedSteps(t, decision)
+		te.interrupted(t, decision, &EnforcementStatus{Status: EnforcementStateRunning, StartedAt: time.Now(), Steps: steps})
+
+		results, err := te.Recover(context.Background())
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, RecoveryOutcomeResumed, results[0].Outcome)
+
+		te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+		assert.Len(t, migrator.executed(), 1)
+	})
+}
+
+func TestRecover_CompensatesEndedEnforcement(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    EnforcementState
+		decision types.DecisionStatus
+	}{
+		{"failed", EnforcementStateFailed, types.DecisionStatusFailed},
+		{"cancelled", EnforcementStateCancelled, types.DecisionStatusCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := newTestEnforcer(t, config.EnforcementConfig{})
+			newTestWorkload(t, te.store, "wl-1")
+
+			// The migration took effect before the enforcement ended, but
+			// the run stopped before undoing it
+			decision := migrateDecision("d-1", "wl-1")
+			steps := te.interruptedSteps(t, decision)
+			steps[0].State = EnforcementStateCompleted
+			te.interrupted(t, decision, &EnforcementStatus{
+				Status:    tt.state,
+				StartedAt: time.Now(),
+				Steps:     steps,
+				Compensations: []*Action{{
+					Type:   ActionTypeMigrate,
+					Target: "wl-1",
+					Parameters: map[string]interface{}{
+						"workload_id":    "wl-1",
+						"target_cluster": "cluster-a",
+						"target_node":    "node-1",
+					},
+				}},
+			})
+			moveWorkload(t, te, "wl-1", "cluster-b", "node-2")
+
+			results, err := te.Recover(context.Background())
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+			assert.Equal(t, RecoveryOutcomeRolledBack, results[0].Outcome)
+			assert.Contains(t, results[0].Reason, string(tt.state))
+
+			stored, err := te.store.Decision().Get(context.Background(), "d-1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.decision, stored.Status)
+			workload := storedWorkload(t, te.store, "wl-1")
+			assert.Equal(t, "cluster-a", workload.Labels["cluster"])
+			assert.Equal(t, "node-1", workload.Labels["node"])
+
+			status, err := te.store.Enforcement().Get(context.Background(), "d-1")
+			require.NoError(t, err)
+			assert.Empty(t, status.Compensations)
+		})
+	}
+}
+
+func TestRecover_RequeuesWaitingEnforcements(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+	newTestWorkload(t, te.store, "wl-2")
+	newTestWorkload(t, te.store, "wl-3")
+
+	queued := te.approve(t, scaleDecision("d-1", "wl-1"))
+	require.NoError(t, te.store.Enforcement().Save(ctx, &EnforcementStatus{
+		DecisionID: queued.ID,
+		Status:     EnforcementStatePending,
+		StartedAt:  time.Now().Add(-time.Minute),
+		Details:    map[string]interface{}{"queued": true},
+	}))
+
+	// The window the decision waited for opened during the restart
+	deferred := te.approve(t, scaleDecision("d-2", "wl-2"))
+	scheduledFor := time.Now().Add(-time.Second)
+	deferred.ScheduledFor = &scheduledFor
+	require.NoError(t, te.store.Decision().Update(ctx, deferred))
+	require.NoError(t, te.store.Enforcement().Save(ctx, &EnforcementStatus{
+		DecisionID: deferred.ID,
+		Status:     EnforcementStatePending,
+		StartedAt:  time.Now(),
+		Details:    map[string]interface{}{"deferred": true},
+	}))
+
+	// A queued decision cancelled in the meantime is not enforced
+	cancelled := te.approve(t, scaleDecision("d-3", "wl-3"))
+	require.NoError(t, te.lifecycle.Transition(ctx, cancelled, decisions.Change{Status: types.DecisionStatusCancelled}))
+	require.NoError(t, te.store.Enforcement().Save(ctx, &EnforcementStatus{
+		DecisionID: cancelled.ID,
+		Status:     EnforcementStatePending,
+		StartedAt:  time.Now(),
+		Details:    map[string]interface{}{"queued": true},
+	}))
+
+	results, err := te.Recover(ctx)
+	require.NoError(t, err)
+	outcomes := make(map[string]RecoveryOutcome)
+	for _, result := range results {
+		outcomes[result.DecisionID] = result.Outcome
+	}
+	assert.Equal(t, map[string]RecoveryOutcome{
+		"d-1": RecoveryOutcomeRequeued,
+		"d-2": RecoveryOutcomeDeferred,
+	}, outcomes)
+
+	te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+	te.waitForDecision(t, "d-2", types.DecisionStatusCompleted)
+	status, err := te.GetEnforcementStatus(ctx, "d-3")
+	require.NoError(t, err)
+	assert.Equal(t, EnforcementStateCancelled, status.Status)
+	assert.Equal(t, "2", storedWorkload(t, te.store, "wl-3").Annotations[AnnotationReplicas])
+}
+
+func TestRecover_SecondPassIsNoOp(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+	migrator, release := blockingExecutor(ActionTypeMigrate)
+	require.NoError(t, te.registry.Register(migrator))
+
+	te.interrupted(t, migrateDecision("d-1", "wl-1"), nil)
+
+	results, err := te.Recover(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	te.waitForState(t, "d-1", EnforcementStateRunning)
+
+	// The restarted enforcement is still running, so it is left alone
+	results, err = te.Recover(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	close(release)
+	te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+	results, err = te.Recover(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	assert.Len(t, migrator.executed(), 1)
+}
@@ -0,0 +1,599 @@
+package enforcer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+const (
+	// defaultHealthWindow is how far back the health before a wave is
+	// sampled when the rollout has no bake time
+	defaultHealthWindow = 5 * time.Minute
+
+	// defaultRolloutCheckInterval is how often a rollout checks on the
+	// enforcements of a wave when no interval is configured
+	defaultRolloutCheckInterval = 5 * time.Second
+)
+
+// rolloutRun is a rollout in progress and the means to steer it
+type rolloutRun struct {
+	rollout   *Rollout
+	decisions map[string]*types.Decision
+	resume    chan struct{}
+	cancel    context.CancelFunc
+}
+
+// healthSample is the average health workloads reported over a period
+type healthSample struct {
+	errorRate float64
+	latency   float64
+	samples   int
+}
+
+// StartRollout splits decisions into waves and enforces them in the
+// background, one wave at a time
+func (pe *policyEnforcer) StartRollout(ctx context.Context, decisions []*types.Decision, strategy *RolloutStrategy) (*Rollout, error) {
+	if len(decisions) == 0 {
+		return nil, fmt.Errorf("rollout has no decisions")
+	}
+
+	resolved, err := pe.rolloutStrategy(strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rollout := &Rollout{
+		ID:        fmt.Sprintf("rollout-%d", now.UnixNano()),
+		State:     types.RolloutStateRunning,
+		Strategy:  resolved,
+		Message:   "Rollout in progress",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	run := &rolloutRun{
+		rollout:   rollout,
+		decisions: make(map[string]*types.Decision, len(decisions)),
+		resume:    make(chan struct{}, 1),
+	}
+	size := rollout.Strategy.InitialWave
+	for start := 0; start < len(decisions); {
+		end := start + size
+		if end > len(decisions) {
+			end = len(decisions)
+		}
+
+		wave := types.RolloutWave{Index: len(rollout.Waves), State: types.RolloutWaveStatePending}
+		for _, decision := range decisions[start:end] {
+			run.decisions[decision.ID] = decision
+			wave.DecisionIDs = append(wave.DecisionIDs, decision.ID)
+			wave.WorkloadIDs = append(wave.WorkloadIDs, decisionWorkloads(decision)...)
+		}
+		rollout.Waves = append(rollout.Waves, wave)
+
+		start = end
+		size = int(math.Ceil(float64(size) * rollout.Strategy.WaveGrowth))
+	}
+
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	run.cancel = cancel
+
+	pe.mu.Lock()
+	pe.rollouts[rollout.ID] = run
+	snapshot := rollout.Copy()
+	pe.mu.Unlock()
+
+	pe.logger.Info("started rollout",
+		"rollout_id", rollout.ID,
+		"decisions", len(decisions),
+		"waves", len(rollout.Waves),
+		"bake_time", rollout.Strategy.BakeTime)
+
+	go pe.runRollout(runCtx, run)
+
+	return snapshot, nil
+}
+
+// GetRollout gets the state of a rollout
+func (pe *policyEnforcer) GetRollout(ctx context.Context, rolloutID string) (*Rollout, error) {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+
+	run, exists := pe.rollouts[rolloutID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", types.ErrRolloutNotFound, rolloutID)
+	}
+	return run.rollout.Copy(), nil
+}
+
+// ListRollouts lists the rollouts started since the enforcer started,
+// oldest first
+func (pe *policyEnforcer) ListRollouts(ctx context.Context) []*Rollout {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+
+	rollouts := make([]*Rollout, 0, len(pe.rollouts))
+	for _, run := range pe.rollouts {
+		rollouts = append(rollouts, run.rollout.Copy())
+	}
+	sort.Slice(rollouts, func(i, j int) bool {
+		return rollouts[i].CreatedAt.Before(rollouts[j].CreatedAt)
+	})
+	return rollouts
+}
+
+// PauseRollout stops a running rollout from starting its next wave. The
+// current wave still finishes enforcing and baking.
+func (pe *policyEnforcer) PauseRollout(ctx context.Context, rolloutID string) (*Rollout, error) {
+	return pe.steerRollout(rolloutID, "pause", func(run *rolloutRun) error {
+		if run.rollout.State != types.RolloutStateRunning {
+			return fmt.Errorf("%w: cannot pause rollout in state %s", types.ErrInvalidRolloutState, run.rollout.State)
+		}
+		run.rollout.State = types.RolloutStatePaused
+		run.rollout.Message = "Rollout paused"
+		return nil
+	})
+}
+
+// ResumeRollout lets a paused rollout continue with its next wave
+func (pe *policyEnforcer) ResumeRollout(ctx context.Context, rolloutID string) (*Rollout, error) {
+	return pe.steerRollout(rolloutID, "resume", func(run *rolloutRun) error {
+		if run.rollout.State != types.RolloutStatePaused {
+			return fmt.Errorf("%w: cannot resume rollout in state %s", types.ErrInvalidRolloutState, run.rollout.State)
+		}
+		run.rollout.State = types.RolloutStateRunning
+		run.rollout.Message = "Rollout in progress"
+		select {
+		case run.resume <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+}
+
+// AbortRollout stops a rollout. The rollout then rolls back the waves it
+// enforced.
+func (pe *policyEnforcer) AbortRollout(ctx context.Context, rolloutID string) (*Rollout, error) {
+	return pe.steerRollout(rolloutID, "abort", func(run *rolloutRun) error {
+		if run.rollout.IsTerminated() {
+			return fmt.Errorf("%w: cannot abort rollout in state %s", types.ErrInvalidRolloutState, run.rollout.State)
+		}
+		run.rollout.State = types.RolloutStateAborted
+		run.rollout.Message = "Rollout aborting"
+		run.cancel()
+		return nil
+	})
+}
+
+// steerRollout applies a change to the state of a rollout
+func (pe *policyEnforcer) steerRollout(rolloutID, operation string, change func(run *rolloutRun) error) (*Rollout, error) {
+	pe.mu.Lock()
+	run, exists := pe.rollouts[rolloutID]
+	if !exists {
+		pe.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", types.ErrRolloutNotFound, rolloutID)
+	}
+	if err := change(run); err != nil {
+		pe.mu.Unlock()
+		return nil, err
+	}
+	run.rollout.UpdatedAt = time.Now()
+	snapshot := run.rollout.Copy()
+	pe.mu.Unlock()
+
+	pe.logger.Info("steered rollout", "rollout_id", rolloutID, "operation", operation, "state", snapshot.State)
+	return snapshot, nil
+}
+
+// runRollout enforces the waves of a rollout in turn until they have all
+// passed, one fails or regresses, or the rollout is aborted
+func (pe *policyEnforcer) runRollout(ctx context.Context, run *rolloutRun) {
+	defer run.cancel()
+
+	// Rolling back must still work once the rollout has been aborted
+	settleCtx := context.WithoutCancel(ctx)
+
+	for index := range run.rollout.Waves {
+		if !pe.awaitResume(ctx, run) {
+			break
+		}
+
+		if err := pe.runWave(ctx, run, index); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			pe.rollbackRollout(settleCtx, run)
+			pe.settleRollout(run, types.RolloutStateHalted, fmt.Sprintf("Rollout halted: %v", err), err)
+			pe.logger.Warn("halted rollout", "rollout_id", run.rollout.ID, "wave", index, "error", err.Error())
+			return
+		}
+	}
+
+	if ctx.Err() != nil {
+		pe.rollbackRollout(settleCtx, run)
+		pe.settleRollout(run, types.RolloutStateAborted, "Rollout aborted", nil)
+		pe.logger.Info("aborted rollout", "rollout_id", run.rollout.ID)
+		return
+	}
+
+	pe.settleRollout(run, types.RolloutStateCompleted, "Rollout completed", nil)
+	pe.logger.Info("completed rollout", "rollout_id", run.rollout.ID, "waves", len(run.rollout.Waves))
+}
+
+// awaitResume blocks while a rollout is paused, returning false once it is
+// aborted
+func (pe *policyEnforcer) awaitResume(ctx context.Context, run *rolloutRun) bool {
+	for {
+		pe.mu.RLock()
+		paused := run.rollout.State == types.RolloutStatePaused
+		pe.mu.RUnlock()
+		if !paused {
+			return ctx.Err() == nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-run.resume:
+		}
+	}
+}
+
+// runWave enforces the decisions of a wave, waits for their enforcements
+// to finish and compares the health of the wave's workloads after the bake
+// time with their health before the wave
+func (pe *policyEnforcer) runWave(ctx context.Context, run *rolloutRun, index int) error {
+	pe.mu.RLock()
+	strategy := run.rollout.Strategy
+	wave := run.rollout.Waves[index]
+	pe.mu.RUnlock()
+
+	window := strategy.BakeTime
+	if window <= 0 {
+		window = defaultHealthWindow
+	}
+	startedAt := time.Now()
+	baseline := pe.sampleHealth(ctx, wave.WorkloadIDs, startedAt.Add(-window), startedAt)
+
+	pe.updateWave(run, index, func(wave *types.RolloutWave) {
+		wave.State = types.RolloutWaveStateEnforcing
+		wave.StartedAt = &startedAt
+	})
+
+	var failed, enforced []string
+	for _, decisionID := range wave.DecisionIDs {
+		if err := pe.Enforce(ctx, run.decisions[decisionID]); err != nil {
+			pe.logger.WithError(err).Warn("failed to enforce rollout decision", "rollout_id", run.rollout.ID, "decision_id", decisionID)
+			failed = append(failed, decisionID)
+			continue
+		}
+		enforced = append(enforced, decisionID)
+	}
+
+	// Dry runs change nothing to bake, so the plans settle the wave
+	if pe.config.Mode == config.EnforcementModeDryRun {
+		return pe.settleDryRunWave(run, index, enforced, failed)
+	}
+
+	unfinished, err := pe.awaitEnforcements(ctx, enforced)
+	if err != nil {
+		return err
+	}
+	failed = append(failed, unfinished...)
+	if len(failed) > 0 {
+		pe.updateWave(run, index, func(wave *types.RolloutWave) {
+			now := time.Now()
+			wave.State = types.RolloutWaveStateFailed
+			wave.Failed = failed
+			wave.CompletedAt = &now
+		})
+		return fmt.Errorf("wave %d: %d of %d enforcements failed", index+1, len(failed), len(wave.DecisionIDs))
+	}
+
+	bakeStart := time.Now()
+	pe.updateWave(run, index, func(wave *types.RolloutWave) {
+		wave.State = types.RolloutWaveStateBaking
+	})
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(strategy.BakeTime):
+	}
+
+	bakedAt := time.Now()
+	health := assessHealth(strategy, baseline, pe.sampleHealth(ctx, wave.WorkloadIDs, bakeStart, bakedAt))
+	pe.updateWave(run, index, func(wave *types.RolloutWave) {
+		wave.Health = health
+		wave.BakedAt = &bakedAt
+		wave.CompletedAt = &bakedAt
+		wave.State = types.RolloutWaveStatePassed
+		if !health.Healthy {
+			wave.State = types.RolloutWaveStateFailed
+		}
+	})
+	if !health.Healthy {
+		return fmt.Errorf("wave %d regressed: %s", index+1, health.Reason)
+	}
+
+	pe.mu.Lock()
+	run.rollout.CurrentWave = index + 1
+	run.rollout.UpdatedAt = time.Now()
+	pe.mu.Unlock()
+
+	pe.logger.Info("rollout wave passed",
+		"rollout_id", run.rollout.ID,
+		"wave", index+1,
+		"decisions", len(wave.DecisionIDs),
+		"error_rate", health.ErrorRate,
+		"latency", health.Latency)
+
+	return nil
+}
+
+// settleDryRunWave passes a wave planned in dry-run mode if every decision
+// in it has an executable plan
+func (pe *policyEnforcer) settleDryRunWave(run *rolloutRun, index int, planned, failed []string) error {
+	pe.mu.RLock()
+	for _, decisionID := range planned {
+		if plan, exists := pe.plans[decisionID]; !exists || !plan.Executable {
+			failed = append(failed, decisionID)
+		}
+	}
+	total := len(run.rollout.Waves[index].DecisionIDs)
+	pe.mu.RUnlock()
+
+	now := time.Now()
+	pe.updateWave(run, index, func(wave *types.RolloutWave) {
+		wave.CompletedAt = &now
+		wave.State = types.RolloutWaveStatePassed
+		if len(failed) > 0 {
+			wave.State = types.RolloutWaveStateFailed
+			wave.Failed = failed
+		}
+	})
+	if len(failed) > 0 {
+		return fmt.Errorf("wave %d: %d of %d decisions cannot be executed", index+1, len(failed), total)
+	}
+
+	pe.mu.Lock()
+	run.rollout.CurrentWave = index + 1
+	run.rollout.UpdatedAt = now
+	pe.mu.Unlock()
+
+	pe.logger.Info("rollout wave planned in dry-run mode", "rollout_id", run.rollout.ID, "wave", index+1, "decisions", total)
+	return nil
+}
+
+// awaitEnforcements waits for the enforcements of decisions to finish and
+// returns the decisions whose enforcement did not complete. Every decision
+// was enforced, so one without an enforcement status failed.
+func (pe *policyEnforcer) awaitEnforcements(ctx context.Context, decisionIDs []string) ([]string, error) {
+	interval := pe.config.Rollout.CheckInterval
+	if interval <= 0 {
+		interval = defaultRolloutCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	remaining := decisionIDs
+	var failed []string
+	for {
+		var running []string
+		for _, decisionID := range remaining {
+			status, err := pe.GetEnforcementStatus(ctx, decisionID)
+			if err != nil {
+				pe.logger.WithError(err).Warn("enforced rollout decision has no enforcement status", "decision_id", decisionID)
+				failed = append(failed, decisionID)
+				continue
+			}
+			if !status.IsTerminated() {
+				running = append(running, decisionID)
+				continue
+			}
+			if status.Status != EnforcementStateCompleted {
+				failed = append(failed, decisionID)
+			}
+		}
+		if len(running) == 0 {
+			return failed, nil
+		}
+		remaining = running
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// rollbackRollout undoes the waves a rollout enforced, latest first.
+// Enforcements still running are cancelled, which rolls them back.
+func (pe *policyEnforcer) rollbackRollout(ctx context.Context, run *rolloutRun) {
+	pe.mu.RLock()
+	waves := run.rollout.Copy().Waves
+	pe.mu.RUnlock()
+
+	for index := len(waves) - 1; index >= 0; index-- {
+		wave := waves[index]
+		if wave.State == types.RolloutWaveStatePending {
+			continue
+		}
+
+		for i := len(wave.DecisionIDs) - 1; i >= 0; i-- {
+			decisionID := wave.DecisionIDs[i]
+			status, err := pe.GetEnforcementStatus(ctx, decisionID)
+			if err != nil {
+				continue
+			}
+
+			switch status.Status {
+			case EnforcementStatePending, EnforcementStateRunning:
+				err = pe.CancelEnforcement(ctx, decisionID)
+			case EnforcementStateCompleted:
+				_, err = pe.Rollback(ctx, decisionID)
+			}
+			if err != nil {
+				pe.logger.WithError(err).Warn("failed to roll back rollout decision", "rollout_id", run.rollout.ID, "decision_id", decisionID)
+			}
+		}
+
+		pe.updateWave(run, index, func(wave *types.RolloutWave) {
+			wave.State = types.RolloutWaveStateRolledBack
+		})
+	}
+}
+
+// settleRollout records how a rollout ended
+func (pe *policyEnforcer) settleRollout(run *rolloutRun, state types.RolloutState, message string, cause error) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	now := time.Now()
+	run.rollout.State = state
+	run.rollout.Message = message
+	if cause != nil {
+		run.rollout.Error = cause.Error()
+	}
+	run.rollout.CompletedAt = &now
+	run.rollout.UpdatedAt = now
+}
+
+// updateWave applies a change to a wave of a rollout
+func (pe *policyEnforcer) updateWave(run *rolloutRun, index int, update func(wave *types.RolloutWave)) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	update(&run.rollout.Waves[index])
+	run.rollout.UpdatedAt = time.Now()
+}
+
+// sampleHealth averages the error rate and latency workloads reported
+// between start and end
+func (pe *policyEnforcer) sampleHealth(ctx context.Context, workloadIDs []string, start, end time.Time) healthSample {
+	var sample healthSample
+	for _, workloadID := range workloadIDs {
+		metrics, err := pe.storage.Workload().GetMetrics(ctx, workloadID, start, end)
+		if err != nil {
+			pe.logger.WithError(err).Warn("failed to get workload metrics for rollout", "workload_id", workloadID)
+			continue
+		}
+		for _, metric := range metrics {
+			sample.errorRate += metric.ErrorRate
+			sample.latency += metric.Latency
+			sample.samples++
+		}
+	}
+
+	if sample.samples > 0 {
+		sample.errorRate /= float64(sample.samples)
+		sample.latency /= float64(sample.samples)
+	}
+	return sample
+}
+
+// assessHealth decides whether the health of a wave regressed from its
+// baseline. A wave without metrics is unhealthy unless the strategy allows
+// missing metrics, since nothing shows it did not regress.
+func assessHealth(strategy RolloutStrategy, baseline, current healthSample) *types.RolloutHealth {
+	health := &types.RolloutHealth{
+		Healthy:           true,
+		BaselineErrorRate: baseline.errorRate,
+		ErrorRate:         current.errorRate,
+		BaselineLatency:   baseline.latency,
+		Latency:           current.latency,
+		Samples:           current.samples,
+		CheckedAt:         time.Now(),
+	}
+
+	switch {
+	case current.samples == 0:
+		health.Healthy = strategy.AllowMissingMetrics
+		health.Reason = "no metrics reported during bake time"
+	case strategy.MaxErrorRate > 0 && current.errorRate > strategy.MaxErrorRate:
+		health.Healthy = false
+		health.Reason = fmt.Sprintf("error rate %.4f exceeds %.4f", current.errorRate, strategy.MaxErrorRate)
+	case strategy.MaxErrorRateIncrease > 0 && baseline.samples > 0 &&
+		current.errorRate-baseline.errorRate > strategy.MaxErrorRateIncrease:
+		health.Healthy = false
+		health.Reason = fmt.Sprintf("error rate rose from %.4f to %.4f", baseline.errorRate, current.errorRate)
+	case strategy.MaxLatencyIncrease > 0 && baseline.latency > 0 &&
+		current.latency > baseline.latency*(1+strategy.MaxLatencyIncrease):
+		health.Healthy = false
+		health.Reason = fmt.Sprintf("latency rose from %.2f to %.2f", baseline.latency, current.latency)
+	}
+
+	return health
+}
+
+// rolloutStrategy returns the given strategy with its zero fields taken
+// from the configured one, and wave sizes that always make progress. A
+// zero bake time samples no metrics, so it is refused unless missing
+// metrics are allowed.
+func (pe *policyEnforcer) rolloutStrategy(strategy *RolloutStrategy) (RolloutStrategy, error) {
+	rollout := pe.config.Rollout
+	resolved := RolloutStrategy{
+		InitialWave:          rollout.InitialWave,
+		WaveGrowth:           rollout.WaveGrowth,
+		BakeTime:             rollout.BakeTime,
+		MaxErrorRate:         rollout.MaxErrorRate,
+		MaxErrorRateIncrease: rollout.MaxErrorRateIncrease,
+		MaxLatencyIncrease:   rollout.MaxLatencyIncrease,
+		AllowMissingMetrics:  rollout.AllowMissingMetrics,
+	}
+	if strategy != nil {
+		if strategy.InitialWave != 0 {
+			resolved.InitialWave = strategy.InitialWave
+		}
+		if strategy.WaveGrowth != 0 {
+			resolved.WaveGrowth = strategy.WaveGrowth
+		}
+		if strategy.BakeTime != 0 {
+			resolved.BakeTime = strategy.BakeTime
+		}
+		if strategy.MaxErrorRate != 0 {
+			resolved.MaxErrorRate = strategy.MaxErrorRate
+		}
+		if strategy.MaxErrorRateIncrease != 0 {
+			resolved.MaxErrorRateIncrease = strategy.MaxErrorRateIncrease
+		}
+		if strategy.MaxLatencyIncrease != 0 {
+			resolved.MaxLatencyIncrease = strategy.MaxLatencyIncrease
+		}
+		resolved.AllowMissingMetrics = resolved.AllowMissingMetrics || strategy.AllowMissingMetrics
+	}
+
+	if resolved.InitialWave < 1 {
+		resolved.InitialWave = 1
+	}
+	if resolved.WaveGrowth < 1 {
+		resolved.WaveGrowth = 1
+	}
+	if resolved.BakeTime < 0 {
+		return resolved, fmt.Errorf("%w: negative bake time %s", types.ErrInvalidRolloutStrategy, resolved.BakeTime)
+	}
+	if resolved.BakeTime == 0 && !resolved.AllowMissingMetrics {
+		return resolved, fmt.Errorf("%w: a rollout without a bake time observes no metrics, so it needs allowMissingMetrics", types.ErrInvalidRolloutStrategy)
+	}
+	return resolved, nil
+}
+
+// decisionWorkloads returns the workloads a decision changes
+func decisionWorkloads(decision *types.Decision) []string {
+	if decision.Type != types.DecisionTypeConsolidate {
+		return []string{decision.WorkloadID}
+	}
+
+	var workloads []string
+	for _, move := range consolidationMoves(decision) {
+		workloads = append(workloads, move.workloadID)
+	}
+	return workloads
+}
@@ -0,0 +1,363 @@
+package enforcer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+func TestAssessHealth(t *testing.T) {
+	baseline := healthSample{errorRate: 0.01, latency: 100, samples: 10}
+
+	tests := []struct {
+		name     string
+		strategy RolloutStrategy
+		current  healthSample
+		healthy  bool
+		reason   string
+	}{
+		{
+			name:    "no metrics halts by default",
+			current: healthSample{},
+			healthy: false,
+			reason:  "no metrics reported during bake time",
+		},
+		{
+			name:     "no metrics allowed by the strategy",
+			strategy: RolloutStrategy{AllowMissingMetrics: true},
+			current:  healthSample{},
+			healthy:  true,
+			reason:   "no metrics reported during bake time",
+		},
+		{
+			name:     "within thresholds",
+			strategy: RolloutStrategy{MaxErrorRate: 0.05, MaxErrorRateIncrease: 0.02, MaxLatencyIncrease: 0.5},
+			current:  healthSample{errorRate: 0.02, latency: 140, samples: 10},
+			healthy:  true,
+		},
+		{
+			name:     "error rate over maximum",
+			strategy: RolloutStrategy{MaxErrorRate: 0.05},
+			current:  healthSample{errorRate: 0.08, latency: 100, samples: 10},
+			healthy:  false,
+			reason:   "error rate 0.0800 exceeds 0.0500",
+		},
+		{
+			name:     "error rate increase",
+			strategy: RolloutStrategy{MaxErrorRateIncrease: 0.02},
+			current:  healthSample{errorRate: 0.04, latency: 100, samples: 10},
+			healthy:  false,
+			reason:   "error rate rose from 0.0100 to 0.0400",
+		},
+		{
+			name:     "latency increase",
+			strategy: RolloutStrategy{MaxLatencyIncrease: 0.5},
+			current:  healthSample{errorRate: 0.01, latency: 160, samples: 10},
+			healthy:  false,
+			reason:   "latency rose from 100.00 to 160.00",
+		},
+		{
+			name:    "zero thresholds are not checked",
+			current: healthSample{errorRate: 0.9, latency: 1000, samples: 10},
+			healthy: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := assessHealth(tt.strategy, baseline, tt.current)
+			assert.Equal(t, tt.healthy, health.Healthy)
+			assert.Equal(t, tt.reason, health.Reason)
+			assert.Equal(t, tt.current.samples, health.Samples)
+		})
+	}
+}
+
+// reportMetrics records a metric sample for each workload every few
+// milliseconds until the test ends
+func reportMetrics(t *testing.T, store storage.StorageManager, errorRate float64, workloadIDs ...string) {
+	t.Helper()
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		close(stop)
+		<-done
+	})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(2 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			for _, workloadID := range workloadIDs {
+				_ = store.Workload().RecordMetrics(context.Background(), &types.WorkloadMetrics{
+					WorkloadID: workloadID,
+					ErrorRate:  errorRate,
+					Latency:    100,
+					Timestamp:  time.Now(),
+				})
+			}
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// newRolloutEnforcer is a test enforcer with an approved scale decision for
+// each workload
+func newRolloutEnforcer(t *testing.T, workloadIDs ...string) (*testEnforcer, []*types.Decision) {
+	t.Helper()
+	te := newTestEnforcer(t, config.EnforcementConfig{Rollout: config.RolloutConfig{CheckInterval: 5 * time.Millisecond}})
+
+	decisions := make([]*types.Decision, 0, len(workloadIDs))
+	for i, workloadID := range workloadIDs {
+		newTestWorkload(t, te.store, workloadID)
+		decisions = append(decisions, te.approve(t, scaleDecision(fmt.Sprintf("d-%d", i+1), workloadID)))
+	}
+	return te, decisions
+}
+
+// waitForRollout waits until a rollout reaches a state
+func (te *testEnforcer) waitForRollout(t *testing.T, rolloutID string, state types.RolloutState) *Rollout {
+	t.Helper()
+	var rollout *Rollout
+	require.Eventually(t, func() bool {
+		var err error
+		rollout, err = te.GetRollout(context.Background(), rolloutID)
+		return err == nil && rollout.State == state
+	}, 2*time.Second, 5*time.Millisecond, "rollout %s never reached %s", rolloutID, state)
+	return rollout
+}
+
+func TestRollout_WavesProgress(t *testing.T) {
+	ctx := context.Background()
+	te, decisions := newRolloutEnforcer(t, "wl-1", "wl-2", "wl-3")
+	reportMetrics(t, te.store, 0.01, "wl-1", "wl-2", "wl-3")
+
+	rollout, err := te.StartRollout(ctx, decisions, &RolloutStrategy{
+		InitialWave:  1,
+		WaveGrowth:   2,
+		BakeTime:     20 * time.Millisecond,
+		MaxErrorRate: 0.05,
+	})
+	require.NoError(t, err)
+	require.Len(t, rollout.Waves, 2)
+	assert.Equal(t, []string{"d-1"}, rollout.Waves[0].DecisionIDs)
+	assert.Equal(t, []string{"d-2", "d-3"}, rollout.Waves[1].DecisionIDs)
+
+	rollout = te.waitForRollout(t, rollout.ID, types.RolloutStateCompleted)
+	assert.Equal(t, 2, rollout.CurrentWave)
+	assert.Empty(t, rollout.Error)
+	for _, wave := range rollout.Waves {
+		assert.Equal(t, types.RolloutWaveStatePassed, wave.State)
+		require.NotNil(t, wave.Health)
+		assert.True(t, wave.Health.Healthy)
+		assert.Positive(t, wave.Health.Samples)
+	}
+
+	// The second wave started only once the first had baked
+	assert.False(t, rollout.Waves[1].StartedAt.Before(*rollout.Waves[0].BakedAt))
+	for _, decision := range decisions {
+		te.waitForDecision(t, decision.ID, types.DecisionStatusCompleted)
+	}
+}
+
+func TestRollout_WaveWithoutMetricsHaltsAndRollsBack(t *testing.T) {
+	ctx := context.Background()
+	te, decisions := newRolloutEnforcer(t, "wl-1", "wl-2")
+
+	rollout, err := te.StartRollout(ctx, decisions, &RolloutStrategy{InitialWave: 1, BakeTime: 10 * time.Millisecond})
+	require.NoError(t, err)
+
+	rollout = te.waitForRollout(t, rollout.ID, types.RolloutStateHalted)
+	assert.Contains(t, rollout.Error, "no metrics reported during bake time")
+	assert.Equal(t, 0, rollout.CurrentWave)
+	assert.Equal(t, types.RolloutWaveStateRolledBack, rollout.Waves[0].State)
+	assert.Equal(t, types.RolloutWaveStatePending, rollout.Waves[1].State)
+
+	// The enforced wave is undone and the next one never starts
+	te.waitForDecision(t, "d-1", types.DecisionStatusRolledBack)
+	assert.Equal(t, "2", storedWorkload(t, te.store, "wl-1").Annotations[AnnotationReplicas])
+	stored, err := te.store.Decision().Get(ctx, "d-2")
+	require.NoError(t, err)
+	assert.Equal(t, types.DecisionStatusApproved, stored.Status)
+	assert.Equal(t, "2", storedWorkload(t, te.store, "wl-2").Annotations[AnnotationReplicas])
+}
+
+func TestRollout_MissingMetricsAllowed(t *testing.T) {
+	te, decisions := newRolloutEnforcer(t, "wl-1", "wl-2")
+
+	rollout, err := te.StartRollout(context.Background(), decisions, &RolloutStrategy{
+		InitialWave:         1,
+		BakeTime:            10 * time.Millisecond,
+		AllowMissingMetrics: true,
+	})
+	require.NoError(t, err)
+
+	rollout = te.waitForRollout(t, rollout.ID, types.RolloutStateCompleted)
+	for _, wave := range rollout.Waves {
+		assert.Equal(t, types.RolloutWaveStatePassed, wave.State)
+		assert.Zero(t, wave.Health.Samples)
+	}
+}
+
+func TestRollout_RegressionHaltsAndRollsBack(t *testing.T) {
+	te, decisions := newRolloutEnforcer(t, "wl-1", "wl-2")
+	reportMetrics(t, te.store, 0.2, "wl-1", "wl-2")
+
+	rollout, err := te.StartRollout(context.Background(), decisions, &RolloutStrategy{
+		InitialWave:  1,
+		BakeTime:     20 * time.Millisecond,
+		MaxErrorRate: 0.05,
+	})
+	require.NoError(t, err)
+
+	rollout = te.waitForRollout(t, rollout.ID, types.RolloutStateHalted)
+	assert.Contains(t, rollout.Error, "wave 1 regressed")
+	require.NotNil(t, rollout.Waves[0].Health)
+	assert.False(t, rollout.Waves[0].Health.Healthy)
+	te.waitForDecision(t, "d-1", types.DecisionStatusRolledBack)
+}
+
+func TestRollout_PauseAndResume(t *testing.T) {
+	ctx := context.Background()
+	te, decisions := newRolloutEnforcer(t, "wl-1", "wl-2")
+	reportMetrics(t, te.store, 0.01, "wl-1", "wl-2")
+
+	rollout, err := te.StartRollout(ctx, decisions, &RolloutStrategy{InitialWave: 1, BakeTime: 100 * time.Millisecond})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		current, err := te.GetRollout(ctx, rollout.ID)
+		return err == nil && current.Waves[0].State == types.RolloutWaveStateBaking
+	}, 2*time.Second, time.Millisecond)
+
+	paused, err := te.PauseRollout(ctx, rollout.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.RolloutStatePaused, paused.State)
+	_, err = te.PauseRollout(ctx, rollout.ID)
+	assert.ErrorIs(t, err, types.ErrInvalidRolloutState)
+
+	// The current wave still finishes, but the next one waits
+	require.Eventually(t, func() bool {
+		current, err := te.GetRollout(ctx, rollout.ID)
+		return err == nil && current.Waves[0].State == types.RolloutWaveStatePassed
+	}, 2*time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	current, err := te.GetRollout(ctx, rollout.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.RolloutStatePaused, current.State)
+	assert.Equal(t, types.RolloutWaveStatePending, current.Waves[1].State)
+	stored, err := te.store.Decision().Get(ctx, "d-2")
+	require.NoError(t, err)
+	assert.Equal(t, types.DecisionStatusApproved, stored.Status)
+
+	resumed, err := te.ResumeRollout(ctx, rollout.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.RolloutStateRunning, resumed.State)
+	_, err = te.ResumeRollout(ctx, rollout.ID)
+	assert.ErrorIs(t, err, types.ErrInvalidRolloutState)
+
+	te.waitForRollout(t, rollout.ID, types.RolloutStateCompleted)
+	te.waitForDecision(t, "d-2", types.DecisionStatusCompleted)
+}
+
+func TestRollout_DryRunSettlesWavesFromPlans(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{Mode: config.EnforcementModeDryRun})
+	newTestWorkload(t, te.store, "wl-1")
+	newTestWorkload(t, te.store, "wl-2")
+	decisions := []*types.Decision{
+		te.approve(t, scaleDecision("d-1", "wl-1")),
+		te.approve(t, scaleDecision("d-2", "wl-2")),
+	}
+
+	// Nothing changes in a dry run, so the waves do not bake
+	rollout, err := te.StartRollout(ctx, decisions, &RolloutStrategy{InitialWave: 1, BakeTime: time.Hour})
+	require.NoError(t, err)
+	rollout = te.waitForRollout(t, rollout.ID, types.RolloutStateCompleted)
+	for _, wave := range rollout.Waves {
+		assert.Equal(t, types.RolloutWaveStatePassed, wave.State)
+	}
+	for _, decision := range decisions {
+		stored, err := te.store.Decision().Get(ctx, decision.ID)
+		require.NoError(t, err)
+		assert.Equal(t, types.DecisionStatusApproved, stored.Status)
+	}
+	assert.Equal(t, "2", storedWorkload(t, te.store, "wl-1").Annotations[AnnotationReplicas])
+
+	// A decision that could not be executed halts the rollout
+	missing := te.approve(t, scaleDecision("d-3", "wl-missing"))
+	rollout, err = te.StartRollout(ctx, []*types.Decision{missing}, &RolloutStrategy{BakeTime: time.Hour})
+	require.NoError(t, err)
+	rollout = te.waitForRollout(t, rollout.ID, types.RolloutStateHalted)
+	assert.Contains(t, rollout.Error, "1 of 1 decisions cannot be executed")
+	assert.Equal(t, []string{"d-3"}, rollout.Waves[0].Failed)
+}
+
+func TestAwaitEnforcements_MissingStatusFails(t *testing.T) {
+	te := newTestEnforcer(t, config.EnforcementConfig{Rollout: config.RolloutConfig{CheckInterval: time.Millisecond}})
+
+	failed, err := te.awaitEnforcements(context.Background(), []string{"d-unknown"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d-unknown"}, failed)
+}
+
+func TestRolloutStrategy_DefaultsFromConfig(t *testing.T) {
+	te := newTestEnforcer(t, config.EnforcementConfig{Rollout: config.RolloutConfig{
+		InitialWave:          5,
+		WaveGrowth:           2,
+		BakeTime:             time.Minute,
+		MaxErrorRateIncrease: 0.05,
+	}})
+
+	strategy, err := te.rolloutStrategy(&RolloutStrategy{InitialWave: 2, MaxErrorRate: 0.1})
+	require.NoError(t, err)
+	assert.Equal(t, RolloutStrategy{
+		InitialWave:          2,
+		WaveGrowth:           2,
+		BakeTime:             time.Minute,
+		MaxErrorRate:         0.1,
+		MaxErrorRateIncrease: 0.05,
+	}, strategy)
+
+	// Without a bake time there are no metrics to judge a wave by
+	te = newTestEnforcer(t, config.EnforcementConfig{})
+	_, err = te.rolloutStrategy(&RolloutStrategy{InitialWave: 2})
+	assert.ErrorIs(t, err, types.ErrInvalidRolloutStrategy)
+	_, err = te.StartRollout(context.Background(), []*types.Decision{scaleDecision("d-1", "wl-1")}, nil)
+	assert.ErrorIs(t, err, types.ErrInvalidRolloutStrategy)
+
+	strategy, err = te.rolloutStrategy(&RolloutStrategy{AllowMissingMetrics: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, strategy.InitialWave)
+	assert.Equal(t, 1.0, strategy.WaveGrowth)
+}
+
+func TestRolloutStrategy_UnmarshalBakeTime(t *testing.T) {
+	var strategy RolloutStrategy
+	require.NoError(t, json.Unmarshal([]byte(`{"initialWave": 3, "bakeTime": "90s", "maxErrorRate": 0.1}`), &strategy))
+	assert.Equal(t, RolloutStrategy{InitialWave: 3, BakeTime: 90 * time.Second, MaxErrorRate: 0.1}, strategy)
+
+	strategy = RolloutStrategy{}
+	require.NoError(t, json.Unmarshal([]byte(`{"bakeTime": 1000000000}`), &strategy))
+	assert.Equal(t, time.Second, strategy.BakeTime)
+
+	strategy = RolloutStrategy{}
+	require.NoError(t, json.Unmarshal([]byte(`{"waveGrowth": 2}`), &strategy))
+	assert.Zero(t, strategy.BakeTime)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"bakeTime": "soon"}`), &strategy))
+}
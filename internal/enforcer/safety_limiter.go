@@ -0,0 +1,402 @@
+package enforcer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// disruption is a workload a decision takes out of service, fully or in part
+type disruption struct {
+	workloadID string
+	cluster    string
+	namespace  string
+	actionType string
+
+	// remaining is the number of replicas left healthy while disrupted
+	remaining int
+}
+
+// startedDisruption is a disruption counted against hourly budgets
+type startedDisruption struct {
+	disruption
+	at time.Time
+}
+
+// safetyLimiter implements SafetyLimiter interface with in-memory budgets
+type safetyLimiter struct {
+	storage        storage.StorageManager
+	config         config.SafetyConfig
+	logger         types.Logger
+	inFlight       map[string][]disruption
+	started        []startedDisruption
+	namespaceSizes map[string]int
+	mu             sync.Mutex
+}
+
+// NewSafetyLimiter creates a new safety limiter
+func NewSafetyLimiter(storage storage.StorageManager, cfg config.SafetyConfig, logger types.Logger) SafetyLimiter {
+	limits := make([]config.SafetyLimit, len(cfg.Limits))
+	for i, limit := range cfg.Limits {
+		if limit.Name == "" {
+			limit.Name = fmt.Sprintf("limit-%d", i+1)
+		}
+		if limit.OverLimit != "" && !validSafetyAction(limit.OverLimit) {
+			logger.Warn("safety limit has unknown over_limit action, decisions over it will be queued",
+				"limit", limit.Name, "over_limit", limit.OverLimit)
+		}
+		limits[i] = limit
+	}
+	cfg.Limits = limits
+
+	return &safetyLimiter{
+		storage:        storage,
+		config:         cfg,
+		logger:         logger,
+		inFlight:       make(map[string][]disruption),
+		namespaceSizes: make(map[string]int),
+	}
+}
+
+// Admit checks a decision against every limit matching its disruptions.
+// Decisions that disrupt nothing, such as scale-ups, are always admitted.
+func (sl *safetyLimiter) Admit(ctx context.Context, decision *types.Decision) (*SafetyVerdict, error) {
+	if decision == nil {
+		return nil, fmt.Errorf("decision cannot be nil")
+	}
+	if !sl.config.Enabled {
+		return &SafetyVerdict{Allowed: true, Reason: "safety limits disabled"}, nil
+	}
+
+	disruptions, err := sl.disruptions(ctx, decision)
+	if err != nil {
+		return nil, err
+	}
+	if len(disruptions) == 0 {
+		return &SafetyVerdict{Allowed: true, Reason: "decision is not disruptive"}, nil
+	}
+
+	// Namespace sizes are read before locking so storage stays out of the
+	// critical section
+	sizes := make(map[string]int)
+	for _, d := range disruptions {
+		if _, exists := sizes[d.namespace]; exists || d.namespace == "" {
+			continue
+		}
+		namespace := d.namespace
+		workloads, err := sl.storage.Workload().List(ctx, &storage.WorkloadFilters{Namespace: &namespace})
+		if err != nil {
+			return nil, fmt.Errorf("failed to count workloads in namespace %s: %w", namespace, err)
+		}
+		sizes[namespace] = len(workloads)
+	}
+
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	for namespace, size := range sizes {
+		sl.namespaceSizes[namespace] = size
+	}
+
+	// A decision admitted before is already counted
+	if _, exists := sl.inFlight[decision.ID]; exists {
+		return &SafetyVerdict{Allowed: true, Reason: "decision already admitted"}, nil
+	}
+
+	now := time.Now()
+	sl.prune(now)
+	for _, limit := range sl.config.Limits {
+		matched := matchDisruptions(limit, disruptions)
+		if len(matched) == 0 {
+			continue
+		}
+		if verdict := sl.check(limit, matched, now); verdict != nil {
+			return verdict, nil
+		}
+	}
+
+	sl.inFlight[decision.ID] = disruptions
+	for _, d := range disruptions {
+		sl.started = append(sl.started, startedDisruption{disruption: d, at: now})
+	}
+
+	return &SafetyVerdict{Allowed: true, Reason: "within safety limits"}, nil
+}
+
+// Release returns the concurrency budget reserved for a decision. Hourly
+// budgets stay used until the disruptions leave the window.
+func (sl *safetyLimiter) Release(decisionID string) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	delete(sl.inFlight, decisionID)
+}
+
+// Usage reports the current budget usage of every limit
+func (sl *safetyLimiter) Usage() []*BudgetUsage {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	sl.prune(time.Now())
+
+	usage := make([]*BudgetUsage, 0, len(sl.config.Limits))
+	for _, limit := range sl.config.Limits {
+		budget := &BudgetUsage{
+			Limit:                limit.Name,
+			Concurrent:           len(sl.concurrent(limit)),
+			MaxConcurrent:        limit.MaxConcurrent,
+			LastHour:             len(sl.recent(limit)),
+			MaxPerHour:           limit.MaxPerHour,
+			MaxNamespaceFraction: limit.MaxNamespaceFraction,
+		}
+		for namespace, disrupted := range sl.namespaceDisruptions(limit) {
+			if size := sl.namespaceSizes[namespace]; size > 0 {
+				if budget.Namespaces == nil {
+					budget.Namespaces = make(map[string]float64)
+				}
+				budget.Namespaces[namespace] = float64(disrupted) / float64(size)
+			}
+		}
+		usage = append(usage, budget)
+	}
+
+	return usage
+}
+
+// check returns a verdict refusing the matched disruptions if they exceed
+// the limit, or nil if the limit has budget for them. Disruptions that could
+// never fit the limit are rejected even when the limit queues.
+func (sl *safetyLimiter) check(limit config.SafetyLimit, matched []disruption, now time.Time) *SafetyVerdict {
+	overLimit := func(action SafetyAction, reason string) *SafetyVerdict {
+		return &SafetyVerdict{Action: action, Limit: limit.Name, Reason: fmt.Sprintf("safety limit %s: %s", limit.Name, reason)}
+	}
+	action := safetyAction(limit.OverLimit)
+
+	if limit.MinHealthyReplicas > 0 {
+		for _, d := range matched {
+			if d.remaining < limit.MinHealthyReplicas {
+				return overLimit(SafetyActionReject, fmt.Sprintf("%s of workload %s would leave %d healthy replicas, below the minimum of %d",
+					d.actionType, d.workloadID, d.remaining, limit.MinHealthyReplicas))
+			}
+		}
+	}
+
+	if limit.MaxConcurrent > 0 {
+		concurrent := len(sl.concurrent(limit))
+		if concurrent+len(matched) > limit.MaxConcurrent {
+			if len(matched) > limit.MaxConcurrent {
+				action = SafetyActionReject
+			}
+			return overLimit(action, fmt.Sprintf("%d disruptive actions would exceed the maximum of %d concurrent, %d in flight",
+				len(matched), limit.MaxConcurrent, concurrent))
+		}
+	}
+
+	if limit.MaxPerHour > 0 {
+		recent := sl.recent(limit)
+		if len(recent)+len(matched) > limit.MaxPerHour {
+			verdict := overLimit(action, fmt.Sprintf("%d disruptive actions would exceed the maximum of %d per hour, %d started in the last hour",
+				len(matched), limit.MaxPerHour, len(recent)))
+			excess := len(recent) + len(matched) - limit.MaxPerHour
+			if len(matched) > limit.MaxPerHour {
+				verdict.Action = SafetyActionReject
+			} else {
+				// Budget frees up as the oldest disruptions leave the window
+				verdict.RetryAfter = recent[excess-1].Add(time.Hour).Sub(now)
+			}
+			return verdict
+		}
+	}
+
+	if limit.MaxNamespaceFraction > 0 {
+		disrupted := sl.namespaceDisruptions(limit)
+		requested := make(map[string]int)
+		for _, d := range matched {
+			if d.namespace != "" {
+				requested[d.namespace]++
+			}
+		}
+		for namespace, count := range requested {
+			size := sl.namespaceSizes[namespace]
+			if size == 0 {
+				continue
+			}
+			fraction := float64(disrupted[namespace]+count) / float64(size)
+			if fraction > limit.MaxNamespaceFraction {
+				if float64(count)/float64(size) > limit.MaxNamespaceFraction {
+					action = SafetyActionReject
+				}
+				return overLimit(action, fmt.Sprintf("disrupting %.0f%% of namespace %s would exceed the maximum of %.0f%%",
+					fraction*100, namespace, limit.MaxNamespaceFraction*100))
+			}
+		}
+	}
+
+	return nil
+}
+
+// concurrent returns the in-flight disruptions a limit matches
+func (sl *safetyLimiter) concurrent(limit config.SafetyLimit) []disruption {
+	var matched []disruption
+	for _, disruptions := range sl.inFlight {
+		matched = append(matched, matchDisruptions(limit, disruptions)...)
+	}
+	return matched
+}
+
+// recent returns the start times of the disruptions a limit matches in the
+// last hour, oldest first
+func (sl *safetyLimiter) recent(limit config.SafetyLimit) []time.Time {
+	var times []time.Time
+	for _, started := range sl.started {
+		if disruptionMatches(limit, started.disruption) {
+			times = append(times, started.at)
+		}
+	}
+	return times
+}
+
+// namespaceDisruptions counts the in-flight disruptions a limit matches by
+// namespace
+func (sl *safetyLimiter) namespaceDisruptions(limit config.SafetyLimit) map[string]int {
+	counts := make(map[string]int)
+	for _, d := range sl.concurrent(limit) {
+		if d.namespace != "" {
+			counts[d.namespace]++
+		}
+	}
+	return counts
+}
+
+// prune drops disruptions that have left the hourly window
+func (sl *safetyLimiter) prune(now time.Time) {
+	cutoff := now.Add(-time.Hour)
+	index := sort.Search(len(sl.started), func(i int) bool {
+		return sl.started[i].at.After(cutoff)
+	})
+	sl.started = sl.started[index:]
+}
+
+// disruptions lists the workloads a decision would take out of service
+func (sl *safetyLimiter) disruptions(ctx context.Context, decision *types.Decision) ([]disruption, error) {
+	if decision.Type == types.DecisionTypeConsolidate {
+		moves := consolidationMoves(decision)
+		disruptions := make([]disruption, 0, len(moves))
+		for _, move := range moves {
+			workload, err := sl.storage.Workload().Get(ctx, move.workloadID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get workload %s: %w", move.workloadID, err)
+			}
+			disruptions = append(disruptions, newDisruption(decision, workload, ActionTypeMigrate, workloadReplicas(workload)-1))
+		}
+		return disruptions, nil
+	}
+
+	workload, err := sl.storage.Workload().Get(ctx, decision.WorkloadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload %s: %w", decision.WorkloadID, err)
+	}
+	replicas := workloadReplicas(workload)
+
+	switch decision.Type {
+	case types.DecisionTypeTerminate:
+		return []disruption{newDisruption(decision, workload, ActionTypeTerminate, 0)}, nil
+	case types.DecisionTypeSuspend:
+		return []disruption{newDisruption(decision, workload, ActionTypeSuspend, 0)}, nil
+	case types.DecisionTypeMigrate:
+		// Replicas move one at a time, so one is unavailable while moving
+		return []disruption{newDisruption(decision, workload, ActionTypeMigrate, replicas-1)}, nil
+	case types.DecisionTypeReschedule:
+		return []disruption{newDisruption(decision, workload, ActionTypeReschedule, replicas-1)}, nil
+	case types.DecisionTypeScale:
+		// Only scaling in disrupts; scale the workload's copy to find out
+		scaled := *workload
+		scaled.Annotations = make(map[string]string, len(workload.Annotations))
+		for k, v := range workload.Annotations {
+			scaled.Annotations[k] = v
+		}
+		parameters := map[string]interface{}{
+			"scale_factor":    decision.Details["scale_factor"],
+			"scale_direction": decision.Details["scale_direction"],
+		}
+		if err := applyScale(&scaled, parameters); err != nil {
+			return nil, nil
+		}
+		if target := workloadReplicas(&scaled); target < replicas {
+			return []disruption{newDisruption(decision, workload, ActionTypeScale, target)}, nil
+		}
+		return nil, nil
+	default:
+		return nil, nil
+	}
+}
+
+// newDisruption describes the disruption of a workload by a decision
+func newDisruption(decision *types.Decision, workload *types.Workload, actionType string, remaining int) disruption {
+	cluster := workload.Labels[placementKey(workload.Labels, labelCluster, labelClusterAlt)]
+	if cluster == "" {
+		cluster = decision.ClusterID
+	}
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return disruption{
+		workloadID: workload.ID,
+		cluster:    cluster,
+		namespace:  workload.Metadata.Namespace,
+		actionType: actionType,
+		remaining:  remaining,
+	}
+}
+
+// matchDisruptions returns the disruptions a limit matches
+func matchDisruptions(limit config.SafetyLimit, disruptions []disruption) []disruption {
+	var matched []disruption
+	for _, d := range disruptions {
+		if disruptionMatches(limit, d) {
+			matched = append(matched, d)
+		}
+	}
+	return matched
+}
+
+// disruptionMatches returns true if every matcher of the limit accepts the
+// disruption
+func disruptionMatches(limit config.SafetyLimit, d disruption) bool {
+	if len(limit.Clusters) > 0 && !containsValue(limit.Clusters, d.cluster) {
+		return false
+	}
+	if len(limit.Namespaces) > 0 && !containsValue(limit.Namespaces, d.namespace) {
+		return false
+	}
+	if len(limit.ActionTypes) > 0 && !containsValue(limit.ActionTypes, d.actionType) {
+		return false
+	}
+	return true
+}
+
+// safetyAction maps a configured over_limit action onto a SafetyAction,
+// queueing unless rejection is asked for
+func safetyAction(action string) SafetyAction {
+	if SafetyAction(action) == SafetyActionReject {
+		return SafetyActionReject
+	}
+	return SafetyActionQueue
+}
+
+// validSafetyAction returns true if the configured over_limit action is known
+func validSafetyAction(action string) bool {
+	switch SafetyAction(action) {
+	case SafetyActionQueue, SafetyActionReject:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,164 @@
+package enforcer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+func newTestLimiter(t *testing.T, limits ...config.SafetyLimit) (SafetyLimiter, storage.StorageManager) {
+	t.Helper()
+	store := memory.NewStorageManager()
+	for _, id := range []string{"wl-1", "wl-2", "wl-3", "wl-4"} {
+		workload := newTestWorkload(t, store, id)
+		workload.Metadata.Namespace = "payments"
+		require.NoError(t, store.Workload().Update(context.Background(), workload))
+	}
+	return NewSafetyLimiter(store, config.SafetyConfig{Enabled: true, Limits: limits}, testLogger{}), store
+}
+
+func migrateDecision(id, workloadID string) *types.Decision {
+	return &types.Decision{
+		ID:                 id,
+		Type:               types.DecisionTypeMigrate,
+		WorkloadID:         workloadID,
+		RecommendedCluster: "cluster-b",
+		RecommendedNode:    "node-2",
+	}
+}
+
+func TestSafetyLimiter_MaxConcurrent(t *testing.T) {
+	ctx := context.Background()
+	limiter, _ := newTestLimiter(t, config.SafetyLimit{Name: "migrations", ActionTypes: []string{ActionTypeMigrate}, MaxConcurrent: 1})
+
+	verdict, err := limiter.Admit(ctx, migrateDecision("d-1", "wl-1"))
+	require.NoError(t, err)
+	assert.True(t, verdict.Allowed)
+
+	// Admitting the same decision again does not count it twice
+	verdict, err = limiter.Admit(ctx, migrateDecision("d-1", "wl-1"))
+	require.NoError(t, err)
+	assert.True(t, verdict.Allowed)
+
+	verdict, err = limiter.Admit(ctx, migrateDecision("d-2", "wl-2"))
+	require.NoError(t, err)
+	assert.False(t, verdict.Allowed)
+	assert.Equal(t, SafetyActionQueue, verdict.Action)
+	assert.Equal(t, "migrations", verdict.Limit)
+
+	// Other disruptions are outside the limit
+	verdict, err = limiter.Admit(ctx, &types.Decision{ID: "d-3", Type: types.DecisionTypeSuspend, WorkloadID: "wl-3"})
+	require.NoError(t, err)
+	assert.True(t, verdict.Allowed)
+
+	limiter.Release("d-1")
+	verdict, err = limiter.Admit(ctx, migrateDecision("d-2", "wl-2"))
+	require.NoError(t, err)
+	assert.True(t, verdict.Allowed)
+
+	usage := limiter.Usage()
+	require.Len(t, usage, 1)
+	assert.Equal(t, 1, usage[0].Concurrent)
+	assert.Equal(t, 2, usage[0].LastHour)
+}
+
+func TestSafetyLimiter_RejectsWhatCanNeverFit(t *testing.T) {
+	ctx := context.Background()
+	limiter, _ := newTestLimiter(t, config.SafetyLimit{Name: "moves", MaxConcurrent: 1})
+
+	consolidate := &types.Decision{
+		ID:        "d-1",
+		Type:      types.DecisionTypeConsolidate,
+		ClusterID: "cluster-a",
+		Details: map[string]interface{}{"moves": []map[string]interface{}{
+			{"workloadId": "wl-1", "sourceNode": "node-1", "targetNode": "node-2"},
+			{"workloadId": "wl-2", "sourceNode": "node-1", "targetNode": "node-2"},
+		}},
+	}
+	verdict, err := limiter.Admit(ctx, consolidate)
+	require.NoError(t, err)
+	assert.False(t, verdict.Allowed)
+	assert.Equal(t, SafetyActionReject, verdict.Action)
+}
+
+func TestSafetyLimiter_MinHealthyReplicas(t *testing.T) {
+	ctx := context.Background()
+	limiter, _ := newTestLimiter(t, config.SafetyLimit{Name: "healthy", MinHealthyReplicas: 1})
+
+	verdict, err := limiter.Admit(ctx, migrateDecision("d-1", "wl-1"))
+	require.NoError(t, err)
+	assert.True(t, verdict.Allowed)
+
+	verdict, err = limiter.Admit(ctx, &types.Decision{ID: "d-2", Type: types.DecisionTypeSuspend, WorkloadID: "wl-2"})
+	require.NoError(t, err)
+	assert.False(t, verdict.Allowed)
+	assert.Equal(t, SafetyActionReject, verdict.Action)
+	assert.Contains(t, verdict.Reason, "would leave 0 healthy replicas")
+
+	// Scaling in to one replica keeps the minimum, scaling out disrupts
+	// nothing
+	verdict, err = limiter.Admit(ctx, &types.Decision{ID: "d-3", Type: types.DecisionTypeScale, WorkloadID: "wl-3",
+		Details: map[string]interface{}{"scale_factor": 2.0, "scale_direction": "down"}})
+	require.NoError(t, err)
+	assert.True(t, verdict.Allowed)
+
+	verdict, err = limiter.Admit(ctx, scaleDecision("d-4", "wl-4"))
+	require.NoError(t, err)
+	assert.True(t, verdict.Allowed)
+	assert.Equal(t, "decision is not disruptive", verdict.Reason)
+}
+
+func TestSafetyLimiter_MaxPerHour(t *testing.T) {
+	ctx := context.Background()
+	limiter, _ := newTestLimiter(t, config.SafetyLimit{Name: "hourly", MaxPerHour: 1})
+
+	verdict, err := limiter.Admit(ctx, migrateDecision("d-1", "wl-1"))
+	require.NoError(t, err)
+	require.True(t, verdict.Allowed)
+	limiter.Release("d-1")
+
+	// Releasing returns concurrency budget, not hourly budget
+	verdict, err = limiter.Admit(ctx, migrateDecision("d-2", "wl-2"))
+	require.NoError(t, err)
+	assert.False(t, verdict.Allowed)
+	assert.Equal(t, SafetyActionQueue, verdict.Action)
+	assert.InDelta(t, time.Hour.Seconds(), verdict.RetryAfter.Seconds(), 5)
+}
+
+func TestSafetyLimiter_MaxNamespaceFraction(t *testing.T) {
+	ctx := context.Background()
+	limiter, _ := newTestLimiter(t, config.SafetyLimit{Name: "payments", Namespaces: []string{"payments"}, MaxNamespaceFraction: 0.5, OverLimit: "reject"})
+
+	for _, id := range []string{"d-1", "d-2"} {
+		verdict, err := limiter.Admit(ctx, migrateDecision(id, "wl-"+id[2:]))
+		require.NoError(t, err)
+		assert.True(t, verdict.Allowed)
+	}
+
+	verdict, err := limiter.Admit(ctx, migrateDecision("d-3", "wl-3"))
+	require.NoError(t, err)
+	assert.False(t, verdict.Allowed)
+	assert.Equal(t, SafetyActionReject, verdict.Action)
+
+	usage := limiter.Usage()
+	require.Len(t, usage, 1)
+	assert.InDelta(t, 0.5, usage[0].Namespaces["payments"], 1e-9)
+}
+
+func TestSafetyLimiter_Disabled(t *testing.T) {
+	limiter := NewSafetyLimiter(memory.NewStorageManager(), config.SafetyConfig{
+		Limits: []config.SafetyLimit{{MaxConcurrent: 1}},
+	}, testLogger{})
+
+	verdict, err := limiter.Admit(context.Background(), migrateDecision("d-1", "missing"))
+	require.NoError(t, err)
+	assert.True(t, verdict.Allowed)
+}
@@ -0,0 +1,527 @@
+package enforcer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/actions"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+const (
+	// defaultReadinessTimeout bounds a readiness gate without a timeout
+	defaultReadinessTimeout = 5 * time.Minute
+
+	// defaultReadinessInterval is how often a readiness gate without an
+	// interval is checked
+	defaultReadinessInterval = 5 * time.Second
+)
+
+// stepOutcome is how a step run in the background ended
+type stepOutcome struct {
+	index int
+	err   error
+}
+
+// generateSteps plans the steps of a decision. Steps run one after another,
+// except the moves of a consolidate decision, which run in parallel and are
+// joined by its notification.
+func (pe *policyEnforcer) generateSteps(ctx context.Context, decision *types.Decision, workload *types.Workload) ([]types.EnforcementStep, error) {
+	generated, err := pe.generateActions(ctx, decision, workload)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := make([]types.EnforcementStep, len(generated))
+	var previous []string
+	for i, action := range generated {
+		id := fmt.Sprintf("%s-%d", action.Type, i+1)
+		steps[i] = types.EnforcementStep{
+			ID:        id,
+			Action:    action,
+			DependsOn: previous,
+			Gate:      pe.readinessGate(action),
+			Retry:     pe.stepRetry(),
+			State:     EnforcementStatePending,
+		}
+		previous = []string{id}
+	}
+
+	if decision.Type == types.DecisionTypeConsolidate && len(steps) > 1 {
+		moves := make([]string, 0, len(steps)-1)
+		for i := range steps[:len(steps)-1] {
+			steps[i].DependsOn = nil
+			moves = append(moves, steps[i].ID)
+		}
+		steps[len(steps)-1].DependsOn = moves
+	}
+
+	return steps, nil
+}
+
+// readinessGate returns the condition under which the change an action
+// makes is live, or nil for actions that need no wait. The condition is
+// checked against the state the executor of the action observes, not the
+// workload record the executor just wrote, so actions whose executor cannot
+// observe readiness get no gate.
+func (pe *policyEnforcer) readinessGate(action *Action) *types.ReadinessGate {
+	if pe.rules == nil {
+		return nil
+	}
+	if workloadID, _ := action.Parameters["workload_id"].(string); workloadID == "" {
+		return nil
+	}
+
+	switch action.Type {
+	case ActionTypeSchedule, ActionTypeResume, ActionTypeMigrate, ActionTypeReschedule:
+	default:
+		return nil
+	}
+	if !pe.enforcementEngine.CanObserve(action.Type) {
+		return nil
+	}
+
+	return &types.ReadinessGate{
+		Condition: "observed.ready == true",
+		Timeout:   pe.config.Steps.ReadinessTimeout,
+		Interval:  pe.config.Steps.ReadinessInterval,
+	}
+}
+
+// stepRetry returns the configured retry policy of steps, if any
+func (pe *policyEnforcer) stepRetry() *types.RetryPolicy {
+	if pe.config.Steps.MaxRetries <= 0 {
+		return nil
+	}
+	return &types.RetryPolicy{
+		MaxRetries: pe.config.Steps.MaxRetries,
+		Interval:   pe.config.Steps.RetryInterval,
+		Backoff:    BackoffTypeExponential,
+	}
+}
+
+// chainSteps gives steps recorded without an ID one, each depending on the
+// step before it, so plans persisted before steps had dependencies still
+// run in order
+func chainSteps(steps []types.EnforcementStep) {
+	for i := range steps {
+		if steps[i].ID != "" {
+			continue
+		}
+		steps[i].ID = fmt.Sprintf("step-%d", i+1)
+		if i > 0 {
+			steps[i].DependsOn = []string{steps[i-1].ID}
+		}
+	}
+}
+
+// validateSteps checks that step IDs are unique and that their dependencies
+// exist and form no cycle
+func validateSteps(steps []types.EnforcementStep) error {
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		if step.ID == "" {
+			return fmt.Errorf("step %d has no ID", i)
+		}
+		if _, exists := index[step.ID]; exists {
+			return fmt.Errorf("duplicate step %s", step.ID)
+		}
+		index[step.ID] = i
+	}
+
+	pending := make([]int, len(steps))
+	dependents := make(map[string][]int)
+	for i, step := range steps {
+		for _, dependency := range step.DependsOn {
+			if _, exists := index[dependency]; !exists {
+				return fmt.Errorf("step %s depends on unknown step %s", step.ID, dependency)
+			}
+			dependents[dependency] = append(dependents[dependency], i)
+		}
+		pending[i] = len(step.DependsOn)
+	}
+
+	var ready []int
+	for i := range steps {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	visited := 0
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		visited++
+		for _, dependent := range dependents[steps[i].ID] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	if visited < len(steps) {
+		return fmt.Errorf("step dependencies form a cycle")
+	}
+
+	return nil
+}
+
+// dependenciesMet returns true if every step a step depends on has completed
+func dependenciesMet(step types.EnforcementStep, completed map[string]bool) bool {
+	for _, dependency := range step.DependsOn {
+		if !completed[dependency] {
+			return false
+		}
+	}
+	return true
+}
+
+// interruption returns true if err reports a cancelled or timed out context
+func interruption(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// runSteps executes the steps of an enforcement that have not completed
+// yet, each as soon as the steps it depends on have completed, and settles
+// the decision once they have all run. After a step fails the steps already
+// running finish, but no more start before the enforcement is rolled back.
+func (pe *policyEnforcer) runSteps(ctx context.Context, decision *types.Decision, status *EnforcementStatus) {
+	// Rolling back and settling the decision must still work once ctx has
+	// been cancelled or has timed out
+	settleCtx := context.WithoutCancel(ctx)
+
+	pe.mu.Lock()
+	chainSteps(status.Steps)
+	steps := make([]types.EnforcementStep, len(status.Steps))
+	copy(steps, status.Steps)
+	status.Progress = stepProgress(status.Steps)
+	pe.mu.Unlock()
+
+	if err := validateSteps(steps); err != nil {
+		pe.updateStatus(status, EnforcementStateFailed, fmt.Sprintf("Invalid enforcement plan: %v", err))
+		pe.setDecisionStatus(settleCtx, decision, types.DecisionStatusFailed)
+		return
+	}
+
+	completed := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		if step.State == EnforcementStateCompleted {
+			completed[step.ID] = true
+		}
+	}
+
+	outcomes := make(chan stepOutcome)
+	started := make(map[int]bool, len(steps))
+	running := 0
+	var failure, interrupted error
+	for {
+		pe.mu.RLock()
+		cancelled := status.Status == EnforcementStateCancelled
+		pe.mu.RUnlock()
+
+		if failure == nil && interrupted == nil && !cancelled {
+			for i, step := range steps {
+				if started[i] || completed[step.ID] || !dependenciesMet(step, completed) {
+					continue
+				}
+				if err := ctx.Err(); err != nil {
+					interrupted = err
+					break
+				}
+				started[i] = true
+				running++
+				go func(index int) {
+					outcomes <- stepOutcome{index: index, err: pe.runStep(ctx, decision, status, index)}
+				}(i)
+			}
+		}
+		if running == 0 {
+			break
+		}
+
+		outcome := <-outcomes
+		running--
+		switch {
+		case outcome.err == nil:
+			completed[steps[outcome.index].ID] = true
+		case interruption(outcome.err):
+			if interrupted == nil {
+				interrupted = outcome.err
+			}
+		case failure == nil:
+			failure = outcome.err
+		}
+	}
+
+	pe.mu.RLock()
+	cancelled := status.Status == EnforcementStateCancelled
+	pe.mu.RUnlock()
+
+	switch {
+	case interrupted != nil:
+		pe.interrupt(settleCtx, decision, status, interrupted)
+	case cancelled:
+		// A cancellation during the last steps still undoes the enforcement
+		pe.compensate(settleCtx, decision, status, "cancelled")
+		pe.setDecisionStatus(settleCtx, decision, types.DecisionStatusCancelled)
+	case failure != nil:
+		pe.updateStatus(status, EnforcementStateFailed, fmt.Sprintf("Action execution failed: %v", failure))
+		pe.compensate(settleCtx, decision, status, "failed")
+		pe.setDecisionStatus(settleCtx, decision, types.DecisionStatusFailed)
+	default:
+		pe.setDecisionStatus(settleCtx, decision, types.DecisionStatusCompleted)
+		if decision.Type == types.DecisionTypeConsolidate {
+			pe.completeGroupedDecisions(settleCtx, decision)
+		}
+	}
+}
+
+// runStep runs the action of a step and waits for its readiness gate,
+// running the step again as its retry policy allows. An action that took
+// effect is only run again if its effect is gone. A step interrupted by
+// cancellation or a timeout is left running for interrupt to settle.
+func (pe *policyEnforcer) runStep(ctx context.Context, decision *types.Decision, status *EnforcementStatus, index int) error {
+	pe.mu.RLock()
+	step := status.Steps[index]
+	pe.mu.RUnlock()
+	action := step.Action
+
+	var result *ActionResult
+	applied := false
+	for attempt := 0; ; attempt++ {
+		var err error
+		if !applied {
+			// Capture the state the action changes before running it, so it
+			// can be compensated even if the process stops mid-action
+			before := pe.captureState(ctx, action)
+			pe.updateStep(status, index, func(step *types.EnforcementStep) {
+				now := time.Now()
+				step.State = EnforcementStateRunning
+				step.Before = before
+				step.StartedAt = &now
+				step.Attempts = attempt + 1
+				step.Result = nil
+				step.Error = ""
+			})
+
+			pe.addEvent(status, EnforcementEvent{
+				Type:      "action_started",
+				Message:   fmt.Sprintf("Executing action: %s", action.Type),
+				Timestamp: time.Now(),
+				Data: map[string]interface{}{
+					"step":          step.ID,
+					"attempt":       attempt + 1,
+					"action_type":   action.Type,
+					"action_target": action.Target,
+				},
+			})
+
+			result, err = pe.enforcementEngine.ExecuteAction(ctx, action)
+			if interruption(err) {
+				return err
+			}
+			if err == nil && !result.Success {
+				err = fmt.Errorf("%s", result.Error)
+			}
+			applied = err == nil
+			if applied {
+				pe.updateStep(status, index, func(step *types.EnforcementStep) {
+					step.Result = result.Data
+				})
+			}
+		}
+
+		if err == nil && step.Gate != nil {
+			err = pe.awaitGate(ctx, step)
+			if interruption(err) {
+				return err
+			}
+		}
+		if err == nil {
+			break
+		}
+
+		if step.Retry == nil || attempt >= step.Retry.MaxRetries {
+			pe.failStep(status, index, applied, err)
+			return err
+		}
+
+		delay := actions.Backoff(step.Retry, attempt)
+		pe.addEvent(status, EnforcementEvent{
+			Type:      "step_retrying",
+			Message:   fmt.Sprintf("Step %s failed, retrying in %s: %v", step.ID, delay, err),
+			Timestamp: time.Now(),
+			Data: map[string]interface{}{
+				"step":        step.ID,
+				"attempt":     attempt + 1,
+				"max_retries": step.Retry.MaxRetries,
+				"error":       err.Error(),
+			},
+		})
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		if applied {
+			pe.mu.RLock()
+			current := status.Steps[index]
+			pe.mu.RUnlock()
+			applied, _ = pe.actionApplied(ctx, current)
+		}
+	}
+
+	pe.completeStep(status, index)
+
+	pe.addEvent(status, EnforcementEvent{
+		Type:      "action_completed",
+		Message:   fmt.Sprintf("Action completed: %s", action.Type),
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"step":        step.ID,
+			"action_type": action.Type,
+			"success":     result.Success,
+			"duration":    result.Duration,
+		},
+	})
+
+	pe.logger.Info("executed action",
+		"decision_id", decision.ID,
+		"step", step.ID,
+		"action_type", action.Type,
+		"success", result.Success,
+		"duration", result.Duration)
+
+	return nil
+}
+
+// failStep marks a step as failed. An action that took effect before its
+// step failed is still recorded for rollback.
+func (pe *policyEnforcer) failStep(status *EnforcementStatus, index int, applied bool, err error) {
+	pe.mu.Lock()
+	step := &status.Steps[index]
+	now := time.Now()
+	step.State = EnforcementStateFailed
+	step.CompletedAt = &now
+	step.Error = err.Error()
+	if applied {
+		if compensation := compensationFor(step.Action, step.Before, step.Result); compensation != nil {
+			status.Compensations = append(status.Compensations, compensation)
+		}
+	}
+	stepID, actionType := step.ID, step.Action.Type
+	pe.mu.Unlock()
+
+	pe.persist(status)
+
+	pe.addEvent(status, EnforcementEvent{
+		Type:      "action_failed",
+		Message:   fmt.Sprintf("Action failed: %v", err),
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"step":        stepID,
+			"action_type": actionType,
+			"error":       err.Error(),
+		},
+	})
+}
+
+// awaitGate checks the readiness gate of a step until its condition holds,
+// failing once the gate times out
+func (pe *policyEnforcer) awaitGate(ctx context.Context, step types.EnforcementStep) error {
+	gate := step.Gate
+	timeout := gate.Timeout
+	if timeout <= 0 {
+		timeout = defaultReadinessTimeout
+	}
+	interval := gate.Interval
+	if interval <= 0 {
+		interval = defaultReadinessInterval
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		ready, err := pe.gateReady(ctx, step)
+		if err == nil && ready {
+			return nil
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			if lastErr != nil {
+				return fmt.Errorf("step %s not ready within %s: %v", step.ID, timeout, lastErr)
+			}
+			return fmt.Errorf("step %s not ready within %s: %s", step.ID, timeout, gate.Condition)
+		case <-ticker.C:
+		}
+	}
+}
+
+// gateReady evaluates the readiness gate of a step against the state the
+// executor of its action observes, the stored workload and the action itself
+func (pe *policyEnforcer) gateReady(ctx context.Context, step types.EnforcementStep) (bool, error) {
+	action := step.Action
+	observed, err := pe.enforcementEngine.ObserveAction(ctx, action)
+	if err != nil {
+		return false, fmt.Errorf("failed to observe %s action: %w", action.Type, err)
+	}
+
+	env := map[string]interface{}{
+		"observed": observed,
+		"action": map[string]interface{}{
+			"type":       action.Type,
+			"target":     action.Target,
+			"parameters": action.Parameters,
+		},
+	}
+
+	if workloadID, _ := action.Parameters["workload_id"].(string); workloadID != "" {
+		workload, err := pe.storage.Workload().Get(ctx, workloadID)
+		if err != nil {
+			return false, fmt.Errorf("failed to get workload: %w", err)
+		}
+		env["workload"] = gateWorkload(workload)
+	}
+
+	return pe.rules.EvaluateCondition(ctx, step.Gate.Condition, env)
+}
+
+// gateWorkload describes a workload to readiness gates
+func gateWorkload(workload *types.Workload) map[string]interface{} {
+	described := workloadSnapshot(workload)
+	described["id"] = workload.ID
+	described["name"] = workload.Name
+	described["type"] = string(workload.Type)
+	described["status"] = string(workload.Status)
+	described["priority"] = workload.Priority
+	described["namespace"] = workload.Metadata.Namespace
+	described["labels"] = workload.Labels
+	described["annotations"] = workload.Annotations
+	return described
+}
+
+// stepProgress returns the percentage of steps that have completed
+func stepProgress(steps []types.EnforcementStep) float64 {
+	if len(steps) == 0 {
+		return 0
+	}
+	completed := 0
+	for _, step := range steps {
+		if step.State == EnforcementStateCompleted {
+			completed++
+		}
+	}
+	return float64(completed) / float64(len(steps)) * 100.0
+}
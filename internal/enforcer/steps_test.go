@@ -0,0 +1,346 @@
+package enforcer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// consolidateDecision moves wl-1 and wl-2 off node-1
+func consolidateDecision(id string) *types.Decision {
+	return &types.Decision{
+		ID:                 id,
+		Type:               types.DecisionTypeConsolidate,
+		ClusterID:          "cluster-a",
+		RecommendedCluster: "cluster-a",
+		Details: map[string]interface{}{"moves": []map[string]interface{}{
+			{"workloadId": "wl-1", "sourceNode": "node-1", "targetNode": "node-2"},
+			{"workloadId": "wl-2", "sourceNode": "node-1", "targetNode": "node-3"},
+		}},
+	}
+}
+
+func TestGenerateSteps_Dependencies(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	workload := newTestWorkload(t, te.store, "wl-1")
+
+	// Steps of other decisions run one after another
+	steps, err := te.generateSteps(ctx, &types.Decision{ID: "d-1", Type: types.DecisionTypeOptimize, WorkloadID: "wl-1"}, workload)
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+	assert.Equal(t, "update-1", steps[0].ID)
+	assert.Empty(t, steps[0].DependsOn)
+	assert.Equal(t, "notify-2", steps[1].ID)
+	assert.Equal(t, []string{"update-1"}, steps[1].DependsOn)
+
+	// Consolidation moves run in parallel and join in the notification
+	steps, err = te.generateSteps(ctx, consolidateDecision("d-2"), nil)
+	require.NoError(t, err)
+	require.Len(t, steps, 3)
+	assert.Empty(t, steps[0].DependsOn)
+	assert.Empty(t, steps[1].DependsOn)
+	assert.Equal(t, ActionTypeNotify, steps[2].Action.Type)
+	assert.Equal(t, []string{"migrate-1", "migrate-2"}, steps[2].DependsOn)
+	for _, step := range steps {
+		assert.Equal(t, EnforcementStatePending, step.State)
+		assert.Nil(t, step.Gate, "no readiness gates without a rule engine")
+		assert.Nil(t, step.Retry, "no retries unless configured")
+	}
+}
+
+func TestValidateSteps(t *testing.T) {
+	step := func(id string, dependsOn ...string) types.EnforcementStep {
+		return types.EnforcementStep{ID: id, DependsOn: dependsOn}
+	}
+
+	assert.NoError(t, validateSteps([]types.EnforcementStep{step("a"), step("b", "a"), step("c", "a", "b")}))
+	assert.ErrorContains(t, validateSteps([]types.EnforcementStep{step("a"), step("a")}), "duplicate step")
+	assert.ErrorContains(t, validateSteps([]types.EnforcementStep{step("a", "missing")}), "unknown step")
+	assert.ErrorContains(t, validateSteps([]types.EnforcementStep{step("a", "b"), step("b", "a")}), "cycle")
+	assert.ErrorContains(t, validateSteps([]types.EnforcementStep{{}}), "no ID")
+
+	// Steps persisted without IDs are chained in order
+	legacy := []types.EnforcementStep{{}, {}, {}}
+	chainSteps(legacy)
+	assert.Equal(t, "step-1", legacy[0].ID)
+	assert.Empty(t, legacy[0].DependsOn)
+	assert.Equal(t, []string{"step-2"}, legacy[2].DependsOn)
+	assert.NoError(t, validateSteps(legacy))
+}
+
+func TestRunSteps_ConsolidationMovesRunInParallel(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+	newTestWorkload(t, te.store, "wl-2")
+
+	var mu sync.Mutex
+	var order []string
+	record := func(entry string) {
+		mu.Lock()
+		order = append(order, entry)
+		mu.Unlock()
+	}
+
+	// Each move waits for the other to start, which only sequential steps
+	// would never see
+	var started sync.WaitGroup
+	started.Add(2)
+	bothStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(bothStarted)
+	}()
+	migrator := newStubExecutor(ActionTypeMigrate, func(ctx context.Context, action *Action) (*ActionResult, error) {
+		started.Done()
+		select {
+		case <-bothStarted:
+		case <-time.After(time.Second):
+			return &ActionResult{ActionType: action.Type, Error: "moves ran one after another"}, nil
+		}
+		record(action.Target)
+		return succeeded(action, nil), nil
+	})
+	notifier := newStubExecutor(ActionTypeNotify, func(ctx context.Context, action *Action) (*ActionResult, error) {
+		record("notify")
+		return succeeded(action, nil), nil
+	})
+	require.NoError(t, te.registry.Register(migrator))
+	require.NoError(t, te.registry.Register(notifier))
+
+	require.NoError(t, te.Enforce(ctx, te.approve(t, consolidateDecision("d-1"))))
+	te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, order, 3)
+	assert.ElementsMatch(t, []string{"wl-1", "wl-2"}, order[:2])
+	assert.Equal(t, "notify", order[2])
+}
+
+// observingExecutor is a stub executor that reports readiness once it has
+// been observed a number of times
+type observingExecutor struct {
+	*stubExecutor
+	readyAfter int
+
+	mu           sync.Mutex
+	observations int
+}
+
+func (oe *observingExecutor) Observe(ctx context.Context, action *Action) (map[string]interface{}, error) {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+
+	oe.observations++
+	ready := oe.readyAfter >= 0 && oe.observations > oe.readyAfter
+	return map[string]interface{}{"ready": ready, "ready_replicas": oe.observations}, nil
+}
+
+func TestRunSteps_ReadinessGate(t *testing.T) {
+	cfg := config.EnforcementConfig{Steps: config.StepConfig{
+		ReadinessTimeout:  50 * time.Millisecond,
+		ReadinessInterval: 5 * time.Millisecond,
+	}}
+
+	t.Run("ready once the executor observes it", func(t *testing.T) {
+		te := newTestEnforcer(t, cfg)
+		te.rules = evaluator.NewRuleEngine(testLogger{})
+		newTestWorkload(t, te.store, "wl-1")
+		migrator := &observingExecutor{stubExecutor: newStubExecutor(ActionTypeMigrate, nil), readyAfter: 2}
+		require.NoError(t, te.registry.Register(migrator))
+
+		require.NoError(t, te.Enforce(context.Background(), te.approve(t, migrateDecision("d-1", "wl-1"))))
+		te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+
+		status := te.waitForState(t, "d-1", EnforcementStateCompleted)
+		require.Len(t, status.Steps, 1)
+		require.NotNil(t, status.Steps[0].Gate)
+		assert.Contains(t, status.Steps[0].Gate.Condition, "observed.ready")
+		migrator.mu.Lock()
+		assert.Equal(t, 3, migrator.observations)
+		migrator.mu.Unlock()
+	})
+
+	t.Run("times out when the executor never observes it ready", func(t *testing.T) {
+		te := newTestEnforcer(t, cfg)
+		te.rules = evaluator.NewRuleEngine(testLogger{})
+		newTestWorkload(t, te.store, "wl-1")
+
+		// The stored workload says it moved, but the executor never sees it
+		// ready
+		require.NoError(t, te.registry.Register(&observingExecutor{stubExecutor: newStubExecutor(ActionTypeMigrate, func(ctx context.Context, action *Action) (*ActionResult, error) {
+			workload, err := te.store.Workload().Get(ctx, "wl-1")
+			if err != nil {
+				return nil, err
+			}
+			setPlacement(workload, labelNode, labelNodeAlt, "node-2")
+			return succeeded(action, nil), te.store.Workload().Update(ctx, workload)
+		}), readyAfter: -1}))
+
+		started := time.Now()
+		require.NoError(t, te.Enforce(context.Background(), te.approve(t, migrateDecision("d-1", "wl-1"))))
+		te.waitForDecision(t, "d-1", types.DecisionStatusFailed)
+		assert.GreaterOrEqual(t, time.Since(started), 50*time.Millisecond)
+
+		status, err := te.GetEnforcementStatus(context.Background(), "d-1")
+		require.NoError(t, err)
+		assert.Equal(t, EnforcementStateFailed, status.Status)
+		require.Len(t, status.Steps, 1)
+		assert.Equal(t, EnforcementStateFailed, status.Steps[0].State)
+		assert.Contains(t, status.Steps[0].Error, "not ready within 50ms")
+	})
+
+	t.Run("relocation the executor never sees ready is moved back", func(t *testing.T) {
+		te := newTestEnforcer(t, cfg)
+		te.rules = evaluator.NewRuleEngine(testLogger{})
+		newTestWorkload(t, te.store, "wl-1")
+		migrator := NewMigrateExecutor(te.store, testLogger{})
+		require.NoError(t, te.registry.Register(&observingExecutor{stubExecutor: newStubExecutor(ActionTypeMigrate, migrator.Execute), readyAfter: -1}))
+
+		require.NoError(t, te.Enforce(context.Background(), te.approve(t, migrateDecision("d-1", "wl-1"))))
+		te.waitForDecision(t, "d-1", types.DecisionStatusFailed)
+
+		// The failed verification compensates the step, moving the workload
+		// back where it was
+		status, err := te.GetEnforcementStatus(context.Background(), "d-1")
+		require.NoError(t, err)
+		assert.Equal(t, EnforcementStateFailed, status.Status)
+		assert.Contains(t, status.Steps[0].Error, "not ready")
+		assert.Empty(t, status.Compensations)
+		workload := storedWorkload(t, te.store, "wl-1")
+		assert.Equal(t, "cluster-a", workload.Labels["cluster"])
+		assert.Equal(t, "node-1", workload.Labels["node"])
+		assert.Equal(t, types.WorkloadStatusRunning, workload.Status)
+	})
+
+	t.Run("no gate when the executor cannot observe", func(t *testing.T) {
+		te := newTestEnforcer(t, cfg)
+		te.rules = evaluator.NewRuleEngine(testLogger{})
+		newTestWorkload(t, te.store, "wl-1")
+
+		steps, err := te.generateSteps(context.Background(), migrateDecision("d-1", "wl-1"), storedWorkload(t, te.store, "wl-1"))
+		require.NoError(t, err)
+		require.Len(t, steps, 1)
+		assert.Nil(t, steps[0].Gate)
+	})
+}
+
+func TestRunSteps_RetriesFailedStep(t *testing.T) {
+	cfg := config.EnforcementConfig{Steps: config.StepConfig{MaxRetries: 2, RetryInterval: time.Millisecond}}
+
+	failing := func(failures int) *stubExecutor {
+		attempts := 0
+		return newStubExecutor(ActionTypeMigrate, func(ctx context.Context, action *Action) (*ActionResult, error) {
+			attempts++
+			if attempts <= failures {
+				return &ActionResult{ActionType: action.Type, Error: "node not ready", Timestamp: time.Now()}, nil
+			}
+			return succeeded(action, nil), nil
+		})
+	}
+	retries := func(status *EnforcementStatus) int {
+		count := 0
+		for _, event := range status.Events {
+			if event.Type == "step_retrying" {
+				count++
+			}
+		}
+		return count
+	}
+
+	t.Run("succeeds within its retries", func(t *testing.T) {
+		te := newTestEnforcer(t, cfg)
+		newTestWorkload(t, te.store, "wl-1")
+		migrator := failing(2)
+		require.NoError(t, te.registry.Register(migrator))
+
+		require.NoError(t, te.Enforce(context.Background(), te.approve(t, migrateDecision("d-1", "wl-1"))))
+		te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+
+		status, err := te.GetEnforcementStatus(context.Background(), "d-1")
+		require.NoError(t, err)
+		require.Len(t, status.Steps, 1)
+		assert.Equal(t, EnforcementStateCompleted, status.Steps[0].State)
+		assert.Equal(t, 3, status.Steps[0].Attempts)
+		assert.Empty(t, status.Steps[0].Error)
+		assert.Equal(t, 2, retries(status))
+		assert.Len(t, migrator.executed(), 3)
+	})
+
+	t.Run("fails once its retries run out", func(t *testing.T) {
+		te := newTestEnforcer(t, cfg)
+		newTestWorkload(t, te.store, "wl-1")
+		migrator := failing(3)
+		require.NoError(t, te.registry.Register(migrator))
+
+		require.NoError(t, te.Enforce(context.Background(), te.approve(t, migrateDecision("d-1", "wl-1"))))
+		te.waitForDecision(t, "d-1", types.DecisionStatusFailed)
+
+		status, err := te.GetEnforcementStatus(context.Background(), "d-1")
+		require.NoError(t, err)
+		require.Len(t, status.Steps, 1)
+		assert.Equal(t, EnforcementStateFailed, status.Steps[0].State)
+		assert.Equal(t, 3, status.Steps[0].Attempts)
+		assert.Equal(t, "node not ready", status.Steps[0].Error)
+		assert.Equal(t, 2, retries(status))
+		assert.Len(t, migrator.executed(), 3)
+	})
+}
+
+func TestStepProgress(t *testing.T) {
+	steps := func(states ...EnforcementState) []types.EnforcementStep {
+		built := make([]types.EnforcementStep, len(states))
+		for i, state := range states {
+			built[i].State = state
+		}
+		return built
+	}
+
+	assert.Equal(t, 0.0, stepProgress(nil))
+	assert.Equal(t, 0.0, stepProgress(steps(EnforcementStatePending, EnforcementStateRunning)))
+	assert.InDelta(t, 100.0/3, stepProgress(steps(EnforcementStateCompleted, EnforcementStateRunning, EnforcementStatePending)), 1e-9)
+	assert.Equal(t, 50.0, stepProgress(steps(EnforcementStateCompleted, EnforcementStateFailed)))
+	assert.Equal(t, 100.0, stepProgress(steps(EnforcementStateCompleted, EnforcementStateCompleted)))
+}
+
+func TestRunSteps_ProgressCountsCompletedSteps(t *testing.T) {
+	ctx := context.Background()
+	te := newTestEnforcer(t, config.EnforcementConfig{})
+	newTestWorkload(t, te.store, "wl-1")
+	newTestWorkload(t, te.store, "wl-2")
+
+	// The move of wl-2 holds until released
+	release := make(chan struct{})
+	require.NoError(t, te.registry.Register(newStubExecutor(ActionTypeMigrate, func(ctx context.Context, action *Action) (*ActionResult, error) {
+		if action.Target == "wl-2" {
+			<-release
+		}
+		return succeeded(action, nil), nil
+	})))
+
+	require.NoError(t, te.Enforce(ctx, te.approve(t, consolidateDecision("d-1"))))
+	require.Eventually(t, func() bool {
+		status, err := te.GetEnforcementStatus(ctx, "d-1")
+		return err == nil && len(status.Steps) == 3 && status.Steps[0].State == EnforcementStateCompleted
+	}, 2*time.Second, 5*time.Millisecond)
+
+	status, err := te.GetEnforcementStatus(ctx, "d-1")
+	require.NoError(t, err)
+	assert.InDelta(t, 100.0/3, status.Progress, 1e-9)
+
+	close(release)
+	te.waitForDecision(t, "d-1", types.DecisionStatusCompleted)
+	status, err = te.GetEnforcementStatus(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, status.Progress)
+}
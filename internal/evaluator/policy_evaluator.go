@@ -311,29 +311,62 @@ func (e *policyEvaluator) evaluateAutomationPolicy(ctx context.Context, workload
 
 // evaluateWorkloadPriorityPolicy evaluates a workload priority policy
 func (e *policyEvaluator) evaluateWorkloadPriorityPolicy(ctx context.Context, workload *types.Workload, policy types.Policy, result *types.EvaluationResult) error {
-	// This is a simplified evaluation for workload priority policies
-
-	// Calculate priority score based on workload characteristics
-	priorityScore := e.calculatePriorityScore(workload, policy)
-	result.Score = priorityScore
+	priorityPolicy, ok := policy.(*types.WorkloadPriorityPolicy)
+	if !ok {
+		return fmt.Errorf("expected *types.WorkloadPriorityPolicy, got %T", policy)
+	}
 
-	// Check if workload priority matches policy recommendations
-	if workload.Priority != types.Priority(priorityScore*1000) {
-		recommendation := types.Recommendation{
-			Type:      "priority_adjustment",
-			Priority:  "medium",
-			Message:   "Consider adjusting workload priority",
-			Action:    "update_priority",
-			Impact:    "scheduling_efficiency",
-			Effort:    "low",
-			Timestamp: time.Now(),
-		}
-		result.Recommendations = append(result.Recommendations, recommendation)
+	resolution, err := ResolvePriorityClass(&priorityPolicy.Spec, workload)
+	if err != nil {
+		return fmt.Errorf("failed to resolve priority class: %w", err)
 	}
 
-	result.Metrics["priority_score"] = priorityScore
-	result.Metrics["current_priority"] = workload.Priority
 	result.Metrics["evaluation_type"] = "workload_priority"
+	result.Metrics["current_priority"] = workload.Priority
+	result.Metrics["priority_source"] = resolution.Source
+	result.Metrics["priority_reason"] = resolution.Reason
+	result.Metrics["matching_mappings"] = len(resolution.Candidates)
+
+	if resolution.PriorityClass == nil {
+		result.Score = 0.0
+		return nil
+	}
+
+	class := resolution.PriorityClass
+	result.Metrics["priority_class"] = class.Name
+	result.Metrics["priority_value"] = class.Value
+	if resolution.MatchedIndex >= 0 {
+		result.Metrics["matched_mapping"] = resolution.MatchedIndex
+		result.Metrics["matched_pattern"] = resolution.MatchedRule
+	}
+
+	if workload.Priority == types.Priority(class.Value) {
+		result.Score = 1.0
+		return nil
+	}
+
+	result.Score = 0.5
+	result.Recommendations = append(result.Recommendations, types.Recommendation{
+		Type:     "priority_adjustment",
+		Priority: "medium",
+		Message: fmt.Sprintf("Assign priority class %s (value %d) instead of current priority %d: %s",
+			class.Name, class.Value, workload.Priority, resolution.Reason),
+		Action: "update_priority",
+		Impact: "scheduling_efficiency",
+		Effort: "low",
+		Details: map[string]interface{}{
+			"priorityClass":    class.Name,
+			"priorityValue":    class.Value,
+			"preemptionPolicy": class.PreemptionPolicy,
+			"currentPriority":  workload.Priority,
+			"source":           resolution.Source,
+			"matchedMapping":   resolution.MatchedIndex,
+			"matchedPattern":   resolution.MatchedRule,
+			"reason":           resolution.Reason,
+			"candidates":       resolution.Candidates,
+		},
+		Timestamp: time.Now(),
+	})
 
 	return nil
 }
@@ -376,44 +409,6 @@ func (e *policyEvaluator) checkAutomationConditions(ctx context.Context, workloa
 	return true
 }
 
-// calculatePriorityScore calculates a priority score for a workload
-func (e *policyEvaluator) calculatePriorityScore(workload *types.Workload, policy types.Policy) float64 {
-
-	score := 0.5 // Base score
-
-	// Adjust based on workload type
-	switch workload.Type {
-	case types.WorkloadTypeRealTime:
-		score = 0.9 // Real-time workloads typically have high priority
-	case types.WorkloadTypeInference:
-		score = 0.8 // Inference workloads often need quick response
-	case types.WorkloadTypeMLTraining:
-		score = 0.6 // Training workloads can be lower priority
-	case types.WorkloadTypeBatch:
-		score = 0.3 // Batch workloads typically have low priority
-	default:
-		score = 0.5
-	}
-
-	// Adjust based on current priority
-	currentPriority := float64(workload.Priority)
-	if currentPriority > 500 {
-		score += 0.2
-	} else if currentPriority < 100 {
-		score -= 0.2
-	}
-
-	// Ensure score is between 0 and 1
-	if score > 1.0 {
-		score = 1.0
-	}
-	if score < 0.0 {
-		score = 0.0
-	}
-
-	return score
-}
-
 // validateCostOptimizationPolicy validates a cost optimization policy
 func (e *policyEvaluator) validateCostOptimizationPolicy(ctx context.Context, policy types.Policy) error {
 	// Basic validation for cost optimization policies
@@ -432,8 +427,10 @@ func (e *policyEvaluator) validateAutomationPolicy(ctx context.Context, policy t
 
 // validateWorkloadPriorityPolicy validates a workload priority policy
 func (e *policyEvaluator) validateWorkloadPriorityPolicy(ctx context.Context, policy types.Policy) error {
-	// Basic validation for workload priority policies
-	// In practice, this would validate the priority class definitions
+	priorityPolicy, ok := policy.(*types.WorkloadPriorityPolicy)
+	if !ok {
+		return fmt.Errorf("expected *types.WorkloadPriorityPolicy, got %T", policy)
+	}
 
-	return nil
+	return ValidateWorkloadPrioritySpec(&priorityPolicy.Spec)
 }
@@ -0,0 +1,378 @@
+package evaluator
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// Pattern match kinds, ordered from least to most specific
+const (
+	PatternKindRegex = "regex"
+	PatternKindGlob  = "glob"
+	PatternKindExact = "exact"
+)
+
+// Priority resolution sources
+const (
+	PrioritySourceMapping       = "mapping"
+	PrioritySourceDefaultClass  = "default_class"
+	PrioritySourceGlobalDefault = "global_default"
+	PrioritySourceNone          = "none"
+)
+
+// PriorityResolution describes how a workload was mapped to a priority class
+type PriorityResolution struct {
+	PriorityClass *types.PriorityClass `json:"priorityClass,omitempty"`
+	Source        string               `json:"source"`
+	MatchedIndex  int                  `json:"matchedIndex"`
+	MatchedRule   string               `json:"matchedRule,omitempty"`
+	Reason        string               `json:"reason"`
+	Candidates    []MappingMatch       `json:"candidates,omitempty"`
+}
+
+// MappingMatch describes a single workload mapping that matched a workload
+type MappingMatch struct {
+	Index         int    `json:"index"`
+	Pattern       string `json:"pattern"`
+	PriorityClass string `json:"priorityClass"`
+	Field         string `json:"field"`
+	Kind          string `json:"kind"`
+	Specificity   int    `json:"specificity"`
+}
+
+// workloadPattern is a parsed WorkloadMapping pattern
+//
+// Patterns take the form "[field:]expression". The field selects what is
+// matched and defaults to the workload name:
+//
+//	train-*                 glob over the workload name
+//	name:/^infer-[0-9]+$/   regex over the workload name
+//	type:inference          exact match on the workload type
+//	label:tier=gold*        glob over the value of label "tier"
+//	label:team              presence of label "team"
+//
+// An expression wrapped in slashes is a regular expression, an expression
+// containing *, ? or [ is a glob, and anything else is matched exactly.
+type workloadPattern struct {
+	field       string
+	labelKey    string
+	expr        string
+	kind        string
+	regex       *regexp.Regexp
+	specificity int
+}
+
+// Characters stripped from patterns to count their literal characters
+var (
+	regexMetacharacters = regexp.MustCompile(`[\\^$.|?*+()\[\]{}]`)
+	globWildcards       = regexp.MustCompile(`\[[^\]]*\]|[*?]`)
+)
+
+// ParseWorkloadPattern parses a workload mapping pattern
+func ParseWorkloadPattern(pattern string) (*workloadPattern, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil, fmt.Errorf("pattern cannot be empty")
+	}
+
+	p := &workloadPattern{field: "name", expr: pattern}
+
+	if idx := strings.Index(pattern, ":"); idx > 0 && !strings.HasPrefix(pattern, "/") {
+		switch field := pattern[:idx]; field {
+		case "name", "type":
+			p.field = field
+			p.expr = pattern[idx+1:]
+		case "label":
+			p.field = field
+			selector := pattern[idx+1:]
+			if eq := strings.Index(selector, "="); eq >= 0 {
+				p.labelKey = selector[:eq]
+				p.expr = selector[eq+1:]
+			} else {
+				p.labelKey = selector
+				p.expr = ""
+			}
+			if p.labelKey == "" {
+				return nil, fmt.Errorf("label pattern %q has no key", pattern)
+			}
+			if p.expr == "" {
+				p.kind = PatternKindExact
+				p.specificity = p.score()
+				return p, nil
+			}
+		}
+	}
+
+	if p.expr == "" {
+		return nil, fmt.Errorf("pattern %q has no expression", pattern)
+	}
+
+	switch {
+	case len(p.expr) >= 2 && strings.HasPrefix(p.expr, "/") && strings.HasSuffix(p.expr, "/"):
+		regex, err := regexp.Compile(p.expr[1 : len(p.expr)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex in pattern %q: %w", pattern, err)
+		}
+		p.kind = PatternKindRegex
+		p.regex = regex
+	case strings.ContainsAny(p.expr, "*?["):
+		if _, err := path.Match(p.expr, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob in pattern %q: %w", pattern, err)
+		}
+		p.kind = PatternKindGlob
+	default:
+		p.kind = PatternKindExact
+	}
+
+	p.specificity = p.score()
+	return p, nil
+}
+
+// Matches returns true if the workload matches the pattern
+func (p *workloadPattern) Matches(workload *types.Workload) bool {
+	var value string
+	switch p.field {
+	case "type":
+		value = string(workload.Type)
+	case "label":
+		labelValue, exists := workload.Labels[p.labelKey]
+		if !exists {
+			return false
+		}
+		if p.expr == "" {
+			return true
+		}
+		value = labelValue
+	default:
+		value = workload.Name
+	}
+
+	switch p.kind {
+	case PatternKindRegex:
+		return p.regex.MatchString(value)
+	case PatternKindGlob:
+		matched, _ := path.Match(p.expr, value)
+		return matched
+	default:
+		return value == p.expr
+	}
+}
+
+// Specificity scores how narrowly the pattern selects workloads. Exact
+// matches beat globs, globs beat regexes, and within a kind the pattern with
+// more literal characters wins.
+func (p *workloadPattern) Specificity() int {
+	return p.specificity
+}
+
+// score computes the specificity of a parsed pattern
+func (p *workloadPattern) score() int {
+	kindWeight := map[string]int{
+		PatternKindRegex: 1000,
+		PatternKindGlob:  2000,
+		PatternKindExact: 3000,
+	}[p.kind]
+
+	literal := p.expr
+	if p.kind == PatternKindRegex {
+		literal = regexMetacharacters.ReplaceAllString(literal, "")
+		literal = strings.Trim(literal, "/")
+	} else if p.kind == PatternKindGlob {
+		literal = globWildcards.ReplaceAllString(literal, "")
+	}
+
+	return kindWeight + len(literal) + len(p.labelKey)
+}
+
+// PriorityMap is a workload priority spec with its mapping patterns parsed
+// once, for resolving the priority classes of many workloads
+type PriorityMap struct {
+	spec     *types.WorkloadPrioritySpec
+	classes  map[string]*types.PriorityClass
+	patterns []*workloadPattern
+}
+
+// NewPriorityMap parses the workload mappings of a spec
+func NewPriorityMap(spec *types.WorkloadPrioritySpec) (*PriorityMap, error) {
+	m := &PriorityMap{
+		spec:     spec,
+		classes:  make(map[string]*types.PriorityClass, len(spec.PriorityClasses)),
+		patterns: make([]*workloadPattern, 0, len(spec.WorkloadMapping)),
+	}
+	for i := range spec.PriorityClasses {
+		m.classes[spec.PriorityClasses[i].Name] = &spec.PriorityClasses[i]
+	}
+	for i, mapping := range spec.WorkloadMapping {
+		pattern, err := ParseWorkloadPattern(mapping.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("workload mapping %d: %w", i, err)
+		}
+		m.patterns = append(m.patterns, pattern)
+	}
+	return m, nil
+}
+
+// ResolvePriorityClass resolves the priority class for a workload using the
+// spec's workload mappings. Specs resolved for many workloads should be
+// parsed once with NewPriorityMap.
+func ResolvePriorityClass(spec *types.WorkloadPrioritySpec, workload *types.Workload) (*PriorityResolution, error) {
+	m, err := NewPriorityMap(spec)
+	if err != nil {
+		return nil, err
+	}
+	return m.Resolve(workload)
+}
+
+// Resolve resolves the priority class for a workload using the workload
+// mappings, falling back to DefaultClass and then to the class marked
+// GlobalDefault. When several mappings match, the most specific one wins
+// and ties go to the mapping declared first.
+func (m *PriorityMap) Resolve(workload *types.Workload) (*PriorityResolution, error) {
+	spec, classes := m.spec, m.classes
+
+	resolution := &PriorityResolution{
+		Source:       PrioritySourceNone,
+		MatchedIndex: -1,
+	}
+
+	var winner *MappingMatch
+	for i, mapping := range spec.WorkloadMapping {
+		pattern := m.patterns[i]
+		if !pattern.Matches(workload) {
+			continue
+		}
+
+		match := MappingMatch{
+			Index:         i,
+			Pattern:       mapping.Pattern,
+			PriorityClass: mapping.PriorityClass,
+			Field:         pattern.field,
+			Kind:          pattern.kind,
+			Specificity:   pattern.Specificity(),
+		}
+		resolution.Candidates = append(resolution.Candidates, match)
+
+		if winner == nil || match.Specificity > winner.Specificity {
+			winner = &resolution.Candidates[len(resolution.Candidates)-1]
+		}
+	}
+
+	if winner != nil {
+		class, exists := classes[winner.PriorityClass]
+		if !exists {
+			return nil, fmt.Errorf("workload mapping %d references unknown priority class %q", winner.Index, winner.PriorityClass)
+		}
+		resolution.PriorityClass = class
+		resolution.Source = PrioritySourceMapping
+		resolution.MatchedIndex = winner.Index
+		resolution.MatchedRule = winner.Pattern
+		resolution.Reason = describeMappingWinner(winner, resolution.Candidates)
+		return resolution, nil
+	}
+
+	if spec.DefaultClass != "" {
+		class, exists := classes[spec.DefaultClass]
+		if !exists {
+			return nil, fmt.Errorf("default class %q is not a defined priority class", spec.DefaultClass)
+		}
+		resolution.PriorityClass = class
+		resolution.Source = PrioritySourceDefaultClass
+		resolution.Reason = fmt.Sprintf("no workload mapping matched; using defaultClass %q", class.Name)
+		return resolution, nil
+	}
+
+	for i := range spec.PriorityClasses {
+		if spec.PriorityClasses[i].GlobalDefault {
+			resolution.PriorityClass = &spec.PriorityClasses[i]
+			resolution.Source = PrioritySourceGlobalDefault
+			resolution.Reason = fmt.Sprintf("no workload mapping matched and no defaultClass set; using globalDefault class %q", spec.PriorityClasses[i].Name)
+			return resolution, nil
+		}
+	}
+
+	resolution.Reason = "no workload mapping matched and no default priority class is defined"
+	return resolution, nil
+}
+
+// describeMappingWinner explains why a mapping won over the other candidates
+func describeMappingWinner(winner *MappingMatch, candidates []MappingMatch) string {
+	if len(candidates) == 1 {
+		return fmt.Sprintf("workload mapping %d (%q) is the only mapping that matched", winner.Index, winner.Pattern)
+	}
+
+	var overridden []string
+	tied := false
+	for _, candidate := range candidates {
+		if candidate.Index == winner.Index {
+			continue
+		}
+		overridden = append(overridden, fmt.Sprintf("%d (%q)", candidate.Index, candidate.Pattern))
+		if candidate.Specificity == winner.Specificity {
+			tied = true
+		}
+	}
+
+	if tied {
+		return fmt.Sprintf("workload mapping %d (%q) won over mappings %s: equally specific, first declared wins",
+			winner.Index, winner.Pattern, strings.Join(overridden, ", "))
+	}
+	return fmt.Sprintf("workload mapping %d (%q) won over mappings %s: most specific %s match on %s",
+		winner.Index, winner.Pattern, strings.Join(overridden, ", "), winner.Kind, winner.Field)
+}
+
+// ValidateWorkloadPrioritySpec checks that priority classes are unique, at
+// most one is the global default, and every mapping and the default class
+// reference a defined class with a well-formed pattern
+func ValidateWorkloadPrioritySpec(spec *types.WorkloadPrioritySpec) error {
+	classes := make(map[string]bool, len(spec.PriorityClasses))
+	globalDefaults := 0
+
+	for i, class := range spec.PriorityClasses {
+		if class.Name == "" {
+			return fmt.Errorf("priority class %d has no name", i)
+		}
+		if classes[class.Name] {
+			return fmt.Errorf("priority class %q is defined more than once", class.Name)
+		}
+		classes[class.Name] = true
+
+		if class.GlobalDefault {
+			globalDefaults++
+		}
+
+		switch class.PreemptionPolicy {
+		case "", PreemptionPolicyLowerPriority, PreemptionPolicyNever:
+		default:
+			return fmt.Errorf("priority class %q has invalid preemptionPolicy %q", class.Name, class.PreemptionPolicy)
+		}
+	}
+
+	if globalDefaults > 1 {
+		return fmt.Errorf("only one priority class can be globalDefault, found %d", globalDefaults)
+	}
+
+	if spec.DefaultClass != "" && !classes[spec.DefaultClass] {
+		return fmt.Errorf("default class %q is not a defined priority class", spec.DefaultClass)
+	}
+
+	for i, mapping := range spec.WorkloadMapping {
+		if _, err := ParseWorkloadPattern(mapping.Pattern); err != nil {
+			return fmt.Errorf("workload mapping %d: %w", i, err)
+		}
+		if !classes[mapping.PriorityClass] {
+			return fmt.Errorf("workload mapping %d references unknown priority class %q", i, mapping.PriorityClass)
+		}
+	}
+
+	return nil
+}
+
+// Preemption policies supported by priority classes
+const (
+	PreemptionPolicyLowerPriority = "PreemptLowerPriority"
+	PreemptionPolicyNever         = "Never"
+)
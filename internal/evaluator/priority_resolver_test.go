@@ -0,0 +1,170 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+func TestParseWorkloadPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		field    string
+		labelKey string
+		expr     string
+		kind     string
+	}{
+		{"train-*", "name", "", "train-*", PatternKindGlob},
+		{"name:/^infer-[0-9]+$/", "name", "", "/^infer-[0-9]+$/", PatternKindRegex},
+		{"/^infer-[0-9]+$/", "name", "", "/^infer-[0-9]+$/", PatternKindRegex},
+		{"type:inference", "type", "", "inference", PatternKindExact},
+		{"label:tier=gold*", "label", "tier", "gold*", PatternKindGlob},
+		{"label:team", "label", "team", "", PatternKindExact},
+		{"batch-job", "name", "", "batch-job", PatternKindExact},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			pattern, err := ParseWorkloadPattern(tt.pattern)
+			require.NoError(t, err)
+			assert.Equal(t, tt.field, pattern.field)
+			assert.Equal(t, tt.labelKey, pattern.labelKey)
+			assert.Equal(t, tt.expr, pattern.expr)
+			assert.Equal(t, tt.kind, pattern.kind)
+		})
+	}
+
+	for _, invalid := range []string{"", "  ", "label:=gold", "name:", "/[a-/", "train-[a"} {
+		_, err := ParseWorkloadPattern(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestWorkloadPattern_Matches(t *testing.T) {
+	workload := &types.Workload{
+		Name:   "infer-42",
+		Type:   types.WorkloadTypeInference,
+		Labels: map[string]string{"tier": "gold-plus"},
+	}
+
+	for pattern, want := range map[string]bool{
+		"infer-*":               true,
+		"train-*":               false,
+		"name:/^infer-[0-9]+$/": true,
+		"type:inference":        true,
+		"type:training":         false,
+		"label:tier=gold*":      true,
+		"label:tier=silver":     false,
+		"label:tier":            true,
+		"label:team":            false,
+	} {
+		parsed, err := ParseWorkloadPattern(pattern)
+		require.NoError(t, err)
+		assert.Equal(t, want, parsed.Matches(workload), pattern)
+	}
+}
+
+func newPrioritySpec(mappings ...types.WorkloadMapping) *types.WorkloadPrioritySpec {
+	return &types.WorkloadPrioritySpec{
+		PriorityClasses: []types.PriorityClass{
+			{Name: "critical", Value: 1000},
+			{Name: "high", Value: 500},
+			{Name: "normal", Value: 100, GlobalDefault: true},
+			{Name: "low", Value: 10},
+		},
+		WorkloadMapping: mappings,
+	}
+}
+
+func TestResolvePriorityClass_ExactBeatsGlobBeatsRegex(t *testing.T) {
+	workload := &types.Workload{Name: "infer-42"}
+
+	// Declared least specific first, so only specificity can pick the winner
+	spec := newPrioritySpec(
+		types.WorkloadMapping{Pattern: "/^infer-.*$/", PriorityClass: "low"},
+		types.WorkloadMapping{Pattern: "infer-*", PriorityClass: "normal"},
+		types.WorkloadMapping{Pattern: "infer-42", PriorityClass: "critical"},
+	)
+	resolution, err := ResolvePriorityClass(spec, workload)
+	require.NoError(t, err)
+	assert.Equal(t, "critical", resolution.PriorityClass.Name)
+	assert.Equal(t, PrioritySourceMapping, resolution.Source)
+	assert.Equal(t, 2, resolution.MatchedIndex)
+	assert.Len(t, resolution.Candidates, 3)
+
+	spec.WorkloadMapping = spec.WorkloadMapping[:2]
+	resolution, err = ResolvePriorityClass(spec, workload)
+	require.NoError(t, err)
+	assert.Equal(t, "normal", resolution.PriorityClass.Name, "glob beats regex")
+
+	// Within a kind the pattern with more literal characters wins
+	spec.WorkloadMapping = []types.WorkloadMapping{
+		{Pattern: "inf*", PriorityClass: "low"},
+		{Pattern: "infer-4*", PriorityClass: "high"},
+	}
+	resolution, err = ResolvePriorityClass(spec, workload)
+	require.NoError(t, err)
+	assert.Equal(t, "high", resolution.PriorityClass.Name)
+
+	// Equally specific mappings go to the one declared first
+	spec.WorkloadMapping = []types.WorkloadMapping{
+		{Pattern: "infer-*", PriorityClass: "high"},
+		{Pattern: "*fer-42", PriorityClass: "low"},
+	}
+	resolution, err = ResolvePriorityClass(spec, workload)
+	require.NoError(t, err)
+	assert.Equal(t, "high", resolution.PriorityClass.Name)
+	assert.Contains(t, resolution.Reason, "first declared wins")
+}
+
+func TestResolvePriorityClass_Fallbacks(t *testing.T) {
+	workload := &types.Workload{Name: "batch-1"}
+	spec := newPrioritySpec(types.WorkloadMapping{Pattern: "train-*", PriorityClass: "high"})
+
+	resolution, err := ResolvePriorityClass(spec, workload)
+	require.NoError(t, err)
+	assert.Equal(t, PrioritySourceGlobalDefault, resolution.Source)
+	assert.Equal(t, "normal", resolution.PriorityClass.Name)
+
+	spec.DefaultClass = "low"
+	resolution, err = ResolvePriorityClass(spec, workload)
+	require.NoError(t, err)
+	assert.Equal(t, PrioritySourceDefaultClass, resolution.Source)
+	assert.Equal(t, "low", resolution.PriorityClass.Name)
+
+	spec.DefaultClass = ""
+	spec.PriorityClasses[2].GlobalDefault = false
+	resolution, err = ResolvePriorityClass(spec, workload)
+	require.NoError(t, err)
+	assert.Equal(t, PrioritySourceNone, resolution.Source)
+	assert.Nil(t, resolution.PriorityClass)
+
+	spec.WorkloadMapping = []types.WorkloadMapping{{Pattern: "batch-*", PriorityClass: "missing"}}
+	_, err = ResolvePriorityClass(spec, workload)
+	assert.Error(t, err)
+}
+
+func TestPriorityMap_ResolvesManyWorkloads(t *testing.T) {
+	spec := newPrioritySpec(
+		types.WorkloadMapping{Pattern: "type:batch", PriorityClass: "low"},
+		types.WorkloadMapping{Pattern: "label:tier=gold", PriorityClass: "critical"},
+	)
+	priorities, err := NewPriorityMap(spec)
+	require.NoError(t, err)
+
+	for name, want := range map[string]string{"gold": "critical", "bronze": "low"} {
+		resolution, err := priorities.Resolve(&types.Workload{
+			Name:   name,
+			Type:   types.WorkloadTypeBatch,
+			Labels: map[string]string{"tier": name},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, want, resolution.PriorityClass.Name, name)
+	}
+
+	spec.WorkloadMapping = append(spec.WorkloadMapping, types.WorkloadMapping{Pattern: "/[/", PriorityClass: "low"})
+	_, err = NewPriorityMap(spec)
+	assert.Error(t, err)
+}
@@ -374,7 +374,7 @@ func ParseMemoryString(memoryStr string) (int64, error) {
 	}
 
 	// Regular expression to match memory format
-	re := regexp.MustCompile(`^(\d+)([KMGTPE]?i?)$`)
+	re := regexp.MustCompile(`^(\d+)([KMGTPE]?I?)$`)
 	matches := re.FindStringSubmatch(strings.ToUpper(memoryStr))
 
 	if len(matches) != 3 {
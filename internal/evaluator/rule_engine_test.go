@@ -0,0 +1,32 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMemoryString(t *testing.T) {
+	tests := map[string]int64{
+		"":      0,
+		"100":   100,
+		"1Ki":   1024,
+		"512Mi": 512 << 20,
+		"1Gi":   1 << 30,
+		"2gi":   2 << 30,
+		"1Ti":   1 << 40,
+		"2G":    2 * 1000 * 1000 * 1000,
+		"5k":    5000,
+	}
+	for memory, want := range tests {
+		got, err := ParseMemoryString(memory)
+		require.NoError(t, err, memory)
+		assert.Equal(t, want, got, memory)
+	}
+
+	for _, invalid := range []string{"1GB", "Gi", "1.5Gi", "-1Gi", "1Xi"} {
+		_, err := ParseMemoryString(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,140 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// bufferSize is how many events may wait for delivery before Publish
+// starts dropping them
+const bufferSize = 1024
+
+// subscription is a handler subscribed to the bus
+type subscription struct {
+	id      string
+	handler Handler
+}
+
+// bus implements Bus interface
+type bus struct {
+	queue         chan *Event
+	subscriptions []subscription
+	published     uint64
+	subscribed    uint64
+	closed        bool
+	done          chan struct{}
+	mu            sync.RWMutex
+	logger        types.Logger
+}
+
+// NewBus creates a new event bus and starts delivering events
+func NewBus(logger types.Logger) Bus {
+	b := &bus{
+		queue:  make(chan *Event, bufferSize),
+		done:   make(chan struct{}),
+		logger: logger,
+	}
+	go b.deliver()
+	return b
+}
+
+// Publish queues an event without blocking. Events published while the
+// queue is full or after the bus is closed are dropped.
+func (b *bus) Publish(ctx context.Context, event *Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		b.logger.Warn("dropped event published after the bus closed", "event_type", event.Type)
+		return
+	}
+
+	b.published++
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	if event.ID == "" {
+		event.ID = fmt.Sprintf("event-%d-%d", event.Timestamp.UnixNano(), b.published)
+	}
+
+	select {
+	case b.queue <- event:
+	default:
+		b.logger.Warn("dropped event, delivery queue is full", "event_id", event.ID, "event_type", event.Type)
+	}
+}
+
+// Subscribe registers a handler
+func (b *bus) Subscribe(handler Handler) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribed++
+	id := fmt.Sprintf("subscription-%d", b.subscribed)
+	b.subscriptions = append(b.subscriptions, subscription{id: id, handler: handler})
+	return id
+}
+
+// Unsubscribe removes a handler
+func (b *bus) Unsubscribe(subscriptionID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, sub := range b.subscriptions {
+		if sub.id == subscriptionID {
+			b.subscriptions = append(b.subscriptions[:i:i], b.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
+
+// Close stops accepting events and waits for the queued ones to be
+// delivered
+func (b *bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.queue)
+	b.mu.Unlock()
+
+	<-b.done
+}
+
+// Health checks the health of the bus
+func (b *bus) Health(ctx context.Context) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return fmt.Errorf("event bus is closed")
+	}
+	return nil
+}
+
+// deliver hands queued events to the subscribed handlers one at a time
+func (b *bus) deliver() {
+	defer close(b.done)
+
+	for event := range b.queue {
+		b.mu.RLock()
+		subscriptions := b.subscriptions
+		b.mu.RUnlock()
+
+		for _, sub := range subscriptions {
+			if !sub.handler.CanHandle(event.Type) {
+				continue
+			}
+			if err := sub.handler.HandleEvent(context.Background(), event); err != nil {
+				b.logger.Warn("event handler failed", "subscription_id", sub.id, "event_id", event.ID,
+					"event_type", event.Type, "error", err)
+			}
+		}
+	}
+}
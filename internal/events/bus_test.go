@@ -0,0 +1,144 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// testLogger discards log output
+type testLogger struct{}
+
+func (l testLogger) Info(msg string, fields ...interface{})                    {}
+func (l testLogger) Warn(msg string, fields ...interface{})                    {}
+func (l testLogger) Error(msg string, fields ...interface{})                   {}
+func (l testLogger) Debug(msg string, fields ...interface{})                   {}
+func (l testLogger) Fatal(msg string, fields ...interface{})                   {}
+func (l testLogger) WithError(err error) types.Logger                          { return l }
+func (l testLogger) WithDuration(duration time.Duration) types.Logger          { return l }
+func (l testLogger) WithPolicy(policyID, policyName string) types.Logger       { return l }
+func (l testLogger) WithWorkload(workloadID, workloadType string) types.Logger { return l }
+func (l testLogger) WithEvaluation(evaluationID string) types.Logger           { return l }
+
+// recordingHandler records the events of the types it listens for
+type recordingHandler struct {
+	pattern string
+	events  []*Event
+}
+
+func (h *recordingHandler) HandleEvent(ctx context.Context, event *Event) error {
+	h.events = append(h.events, event)
+	return nil
+}
+
+func (h *recordingHandler) CanHandle(eventType string) bool {
+	return MatchesType(h.pattern, eventType)
+}
+
+func (h *recordingHandler) Health(ctx context.Context) error { return nil }
+
+func (h *recordingHandler) types() []string {
+	var eventTypes []string
+	for _, event := range h.events {
+		eventTypes = append(eventTypes, event.Type)
+	}
+	return eventTypes
+}
+
+func TestMatchesType(t *testing.T) {
+	assert.True(t, MatchesType("workload.created", "workload.created"))
+	assert.True(t, MatchesType("workload.*", "workload.failed"))
+	assert.True(t, MatchesType("*", "decision.created"))
+	assert.False(t, MatchesType("workload.*", "workloads.created"))
+	assert.False(t, MatchesType("workload.created", "workload.updated"))
+	assert.False(t, MatchesType("", "workload.created"))
+}
+
+func TestBus_DeliversToSubscribedHandlers(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus(testLogger{})
+
+	workloads := &recordingHandler{pattern: "workload.*"}
+	policies := &recordingHandler{pattern: EventTypePolicyCreated}
+	b.Subscribe(workloads)
+	id := b.Subscribe(policies)
+
+	b.Publish(ctx, &Event{Type: EventTypeWorkloadCreated})
+	b.Publish(ctx, &Event{Type: EventTypePolicyCreated})
+	b.Publish(ctx, &Event{Type: EventTypeWorkloadFailed})
+	b.Close()
+
+	assert.Equal(t, []string{EventTypeWorkloadCreated, EventTypeWorkloadFailed}, workloads.types())
+	require.Len(t, policies.events, 1)
+	assert.NotEmpty(t, policies.events[0].ID)
+	assert.False(t, policies.events[0].Timestamp.IsZero())
+	assert.Error(t, b.Health(ctx))
+
+	// Publishing after close is dropped rather than panicking
+	b.Unsubscribe(id)
+	b.Publish(ctx, &Event{Type: EventTypePolicyCreated})
+	assert.Len(t, policies.events, 1)
+}
+
+func TestPublishingStorage_PublishesWrites(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus(testLogger{})
+	recorder := &recordingHandler{pattern: "*"}
+	b.Subscribe(recorder)
+	store := NewPublishingStorage(memory.NewStorageManager(), b)
+
+	workload := &types.Workload{
+		ID:     "wl-1",
+		Name:   "trainer",
+		Type:   types.WorkloadTypeMLTraining,
+		Status: types.WorkloadStatusRunning,
+		Labels: map[string]string{"team": "ml"},
+	}
+	require.NoError(t, store.Workload().Create(ctx, workload))
+	workload.Status = types.WorkloadStatusFailed
+	require.NoError(t, store.Workload().Update(ctx, workload))
+	require.NoError(t, store.Workload().Update(ctx, workload))
+	require.NoError(t, store.Workload().Delete(ctx, "wl-1"))
+
+	decision := &types.Decision{
+		ID:         "d-1",
+		Type:       types.DecisionTypeMigrate,
+		Status:     types.DecisionStatusExecuting,
+		WorkloadID: "wl-1",
+		PolicyID:   "policy-1",
+	}
+	require.NoError(t, store.Decision().Create(ctx, decision))
+	decision.Status = types.DecisionStatusCompleted
+	require.NoError(t, store.Decision().Update(ctx, decision))
+
+	// Failed writes publish nothing
+	assert.Error(t, store.Workload().Update(ctx, &types.Workload{ID: "missing"}))
+	b.Close()
+
+	assert.Equal(t, []string{
+		EventTypeWorkloadCreated,
+		EventTypeWorkloadUpdated,
+		EventTypeWorkloadFailed,
+		EventTypeWorkloadUpdated,
+		EventTypeWorkloadDeleted,
+		EventTypeDecisionCreated,
+		EventTypeDecisionUpdated,
+		EventTypeDecisionCompleted,
+	}, recorder.types())
+
+	failed := recorder.events[2]
+	assert.Equal(t, storageSource, failed.Source)
+	assert.Equal(t, "running", failed.Data["previousStatus"])
+	payload := failed.Data["workload"].(map[string]interface{})
+	assert.Equal(t, "failed", payload["status"])
+	assert.Equal(t, "ml", payload["labels"].(map[string]interface{})["team"])
+	assert.NotContains(t, recorder.events[3].Data, "previousStatus")
+	assert.Equal(t, "wl-1", recorder.events[4].Data["workloadId"])
+	assert.Equal(t, "executing", recorder.events[7].Data["previousStatus"])
+}
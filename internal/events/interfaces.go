@@ -0,0 +1,84 @@
+package events
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Bus delivers events published in this process to the handlers
+// subscribed to them. Events are delivered in the order they were
+// published, after Publish returns.
+type Bus interface {
+	// Publish queues an event for delivery to every handler that can
+	// handle its type
+	Publish(ctx context.Context, event *Event)
+
+	// Subscribe registers a handler and returns the ID to unsubscribe it with
+	Subscribe(handler Handler) string
+
+	// Unsubscribe removes a handler
+	Unsubscribe(subscriptionID string)
+
+	// Close stops delivering events
+	Close()
+
+	// Health checks the health of the bus
+	Health(ctx context.Context) error
+}
+
+// Handler handles the events it subscribes to
+type Handler interface {
+	// HandleEvent handles an event
+	HandleEvent(ctx context.Context, event *Event) error
+
+	// CanHandle checks if this handler can handle the event type
+	CanHandle(eventType string) bool
+
+	// Health checks the health of the event handler
+	Health(ctx context.Context) error
+}
+
+// Event is something that happened in the policy engine
+type Event struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	Source    string                 `json:"source"`
+	Data      map[string]interface{} `json:"data"`
+	Timestamp time.Time              `json:"timestamp"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Event types
+const (
+	EventTypeWorkloadCreated   = "workload.created"
+	EventTypeWorkloadUpdated   = "workload.updated"
+	EventTypeWorkloadDeleted   = "workload.deleted"
+	EventTypeWorkloadCompleted = "workload.completed"
+	EventTypeWorkloadFailed    = "workload.failed"
+
+	EventTypePolicyCreated = "policy.created"
+	EventTypePolicyUpdated = "policy.updated"
+	EventTypePolicyDeleted = "policy.deleted"
+
+	EventTypeDecisionCreated   = "decision.created"
+	EventTypeDecisionUpdated   = "decision.updated"
+	EventTypeDecisionDeleted   = "decision.deleted"
+	EventTypeDecisionCompleted = "decision.completed"
+	EventTypeDecisionFailed    = "decision.failed"
+
+	EventTypeSchedule = "schedule"
+	EventTypeManual   = "manual"
+)
+
+// MatchesType returns true if an event type matches a pattern, which is
+// either an exact type, a family such as "workload.*" or "*"
+func MatchesType(pattern, eventType string) bool {
+	if pattern == "*" || pattern == eventType {
+		return true
+	}
+	if family, ok := strings.CutSuffix(pattern, ".*"); ok {
+		return strings.HasPrefix(eventType, family+".")
+	}
+	return false
+}
@@ -0,0 +1,415 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// storageSource is the source of events published by the storage decorator
+const storageSource = "storage"
+
+// publishingStorage wraps a storage manager so that every writer, the API
+// handlers included, publishes workload, policy and decision events
+type publishingStorage struct {
+	storage.StorageManager
+	workloads storage.WorkloadStore
+	policies  storage.PolicyStore
+	decisions storage.DecisionStore
+}
+
+// NewPublishingStorage wraps a storage manager so that successful writes of
+// workloads, policies and decisions are published on the bus
+func NewPublishingStorage(manager storage.StorageManager, bus Bus) storage.StorageManager {
+	return &publishingStorage{
+		StorageManager: manager,
+		workloads:      &publishingWorkloadStore{WorkloadStore: manager.Workload(), bus: bus, statuses: newStatusCache()},
+		policies:       &publishingPolicyStore{PolicyStore: manager.Policy(), bus: bus},
+		decisions:      &publishingDecisionStore{DecisionStore: manager.Decision(), bus: bus, statuses: newStatusCache()},
+	}
+}
+
+// Workload returns the publishing workload store
+func (s *publishingStorage) Workload() storage.WorkloadStore {
+	return s.workloads
+}
+
+// Policy returns the publishing policy store
+func (s *publishingStorage) Policy() storage.PolicyStore {
+	return s.policies
+}
+
+// Decision returns the publishing decision store
+func (s *publishingStorage) Decision() storage.DecisionStore {
+	return s.decisions
+}
+
+// publishingWorkloadStore publishes workload events
+type publishingWorkloadStore struct {
+	storage.WorkloadStore
+	bus      Bus
+	statuses *statusCache
+}
+
+// Create creates a workload and publishes workload.created
+func (s *publishingWorkloadStore) Create(ctx context.Context, workload *types.Workload) error {
+	if err := s.WorkloadStore.Create(ctx, workload); err != nil {
+		return err
+	}
+	s.created(ctx, workload)
+	return nil
+}
+
+// Update updates a workload and publishes workload.updated, followed by
+// workload.completed or workload.failed when it reaches that status
+func (s *publishingWorkloadStore) Update(ctx context.Context, workload *types.Workload) error {
+	if err := s.WorkloadStore.Update(ctx, workload); err != nil {
+		return err
+	}
+	s.updated(ctx, workload)
+	return nil
+}
+
+// Delete deletes a workload and publishes workload.deleted
+func (s *publishingWorkloadStore) Delete(ctx context.Context, id string) error {
+	workload, _ := s.WorkloadStore.Get(ctx, id)
+	if err := s.WorkloadStore.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.deleted(ctx, id, workload)
+	return nil
+}
+
+// CreateMany creates workloads and publishes workload.created for each
+func (s *publishingWorkloadStore) CreateMany(ctx context.Context, workloads []*types.Workload) error {
+	if err := s.WorkloadStore.CreateMany(ctx, workloads); err != nil {
+		return err
+	}
+	for _, workload := range workloads {
+		s.created(ctx, workload)
+	}
+	return nil
+}
+
+// UpdateMany updates workloads and publishes their events
+func (s *publishingWorkloadStore) UpdateMany(ctx context.Context, workloads []*types.Workload) error {
+	if err := s.WorkloadStore.UpdateMany(ctx, workloads); err != nil {
+		return err
+	}
+	for _, workload := range workloads {
+		s.updated(ctx, workload)
+	}
+	return nil
+}
+
+// DeleteMany deletes workloads and publishes workload.deleted for each
+func (s *publishingWorkloadStore) DeleteMany(ctx context.Context, ids []string) error {
+	workloads := make([]*types.Workload, len(ids))
+	for i, id := range ids {
+		workloads[i], _ = s.WorkloadStore.Get(ctx, id)
+	}
+	if err := s.WorkloadStore.DeleteMany(ctx, ids); err != nil {
+		return err
+	}
+	for i, id := range ids {
+		s.deleted(ctx, id, workloads[i])
+	}
+	return nil
+}
+
+func (s *publishingWorkloadStore) created(ctx context.Context, workload *types.Workload) {
+	s.statuses.set(workload.ID, string(workload.Status))
+	publish(ctx, s.bus, EventTypeWorkloadCreated, workloadData(workload))
+}
+
+func (s *publishingWorkloadStore) updated(ctx context.Context, workload *types.Workload) {
+	data := workloadData(workload)
+	previous, changed := s.statuses.swap(workload.ID, string(workload.Status))
+	if changed {
+		data["previousStatus"] = previous
+	}
+	publish(ctx, s.bus, EventTypeWorkloadUpdated, data)
+
+	if !changed {
+		return
+	}
+	switch workload.Status {
+	case types.WorkloadStatusCompleted:
+		publish(ctx, s.bus, EventTypeWorkloadCompleted, data)
+	case types.WorkloadStatusFailed:
+		publish(ctx, s.bus, EventTypeWorkloadFailed, data)
+	}
+}
+
+func (s *publishingWorkloadStore) deleted(ctx context.Context, id string, workload *types.Workload) {
+	s.statuses.delete(id)
+	data := map[string]interface{}{"workloadId": id}
+	if workload != nil {
+		data = workloadData(workload)
+	}
+	publish(ctx, s.bus, EventTypeWorkloadDeleted, data)
+}
+
+// publishingPolicyStore publishes policy events
+type publishingPolicyStore struct {
+	storage.PolicyStore
+	bus Bus
+}
+
+// Create creates a policy and publishes policy.created
+func (s *publishingPolicyStore) Create(ctx context.Context, policy types.Policy) error {
+	if err := s.PolicyStore.Create(ctx, policy); err != nil {
+		return err
+	}
+	publish(ctx, s.bus, EventTypePolicyCreated, policyData(policy))
+	return nil
+}
+
+// Update updates a policy and publishes policy.updated
+func (s *publishingPolicyStore) Update(ctx context.Context, policy types.Policy) error {
+	if err := s.PolicyStore.Update(ctx, policy); err != nil {
+		return err
+	}
+	publish(ctx, s.bus, EventTypePolicyUpdated, policyData(policy))
+	return nil
+}
+
+// Delete deletes a policy and publishes policy.deleted
+func (s *publishingPolicyStore) Delete(ctx context.Context, id string) error {
+	policy, _ := s.PolicyStore.Get(ctx, id)
+	if err := s.PolicyStore.Delete(ctx, id); err != nil {
+		return err
+	}
+	publish(ctx, s.bus, EventTypePolicyDeleted, deletedPolicyData(id, policy))
+	return nil
+}
+
+// CreateMany creates policies and publishes policy.created for each
+func (s *publishingPolicyStore) CreateMany(ctx context.Context, policies []types.Policy) error {
+	if err := s.PolicyStore.CreateMany(ctx, policies); err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		publish(ctx, s.bus, EventTypePolicyCreated, policyData(policy))
+	}
+	return nil
+}
+
+// UpdateMany updates policies and publishes policy.updated for each
+func (s *publishingPolicyStore) UpdateMany(ctx context.Context, policies []types.Policy) error {
+	if err := s.PolicyStore.UpdateMany(ctx, policies); err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		publish(ctx, s.bus, EventTypePolicyUpdated, policyData(policy))
+	}
+	return nil
+}
+
+// DeleteMany deletes policies and publishes policy.deleted for each
+func (s *publishingPolicyStore) DeleteMany(ctx context.Context, ids []string) error {
+	policies := make([]types.Policy, len(ids))
+	for i, id := range ids {
+		policies[i], _ = s.PolicyStore.Get(ctx, id)
+	}
+	if err := s.PolicyStore.DeleteMany(ctx, ids); err != nil {
+		return err
+	}
+	for i, id := range ids {
+		publish(ctx, s.bus, EventTypePolicyDeleted, deletedPolicyData(id, policies[i]))
+	}
+	return nil
+}
+
+// publishingDecisionStore publishes decision events
+type publishingDecisionStore struct {
+	storage.DecisionStore
+	bus      Bus
+	statuses *statusCache
+}
+
+// Create creates a decision and publishes decision.created
+func (s *publishingDecisionStore) Create(ctx context.Context, decision *types.Decision) error {
+	if err := s.DecisionStore.Create(ctx, decision); err != nil {
+		return err
+	}
+	s.created(ctx, decision)
+	return nil
+}
+
+// Update updates a decision and publishes decision.updated, followed by
+// decision.completed or decision.failed when it reaches that status
+func (s *publishingDecisionStore) Update(ctx context.Context, decision *types.Decision) error {
+	if err := s.DecisionStore.Update(ctx, decision); err != nil {
+		return err
+	}
+	s.updated(ctx, decision)
+	return nil
+}
+
+// Delete deletes a decision and publishes decision.deleted
+func (s *publishingDecisionStore) Delete(ctx context.Context, id string) error {
+	decision, _ := s.DecisionStore.Get(ctx, id)
+	if err := s.DecisionStore.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.deleted(ctx, id, decision)
+	return nil
+}
+
+// CreateMany creates decisions and publishes decision.created for each
+func (s *publishingDecisionStore) CreateMany(ctx context.Context, decisions []*types.Decision) error {
+	if err := s.DecisionStore.CreateMany(ctx, decisions); err != nil {
+		return err
+	}
+	for _, decision := range decisions {
+		s.created(ctx, decision)
+	}
+	return nil
+}
+
+// UpdateMany updates decisions and publishes their events
+func (s *publishingDecisionStore) UpdateMany(ctx context.Context, decisions []*types.Decision) error {
+	if err := s.DecisionStore.UpdateMany(ctx, decisions); err != nil {
+		return err
+	}
+	for _, decision := range decisions {
+		s.updated(ctx, decision)
+	}
+	return nil
+}
+
+// DeleteMany deletes decisions and publishes decision.deleted for each
+func (s *publishingDecisionStore) DeleteMany(ctx context.Context, ids []string) error {
+	decisions := make([]*types.Decision, len(ids))
+	for i, id := range ids {
+		decisions[i], _ = s.DecisionStore.Get(ctx, id)
+	}
+	if err := s.DecisionStore.DeleteMany(ctx, ids); err != nil {
+		return err
+	}
+	for i, id := range ids {
+		s.deleted(ctx, id, decisions[i])
+	}
+	return nil
+}
+
+func (s *publishingDecisionStore) created(ctx context.Context, decision *types.Decision) {
+	s.statuses.set(decision.ID, string(decision.Status))
+	publish(ctx, s.bus, EventTypeDecisionCreated, decisionData(decision))
+}
+
+func (s *publishingDecisionStore) updated(ctx context.Context, decision *types.Decision) {
+	data := decisionData(decision)
+	previous, changed := s.statuses.swap(decision.ID, string(decision.Status))
+	if changed {
+		data["previousStatus"] = previous
+	}
+	publish(ctx, s.bus, EventTypeDecisionUpdated, data)
+
+	if !changed {
+		return
+	}
+	switch decision.Status {
+	case types.DecisionStatusCompleted:
+		publish(ctx, s.bus, EventTypeDecisionCompleted, data)
+	case types.DecisionStatusFailed:
+		publish(ctx, s.bus, EventTypeDecisionFailed, data)
+	}
+}
+
+func (s *publishingDecisionStore) deleted(ctx context.Context, id string, decision *types.Decision) {
+	s.statuses.delete(id)
+	data := map[string]interface{}{"decisionId": id}
+	if decision != nil {
+		data = decisionData(decision)
+	}
+	publish(ctx, s.bus, EventTypeDecisionDeleted, data)
+}
+
+// statusCache remembers the last status written for each entity. The
+// stores may hand out the pointers they keep, so the previous status
+// cannot be read back from them.
+type statusCache struct {
+	statuses map[string]string
+	mu       sync.Mutex
+}
+
+func newStatusCache() *statusCache {
+	return &statusCache{statuses: make(map[string]string)}
+}
+
+func (c *statusCache) set(id, status string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statuses[id] = status
+}
+
+// swap records a status and returns the previous one and whether it
+// changed. An entity seen for the first time has not changed.
+func (c *statusCache) swap(id, status string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous, known := c.statuses[id]
+	c.statuses[id] = status
+	return previous, known && previous != status
+}
+
+func (c *statusCache) delete(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.statuses, id)
+}
+
+// publish publishes a storage event
+func publish(ctx context.Context, bus Bus, eventType string, data map[string]interface{}) {
+	bus.Publish(ctx, &Event{Type: eventType, Source: storageSource, Data: data})
+}
+
+func workloadData(workload *types.Workload) map[string]interface{} {
+	return map[string]interface{}{
+		"workloadId": workload.ID,
+		"workload":   toMap(workload),
+	}
+}
+
+func policyData(policy types.Policy) map[string]interface{} {
+	return map[string]interface{}{
+		"policyName": policy.GetMetadata().Name,
+		"policyType": string(policy.GetType()),
+		"policy":     toMap(policy),
+	}
+}
+
+func deletedPolicyData(id string, policy types.Policy) map[string]interface{} {
+	data := map[string]interface{}{"policyId": id}
+	if policy != nil {
+		data = policyData(policy)
+		data["policyId"] = id
+	}
+	return data
+}
+
+func decisionData(decision *types.Decision) map[string]interface{} {
+	return map[string]interface{}{
+		"decisionId": decision.ID,
+		"decision":   toMap(decision),
+	}
+}
+
+// toMap converts an entity to the nested maps rule conditions and filters
+// read fields from
+func toMap(value interface{}) map[string]interface{} {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	return data
+}
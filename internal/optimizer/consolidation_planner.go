@@ -0,0 +1,572 @@
+package optimizer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/decisions"
+	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// defaultUtilizationThreshold is the utilization below which a node is
+// considered for draining when the request does not set one
+const defaultUtilizationThreshold = 0.5
+
+// AnnotationDoNotDisrupt marks a workload that consolidation must not move
+const AnnotationDoNotDisrupt = "kcloud.io/do-not-disrupt"
+
+// hostnameTopologyKey is the topology key that scopes affinity to a node
+const hostnameTopologyKey = "kubernetes.io/hostname"
+
+// consolidationPlanner implements ConsolidationPlanner interface
+type consolidationPlanner struct {
+	lifecycle decisions.Lifecycle
+	logger    types.Logger
+}
+
+// NewConsolidationPlanner creates a new consolidation planner
+func NewConsolidationPlanner(lifecycle decisions.Lifecycle, logger types.Logger) ConsolidationPlanner {
+	return &consolidationPlanner{
+		lifecycle: lifecycle,
+		logger:    logger,
+	}
+}
+
+// consolidationNode tracks the simulated state of a node during planning
+type consolidationNode struct {
+	node        *evaluator.NodeInfo
+	free        resourceVector
+	utilization float64
+	workloads   []*types.Workload
+	receiving   bool
+	drained     bool
+}
+
+// consolidationState is the simulated cluster state during planning
+type consolidationState struct {
+	nodes       []*consolidationNode
+	budgetsUsed []int
+}
+
+// Plan computes a grouped set of migrations that drain underutilized nodes.
+// Nodes are drained emptiest first; each workload on a node is placed
+// best-fit onto a remaining node that satisfies its resource, accelerator,
+// cluster, node selector and affinity constraints. A node is only drained
+// if every workload on it can be placed within the disruption budgets. A
+// plan that drains nodes creates its decision through the decision
+// lifecycle.
+func (p *consolidationPlanner) Plan(ctx context.Context, request *ConsolidationRequest) (*ConsolidationPlan, error) {
+	if request == nil || len(request.Nodes) == 0 {
+		return nil, fmt.Errorf("consolidation request must include nodes")
+	}
+	if request.ClusterID == "" {
+		return nil, fmt.Errorf("consolidation request must include a cluster")
+	}
+	if request.PolicyID == "" {
+		return nil, fmt.Errorf("consolidation request must include the policy its decision is recorded against")
+	}
+
+	threshold := request.UtilizationThreshold
+	if threshold <= 0 {
+		threshold = defaultUtilizationThreshold
+	}
+	if threshold > 1 {
+		return nil, fmt.Errorf("utilization threshold must be in (0, 1], got %v", threshold)
+	}
+
+	state, err := p.buildState(request)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &ConsolidationPlan{
+		ID:           fmt.Sprintf("consolidation-%s-%d", request.ClusterID, time.Now().UnixNano()),
+		ClusterID:    request.ClusterID,
+		SkippedNodes: make(map[string]string),
+	}
+
+	candidates := make([]*consolidationNode, 0, len(state.nodes))
+	for _, n := range state.nodes {
+		if n.utilization < threshold {
+			candidates = append(candidates, n)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].utilization != candidates[j].utilization {
+			return candidates[i].utilization < candidates[j].utilization
+		}
+		return nodeHourlyCost(candidates[i].node) > nodeHourlyCost(candidates[j].node)
+	})
+
+	for _, candidate := range candidates {
+		if request.MaxNodesToDrain > 0 && len(plan.DrainedNodes) >= request.MaxNodesToDrain {
+			plan.SkippedNodes[candidate.node.ID] = "maximum number of nodes to drain reached"
+			continue
+		}
+		if candidate.receiving {
+			plan.SkippedNodes[candidate.node.ID] = "node receives workloads from another drained node"
+			continue
+		}
+
+		moves, reason, err := p.drainNode(request, state, candidate)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			plan.SkippedNodes[candidate.node.ID] = reason
+			continue
+		}
+
+		plan.DrainedNodes = append(plan.DrainedNodes, candidate.node.ID)
+		plan.Moves = append(plan.Moves, moves...)
+		if candidate.node.Cost != nil {
+			plan.CostSavingsPerHour += candidate.node.Cost.CostPerHour
+		}
+		if candidate.node.Power != nil {
+			plan.PowerSavings += candidate.node.Power.PowerConsumption
+		}
+	}
+
+	if len(plan.DrainedNodes) > 0 {
+		plan.Decision = p.buildDecision(request, plan)
+		if err := p.lifecycle.Create(ctx, plan.Decision); err != nil {
+			return nil, fmt.Errorf("failed to record consolidation decision: %w", err)
+		}
+	}
+
+	p.logger.Info("consolidation plan computed",
+		"plan_id", plan.ID,
+		"cluster_id", request.ClusterID,
+		"candidates", len(candidates),
+		"drained_nodes", len(plan.DrainedNodes),
+		"moves", len(plan.Moves),
+		"cost_savings_per_hour", plan.CostSavingsPerHour,
+		"power_savings", plan.PowerSavings)
+
+	return plan, nil
+}
+
+// Health checks the health of the consolidation planner
+func (p *consolidationPlanner) Health(ctx context.Context) error {
+	if p.lifecycle == nil {
+		return fmt.Errorf("decision lifecycle not configured")
+	}
+	return p.lifecycle.Health(ctx)
+}
+
+// buildState computes free capacity and utilization for every node in the
+// cluster and assigns running workloads to the nodes they are placed on
+func (p *consolidationPlanner) buildState(request *ConsolidationRequest) (*consolidationState, error) {
+	state := &consolidationState{budgetsUsed: make([]int, len(request.DisruptionBudgets))}
+	byID := make(map[string]*consolidationNode)
+
+	for _, node := range request.Nodes {
+		if node.ClusterID != request.ClusterID {
+			continue
+		}
+
+		capacity, err := nodeCapacity(node)
+		if err != nil {
+			return nil, err
+		}
+		free, err := nodeFree(node)
+		if err != nil {
+			return nil, err
+		}
+
+		n := &consolidationNode{
+			node:        node,
+			free:        free,
+			utilization: utilization(capacity, free),
+		}
+		state.nodes = append(state.nodes, n)
+		byID[node.ID] = n
+	}
+
+	for _, workload := range request.Workloads {
+		if !workload.IsRunning() {
+			continue
+		}
+		if n, exists := byID[workloadNode(workload)]; exists {
+			n.workloads = append(n.workloads, workload)
+		}
+	}
+
+	return state, nil
+}
+
+// drainNode tries to place every workload of the candidate on other nodes.
+// On success the simulated state is updated and the moves are returned; on
+// failure the state is left untouched and a reason is returned.
+func (p *consolidationPlanner) drainNode(request *ConsolidationRequest, state *consolidationState, candidate *consolidationNode) ([]*ConsolidationMove, string, error) {
+	type placement struct {
+		workload *types.Workload
+		demand   resourceVector
+		weight   float64
+	}
+
+	placements := make([]placement, 0, len(candidate.workloads))
+	for _, workload := range candidate.workloads {
+		if isEnabled(workload.Annotations[AnnotationDoNotDisrupt]) {
+			return nil, fmt.Sprintf("workload %s must not be disrupted", workload.ID), nil
+		}
+		demand, err := workloadDemand(workload)
+		if err != nil {
+			return nil, "", err
+		}
+		placements = append(placements, placement{workload, demand, resourceWeight(demand)})
+	}
+	sort.SliceStable(placements, func(i, j int) bool {
+		return placements[i].weight > placements[j].weight
+	})
+
+	// Snapshot the state so a partial drain can be rolled back
+	type snapshot struct {
+		free      resourceVector
+		workloads []*types.Workload
+		receiving bool
+	}
+	saved := make([]snapshot, len(state.nodes))
+	for i, n := range state.nodes {
+		saved[i] = snapshot{n.free, n.workloads, n.receiving}
+	}
+	savedBudgets := append([]int(nil), state.budgetsUsed...)
+	rollback := func() {
+		for i, n := range state.nodes {
+			n.free, n.workloads, n.receiving = saved[i].free, saved[i].workloads, saved[i].receiving
+		}
+		state.budgetsUsed = savedBudgets
+	}
+
+	// Workloads leave the candidate as they are placed so affinity checks
+	// see the simulated layout
+	candidate.workloads = nil
+
+	moves := make([]*ConsolidationMove, 0, len(placements))
+	for _, pl := range placements {
+		if budget, ok := p.consumeBudget(request, state, pl.workload); !ok {
+			rollback()
+			return nil, fmt.Sprintf("disruption budget %s exhausted by workload %s", budget, pl.workload.ID), nil
+		}
+
+		target := p.selectTarget(state, candidate, pl.workload, pl.demand)
+		if target == nil {
+			rollback()
+			return nil, fmt.Sprintf("no node can host workload %s", pl.workload.ID), nil
+		}
+
+		target.free = target.free.sub(pl.demand)
+		target.workloads = append(append([]*types.Workload(nil), target.workloads...), pl.workload)
+		target.receiving = true
+
+		moves = append(moves, &ConsolidationMove{
+			WorkloadID: pl.workload.ID,
+			SourceNode: candidate.node.ID,
+			TargetNode: target.node.ID,
+		})
+	}
+
+	candidate.drained = true
+	return moves, "", nil
+}
+
+// consumeBudget charges a move against every disruption budget selecting the
+// workload, returning the name of the first exhausted budget
+func (p *consolidationPlanner) consumeBudget(request *ConsolidationRequest, state *consolidationState, workload *types.Workload) (string, bool) {
+	var matched []int
+	for i, budget := range request.DisruptionBudgets {
+		if !labelsMatch(budget.Selector, workload.Labels) {
+			continue
+		}
+		if state.budgetsUsed[i] >= budget.MaxDisruptions {
+			return budget.Name, false
+		}
+		matched = append(matched, i)
+	}
+	for _, i := range matched {
+		state.budgetsUsed[i]++
+	}
+	return "", true
+}
+
+// selectTarget returns the feasible node leaving the least free capacity
+// after placement
+func (p *consolidationPlanner) selectTarget(state *consolidationState, source *consolidationNode, workload *types.Workload, demand resourceVector) *consolidationNode {
+	var best *consolidationNode
+	var bestWeight float64
+
+	for _, n := range state.nodes {
+		if n == source || n.drained || !nodeSchedulable(n.node) {
+			continue
+		}
+		if !clusterAllowed(workload, n.node.ClusterID) || !nodeSelectorsMatch(workload, n.node) || !nodeAffinityMatches(workload, n.node) {
+			continue
+		}
+		if !n.free.fits(demand) {
+			continue
+		}
+		if !p.podAffinitySatisfied(state, n, workload) {
+			continue
+		}
+
+		weight := resourceWeight(n.free.sub(demand))
+		if best == nil || weight < bestWeight {
+			best, bestWeight = n, weight
+		}
+	}
+
+	return best
+}
+
+// podAffinitySatisfied checks required pod affinity and anti-affinity of the
+// workload against the target's topology domain, and the anti-affinity of
+// workloads already in that domain against the workload
+func (p *consolidationPlanner) podAffinitySatisfied(state *consolidationState, target *consolidationNode, workload *types.Workload) bool {
+	var affinity *types.Affinity
+	if workload.Constraints != nil {
+		affinity = workload.Constraints.Affinity
+	}
+
+	if affinity != nil && affinity.PodAffinity != nil {
+		for _, term := range affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			found := false
+			for _, peer := range domainWorkloads(state, target, term.TopologyKey) {
+				if peer.ID != workload.ID && labelSelectorMatches(term.LabelSelector, peer.Labels) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+
+	if affinity != nil && affinity.PodAntiAffinity != nil {
+		for _, term := range affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			for _, peer := range domainWorkloads(state, target, term.TopologyKey) {
+				if peer.ID != workload.ID && labelSelectorMatches(term.LabelSelector, peer.Labels) {
+					return false
+				}
+			}
+		}
+	}
+
+	// Anti-affinity is symmetric: existing workloads may reject the newcomer
+	for _, n := range state.nodes {
+		for _, peer := range n.workloads {
+			if peer.ID == workload.ID || peer.Constraints == nil || peer.Constraints.Affinity == nil || peer.Constraints.Affinity.PodAntiAffinity == nil {
+				continue
+			}
+			for _, term := range peer.Constraints.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+				if sameDomain(n.node, target.node, term.TopologyKey) && labelSelectorMatches(term.LabelSelector, workload.Labels) {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// buildDecision emits the consolidate decision grouping the plan's moves.
+// Each move is a migration the enforcer applies as part of the group; the
+// moves are not separate decisions, so they cannot be approved or enforced
+// on their own. Since the decision spans many workloads its subject is the
+// cluster rather than a single workload.
+func (p *consolidationPlanner) buildDecision(request *ConsolidationRequest, plan *ConsolidationPlan) *types.Decision {
+	now := time.Now()
+	moves := make([]map[string]interface{}, 0, len(plan.Moves))
+	for i, move := range plan.Moves {
+		moves = append(moves, map[string]interface{}{
+			"workloadId": move.WorkloadID,
+			"sourceNode": move.SourceNode,
+			"targetNode": move.TargetNode,
+			"sequence":   i,
+		})
+	}
+
+	return &types.Decision{
+		ID:                 plan.ID,
+		Type:               types.DecisionTypeConsolidate,
+		Status:             types.DecisionStatusPending,
+		Reason:             types.DecisionReasonResourceUtilization,
+		PolicyID:           request.PolicyID,
+		ClusterID:          plan.ClusterID,
+		RecommendedCluster: plan.ClusterID,
+		Confidence:         1.0,
+		Message: fmt.Sprintf("Drain %d underutilized node(s) in cluster %s by migrating %d workload(s)",
+			len(plan.DrainedNodes), plan.ClusterID, len(plan.Moves)),
+		Details: map[string]interface{}{
+			"consolidationPlanId": plan.ID,
+			"drainedNodes":        plan.DrainedNodes,
+			"moves":               moves,
+			"costSavingsPerHour":  plan.CostSavingsPerHour,
+			"costSavingsPerMonth": plan.CostSavingsPerHour * hoursPerMonth,
+			"powerSavings":        plan.PowerSavings,
+		},
+		Metadata: types.DecisionMetadata{
+			Source:    "consolidation-planner",
+			Timestamp: now,
+			Labels: map[string]string{
+				"consolidation-plan": plan.ID,
+			},
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// utilization returns the highest allocated fraction across the resources
+// the node provides
+func utilization(capacity, free resourceVector) float64 {
+	var highest float64
+	ratio := func(total, available float64) {
+		if total <= 0 {
+			return
+		}
+		if used := (total - available) / total; used > highest {
+			highest = used
+		}
+	}
+	ratio(float64(capacity.CPU), float64(free.CPU))
+	ratio(float64(capacity.Memory), float64(free.Memory))
+	ratio(float64(capacity.GPU), float64(free.GPU))
+	ratio(float64(capacity.NPU), float64(free.NPU))
+	return highest
+}
+
+// nodeHourlyCost returns the hourly cost of a node, or zero if unknown
+func nodeHourlyCost(node *evaluator.NodeInfo) float64 {
+	if node.Cost == nil {
+		return 0
+	}
+	return node.Cost.CostPerHour
+}
+
+// nodeSchedulable returns true if the node can accept new workloads
+func nodeSchedulable(node *evaluator.NodeInfo) bool {
+	return node.Status == "" || strings.EqualFold(node.Status, "ready")
+}
+
+// domainWorkloads returns the workloads in the target's topology domain
+func domainWorkloads(state *consolidationState, target *consolidationNode, topologyKey string) []*types.Workload {
+	var workloads []*types.Workload
+	for _, n := range state.nodes {
+		if sameDomain(n.node, target.node, topologyKey) {
+			workloads = append(workloads, n.workloads...)
+		}
+	}
+	return workloads
+}
+
+// sameDomain returns true if both nodes share the topology domain. The
+// hostname key, or no key, scopes the domain to a single node.
+func sameDomain(a, b *evaluator.NodeInfo, topologyKey string) bool {
+	if topologyKey == "" || topologyKey == hostnameTopologyKey {
+		return a.ID == b.ID
+	}
+	value, exists := a.Labels[topologyKey]
+	return exists && value == b.Labels[topologyKey]
+}
+
+// labelsMatch returns true if labels contain every selector entry
+func labelsMatch(selector, labels map[string]string) bool {
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// labelSelectorMatches evaluates a label selector against labels. A nil
+// selector matches nothing.
+func labelSelectorMatches(selector *types.LabelSelector, labels map[string]string) bool {
+	if selector == nil {
+		return false
+	}
+	if !labelsMatch(selector.MatchLabels, labels) {
+		return false
+	}
+	for _, requirement := range selector.MatchExpressions {
+		if !requirementMatches(requirement.Key, requirement.Operator, requirement.Values, labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeAffinityMatches returns true if the node satisfies the workload's
+// required node affinity. Terms are ORed and expressions within a term ANDed.
+func nodeAffinityMatches(workload *types.Workload, node *evaluator.NodeInfo) bool {
+	if workload.Constraints == nil || workload.Constraints.Affinity == nil || workload.Constraints.Affinity.NodeAffinity == nil {
+		return true
+	}
+	required := workload.Constraints.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if required == nil || len(required.NodeSelectorTerms) == 0 {
+		return true
+	}
+
+	for _, term := range required.NodeSelectorTerms {
+		matched := true
+		for _, requirement := range term.MatchExpressions {
+			if !requirementMatches(requirement.Key, requirement.Operator, requirement.Values, node.Labels) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// requirementMatches evaluates a single selector requirement
+func requirementMatches(key, operator string, values []string, labels map[string]string) bool {
+	value, exists := labels[key]
+	switch operator {
+	case "In":
+		return exists && containsString(values, value)
+	case "NotIn":
+		return !exists || !containsString(values, value)
+	case "Exists":
+		return exists
+	case "DoesNotExist":
+		return !exists
+	case "Gt", "Lt":
+		if !exists || len(values) != 1 {
+			return false
+		}
+		actual, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+		bound, err := strconv.ParseInt(values[0], 10, 64)
+		if err != nil {
+			return false
+		}
+		if operator == "Gt" {
+			return actual > bound
+		}
+		return actual < bound
+	default:
+		return false
+	}
+}
+
+// containsString returns true if values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
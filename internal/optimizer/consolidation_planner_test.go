@@ -0,0 +1,165 @@
+package optimizer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/decisions"
+	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+func newTestConsolidationPlanner() (ConsolidationPlanner, storage.StorageManager) {
+	store := memory.NewStorageManager()
+	lifecycle := decisions.NewLifecycle(store, config.DecisionConfig{SupersedePending: true}, testLogger{})
+	return NewConsolidationPlanner(lifecycle, testLogger{}), store
+}
+
+func clusterNode(id string, allocatedCPU int, costPerHour float64) *evaluator.NodeInfo {
+	return &evaluator.NodeInfo{
+		ID:        id,
+		ClusterID: "cluster-a",
+		Capacity:  &evaluator.ResourceCapacity{CPU: 8, Memory: "32Gi"},
+		Allocated: &evaluator.ResourceAllocation{CPU: allocatedCPU, Memory: "1Gi"},
+		Cost:      &evaluator.CostInfo{CostPerHour: costPerHour},
+	}
+}
+
+func placedWorkload(id, node string, cpu int) *types.Workload {
+	return &types.Workload{
+		ID:           id,
+		Name:         id,
+		Status:       types.WorkloadStatusRunning,
+		Labels:       map[string]string{"node": node, "app": id},
+		Requirements: types.Resources{CPU: cpu, Memory: "1Gi"},
+	}
+}
+
+// newConsolidationRequest has an almost empty node-1, a busy node-2 and a
+// lightly used node-3
+func newConsolidationRequest() *ConsolidationRequest {
+	return &ConsolidationRequest{
+		ClusterID: "cluster-a",
+		Nodes: []*evaluator.NodeInfo{
+			clusterNode("node-1", 1, 2.0),
+			clusterNode("node-2", 6, 2.0),
+			clusterNode("node-3", 2, 2.0),
+			{ID: "other-1", ClusterID: "cluster-b", Capacity: &evaluator.ResourceCapacity{CPU: 64, Memory: "256Gi"}},
+		},
+		Workloads: []*types.Workload{
+			placedWorkload("w1", "node-1", 1),
+			placedWorkload("w2", "node-2", 6),
+			placedWorkload("w3", "node-3", 2),
+		},
+		PolicyID: "consolidation-policy",
+	}
+}
+
+func TestConsolidationPlanner_DrainsEmptiestNode(t *testing.T) {
+	planner, _ := newTestConsolidationPlanner()
+
+	plan, err := planner.Plan(context.Background(), newConsolidationRequest())
+	require.NoError(t, err)
+
+	// w1 is packed best-fit onto node-2, which then cannot also take w3
+	assert.Equal(t, []string{"node-1"}, plan.DrainedNodes)
+	require.Len(t, plan.Moves, 1)
+	assert.Equal(t, ConsolidationMove{WorkloadID: "w1", SourceNode: "node-1", TargetNode: "node-2"}, *plan.Moves[0])
+	assert.Contains(t, plan.SkippedNodes, "node-3")
+	assert.NotContains(t, plan.SkippedNodes, "other-1")
+	assert.InDelta(t, 2.0, plan.CostSavingsPerHour, 1e-9)
+}
+
+func TestConsolidationPlanner_GroupDecisionIsScopedToCluster(t *testing.T) {
+	planner, _ := newTestConsolidationPlanner()
+
+	plan, err := planner.Plan(context.Background(), newConsolidationRequest())
+	require.NoError(t, err)
+	require.NotNil(t, plan.Decision)
+
+	decision := plan.Decision
+	assert.Equal(t, plan.ID, decision.ID)
+	assert.Equal(t, types.DecisionTypeConsolidate, decision.Type)
+	assert.Equal(t, types.DecisionStatusPending, decision.Status)
+	assert.Empty(t, decision.WorkloadID)
+	assert.Equal(t, "cluster-a", decision.ClusterID)
+	assert.Equal(t, "consolidation-policy", decision.PolicyID)
+
+	moves, ok := decision.Details["moves"].([]map[string]interface{})
+	require.True(t, ok)
+	require.Len(t, moves, 1)
+	assert.Equal(t, "w1", moves[0]["workloadId"])
+	assert.Equal(t, "node-2", moves[0]["targetNode"])
+	assert.NotContains(t, moves[0], "decisionId")
+}
+
+func TestConsolidationPlanner_RecordsDecisionThroughLifecycle(t *testing.T) {
+	ctx := context.Background()
+	planner, store := newTestConsolidationPlanner()
+
+	first, err := planner.Plan(ctx, newConsolidationRequest())
+	require.NoError(t, err)
+	stored, err := store.Decision().Get(ctx, first.Decision.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.DecisionStatusPending, stored.Status)
+
+	// Planning the cluster again supersedes the earlier pending plan
+	second, err := planner.Plan(ctx, newConsolidationRequest())
+	require.NoError(t, err)
+	stored, err = store.Decision().Get(ctx, first.Decision.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.DecisionStatusSuperseded, stored.Status)
+	assert.Equal(t, second.Decision.ID, stored.Details["supersededBy"])
+
+	stored, err = store.Decision().Get(ctx, second.Decision.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.DecisionStatusPending, stored.Status)
+}
+
+func TestConsolidationPlanner_RespectsDisruptionConstraints(t *testing.T) {
+	planner, _ := newTestConsolidationPlanner()
+
+	request := newConsolidationRequest()
+	request.Workloads[0].Annotations = map[string]string{AnnotationDoNotDisrupt: "true"}
+	plan, err := planner.Plan(context.Background(), request)
+	require.NoError(t, err)
+	assert.Contains(t, plan.SkippedNodes["node-1"], "must not be disrupted")
+
+	request = newConsolidationRequest()
+	request.DisruptionBudgets = []DisruptionBudget{{Name: "w1-budget", Selector: map[string]string{"app": "w1"}, MaxDisruptions: 0}}
+	plan, err = planner.Plan(context.Background(), request)
+	require.NoError(t, err)
+	assert.Contains(t, plan.SkippedNodes["node-1"], "disruption budget w1-budget exhausted")
+
+	request = newConsolidationRequest()
+	request.Workloads[0].Constraints = &types.WorkloadConstraints{NodeSelectors: map[string]string{"zone": "a"}}
+	plan, err = planner.Plan(context.Background(), request)
+	require.NoError(t, err)
+	assert.Contains(t, plan.SkippedNodes["node-1"], "no node can host workload w1")
+}
+
+func TestConsolidationPlanner_NothingToDrain(t *testing.T) {
+	planner, _ := newTestConsolidationPlanner()
+
+	request := newConsolidationRequest()
+	request.UtilizationThreshold = 0.1
+	plan, err := planner.Plan(context.Background(), request)
+	require.NoError(t, err)
+	assert.Empty(t, plan.DrainedNodes)
+	assert.Nil(t, plan.Decision)
+}
+
+func TestConsolidationPlanner_RequiresCluster(t *testing.T) {
+	planner, _ := newTestConsolidationPlanner()
+
+	request := newConsolidationRequest()
+	request.ClusterID = ""
+	_, err := planner.Plan(context.Background(), request)
+	assert.Error(t, err)
+}
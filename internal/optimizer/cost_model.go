@@ -0,0 +1,47 @@
+package optimizer
+
+import (
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// hoursPerMonth is the average number of hours in a month
+const hoursPerMonth = 730
+
+// rateCostModel implements CostModel using flat per-resource hourly rates
+type rateCostModel struct {
+	rates config.CostConfig
+}
+
+// NewCostModel creates a cost model from per-resource hourly rates
+func NewCostModel(rates config.CostConfig) CostModel {
+	if rates.Currency == "" {
+		rates.Currency = "USD"
+	}
+	return &rateCostModel{
+		rates: rates,
+	}
+}
+
+// HourlyCost returns the hourly cost of the given resources
+func (m *rateCostModel) HourlyCost(resources *types.Resources) (float64, error) {
+	demand, err := workloadDemand(&types.Workload{Requirements: *resources})
+	if err != nil {
+		return 0, err
+	}
+	return m.vectorCost(demand), nil
+}
+
+// Currency returns the currency costs are expressed in
+func (m *rateCostModel) Currency() string {
+	return m.rates.Currency
+}
+
+// vectorCost returns the hourly cost of a resource vector
+func (m *rateCostModel) vectorCost(r resourceVector) float64 {
+	memoryGiB := float64(r.Memory) / (1024 * 1024 * 1024)
+	return float64(r.CPU)*m.rates.CPUPerHour +
+		memoryGiB*m.rates.MemoryGiBPerHour +
+		float64(r.GPU)*m.rates.GPUPerHour +
+		float64(r.NPU)*m.rates.NPUPerHour
+}
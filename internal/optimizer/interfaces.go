@@ -0,0 +1,186 @@
+package optimizer
+
+import (
+	"context"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// PreemptionPlanner plans evictions that make room for a pending workload
+type PreemptionPlanner interface {
+	// Plan computes the minimal set of victims to evict so the workload fits
+	Plan(ctx context.Context, request *PreemptionRequest) (*PreemptionPlan, error)
+
+	// Health checks the health of the preemption planner
+	Health(ctx context.Context) error
+}
+
+// PreemptionRequest describes a pending workload and the capacity it may use
+type PreemptionRequest struct {
+	Workload       *types.Workload               `json:"workload"`
+	Nodes          []*evaluator.NodeInfo         `json:"nodes"`
+	Running        []*types.Workload             `json:"running"`
+	PriorityPolicy *types.WorkloadPriorityPolicy `json:"priorityPolicy,omitempty"`
+	PolicyID       string                        `json:"policyId,omitempty"`
+}
+
+// PreemptionPlan is the result of preemption planning
+type PreemptionPlan struct {
+	ID         string              `json:"id"`
+	WorkloadID string              `json:"workloadId"`
+	ClusterID  string              `json:"clusterId,omitempty"`
+	NodeID     string              `json:"nodeId,omitempty"`
+	Feasible   bool                `json:"feasible"`
+	Reason     string              `json:"reason"`
+	Victims    []*PreemptionVictim `json:"victims,omitempty"`
+	Decisions  []*types.Decision   `json:"decisions,omitempty"`
+}
+
+// PreemptionVictim is a workload selected for eviction
+type PreemptionVictim struct {
+	WorkloadID    string         `json:"workloadId"`
+	WorkloadName  string         `json:"workloadName"`
+	NodeID        string         `json:"nodeId"`
+	Priority      types.Priority `json:"priority"`
+	PriorityClass string         `json:"priorityClass,omitempty"`
+}
+
+// CostModel prices resources
+type CostModel interface {
+	// HourlyCost returns the hourly cost of the given resources
+	HourlyCost(resources *types.Resources) (float64, error)
+
+	// Currency returns the currency costs are expressed in
+	Currency() string
+}
+
+// RightSizingAnalyzer recommends resource requests from observed usage
+type RightSizingAnalyzer interface {
+	// Analyze computes right-sizing recommendations for a workload
+	Analyze(ctx context.Context, workloadID string, options *RightSizingOptions) (*RightSizingReport, error)
+
+	// Health checks the health of the right-sizing analyzer
+	Health(ctx context.Context) error
+}
+
+// RightSizingOptions controls how usage history is summarized. Headroom is
+// a pointer so an explicit zero headroom can override the default.
+type RightSizingOptions struct {
+	Window     time.Duration `json:"window"`
+	Percentile float64       `json:"percentile"`
+	Headroom   *float64      `json:"headroom,omitempty"`
+	MinSamples int           `json:"minSamples"`
+}
+
+// UsageStats summarizes the observed usage of a single resource
+type UsageStats struct {
+	Requested  float64 `json:"requested"`
+	Mean       float64 `json:"mean"`
+	Percentile float64 `json:"percentile"`
+	Max        float64 `json:"max"`
+}
+
+// RightSizingReport is the result of right-sizing analysis
+type RightSizingReport struct {
+	WorkloadID              string                 `json:"workloadId"`
+	Window                  string                 `json:"window"`
+	Percentile              float64                `json:"percentile"`
+	Samples                 int                    `json:"samples"`
+	Sufficient              bool                   `json:"sufficient"`
+	Current                 types.Resources        `json:"current"`
+	Recommended             types.Resources        `json:"recommended"`
+	Usage                   map[string]*UsageStats `json:"usage,omitempty"`
+	CurrentCostPerHour      float64                `json:"currentCostPerHour"`
+	RecommendedCostPerHour  float64                `json:"recommendedCostPerHour"`
+	EstimatedSavingsPerHour float64                `json:"estimatedSavingsPerHour"`
+	EstimatedMonthlySavings float64                `json:"estimatedMonthlySavings"`
+	Currency                string                 `json:"currency"`
+	Recommendations         []types.Recommendation `json:"recommendations,omitempty"`
+	GeneratedAt             time.Time              `json:"generatedAt"`
+}
+
+// SpotAnalyzer decides whether workloads can run on spot capacity
+type SpotAnalyzer interface {
+	// Analyze assesses spot eligibility and records the resulting decision
+	Analyze(ctx context.Context, request *SpotRequest) (*SpotAssessment, error)
+
+	// Health checks the health of the spot analyzer
+	Health(ctx context.Context) error
+}
+
+// SpotRequest describes a workload and the spot capacity it may move to
+type SpotRequest struct {
+	Workload     *types.Workload               `json:"workload"`
+	Policy       *types.CostOptimizationPolicy `json:"policy,omitempty"`
+	SpotClusters []*evaluator.ClusterInfo      `json:"spotClusters,omitempty"`
+}
+
+// SpotCheck is the outcome of a single eligibility criterion
+type SpotCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Reason string `json:"reason"`
+}
+
+// SpotAssessment is the result of spot eligibility analysis
+type SpotAssessment struct {
+	WorkloadID              string          `json:"workloadId"`
+	Eligible                bool            `json:"eligible"`
+	Checks                  []SpotCheck     `json:"checks"`
+	Reasons                 []string        `json:"reasons,omitempty"`
+	TargetCluster           string          `json:"targetCluster,omitempty"`
+	OnDemandCostPerHour     float64         `json:"onDemandCostPerHour"`
+	SpotCostPerHour         float64         `json:"spotCostPerHour,omitempty"`
+	EstimatedSavingsPerHour float64         `json:"estimatedSavingsPerHour,omitempty"`
+	EstimatedMonthlySavings float64         `json:"estimatedMonthlySavings,omitempty"`
+	Currency                string          `json:"currency"`
+	Decision                *types.Decision `json:"decision,omitempty"`
+}
+
+// ConsolidationPlanner plans migrations that empty underutilized nodes
+type ConsolidationPlanner interface {
+	// Plan computes a grouped set of migrations that drain nodes
+	Plan(ctx context.Context, request *ConsolidationRequest) (*ConsolidationPlan, error)
+
+	// Health checks the health of the consolidation planner
+	Health(ctx context.Context) error
+}
+
+// ConsolidationRequest describes a cluster's nodes and running workloads
+type ConsolidationRequest struct {
+	ClusterID            string                `json:"clusterId"`
+	Nodes                []*evaluator.NodeInfo `json:"nodes"`
+	Workloads            []*types.Workload     `json:"workloads"`
+	UtilizationThreshold float64               `json:"utilizationThreshold,omitempty"`
+	MaxNodesToDrain      int                   `json:"maxNodesToDrain,omitempty"`
+	DisruptionBudgets    []DisruptionBudget    `json:"disruptionBudgets,omitempty"`
+	PolicyID             string                `json:"policyId,omitempty"`
+}
+
+// DisruptionBudget limits how many matching workloads a plan may move
+type DisruptionBudget struct {
+	Name           string            `json:"name"`
+	Selector       map[string]string `json:"selector"`
+	MaxDisruptions int               `json:"maxDisruptions"`
+}
+
+// ConsolidationMove relocates one workload off a drained node
+type ConsolidationMove struct {
+	WorkloadID string `json:"workloadId"`
+	SourceNode string `json:"sourceNode"`
+	TargetNode string `json:"targetNode"`
+}
+
+// ConsolidationPlan is the result of consolidation planning
+type ConsolidationPlan struct {
+	ID                 string               `json:"id"`
+	ClusterID          string               `json:"clusterId"`
+	DrainedNodes       []string             `json:"drainedNodes,omitempty"`
+	SkippedNodes       map[string]string    `json:"skippedNodes,omitempty"`
+	Moves              []*ConsolidationMove `json:"moves,omitempty"`
+	CostSavingsPerHour float64              `json:"costSavingsPerHour"`
+	PowerSavings       float64              `json:"powerSavings"`
+	Decision           *types.Decision      `json:"decision,omitempty"`
+}
@@ -0,0 +1,397 @@
+package optimizer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/decisions"
+	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// preemptionPlanner implements PreemptionPlanner interface
+type preemptionPlanner struct {
+	lifecycle decisions.Lifecycle
+	logger    types.Logger
+}
+
+// NewPreemptionPlanner creates a new preemption planner
+func NewPreemptionPlanner(lifecycle decisions.Lifecycle, logger types.Logger) PreemptionPlanner {
+	return &preemptionPlanner{
+		lifecycle: lifecycle,
+		logger:    logger,
+	}
+}
+
+// maxExhaustiveVictims bounds the per-node candidate count for which the
+// minimal victim set is found exactly rather than greedily
+const maxExhaustiveVictims = 12
+
+// workloadPriority is the effective priority of a workload
+type workloadPriority struct {
+	Value            types.Priority
+	Class            string
+	PreemptionPolicy string
+}
+
+// nodePreemption is a candidate placement on a single node
+type nodePreemption struct {
+	node    *evaluator.NodeInfo
+	victims []*types.Workload
+}
+
+// Plan computes the minimal set of victims to evict so the workload fits.
+// A workload whose priority class has PreemptionPolicy Never never preempts
+// others, but it can still be chosen as a victim by a higher priority.
+// The decisions of a feasible plan are created pending through the decision
+// lifecycle.
+func (p *preemptionPlanner) Plan(ctx context.Context, request *PreemptionRequest) (*PreemptionPlan, error) {
+	if request == nil || request.Workload == nil {
+		return nil, fmt.Errorf("preemption request must include a workload")
+	}
+	if request.PolicyID == "" {
+		return nil, fmt.Errorf("preemption request must include the policy its decisions are recorded against")
+	}
+
+	workload := request.Workload
+	plan := &PreemptionPlan{
+		ID:         fmt.Sprintf("preemption-%s-%d", workload.ID, time.Now().UnixNano()),
+		WorkloadID: workload.ID,
+	}
+
+	demand, err := workloadDemand(workload)
+	if err != nil {
+		return nil, err
+	}
+
+	priorities, err := newPriorityResolver(request.PriorityPolicy)
+	if err != nil {
+		return nil, err
+	}
+	preemptor, err := priorities.resolve(workload)
+	if err != nil {
+		return nil, err
+	}
+	canPreempt := preemptor.PreemptionPolicy != evaluator.PreemptionPolicyNever
+
+	var best *nodePreemption
+	for _, node := range request.Nodes {
+		if !clusterAllowed(workload, node.ClusterID) || !nodeSelectorsMatch(workload, node) {
+			continue
+		}
+
+		candidate, err := p.planNode(request, priorities, node, demand, preemptor, canPreempt)
+		if err != nil {
+			return nil, err
+		}
+		if candidate != nil && (best == nil || p.better(priorities, candidate, best)) {
+			best = candidate
+		}
+	}
+
+	if best == nil {
+		plan.Reason = "no node can fit the workload"
+		if !canPreempt {
+			plan.Reason = fmt.Sprintf("no node has free capacity and priority class %q does not allow preemption", preemptor.Class)
+		}
+		p.logger.WithWorkload(workload.ID, string(workload.Type)).Info("preemption planning found no placement", "reason", plan.Reason)
+		return plan, nil
+	}
+
+	plan.Feasible = true
+	plan.NodeID = best.node.ID
+	plan.ClusterID = best.node.ClusterID
+	if len(best.victims) == 0 {
+		plan.Reason = fmt.Sprintf("node %s has enough free capacity, no preemption required", best.node.ID)
+	} else {
+		plan.Reason = fmt.Sprintf("evicting %d lower-priority workload(s) from node %s frees enough capacity", len(best.victims), best.node.ID)
+	}
+
+	for _, victim := range best.victims {
+		priority, err := priorities.resolve(victim)
+		if err != nil {
+			return nil, err
+		}
+		plan.Victims = append(plan.Victims, &PreemptionVictim{
+			WorkloadID:    victim.ID,
+			WorkloadName:  victim.Name,
+			NodeID:        best.node.ID,
+			Priority:      priority.Value,
+			PriorityClass: priority.Class,
+		})
+	}
+
+	plan.Decisions = p.buildDecisions(request, plan, preemptor)
+	for _, decision := range plan.Decisions {
+		if err := p.lifecycle.Create(ctx, decision); err != nil {
+			return nil, fmt.Errorf("failed to record preemption decision %s: %w", decision.ID, err)
+		}
+	}
+
+	p.logger.WithWorkload(workload.ID, string(workload.Type)).Info("preemption plan created",
+		"plan_id", plan.ID, "node_id", plan.NodeID, "victims", len(plan.Victims))
+
+	return plan, nil
+}
+
+// Health checks the health of the preemption planner
+func (p *preemptionPlanner) Health(ctx context.Context) error {
+	if p.lifecycle == nil {
+		return fmt.Errorf("decision lifecycle not configured")
+	}
+	return p.lifecycle.Health(ctx)
+}
+
+// planNode computes the minimal victim set on a node, or nil if the
+// workload cannot fit there even after preemption
+func (p *preemptionPlanner) planNode(request *PreemptionRequest, priorities *priorityResolver, node *evaluator.NodeInfo, demand resourceVector, preemptor *workloadPriority, canPreempt bool) (*nodePreemption, error) {
+	free, err := nodeFree(node)
+	if err != nil {
+		return nil, err
+	}
+	if !free.acceleratorsMatch(demand) {
+		return nil, nil
+	}
+	if free.fits(demand) {
+		return &nodePreemption{node: node}, nil
+	}
+	if !canPreempt {
+		return nil, nil
+	}
+
+	type eligibleVictim struct {
+		workload *types.Workload
+		demand   resourceVector
+		priority types.Priority
+	}
+
+	var eligible []eligibleVictim
+	released := free
+	for _, running := range request.Running {
+		if running.ID == request.Workload.ID || running.Status != types.WorkloadStatusRunning {
+			continue
+		}
+		if nodeID := workloadNode(running); nodeID != node.ID && nodeID != node.Name {
+			continue
+		}
+
+		priority, err := priorities.resolve(running)
+		if err != nil {
+			return nil, err
+		}
+		if priority.Value >= preemptor.Value {
+			continue
+		}
+
+		runningDemand, err := workloadDemand(running)
+		if err != nil {
+			return nil, err
+		}
+		eligible = append(eligible, eligibleVictim{workload: running, demand: runningDemand, priority: priority.Value})
+		released = released.add(runningDemand)
+	}
+
+	if !released.fits(demand) {
+		return nil, nil
+	}
+
+	candidate := &nodePreemption{node: node}
+
+	// Small candidate sets are searched exhaustively for the smallest victim
+	// set, preferring lower priorities among sets of the same size
+	if len(eligible) <= maxExhaustiveVictims {
+		var bestMask int
+		bestSize, bestMax, bestSum := len(eligible)+1, types.Priority(0), types.Priority(0)
+		for mask := 0; mask < 1<<len(eligible); mask++ {
+			size, max, sum := 0, types.Priority(0), types.Priority(0)
+			freed := free
+			for i, victim := range eligible {
+				if mask&(1<<i) == 0 {
+					continue
+				}
+				size++
+				sum += victim.priority
+				if victim.priority > max {
+					max = victim.priority
+				}
+				freed = freed.add(victim.demand)
+			}
+			if size > bestSize || !freed.fits(demand) {
+				continue
+			}
+			if size < bestSize || max < bestMax || (max == bestMax && sum < bestSum) {
+				bestMask, bestSize, bestMax, bestSum = mask, size, max, sum
+			}
+		}
+		for i, victim := range eligible {
+			if bestMask&(1<<i) != 0 {
+				candidate.victims = append(candidate.victims, victim.workload)
+			}
+		}
+		return candidate, nil
+	}
+
+	// Otherwise reprieve as many victims as possible, highest priority first
+	// and, at equal priority, smallest first so fewer workloads are evicted
+	sort.SliceStable(eligible, func(i, j int) bool {
+		if eligible[i].priority != eligible[j].priority {
+			return eligible[i].priority > eligible[j].priority
+		}
+		return resourceWeight(eligible[i].demand) < resourceWeight(eligible[j].demand)
+	})
+
+	for _, victim := range eligible {
+		if released.sub(victim.demand).fits(demand) {
+			released = released.sub(victim.demand)
+			continue
+		}
+		candidate.victims = append(candidate.victims, victim.workload)
+	}
+
+	return candidate, nil
+}
+
+// better returns true if candidate a disrupts less than candidate b: fewer
+// victims, then lower highest victim priority, then lower total priority
+func (p *preemptionPlanner) better(priorities *priorityResolver, a, b *nodePreemption) bool {
+	if len(a.victims) != len(b.victims) {
+		return len(a.victims) < len(b.victims)
+	}
+
+	maxA, sumA := p.victimPriorities(priorities, a.victims)
+	maxB, sumB := p.victimPriorities(priorities, b.victims)
+	if maxA != maxB {
+		return maxA < maxB
+	}
+	return sumA < sumB
+}
+
+// victimPriorities returns the highest and total priority of the victims
+func (p *preemptionPlanner) victimPriorities(priorities *priorityResolver, victims []*types.Workload) (types.Priority, types.Priority) {
+	var max, sum types.Priority
+	for _, victim := range victims {
+		priority, err := priorities.resolve(victim)
+		if err != nil {
+			continue
+		}
+		if priority.Value > max {
+			max = priority.Value
+		}
+		sum += priority.Value
+	}
+	return max, sum
+}
+
+// priorityResolver resolves the effective priorities of the workloads of a
+// request, using the request's priority policy when one is provided
+type priorityResolver struct {
+	priorities *evaluator.PriorityMap
+}
+
+// newPriorityResolver parses the mappings of a priority policy once for
+// the whole plan
+func newPriorityResolver(policy *types.WorkloadPriorityPolicy) (*priorityResolver, error) {
+	if policy == nil {
+		return &priorityResolver{}, nil
+	}
+	priorities, err := evaluator.NewPriorityMap(&policy.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid priority policy %s: %w", policy.Metadata.Name, err)
+	}
+	return &priorityResolver{priorities: priorities}, nil
+}
+
+// resolve returns the effective priority of a workload
+func (r *priorityResolver) resolve(workload *types.Workload) (*workloadPriority, error) {
+	priority := &workloadPriority{
+		Value:            workload.Priority,
+		PreemptionPolicy: evaluator.PreemptionPolicyLowerPriority,
+	}
+	if r.priorities == nil {
+		return priority, nil
+	}
+
+	resolution, err := r.priorities.Resolve(workload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve priority class for workload %s: %w", workload.ID, err)
+	}
+	if resolution.PriorityClass == nil {
+		return priority, nil
+	}
+
+	priority.Value = types.Priority(resolution.PriorityClass.Value)
+	priority.Class = resolution.PriorityClass.Name
+	if resolution.PriorityClass.PreemptionPolicy != "" {
+		priority.PreemptionPolicy = resolution.PriorityClass.PreemptionPolicy
+	}
+	return priority, nil
+}
+
+// buildDecisions emits one suspend decision per victim followed by a
+// schedule decision for the preempting workload. Decisions are linked by
+// the plan ID and the schedule decision depends on every suspend decision.
+func (p *preemptionPlanner) buildDecisions(request *PreemptionRequest, plan *PreemptionPlan, preemptor *workloadPriority) []*types.Decision {
+	now := time.Now()
+	decisions := make([]*types.Decision, 0, len(plan.Victims)+1)
+	dependsOn := make([]string, 0, len(plan.Victims))
+
+	newDecision := func(sequence int, decisionType types.DecisionType, workloadID, message string) *types.Decision {
+		return &types.Decision{
+			ID:         fmt.Sprintf("%s-%d", plan.ID, sequence),
+			Type:       decisionType,
+			Status:     types.DecisionStatusPending,
+			Reason:     types.DecisionReasonPreemption,
+			WorkloadID: workloadID,
+			PolicyID:   request.PolicyID,
+			ClusterID:  plan.ClusterID,
+			NodeID:     plan.NodeID,
+			Confidence: 1.0,
+			Message:    message,
+			Details: map[string]interface{}{
+				"preemptionPlanId": plan.ID,
+				"sequence":         sequence,
+			},
+			Metadata: types.DecisionMetadata{
+				Source:    "preemption-planner",
+				Timestamp: now,
+				Labels: map[string]string{
+					"preemption-plan": plan.ID,
+				},
+			},
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	}
+
+	for i, victim := range plan.Victims {
+		decision := newDecision(i, types.DecisionTypeSuspend, victim.WorkloadID,
+			fmt.Sprintf("Suspend workload %s (priority %d) to make room for workload %s (priority %d)",
+				victim.WorkloadID, victim.Priority, plan.WorkloadID, preemptor.Value))
+		decision.AddDetail("preemptedBy", plan.WorkloadID)
+		decision.AddDetail("victimPriority", victim.Priority)
+		decision.AddDetail("victimPriorityClass", victim.PriorityClass)
+		decisions = append(decisions, decision)
+		dependsOn = append(dependsOn, decision.ID)
+	}
+
+	schedule := newDecision(len(plan.Victims), types.DecisionTypeSchedule, plan.WorkloadID,
+		fmt.Sprintf("Schedule workload %s on node %s after preempting %d workload(s)", plan.WorkloadID, plan.NodeID, len(plan.Victims)))
+	schedule.ClusterID = ""
+	schedule.NodeID = ""
+	schedule.RecommendedCluster = plan.ClusterID
+	schedule.RecommendedNode = plan.NodeID
+	schedule.AddDetail("dependsOn", dependsOn)
+	schedule.AddDetail("priority", preemptor.Value)
+	schedule.AddDetail("priorityClass", preemptor.Class)
+	decisions = append(decisions, schedule)
+
+	return decisions
+}
+
+// resourceWeight returns a rough scalar size of a resource vector, used to
+// order otherwise equal candidates
+func resourceWeight(r resourceVector) float64 {
+	return float64(r.CPU) + float64(r.Memory)/(1024*1024*1024) + float64(r.GPU)*8 + float64(r.NPU)*8
+}
@@ -0,0 +1,197 @@
+package optimizer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/decisions"
+	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// testLogger discards log output
+type testLogger struct{}
+
+func (l testLogger) Info(msg string, fields ...interface{})                    {}
+func (l testLogger) Warn(msg string, fields ...interface{})                    {}
+func (l testLogger) Error(msg string, fields ...interface{})                   {}
+func (l testLogger) Debug(msg string, fields ...interface{})                   {}
+func (l testLogger) Fatal(msg string, fields ...interface{})                   {}
+func (l testLogger) WithError(err error) types.Logger                          { return l }
+func (l testLogger) WithDuration(duration time.Duration) types.Logger          { return l }
+func (l testLogger) WithPolicy(policyID, policyName string) types.Logger       { return l }
+func (l testLogger) WithWorkload(workloadID, workloadType string) types.Logger { return l }
+func (l testLogger) WithEvaluation(evaluationID string) types.Logger           { return l }
+
+func newTestPreemptionPlanner() (PreemptionPlanner, storage.StorageManager) {
+	store := memory.NewStorageManager()
+	lifecycle := decisions.NewLifecycle(store, config.DecisionConfig{PendingTTL: time.Hour}, testLogger{})
+	return NewPreemptionPlanner(lifecycle, testLogger{}), store
+}
+
+func preemptionNode(id string, freeCPU int, freeMemory string) *evaluator.NodeInfo {
+	return &evaluator.NodeInfo{
+		ID:        id,
+		ClusterID: "cluster-a",
+		Available: &evaluator.ResourceAvailability{CPU: freeCPU, Memory: freeMemory},
+	}
+}
+
+func runningWorkload(id, node string, priority types.Priority, cpu int) *types.Workload {
+	return &types.Workload{
+		ID:           id,
+		Name:         id,
+		Status:       types.WorkloadStatusRunning,
+		Priority:     priority,
+		Labels:       map[string]string{"node": node},
+		Requirements: types.Resources{CPU: cpu, Memory: "1Gi"},
+	}
+}
+
+func neverPolicy() *types.WorkloadPriorityPolicy {
+	return &types.WorkloadPriorityPolicy{
+		Metadata: types.PolicyMetadata{Name: "priorities"},
+		Spec: types.WorkloadPrioritySpec{
+			PriorityClasses: []types.PriorityClass{
+				{Name: "critical", Value: 1000},
+				{Name: "reserved", Value: 100, PreemptionPolicy: evaluator.PreemptionPolicyNever},
+			},
+			WorkloadMapping: []types.WorkloadMapping{
+				{Pattern: "critical-*", PriorityClass: "critical"},
+				{Pattern: "reserved-*", PriorityClass: "reserved"},
+			},
+		},
+	}
+}
+
+func TestPreemptionPlanner_PicksMinimalVictimSet(t *testing.T) {
+	planner, _ := newTestPreemptionPlanner()
+	preemptor := &types.Workload{ID: "train", Priority: 500, Requirements: types.Resources{CPU: 4, Memory: "1Gi"}}
+
+	plan, err := planner.Plan(context.Background(), &PreemptionRequest{
+		Workload: preemptor,
+		Nodes: []*evaluator.NodeInfo{
+			preemptionNode("node-1", 0, "4Gi"),
+			preemptionNode("node-2", 0, "4Gi"),
+		},
+		Running: []*types.Workload{
+			// node-1 needs two evictions, node-2 only one
+			runningWorkload("small-1", "node-1", 10, 2),
+			runningWorkload("small-2", "node-1", 20, 2),
+			runningWorkload("big", "node-2", 50, 4),
+			runningWorkload("tiny", "node-2", 5, 1),
+			runningWorkload("peer", "node-2", 500, 8),
+		},
+		PolicyID: "priority-policy",
+	})
+	require.NoError(t, err)
+	require.True(t, plan.Feasible)
+	assert.Equal(t, "node-2", plan.NodeID)
+	require.Len(t, plan.Victims, 1)
+	assert.Equal(t, "big", plan.Victims[0].WorkloadID)
+}
+
+func TestPreemptionPlanner_NoPreemptionWhenCapacityIsFree(t *testing.T) {
+	planner, _ := newTestPreemptionPlanner()
+
+	plan, err := planner.Plan(context.Background(), &PreemptionRequest{
+		Workload: &types.Workload{ID: "train", Priority: 500, Requirements: types.Resources{CPU: 2, Memory: "1Gi"}},
+		Nodes:    []*evaluator.NodeInfo{preemptionNode("node-1", 4, "4Gi")},
+		Running:  []*types.Workload{runningWorkload("low", "node-1", 1, 2)},
+		PolicyID: "priority-policy",
+	})
+	require.NoError(t, err)
+	assert.True(t, plan.Feasible)
+	assert.Empty(t, plan.Victims)
+	require.Len(t, plan.Decisions, 1)
+	assert.Equal(t, types.DecisionTypeSchedule, plan.Decisions[0].Type)
+}
+
+func TestPreemptionPlanner_NeverClassCanBeVictim(t *testing.T) {
+	planner, _ := newTestPreemptionPlanner()
+
+	plan, err := planner.Plan(context.Background(), &PreemptionRequest{
+		Workload:       &types.Workload{ID: "critical-1", Name: "critical-1", Requirements: types.Resources{CPU: 4, Memory: "1Gi"}},
+		Nodes:          []*evaluator.NodeInfo{preemptionNode("node-1", 0, "4Gi")},
+		Running:        []*types.Workload{runningWorkload("reserved-1", "node-1", 0, 4)},
+		PriorityPolicy: neverPolicy(),
+		PolicyID:       "priority-policy",
+	})
+	require.NoError(t, err)
+	require.True(t, plan.Feasible)
+	require.Len(t, plan.Victims, 1)
+	assert.Equal(t, "reserved-1", plan.Victims[0].WorkloadID)
+	assert.Equal(t, "reserved", plan.Victims[0].PriorityClass)
+}
+
+func TestPreemptionPlanner_NeverClassDoesNotPreempt(t *testing.T) {
+	planner, _ := newTestPreemptionPlanner()
+
+	plan, err := planner.Plan(context.Background(), &PreemptionRequest{
+		Workload:       &types.Workload{ID: "reserved-1", Name: "reserved-1", Requirements: types.Resources{CPU: 4, Memory: "1Gi"}},
+		Nodes:          []*evaluator.NodeInfo{preemptionNode("node-1", 0, "4Gi")},
+		Running:        []*types.Workload{runningWorkload("batch-1", "node-1", 1, 4)},
+		PriorityPolicy: neverPolicy(),
+		PolicyID:       "priority-policy",
+	})
+	require.NoError(t, err)
+	assert.False(t, plan.Feasible)
+	assert.Empty(t, plan.Victims)
+	assert.Contains(t, plan.Reason, "does not allow preemption")
+}
+
+func TestPreemptionPlanner_LinksDecisions(t *testing.T) {
+	planner, store := newTestPreemptionPlanner()
+
+	plan, err := planner.Plan(context.Background(), &PreemptionRequest{
+		Workload: &types.Workload{ID: "train", Priority: 500, Requirements: types.Resources{CPU: 4, Memory: "1Gi"}},
+		Nodes:    []*evaluator.NodeInfo{preemptionNode("node-1", 0, "4Gi")},
+		Running: []*types.Workload{
+			runningWorkload("low-1", "node-1", 10, 2),
+			runningWorkload("low-2", "node-1", 20, 2),
+		},
+		PolicyID: "priority-policy",
+	})
+	require.NoError(t, err)
+	require.Len(t, plan.Decisions, 3)
+
+	var suspendIDs []string
+	for _, decision := range plan.Decisions[:2] {
+		assert.Equal(t, types.DecisionTypeSuspend, decision.Type)
+		assert.Equal(t, types.DecisionReasonPreemption, decision.Reason)
+		assert.Equal(t, plan.ID, decision.Details["preemptionPlanId"])
+		assert.Equal(t, "train", decision.Details["preemptedBy"])
+		suspendIDs = append(suspendIDs, decision.ID)
+	}
+
+	schedule := plan.Decisions[2]
+	assert.Equal(t, types.DecisionTypeSchedule, schedule.Type)
+	assert.Equal(t, "train", schedule.WorkloadID)
+	assert.Equal(t, "priority-policy", schedule.PolicyID)
+	assert.Equal(t, "node-1", schedule.RecommendedNode)
+	assert.Equal(t, suspendIDs, schedule.Details["dependsOn"])
+
+	// Every decision of the plan is created pending
+	for _, decision := range plan.Decisions {
+		stored, err := store.Decision().Get(context.Background(), decision.ID)
+		require.NoError(t, err)
+		assert.Equal(t, types.DecisionStatusPending, stored.Status, decision.ID)
+		assert.NotNil(t, stored.ExpiresAt, decision.ID)
+	}
+}
+
+func TestPreemptionPlanner_RequiresWorkload(t *testing.T) {
+	planner, _ := newTestPreemptionPlanner()
+	_, err := planner.Plan(context.Background(), &PreemptionRequest{})
+	assert.Error(t, err)
+
+	_, err = planner.Plan(context.Background(), &PreemptionRequest{Workload: &types.Workload{ID: "train"}})
+	assert.ErrorContains(t, err, "policy")
+}
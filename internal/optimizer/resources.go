@@ -0,0 +1,191 @@
+package optimizer
+
+import (
+	"fmt"
+
+	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// resourceVector is a comparable set of schedulable resources
+type resourceVector struct {
+	CPU     int
+	Memory  int64
+	GPU     int
+	GPUType string
+	NPU     int
+	NPUType string
+}
+
+// workloadDemand returns the resources requested by a workload
+func workloadDemand(workload *types.Workload) (resourceVector, error) {
+	memory, err := evaluator.ParseMemoryString(workload.Requirements.Memory)
+	if err != nil {
+		return resourceVector{}, fmt.Errorf("workload %s: %w", workload.ID, err)
+	}
+
+	demand := resourceVector{
+		CPU:    workload.Requirements.CPU,
+		Memory: memory,
+	}
+	if gpu := workload.Requirements.GPU; gpu != nil {
+		demand.GPU = gpu.Count
+		demand.GPUType = gpu.Type
+	}
+	if npu := workload.Requirements.NPU; npu != nil {
+		demand.NPU = npu.Count
+		demand.NPUType = npu.Type
+	}
+
+	return demand, nil
+}
+
+// nodeFree returns the unallocated resources of a node, preferring the
+// reported availability and falling back to capacity minus allocation
+func nodeFree(node *evaluator.NodeInfo) (resourceVector, error) {
+	if node.Available != nil {
+		return resourcesFrom(node.Available.CPU, node.Available.Memory, node.Available.GPU, node.Available.NPU)
+	}
+
+	capacity, err := nodeCapacity(node)
+	if err != nil {
+		return resourceVector{}, err
+	}
+	if node.Allocated == nil {
+		return capacity, nil
+	}
+
+	allocated, err := resourcesFrom(node.Allocated.CPU, node.Allocated.Memory, node.Allocated.GPU, node.Allocated.NPU)
+	if err != nil {
+		return resourceVector{}, fmt.Errorf("node %s allocation: %w", node.ID, err)
+	}
+	return capacity.sub(allocated), nil
+}
+
+// nodeCapacity returns the total resources of a node
+func nodeCapacity(node *evaluator.NodeInfo) (resourceVector, error) {
+	if node.Capacity == nil {
+		return resourceVector{}, fmt.Errorf("node %s has no capacity information", node.ID)
+	}
+
+	capacity, err := resourcesFrom(node.Capacity.CPU, node.Capacity.Memory, node.Capacity.GPU, node.Capacity.NPU)
+	if err != nil {
+		return resourceVector{}, fmt.Errorf("node %s capacity: %w", node.ID, err)
+	}
+	return capacity, nil
+}
+
+// resourcesFrom builds a resource vector from evaluator resource fields
+func resourcesFrom(cpu int, memory string, gpu *evaluator.GPUResource, npu *evaluator.NPUResource) (resourceVector, error) {
+	bytes, err := evaluator.ParseMemoryString(memory)
+	if err != nil {
+		return resourceVector{}, err
+	}
+
+	vector := resourceVector{CPU: cpu, Memory: bytes}
+	if gpu != nil {
+		vector.GPU = gpu.Count
+		vector.GPUType = gpu.Type
+	}
+	if npu != nil {
+		vector.NPU = npu.Count
+		vector.NPUType = npu.Type
+	}
+	return vector, nil
+}
+
+// add returns the sum of two resource vectors, keeping the receiver's
+// accelerator types
+func (r resourceVector) add(other resourceVector) resourceVector {
+	r.CPU += other.CPU
+	r.Memory += other.Memory
+	r.GPU += other.GPU
+	r.NPU += other.NPU
+	return r
+}
+
+// sub returns the difference of two resource vectors, keeping the
+// receiver's accelerator types
+func (r resourceVector) sub(other resourceVector) resourceVector {
+	r.CPU -= other.CPU
+	r.Memory -= other.Memory
+	r.GPU -= other.GPU
+	r.NPU -= other.NPU
+	return r
+}
+
+// fits returns true if demand fits within the receiver
+func (r resourceVector) fits(demand resourceVector) bool {
+	if demand.CPU > r.CPU || demand.Memory > r.Memory || demand.GPU > r.GPU || demand.NPU > r.NPU {
+		return false
+	}
+	return r.acceleratorsMatch(demand)
+}
+
+// acceleratorsMatch returns true if the receiver provides the accelerator
+// types requested by demand
+func (r resourceVector) acceleratorsMatch(demand resourceVector) bool {
+	if demand.GPU > 0 && demand.GPUType != "" && r.GPUType != "" && demand.GPUType != r.GPUType {
+		return false
+	}
+	if demand.NPU > 0 && demand.NPUType != "" && r.NPUType != "" && demand.NPUType != r.NPUType {
+		return false
+	}
+	return true
+}
+
+// workloadNode returns the node a workload is placed on
+func workloadNode(workload *types.Workload) string {
+	if node, exists := workload.Labels["node"]; exists {
+		return node
+	}
+	return workload.Labels["node-id"]
+}
+
+// workloadCluster returns the cluster a workload is placed on
+func workloadCluster(workload *types.Workload) string {
+	if cluster, exists := workload.Labels["cluster"]; exists {
+		return cluster
+	}
+	return workload.Labels["cluster-id"]
+}
+
+// clusterAllowed returns true if the workload's constraints permit the cluster
+func clusterAllowed(workload *types.Workload, clusterID string) bool {
+	if workload.Constraints == nil {
+		return true
+	}
+	for _, forbidden := range workload.Constraints.ForbiddenClusters {
+		if forbidden == clusterID {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeSelectorsMatch returns true if the node satisfies the workload's
+// node selectors
+func nodeSelectorsMatch(workload *types.Workload, node *evaluator.NodeInfo) bool {
+	if workload.Constraints == nil {
+		return true
+	}
+	for key, value := range workload.Constraints.NodeSelectors {
+		if node.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// formatMemory formats a byte count as a Kubernetes quantity, using Gi when
+// the value is a whole number of GiB and Mi otherwise
+func formatMemory(bytes int64) string {
+	const (
+		mi = 1024 * 1024
+		gi = 1024 * mi
+	)
+	if bytes > 0 && bytes%gi == 0 {
+		return fmt.Sprintf("%dGi", bytes/gi)
+	}
+	return fmt.Sprintf("%dMi", (bytes+mi-1)/mi)
+}
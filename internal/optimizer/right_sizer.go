@@ -0,0 +1,344 @@
+package optimizer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// memoryGranularity is the step recommended memory values are rounded up to
+const memoryGranularity = 64 * 1024 * 1024
+
+// rightSizer implements RightSizingAnalyzer interface
+type rightSizer struct {
+	storage   storage.StorageManager
+	costModel CostModel
+	defaults  RightSizingOptions
+	logger    types.Logger
+}
+
+// NewRightSizingAnalyzer creates a new right-sizing analyzer
+func NewRightSizingAnalyzer(storage storage.StorageManager, costModel CostModel, cfg config.OptimizerConfig, logger types.Logger) RightSizingAnalyzer {
+	headroom := cfg.RightSizingHeadroom
+	return &rightSizer{
+		storage:   storage,
+		costModel: costModel,
+		defaults: RightSizingOptions{
+			Window:     cfg.RightSizingWindow,
+			Percentile: cfg.RightSizingPercentile,
+			Headroom:   &headroom,
+			MinSamples: cfg.RightSizingMinSamples,
+		},
+		logger: logger,
+	}
+}
+
+// Analyze computes right-sizing recommendations for a workload. Usage
+// samples are percentages of the current request, as elsewhere in the
+// engine, and are converted to absolute quantities before being summarized:
+// CPU in cores, memory in bytes and GPU/NPU in devices in use.
+func (r *rightSizer) Analyze(ctx context.Context, workloadID string, options *RightSizingOptions) (*RightSizingReport, error) {
+	opts := r.resolveOptions(options)
+	if opts.Percentile <= 0 || opts.Percentile > 100 {
+		return nil, fmt.Errorf("percentile must be in (0, 100], got %v", opts.Percentile)
+	}
+	if opts.Window <= 0 {
+		return nil, fmt.Errorf("window must be positive, got %s", opts.Window)
+	}
+	if opts.Headroom == nil || *opts.Headroom < 0 {
+		return nil, fmt.Errorf("headroom must be a non-negative fraction")
+	}
+	headroom := *opts.Headroom
+
+	workload, err := r.storage.Workload().Get(ctx, workloadID)
+	if err != nil {
+		return nil, err
+	}
+
+	endTime := time.Now()
+	samples, err := r.storage.Workload().GetMetrics(ctx, workloadID, endTime.Add(-opts.Window), endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RightSizingReport{
+		WorkloadID:  workloadID,
+		Window:      opts.Window.String(),
+		Percentile:  opts.Percentile,
+		Samples:     len(samples),
+		Current:     workload.Requirements,
+		Recommended: workload.Requirements,
+		Usage:       make(map[string]*UsageStats),
+		Currency:    r.costModel.Currency(),
+		GeneratedAt: endTime,
+	}
+
+	currentCost, err := r.costModel.HourlyCost(&workload.Requirements)
+	if err != nil {
+		return nil, err
+	}
+	report.CurrentCostPerHour = currentCost
+	report.RecommendedCostPerHour = currentCost
+
+	if len(samples) < opts.MinSamples || len(samples) == 0 {
+		r.logger.WithWorkload(workloadID, string(workload.Type)).Info("insufficient metrics for right-sizing",
+			"samples", len(samples), "min_samples", opts.MinSamples)
+		return report, nil
+	}
+	report.Sufficient = true
+
+	requestedMemory, err := evaluator.ParseMemoryString(workload.Requirements.Memory)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := map[string]float64{
+		"cpu":    float64(workload.Requirements.CPU),
+		"memory": float64(requestedMemory),
+	}
+	if gpu := workload.Requirements.GPU; gpu != nil {
+		requested["gpu"] = float64(gpu.Count)
+	}
+	if npu := workload.Requirements.NPU; npu != nil {
+		requested["npu"] = float64(npu.Count)
+	}
+
+	usage := map[string][]float64{}
+	for _, sample := range samples {
+		usage["cpu"] = append(usage["cpu"], absoluteUsage(sample.CPUUsage, requested["cpu"]))
+		usage["memory"] = append(usage["memory"], absoluteUsage(sample.MemoryUsage, requested["memory"]))
+		usage["gpu"] = append(usage["gpu"], absoluteUsage(sample.GPUUsage, requested["gpu"]))
+		usage["npu"] = append(usage["npu"], absoluteUsage(sample.NPUUsage, requested["npu"]))
+	}
+
+	for resource, requestedValue := range requested {
+		report.Usage[resource] = summarizeUsage(usage[resource], requestedValue, opts.Percentile)
+	}
+
+	// Resources that never reported usage keep their current request
+	recommended := workload.Requirements
+	if stats := report.Usage["cpu"]; stats.Max > 0 {
+		recommended.CPU = recommendCount(stats, headroom)
+	}
+	if stats := report.Usage["memory"]; stats.Max > 0 {
+		recommended.Memory = formatMemory(recommendMemory(stats, headroom))
+	}
+	if gpu := workload.Requirements.GPU; gpu != nil && report.Usage["gpu"].Max > 0 {
+		resized := *gpu
+		resized.Count = recommendCount(report.Usage["gpu"], headroom)
+		recommended.GPU = &resized
+	}
+	if npu := workload.Requirements.NPU; npu != nil && report.Usage["npu"].Max > 0 {
+		resized := *npu
+		resized.Count = recommendCount(report.Usage["npu"], headroom)
+		recommended.NPU = &resized
+	}
+	report.Recommended = recommended
+
+	recommendedCost, err := r.costModel.HourlyCost(&recommended)
+	if err != nil {
+		return nil, err
+	}
+	report.RecommendedCostPerHour = recommendedCost
+	report.EstimatedSavingsPerHour = currentCost - recommendedCost
+	report.EstimatedMonthlySavings = report.EstimatedSavingsPerHour * hoursPerMonth
+
+	recommendations, err := r.buildRecommendations(workload, report, currentCost)
+	if err != nil {
+		return nil, err
+	}
+	report.Recommendations = recommendations
+
+	r.logger.WithWorkload(workloadID, string(workload.Type)).Info("right-sizing analysis completed",
+		"samples", len(samples), "recommendations", len(recommendations), "savings_per_hour", report.EstimatedSavingsPerHour)
+
+	return report, nil
+}
+
+// Health checks the health of the right-sizing analyzer
+func (r *rightSizer) Health(ctx context.Context) error {
+	if r.storage == nil {
+		return fmt.Errorf("storage not configured")
+	}
+	return r.storage.Health(ctx)
+}
+
+// resolveOptions fills unset options from the configured defaults
+func (r *rightSizer) resolveOptions(options *RightSizingOptions) RightSizingOptions {
+	opts := r.defaults
+	if options == nil {
+		return opts
+	}
+	if options.Window > 0 {
+		opts.Window = options.Window
+	}
+	if options.Percentile > 0 {
+		opts.Percentile = options.Percentile
+	}
+	if options.Headroom != nil {
+		opts.Headroom = options.Headroom
+	}
+	if options.MinSamples > 0 {
+		opts.MinSamples = options.MinSamples
+	}
+	return opts
+}
+
+// buildRecommendations emits one recommendation per resource whose
+// recommended value differs from the current request
+func (r *rightSizer) buildRecommendations(workload *types.Workload, report *RightSizingReport, currentCost float64) ([]types.Recommendation, error) {
+	current := workload.Requirements
+	recommended := report.Recommended
+
+	type change struct {
+		resource    string
+		current     interface{}
+		recommended interface{}
+		increase    bool
+		apply       func(*types.Resources)
+	}
+
+	var changes []change
+	if current.CPU != recommended.CPU {
+		changes = append(changes, change{"cpu", current.CPU, recommended.CPU, recommended.CPU > current.CPU,
+			func(res *types.Resources) { res.CPU = recommended.CPU }})
+	}
+	currentMemory, _ := evaluator.ParseMemoryString(current.Memory)
+	recommendedMemory, _ := evaluator.ParseMemoryString(recommended.Memory)
+	if currentMemory != recommendedMemory {
+		changes = append(changes, change{"memory", current.Memory, recommended.Memory, recommendedMemory > currentMemory,
+			func(res *types.Resources) { res.Memory = recommended.Memory }})
+	}
+	if current.GPU != nil && current.GPU.Count != recommended.GPU.Count {
+		changes = append(changes, change{"gpu", current.GPU.Count, recommended.GPU.Count, recommended.GPU.Count > current.GPU.Count,
+			func(res *types.Resources) { res.GPU = recommended.GPU }})
+	}
+	if current.NPU != nil && current.NPU.Count != recommended.NPU.Count {
+		changes = append(changes, change{"npu", current.NPU.Count, recommended.NPU.Count, recommended.NPU.Count > current.NPU.Count,
+			func(res *types.Resources) { res.NPU = recommended.NPU }})
+	}
+
+	now := time.Now()
+	recommendations := make([]types.Recommendation, 0, len(changes))
+	for _, c := range changes {
+		resized := current
+		c.apply(&resized)
+		resizedCost, err := r.costModel.HourlyCost(&resized)
+		if err != nil {
+			return nil, err
+		}
+		savings := currentCost - resizedCost
+		stats := report.Usage[c.resource]
+
+		recommendation := types.Recommendation{
+			Type:   "rightsizing",
+			Action: "resize_" + c.resource,
+			Effort: "low",
+			Details: map[string]interface{}{
+				"resource":         c.resource,
+				"current":          c.current,
+				"recommended":      c.recommended,
+				"percentile":       report.Percentile,
+				"percentileUsage":  stats.Percentile,
+				"meanUsage":        stats.Mean,
+				"maxUsage":         stats.Max,
+				"savingsPerHour":   savings,
+				"savingsPerMonth":  savings * hoursPerMonth,
+				"currency":         report.Currency,
+				"samples":          report.Samples,
+				"observationRange": report.Window,
+			},
+			Timestamp: now,
+		}
+
+		if c.increase {
+			recommendation.Priority = "high"
+			recommendation.Impact = "performance"
+			recommendation.Message = fmt.Sprintf("Increase %s request from %v to %v: p%.0f usage %s exceeds the current request",
+				c.resource, c.current, c.recommended, report.Percentile, formatUsage(c.resource, stats.Percentile))
+		} else {
+			recommendation.Priority = "medium"
+			if currentCost > 0 && savings/currentCost >= 0.25 {
+				recommendation.Priority = "high"
+			}
+			recommendation.Impact = "cost_reduction"
+			recommendation.Message = fmt.Sprintf("Reduce %s request from %v to %v based on p%.0f usage %s, saving %.2f %s/hour",
+				c.resource, c.current, c.recommended, report.Percentile, formatUsage(c.resource, stats.Percentile), savings, report.Currency)
+		}
+
+		recommendations = append(recommendations, recommendation)
+	}
+
+	return recommendations, nil
+}
+
+// formatUsage formats an observed usage value for display
+func formatUsage(resource string, value float64) string {
+	if resource == "memory" {
+		return formatMemory(int64(math.Ceil(value)))
+	}
+	return fmt.Sprintf("%.2f", value)
+}
+
+// absoluteUsage converts a usage percentage of the requested amount into
+// an absolute quantity
+func absoluteUsage(percent, requested float64) float64 {
+	return percent / 100 * requested
+}
+
+// summarizeUsage computes mean, percentile and max of the samples
+func summarizeUsage(samples []float64, requested, percentile float64) *UsageStats {
+	stats := &UsageStats{Requested: requested}
+	if len(samples) == 0 {
+		return stats
+	}
+
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, value := range sorted {
+		sum += value
+	}
+	stats.Mean = sum / float64(len(sorted))
+	stats.Max = sorted[len(sorted)-1]
+	stats.Percentile = percentileOf(sorted, percentile)
+	return stats
+}
+
+// percentileOf returns the nearest-rank percentile of sorted samples
+func percentileOf(sorted []float64, percentile float64) float64 {
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// recommendCount recommends a whole-unit request of at least one covering
+// percentile usage plus headroom
+func recommendCount(stats *UsageStats, headroom float64) int {
+	count := int(math.Ceil(stats.Percentile * (1 + headroom)))
+	if count < 1 {
+		count = 1
+	}
+	return count
+}
+
+// recommendMemory recommends a memory request in bytes covering percentile
+// usage plus headroom, rounded up to memoryGranularity
+func recommendMemory(stats *UsageStats, headroom float64) int64 {
+	target := int64(math.Ceil(stats.Percentile * (1 + headroom)))
+	steps := (target + memoryGranularity - 1) / memoryGranularity
+	if steps < 1 {
+		steps = 1
+	}
+	return steps * memoryGranularity
+}
@@ -0,0 +1,108 @@
+package optimizer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+func newTestRightSizer(t *testing.T, cpuPercent, memoryPercent float64) RightSizingAnalyzer {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.NewStorageManager()
+
+	require.NoError(t, store.Workload().Create(ctx, &types.Workload{
+		ID:           "web-1",
+		Name:         "web-1",
+		Type:         types.WorkloadTypeWeb,
+		Status:       types.WorkloadStatusRunning,
+		Requirements: types.Resources{CPU: 8, Memory: "8Gi"},
+	}))
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, store.Workload().RecordMetrics(ctx, &types.WorkloadMetrics{
+			WorkloadID:  "web-1",
+			CPUUsage:    cpuPercent,
+			MemoryUsage: memoryPercent,
+			Timestamp:   now.Add(-time.Duration(i+1) * time.Minute),
+		}))
+	}
+
+	costModel := NewCostModel(config.CostConfig{CPUPerHour: 0.05, MemoryGiBPerHour: 0.01})
+	analyzer := NewRightSizingAnalyzer(store, costModel, config.OptimizerConfig{
+		RightSizingWindow:     time.Hour,
+		RightSizingPercentile: 95,
+		RightSizingHeadroom:   0.2,
+		RightSizingMinSamples: 5,
+	}, testLogger{})
+	return analyzer
+}
+
+func TestRightSizer_ReadsUsageAsPercentOfRequest(t *testing.T) {
+	analyzer := newTestRightSizer(t, 25, 50)
+
+	report, err := analyzer.Analyze(context.Background(), "web-1", nil)
+	require.NoError(t, err)
+	require.True(t, report.Sufficient)
+
+	// 25% of 8 cores is 2 cores, plus 20% headroom rounds up to 3
+	assert.InDelta(t, 2.0, report.Usage["cpu"].Percentile, 1e-9)
+	assert.Equal(t, 3, report.Recommended.CPU)
+
+	// 50% of 8Gi is 4Gi, plus 20% headroom rounded to 64Mi steps
+	assert.InDelta(t, float64(4<<30), report.Usage["memory"].Percentile, 1)
+	assert.Equal(t, "4928Mi", report.Recommended.Memory)
+
+	assert.Greater(t, report.EstimatedSavingsPerHour, 0.0)
+	require.Len(t, report.Recommendations, 2)
+	for _, recommendation := range report.Recommendations {
+		assert.Equal(t, "cost_reduction", recommendation.Impact)
+	}
+}
+
+func TestRightSizer_ExplicitZeroHeadroom(t *testing.T) {
+	analyzer := newTestRightSizer(t, 25, 50)
+
+	headroom := 0.0
+	report, err := analyzer.Analyze(context.Background(), "web-1", &RightSizingOptions{Headroom: &headroom})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Recommended.CPU)
+	assert.Equal(t, "4Gi", report.Recommended.Memory)
+
+	negative := -0.1
+	_, err = analyzer.Analyze(context.Background(), "web-1", &RightSizingOptions{Headroom: &negative})
+	assert.Error(t, err)
+}
+
+func TestRightSizer_RecommendsIncreaseAboveRequest(t *testing.T) {
+	analyzer := newTestRightSizer(t, 150, 50)
+
+	report, err := analyzer.Analyze(context.Background(), "web-1", nil)
+	require.NoError(t, err)
+
+	// 150% of 8 cores is 12 cores, plus 20% headroom
+	assert.Equal(t, 15, report.Recommended.CPU)
+	for _, recommendation := range report.Recommendations {
+		if recommendation.Action == "resize_cpu" {
+			assert.Equal(t, "performance", recommendation.Impact)
+			assert.Equal(t, "high", recommendation.Priority)
+		}
+	}
+}
+
+func TestRightSizer_InsufficientSamples(t *testing.T) {
+	analyzer := newTestRightSizer(t, 25, 50)
+
+	report, err := analyzer.Analyze(context.Background(), "web-1", &RightSizingOptions{MinSamples: 50})
+	require.NoError(t, err)
+	assert.False(t, report.Sufficient)
+	assert.Equal(t, report.Current, report.Recommended)
+	assert.Empty(t, report.Recommendations)
+}
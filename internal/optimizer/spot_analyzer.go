@@ -0,0 +1,361 @@
+package optimizer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/decisions"
+	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// Workload annotations read by the spot analyzer
+const (
+	AnnotationCheckpointing         = "kcloud.io/checkpointing"
+	AnnotationInterruptionTolerance = "kcloud.io/interruption-tolerance"
+	AnnotationSLATier               = "kcloud.io/sla-tier"
+)
+
+// interruptionReasons are WorkloadHistory reasons that denote an interruption
+var interruptionReasons = map[string]bool{
+	"preempted":         true,
+	"interrupted":       true,
+	"evicted":           true,
+	"spot_interruption": true,
+}
+
+// spotIntolerantTypes are workload types that cannot survive interruption
+var spotIntolerantTypes = map[types.WorkloadType]string{
+	types.WorkloadTypeRealTime: "realtime workloads cannot tolerate interruption",
+	types.WorkloadTypeDatabase: "databases hold state that is lost on interruption",
+	types.WorkloadTypeStorage:  "storage workloads hold state that is lost on interruption",
+	types.WorkloadTypeCache:    "caches lose their working set on interruption",
+}
+
+// spotAnalyzer implements SpotAnalyzer interface
+type spotAnalyzer struct {
+	storage   storage.StorageManager
+	lifecycle decisions.Lifecycle
+	costModel CostModel
+	config    config.SpotConfig
+	logger    types.Logger
+}
+
+// NewSpotAnalyzer creates a new spot eligibility analyzer
+func NewSpotAnalyzer(storage storage.StorageManager, lifecycle decisions.Lifecycle, costModel CostModel, cfg config.SpotConfig, logger types.Logger) SpotAnalyzer {
+	return &spotAnalyzer{
+		storage:   storage,
+		lifecycle: lifecycle,
+		costModel: costModel,
+		config:    cfg,
+		logger:    logger,
+	}
+}
+
+// Analyze assesses spot eligibility. Only an eligible workload with spot
+// capacity available gets a pending migrate decision; otherwise the
+// assessment's reasons record why it was not moved and nothing is stored.
+func (a *spotAnalyzer) Analyze(ctx context.Context, request *SpotRequest) (*SpotAssessment, error) {
+	if request == nil || request.Workload == nil {
+		return nil, fmt.Errorf("spot request must include a workload")
+	}
+	if request.Policy == nil {
+		return nil, fmt.Errorf("spot request must include a cost optimization policy")
+	}
+
+	workload := request.Workload
+	assessment := &SpotAssessment{
+		WorkloadID: workload.ID,
+		Currency:   a.costModel.Currency(),
+	}
+
+	onDemandCost, err := a.costModel.HourlyCost(&workload.Requirements)
+	if err != nil {
+		return nil, err
+	}
+	assessment.OnDemandCostPerHour = onDemandCost
+
+	history, err := a.storage.Workload().GetHistory(ctx, workload.ID, 0)
+	if err != nil {
+		a.logger.WithError(err).WithWorkload(workload.ID, string(workload.Type)).Debug("no workload history for interruption analysis")
+	}
+
+	assessment.Checks = []SpotCheck{
+		a.checkPolicy(workload, request.Policy),
+		a.checkWorkloadType(workload),
+		a.checkSLA(workload, request.Policy),
+		a.checkRuntime(workload),
+		a.checkInterruptionTolerance(workload, history),
+	}
+
+	assessment.Eligible = true
+	for _, check := range assessment.Checks {
+		if !check.Passed {
+			assessment.Eligible = false
+			assessment.Reasons = append(assessment.Reasons, check.Reason)
+		}
+	}
+
+	if assessment.Eligible {
+		target, spotCost, err := a.selectTarget(workload, request.SpotClusters, onDemandCost)
+		if err != nil {
+			return nil, err
+		}
+		if target == nil {
+			assessment.Reasons = append(assessment.Reasons, "no spot cluster has capacity for the workload")
+		} else {
+			assessment.TargetCluster = target.ID
+			assessment.SpotCostPerHour = spotCost
+			assessment.EstimatedSavingsPerHour = onDemandCost - spotCost
+			assessment.EstimatedMonthlySavings = assessment.EstimatedSavingsPerHour * hoursPerMonth
+		}
+	}
+
+	if assessment.TargetCluster != "" {
+		assessment.Decision = a.buildDecision(workload, request.Policy, assessment)
+		if err := a.lifecycle.Create(ctx, assessment.Decision); err != nil {
+			return nil, fmt.Errorf("failed to record spot decision: %w", err)
+		}
+	}
+
+	a.logger.WithWorkload(workload.ID, string(workload.Type)).Info("spot eligibility analyzed",
+		"eligible", assessment.Eligible, "target_cluster", assessment.TargetCluster,
+		"savings_per_hour", assessment.EstimatedSavingsPerHour, "reasons", strings.Join(assessment.Reasons, "; "))
+
+	return assessment, nil
+}
+
+// Health checks the health of the spot analyzer
+func (a *spotAnalyzer) Health(ctx context.Context) error {
+	if a.storage == nil {
+		return fmt.Errorf("storage not configured")
+	}
+	return a.storage.Health(ctx)
+}
+
+// checkPolicy requires a workload policy for the workload's type that
+// allows spot instances
+func (a *spotAnalyzer) checkPolicy(workload *types.Workload, policy *types.CostOptimizationPolicy) SpotCheck {
+	check := SpotCheck{Name: "policy"}
+	for _, workloadPolicy := range policy.Spec.WorkloadPolicies {
+		if workloadPolicy.Type != string(workload.Type) {
+			continue
+		}
+		if workloadPolicy.AllowSpotInstances {
+			check.Passed = true
+			check.Reason = fmt.Sprintf("policy %s allows spot instances for %s workloads", policy.Metadata.Name, workload.Type)
+		} else {
+			check.Reason = fmt.Sprintf("policy %s does not allow spot instances for %s workloads", policy.Metadata.Name, workload.Type)
+		}
+		return check
+	}
+	check.Reason = fmt.Sprintf("policy %s has no workload policy for %s workloads", policy.Metadata.Name, workload.Type)
+	return check
+}
+
+// checkWorkloadType rejects workload types that cannot survive interruption
+func (a *spotAnalyzer) checkWorkloadType(workload *types.Workload) SpotCheck {
+	if reason, intolerant := spotIntolerantTypes[workload.Type]; intolerant {
+		return SpotCheck{Name: "workload_type", Reason: reason}
+	}
+	return SpotCheck{Name: "workload_type", Passed: true, Reason: fmt.Sprintf("%s workloads can be rescheduled after interruption", workload.Type)}
+}
+
+// checkSLA rejects workloads whose SLA is stricter than spot capacity offers
+func (a *spotAnalyzer) checkSLA(workload *types.Workload, policy *types.CostOptimizationPolicy) SpotCheck {
+	check := SpotCheck{Name: "sla"}
+
+	if tier := strings.ToLower(workload.Annotations[AnnotationSLATier]); tier == "critical" || tier == "gold" {
+		check.Reason = fmt.Sprintf("SLA tier %q requires on-demand capacity", tier)
+		return check
+	}
+	if workload.Priority >= types.PriorityCritical {
+		check.Reason = fmt.Sprintf("critical priority %d requires on-demand capacity", workload.Priority)
+		return check
+	}
+	if required := policy.Spec.Constraints.MinAvailabilityRatio; required > a.config.ExpectedAvailability {
+		check.Reason = fmt.Sprintf("policy requires availability %.4f but spot capacity offers %.4f", required, a.config.ExpectedAvailability)
+		return check
+	}
+	for _, workloadPolicy := range policy.Spec.WorkloadPolicies {
+		if workloadPolicy.Type == string(workload.Type) && workloadPolicy.MaxLatencyMs > 0 &&
+			(workload.Type == types.WorkloadTypeInference || workload.Type == types.WorkloadTypeWeb) {
+			check.Reason = fmt.Sprintf("latency SLA of %dms for serving workloads cannot absorb spot interruptions", workloadPolicy.MaxLatencyMs)
+			return check
+		}
+	}
+
+	check.Passed = true
+	check.Reason = "SLA permits interruptible capacity"
+	return check
+}
+
+// checkRuntime requires checkpointing for training and for runs that would
+// exceed the maximum uninterrupted runtime
+func (a *spotAnalyzer) checkRuntime(workload *types.Workload) SpotCheck {
+	check := SpotCheck{Name: "runtime"}
+	checkpointing := isEnabled(workload.Annotations[AnnotationCheckpointing])
+
+	var maxExecution time.Duration
+	if workload.Constraints != nil && workload.Constraints.MaxExecutionTime != "" {
+		parsed, err := time.ParseDuration(workload.Constraints.MaxExecutionTime)
+		if err != nil {
+			check.Reason = fmt.Sprintf("cannot parse maxExecutionTime %q: %v", workload.Constraints.MaxExecutionTime, err)
+			return check
+		}
+		maxExecution = parsed
+	}
+
+	switch {
+	case checkpointing:
+		check.Passed = true
+		check.Reason = "workload checkpoints and can resume after interruption"
+	case workload.Type == types.WorkloadTypeMLTraining:
+		check.Reason = fmt.Sprintf("training workloads need the %s annotation to resume after interruption", AnnotationCheckpointing)
+	case workload.Type == types.WorkloadTypeBatch && maxExecution == 0:
+		check.Reason = "batch workload has no maxExecutionTime and does not checkpoint"
+	case maxExecution > a.config.MaxUninterruptedRuntime:
+		check.Reason = fmt.Sprintf("maxExecutionTime %s exceeds %s without checkpointing", maxExecution, a.config.MaxUninterruptedRuntime)
+	default:
+		check.Passed = true
+		check.Reason = "workload is short or stateless enough to restart after interruption"
+	}
+	return check
+}
+
+// checkInterruptionTolerance rejects workloads that declare no tolerance or
+// that have historically failed after interruptions
+func (a *spotAnalyzer) checkInterruptionTolerance(workload *types.Workload, history []*types.WorkloadHistory) SpotCheck {
+	check := SpotCheck{Name: "interruption_tolerance"}
+
+	if tolerance := strings.ToLower(workload.Annotations[AnnotationInterruptionTolerance]); tolerance == "none" || tolerance == "false" {
+		check.Reason = fmt.Sprintf("workload declares %s=%s", AnnotationInterruptionTolerance, tolerance)
+		return check
+	}
+
+	interruptions, failures := 0, 0
+	for _, entry := range history {
+		if !interruptionReasons[strings.ToLower(entry.Reason)] {
+			continue
+		}
+		interruptions++
+		if entry.Status == types.WorkloadStatusFailed {
+			failures++
+		}
+	}
+
+	if interruptions == 0 {
+		check.Passed = true
+		check.Reason = "no interruption history; assuming tolerance"
+		return check
+	}
+
+	rate := float64(failures) / float64(interruptions)
+	if rate > a.config.MaxInterruptionFailureRate {
+		check.Reason = fmt.Sprintf("%d of %d past interruptions ended in failure (%.0f%% > %.0f%%)",
+			failures, interruptions, rate*100, a.config.MaxInterruptionFailureRate*100)
+		return check
+	}
+
+	check.Passed = true
+	check.Reason = fmt.Sprintf("recovered from %d of %d past interruptions", interruptions-failures, interruptions)
+	return check
+}
+
+// selectTarget picks the cheapest spot cluster with capacity for the workload
+func (a *spotAnalyzer) selectTarget(workload *types.Workload, clusters []*evaluator.ClusterInfo, onDemandCost float64) (*evaluator.ClusterInfo, float64, error) {
+	demand, err := workloadDemand(workload)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var best *evaluator.ClusterInfo
+	var bestCost float64
+	for _, cluster := range clusters {
+		if !isSpotCluster(cluster) || !clusterAllowed(workload, cluster.ID) {
+			continue
+		}
+		if cluster.Available != nil {
+			free, err := resourcesFrom(cluster.Available.CPU, cluster.Available.Memory, cluster.Available.GPU, cluster.Available.NPU)
+			if err != nil {
+				return nil, 0, fmt.Errorf("cluster %s availability: %w", cluster.ID, err)
+			}
+			if !free.fits(demand) {
+				continue
+			}
+		}
+
+		cost := a.spotCost(cluster, demand, onDemandCost)
+		if best == nil || cost < bestCost {
+			best, bestCost = cluster, cost
+		}
+	}
+
+	return best, bestCost, nil
+}
+
+// spotCost prices the demand on a spot cluster, using the cluster's own
+// rates when it publishes them and the configured discount otherwise
+func (a *spotAnalyzer) spotCost(cluster *evaluator.ClusterInfo, demand resourceVector, onDemandCost float64) float64 {
+	if cost := cluster.Cost; cost != nil && (cost.CostPerCPU > 0 || cost.CostPerMemory > 0 || cost.CostPerGPU > 0 || cost.CostPerNPU > 0) {
+		memoryGiB := float64(demand.Memory) / (1024 * 1024 * 1024)
+		return float64(demand.CPU)*cost.CostPerCPU + memoryGiB*cost.CostPerMemory +
+			float64(demand.GPU)*cost.CostPerGPU + float64(demand.NPU)*cost.CostPerNPU
+	}
+	return onDemandCost * (1 - a.config.Discount)
+}
+
+// buildDecision builds the migrate decision moving an eligible workload to
+// the selected spot cluster
+func (a *spotAnalyzer) buildDecision(workload *types.Workload, policy *types.CostOptimizationPolicy, assessment *SpotAssessment) *types.Decision {
+	now := time.Now()
+	decision := &types.Decision{
+		ID:         fmt.Sprintf("decision-%s-spot-%d", workload.ID, now.UnixNano()),
+		Type:       types.DecisionTypeMigrate,
+		Reason:     types.DecisionReasonCostOptimization,
+		WorkloadID: workload.ID,
+		PolicyID:   policy.Metadata.Name,
+		ClusterID:  workloadCluster(workload),
+		Details: map[string]interface{}{
+			"capacityType":        "spot",
+			"checks":              assessment.Checks,
+			"onDemandCostPerHour": assessment.OnDemandCostPerHour,
+			"currency":            assessment.Currency,
+		},
+		Metadata: types.DecisionMetadata{
+			Source:    "spot-analyzer",
+			Timestamp: now,
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	decision.Status = types.DecisionStatusPending
+	decision.RecommendedCluster = assessment.TargetCluster
+	decision.EstimatedCost = assessment.SpotCostPerHour
+	decision.Confidence = 0.8
+	decision.Message = fmt.Sprintf("Migrate workload %s to spot cluster %s, saving %.2f %s/hour",
+		workload.ID, assessment.TargetCluster, assessment.EstimatedSavingsPerHour, assessment.Currency)
+	decision.AddDetail("spotCostPerHour", assessment.SpotCostPerHour)
+	decision.AddDetail("savingsPerHour", assessment.EstimatedSavingsPerHour)
+	decision.AddDetail("savingsPerMonth", assessment.EstimatedMonthlySavings)
+	return decision
+}
+
+// isSpotCluster returns true if the cluster offers spot capacity
+func isSpotCluster(cluster *evaluator.ClusterInfo) bool {
+	return strings.EqualFold(cluster.Type, "spot") || strings.EqualFold(cluster.Labels["capacity-type"], "spot")
+}
+
+// isEnabled interprets an annotation value as a boolean switch
+func isEnabled(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "enabled", "yes", "1":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package optimizer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/decisions"
+	"github.com/kcloud-opt/policy/internal/evaluator"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+func newTestSpotAnalyzer() (SpotAnalyzer, storage.StorageManager) {
+	store := memory.NewStorageManager()
+	lifecycle := decisions.NewLifecycle(store, config.DecisionConfig{}, testLogger{})
+	costModel := NewCostModel(config.CostConfig{CPUPerHour: 0.05, MemoryGiBPerHour: 0.01})
+	analyzer := NewSpotAnalyzer(store, lifecycle, costModel, config.SpotConfig{
+		Discount:                   0.7,
+		ExpectedAvailability:       0.95,
+		MaxUninterruptedRuntime:    2 * time.Hour,
+		MaxInterruptionFailureRate: 0.2,
+	}, testLogger{})
+	return analyzer, store
+}
+
+func spotPolicy(workloadType types.WorkloadType, allowSpot bool) *types.CostOptimizationPolicy {
+	return &types.CostOptimizationPolicy{
+		Metadata: types.PolicyMetadata{Name: "cost"},
+		Spec: types.CostOptimizationSpec{
+			WorkloadPolicies: []types.WorkloadPolicy{
+				{Type: string(workloadType), AllowSpotInstances: allowSpot},
+			},
+		},
+	}
+}
+
+func spotWorkload() *types.Workload {
+	return &types.Workload{
+		ID:           "batch-1",
+		Name:         "batch-1",
+		Type:         types.WorkloadTypeBatch,
+		Labels:       map[string]string{"cluster": "on-demand"},
+		Requirements: types.Resources{CPU: 4, Memory: "8Gi"},
+		Constraints:  &types.WorkloadConstraints{MaxExecutionTime: "1h"},
+	}
+}
+
+func spotCluster(id string, freeCPU int) *evaluator.ClusterInfo {
+	return &evaluator.ClusterInfo{
+		ID:        id,
+		Type:      "spot",
+		Available: &evaluator.ResourceAvailability{CPU: freeCPU, Memory: "64Gi"},
+	}
+}
+
+func TestSpotAnalyzer_EligibleWorkloadGetsPendingDecision(t *testing.T) {
+	analyzer, store := newTestSpotAnalyzer()
+
+	assessment, err := analyzer.Analyze(context.Background(), &SpotRequest{
+		Workload:     spotWorkload(),
+		Policy:       spotPolicy(types.WorkloadTypeBatch, true),
+		SpotClusters: []*evaluator.ClusterInfo{spotCluster("spot-small", 2), spotCluster("spot-a", 16)},
+	})
+	require.NoError(t, err)
+	assert.True(t, assessment.Eligible)
+	assert.Equal(t, "spot-a", assessment.TargetCluster)
+	assert.InDelta(t, assessment.OnDemandCostPerHour*0.3, assessment.SpotCostPerHour, 1e-9)
+
+	require.NotNil(t, assessment.Decision)
+	assert.Equal(t, types.DecisionStatusPending, assessment.Decision.Status)
+	assert.Equal(t, types.DecisionTypeMigrate, assessment.Decision.Type)
+	assert.Equal(t, "spot-a", assessment.Decision.RecommendedCluster)
+
+	stored, err := store.Decision().GetByWorkload(context.Background(), "batch-1")
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, assessment.Decision.ID, stored[0].ID)
+}
+
+func TestSpotAnalyzer_IneligibleWorkloadStoresNothing(t *testing.T) {
+	tests := map[string]*SpotRequest{
+		"policy disallows spot": {
+			Workload:     spotWorkload(),
+			Policy:       spotPolicy(types.WorkloadTypeBatch, false),
+			SpotClusters: []*evaluator.ClusterInfo{spotCluster("spot-a", 16)},
+		},
+		"no spot capacity": {
+			Workload:     spotWorkload(),
+			Policy:       spotPolicy(types.WorkloadTypeBatch, true),
+			SpotClusters: []*evaluator.ClusterInfo{spotCluster("spot-small", 2)},
+		},
+	}
+
+	for name, request := range tests {
+		t.Run(name, func(t *testing.T) {
+			analyzer, store := newTestSpotAnalyzer()
+
+			assessment, err := analyzer.Analyze(context.Background(), request)
+			require.NoError(t, err)
+			assert.Empty(t, assessment.TargetCluster)
+			assert.NotEmpty(t, assessment.Reasons)
+			assert.Nil(t, assessment.Decision)
+
+			stored, err := store.Decision().GetByWorkload(context.Background(), "batch-1")
+			require.NoError(t, err)
+			assert.Empty(t, stored)
+		})
+	}
+}
+
+func TestSpotAnalyzer_Checks(t *testing.T) {
+	analyzer, _ := newTestSpotAnalyzer()
+	failed := func(request *SpotRequest) []string {
+		assessment, err := analyzer.Analyze(context.Background(), request)
+		require.NoError(t, err)
+		var names []string
+		for _, check := range assessment.Checks {
+			if !check.Passed {
+				names = append(names, check.Name)
+			}
+		}
+		return names
+	}
+
+	database := spotWorkload()
+	database.Type = types.WorkloadTypeDatabase
+	assert.Contains(t, failed(&SpotRequest{Workload: database, Policy: spotPolicy(types.WorkloadTypeDatabase, true)}), "workload_type")
+
+	critical := spotWorkload()
+	critical.Annotations = map[string]string{AnnotationSLATier: "gold"}
+	assert.Equal(t, []string{"sla"}, failed(&SpotRequest{Workload: critical, Policy: spotPolicy(types.WorkloadTypeBatch, true)}))
+
+	training := spotWorkload()
+	training.Type = types.WorkloadTypeMLTraining
+	assert.Equal(t, []string{"runtime"}, failed(&SpotRequest{Workload: training, Policy: spotPolicy(types.WorkloadTypeMLTraining, true)}))
+
+	training.Annotations = map[string]string{AnnotationCheckpointing: "true"}
+	assert.Empty(t, failed(&SpotRequest{Workload: training, Policy: spotPolicy(types.WorkloadTypeMLTraining, true)}))
+
+	intolerant := spotWorkload()
+	intolerant.Annotations = map[string]string{AnnotationInterruptionTolerance: "none"}
+	assert.Equal(t, []string{"interruption_tolerance"}, failed(&SpotRequest{Workload: intolerant, Policy: spotPolicy(types.WorkloadTypeBatch, true)}))
+}
@@ -77,6 +77,7 @@ type WorkloadStore interface {
 	Count(ctx context.Context, filters *WorkloadFilters) (int64, error)
 
 	// Metrics and history
+	RecordMetrics(ctx context.Context, metrics *types.WorkloadMetrics) error
 	GetMetrics(ctx context.Context, workloadID string, startTime, endTime time.Time) ([]*types.WorkloadMetrics, error)
 	GetHistory(ctx context.Context, workloadID string, limit int) ([]*types.WorkloadHistory, error)
 
@@ -110,6 +111,7 @@ type DecisionStore interface {
 	Count(ctx context.Context, filters *DecisionFilters) (int64, error)
 
 	// History and analytics
+	AddHistory(ctx context.Context, entry *types.DecisionHistory) error
 	GetHistory(ctx context.Context, decisionID string) ([]*types.DecisionHistory, error)
 	GetAnalytics(ctx context.Context, query *AnalyticsQuery) (*AnalyticsResult, error)
 
@@ -149,8 +151,55 @@ type EvaluationStore interface {
 	Close() error
 }
 
+// EnforcementStore defines the interface for enforcement state storage operations
+type EnforcementStore interface {
+	// Save creates or replaces the enforcement state of a decision
+	Save(ctx context.Context, status *types.EnforcementStatus) error
+	Get(ctx context.Context, decisionID string) (*types.EnforcementStatus, error)
+	Delete(ctx context.Context, decisionID string) error
+	List(ctx context.Context, filters *EnforcementFilters) ([]*types.EnforcementStatus, error)
+
+	// Health and maintenance
+	Health(ctx context.Context) error
+	Close() error
+}
+
+// MaintenanceStore defines the interface for maintenance window storage operations
+type MaintenanceStore interface {
+	// Basic CRUD operations
+	Create(ctx context.Context, window *types.MaintenanceWindow) error
+	Get(ctx context.Context, id string) (*types.MaintenanceWindow, error)
+	Update(ctx context.Context, window *types.MaintenanceWindow) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, filters *MaintenanceFilters) ([]*types.MaintenanceWindow, error)
+
+	// Health and maintenance
+	Health(ctx context.Context) error
+	Close() error
+}
+
+// ConditionStateStore defines the interface for storing how long the
+// duration qualified conditions of automation rules have held
+type ConditionStateStore interface {
+	// Save creates or replaces the state of a condition for a subject
+	Save(ctx context.Context, state *types.ConditionState) error
+	Get(ctx context.Context, ruleID, condition, subject string) (*types.ConditionState, error)
+	Delete(ctx context.Context, ruleID, condition, subject string) error
+	List(ctx context.Context, filters *ConditionStateFilters) ([]*types.ConditionState, error)
+
+	// Health and maintenance
+	Health(ctx context.Context) error
+	Close() error
+}
+
 // Filter structures for different store types
 
+// MaintenanceFilters defines filters for maintenance window queries
+type MaintenanceFilters struct {
+	Kind    *types.MaintenanceWindowKind `json:"kind,omitempty"`
+	Enabled *bool                        `json:"enabled,omitempty"`
+}
+
 // PolicyFilters defines filters for policy queries
 type PolicyFilters struct {
 	Type      *types.PolicyType   `json:"type,omitempty"`
@@ -187,6 +236,19 @@ type DecisionFilters struct {
 	Offset     int                   `json:"offset,omitempty"`
 }
 
+// ConditionStateFilters defines filters for condition state queries
+type ConditionStateFilters struct {
+	RuleID  *string `json:"ruleId,omitempty"`
+	Subject *string `json:"subject,omitempty"`
+}
+
+// EnforcementFilters defines filters for enforcement queries
+type EnforcementFilters struct {
+	Status *types.EnforcementState `json:"status,omitempty"`
+	Limit  int                     `json:"limit,omitempty"`
+	Offset int                     `json:"offset,omitempty"`
+}
+
 // EvaluationFilters defines filters for evaluation queries
 type EvaluationFilters struct {
 	PolicyID   *string    `json:"policyId,omitempty"`
@@ -292,6 +354,9 @@ type StorageManager interface {
 	Workload() WorkloadStore
 	Decision() DecisionStore
 	Evaluation() EvaluationStore
+	Enforcement() EnforcementStore
+	Maintenance() MaintenanceStore
+	ConditionState() ConditionStateStore
 
 	// Transaction support
 	BeginTransaction(ctx context.Context) (Transaction, error)
@@ -311,6 +376,9 @@ type Transaction interface {
 	Workload() WorkloadStore
 	Decision() DecisionStore
 	Evaluation() EvaluationStore
+	Enforcement() EnforcementStore
+	Maintenance() MaintenanceStore
+	ConditionState() ConditionStateStore
 
 	// Transaction control
 	Commit() error
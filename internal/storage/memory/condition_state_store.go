@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// conditionStateKey identifies the state of a rule condition for a subject
+type conditionStateKey struct {
+	ruleID    string
+	condition string
+	subject   string
+}
+
+// memoryConditionStateStore implements ConditionStateStore interface using in-memory storage
+type memoryConditionStateStore struct {
+	states map[conditionStateKey]*types.ConditionState
+	mu     sync.RWMutex
+}
+
+// NewMemoryConditionStateStore creates a new memory-based condition state store
+func NewMemoryConditionStateStore() storage.ConditionStateStore {
+	return &memoryConditionStateStore{
+		states: make(map[conditionStateKey]*types.ConditionState),
+	}
+}
+
+// Save creates or replaces the state of a condition for a subject
+func (s *memoryConditionStateStore) Save(ctx context.Context, state *types.ConditionState) error {
+	if state == nil || state.RuleID == "" || state.Condition == "" {
+		return types.NewStorageError("condition_state", "save", storage.ErrStorageInvalidData)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stateCopy := *state
+	s.states[conditionStateKey{state.RuleID, state.Condition, state.Subject}] = &stateCopy
+
+	return nil
+}
+
+// Get retrieves the state of a condition for a subject
+func (s *memoryConditionStateStore) Get(ctx context.Context, ruleID, condition, subject string) (*types.ConditionState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, exists := s.states[conditionStateKey{ruleID, condition, subject}]
+	if !exists {
+		return nil, types.NewStorageError("condition_state", "get", storage.ErrStorageNotFound)
+	}
+
+	stateCopy := *state
+	return &stateCopy, nil
+}
+
+// Delete deletes the state of a condition for a subject
+func (s *memoryConditionStateStore) Delete(ctx context.Context, ruleID, condition, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := conditionStateKey{ruleID, condition, subject}
+	if _, exists := s.states[key]; !exists {
+		return types.NewStorageError("condition_state", "delete", storage.ErrStorageNotFound)
+	}
+	delete(s.states, key)
+
+	return nil
+}
+
+// List lists condition states with optional filters, oldest first
+func (s *memoryConditionStateStore) List(ctx context.Context, filters *storage.ConditionStateFilters) ([]*types.ConditionState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var states []*types.ConditionState
+	for _, state := range s.states {
+		if filters != nil && filters.RuleID != nil && state.RuleID != *filters.RuleID {
+			continue
+		}
+		if filters != nil && filters.Subject != nil && state.Subject != *filters.Subject {
+			continue
+		}
+		stateCopy := *state
+		states = append(states, &stateCopy)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Since.Before(states[j].Since)
+	})
+
+	return states, nil
+}
+
+// Health checks the health of the store
+func (s *memoryConditionStateStore) Health(ctx context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_ = len(s.states)
+
+	return nil
+}
+
+// Close closes the store
+func (s *memoryConditionStateStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states = make(map[conditionStateKey]*types.ConditionState)
+
+	return nil
+}
@@ -49,8 +49,9 @@ func (s *memoryDecisionStore) Create(ctx context.Context, decision *types.Decisi
 		return types.NewDecisionError(decisionID, string(decision.Type), decision.WorkloadID, decision.PolicyID, "create", err)
 	}
 
-	// Store decision
-	s.decisions[decisionID] = decision
+	// Store a copy the caller cannot change outside the store lock
+	decisionCopy := *decision
+	s.decisions[decisionID] = &decisionCopy
 
 	// Initialize empty history
 	s.history[decisionID] = []*types.DecisionHistory{}
@@ -93,8 +94,9 @@ func (s *memoryDecisionStore) Update(ctx context.Context, decision *types.Decisi
 		return types.NewDecisionError(decisionID, string(decision.Type), decision.WorkloadID, decision.PolicyID, "update", err)
 	}
 
-	// Update decision
-	s.decisions[decisionID] = decision
+	// Update decision with a copy
+	decisionCopy := *decision
+	s.decisions[decisionID] = &decisionCopy
 
 	return nil
 }
@@ -335,6 +337,23 @@ func (s *memoryDecisionStore) Count(ctx context.Context, filters *storage.Decisi
 	return count, nil
 }
 
+// AddHistory appends an entry to a decision's history
+func (s *memoryDecisionStore) AddHistory(ctx context.Context, entry *types.DecisionHistory) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.decisions[entry.DecisionID]; !exists {
+		return types.NewDecisionError(entry.DecisionID, "", entry.WorkloadID, "", "addHistory", types.ErrDecisionNotFound)
+	}
+
+	if entry.StartTime.IsZero() {
+		entry.StartTime = time.Now()
+	}
+	s.history[entry.DecisionID] = append(s.history[entry.DecisionID], entry)
+
+	return nil
+}
+
 // GetHistory retrieves decision execution history
 func (s *memoryDecisionStore) GetHistory(ctx context.Context, decisionID string) ([]*types.DecisionHistory, error) {
 	s.mu.RLock()
@@ -432,9 +451,14 @@ func (s *memoryDecisionStore) generateDecisionID(decision *types.Decision) strin
 	return fmt.Sprintf("decision-%s-%s-%d", decision.WorkloadID, string(decision.Type), time.Now().UnixNano())
 }
 
-// validateDecision validates a decision
+// validateDecision validates a decision. Consolidate decisions span many
+// workloads and are scoped to their cluster instead of a workload.
 func (s *memoryDecisionStore) validateDecision(decision *types.Decision) error {
-	if decision.WorkloadID == "" {
+	if decision.Type == types.DecisionTypeConsolidate {
+		if decision.ClusterID == "" {
+			return types.ErrInvalidDecisionType
+		}
+	} else if decision.WorkloadID == "" {
 		return types.ErrInvalidDecisionType
 	}
 	if decision.PolicyID == "" {
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// memoryEnforcementStore implements EnforcementStore interface using in-memory storage
+type memoryEnforcementStore struct {
+	enforcements map[string]*types.EnforcementStatus
+	mu           sync.RWMutex
+}
+
+// NewMemoryEnforcementStore creates a new memory-based enforcement store
+func NewMemoryEnforcementStore() storage.EnforcementStore {
+	return &memoryEnforcementStore{
+		enforcements: make(map[string]*types.EnforcementStatus),
+	}
+}
+
+// Save creates or replaces the enforcement state of a decision
+func (s *memoryEnforcementStore) Save(ctx context.Context, status *types.EnforcementStatus) error {
+	if status == nil || status.DecisionID == "" {
+		return types.NewStorageError("enforcement", "save", storage.ErrStorageInvalidData)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Store a copy so later changes by the caller are only seen once saved
+	statusCopy := status.Copy()
+	statusCopy.UpdatedAt = time.Now()
+	s.enforcements[status.DecisionID] = statusCopy
+
+	return nil
+}
+
+// Get retrieves the enforcement state of a decision
+func (s *memoryEnforcementStore) Get(ctx context.Context, decisionID string) (*types.EnforcementStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status, exists := s.enforcements[decisionID]
+	if !exists {
+		return nil, types.NewStorageError("enforcement", "get", storage.ErrStorageNotFound)
+	}
+
+	return status.Copy(), nil
+}
+
+// Delete deletes the enforcement state of a decision
+func (s *memoryEnforcementStore) Delete(ctx context.Context, decisionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.enforcements[decisionID]; !exists {
+		return types.NewStorageError("enforcement", "delete", storage.ErrStorageNotFound)
+	}
+	delete(s.enforcements, decisionID)
+
+	return nil
+}
+
+// List lists enforcement states with optional filters
+func (s *memoryEnforcementStore) List(ctx context.Context, filters *storage.EnforcementFilters) ([]*types.EnforcementStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var enforcements []*types.EnforcementStatus
+	for _, status := range s.enforcements {
+		if filters != nil && filters.Status != nil && status.Status != *filters.Status {
+			continue
+		}
+		enforcements = append(enforcements, status.Copy())
+	}
+
+	// Sort by start time (newest first)
+	sort.Slice(enforcements, func(i, j int) bool {
+		return enforcements[i].StartedAt.After(enforcements[j].StartedAt)
+	})
+
+	// Apply pagination
+	if filters != nil {
+		if filters.Offset > 0 && filters.Offset < len(enforcements) {
+			enforcements = enforcements[filters.Offset:]
+		}
+		if filters.Limit > 0 && filters.Limit < len(enforcements) {
+			enforcements = enforcements[:filters.Limit]
+		}
+	}
+
+	return enforcements, nil
+}
+
+// Health checks the health of the store
+func (s *memoryEnforcementStore) Health(ctx context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_ = len(s.enforcements)
+
+	return nil
+}
+
+// Close closes the store
+func (s *memoryEnforcementStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.enforcements = make(map[string]*types.EnforcementStatus)
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+func TestEnforcementStore_SaveStoresCopy(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnforcementStore()
+
+	status := &types.EnforcementStatus{
+		DecisionID: "d-1",
+		Status:     types.EnforcementStateRunning,
+		StartedAt:  time.Now(),
+		Details:    map[string]interface{}{"queued": true},
+	}
+	require.NoError(t, store.Save(ctx, status))
+
+	// Changes after saving are not seen until saved again
+	status.Status = types.EnforcementStateFailed
+	status.Details["queued"] = false
+
+	stored, err := store.Get(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, types.EnforcementStateRunning, stored.Status)
+	assert.Equal(t, true, stored.Details["queued"])
+	assert.False(t, stored.UpdatedAt.IsZero())
+
+	// Neither are changes to a status read back
+	stored.Status = types.EnforcementStateCompleted
+	stored, err = store.Get(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, types.EnforcementStateRunning, stored.Status)
+
+	assert.ErrorIs(t, store.Save(ctx, &types.EnforcementStatus{}), storage.ErrStorageInvalidData)
+	_, err = store.Get(ctx, "d-2")
+	assert.ErrorIs(t, err, storage.ErrStorageNotFound)
+}
+
+func TestEnforcementStore_List(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnforcementStore()
+
+	now := time.Now()
+	states := []types.EnforcementState{
+		types.EnforcementStatePending,
+		types.EnforcementStateRunning,
+		types.EnforcementStatePending,
+		types.EnforcementStatePending,
+	}
+	for i, state := range states {
+		require.NoError(t, store.Save(ctx, &types.EnforcementStatus{
+			DecisionID: fmt.Sprintf("d-%d", i+1),
+			Status:     state,
+			StartedAt:  now.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+
+	decisionIDs := func(statuses []*types.EnforcementStatus) []string {
+		ids := make([]string, len(statuses))
+		for i, status := range statuses {
+			ids[i] = status.DecisionID
+		}
+		return ids
+	}
+
+	all, err := store.List(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d-4", "d-3", "d-2", "d-1"}, decisionIDs(all))
+
+	pending := types.EnforcementStatePending
+	filtered, err := store.List(ctx, &storage.EnforcementFilters{Status: &pending})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d-4", "d-3", "d-1"}, decisionIDs(filtered))
+
+	page, err := store.List(ctx, &storage.EnforcementFilters{Status: &pending, Offset: 1, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d-3"}, decisionIDs(page))
+
+	require.NoError(t, store.Delete(ctx, "d-3"))
+	filtered, err = store.List(ctx, &storage.EnforcementFilters{Status: &pending})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d-4", "d-1"}, decisionIDs(filtered))
+	assert.ErrorIs(t, store.Delete(ctx, "d-3"), storage.ErrStorageNotFound)
+}
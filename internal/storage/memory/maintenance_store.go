@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// memoryMaintenanceStore implements MaintenanceStore interface using in-memory storage
+type memoryMaintenanceStore struct {
+	windows map[string]*types.MaintenanceWindow
+	mu      sync.RWMutex
+}
+
+// NewMemoryMaintenanceStore creates a new memory-based maintenance window store
+func NewMemoryMaintenanceStore() storage.MaintenanceStore {
+	return &memoryMaintenanceStore{
+		windows: make(map[string]*types.MaintenanceWindow),
+	}
+}
+
+// Create creates a new maintenance window, generating its ID if not provided
+func (s *memoryMaintenanceStore) Create(ctx context.Context, window *types.MaintenanceWindow) error {
+	if err := window.Validate(); err != nil {
+		return types.NewStorageError("maintenance", "create", fmt.Errorf("%w: %v", storage.ErrStorageInvalidData, err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if window.ID == "" {
+		window.ID = fmt.Sprintf("maintenance-%s-%d", window.Name, time.Now().UnixNano())
+	}
+	if _, exists := s.windows[window.ID]; exists {
+		return types.NewStorageError("maintenance", "create", storage.ErrStorageAlreadyExists)
+	}
+
+	now := time.Now()
+	window.CreatedAt = now
+	window.UpdatedAt = now
+	s.windows[window.ID] = copyMaintenanceWindow(window)
+
+	return nil
+}
+
+// Get retrieves a maintenance window by ID
+func (s *memoryMaintenanceStore) Get(ctx context.Context, id string) (*types.MaintenanceWindow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	window, exists := s.windows[id]
+	if !exists {
+		return nil, types.NewStorageError("maintenance", "get", storage.ErrStorageNotFound)
+	}
+
+	return copyMaintenanceWindow(window), nil
+}
+
+// Update updates an existing maintenance window
+func (s *memoryMaintenanceStore) Update(ctx context.Context, window *types.MaintenanceWindow) error {
+	if err := window.Validate(); err != nil {
+		return types.NewStorageError("maintenance", "update", fmt.Errorf("%w: %v", storage.ErrStorageInvalidData, err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.windows[window.ID]
+	if !exists {
+		return types.NewStorageError("maintenance", "update", storage.ErrStorageNotFound)
+	}
+
+	window.CreatedAt = existing.CreatedAt
+	window.UpdatedAt = time.Now()
+	s.windows[window.ID] = copyMaintenanceWindow(window)
+
+	return nil
+}
+
+// Delete deletes a maintenance window
+func (s *memoryMaintenanceStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.windows[id]; !exists {
+		return types.NewStorageError("maintenance", "delete", storage.ErrStorageNotFound)
+	}
+	delete(s.windows, id)
+
+	return nil
+}
+
+// List lists maintenance windows with optional filters
+func (s *memoryMaintenanceStore) List(ctx context.Context, filters *storage.MaintenanceFilters) ([]*types.MaintenanceWindow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var windows []*types.MaintenanceWindow
+	for _, window := range s.windows {
+		if filters != nil {
+			if filters.Kind != nil && window.Kind != *filters.Kind {
+				continue
+			}
+			if filters.Enabled != nil && window.Enabled != *filters.Enabled {
+				continue
+			}
+		}
+		windows = append(windows, copyMaintenanceWindow(window))
+	}
+
+	// Sort by name for a stable listing
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].Name < windows[j].Name
+	})
+
+	return windows, nil
+}
+
+// Health checks the health of the store
+func (s *memoryMaintenanceStore) Health(ctx context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_ = len(s.windows)
+
+	return nil
+}
+
+// Close closes the store
+func (s *memoryMaintenanceStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.windows = make(map[string]*types.MaintenanceWindow)
+
+	return nil
+}
+
+// copyMaintenanceWindow returns a copy of a window that shares no slices or
+// maps with it
+func copyMaintenanceWindow(window *types.MaintenanceWindow) *types.MaintenanceWindow {
+	windowCopy := *window
+	windowCopy.DecisionTypes = append([]types.DecisionType(nil), window.DecisionTypes...)
+	windowCopy.Scope.Namespaces = append([]string(nil), window.Scope.Namespaces...)
+	windowCopy.Scope.Clusters = append([]string(nil), window.Scope.Clusters...)
+	if window.Scope.Labels != nil {
+		windowCopy.Scope.Labels = make(map[string]string, len(window.Scope.Labels))
+		for k, v := range window.Scope.Labels {
+			windowCopy.Scope.Labels[k] = v
+		}
+	}
+	return &windowCopy
+}
@@ -10,22 +10,28 @@ import (
 
 // memoryStorageManager implements StorageManager interface using in-memory storage
 type memoryStorageManager struct {
-	policyStore     storage.PolicyStore
-	workloadStore   storage.WorkloadStore
-	decisionStore   storage.DecisionStore
-	evaluationStore storage.EvaluationStore
-	mu              sync.RWMutex
-	closed          bool
+	policyStore      storage.PolicyStore
+	workloadStore    storage.WorkloadStore
+	decisionStore    storage.DecisionStore
+	evaluationStore  storage.EvaluationStore
+	enforcementStore storage.EnforcementStore
+	maintenanceStore storage.MaintenanceStore
+	conditionStore   storage.ConditionStateStore
+	mu               sync.RWMutex
+	closed           bool
 }
 
 // NewMemoryStorageManager creates a new memory-based storage manager
 func NewMemoryStorageManager() storage.StorageManager {
 	return &memoryStorageManager{
-		policyStore:     NewMemoryPolicyStore(),
-		workloadStore:   NewMemoryWorkloadStore(),
-		decisionStore:   NewMemoryDecisionStore(),
-		evaluationStore: NewMemoryEvaluationStore(),
-		closed:          false,
+		policyStore:      NewMemoryPolicyStore(),
+		workloadStore:    NewMemoryWorkloadStore(),
+		decisionStore:    NewMemoryDecisionStore(),
+		evaluationStore:  NewMemoryEvaluationStore(),
+		enforcementStore: NewMemoryEnforcementStore(),
+		maintenanceStore: NewMemoryMaintenanceStore(),
+		conditionStore:   NewMemoryConditionStateStore(),
+		closed:           false,
 	}
 }
 
@@ -82,6 +88,42 @@ func (m *memoryStorageManager) Evaluation() storage.EvaluationStore {
 	return m.evaluationStore
 }
 
+// Enforcement returns the enforcement store
+func (m *memoryStorageManager) Enforcement() storage.EnforcementStore {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil
+	}
+
+	return m.enforcementStore
+}
+
+// Maintenance returns the maintenance window store
+func (m *memoryStorageManager) Maintenance() storage.MaintenanceStore {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil
+	}
+
+	return m.maintenanceStore
+}
+
+// ConditionState returns the condition state store
+func (m *memoryStorageManager) ConditionState() storage.ConditionStateStore {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil
+	}
+
+	return m.conditionStore
+}
+
 // BeginTransaction begins a new transaction
 func (m *memoryStorageManager) BeginTransaction(ctx context.Context) (storage.Transaction, error) {
 	m.mu.RLock()
@@ -139,6 +181,24 @@ func (m *memoryStorageManager) GetMetrics(ctx context.Context) (map[string]inter
 		evaluationStore.mu.RUnlock()
 	}
 
+	if enforcementStore, ok := m.enforcementStore.(*memoryEnforcementStore); ok {
+		enforcementStore.mu.RLock()
+		metrics["enforcements_count"] = len(enforcementStore.enforcements)
+		enforcementStore.mu.RUnlock()
+	}
+
+	if maintenanceStore, ok := m.maintenanceStore.(*memoryMaintenanceStore); ok {
+		maintenanceStore.mu.RLock()
+		metrics["maintenance_windows_count"] = len(maintenanceStore.windows)
+		maintenanceStore.mu.RUnlock()
+	}
+
+	if conditionStore, ok := m.conditionStore.(*memoryConditionStateStore); ok {
+		conditionStore.mu.RLock()
+		metrics["condition_states_count"] = len(conditionStore.states)
+		conditionStore.mu.RUnlock()
+	}
+
 	return metrics, nil
 }
 
@@ -168,6 +228,18 @@ func (m *memoryStorageManager) Health(ctx context.Context) error {
 		return err
 	}
 
+	if err := m.enforcementStore.Health(ctx); err != nil {
+		return err
+	}
+
+	if err := m.maintenanceStore.Health(ctx); err != nil {
+		return err
+	}
+
+	if err := m.conditionStore.Health(ctx); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -205,6 +277,24 @@ func (m *memoryStorageManager) Close() error {
 		}
 	}
 
+	if closeErr := m.enforcementStore.Close(); closeErr != nil {
+		if err == nil {
+			err = closeErr
+		}
+	}
+
+	if closeErr := m.maintenanceStore.Close(); closeErr != nil {
+		if err == nil {
+			err = closeErr
+		}
+	}
+
+	if closeErr := m.conditionStore.Close(); closeErr != nil {
+		if err == nil {
+			err = closeErr
+		}
+	}
+
 	m.closed = true
 
 	return err
@@ -260,6 +350,33 @@ func (t *memoryTransaction) Evaluation() storage.EvaluationStore {
 	return t.manager.evaluationStore
 }
 
+// Enforcement returns the enforcement store within the transaction
+func (t *memoryTransaction) Enforcement() storage.EnforcementStore {
+	if t.committed || t.rolledBack {
+		return nil
+	}
+
+	return t.manager.enforcementStore
+}
+
+// Maintenance returns the maintenance window store within the transaction
+func (t *memoryTransaction) Maintenance() storage.MaintenanceStore {
+	if t.committed || t.rolledBack {
+		return nil
+	}
+
+	return t.manager.maintenanceStore
+}
+
+// ConditionState returns the condition state store within the transaction
+func (t *memoryTransaction) ConditionState() storage.ConditionStateStore {
+	if t.committed || t.rolledBack {
+		return nil
+	}
+
+	return t.manager.conditionStore
+}
+
 // Commit commits the transaction
 func (t *memoryTransaction) Commit() error {
 	if t.committed {
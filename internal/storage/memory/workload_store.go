@@ -12,6 +12,9 @@ import (
 	"github.com/kcloud-opt/policy/internal/types"
 )
 
+// maxMetricsSamples bounds the number of metrics samples kept per workload
+const maxMetricsSamples = 10000
+
 // memoryWorkloadStore implements WorkloadStore interface using in-memory storage
 type memoryWorkloadStore struct {
 	workloads map[string]*types.Workload
@@ -356,6 +359,28 @@ func (s *memoryWorkloadStore) Count(ctx context.Context, filters *storage.Worklo
 	return count, nil
 }
 
+// RecordMetrics appends a metrics sample for a workload
+func (s *memoryWorkloadStore) RecordMetrics(ctx context.Context, metrics *types.WorkloadMetrics) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.workloads[metrics.WorkloadID]; !exists {
+		return types.NewWorkloadError(metrics.WorkloadID, "", "", "recordMetrics", types.ErrWorkloadNotFound)
+	}
+
+	if metrics.Timestamp.IsZero() {
+		metrics.Timestamp = time.Now()
+	}
+
+	samples := append(s.metrics[metrics.WorkloadID], metrics)
+	if len(samples) > maxMetricsSamples {
+		samples = samples[len(samples)-maxMetricsSamples:]
+	}
+	s.metrics[metrics.WorkloadID] = samples
+
+	return nil
+}
+
 // GetMetrics retrieves workload metrics for a time range
 func (s *memoryWorkloadStore) GetMetrics(ctx context.Context, workloadID string, startTime, endTime time.Time) ([]*types.WorkloadMetrics, error) {
 	s.mu.RLock()
@@ -0,0 +1,25 @@
+package types
+
+import "time"
+
+// ConditionState records since when a duration qualified condition of an
+// automation rule has held for a subject. The condition counts as met once
+// it has held for Duration without going false.
+type ConditionState struct {
+	RuleID    string        `json:"ruleId" yaml:"ruleId"`
+	Condition string        `json:"condition" yaml:"condition"`
+	Subject   string        `json:"subject,omitempty" yaml:"subject,omitempty"`
+	Duration  time.Duration `json:"duration" yaml:"duration"`
+	Since     time.Time     `json:"since" yaml:"since"`
+	LastSeen  time.Time     `json:"lastSeen" yaml:"lastSeen"`
+}
+
+// HeldFor returns how long the condition has held at now
+func (s *ConditionState) HeldFor(now time.Time) time.Duration {
+	return now.Sub(s.Since)
+}
+
+// Satisfied returns true if the condition has held for its duration at now
+func (s *ConditionState) Satisfied(now time.Time) bool {
+	return s.HeldFor(now) >= s.Duration
+}
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -23,15 +24,54 @@ const (
 type DecisionStatus string
 
 const (
-	DecisionStatusPending   DecisionStatus = "pending"
-	DecisionStatusApproved  DecisionStatus = "approved"
-	DecisionStatusRejected  DecisionStatus = "rejected"
-	DecisionStatusExecuting DecisionStatus = "executing"
-	DecisionStatusCompleted DecisionStatus = "completed"
-	DecisionStatusFailed    DecisionStatus = "failed"
-	DecisionStatusCancelled DecisionStatus = "cancelled"
+	DecisionStatusPending    DecisionStatus = "pending"
+	DecisionStatusApproved   DecisionStatus = "approved"
+	DecisionStatusRejected   DecisionStatus = "rejected"
+	DecisionStatusExecuting  DecisionStatus = "executing"
+	DecisionStatusCompleted  DecisionStatus = "completed"
+	DecisionStatusFailed     DecisionStatus = "failed"
+	DecisionStatusCancelled  DecisionStatus = "cancelled"
+	DecisionStatusRolledBack DecisionStatus = "rolled_back"
+	DecisionStatusExpired    DecisionStatus = "expired"
+	DecisionStatusSuperseded DecisionStatus = "superseded"
 )
 
+// decisionTransitions lists the statuses a decision may move to from each
+// status. Statuses without an entry are final.
+var decisionTransitions = map[DecisionStatus][]DecisionStatus{
+	DecisionStatusPending: {
+		DecisionStatusApproved,
+		DecisionStatusRejected,
+		DecisionStatusCancelled,
+		DecisionStatusExpired,
+		DecisionStatusSuperseded,
+	},
+	DecisionStatusApproved: {
+		DecisionStatusExecuting,
+		DecisionStatusRejected,
+		DecisionStatusCancelled,
+	},
+	DecisionStatusExecuting: {
+		DecisionStatusCompleted,
+		DecisionStatusFailed,
+		DecisionStatusCancelled,
+	},
+	DecisionStatusCompleted: {
+		DecisionStatusRolledBack,
+	},
+}
+
+// CanTransition returns true if a decision may move from one status to
+// another
+func CanTransition(from, to DecisionStatus) bool {
+	for _, next := range decisionTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
 // DecisionReason represents the reason for a decision
 type DecisionReason string
 
@@ -45,6 +85,7 @@ const (
 	DecisionReasonAutomationRule          DecisionReason = "automation_rule"
 	DecisionReasonManual                  DecisionReason = "manual"
 	DecisionReasonSystemMaintenance       DecisionReason = "system_maintenance"
+	DecisionReasonPreemption              DecisionReason = "preemption"
 )
 
 // EvaluationStatus represents the status of an evaluation
@@ -81,6 +122,13 @@ type Decision struct {
 	CreatedAt          time.Time              `json:"createdAt" yaml:"createdAt"`
 	UpdatedAt          time.Time              `json:"updatedAt" yaml:"updatedAt"`
 	ExecutedAt         *time.Time             `json:"executedAt,omitempty" yaml:"executedAt,omitempty"`
+
+	// ScheduledFor is when a decision held for a maintenance window will
+	// be enforced
+	ScheduledFor *time.Time `json:"scheduledFor,omitempty" yaml:"scheduledFor,omitempty"`
+
+	// ExpiresAt is when a decision still pending expires
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" yaml:"expiresAt,omitempty"`
 }
 
 // DecisionMetadata contains decision metadata
@@ -181,6 +229,8 @@ type DecisionHistory struct {
 	Duration     time.Duration   `json:"duration,omitempty" yaml:"duration,omitempty"`
 	Result       string          `json:"result" yaml:"result"`
 	ErrorMessage string          `json:"errorMessage,omitempty" yaml:"errorMessage,omitempty"`
+	Approver     string          `json:"approver,omitempty" yaml:"approver,omitempty"`
+	Comment      string          `json:"comment,omitempty" yaml:"comment,omitempty"`
 	Events       []DecisionEvent `json:"events,omitempty" yaml:"events,omitempty"`
 }
 
@@ -220,11 +270,14 @@ func (d *Decision) IsFailed() bool {
 	return d.Status == DecisionStatusFailed
 }
 
-// IsTerminated returns true if the decision has terminated (completed, failed, or cancelled)
+// IsTerminated returns true if the decision has terminated (completed, failed, cancelled, or rolled back)
 func (d *Decision) IsTerminated() bool {
 	return d.Status == DecisionStatusCompleted ||
 		d.Status == DecisionStatusFailed ||
-		d.Status == DecisionStatusCancelled
+		d.Status == DecisionStatusCancelled ||
+		d.Status == DecisionStatusRolledBack ||
+		d.Status == DecisionStatusExpired ||
+		d.Status == DecisionStatusSuperseded
 }
 
 // CanBeExecuted returns true if the decision can be executed
@@ -232,6 +285,17 @@ func (d *Decision) CanBeExecuted() bool {
 	return d.Status == DecisionStatusApproved
 }
 
+// IsDisruptive returns true if enforcing decisions of this type takes
+// workloads out of service, fully or in part
+func (t DecisionType) IsDisruptive() bool {
+	switch t {
+	case DecisionTypeMigrate, DecisionTypeReschedule, DecisionTypeTerminate, DecisionTypeSuspend, DecisionTypeConsolidate:
+		return true
+	default:
+		return false
+	}
+}
+
 // GetExecutionDuration returns the execution duration if completed
 func (d *Decision) GetExecutionDuration() *time.Duration {
 	if d.ExecutedAt != nil && d.IsCompleted() {
@@ -258,8 +322,18 @@ func (d *Decision) GetDetail(key string) (interface{}, bool) {
 	return value, exists
 }
 
-// SetStatus updates the decision status and timestamp
-func (d *Decision) SetStatus(status DecisionStatus) {
+// SetStatus moves the decision to a new status and updates its
+// timestamps. Transitions the decision lifecycle does not allow are
+// refused; setting the current status again is a no-op.
+func (d *Decision) SetStatus(status DecisionStatus) error {
+	if d.Status == status {
+		return nil
+	}
+	if !CanTransition(d.Status, status) {
+		return NewDecisionError(d.ID, string(d.Type), d.WorkloadID, d.PolicyID, "transition",
+			fmt.Errorf("%w: %s to %s", ErrInvalidTransition, d.Status, status))
+	}
+
 	d.Status = status
 	d.UpdatedAt = time.Now()
 
@@ -267,6 +341,7 @@ func (d *Decision) SetStatus(status DecisionStatus) {
 		now := time.Now()
 		d.ExecutedAt = &now
 	}
+	return nil
 }
 
 // Helper methods for EvaluationResult
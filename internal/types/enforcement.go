@@ -0,0 +1,163 @@
+package types
+
+import (
+	"time"
+)
+
+// EnforcementState represents the state of enforcement
+type EnforcementState string
+
+const (
+	EnforcementStatePending    EnforcementState = "pending"
+	EnforcementStateRunning    EnforcementState = "running"
+	EnforcementStateCompleted  EnforcementState = "completed"
+	EnforcementStateFailed     EnforcementState = "failed"
+	EnforcementStateCancelled  EnforcementState = "cancelled"
+	EnforcementStateTimeout    EnforcementState = "timeout"
+	EnforcementStateRolledBack EnforcementState = "rolled_back"
+)
+
+// EnforcementStatus represents the status of policy enforcement
+type EnforcementStatus struct {
+	DecisionID  string                 `json:"decisionId" yaml:"decisionId"`
+	Status      EnforcementState       `json:"status" yaml:"status"`
+	Progress    float64                `json:"progress" yaml:"progress"`
+	Message     string                 `json:"message" yaml:"message"`
+	StartedAt   time.Time              `json:"startedAt" yaml:"startedAt"`
+	CompletedAt *time.Time             `json:"completedAt,omitempty" yaml:"completedAt,omitempty"`
+	Duration    *time.Duration         `json:"duration,omitempty" yaml:"duration,omitempty"`
+	Error       string                 `json:"error,omitempty" yaml:"error,omitempty"`
+	Details     map[string]interface{} `json:"details,omitempty" yaml:"details,omitempty"`
+	Events      []EnforcementEvent     `json:"events,omitempty" yaml:"events,omitempty"`
+
+	// Steps are the planned actions of the enforcement and how far each got
+	Steps []EnforcementStep `json:"steps,omitempty" yaml:"steps,omitempty"`
+
+	// Compensations undo the actions executed so far, in execution order
+	Compensations []*EnforcementAction `json:"compensations,omitempty" yaml:"compensations,omitempty"`
+
+	// Lock describes the workload and node locks of the enforcement
+	Lock *LockStatus `json:"lock,omitempty" yaml:"lock,omitempty"`
+
+	UpdatedAt time.Time `json:"updatedAt" yaml:"updatedAt"`
+}
+
+// EnforcementEvent represents an enforcement event
+type EnforcementEvent struct {
+	Type      string                 `json:"type" yaml:"type"`
+	Message   string                 `json:"message" yaml:"message"`
+	Timestamp time.Time              `json:"timestamp" yaml:"timestamp"`
+	Data      map[string]interface{} `json:"data,omitempty" yaml:"data,omitempty"`
+}
+
+// EnforcementStep is a planned enforcement action and its progress. A step
+// runs once the steps it depends on have completed, so steps that do not
+// depend on each other run in parallel.
+type EnforcementStep struct {
+	ID        string             `json:"id,omitempty" yaml:"id,omitempty"`
+	Action    *EnforcementAction `json:"action" yaml:"action"`
+	DependsOn []string           `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty"`
+
+	// Gate holds the step open after its action until the change it made
+	// is ready
+	Gate *ReadinessGate `json:"gate,omitempty" yaml:"gate,omitempty"`
+
+	// Retry runs a failed step again, action and gate alike
+	Retry    *RetryPolicy `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Attempts int          `json:"attempts,omitempty" yaml:"attempts,omitempty"`
+
+	State  EnforcementState       `json:"state" yaml:"state"`
+	Before map[string]interface{} `json:"before,omitempty" yaml:"before,omitempty"`
+
+	// Result is the data the executor reported for the action, including
+	// the previous state a rollback restores
+	Result map[string]interface{} `json:"result,omitempty" yaml:"result,omitempty"`
+
+	StartedAt   *time.Time `json:"startedAt,omitempty" yaml:"startedAt,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" yaml:"completedAt,omitempty"`
+	Error       string     `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ReadinessGate is a condition over the state the executor of a step's
+// action observes that must hold before the step completes
+type ReadinessGate struct {
+	Condition string        `json:"condition" yaml:"condition"`
+	Timeout   time.Duration `json:"timeout" yaml:"timeout"`
+	Interval  time.Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+}
+
+// EnforcementAction represents an action to be executed
+type EnforcementAction struct {
+	Type        string                 `json:"type" yaml:"type"`
+	Target      string                 `json:"target" yaml:"target"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Timeout     time.Duration          `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	RetryPolicy *RetryPolicy           `json:"retryPolicy,omitempty" yaml:"retryPolicy,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// RetryPolicy defines retry behavior for actions
+type RetryPolicy struct {
+	MaxRetries int           `json:"maxRetries" yaml:"maxRetries"`
+	Interval   time.Duration `json:"interval" yaml:"interval"`
+	Backoff    BackoffType   `json:"backoff" yaml:"backoff"`
+}
+
+// BackoffType represents the backoff strategy
+type BackoffType string
+
+const (
+	BackoffTypeLinear      BackoffType = "linear"
+	BackoffTypeExponential BackoffType = "exponential"
+	BackoffTypeFixed       BackoffType = "fixed"
+)
+
+// LockStatus describes the locks an enforcement holds or waits for
+type LockStatus struct {
+	Resources []string `json:"resources" yaml:"resources"`
+
+	// Holder is the decision holding the locks: the enforcement's own once
+	// it holds them, otherwise the decision it waits for
+	Holder     string     `json:"holder" yaml:"holder"`
+	Held       bool       `json:"held" yaml:"held"`
+	AcquiredAt *time.Time `json:"acquiredAt,omitempty" yaml:"acquiredAt,omitempty"`
+}
+
+// IsTerminated returns true if the enforcement has finished running
+func (s *EnforcementStatus) IsTerminated() bool {
+	return s.Status != EnforcementStatePending && s.Status != EnforcementStateRunning
+}
+
+// Copy returns a copy of the status that shares no slices or maps with it
+func (s *EnforcementStatus) Copy() *EnforcementStatus {
+	statusCopy := *s
+
+	if s.Details != nil {
+		statusCopy.Details = make(map[string]interface{}, len(s.Details))
+		for k, v := range s.Details {
+			statusCopy.Details[k] = v
+		}
+	}
+	if s.Events != nil {
+		statusCopy.Events = make([]EnforcementEvent, len(s.Events))
+		copy(statusCopy.Events, s.Events)
+	}
+	if s.Steps != nil {
+		statusCopy.Steps = make([]EnforcementStep, len(s.Steps))
+		copy(statusCopy.Steps, s.Steps)
+		for i := range statusCopy.Steps {
+			statusCopy.Steps[i].DependsOn = append([]string(nil), s.Steps[i].DependsOn...)
+		}
+	}
+	if s.Compensations != nil {
+		statusCopy.Compensations = make([]*EnforcementAction, len(s.Compensations))
+		copy(statusCopy.Compensations, s.Compensations)
+	}
+	if s.Lock != nil {
+		lock := *s.Lock
+		lock.Resources = append([]string(nil), s.Lock.Resources...)
+		statusCopy.Lock = &lock
+	}
+
+	return &statusCopy
+}
@@ -32,12 +32,15 @@ var (
 	ErrDecisionNotFound         = errors.New("decision not found")
 	ErrInvalidDecisionType      = errors.New("invalid decision type")
 	ErrInvalidDecisionStatus    = errors.New("invalid decision status")
+	ErrInvalidTransition        = errors.New("invalid decision status transition")
 	ErrDecisionAlreadyExists    = errors.New("decision already exists")
 	ErrDecisionValidationFailed = errors.New("decision validation failed")
 	ErrDecisionNotApproved      = errors.New("decision is not approved")
 	ErrDecisionExecuting        = errors.New("decision is already executing")
 	ErrDecisionCompleted        = errors.New("decision is already completed")
 	ErrDecisionFailed           = errors.New("decision execution failed")
+	ErrSafetyLimitExceeded      = errors.New("safety limit exceeded")
+	ErrChangeFreeze             = errors.New("change freeze in effect")
 
 	// Evaluation errors
 	ErrEvaluationFailed       = errors.New("policy evaluation failed")
@@ -54,6 +57,16 @@ var (
 	ErrRuleTimeout            = errors.New("rule execution timeout")
 	ErrRuleConflict           = errors.New("rule conflict detected")
 
+	// Rollout errors
+	ErrRolloutNotFound        = errors.New("rollout not found")
+	ErrInvalidRolloutState    = errors.New("invalid rollout state")
+	ErrInvalidRolloutStrategy = errors.New("invalid rollout strategy")
+
+	// Webhook errors
+	ErrWebhookTargetNotFound   = errors.New("webhook target not found")
+	ErrWebhookCallbackNotFound = errors.New("webhook callback not found")
+	ErrInvalidSignature        = errors.New("invalid webhook signature")
+
 	// Storage errors
 	ErrStorageConnection   = errors.New("storage connection failed")
 	ErrStorageOperation    = errors.New("storage operation failed")
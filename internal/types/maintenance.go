@@ -0,0 +1,211 @@
+package types
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/cron"
+)
+
+// MaintenanceWindowKind distinguishes windows that allow disruption from
+// freezes that forbid it
+type MaintenanceWindowKind string
+
+const (
+	MaintenanceWindowKindWindow MaintenanceWindowKind = "window"
+	MaintenanceWindowKindFreeze MaintenanceWindowKind = "freeze"
+)
+
+// FreezeAction is what a freeze does with the decisions it covers
+type FreezeAction string
+
+const (
+	// FreezeActionDefer holds decisions until the freeze ends
+	FreezeActionDefer FreezeAction = "defer"
+
+	// FreezeActionBlock refuses decisions while the freeze is in effect
+	FreezeActionBlock FreezeAction = "block"
+)
+
+// MaintenanceWindow is a period in which disruptive decisions may be
+// enforced or, for freezes, must not be. Recurring periods open at every
+// activation of Cron and last Duration; one-off periods run from Start to
+// End.
+type MaintenanceWindow struct {
+	ID          string                `json:"id" yaml:"id"`
+	Name        string                `json:"name" yaml:"name"`
+	Description string                `json:"description,omitempty" yaml:"description,omitempty"`
+	Kind        MaintenanceWindowKind `json:"kind" yaml:"kind"`
+	Enabled     bool                  `json:"enabled" yaml:"enabled"`
+
+	Cron     string     `json:"cron,omitempty" yaml:"cron,omitempty"`
+	Duration string     `json:"duration,omitempty" yaml:"duration,omitempty"`
+	Start    *time.Time `json:"start,omitempty" yaml:"start,omitempty"`
+	End      *time.Time `json:"end,omitempty" yaml:"end,omitempty"`
+	Timezone string     `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+
+	// Scope limits the workloads the period applies to
+	Scope MaintenanceScope `json:"scope,omitempty" yaml:"scope,omitempty"`
+
+	// DecisionTypes the period applies to; empty means every disruptive
+	// decision type
+	DecisionTypes []DecisionType `json:"decisionTypes,omitempty" yaml:"decisionTypes,omitempty"`
+
+	// FreezeAction applies to freezes only and defaults to defer
+	FreezeAction FreezeAction `json:"freezeAction,omitempty" yaml:"freezeAction,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt" yaml:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" yaml:"updatedAt"`
+}
+
+// MaintenanceScope selects workloads by namespace, cluster and labels. Empty
+// matchers match every workload.
+type MaintenanceScope struct {
+	Namespaces []string          `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
+	Clusters   []string          `json:"clusters,omitempty" yaml:"clusters,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// maxCronOccurrences bounds the occurrences scanned to find whether a
+// recurring period is open
+const maxCronOccurrences = 10000
+
+// Validate validates the maintenance window
+func (w *MaintenanceWindow) Validate() error {
+	if w.Name == "" {
+		return fmt.Errorf("maintenance window name is required")
+	}
+	switch w.Kind {
+	case MaintenanceWindowKindWindow, MaintenanceWindowKindFreeze:
+	default:
+		return fmt.Errorf("maintenance window kind must be %s or %s, got %q", MaintenanceWindowKindWindow, MaintenanceWindowKindFreeze, w.Kind)
+	}
+	switch w.FreezeAction {
+	case "", FreezeActionDefer, FreezeActionBlock:
+	default:
+		return fmt.Errorf("freeze action must be %s or %s, got %q", FreezeActionDefer, FreezeActionBlock, w.FreezeAction)
+	}
+
+	if _, err := w.location(); err != nil {
+		return err
+	}
+
+	switch {
+	case w.Cron != "":
+		if w.Start != nil || w.End != nil {
+			return fmt.Errorf("maintenance window takes either cron and duration or start and end")
+		}
+		if _, err := cron.Parse(w.Cron, nil); err != nil {
+			return fmt.Errorf("invalid maintenance window cron: %w", err)
+		}
+		duration, err := time.ParseDuration(w.Duration)
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("maintenance window duration must be a positive duration, got %q", w.Duration)
+		}
+	case w.Start != nil && w.End != nil:
+		if !w.End.After(*w.Start) {
+			return fmt.Errorf("maintenance window must end after it starts")
+		}
+	default:
+		return fmt.Errorf("maintenance window requires cron and duration or start and end")
+	}
+
+	return nil
+}
+
+// IsFreeze returns true if the period forbids disruption
+func (w *MaintenanceWindow) IsFreeze() bool {
+	return w.Kind == MaintenanceWindowKindFreeze
+}
+
+// Blocks returns true if the freeze refuses decisions rather than
+// deferring them
+func (w *MaintenanceWindow) Blocks() bool {
+	return w.IsFreeze() && w.FreezeAction == FreezeActionBlock
+}
+
+// AppliesTo returns true if the period covers the decision type
+func (w *MaintenanceWindow) AppliesTo(decisionType DecisionType) bool {
+	if len(w.DecisionTypes) == 0 {
+		return decisionType.IsDisruptive()
+	}
+	for _, t := range w.DecisionTypes {
+		if t == decisionType {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenAt reports whether the period is open at t and, if so, when it closes
+func (w *MaintenanceWindow) OpenAt(t time.Time) (time.Time, bool, error) {
+	if w.Cron == "" {
+		if w.Start == nil || w.End == nil {
+			return time.Time{}, false, nil
+		}
+		return *w.End, !t.Before(*w.Start) && t.Before(*w.End), nil
+	}
+
+	schedule, duration, err := w.schedule()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	// The latest occurrence that started within duration before t decides
+	var end time.Time
+	open := false
+	start := schedule.Next(t.Add(-duration).Add(-time.Second))
+	for i := 0; i < maxCronOccurrences && !start.IsZero() && !start.After(t); i++ {
+		end = start.Add(duration)
+		open = end.After(t)
+		start = schedule.Next(start)
+	}
+	return end, open, nil
+}
+
+// NextOpening returns when the period next opens after t, or false if it
+// never does
+func (w *MaintenanceWindow) NextOpening(t time.Time) (time.Time, bool, error) {
+	if w.Cron == "" {
+		if w.Start == nil || !t.Before(*w.Start) {
+			return time.Time{}, false, nil
+		}
+		return *w.Start, true, nil
+	}
+
+	schedule, _, err := w.schedule()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	next := schedule.Next(t)
+	return next, !next.IsZero(), nil
+}
+
+// schedule parses the recurrence of the period
+func (w *MaintenanceWindow) schedule() (*cron.Schedule, time.Duration, error) {
+	location, err := w.location()
+	if err != nil {
+		return nil, 0, err
+	}
+	schedule, err := cron.Parse(w.Cron, location)
+	if err != nil {
+		return nil, 0, err
+	}
+	duration, err := time.ParseDuration(w.Duration)
+	if err != nil {
+		return nil, 0, err
+	}
+	return schedule, duration, nil
+}
+
+// location loads the time zone of the period, defaulting to UTC
+func (w *MaintenanceWindow) location() (*time.Location, error) {
+	if w.Timezone == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maintenance window timezone %q: %w", w.Timezone, err)
+	}
+	return location, nil
+}
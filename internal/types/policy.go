@@ -198,6 +198,10 @@ type AutomationRulePolicy struct {
 	Metadata   PolicyMetadata     `json:"metadata" yaml:"metadata"`
 	Spec       AutomationRuleSpec `json:"spec" yaml:"spec"`
 	Status     PolicyStatus       `json:"status" yaml:"status"`
+
+	// StatusMessage explains why the automation engine cannot run the
+	// policy; it is empty while the policy converts to a rule
+	StatusMessage string `json:"statusMessage,omitempty" yaml:"statusMessage,omitempty"`
 }
 
 // AutomationRuleSpec defines automation rule specification
@@ -209,12 +213,18 @@ type AutomationRuleSpec struct {
 	Schedule   *Schedule             `json:"schedule,omitempty" yaml:"schedule,omitempty"`
 }
 
-// AutomationCondition represents a condition for automation
+// AutomationCondition represents a condition for automation: a comparison
+// of a field with a value, an expression, or an all, any or not group of
+// nested conditions
 type AutomationCondition struct {
-	Field    string      `json:"field" yaml:"field"`
-	Operator string      `json:"operator" yaml:"operator"`
-	Value    interface{} `json:"value" yaml:"value"`
-	Duration *string     `json:"duration,omitempty" yaml:"duration,omitempty"`
+	Field      string                `json:"field,omitempty" yaml:"field,omitempty"`
+	Operator   string                `json:"operator,omitempty" yaml:"operator,omitempty"`
+	Value      interface{}           `json:"value,omitempty" yaml:"value,omitempty"`
+	Expression string                `json:"expression,omitempty" yaml:"expression,omitempty"`
+	All        []AutomationCondition `json:"all,omitempty" yaml:"all,omitempty"`
+	Any        []AutomationCondition `json:"any,omitempty" yaml:"any,omitempty"`
+	Not        *AutomationCondition  `json:"not,omitempty" yaml:"not,omitempty"`
+	Duration   *string               `json:"duration,omitempty" yaml:"duration,omitempty"`
 }
 
 // AutomationAction represents an automation action
@@ -0,0 +1,154 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RolloutState represents the state of a progressive rollout
+type RolloutState string
+
+const (
+	RolloutStateRunning   RolloutState = "running"
+	RolloutStatePaused    RolloutState = "paused"
+	RolloutStateCompleted RolloutState = "completed"
+	RolloutStateHalted    RolloutState = "halted"
+	RolloutStateAborted   RolloutState = "aborted"
+)
+
+// RolloutWaveState represents the state of a wave of a rollout
+type RolloutWaveState string
+
+const (
+	RolloutWaveStatePending    RolloutWaveState = "pending"
+	RolloutWaveStateEnforcing  RolloutWaveState = "enforcing"
+	RolloutWaveStateBaking     RolloutWaveState = "baking"
+	RolloutWaveStatePassed     RolloutWaveState = "passed"
+	RolloutWaveStateFailed     RolloutWaveState = "failed"
+	RolloutWaveStateRolledBack RolloutWaveState = "rolled_back"
+)
+
+// RolloutStrategy controls how a rollout advances from wave to wave. Zero
+// health thresholds are not checked.
+type RolloutStrategy struct {
+	// InitialWave is the number of decisions enforced by the first wave;
+	// each later wave is WaveGrowth times the size of the one before
+	InitialWave int     `json:"initialWave" yaml:"initialWave"`
+	WaveGrowth  float64 `json:"waveGrowth" yaml:"waveGrowth"`
+
+	// BakeTime is how long the health of a wave is observed before the
+	// next wave starts. In JSON it is a duration string such as "5m" or a
+	// number of nanoseconds.
+	BakeTime time.Duration `json:"bakeTime" yaml:"bakeTime"`
+
+	// MaxErrorRate bounds the error rate of a wave's workloads, and
+	// MaxErrorRateIncrease its increase over the rate before the wave
+	MaxErrorRate         float64 `json:"maxErrorRate,omitempty" yaml:"maxErrorRate,omitempty"`
+	MaxErrorRateIncrease float64 `json:"maxErrorRateIncrease,omitempty" yaml:"maxErrorRateIncrease,omitempty"`
+
+	// MaxLatencyIncrease bounds the relative latency increase of a wave's
+	// workloads, so 0.2 allows latency 20% above the latency before the wave
+	MaxLatencyIncrease float64 `json:"maxLatencyIncrease,omitempty" yaml:"maxLatencyIncrease,omitempty"`
+
+	// AllowMissingMetrics passes a wave whose workloads reported no metrics
+	// during the bake time; by default such a wave halts the rollout
+	AllowMissingMetrics bool `json:"allowMissingMetrics,omitempty" yaml:"allowMissingMetrics,omitempty"`
+}
+
+// UnmarshalJSON reads a strategy whose bake time is a duration string or a
+// number of nanoseconds
+func (s *RolloutStrategy) UnmarshalJSON(data []byte) error {
+	type strategy RolloutStrategy
+	decoded := struct {
+		*strategy
+		BakeTime json.RawMessage `json:"bakeTime"`
+	}{strategy: (*strategy)(s)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if len(decoded.BakeTime) == 0 || string(decoded.BakeTime) == "null" {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(decoded.BakeTime, &text); err == nil {
+		bakeTime, err := time.ParseDuration(text)
+		if err != nil {
+			return fmt.Errorf("invalid bake time %q: %w", text, err)
+		}
+		s.BakeTime = bakeTime
+		return nil
+	}
+	var nanoseconds int64
+	if err := json.Unmarshal(decoded.BakeTime, &nanoseconds); err != nil {
+		return fmt.Errorf("invalid bake time %s: %w", decoded.BakeTime, err)
+	}
+	s.BakeTime = time.Duration(nanoseconds)
+	return nil
+}
+
+// Rollout is a batch of decisions enforced in waves. A wave starts only
+// once the one before it has been enforced and stayed healthy for the
+// bake time; a wave that fails or regresses halts the rollout and rolls
+// back every wave enforced so far.
+type Rollout struct {
+	ID          string          `json:"id" yaml:"id"`
+	State       RolloutState    `json:"state" yaml:"state"`
+	Strategy    RolloutStrategy `json:"strategy" yaml:"strategy"`
+	Waves       []RolloutWave   `json:"waves" yaml:"waves"`
+	CurrentWave int             `json:"currentWave" yaml:"currentWave"`
+	Message     string          `json:"message,omitempty" yaml:"message,omitempty"`
+	Error       string          `json:"error,omitempty" yaml:"error,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt" yaml:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt" yaml:"updatedAt"`
+	CompletedAt *time.Time      `json:"completedAt,omitempty" yaml:"completedAt,omitempty"`
+}
+
+// RolloutWave is a group of decisions of a rollout enforced together
+type RolloutWave struct {
+	Index       int              `json:"index" yaml:"index"`
+	State       RolloutWaveState `json:"state" yaml:"state"`
+	DecisionIDs []string         `json:"decisionIds" yaml:"decisionIds"`
+	WorkloadIDs []string         `json:"workloadIds" yaml:"workloadIds"`
+	Failed      []string         `json:"failed,omitempty" yaml:"failed,omitempty"`
+	Health      *RolloutHealth   `json:"health,omitempty" yaml:"health,omitempty"`
+	StartedAt   *time.Time       `json:"startedAt,omitempty" yaml:"startedAt,omitempty"`
+	BakedAt     *time.Time       `json:"bakedAt,omitempty" yaml:"bakedAt,omitempty"`
+	CompletedAt *time.Time       `json:"completedAt,omitempty" yaml:"completedAt,omitempty"`
+}
+
+// RolloutHealth compares the health of a wave's workloads after the wave
+// with their health before it
+type RolloutHealth struct {
+	Healthy           bool      `json:"healthy" yaml:"healthy"`
+	Reason            string    `json:"reason,omitempty" yaml:"reason,omitempty"`
+	BaselineErrorRate float64   `json:"baselineErrorRate" yaml:"baselineErrorRate"`
+	ErrorRate         float64   `json:"errorRate" yaml:"errorRate"`
+	BaselineLatency   float64   `json:"baselineLatency" yaml:"baselineLatency"`
+	Latency           float64   `json:"latency" yaml:"latency"`
+	Samples           int       `json:"samples" yaml:"samples"`
+	CheckedAt         time.Time `json:"checkedAt" yaml:"checkedAt"`
+}
+
+// IsTerminated returns true if the rollout has stopped for good
+func (r *Rollout) IsTerminated() bool {
+	return r.State == RolloutStateCompleted || r.State == RolloutStateHalted || r.State == RolloutStateAborted
+}
+
+// Copy returns a copy of the rollout that shares nothing mutable with it
+func (r *Rollout) Copy() *Rollout {
+	rolloutCopy := *r
+	rolloutCopy.Waves = make([]RolloutWave, len(r.Waves))
+	for i, wave := range r.Waves {
+		wave.DecisionIDs = append([]string(nil), wave.DecisionIDs...)
+		wave.WorkloadIDs = append([]string(nil), wave.WorkloadIDs...)
+		wave.Failed = append([]string(nil), wave.Failed...)
+		if wave.Health != nil {
+			health := *wave.Health
+			wave.Health = &health
+		}
+		rolloutCopy.Waves[i] = wave
+	}
+	return &rolloutCopy
+}
@@ -31,6 +31,53 @@ func (sv *SchemaValidator) LoadSchemas() error {
 		"$schema": "http://json-schema.org/draft-07/schema#",
 		"type": "object",
 		"required": ["metadata", "spec"],
+		"definitions": {
+			"condition": {
+				"type": "object",
+				"properties": {
+					"field": {
+						"type": "string",
+						"minLength": 1
+					},
+					"operator": {
+						"type": "string",
+						"minLength": 1
+					},
+					"value": {},
+					"expression": {
+						"type": "string",
+						"minLength": 1
+					},
+					"all": {
+						"type": "array",
+						"minItems": 1,
+						"items": {
+							"$ref": "#/definitions/condition"
+						}
+					},
+					"any": {
+						"type": "array",
+						"minItems": 1,
+						"items": {
+							"$ref": "#/definitions/condition"
+						}
+					},
+					"not": {
+						"$ref": "#/definitions/condition"
+					},
+					"duration": {
+						"type": "string"
+					}
+				},
+				"oneOf": [
+					{"required": ["field", "operator"]},
+					{"required": ["expression"]},
+					{"required": ["all"]},
+					{"required": ["any"]},
+					{"required": ["not"]}
+				]
+			}
+		},
 		"properties": {
 			"metadata": {
 				"type": "object",
@@ -162,6 +209,12 @@ func (sv *SchemaValidator) LoadSchemas() error {
 							}
 						}
 					},
+					"conditions": {
+						"type": "array",
+						"items": {
+							"$ref": "#/definitions/condition"
+						}
+					},
 					"actions": {
 						"type": "array",
 						"items": {
@@ -0,0 +1,342 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// maxResponseSize bounds the response and callback bodies read from targets
+const maxResponseSize = 1 << 20
+
+// pendingDelivery is an asynchronous delivery waiting for its callback
+type pendingDelivery struct {
+	target config.WebhookTarget
+	result chan *Result
+}
+
+// dispatcher implements Dispatcher interface
+type dispatcher struct {
+	config  config.WebhookConfig
+	client  *http.Client
+	pending map[string]*pendingDelivery
+	mu      sync.Mutex
+	logger  types.Logger
+}
+
+// NewDispatcher creates a new webhook dispatcher
+func NewDispatcher(cfg config.WebhookConfig, client *http.Client, logger types.Logger) Dispatcher {
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &dispatcher{
+		config:  cfg,
+		client:  client,
+		pending: make(map[string]*pendingDelivery),
+		logger:  logger,
+	}
+}
+
+// NewEvent creates the event delivering a payload to a target, filling in
+// the delivery mode and, for asynchronous targets, the callback URL
+func (d *dispatcher) NewEvent(target config.WebhookTarget, eventType, subject string, payload *Payload) (*CloudEvent, error) {
+	id := newEventID()
+	payload.Mode = config.WebhookModeSync
+	if target.Mode == config.WebhookModeAsync {
+		payload.Mode = config.WebhookModeAsync
+		payload.CallbackURL = callbackURL(d.config.CallbackURL, id)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event data: %w", err)
+	}
+	return &CloudEvent{
+		SpecVersion:     SpecVersion,
+		ID:              id,
+		Source:          d.config.Source,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}, nil
+}
+
+// Sign returns the signature of a body as carried in SignatureHeader
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a body in constant time
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Dispatch delivers an event to a target and returns the result it reports
+func (d *dispatcher) Dispatch(ctx context.Context, target config.WebhookTarget, event *CloudEvent) (*Result, error) {
+	if err := validateTarget(target); err != nil {
+		return nil, err
+	}
+
+	var pending *pendingDelivery
+	if target.Mode == config.WebhookModeAsync {
+		// Register before sending so a fast callback cannot be missed
+		pending = &pendingDelivery{target: target, result: make(chan *Result, 1)}
+		d.mu.Lock()
+		d.pending[event.ID] = pending
+		d.mu.Unlock()
+		defer d.forget(event.ID)
+	}
+
+	// A synchronous result, or the rejection of an asynchronous delivery
+	result, err := d.send(ctx, target, event)
+	if err != nil || result != nil || pending == nil {
+		return result, err
+	}
+
+	d.logger.Debug("webhook accepted, waiting for callback", "target", target.Name, "event_id", event.ID)
+
+	timeout := target.CallbackTimeout
+	if timeout == 0 {
+		timeout = d.config.CallbackTimeout
+	}
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	select {
+	case result := <-pending.result:
+		return result, nil
+	case <-waitCtx.Done():
+		return nil, fmt.Errorf("webhook %s did not call back for event %s: %w", target.Name, event.ID, waitCtx.Err())
+	}
+}
+
+// send posts an event to a target; the result of an asynchronous target is
+// only an acknowledgement
+func (d *dispatcher) send(ctx context.Context, target config.WebhookTarget, event *CloudEvent) (*Result, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	timeout := target.Timeout
+	if timeout == 0 {
+		timeout = d.config.Timeout
+	}
+	requestCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		requestCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(requestCtx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	for name, value := range target.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set(SignatureHeader, Sign(target.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deliver webhook to %s: %w", target.Name, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook response from %s: %w", target.Name, err)
+	}
+
+	d.logger.Debug("webhook delivered", "target", target.Name, "event_id", event.ID, "status", resp.StatusCode)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		result := decodeResult(respBody)
+		failed := false
+		result.Success = &failed
+		if result.Error == "" {
+			result.Error = fmt.Sprintf("webhook %s responded with status %d", target.Name, resp.StatusCode)
+		}
+		if result.Message == "" {
+			result.Message = result.Error
+		}
+		return result, nil
+	}
+
+	if target.Mode == config.WebhookModeAsync {
+		return nil, nil
+	}
+
+	result := decodeResult(respBody)
+	if result.Success == nil {
+		succeeded := true
+		result.Success = &succeeded
+	}
+	return result, nil
+}
+
+// Complete settles an asynchronous delivery with its callback body
+func (d *dispatcher) Complete(eventID string, body []byte, signature string) error {
+	d.mu.Lock()
+	pending, exists := d.pending[eventID]
+	d.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("event %s: %w", eventID, types.ErrWebhookCallbackNotFound)
+	}
+	if !Verify(pending.target.Secret, body, signature) {
+		return fmt.Errorf("callback for event %s: %w", eventID, types.ErrInvalidSignature)
+	}
+
+	var result Result
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("invalid callback body for event %s: %w", eventID, err)
+	}
+	if result.Success == nil {
+		succeeded := result.Error == ""
+		result.Success = &succeeded
+	}
+
+	d.mu.Lock()
+	_, exists = d.pending[eventID]
+	delete(d.pending, eventID)
+	d.mu.Unlock()
+
+	// A concurrent callback for the same event already settled it
+	if !exists {
+		return fmt.Errorf("event %s: %w", eventID, types.ErrWebhookCallbackNotFound)
+	}
+	pending.result <- &result
+
+	d.logger.Info("webhook callback received", "target", pending.target.Name, "event_id", eventID, "success", *result.Success)
+	return nil
+}
+
+// Target returns the target with the given name
+func (d *dispatcher) Target(name string) (config.WebhookTarget, bool) {
+	for _, target := range d.config.Targets {
+		if target.Name == name {
+			return target, true
+		}
+	}
+	return config.WebhookTarget{}, false
+}
+
+// TargetFor returns the first target that lists the action type
+func (d *dispatcher) TargetFor(actionType string) (config.WebhookTarget, bool) {
+	for _, target := range d.config.Targets {
+		for _, candidate := range target.ActionTypes {
+			if candidate == actionType {
+				return target, true
+			}
+		}
+	}
+	return config.WebhookTarget{}, false
+}
+
+// Pending returns the IDs of deliveries waiting for their callback
+func (d *dispatcher) Pending() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ids := make([]string, 0, len(d.pending))
+	for id := range d.pending {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Health checks that every target is usable
+func (d *dispatcher) Health(ctx context.Context) error {
+	for _, target := range d.config.Targets {
+		if err := validateTarget(target); err != nil {
+			return err
+		}
+		if target.Mode == config.WebhookModeAsync && d.config.CallbackURL == "" {
+			return fmt.Errorf("webhook target %s is asynchronous but no callback URL is configured", target.Name)
+		}
+	}
+	return nil
+}
+
+// callbackURL returns the URL an asynchronous target posts the result of an
+// event to
+func callbackURL(base, eventID string) string {
+	if base == "" {
+		return ""
+	}
+	return strings.TrimSuffix(base, "/") + "/" + eventID
+}
+
+// forget drops an asynchronous delivery that is no longer waited for
+func (d *dispatcher) forget(eventID string) {
+	d.mu.Lock()
+	delete(d.pending, eventID)
+	d.mu.Unlock()
+}
+
+// validateTarget checks the configuration of a target
+func validateTarget(target config.WebhookTarget) error {
+	if target.Name == "" {
+		return fmt.Errorf("webhook target name is required")
+	}
+	if !strings.HasPrefix(target.URL, "http://") && !strings.HasPrefix(target.URL, "https://") {
+		return fmt.Errorf("webhook target %s has invalid URL %q", target.Name, target.URL)
+	}
+	if target.Secret == "" {
+		return fmt.Errorf("webhook target %s has no secret to sign payloads with", target.Name)
+	}
+	switch target.Mode {
+	case "", config.WebhookModeSync, config.WebhookModeAsync:
+		return nil
+	default:
+		return fmt.Errorf("webhook target %s has invalid mode %q", target.Name, target.Mode)
+	}
+}
+
+// decodeResult reads a result from a body, tolerating empty and foreign ones
+func decodeResult(body []byte) *Result {
+	result := &Result{}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return result
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return &Result{Message: strings.TrimSpace(string(body))}
+	}
+	return result
+}
+
+// newEventID returns a random event ID, which also names the callback URL
+// of an asynchronous delivery
+func newEventID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/actions"
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// executor delivers actions through the dispatcher. Webhook actions are
+// delivered to the target they name; other actions to the target listing
+// their type.
+type executor struct {
+	dispatcher Dispatcher
+	storage    storage.StorageManager
+	logger     types.Logger
+}
+
+// NewExecutor creates an action executor that delivers actions to webhook
+// targets
+func NewExecutor(dispatcher Dispatcher, storage storage.StorageManager, logger types.Logger) actions.Executor {
+	return &executor{
+		dispatcher: dispatcher,
+		storage:    storage,
+		logger:     logger,
+	}
+}
+
+// CanExecute checks if a target receives the given action type
+func (e *executor) CanExecute(actionType string) bool {
+	if actionType == actions.ActionTypeWebhook {
+		return true
+	}
+	_, ok := e.dispatcher.TargetFor(actionType)
+	return ok
+}
+
+// Validate validates the action before execution
+func (e *executor) Validate(action *actions.Action) error {
+	if action.Type == "" {
+		return fmt.Errorf("action type cannot be empty")
+	}
+	_, err := e.target(action)
+	return err
+}
+
+// Health checks the health of the dispatcher
+func (e *executor) Health(ctx context.Context) error {
+	return e.dispatcher.Health(ctx)
+}
+
+// Execute delivers the action with the decision and workload it refers to
+// and reports the result of its target
+func (e *executor) Execute(ctx context.Context, action *actions.Action) (*actions.Result, error) {
+	startTime := time.Now()
+
+	target, err := e.target(action)
+	if err != nil {
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Webhook delivery failed: %v", err), err.Error(), nil), nil
+	}
+
+	payload, err := e.payload(ctx, action)
+	if err != nil {
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Webhook delivery failed: %v", err), err.Error(), nil), nil
+	}
+
+	subject := stringValue(action.Parameters, "workload_id")
+	if subject == "" {
+		subject = action.Target
+	}
+	event, err := e.dispatcher.NewEvent(target, EventTypeAction, subject, payload)
+	if err != nil {
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Webhook delivery failed: %v", err), err.Error(), nil), nil
+	}
+
+	e.logger.Info("delivering action to webhook",
+		"target", target.Name,
+		"action_type", action.Type,
+		"event_id", event.ID,
+		"mode", payload.Mode)
+
+	result, err := e.dispatcher.Dispatch(ctx, target, event)
+	if err != nil {
+		return actions.FailedResult(action, startTime, fmt.Sprintf("Webhook delivery failed: %v", err), err.Error(), nil), nil
+	}
+
+	data := make(map[string]interface{}, len(result.Data)+2)
+	for k, v := range result.Data {
+		data[k] = v
+	}
+	data["webhook"] = target.Name
+	data["event_id"] = event.ID
+
+	message := result.Message
+	if message == "" {
+		if result.Succeeded() {
+			message = fmt.Sprintf("Action delivered to webhook %s", target.Name)
+		} else {
+			message = fmt.Sprintf("Webhook %s reported failure", target.Name)
+		}
+	}
+
+	return &actions.Result{
+		ActionType: action.Type,
+		Success:    result.Succeeded(),
+		Message:    message,
+		Data:       data,
+		Duration:   time.Since(startTime),
+		Timestamp:  time.Now(),
+		Error:      result.Error,
+	}, nil
+}
+
+// target resolves the target an action is delivered to
+func (e *executor) target(action *actions.Action) (config.WebhookTarget, error) {
+	if action.Type == actions.ActionTypeWebhook {
+		target, ok := e.dispatcher.Target(action.Target)
+		if !ok {
+			return config.WebhookTarget{}, fmt.Errorf("%w: %s", types.ErrWebhookTargetNotFound, action.Target)
+		}
+		return target, nil
+	}
+	target, ok := e.dispatcher.TargetFor(action.Type)
+	if !ok {
+		return config.WebhookTarget{}, fmt.Errorf("%w: no target receives %s actions", types.ErrWebhookTargetNotFound, action.Type)
+	}
+	return target, nil
+}
+
+// payload loads the decision and workload an action refers to
+func (e *executor) payload(ctx context.Context, action *actions.Action) (*Payload, error) {
+	payload := &Payload{Action: action}
+
+	if workloadID := stringValue(action.Parameters, "workload_id"); workloadID != "" {
+		workload, err := e.storage.Workload().Get(ctx, workloadID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get workload: %w", err)
+		}
+		payload.Workload = workload
+	}
+
+	decisionID := stringValue(action.Parameters, "decision_id")
+	if decisionID == "" {
+		decisionID = stringValue(action.Metadata, "decision_id")
+	}
+	if decisionID != "" {
+		decision, err := e.storage.Decision().Get(ctx, decisionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get decision: %w", err)
+		}
+		payload.Decision = decision
+	}
+
+	return payload, nil
+}
+
+// stringValue reads a string from a map that may be nil
+func stringValue(values map[string]interface{}, key string) string {
+	value, _ := values[key].(string)
+	return value
+}
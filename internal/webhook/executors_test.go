@@ -0,0 +1,323 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcloud-opt/policy/internal/actions"
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/storage"
+	"github.com/kcloud-opt/policy/internal/storage/memory"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+const testSecret = "s3cret"
+
+// testLogger discards log output
+type testLogger struct{}
+
+func (l testLogger) Info(msg string, fields ...interface{})                    {}
+func (l testLogger) Warn(msg string, fields ...interface{})                    {}
+func (l testLogger) Error(msg string, fields ...interface{})                   {}
+func (l testLogger) Debug(msg string, fields ...interface{})                   {}
+func (l testLogger) Fatal(msg string, fields ...interface{})                   {}
+func (l testLogger) WithError(err error) types.Logger                          { return l }
+func (l testLogger) WithDuration(duration time.Duration) types.Logger          { return l }
+func (l testLogger) WithPolicy(policyID, policyName string) types.Logger       { return l }
+func (l testLogger) WithWorkload(workloadID, workloadType string) types.Logger { return l }
+func (l testLogger) WithEvaluation(evaluationID string) types.Logger           { return l }
+
+// delivered is a request received by the stand-in target
+type delivered struct {
+	header http.Header
+	body   []byte
+	event  CloudEvent
+	data   struct {
+		Action      map[string]interface{} `json:"action"`
+		Decision    *types.Decision        `json:"decision"`
+		Workload    *types.Workload        `json:"workload"`
+		Mode        string                 `json:"mode"`
+		CallbackURL string                 `json:"callbackUrl"`
+	}
+}
+
+// standIn is an httptest server recording the events delivered to it
+type standIn struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*delivered
+}
+
+func newStandIn(t *testing.T, respond func(w http.ResponseWriter, req *delivered)) *standIn {
+	t.Helper()
+
+	s := &standIn{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		req := &delivered{header: r.Header, body: body}
+		require.NoError(t, json.Unmarshal(body, &req.event))
+		require.NoError(t, json.Unmarshal(req.event.Data, &req.data))
+
+		s.mu.Lock()
+		s.requests = append(s.requests, req)
+		s.mu.Unlock()
+		respond(w, req)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *standIn) last(t *testing.T) *delivered {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	require.NotEmpty(t, s.requests)
+	return s.requests[len(s.requests)-1]
+}
+
+func setup(t *testing.T) storage.StorageManager {
+	t.Helper()
+
+	store := memory.NewStorageManager()
+	ctx := context.Background()
+	require.NoError(t, store.Workload().Create(ctx, &types.Workload{
+		ID:           "wl-1",
+		Name:         "api",
+		Type:         types.WorkloadTypeWeb,
+		Status:       types.WorkloadStatusRunning,
+		Requirements: types.Resources{CPU: 1, Memory: "1Gi"},
+	}))
+	require.NoError(t, store.Decision().Create(ctx, &types.Decision{
+		ID:         "dec-1",
+		PolicyID:   "policy-1",
+		WorkloadID: "wl-1",
+		Type:       types.DecisionTypeScale,
+		Status:     types.DecisionStatusApproved,
+	}))
+	return store
+}
+
+func newDispatcher(targets ...config.WebhookTarget) Dispatcher {
+	return NewDispatcher(config.WebhookConfig{
+		Source:          "/test/policy-engine",
+		CallbackURL:     "http://policy-engine/api/v1/webhooks/callbacks/",
+		Timeout:         time.Second,
+		CallbackTimeout: time.Second,
+		Targets:         targets,
+	}, nil, testLogger{})
+}
+
+func scaleAction() *actions.Action {
+	return &actions.Action{
+		Type:       actions.ActionTypeScale,
+		Target:     "wl-1",
+		Parameters: map[string]interface{}{"workload_id": "wl-1", "replicas": 3},
+		Metadata:   map[string]interface{}{"decision_id": "dec-1"},
+	}
+}
+
+func TestExecutor_SyncDeliversSignedCloudEvent(t *testing.T) {
+	server := newStandIn(t, func(w http.ResponseWriter, req *delivered) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"success":true,"message":"scaled by controller","data":{"replicas":3}}`))
+	})
+	store := setup(t)
+	dispatcher := newDispatcher(config.WebhookTarget{
+		Name:        "platform",
+		URL:         server.URL,
+		Secret:      testSecret,
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+		ActionTypes: []string{actions.ActionTypeScale},
+	})
+	executor := NewExecutor(dispatcher, store, testLogger{})
+
+	require.True(t, executor.CanExecute(actions.ActionTypeScale))
+	require.False(t, executor.CanExecute(actions.ActionTypeMigrate))
+	require.NoError(t, executor.Validate(scaleAction()))
+
+	result, err := executor.Execute(context.Background(), scaleAction())
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Message)
+	assert.Equal(t, "scaled by controller", result.Message)
+	assert.EqualValues(t, 3, result.Data["replicas"])
+	assert.Equal(t, "platform", result.Data["webhook"])
+
+	req := server.last(t)
+	assert.Equal(t, ContentType, req.header.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", req.header.Get("Authorization"))
+	assert.True(t, Verify(testSecret, req.body, req.header.Get(SignatureHeader)))
+	assert.Equal(t, SpecVersion, req.event.SpecVersion)
+	assert.Equal(t, EventTypeAction, req.event.Type)
+	assert.Equal(t, "/test/policy-engine", req.event.Source)
+	assert.Equal(t, "wl-1", req.event.Subject)
+	assert.Equal(t, result.Data["event_id"], req.event.ID)
+	assert.Equal(t, actions.ActionTypeScale, req.data.Action["type"])
+	require.NotNil(t, req.data.Decision)
+	assert.Equal(t, "dec-1", req.data.Decision.ID)
+	require.NotNil(t, req.data.Workload)
+	assert.Equal(t, "wl-1", req.data.Workload.ID)
+	assert.Equal(t, config.WebhookModeSync, req.data.Mode)
+	assert.Empty(t, req.data.CallbackURL)
+}
+
+func TestExecutor_SyncFailures(t *testing.T) {
+	t.Run("error status", func(t *testing.T) {
+		server := newStandIn(t, func(w http.ResponseWriter, req *delivered) {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":"controller unavailable"}`))
+		})
+		executor := NewExecutor(newDispatcher(config.WebhookTarget{
+			Name: "platform", URL: server.URL, Secret: testSecret, ActionTypes: []string{actions.ActionTypeScale},
+		}), setup(t), testLogger{})
+
+		result, err := executor.Execute(context.Background(), scaleAction())
+		require.NoError(t, err)
+		assert.False(t, result.Success)
+		assert.Equal(t, "controller unavailable", result.Error)
+	})
+
+	t.Run("reported failure", func(t *testing.T) {
+		server := newStandIn(t, func(w http.ResponseWriter, req *delivered) {
+			_, _ = w.Write([]byte(`{"success":false,"error":"quota exceeded"}`))
+		})
+		executor := NewExecutor(newDispatcher(config.WebhookTarget{
+			Name: "platform", URL: server.URL, Secret: testSecret, ActionTypes: []string{actions.ActionTypeScale},
+		}), setup(t), testLogger{})
+
+		result, err := executor.Execute(context.Background(), scaleAction())
+		require.NoError(t, err)
+		assert.False(t, result.Success)
+		assert.Equal(t, "quota exceeded", result.Error)
+	})
+
+	t.Run("target timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		server := newStandIn(t, func(w http.ResponseWriter, req *delivered) {
+			<-release
+		})
+		defer close(release)
+		executor := NewExecutor(newDispatcher(config.WebhookTarget{
+			Name: "platform", URL: server.URL, Secret: testSecret, Timeout: 50 * time.Millisecond, ActionTypes: []string{actions.ActionTypeScale},
+		}), setup(t), testLogger{})
+
+		result, err := executor.Execute(context.Background(), scaleAction())
+		require.NoError(t, err)
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Error, "deadline exceeded")
+	})
+}
+
+func TestExecutor_WebhookActionNamesTarget(t *testing.T) {
+	server := newStandIn(t, func(w http.ResponseWriter, req *delivered) {})
+	executor := NewExecutor(newDispatcher(config.WebhookTarget{Name: "audit", URL: server.URL, Secret: testSecret}), setup(t), testLogger{})
+
+	require.True(t, executor.CanExecute(actions.ActionTypeWebhook))
+	err := executor.Validate(&actions.Action{Type: actions.ActionTypeWebhook, Target: "missing"})
+	assert.True(t, errors.Is(err, types.ErrWebhookTargetNotFound))
+
+	result, err := executor.Execute(context.Background(), &actions.Action{Type: actions.ActionTypeWebhook, Target: "audit"})
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Message)
+	assert.Equal(t, "Action delivered to webhook audit", result.Message)
+	assert.Nil(t, server.last(t).data.Workload)
+}
+
+func TestExecutor_AsyncCallback(t *testing.T) {
+	var dispatcher Dispatcher
+	server := newStandIn(t, func(w http.ResponseWriter, req *delivered) {
+		w.WriteHeader(http.StatusAccepted)
+
+		eventID := req.event.ID
+		go func() {
+			body := []byte(`{"success":true,"message":"migrated","data":{"node":"node-b"}}`)
+			assert.True(t, errors.Is(dispatcher.Complete(eventID, body, ""), types.ErrInvalidSignature))
+			assert.True(t, errors.Is(dispatcher.Complete(eventID, body, Sign("wrong", body)), types.ErrInvalidSignature))
+			assert.NoError(t, dispatcher.Complete(eventID, body, Sign(testSecret, body)))
+		}()
+	})
+	dispatcher = newDispatcher(config.WebhookTarget{
+		Name:        "platform",
+		URL:         server.URL,
+		Secret:      testSecret,
+		Mode:        config.WebhookModeAsync,
+		ActionTypes: []string{actions.ActionTypeMigrate},
+	})
+	executor := NewExecutor(dispatcher, setup(t), testLogger{})
+
+	result, err := executor.Execute(context.Background(), &actions.Action{
+		Type:       actions.ActionTypeMigrate,
+		Target:     "wl-1",
+		Parameters: map[string]interface{}{"workload_id": "wl-1", "decision_id": "dec-1"},
+	})
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Message)
+	assert.Equal(t, "migrated", result.Message)
+	assert.Equal(t, "node-b", result.Data["node"])
+	assert.Empty(t, dispatcher.Pending())
+
+	req := server.last(t)
+	assert.Equal(t, EventTypeAction, req.event.Type)
+	assert.Equal(t, config.WebhookModeAsync, req.data.Mode)
+	assert.Equal(t, "http://policy-engine/api/v1/webhooks/callbacks/"+req.event.ID, req.data.CallbackURL)
+	require.NotNil(t, req.data.Decision)
+	assert.Equal(t, "dec-1", req.data.Decision.ID)
+
+	body := []byte(`{"success":true}`)
+	assert.True(t, errors.Is(dispatcher.Complete(req.event.ID, body, Sign(testSecret, body)), types.ErrWebhookCallbackNotFound))
+}
+
+func TestExecutor_AsyncCallbackTimeout(t *testing.T) {
+	server := newStandIn(t, func(w http.ResponseWriter, req *delivered) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	dispatcher := newDispatcher(config.WebhookTarget{
+		Name:            "platform",
+		URL:             server.URL,
+		Secret:          testSecret,
+		Mode:            config.WebhookModeAsync,
+		CallbackTimeout: 50 * time.Millisecond,
+		ActionTypes:     []string{actions.ActionTypeScale},
+	})
+	executor := NewExecutor(dispatcher, setup(t), testLogger{})
+
+	result, err := executor.Execute(context.Background(), &actions.Action{
+		Type:       actions.ActionTypeScale,
+		Parameters: map[string]interface{}{"workload_id": "wl-1"},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "did not call back")
+	assert.Empty(t, dispatcher.Pending())
+}
+
+func TestExecutor_TargetWithoutSecretIsRejected(t *testing.T) {
+	server := newStandIn(t, func(w http.ResponseWriter, req *delivered) {
+		t.Errorf("target without a secret was sent event %s", req.event.ID)
+	})
+	dispatcher := newDispatcher(config.WebhookTarget{
+		Name:        "platform",
+		URL:         server.URL,
+		Mode:        config.WebhookModeAsync,
+		ActionTypes: []string{actions.ActionTypeScale},
+	})
+	executor := NewExecutor(dispatcher, setup(t), testLogger{})
+
+	assert.ErrorContains(t, executor.Health(context.Background()), "no secret")
+
+	result, err := executor.Execute(context.Background(), scaleAction())
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "no secret")
+	assert.Empty(t, dispatcher.Pending())
+}
@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/kcloud-opt/policy/internal/actions"
+	"github.com/kcloud-opt/policy/internal/config"
+	"github.com/kcloud-opt/policy/internal/types"
+)
+
+// Dispatcher delivers actions to HTTP targets as signed CloudEvents
+type Dispatcher interface {
+	// NewEvent creates the event delivering a payload to a target
+	NewEvent(target config.WebhookTarget, eventType, subject string, payload *Payload) (*CloudEvent, error)
+
+	// Dispatch delivers an event to a target and returns the result the
+	// target reports, waiting for its callback if the target is asynchronous
+	Dispatch(ctx context.Context, target config.WebhookTarget, event *CloudEvent) (*Result, error)
+
+	// Complete settles an asynchronous delivery with the callback body its
+	// target posted, after checking the body's signature
+	Complete(eventID string, body []byte, signature string) error
+
+	// Target returns the target with the given name
+	Target(name string) (config.WebhookTarget, bool)
+
+	// TargetFor returns the target actions of the given type are delivered to
+	TargetFor(actionType string) (config.WebhookTarget, bool)
+
+	// Pending returns the IDs of deliveries waiting for their callback
+	Pending() []string
+
+	// Health checks the health of the dispatcher
+	Health(ctx context.Context) error
+}
+
+// CloudEvent is a CloudEvents 1.0 event in structured JSON mode
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// Payload is the data of an action event
+type Payload struct {
+	Action   *actions.Action `json:"action"`
+	Decision *types.Decision `json:"decision,omitempty"`
+	Workload *types.Workload `json:"workload,omitempty"`
+
+	// Mode tells the target whether to answer in the response body or
+	// post the result to CallbackURL
+	Mode        string `json:"mode"`
+	CallbackURL string `json:"callbackUrl,omitempty"`
+}
+
+// Result is the outcome of an action as reported by a target, in the
+// response body of a synchronous delivery or the body of a callback. A
+// missing success field follows the HTTP status of a response and the
+// error of a callback.
+type Result struct {
+	Success *bool                  `json:"success,omitempty"`
+	Message string                 `json:"message,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// Succeeded reports whether the target reported success
+func (r *Result) Succeeded() bool {
+	return r.Success != nil && *r.Success
+}
+
+// CloudEvents attributes and headers of delivered events
+const (
+	SpecVersion = "1.0"
+
+	// ContentType is the media type of events in structured mode
+	ContentType = "application/cloudevents+json"
+
+	// SignatureHeader carries the HMAC-SHA256 signature of the body as
+	// sha256=<hex>, on both deliveries and callbacks
+	SignatureHeader = "X-Kcloud-Signature"
+
+	// EventTypeAction is the type of delivered actions, whether they come
+	// from a policy enforcement or an automation rule
+	EventTypeAction = "io.kcloud.policy.action"
+)